@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/archive"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// maxImportSize caps base archive uploads; parts past 10MB are spooled to disk
+const maxImportSize = 4 << 30
+
+type ArchiveHandler struct {
+	store *store.ArchiveStore
+}
+
+func NewArchiveHandler(store *store.ArchiveStore) *ArchiveHandler {
+	return &ArchiveHandler{store: store}
+}
+
+// ExportBase handles GET /bases/:id/export, downloading the base as a zip archive
+func (h *ArchiveHandler) ExportBase(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	baseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid base ID")
+		return
+	}
+
+	a, err := h.store.ExportBase(r.Context(), baseID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Base not found")
+			return
+		}
+		if errors.Is(err, store.ErrForbidden) {
+			writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to export this base")
+			return
+		}
+		log.Printf("Error exporting base: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to export base")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Base.Name + ".zip"}))
+	if err := h.store.WriteArchive(r.Context(), w, a); err != nil {
+		// The response has started, so the client sees a truncated archive
+		log.Printf("Error writing archive of base %s: %v", baseID, err)
+	}
+}
+
+// ImportBase handles POST /bases/import with the archive as the multipart "file"
+// and an optional "name" for the new base
+func (h *ArchiveHandler) ImportBase(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "file_too_large", "Archive exceeds the server limit of "+strconv.Itoa(maxImportSize)+" bytes")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file_required", "File is required")
+		return
+	}
+	defer file.Close()
+
+	reader, err := archive.Read(file, header.Size)
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalidArchive) || errors.Is(err, archive.ErrUnsupportedVersion) {
+			writeError(w, http.StatusBadRequest, "invalid_archive", capitalize(err.Error()))
+			return
+		}
+		log.Printf("Error reading archive: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to read archive")
+		return
+	}
+
+	base, err := h.store.ImportBase(r.Context(), reader, strings.TrimSpace(r.FormValue("name")), user.ID)
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalidArchive) {
+			writeError(w, http.StatusBadRequest, "invalid_archive", capitalize(err.Error()))
+			return
+		}
+		if writeUploadRuleError(w, err) {
+			return
+		}
+		log.Printf("Error importing base: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to import base")
+		return
+	}
+
+	log.Printf("Base imported: %s (id=%s) by user %s", base.Name, base.ID, user.Email)
+	writeJSON(w, http.StatusCreated, base)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+)
+
+func TestArchiveHandler_ExportBase(t *testing.T) {
+	t.Run("returns 401 when no user in context", func(t *testing.T) {
+		handler := NewArchiveHandler(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/bases/123/export", nil)
+		w := httptest.NewRecorder()
+
+		handler.ExportBase(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("returns 400 for invalid base ID", func(t *testing.T) {
+		handler := NewArchiveHandler(nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		req := httptest.NewRequest(http.MethodGet, "/bases/not-a-uuid/export", nil)
+		req = withURLParam(req, "id", "not-a-uuid")
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.ExportBase(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestArchiveHandler_ImportBase(t *testing.T) {
+	t.Run("returns 401 when no user in context", func(t *testing.T) {
+		handler := NewArchiveHandler(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/bases/import", nil)
+		w := httptest.NewRecorder()
+
+		handler.ImportBase(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	upload := func(t *testing.T, field, content string) *http.Request {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, err := mw.CreateFormFile(field, "base.zip")
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		req := httptest.NewRequest(http.MethodPost, "/bases/import", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req.WithContext(SetUserInContext(req.Context(), user))
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		code  string
+	}{
+		{"missing file", "attachment", "file_required"},
+		{"file that is not an archive", "file", "invalid_archive"},
+	}
+	for _, tt := range tests {
+		t.Run("returns 400 for "+tt.name, func(t *testing.T) {
+			handler := NewArchiveHandler(nil)
+
+			req := upload(t, tt.field, "not a zip file")
+			w := httptest.NewRecorder()
+
+			handler.ImportBase(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response ErrorResponse
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, response.Error)
+		})
+	}
+}
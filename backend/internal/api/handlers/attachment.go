@@ -4,14 +4,26 @@ import (
 	"errors"
 	"io"
 	"log"
+	"mime"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/storage"
 	"github.com/vibetable/backend/internal/store"
 )
 
+// maxUploadSize caps upload request bodies regardless of the field's own limit
+const maxUploadSize = 100 << 20
+
+// attachmentCSP stops downloaded files, such as SVG or HTML, from running scripts
+// if a browser renders them
+const attachmentCSP = "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+
 type AttachmentHandler struct {
 	store *store.AttachmentStore
 }
@@ -40,8 +52,14 @@ func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Parse multipart form (max 10MB)
+	// Parse multipart form, keeping up to 10MB in memory
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "file_too_large", "Upload exceeds the server limit of "+strconv.Itoa(maxUploadSize)+" bytes")
+			return
+		}
 		writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse multipart form")
 		return
 	}
@@ -69,6 +87,9 @@ func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Requ
 			writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to add attachments")
 			return
 		}
+		if writeUploadRuleError(w, err) {
+			return
+		}
 		log.Printf("Error creating attachment: %v", err)
 		writeError(w, http.StatusInternalServerError, "server_error", "Failed to upload attachment")
 		return
@@ -141,22 +162,31 @@ func (h *AttachmentHandler) GetAttachment(w http.ResponseWriter, r *http.Request
 	writeJSON(w, http.StatusOK, attachment)
 }
 
-// DownloadAttachment handles GET /attachments/:id/download
+// DownloadAttachment handles GET /attachments/:id/download. A signed URL from the
+// attachment's url field works without a session.
 func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
-	user := GetUserFromContext(r.Context())
-	if user == nil {
-		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
-		return
-	}
-
 	attachmentID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid attachment ID")
 		return
 	}
 
-	reader, attachment, err := h.store.DownloadAttachment(r.Context(), attachmentID, user.ID)
+	var reader io.ReadCloser
+	var attachment *models.Attachment
+	if r.URL.Query().Has("signature") {
+		reader, attachment, err = h.store.DownloadSignedAttachment(r.Context(), attachmentID, r.URL.Query())
+	} else {
+		user := GetUserFromContext(r.Context())
+		if user == nil {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+			return
+		}
+		reader, attachment, err = h.store.DownloadAttachment(r.Context(), attachmentID, user.ID)
+	}
 	if err != nil {
+		if writeSignedURLError(w, err) {
+			return
+		}
 		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "not_found", "Attachment not found or access denied")
 			return
@@ -167,9 +197,13 @@ func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Re
 	}
 	defer reader.Close()
 
-	// Set headers for download
+	// Set headers for download. Files are always downloaded rather than displayed,
+	// under their stored type rather than one the browser sniffs, and the filename
+	// is encoded so it cannot inject header parameters.
 	w.Header().Set("Content-Type", attachment.ContentType)
-	w.Header().Set("Content-Disposition", "attachment; filename=\""+attachment.Filename+"\"")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", attachmentCSP)
 	w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
 
 	// Stream the file
@@ -178,6 +212,105 @@ func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Re
 	}
 }
 
+// DownloadThumbnail handles GET /attachments/:id/thumbnail?size=small|large. A signed
+// URL from the attachment's thumbnail_url field works without a session.
+func (h *AttachmentHandler) DownloadThumbnail(w http.ResponseWriter, r *http.Request) {
+	attachmentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid attachment ID")
+		return
+	}
+
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = models.DefaultThumbnailSize
+	}
+	if !models.HasThumbnailSize(size) {
+		writeError(w, http.StatusBadRequest, "invalid_size", "Invalid thumbnail size")
+		return
+	}
+
+	var reader io.ReadCloser
+	if r.URL.Query().Has("signature") {
+		reader, err = h.store.DownloadSignedThumbnail(r.Context(), attachmentID, size, r.URL.Query())
+	} else {
+		user := GetUserFromContext(r.Context())
+		if user == nil {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+			return
+		}
+		reader, _, err = h.store.DownloadThumbnail(r.Context(), attachmentID, user.ID, size)
+	}
+	if err != nil {
+		if writeSignedURLError(w, err) {
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Thumbnail not found or access denied")
+			return
+		}
+		log.Printf("Error downloading thumbnail: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to download thumbnail")
+		return
+	}
+	defer reader.Close()
+
+	// Thumbnails never change once generated
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+
+	if _, err := io.Copy(w, reader); err != nil {
+		log.Printf("Error streaming thumbnail: %v", err)
+	}
+}
+
+// DownloadFile handles GET /attachments/file/* for signed URLs from storage.GetURL
+func (h *AttachmentHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+	if key == "" || !r.URL.Query().Has("signature") {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "A signed URL is required")
+		return
+	}
+
+	reader, err := h.store.DownloadSignedFile(r.Context(), key, r.URL.Query())
+	if err != nil {
+		if writeSignedURLError(w, err) {
+			return
+		}
+		log.Printf("Error downloading file: %v", err)
+		writeError(w, http.StatusNotFound, "not_found", "File not found")
+		return
+	}
+	defer reader.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", attachmentCSP)
+
+	if _, err := io.Copy(w, reader); err != nil {
+		log.Printf("Error streaming file: %v", err)
+	}
+}
+
+// writeSignedURLError writes the response for a rejected signed URL and reports
+// whether err was one
+func writeSignedURLError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, storage.ErrURLExpired):
+		writeError(w, http.StatusForbidden, "link_expired", "This link has expired")
+	case errors.Is(err, storage.ErrInvalidSignature):
+		writeError(w, http.StatusForbidden, "invalid_signature", "This link is not valid")
+	default:
+		return false
+	}
+	return true
+}
+
 // DeleteAttachment handles DELETE /attachments/:id
 func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
 	user := GetUserFromContext(r.Context())
@@ -211,3 +344,57 @@ func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Requ
 		"message": "Attachment deleted successfully",
 	})
 }
+
+// GetStorageUsage handles GET /bases/:id/storage
+func (h *AttachmentHandler) GetStorageUsage(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	baseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid base ID")
+		return
+	}
+
+	usage, err := h.store.GetStorageUsage(r.Context(), baseID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Base not found")
+			return
+		}
+		log.Printf("Error getting storage usage: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get storage usage")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// writeUploadRuleError writes the response for an upload that breaks the field's
+// rules or the base's quota, reporting whether err was one of those
+func writeUploadRuleError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, store.ErrNotAttachmentField):
+		writeError(w, http.StatusBadRequest, "invalid_field", "Field is not an attachment field")
+	case errors.Is(err, store.ErrFileTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "file_too_large", capitalize(err.Error()))
+	case errors.Is(err, store.ErrUnsupportedMediaType):
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", capitalize(err.Error()))
+	case errors.Is(err, store.ErrStorageQuotaExceeded):
+		writeError(w, http.StatusRequestEntityTooLarge, "storage_quota_exceeded", capitalize(err.Error()))
+	default:
+		return false
+	}
+	return true
+}
+
+// capitalize upper-cases the first letter of an error message for display
+func capitalize(message string) string {
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
@@ -7,13 +7,17 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/storage"
+	"github.com/vibetable/backend/internal/store"
 )
 
 func TestNewAttachmentHandler(t *testing.T) {
@@ -320,6 +324,70 @@ func TestAttachmentHandler_DownloadAttachment(t *testing.T) {
 	})
 }
 
+func TestAttachmentHandler_SignedDownloads(t *testing.T) {
+	attachmentStore := store.NewAttachmentStore(nil, nil, nil, nil, nil, "http://localhost")
+	attachmentStore.SetURLSigner(storage.NewURLSigner([]byte("test-key")))
+	handler := NewAttachmentHandler(attachmentStore)
+
+	t.Run("rejects a forged download signature without a user", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/attachments/123/download?expires=9999999999&signature=forged", nil)
+		req = withURLParam(req, "id", uuid.New().String())
+		w := httptest.NewRecorder()
+
+		handler.DownloadAttachment(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_signature", response.Error)
+	})
+
+	t.Run("rejects an unknown thumbnail size", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/attachments/123/thumbnail?size=huge", nil)
+		req = withURLParam(req, "id", uuid.New().String())
+		w := httptest.NewRecorder()
+
+		handler.DownloadThumbnail(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("requires a signature for stored files", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/attachments/file/a/b/c.png", nil)
+		req = withURLParam(req, "*", "a/b/c.png")
+		w := httptest.NewRecorder()
+
+		handler.DownloadFile(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("serves stored files as downloads the browser cannot re-sniff", func(t *testing.T) {
+		signer := storage.NewURLSigner([]byte("test-key"))
+		local, err := storage.NewLocalStorage(t.TempDir(), "http://localhost")
+		require.NoError(t, err)
+		local.SetURLSigner(signer)
+		require.NoError(t, local.Upload(context.Background(), "a/b/page.html", strings.NewReader("<script></script>"), "text/html"))
+		fileStore := store.NewAttachmentStore(nil, nil, nil, nil, local, "http://localhost")
+		fileStore.SetURLSigner(signer)
+
+		fileURL, err := local.GetURL(context.Background(), "a/b/page.html", time.Minute)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, fileURL, nil)
+		req = withURLParam(req, "*", "a/b/page.html")
+		w := httptest.NewRecorder()
+
+		NewAttachmentHandler(fileStore).DownloadFile(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+		assert.Contains(t, w.Header().Get("Content-Security-Policy"), "sandbox")
+	})
+}
+
 func TestAttachmentHandler_DeleteAttachment(t *testing.T) {
 	t.Run("returns 401 when no user in context", func(t *testing.T) {
 		handler := NewAttachmentHandler(nil)
@@ -357,3 +425,36 @@ func TestAttachmentHandler_DeleteAttachment(t *testing.T) {
 		assert.Equal(t, "invalid_id", response.Error)
 	})
 }
+
+func TestAttachmentHandler_GetStorageUsage(t *testing.T) {
+	t.Run("returns 401 when no user in context", func(t *testing.T) {
+		handler := NewAttachmentHandler(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/bases/123/storage", nil)
+		req = withURLParam(req, "id", uuid.New().String())
+		w := httptest.NewRecorder()
+
+		handler.GetStorageUsage(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("returns 400 for invalid base ID", func(t *testing.T) {
+		handler := NewAttachmentHandler(nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		req := httptest.NewRequest(http.MethodGet, "/bases/not-a-uuid/storage", nil)
+		req = withURLParam(req, "id", "not-a-uuid")
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.GetStorageUsage(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_id", response.Error)
+	})
+}
@@ -1,23 +1,40 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/automation"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/outbound"
 	"github.com/vibetable/backend/internal/store"
 )
 
 type AutomationHandler struct {
-	store *store.AutomationStore
+	store    *store.AutomationStore
+	engine   *automation.Engine
+	outbound outbound.Policy
 }
 
 func NewAutomationHandler(store *store.AutomationStore) *AutomationHandler {
-	return &AutomationHandler{store: store}
+	return &AutomationHandler{store: store, outbound: outbound.DefaultPolicy()}
+}
+
+// SetEngine sets the automation engine used for test runs
+func (h *AutomationHandler) SetEngine(engine *automation.Engine) {
+	h.engine = engine
+}
+
+// SetOutboundPolicy sets the policy send_webhook URLs are checked against
+func (h *AutomationHandler) SetOutboundPolicy(policy outbound.Policy) {
+	h.outbound = policy
 }
 
 // ListAutomations GET /tables/:tableId/automations
@@ -98,6 +115,14 @@ func (h *AutomationHandler) CreateAutomation(w http.ResponseWriter, r *http.Requ
 	if req.ActionConfig == nil {
 		req.ActionConfig = json.RawMessage("{}")
 	}
+	if err := h.checkWebhookURL(r.Context(), models.ActionType(req.ActionType), req.ActionConfig); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_url", err.Error())
+		return
+	}
+	if errs := automation.ValidateActionTemplates(models.ActionType(req.ActionType), req.ActionConfig); len(errs) > 0 {
+		writeError(w, http.StatusBadRequest, "invalid_template", strings.Join(errs, "; "))
+		return
+	}
 
 	automation := &models.Automation{
 		TableID:       tableID,
@@ -192,6 +217,25 @@ func (h *AutomationHandler) UpdateAutomation(w http.ResponseWriter, r *http.Requ
 		updates["actionType"] = models.ActionType(*req.ActionType)
 	}
 	if req.ActionConfig != nil {
+		var actionType models.ActionType
+		if req.ActionType != nil {
+			actionType = models.ActionType(*req.ActionType)
+		} else {
+			existing, err := h.store.GetAutomation(r.Context(), automationID, user.ID)
+			if err != nil {
+				handleAutomationStoreError(w, err)
+				return
+			}
+			actionType = existing.ActionType
+		}
+		if err := h.checkWebhookURL(r.Context(), actionType, req.ActionConfig); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_url", err.Error())
+			return
+		}
+		if errs := automation.ValidateActionTemplates(actionType, req.ActionConfig); len(errs) > 0 {
+			writeError(w, http.StatusBadRequest, "invalid_template", strings.Join(errs, "; "))
+			return
+		}
 		updates["actionConfig"] = []byte(req.ActionConfig)
 	}
 
@@ -283,6 +327,128 @@ func (h *AutomationHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// TestAutomation POST /automations/:id/test
+// Runs the automation against a record in dry-run mode: nothing is written and
+// no outbound HTTP request is made unless allowHttp is set.
+func (h *AutomationHandler) TestAutomation(w http.ResponseWriter, r *http.Request) {
+	automationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid automation ID")
+		return
+	}
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	var req struct {
+		RecordID  *uuid.UUID             `json:"recordId"`
+		OldValues map[string]interface{} `json:"oldValues"`
+		Payload   json.RawMessage        `json:"payload"`
+		AllowHTTP bool                   `json:"allowHttp"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+		return
+	}
+
+	if h.engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Automation engine is not available")
+		return
+	}
+
+	a, err := h.store.GetAutomationForEdit(r.Context(), automationID, user.ID)
+	if err != nil {
+		handleAutomationStoreError(w, err)
+		return
+	}
+
+	result, err := h.engine.DryRun(r.Context(), *a, automation.DryRunOptions{
+		RecordID:  req.RecordID,
+		OldValues: req.OldValues,
+		Payload:   req.Payload,
+		AllowHTTP: req.AllowHTTP,
+	}, user.ID)
+	if err != nil {
+		if errors.Is(err, automation.ErrRecordNotInTable) {
+			writeError(w, http.StatusBadRequest, "invalid_record", "Record does not belong to this automation's table")
+			return
+		}
+		handleAutomationStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// maxInboundWebhookBytes caps the body accepted by inbound automation webhooks
+const maxInboundWebhookBytes = 1 << 20 // 1MB
+
+// ReceiveWebhook POST /public/automation-webhooks/:token
+// Triggers a webhook_received automation; the JSON body is available to its action
+// through {{payload:path}} references.
+func (h *AutomationHandler) ReceiveWebhook(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	if token == "" {
+		writeError(w, http.StatusNotFound, "not_found", "Automation not found")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboundWebhookBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Could not read request body")
+		return
+	}
+	if len(body) > maxInboundWebhookBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "payload_too_large", "Request body is too large")
+		return
+	}
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+	if !json.Valid(body) {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+		return
+	}
+
+	if h.engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Automation engine is not available")
+		return
+	}
+
+	a, err := h.store.GetAutomationByTriggerToken(r.Context(), token)
+	if err != nil {
+		handleAutomationStoreError(w, err)
+		return
+	}
+	if !a.Enabled {
+		writeError(w, http.StatusNotFound, "not_found", "Automation not found")
+		return
+	}
+
+	// Run detached from the request so the caller isn't kept waiting
+	h.engine.ProcessWebhook(context.Background(), *a, body)
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"accepted": true,
+	})
+}
+
+// checkWebhookURL rejects send_webhook actions that point at internal addresses
+func (h *AutomationHandler) checkWebhookURL(ctx context.Context, actionType models.ActionType, config json.RawMessage) error {
+	if actionType != models.ActionSendWebhook {
+		return nil
+	}
+	var c models.SendWebhookConfig
+	if err := json.Unmarshal(config, &c); err != nil || c.URL == "" {
+		return nil // Malformed configs are reported by validation and dry runs
+	}
+	return h.outbound.CheckURL(ctx, c.URL)
+}
+
 // handleAutomationStoreError converts store errors to HTTP responses
 func handleAutomationStoreError(w http.ResponseWriter, err error) {
 	if errors.Is(err, store.ErrNotFound) {
@@ -192,6 +192,49 @@ func TestAutomationHandler_CreateAutomation(t *testing.T) {
 		assert.Equal(t, "action_required", response.Error)
 	})
 
+	t.Run("returns 400 for webhook actions aimed at internal addresses", func(t *testing.T) {
+		handler := NewAutomationHandler(nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		body := bytes.NewBufferString(`{"name": "Test", "triggerType": "record_created", "actionType": "send_webhook", "actionConfig": {"url": "http://169.254.169.254/latest/meta-data"}}`)
+		req := httptest.NewRequest(http.MethodPost, "/tables/123/automations", body)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("tableId", uuid.New().String())
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.CreateAutomation(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_url", response.Error)
+	})
+
+	t.Run("returns 400 for malformed templates", func(t *testing.T) {
+		handler := NewAutomationHandler(nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		body := bytes.NewBufferString(`{"name": "Test", "triggerType": "record_created", "actionType": "add_comment", "actionConfig": {"content": "{{#if field:Status}}open"}}`)
+		req := httptest.NewRequest(http.MethodPost, "/tables/123/automations", body)
+		req = withURLParam(req, "tableId", uuid.New().String())
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.CreateAutomation(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_template", response.Error)
+		assert.Contains(t, response.Message, "actionConfig.content")
+	})
+
 	t.Run("handles optional fields", func(t *testing.T) {
 		handler := NewAutomationHandler(nil)
 
@@ -465,6 +508,87 @@ func TestAutomationHandler_ListRuns(t *testing.T) {
 	})
 }
 
+func TestAutomationHandler_TestAutomation(t *testing.T) {
+	t.Run("returns 400 for invalid automation ID", func(t *testing.T) {
+		handler := NewAutomationHandler(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/automations/not-a-uuid/test", bytes.NewBufferString(`{}`))
+		req = withURLParam(req, "id", "not-a-uuid")
+		w := httptest.NewRecorder()
+
+		handler.TestAutomation(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_id", response.Error)
+	})
+
+	t.Run("returns 401 when no user in context", func(t *testing.T) {
+		handler := NewAutomationHandler(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/automations/123/test", bytes.NewBufferString(`{}`))
+		req = withURLParam(req, "id", uuid.New().String())
+		w := httptest.NewRecorder()
+
+		handler.TestAutomation(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("returns 400 for invalid JSON", func(t *testing.T) {
+		handler := NewAutomationHandler(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/automations/123/test", bytes.NewBufferString(`{invalid}`))
+		req = withURLParam(req, "id", uuid.New().String())
+		req = req.WithContext(SetUserInContext(req.Context(), &models.User{ID: uuid.New()}))
+		w := httptest.NewRecorder()
+
+		handler.TestAutomation(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_request", response.Error)
+	})
+}
+
+func TestAutomationHandler_ReceiveWebhook(t *testing.T) {
+	t.Run("returns 400 for invalid JSON", func(t *testing.T) {
+		handler := NewAutomationHandler(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/public/automation-webhooks/token", bytes.NewBufferString(`{not json`))
+		req = withURLParam(req, "token", "token")
+		w := httptest.NewRecorder()
+
+		handler.ReceiveWebhook(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_request", response.Error)
+	})
+
+	t.Run("returns 413 for oversized body", func(t *testing.T) {
+		handler := NewAutomationHandler(nil)
+
+		body := bytes.Repeat([]byte("a"), maxInboundWebhookBytes+1)
+		req := httptest.NewRequest(http.MethodPost, "/public/automation-webhooks/token", bytes.NewReader(body))
+		req = withURLParam(req, "token", "token")
+		w := httptest.NewRecorder()
+
+		handler.ReceiveWebhook(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
+
 func TestHandleAutomationStoreError(t *testing.T) {
 	t.Run("handles not found error", func(t *testing.T) {
 		w := httptest.NewRecorder()
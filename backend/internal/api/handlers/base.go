@@ -15,12 +15,18 @@ import (
 
 type BaseHandler struct {
 	store *store.BaseStore
+	jobs  *store.JobStore
 }
 
 func NewBaseHandler(store *store.BaseStore) *BaseHandler {
 	return &BaseHandler{store: store}
 }
 
+// SetJobStore makes duplicating a base run as a background job
+func (h *BaseHandler) SetJobStore(jobs *store.JobStore) {
+	h.jobs = jobs
+}
+
 // Request types
 type CreateBaseRequest struct {
 	Name string `json:"name"`
@@ -228,7 +234,18 @@ func (h *BaseHandler) DuplicateBase(w http.ResponseWriter, r *http.Request) {
 		req.IncludeRecords = false
 	}
 
-	base, err := h.store.DuplicateBase(r.Context(), baseID, user.ID, req.IncludeRecords)
+	if h.jobs != nil {
+		payload := models.DuplicateBasePayload{IncludeRecords: req.IncludeRecords}
+		job, err := h.jobs.CreateJob(r.Context(), baseID, models.JobDuplicateBase, payload, user.ID)
+		if err != nil {
+			writeJobError(w, err, "Base not found", "You don't have permission to duplicate this base")
+			return
+		}
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+
+	base, err := h.store.DuplicateBase(r.Context(), baseID, user.ID, req.IncludeRecords, nil)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "not_found", "Base not found")
@@ -238,6 +255,10 @@ func (h *BaseHandler) DuplicateBase(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to duplicate this base")
 			return
 		}
+		if errors.Is(err, store.ErrStorageQuotaExceeded) {
+			writeError(w, http.StatusRequestEntityTooLarge, "storage_quota_exceeded", capitalize(err.Error()))
+			return
+		}
 		log.Printf("Error duplicating base: %v", err)
 		writeError(w, http.StatusInternalServerError, "server_error", "Failed to duplicate base")
 		return
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
@@ -10,6 +11,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -21,6 +23,7 @@ type CSVHandler struct {
 	recordStore *store.RecordStore
 	fieldStore  *store.FieldStore
 	tableStore  *store.TableStore
+	jobs        *store.JobStore
 }
 
 func NewCSVHandler(recordStore *store.RecordStore, fieldStore *store.FieldStore, tableStore *store.TableStore) *CSVHandler {
@@ -31,6 +34,11 @@ func NewCSVHandler(recordStore *store.RecordStore, fieldStore *store.FieldStore,
 	}
 }
 
+// SetJobStore makes imports create their records in a background job
+func (h *CSVHandler) SetJobStore(jobs *store.JobStore) {
+	h.jobs = jobs
+}
+
 // CSVPreviewResponse contains preview data from an uploaded CSV
 type CSVPreviewResponse struct {
 	Columns []string            `json:"columns"`
@@ -236,18 +244,20 @@ func (h *CSVHandler) Import(w http.ResponseWriter, r *http.Request) {
 		recordValues = append(recordValues, jsonValues)
 	}
 
-	// Bulk create records
-	if len(recordValues) > 0 {
-		_, err = h.recordStore.BulkCreateRecords(r.Context(), tableID, recordValues, user.ID)
-		if err != nil {
-			if errors.Is(err, store.ErrForbidden) {
-				writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to create records")
-				return
-			}
-			log.Printf("Error bulk creating records: %v", err)
-			writeError(w, http.StatusInternalServerError, "server_error", "Failed to create records")
-			return
-		}
+	payload := models.ImportRecordsPayload{
+		TableID: tableID,
+		Records: recordValues,
+		Skipped: skipped,
+		Errors:  errCount,
+	}
+	job, err := importRecords(r.Context(), h.jobs, h.recordStore, payload, user.ID)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+	if job != nil {
+		writeJSON(w, http.StatusAccepted, job)
+		return
 	}
 
 	writeJSON(w, http.StatusOK, CSVImportResponse{
@@ -257,6 +267,35 @@ func (h *CSVHandler) Import(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// importRecords creates the records converted from an imported file. Large
+// imports outlast proxy timeouts, so when jobs are configured the records are
+// created in one and the job is returned; otherwise the job is nil.
+func importRecords(ctx context.Context, jobs *store.JobStore, records *store.RecordStore, payload models.ImportRecordsPayload, userID uuid.UUID) (*models.Job, error) {
+	if jobs != nil {
+		return jobs.CreateTableJob(ctx, payload.TableID, models.JobImportRecords, payload, userID)
+	}
+	if len(payload.Records) > 0 {
+		if _, err := records.BulkCreateRecords(ctx, payload.TableID, payload.Records, userID, nil); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// writeImportError writes the error for records that could not be imported
+func writeImportError(w http.ResponseWriter, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "Table not found")
+		return
+	}
+	if errors.Is(err, store.ErrForbidden) {
+		writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to create records")
+		return
+	}
+	log.Printf("Error importing records: %v", err)
+	writeError(w, http.StatusInternalServerError, "server_error", "Failed to import records")
+}
+
 // Export handles GET /tables/:tableId/export
 // Returns table data as CSV
 func (h *CSVHandler) Export(w http.ResponseWriter, r *http.Request) {
@@ -392,6 +431,11 @@ func (h *CSVHandler) parseCSVPreview(content string, maxRows int) (*CSVPreviewRe
 
 // convertCellValue converts a string cell value to the appropriate type based on field type
 func (h *CSVHandler) convertCellValue(value string, field models.Field) interface{} {
+	return convertTextValue(value, field)
+}
+
+// convertTextValue converts imported text to a value of the field's type
+func convertTextValue(value string, field models.Field) interface{} {
 	switch field.FieldType {
 	case models.FieldTypeNumber:
 		// Try to parse as float
@@ -406,15 +450,20 @@ func (h *CSVHandler) convertCellValue(value string, field models.Field) interfac
 	case models.FieldTypeDate:
 		// Return date string as-is (frontend will handle parsing)
 		return value
+	case models.FieldTypeSingleSelect:
+		return selectOptionIDs(field, []string{value})[0]
+	case models.FieldTypeMultiSelect:
+		// Options are separated by commas, as they are exported
+		return selectOptionIDs(field, splitList(value))
 	case models.FieldTypeLinkedRecord:
 		// For linked records, try to parse as JSON array
 		var ids []string
 		if err := json.Unmarshal([]byte(value), &ids); err == nil {
 			return ids
 		}
-		// If not JSON, treat as single ID
+		// If not JSON, treat as a comma separated list of IDs
 		if value != "" {
-			return []string{value}
+			return splitList(value)
 		}
 		return []string{}
 	default:
@@ -422,6 +471,37 @@ func (h *CSVHandler) convertCellValue(value string, field models.Field) interfac
 	}
 }
 
+// splitList splits comma separated text, leaving out empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// selectOptionIDs returns the IDs of select options given by name or ID. Names
+// match ignoring case; values matching no option are kept as they are.
+func selectOptionIDs(field models.Field, values []string) []string {
+	var options models.FieldOptions
+	if len(field.Options) > 0 {
+		json.Unmarshal(field.Options, &options)
+	}
+	ids := make([]string, len(values))
+	for i, value := range values {
+		ids[i] = value
+		for _, option := range options.Options {
+			if option.ID == value || strings.EqualFold(option.Name, strings.TrimSpace(value)) {
+				ids[i] = option.ID
+				break
+			}
+		}
+	}
+	return ids
+}
+
 // formatCellValue formats a cell value for CSV export
 func (h *CSVHandler) formatCellValue(value interface{}, field models.Field) string {
 	if value == nil {
@@ -250,6 +250,17 @@ func TestCSVHandler_convertCellValue(t *testing.T) {
 		result = handler.convertCellValue("", field)
 		assert.Equal(t, []string{}, result)
 	})
+
+	t.Run("converts select fields to option IDs", func(t *testing.T) {
+		options := json.RawMessage(`{"options":[{"id":"opt-1","name":"Done"},{"id":"opt-2","name":"Blocked"}]}`)
+		single := models.Field{FieldType: models.FieldTypeSingleSelect, Options: options}
+		multi := models.Field{FieldType: models.FieldTypeMultiSelect, Options: options}
+
+		assert.Equal(t, "opt-1", handler.convertCellValue("done", single))
+		assert.Equal(t, "opt-2", handler.convertCellValue("opt-2", single))
+		assert.Equal(t, "Unknown", handler.convertCellValue("Unknown", single))
+		assert.Equal(t, []string{"opt-1", "opt-2", "Other"}, handler.convertCellValue("Done, blocked,,Other", multi))
+	})
 }
 
 func TestCSVHandler_formatCellValue(t *testing.T) {
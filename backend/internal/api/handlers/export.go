@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/api/middleware"
+	"github.com/vibetable/backend/internal/storage"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// ExportLinkExpiry is how long a signed export link stays valid
+const ExportLinkExpiry = 5 * time.Minute
+
+// ExportLinkHandler issues signed links for the export endpoints, so a browser can
+// open a download in a new tab without putting the session token in the URL
+type ExportLinkHandler struct {
+	baseStore  *store.BaseStore
+	tableStore *store.TableStore
+	signer     *storage.URLSigner
+	baseURL    string
+}
+
+func NewExportLinkHandler(baseStore *store.BaseStore, tableStore *store.TableStore, signer *storage.URLSigner, baseURL string) *ExportLinkHandler {
+	return &ExportLinkHandler{
+		baseStore:  baseStore,
+		tableStore: tableStore,
+		signer:     signer,
+		baseURL:    baseURL,
+	}
+}
+
+// ExportLinkRequest selects the export format to link to
+type ExportLinkRequest struct {
+	Format string `json:"format"`
+}
+
+// ExportLinkResponse contains a signed export link
+type ExportLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// tableExportPaths maps each table export format to its endpoint
+var tableExportPaths = map[string]string{
+	"csv":    "/csv/export",
+	"xlsx":   "/xlsx/export",
+	"ndjson": "/ndjson/export",
+}
+
+// baseExportPaths maps each base export format to its endpoint
+var baseExportPaths = map[string]string{
+	"archive": "/export",
+	"xlsx":    "/xlsx/export",
+}
+
+// CreateTableLink handles POST /tables/:tableId/export-link
+func (h *ExportLinkHandler) CreateTableLink(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	tableID, err := uuid.Parse(chi.URLParam(r, "tableId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid table ID")
+		return
+	}
+
+	var req ExportLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	path, ok := tableExportPaths[req.Format]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_format", "Format must be csv, xlsx or ndjson")
+		return
+	}
+
+	if _, err := h.tableStore.GetTable(r.Context(), tableID, user.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Table not found")
+			return
+		}
+		log.Printf("Error getting table: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get table")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.sign("/api/v1/tables/"+tableID.String()+path, user.ID))
+}
+
+// CreateBaseLink handles POST /bases/:id/export-link
+func (h *ExportLinkHandler) CreateBaseLink(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	baseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid base ID")
+		return
+	}
+
+	var req ExportLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	path, ok := baseExportPaths[req.Format]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_format", "Format must be archive or xlsx")
+		return
+	}
+
+	if _, err := h.baseStore.GetBase(r.Context(), baseID, user.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Base not found")
+			return
+		}
+		log.Printf("Error getting base: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get base")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.sign("/api/v1/bases/"+baseID.String()+path, user.ID))
+}
+
+// sign links to path for userID. The export itself still runs with the user's
+// current access, so a link stops working if they are removed from the base.
+func (h *ExportLinkHandler) sign(path string, userID uuid.UUID) ExportLinkResponse {
+	query := h.signer.Sign(middleware.DownloadLinkResource(path, userID), ExportLinkExpiry)
+	return ExportLinkResponse{
+		URL:       h.baseURL + path + "?user=" + userID.String() + "&" + query,
+		ExpiresAt: time.Now().Add(ExportLinkExpiry).UTC(),
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/api/middleware"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/storage"
+	"github.com/vibetable/backend/internal/store"
+)
+
+func TestExportLinkHandler_CreateTableLink(t *testing.T) {
+	signer := storage.NewURLSigner([]byte("test-key"))
+
+	t.Run("returns 401 when no user in context", func(t *testing.T) {
+		handler := NewExportLinkHandler(nil, nil, signer, "http://localhost:8080")
+
+		req := httptest.NewRequest(http.MethodPost, "/tables/123/export-link", bytes.NewBufferString(`{"format":"csv"}`))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("tableId", uuid.New().String())
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+
+		handler.CreateTableLink(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("returns 400 for an unknown format", func(t *testing.T) {
+		handler := NewExportLinkHandler(nil, nil, signer, "http://localhost:8080")
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		req := httptest.NewRequest(http.MethodPost, "/tables/123/export-link", bytes.NewBufferString(`{"format":"pdf"}`))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("tableId", uuid.New().String())
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.CreateTableLink(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "invalid_format", response.Error)
+	})
+}
+
+func TestExportLinkHandler_sign(t *testing.T) {
+	signer := storage.NewURLSigner([]byte("test-key"))
+	handler := NewExportLinkHandler(nil, nil, signer, "http://localhost:8080")
+	userID := uuid.New()
+	path := "/api/v1/tables/" + uuid.New().String() + "/csv/export"
+
+	serve := func(t *testing.T, target string, expectUser bool) *httptest.ResponseRecorder {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		auth := middleware.NewAuthMiddleware(store.NewAuthStore(mock))
+		auth.SetURLSigner(signer)
+		if expectUser {
+			now := time.Now()
+			mock.ExpectQuery("SELECT (.+) FROM users WHERE id").
+				WithArgs(userID).
+				WillReturnRows(pgxmock.NewRows([]string{"id", "email", "name", "password_hash", "created_at", "updated_at"}).
+					AddRow(userID, "test@example.com", nil, nil, now, now))
+		}
+
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUserFromContext(r.Context())
+			require.NotNil(t, user)
+			assert.Equal(t, userID, user.ID)
+		})
+		w := httptest.NewRecorder()
+		auth.Required(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.NoError(t, mock.ExpectationsWereMet())
+		return w
+	}
+
+	link := handler.sign(path, userID)
+	require.True(t, strings.HasPrefix(link.URL, "http://localhost:8080"+path+"?"))
+	target := strings.TrimPrefix(link.URL, "http://localhost:8080")
+
+	t.Run("authenticates the link's user", func(t *testing.T) {
+		w := serve(t, target, true)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("rejects the link for another path", func(t *testing.T) {
+		other := strings.Replace(target, "/csv/", "/ndjson/", 1)
+		w := serve(t, other, false)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_signature")
+	})
+
+	t.Run("rejects the link for another user", func(t *testing.T) {
+		other := strings.Replace(target, userID.String(), uuid.New().String(), 1)
+		w := serve(t, other, false)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
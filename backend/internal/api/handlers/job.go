@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// jobListLimit is how many of a base's recent jobs are listed
+const jobListLimit = 50
+
+type JobHandler struct {
+	store *store.JobStore
+}
+
+func NewJobHandler(store *store.JobStore) *JobHandler {
+	return &JobHandler{store: store}
+}
+
+// writeJobError writes the error for a job that could not be queued
+func writeJobError(w http.ResponseWriter, err error, notFound, forbidden string) {
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", notFound)
+		return
+	}
+	if errors.Is(err, store.ErrForbidden) {
+		writeError(w, http.StatusForbidden, "forbidden", forbidden)
+		return
+	}
+	log.Printf("Error queuing job: %v", err)
+	writeError(w, http.StatusInternalServerError, "server_error", "Failed to start job")
+}
+
+// ListJobs handles GET /bases/:id/jobs
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	baseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid base ID")
+		return
+	}
+
+	jobs, err := h.store.ListJobsForBase(r.Context(), baseID, user.ID, jobListLimit)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Base not found")
+			return
+		}
+		log.Printf("Error listing jobs: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to list jobs")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"jobs": jobs,
+	})
+}
+
+// GetJob handles GET /jobs/:id
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	jobID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid job ID")
+		return
+	}
+
+	job, err := h.store.GetJob(r.Context(), jobID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Job not found")
+			return
+		}
+		log.Printf("Error getting job: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get job")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// CancelJob handles POST /jobs/:id/cancel
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	jobID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid job ID")
+		return
+	}
+
+	job, err := h.store.CancelJob(r.Context(), jobID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Job not found")
+			return
+		}
+		if errors.Is(err, store.ErrForbidden) {
+			writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to cancel this job")
+			return
+		}
+		if errors.Is(err, store.ErrJobFinished) {
+			writeError(w, http.StatusConflict, "job_finished", "Job has already finished")
+			return
+		}
+		log.Printf("Error cancelling job: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to cancel job")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/vibetable/backend/internal/models"
+)
+
+func TestJobHandler(t *testing.T) {
+	handlers := map[string]func(*JobHandler) http.HandlerFunc{
+		"ListJobs":  func(h *JobHandler) http.HandlerFunc { return h.ListJobs },
+		"GetJob":    func(h *JobHandler) http.HandlerFunc { return h.GetJob },
+		"CancelJob": func(h *JobHandler) http.HandlerFunc { return h.CancelJob },
+	}
+	for name, handlerFunc := range handlers {
+		t.Run(name+" returns 401 when no user in context", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/jobs/123", nil)
+			w := httptest.NewRecorder()
+
+			handlerFunc(NewJobHandler(nil))(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+
+		t.Run(name+" returns 400 for invalid ID", func(t *testing.T) {
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			req := httptest.NewRequest(http.MethodGet, "/jobs/not-a-uuid", nil)
+			req = withURLParam(req, "id", "not-a-uuid")
+			req = req.WithContext(SetUserInContext(req.Context(), user))
+			w := httptest.NewRecorder()
+
+			handlerFunc(NewJobHandler(nil))(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// maxNDJSONLine caps a single record's line
+const maxNDJSONLine = 16 << 20
+
+type NDJSONHandler struct {
+	recordStore *store.RecordStore
+	fieldStore  *store.FieldStore
+	tableStore  *store.TableStore
+	jobs        *store.JobStore
+}
+
+func NewNDJSONHandler(recordStore *store.RecordStore, fieldStore *store.FieldStore, tableStore *store.TableStore) *NDJSONHandler {
+	return &NDJSONHandler{
+		recordStore: recordStore,
+		fieldStore:  fieldStore,
+		tableStore:  tableStore,
+	}
+}
+
+// SetJobStore makes imports create their records in a background job
+func (h *NDJSONHandler) SetJobStore(jobs *store.JobStore) {
+	h.jobs = jobs
+}
+
+// NDJSONRecord is a line of an export: a record's stored values by field ID,
+// exactly as they are kept. Imports also accept field names as keys.
+type NDJSONRecord struct {
+	ID     *uuid.UUID                 `json:"id,omitempty"`
+	Values map[string]json.RawMessage `json:"values"`
+}
+
+// Export handles GET /tables/:tableId/ndjson/export, downloading the table's
+// records as newline-delimited JSON
+func (h *NDJSONHandler) Export(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	tableID, err := uuid.Parse(chi.URLParam(r, "tableId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid table ID")
+		return
+	}
+
+	table, err := h.tableStore.GetTable(r.Context(), tableID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Table not found")
+			return
+		}
+		log.Printf("Error getting table: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get table")
+		return
+	}
+
+	fields, err := h.fieldStore.ListFieldsForTable(r.Context(), tableID, user.ID)
+	if err != nil {
+		log.Printf("Error listing fields: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get fields")
+		return
+	}
+
+	records, err := h.recordStore.ListRecordsForTable(r.Context(), tableID, user.ID)
+	if err != nil {
+		log.Printf("Error listing records: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get records")
+		return
+	}
+
+	// Computed values are worked out again wherever the records are imported
+	stored := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if !models.IsComputedField(f.FieldType) {
+			stored[f.ID.String()] = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": table.Name + ".ndjson"}))
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i := range records {
+		var values map[string]json.RawMessage
+		json.Unmarshal(records[i].Values, &values)
+		line := NDJSONRecord{ID: &records[i].ID, Values: make(map[string]json.RawMessage, len(values))}
+		for id, value := range values {
+			if stored[id] {
+				line.Values[id] = value
+			}
+		}
+		if err := enc.Encode(line); err != nil {
+			log.Printf("Error writing records of table %s: %v", tableID, err)
+			return
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		// The response has started, so the client sees a truncated file
+		log.Printf("Error writing records of table %s: %v", tableID, err)
+	}
+}
+
+// Import handles POST /tables/:tableId/ndjson/import with newline-delimited
+// NDJSONRecord lines as the body. Lines that are not valid JSON or hold values
+// of the wrong type for their field are counted as errors; keys naming no
+// field, and computed fields, are ignored.
+func (h *NDJSONHandler) Import(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	tableID, err := uuid.Parse(chi.URLParam(r, "tableId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid table ID")
+		return
+	}
+
+	fields, err := h.fieldStore.ListFieldsForTable(r.Context(), tableID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Table not found")
+			return
+		}
+		log.Printf("Error listing fields: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get table fields")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSpreadsheetSize)
+	payload, err := ndjsonRecords(r.Body, tableID, fields)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "file_too_large", "File exceeds the limit of "+strconv.Itoa(maxSpreadsheetSize)+" bytes")
+			return
+		}
+		if errors.Is(err, bufio.ErrTooLong) {
+			writeError(w, http.StatusRequestEntityTooLarge, "line_too_long", "A record exceeds the limit of "+strconv.Itoa(maxNDJSONLine)+" bytes")
+			return
+		}
+		log.Printf("Error reading records: %v", err)
+		writeError(w, http.StatusBadRequest, "invalid_request", "Failed to read records")
+		return
+	}
+	if len(payload.Records) == 0 && payload.Skipped == 0 && payload.Errors == 0 {
+		writeError(w, http.StatusBadRequest, "no_data", "At least one record is required")
+		return
+	}
+
+	job, err := importRecords(r.Context(), h.jobs, h.recordStore, payload, user.ID)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+	if job != nil {
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.ImportRecordsResult{
+		Imported: len(payload.Records),
+		Skipped:  payload.Skipped,
+		Errors:   payload.Errors,
+	})
+}
+
+// ndjsonRecords reads NDJSONRecord lines into the values of records for a table
+// with fields, keyed by field ID
+func ndjsonRecords(body io.Reader, tableID uuid.UUID, fields []models.Field) (models.ImportRecordsPayload, error) {
+	payload := models.ImportRecordsPayload{TableID: tableID}
+
+	byKey := make(map[string]models.Field, len(fields)*2)
+	for _, f := range fields {
+		if _, taken := byKey[f.Name]; !taken {
+			byKey[f.Name] = f
+		}
+	}
+	// IDs take precedence over names
+	for _, f := range fields {
+		byKey[f.ID.String()] = f
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), maxNDJSONLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record NDJSONRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			payload.Errors++
+			continue
+		}
+
+		values := make(map[string]json.RawMessage, len(record.Values))
+		valid := true
+		for key, value := range record.Values {
+			field, ok := byKey[key]
+			if !ok || models.IsComputedField(field.FieldType) || field.FieldType == models.FieldTypeAttachment {
+				continue
+			}
+			if bytes.Equal(value, []byte("null")) {
+				continue
+			}
+			if !validFieldValue(field.FieldType, value) {
+				valid = false
+				break
+			}
+			values[field.ID.String()] = value
+		}
+
+		if !valid {
+			payload.Errors++
+			continue
+		}
+		if len(values) == 0 {
+			payload.Skipped++
+			continue
+		}
+		b, err := json.Marshal(values)
+		if err != nil {
+			payload.Errors++
+			continue
+		}
+		payload.Records = append(payload.Records, b)
+	}
+	return payload, scanner.Err()
+}
+
+// validFieldValue reports whether a JSON value has the type that fields of
+// fieldType store
+func validFieldValue(fieldType models.FieldType, value json.RawMessage) bool {
+	switch fieldType {
+	case models.FieldTypeNumber:
+		var n float64
+		return json.Unmarshal(value, &n) == nil
+	case models.FieldTypeCheckbox:
+		var b bool
+		return json.Unmarshal(value, &b) == nil
+	case models.FieldTypeMultiSelect, models.FieldTypeLinkedRecord:
+		var items []string
+		return json.Unmarshal(value, &items) == nil
+	default:
+		var s string
+		return json.Unmarshal(value, &s) == nil
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+)
+
+func TestNDJSONHandler(t *testing.T) {
+	handlers := map[string]func(*NDJSONHandler) http.HandlerFunc{
+		"Import": func(h *NDJSONHandler) http.HandlerFunc { return h.Import },
+		"Export": func(h *NDJSONHandler) http.HandlerFunc { return h.Export },
+	}
+	for name, handlerFunc := range handlers {
+		t.Run(name+" returns 401 when no user in context", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tables/123/ndjson", nil)
+			w := httptest.NewRecorder()
+
+			handlerFunc(NewNDJSONHandler(nil, nil, nil))(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+
+		t.Run(name+" returns 400 for invalid table ID", func(t *testing.T) {
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			req := httptest.NewRequest(http.MethodPost, "/tables/not-a-uuid/ndjson", nil)
+			req = withURLParam(req, "tableId", "not-a-uuid")
+			req = req.WithContext(SetUserInContext(req.Context(), user))
+			w := httptest.NewRecorder()
+
+			handlerFunc(NewNDJSONHandler(nil, nil, nil))(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestNDJSONRecords(t *testing.T) {
+	name := models.Field{ID: uuid.New(), Name: "Name", FieldType: models.FieldTypeText}
+	price := models.Field{ID: uuid.New(), Name: "Price", FieldType: models.FieldTypeNumber}
+	tags := models.Field{ID: uuid.New(), Name: "Tags", FieldType: models.FieldTypeMultiSelect}
+	total := models.Field{ID: uuid.New(), Name: "Total", FieldType: models.FieldTypeFormula}
+	fields := []models.Field{name, price, tags, total}
+	tableID := uuid.New()
+
+	body := strings.Join([]string{
+		`{"id":"` + uuid.NewString() + `","values":{"` + name.ID.String() + `":"Ada","` + price.ID.String() + `":1.10,"` + tags.ID.String() + `":["opt-1"]}}`,
+		``,
+		`{"values":{"Name":"by name","Total":5,"Unknown":true}}`,
+		`{"values":{"Price":"12"}}`,
+		`{"values":{"Total":5,"Name":null}}`,
+		`not json`,
+	}, "\n")
+
+	payload, err := ndjsonRecords(strings.NewReader(body), tableID, fields)
+	require.NoError(t, err)
+	assert.Equal(t, tableID, payload.TableID)
+	assert.Equal(t, 2, payload.Errors)
+	assert.Equal(t, 1, payload.Skipped)
+	require.Len(t, payload.Records, 2)
+
+	var first map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(payload.Records[0], &first))
+	assert.Equal(t, "1.10", string(first[price.ID.String()]), "numbers keep their exact text")
+	assert.JSONEq(t, `["opt-1"]`, string(first[tags.ID.String()]))
+	assert.JSONEq(t, `{"`+name.ID.String()+`":"by name"}`, string(payload.Records[1]))
+}
+
+func TestValidFieldValue(t *testing.T) {
+	tests := []struct {
+		fieldType models.FieldType
+		value     string
+		want      bool
+	}{
+		{models.FieldTypeNumber, `3.5`, true},
+		{models.FieldTypeNumber, `"3.5"`, false},
+		{models.FieldTypeCheckbox, `true`, true},
+		{models.FieldTypeCheckbox, `"yes"`, false},
+		{models.FieldTypeMultiSelect, `["a","b"]`, true},
+		{models.FieldTypeLinkedRecord, `"id"`, false},
+		{models.FieldTypeDate, `"2024-01-02"`, true},
+		{models.FieldTypeText, `{"a":1}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.fieldType)+" "+tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, validFieldValue(tt.fieldType, json.RawMessage(tt.value)))
+		})
+	}
+}
@@ -167,7 +167,7 @@ func (h *RecordHandler) BulkCreateRecords(w http.ResponseWriter, r *http.Request
 		recordValues = append(recordValues, jsonVals)
 	}
 
-	records, err := h.store.BulkCreateRecords(r.Context(), tableID, recordValues, user.ID)
+	records, err := h.store.BulkCreateRecords(r.Context(), tableID, recordValues, user.ID, nil)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "not_found", "Table not found")
@@ -252,6 +252,10 @@ func (h *RecordHandler) UpdateRecord(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to edit this record")
 			return
 		}
+		if errors.Is(err, store.ErrCellLocked) {
+			writeError(w, http.StatusConflict, "cell_locked", "Another user is editing this cell")
+			return
+		}
 		log.Printf("Error updating record: %v", err)
 		writeError(w, http.StatusInternalServerError, "server_error", "Failed to update record")
 		return
@@ -290,6 +294,10 @@ func (h *RecordHandler) PatchRecord(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to edit this record")
 			return
 		}
+		if errors.Is(err, store.ErrCellLocked) {
+			writeError(w, http.StatusConflict, "cell_locked", "Another user is editing this cell")
+			return
+		}
 		log.Printf("Error patching record: %v", err)
 		writeError(w, http.StatusInternalServerError, "server_error", "Failed to update record")
 		return
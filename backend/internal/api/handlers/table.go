@@ -9,17 +9,24 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
 	"github.com/vibetable/backend/internal/store"
 )
 
 type TableHandler struct {
 	store *store.TableStore
+	jobs  *store.JobStore
 }
 
 func NewTableHandler(store *store.TableStore) *TableHandler {
 	return &TableHandler{store: store}
 }
 
+// SetJobStore makes duplicating a table run as a background job
+func (h *TableHandler) SetJobStore(jobs *store.JobStore) {
+	h.jobs = jobs
+}
+
 // Request types
 type CreateTableRequest struct {
 	Name string `json:"name"`
@@ -291,7 +298,18 @@ func (h *TableHandler) DuplicateTable(w http.ResponseWriter, r *http.Request) {
 		req.IncludeRecords = false
 	}
 
-	table, err := h.store.DuplicateTable(r.Context(), tableID, user.ID, req.IncludeRecords)
+	if h.jobs != nil {
+		payload := models.DuplicateTablePayload{TableID: tableID, IncludeRecords: req.IncludeRecords}
+		job, err := h.jobs.CreateTableJob(r.Context(), tableID, models.JobDuplicateTable, payload, user.ID)
+		if err != nil {
+			writeJobError(w, err, "Table not found", "You don't have permission to duplicate this table")
+			return
+		}
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+
+	table, err := h.store.DuplicateTable(r.Context(), tableID, user.ID, req.IncludeRecords, nil)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "not_found", "Table not found")
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/store"
+)
+
+type TemplateHandler struct {
+	store *store.TemplateStore
+}
+
+func NewTemplateHandler(store *store.TemplateStore) *TemplateHandler {
+	return &TemplateHandler{store: store}
+}
+
+type CreateBaseFromTemplateRequest struct {
+	Name string `json:"name"`
+}
+
+// ListTemplates handles GET /templates
+func (h *TemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	templates, err := h.store.ListTemplates(r.Context())
+	if err != nil {
+		log.Printf("Error listing templates: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to list templates")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"templates": templates,
+	})
+}
+
+// CreateTemplate handles POST /templates, publishing a base as a template
+func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	var req models.CreateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Name is required")
+		return
+	}
+	if req.BaseID == uuid.Nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Base ID is required")
+		return
+	}
+
+	template, err := h.store.CreateTemplate(r.Context(), user.ID, &req)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Base not found")
+			return
+		}
+		if errors.Is(err, store.ErrForbidden) {
+			writeError(w, http.StatusForbidden, "forbidden", "Only owners can publish a base as a template")
+			return
+		}
+		log.Printf("Error creating template: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to create template")
+		return
+	}
+
+	log.Printf("Template published: %s (id=%s) from base %s by user %s", template.Name, template.ID, req.BaseID, user.Email)
+	writeJSON(w, http.StatusCreated, template)
+}
+
+// GetTemplate handles GET /templates/:id
+func (h *TemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	templateID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid template ID")
+		return
+	}
+
+	template, err := h.store.GetTemplate(r.Context(), templateID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Template not found")
+			return
+		}
+		log.Printf("Error getting template: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get template")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, template)
+}
+
+// DeleteTemplate handles DELETE /templates/:id
+func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	templateID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid template ID")
+		return
+	}
+
+	if err := h.store.DeleteTemplate(r.Context(), templateID, user.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Template not found")
+			return
+		}
+		if errors.Is(err, store.ErrForbidden) {
+			writeError(w, http.StatusForbidden, "forbidden", "Only the user who published a template can delete it")
+			return
+		}
+		log.Printf("Error deleting template: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to delete template")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"message": "Template deleted successfully",
+	})
+}
+
+// CreateBaseFromTemplate handles POST /templates/:id/bases
+func (h *TemplateHandler) CreateBaseFromTemplate(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	templateID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid template ID")
+		return
+	}
+
+	var req CreateBaseFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		// The body is optional; without a name the base is named after the template
+		req.Name = ""
+	}
+
+	base, err := h.store.CreateBaseFromTemplate(r.Context(), templateID, strings.TrimSpace(req.Name), user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Template not found")
+			return
+		}
+		log.Printf("Error creating base from template: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to create base from template")
+		return
+	}
+
+	log.Printf("Base created from template %s: %s (id=%s) by user %s", templateID, base.Name, base.ID, user.Email)
+	writeJSON(w, http.StatusCreated, base)
+}
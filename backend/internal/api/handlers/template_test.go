@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+)
+
+func TestTemplateHandler_ListTemplates(t *testing.T) {
+	t.Run("returns 401 when no user in context", func(t *testing.T) {
+		handler := NewTemplateHandler(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/templates", nil)
+		w := httptest.NewRecorder()
+
+		handler.ListTemplates(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestTemplateHandler_CreateTemplate(t *testing.T) {
+	t.Run("returns 401 when no user in context", func(t *testing.T) {
+		handler := NewTemplateHandler(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/templates", nil)
+		w := httptest.NewRecorder()
+
+		handler.CreateTemplate(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid JSON", "{"},
+		{"missing name", `{"base_id": "` + uuid.New().String() + `", "name": "  "}`},
+		{"missing base ID", `{"name": "CRM"}`},
+	}
+	for _, tt := range tests {
+		t.Run("returns 400 for "+tt.name, func(t *testing.T) {
+			handler := NewTemplateHandler(nil)
+
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			req := httptest.NewRequest(http.MethodPost, "/templates", bytes.NewBufferString(tt.body))
+			req = req.WithContext(SetUserInContext(req.Context(), user))
+			w := httptest.NewRecorder()
+
+			handler.CreateTemplate(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response ErrorResponse
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			require.NoError(t, err)
+			assert.Equal(t, "invalid_request", response.Error)
+		})
+	}
+}
+
+func TestTemplateHandler_ByID(t *testing.T) {
+	handlers := map[string]func(*TemplateHandler) http.HandlerFunc{
+		"GetTemplate":            func(h *TemplateHandler) http.HandlerFunc { return h.GetTemplate },
+		"DeleteTemplate":         func(h *TemplateHandler) http.HandlerFunc { return h.DeleteTemplate },
+		"CreateBaseFromTemplate": func(h *TemplateHandler) http.HandlerFunc { return h.CreateBaseFromTemplate },
+	}
+	for name, handlerFunc := range handlers {
+		t.Run(name+" returns 401 when no user in context", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/templates/123", nil)
+			w := httptest.NewRecorder()
+
+			handlerFunc(NewTemplateHandler(nil))(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+
+		t.Run(name+" returns 400 for invalid template ID", func(t *testing.T) {
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			req := httptest.NewRequest(http.MethodGet, "/templates/not-a-uuid", nil)
+			req = withURLParam(req, "id", "not-a-uuid")
+			req = req.WithContext(SetUserInContext(req.Context(), user))
+			w := httptest.NewRecorder()
+
+			handlerFunc(NewTemplateHandler(nil))(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
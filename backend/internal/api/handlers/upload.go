@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// CreateUpload handles POST /records/:recordId/fields/:fieldId/uploads
+func (h *AttachmentHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	recordID, err := uuid.Parse(chi.URLParam(r, "recordId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid record ID")
+		return
+	}
+
+	fieldID, err := uuid.Parse(chi.URLParam(r, "fieldId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid field ID")
+		return
+	}
+
+	var req models.CreateUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+		return
+	}
+	if req.Filename == "" || len(req.Filename) > 255 {
+		writeError(w, http.StatusBadRequest, "invalid_filename", "Filename is required and must be at most 255 characters")
+		return
+	}
+	if req.SizeBytes <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_size", "Size must be greater than zero")
+		return
+	}
+	if req.ContentType == "" {
+		req.ContentType = "application/octet-stream"
+	}
+
+	session, err := h.store.CreateUpload(r.Context(), recordID, fieldID, user.ID, &req)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Record or field not found")
+			return
+		}
+		if errors.Is(err, store.ErrForbidden) {
+			writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to add attachments")
+			return
+		}
+		if writeUploadRuleError(w, err) {
+			return
+		}
+		log.Printf("Error creating upload: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to create upload")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, session)
+}
+
+// GetUpload handles GET /uploads/:id
+func (h *AttachmentHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	uploadID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid upload ID")
+		return
+	}
+
+	session, err := h.store.GetUpload(r.Context(), uploadID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Upload not found")
+			return
+		}
+		log.Printf("Error getting upload: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get upload")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, session)
+}
+
+// WriteUploadChunk handles PUT /uploads/:id?offset=N, with the chunk as the body
+func (h *AttachmentHandler) WriteUploadChunk(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	uploadID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid upload ID")
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "invalid_offset", "Offset must be a non-negative integer")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, store.MaxUploadChunkSize)
+	session, err := h.store.WriteUploadChunk(r.Context(), uploadID, user.ID, offset, r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "chunk_too_large", "Chunks may be at most "+strconv.Itoa(store.MaxUploadChunkSize)+" bytes")
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Upload not found")
+			return
+		}
+		if errors.Is(err, store.ErrUploadOffsetMismatch) {
+			writeError(w, http.StatusConflict, "offset_mismatch", capitalize(err.Error()))
+			return
+		}
+		if errors.Is(err, store.ErrUploadCompleting) {
+			writeError(w, http.StatusConflict, "upload_completing", "Upload is already being completed")
+			return
+		}
+		if errors.Is(err, store.ErrFileTooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "file_too_large", capitalize(err.Error()))
+			return
+		}
+		log.Printf("Error writing upload chunk: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to write upload chunk")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, session)
+}
+
+// CompleteUpload handles POST /uploads/:id/complete
+func (h *AttachmentHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	uploadID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid upload ID")
+		return
+	}
+
+	attachment, err := h.store.CompleteUpload(r.Context(), uploadID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Upload not found")
+			return
+		}
+		if errors.Is(err, store.ErrForbidden) {
+			writeError(w, http.StatusForbidden, "forbidden", "You don't have permission to add attachments")
+			return
+		}
+		if errors.Is(err, store.ErrUploadIncomplete) {
+			writeError(w, http.StatusConflict, "upload_incomplete", capitalize(err.Error()))
+			return
+		}
+		if errors.Is(err, store.ErrUploadCompleting) {
+			writeError(w, http.StatusConflict, "upload_completing", "Upload is already being completed")
+			return
+		}
+		if writeUploadRuleError(w, err) {
+			return
+		}
+		log.Printf("Error completing upload: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to complete upload")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, attachment)
+}
+
+// CancelUpload handles DELETE /uploads/:id
+func (h *AttachmentHandler) CancelUpload(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	uploadID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid upload ID")
+		return
+	}
+
+	if err := h.store.CancelUpload(r.Context(), uploadID, user.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Upload not found")
+			return
+		}
+		if errors.Is(err, store.ErrUploadCompleting) {
+			writeError(w, http.StatusConflict, "upload_completing", "Upload is already being completed")
+			return
+		}
+		log.Printf("Error cancelling upload: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to cancel upload")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"message": "Upload cancelled",
+	})
+}
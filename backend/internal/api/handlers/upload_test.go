@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+)
+
+func TestAttachmentHandler_CreateUpload(t *testing.T) {
+	t.Run("returns 401 when no user in context", func(t *testing.T) {
+		handler := NewAttachmentHandler(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/records/123/fields/456/uploads", nil)
+		w := httptest.NewRecorder()
+
+		handler.CreateUpload(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"invalid JSON", "{", "invalid_request"},
+		{"missing filename", `{"size_bytes": 10}`, "invalid_filename"},
+		{"missing size", `{"filename": "video.mp4"}`, "invalid_size"},
+	}
+	for _, tt := range tests {
+		t.Run("returns 400 for "+tt.name, func(t *testing.T) {
+			handler := NewAttachmentHandler(nil)
+
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			req := httptest.NewRequest(http.MethodPost, "/records/123/fields/456/uploads", bytes.NewBufferString(tt.body))
+			req = withURLParam(req, "recordId", uuid.New().String())
+			req = withURLParam(req, "fieldId", uuid.New().String())
+			req = req.WithContext(SetUserInContext(req.Context(), user))
+			w := httptest.NewRecorder()
+
+			handler.CreateUpload(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response ErrorResponse
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, response.Error)
+		})
+	}
+}
+
+func TestAttachmentHandler_WriteUploadChunk(t *testing.T) {
+	t.Run("returns 401 when no user in context", func(t *testing.T) {
+		handler := NewAttachmentHandler(nil)
+
+		req := httptest.NewRequest(http.MethodPut, "/uploads/123?offset=0", nil)
+		w := httptest.NewRecorder()
+
+		handler.WriteUploadChunk(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	for _, offset := range []string{"", "-1", "abc"} {
+		t.Run("returns 400 for offset "+offset, func(t *testing.T) {
+			handler := NewAttachmentHandler(nil)
+
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			req := httptest.NewRequest(http.MethodPut, "/uploads/123?offset="+offset, bytes.NewBufferString("data"))
+			req = withURLParam(req, "id", uuid.New().String())
+			req = req.WithContext(SetUserInContext(req.Context(), user))
+			w := httptest.NewRecorder()
+
+			handler.WriteUploadChunk(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response ErrorResponse
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			require.NoError(t, err)
+			assert.Equal(t, "invalid_offset", response.Error)
+		})
+	}
+}
+
+func TestAttachmentHandler_UploadByID(t *testing.T) {
+	handlers := map[string]func(*AttachmentHandler) http.HandlerFunc{
+		"GetUpload":      func(h *AttachmentHandler) http.HandlerFunc { return h.GetUpload },
+		"CompleteUpload": func(h *AttachmentHandler) http.HandlerFunc { return h.CompleteUpload },
+		"CancelUpload":   func(h *AttachmentHandler) http.HandlerFunc { return h.CancelUpload },
+	}
+	for name, handlerFunc := range handlers {
+		t.Run(name+" returns 401 when no user in context", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/uploads/123", nil)
+			w := httptest.NewRecorder()
+
+			handlerFunc(NewAttachmentHandler(nil))(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+
+		t.Run(name+" returns 400 for invalid upload ID", func(t *testing.T) {
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			req := httptest.NewRequest(http.MethodGet, "/uploads/not-a-uuid", nil)
+			req = withURLParam(req, "id", "not-a-uuid")
+			req = req.WithContext(SetUserInContext(req.Context(), user))
+			w := httptest.NewRecorder()
+
+			handlerFunc(NewAttachmentHandler(nil))(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
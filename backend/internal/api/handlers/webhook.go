@@ -2,27 +2,54 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/automation"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/outbound"
 	"github.com/vibetable/backend/internal/store"
+	"github.com/vibetable/backend/internal/webhook"
+)
+
+// Secret rotation grace periods, in hours
+const (
+	defaultSecretGraceHours = 24
+	maxSecretGraceHours     = 7 * 24
 )
 
 type WebhookHandler struct {
 	webhookStore *store.WebhookStore
 	baseStore    *store.BaseStore
+	engine       *webhook.DeliveryEngine
+	outbound     outbound.Policy
 }
 
 func NewWebhookHandler(webhookStore *store.WebhookStore, baseStore *store.BaseStore) *WebhookHandler {
 	return &WebhookHandler{
 		webhookStore: webhookStore,
 		baseStore:    baseStore,
+		outbound:     outbound.DefaultPolicy(),
 	}
 }
 
+// SetDeliveryEngine sets the delivery engine used to queue redeliveries
+func (h *WebhookHandler) SetDeliveryEngine(engine *webhook.DeliveryEngine) {
+	h.engine = engine
+}
+
+// SetOutboundPolicy sets the policy webhook URLs are checked against
+func (h *WebhookHandler) SetOutboundPolicy(policy outbound.Policy) {
+	h.outbound = policy
+}
+
 // ListWebhooks handles GET /bases/{id}/webhooks
 func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
 	user := GetUserFromContext(r.Context())
@@ -54,6 +81,9 @@ func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
 	if webhooks == nil {
 		webhooks = []models.Webhook{}
 	}
+	for i := range webhooks {
+		webhooks[i].RedactSecrets()
+	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"webhooks": webhooks,
@@ -96,15 +126,37 @@ func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid_request", "URL is required")
 		return
 	}
+	if err := h.outbound.CheckURL(r.Context(), req.URL); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_url", err.Error())
+		return
+	}
+	if event, ok := findInvalidEvent(req.Events); !ok {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Unknown event: "+string(event))
+		return
+	}
+	if problem := validateWebhookFormat(req.Format, req.MessageTemplate); problem != "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", problem)
+		return
+	}
 
-	webhook, err := h.webhookStore.Create(r.Context(), baseID, user.ID, &req)
+	// Every webhook is signed; the secret is only shown in this response
+	if req.Secret == nil || *req.Secret == "" {
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Failed to generate secret")
+			return
+		}
+		req.Secret = &secret
+	}
+
+	created, err := h.webhookStore.Create(r.Context(), baseID, user.ID, &req)
 	if err != nil {
 		log.Printf("Error creating webhook: %v", err)
 		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create webhook")
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, webhook)
+	writeJSON(w, http.StatusCreated, created)
 }
 
 // GetWebhook handles GET /webhooks/{id}
@@ -135,6 +187,7 @@ func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	webhook.RedactSecrets()
 	writeJSON(w, http.StatusOK, webhook)
 }
 
@@ -171,6 +224,28 @@ func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
 		return
 	}
+	if req.URL != nil {
+		if err := h.outbound.CheckURL(r.Context(), *req.URL); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_url", err.Error())
+			return
+		}
+	}
+	if event, ok := findInvalidEvent(req.Events); !ok {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Unknown event: "+string(event))
+		return
+	}
+	var format models.WebhookFormat
+	if req.Format != nil {
+		format = *req.Format
+		if format == "" {
+			writeError(w, http.StatusBadRequest, "invalid_request", "Format cannot be empty")
+			return
+		}
+	}
+	if problem := validateWebhookFormat(format, req.MessageTemplate); problem != "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", problem)
+		return
+	}
 
 	updated, err := h.webhookStore.Update(r.Context(), id, &req)
 	if err != nil {
@@ -179,6 +254,7 @@ func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	updated.RedactSecrets()
 	writeJSON(w, http.StatusOK, updated)
 }
 
@@ -262,3 +338,200 @@ func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request)
 		"deliveries": deliveries,
 	})
 }
+
+// RedeliverDelivery handles POST /webhooks/{id}/deliveries/{deliveryId}/redeliver
+func (h *WebhookHandler) RedeliverDelivery(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid webhook ID")
+		return
+	}
+	deliveryID, err := uuid.Parse(chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid delivery ID")
+		return
+	}
+
+	if h.engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Webhook delivery is not available")
+		return
+	}
+
+	hook, err := h.webhookStore.GetByID(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Webhook not found")
+		return
+	}
+
+	// Check access (need at least editor)
+	role, err := h.baseStore.GetUserRole(r.Context(), hook.BaseID, user.ID)
+	if err != nil || (role != "owner" && role != "editor") {
+		writeError(w, http.StatusForbidden, "forbidden", "Access denied")
+		return
+	}
+
+	delivery, err := h.webhookStore.GetDelivery(r.Context(), deliveryID)
+	if err != nil || delivery.WebhookID != hook.ID {
+		writeError(w, http.StatusNotFound, "not_found", "Delivery not found")
+		return
+	}
+
+	if !hook.IsActive {
+		writeError(w, http.StatusConflict, "webhook_inactive", "Enable the webhook before redelivering")
+		return
+	}
+
+	item, err := h.engine.Redeliver(r.Context(), delivery)
+	if err != nil {
+		log.Printf("Error queueing redelivery: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to queue redelivery")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, item)
+}
+
+// TestWebhook handles POST /webhooks/{id}/test. It sends a sample event straight away
+// and returns the recorded delivery, whether or not the receiver accepted it.
+func (h *WebhookHandler) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid webhook ID")
+		return
+	}
+
+	var req models.TestWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+		return
+	}
+	if req.Event != "" && !models.IsValidWebhookEvent(req.Event) {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Unknown event: "+string(req.Event))
+		return
+	}
+
+	if h.engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Webhook delivery is not available")
+		return
+	}
+
+	hook, err := h.webhookStore.GetByID(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Webhook not found")
+		return
+	}
+
+	// Check access (need at least editor)
+	role, err := h.baseStore.GetUserRole(r.Context(), hook.BaseID, user.ID)
+	if err != nil || (role != "owner" && role != "editor") {
+		writeError(w, http.StatusForbidden, "forbidden", "Access denied")
+		return
+	}
+
+	event := req.Event
+	if event == "" {
+		event = models.WebhookEventRecordCreated
+		if len(hook.Events) > 0 {
+			event = hook.Events[0]
+		}
+	}
+
+	writeJSON(w, http.StatusOK, h.engine.SendTest(r.Context(), hook, event, user.ID))
+}
+
+// RotateSecret handles POST /webhooks/{id}/rotate-secret. The new secret is returned
+// once; the old one keeps signing deliveries until the grace period ends.
+func (h *WebhookHandler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid webhook ID")
+		return
+	}
+
+	var req models.RotateWebhookSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+		return
+	}
+	graceHours := defaultSecretGraceHours
+	if req.GracePeriodHours != nil {
+		graceHours = *req.GracePeriodHours
+		if graceHours < 0 || graceHours > maxSecretGraceHours {
+			writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Grace period must be between 0 and %d hours", maxSecretGraceHours))
+			return
+		}
+	}
+
+	hook, err := h.webhookStore.GetByID(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Webhook not found")
+		return
+	}
+
+	// Check access (need at least editor)
+	role, err := h.baseStore.GetUserRole(r.Context(), hook.BaseID, user.ID)
+	if err != nil || (role != "owner" && role != "editor") {
+		writeError(w, http.StatusForbidden, "forbidden", "Access denied")
+		return
+	}
+
+	var secret string
+	if req.Secret != nil && *req.Secret != "" {
+		secret = *req.Secret
+	} else if secret, err = webhook.GenerateSecret(); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to generate secret")
+		return
+	}
+
+	rotated, err := h.webhookStore.RotateSecret(r.Context(), id, secret, time.Duration(graceHours)*time.Hour)
+	if err != nil {
+		log.Printf("Error rotating webhook secret: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to rotate secret")
+		return
+	}
+
+	rotated.PreviousSecret = nil
+	writeJSON(w, http.StatusOK, rotated)
+}
+
+// validateWebhookFormat returns a problem with a payload format or message template,
+// or "" if both are fine. An empty format means the default.
+func validateWebhookFormat(format models.WebhookFormat, template *string) string {
+	if format != "" && !models.IsValidWebhookFormat(format) {
+		return "Unknown format: " + string(format)
+	}
+	if template != nil {
+		if errs := automation.ValidateTemplate("message_template", *template); len(errs) > 0 {
+			return strings.Join(errs, "; ")
+		}
+	}
+	return ""
+}
+
+// findInvalidEvent returns the first unknown event, and false if there is one
+func findInvalidEvent(events []models.WebhookEvent) (models.WebhookEvent, bool) {
+	for _, event := range events {
+		if !models.IsValidWebhookEvent(event) {
+			return event, false
+		}
+	}
+	return "", true
+}
@@ -204,3 +204,161 @@ func TestWebhookHandler_ListDeliveries(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 }
+
+func TestWebhookHandler_RedeliverDelivery(t *testing.T) {
+	t.Run("should return 401 when no user in context", func(t *testing.T) {
+		handler := NewWebhookHandler(nil, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/123/deliveries/456/redeliver", nil)
+		req = withURLParam(req, "id", uuid.New().String())
+		req = withURLParam(req, "deliveryId", uuid.New().String())
+		w := httptest.NewRecorder()
+
+		handler.RedeliverDelivery(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should return 400 for invalid delivery UUID", func(t *testing.T) {
+		handler := NewWebhookHandler(nil, nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/123/deliveries/not-a-uuid/redeliver", nil)
+		req = withURLParam(req, "id", uuid.New().String())
+		req = withURLParam(req, "deliveryId", "not-a-uuid")
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.RedeliverDelivery(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_id", response.Error)
+	})
+
+	t.Run("should return 503 when delivery engine is not configured", func(t *testing.T) {
+		handler := NewWebhookHandler(nil, nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/123/deliveries/456/redeliver", nil)
+		req = withURLParam(req, "id", uuid.New().String())
+		req = withURLParam(req, "deliveryId", uuid.New().String())
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.RedeliverDelivery(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+func TestFindInvalidEvent(t *testing.T) {
+	_, ok := findInvalidEvent([]models.WebhookEvent{models.WebhookEventRecordCreated, models.WebhookEventFormSubmitted})
+	assert.True(t, ok)
+
+	event, ok := findInvalidEvent([]models.WebhookEvent{models.WebhookEventFieldCreated, "record.archived"})
+	assert.False(t, ok)
+	assert.Equal(t, models.WebhookEvent("record.archived"), event)
+
+	_, ok = findInvalidEvent(nil)
+	assert.True(t, ok)
+}
+
+func TestValidateWebhookFormat(t *testing.T) {
+	template := `{{#if field:Status == "Done"}}Done{{/if}}`
+	assert.Empty(t, validateWebhookFormat("", nil))
+	assert.Empty(t, validateWebhookFormat(models.WebhookFormatSlack, &template))
+	assert.Equal(t, "Unknown format: xml", validateWebhookFormat("xml", nil))
+
+	broken := "{{#if field:Status}}Done"
+	assert.Contains(t, validateWebhookFormat(models.WebhookFormatTeams, &broken), "message_template")
+}
+
+func TestWebhookHandler_TestWebhook(t *testing.T) {
+	t.Run("should return 401 when no user in context", func(t *testing.T) {
+		handler := NewWebhookHandler(nil, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/123/test", nil)
+		req = withURLParam(req, "id", uuid.New().String())
+		w := httptest.NewRecorder()
+
+		handler.TestWebhook(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should return 400 for unknown event", func(t *testing.T) {
+		handler := NewWebhookHandler(nil, nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/123/test", bytes.NewBufferString(`{"event": "record.archived"}`))
+		req = withURLParam(req, "id", uuid.New().String())
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.TestWebhook(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should return 503 without a delivery engine", func(t *testing.T) {
+		handler := NewWebhookHandler(nil, nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/123/test", nil)
+		req = withURLParam(req, "id", uuid.New().String())
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.TestWebhook(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+func TestWebhookHandler_RotateSecret(t *testing.T) {
+	t.Run("should return 401 when no user in context", func(t *testing.T) {
+		handler := NewWebhookHandler(nil, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/123/rotate-secret", nil)
+		req = withURLParam(req, "id", uuid.New().String())
+		w := httptest.NewRecorder()
+
+		handler.RotateSecret(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should return 400 for invalid webhook ID", func(t *testing.T) {
+		handler := NewWebhookHandler(nil, nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/invalid/rotate-secret", nil)
+		req = withURLParam(req, "id", "invalid")
+		req = req.WithContext(SetUserInContext(req.Context(), user))
+		w := httptest.NewRecorder()
+
+		handler.RotateSecret(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should return 400 for an out of range grace period", func(t *testing.T) {
+		handler := NewWebhookHandler(nil, nil)
+
+		user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+		for _, body := range []string{`{"grace_period_hours": -1}`, `{"grace_period_hours": 1000}`} {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/123/rotate-secret", bytes.NewBufferString(body))
+			req = withURLParam(req, "id", uuid.New().String())
+			req = req.WithContext(SetUserInContext(req.Context(), user))
+			w := httptest.NewRecorder()
+
+			handler.RotateSecret(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+}
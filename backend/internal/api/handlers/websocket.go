@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
@@ -46,12 +47,12 @@ type WebSocketHandler struct {
 }
 
 // NewWebSocketHandler creates a new WebSocket handler
-func NewWebSocketHandler(hub *realtime.Hub, authStore *store.AuthStore, baseStore *store.BaseStore) *WebSocketHandler {
+func NewWebSocketHandler(hub *realtime.Hub, authStore *store.AuthStore, baseStore *store.BaseStore, ticketStore *store.WSTicketStore) *WebSocketHandler {
 	return &WebSocketHandler{
 		hub:         hub,
 		authStore:   authStore,
 		baseStore:   baseStore,
-		ticketStore: store.NewWSTicketStore(),
+		ticketStore: ticketStore,
 	}
 }
 
@@ -59,8 +60,8 @@ func NewWebSocketHandler(hub *realtime.Hub, authStore *store.AuthStore, baseStor
 // POST /api/v1/ws/ticket
 func (h *WebSocketHandler) GetTicket(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
-	userID, ok := r.Context().Value("user_id").(uuid.UUID)
-	if !ok {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
 		writeWSError(w, http.StatusUnauthorized, "unauthorized", "User not authenticated")
 		return
 	}
@@ -82,14 +83,21 @@ func (h *WebSocketHandler) GetTicket(w http.ResponseWriter, r *http.Request) {
 
 	// Verify user has access to the base
 	ctx := r.Context()
-	_, err = h.baseStore.GetUserRole(ctx, baseID, userID)
+	_, err = h.baseStore.GetUserRole(ctx, baseID, user.ID)
 	if err != nil {
 		writeWSError(w, http.StatusForbidden, "forbidden", "You don't have access to this base")
 		return
 	}
 
+	// The connection is re-checked against this session while it stays open
+	session, err := h.authStore.GetSessionByToken(ctx, GetTokenFromContext(ctx))
+	if err != nil {
+		writeWSError(w, http.StatusUnauthorized, "unauthorized", "Invalid session")
+		return
+	}
+
 	// Generate ticket
-	ticket, err := h.ticketStore.GenerateTicket(ctx, userID, baseID)
+	ticket, err := h.ticketStore.GenerateTicket(ctx, user.ID, session.ID, baseID)
 	if err != nil {
 		log.Printf("Failed to generate WebSocket ticket: %v", err)
 		writeWSError(w, http.StatusInternalServerError, "internal_error", "Failed to generate ticket")
@@ -102,8 +110,9 @@ func (h *WebSocketHandler) GetTicket(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ServeWS handles WebSocket upgrade requests
-// GET /ws?baseId=xxx&ticket=xxx
+// ServeWS handles WebSocket upgrade requests. A reconnecting client passes the last
+// sequence number it saw to receive the messages it missed.
+// GET /ws?baseId=xxx&ticket=xxx[&lastSeq=n]
 func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 	// Get base ID from query
 	baseIDStr := r.URL.Query().Get("baseId")
@@ -118,6 +127,16 @@ func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var lastSeq *int64
+	if lastSeqStr := r.URL.Query().Get("lastSeq"); lastSeqStr != "" {
+		seq, err := strconv.ParseInt(lastSeqStr, 10, 64)
+		if err != nil || seq < 0 {
+			http.Error(w, "invalid lastSeq", http.StatusBadRequest)
+			return
+		}
+		lastSeq = &seq
+	}
+
 	// Get ticket from query (short-lived, one-time use token)
 	ticket := r.URL.Query().Get("ticket")
 	if ticket == "" {
@@ -127,7 +146,7 @@ func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 
 	// Validate ticket and get user ID
 	ctx := context.Background()
-	userID, err := h.ticketStore.ValidateTicket(ctx, ticket, baseID)
+	userID, sessionID, err := h.ticketStore.ValidateTicket(ctx, ticket, baseID)
 	if err != nil {
 		log.Printf("Invalid WebSocket ticket: %v", err)
 		http.Error(w, "invalid or expired ticket", http.StatusUnauthorized)
@@ -142,6 +161,13 @@ func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Access may have been revoked since the ticket was issued
+	role, err := h.baseStore.GetUserRole(ctx, baseID, userID)
+	if err != nil {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+
 	// Upgrade connection
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -151,6 +177,10 @@ func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 
 	// Create client
 	client := realtime.NewClient(h.hub, conn, user.ID, user.Email, user.Name, baseID)
+	client.SetAccess(sessionID, string(role))
+	if lastSeq != nil {
+		client.ResumeFrom(*lastSeq)
+	}
 
 	// Register client
 	h.hub.Register(client)
@@ -12,7 +12,7 @@ import (
 
 func TestNewWebSocketHandler(t *testing.T) {
 	hub := realtime.NewHub()
-	handler := NewWebSocketHandler(hub, nil, nil)
+	handler := NewWebSocketHandler(hub, nil, nil, nil)
 
 	assert.NotNil(t, handler)
 	assert.Equal(t, hub, handler.GetHub())
@@ -20,14 +20,14 @@ func TestNewWebSocketHandler(t *testing.T) {
 
 func TestWebSocketHandler_GetHub(t *testing.T) {
 	hub := realtime.NewHub()
-	handler := NewWebSocketHandler(hub, nil, nil)
+	handler := NewWebSocketHandler(hub, nil, nil, nil)
 
 	assert.Equal(t, hub, handler.GetHub())
 }
 
 func TestWebSocketHandler_ServeWS_MissingBaseId(t *testing.T) {
 	hub := realtime.NewHub()
-	handler := NewWebSocketHandler(hub, nil, nil)
+	handler := NewWebSocketHandler(hub, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
 	w := httptest.NewRecorder()
@@ -40,7 +40,7 @@ func TestWebSocketHandler_ServeWS_MissingBaseId(t *testing.T) {
 
 func TestWebSocketHandler_ServeWS_InvalidBaseId(t *testing.T) {
 	hub := realtime.NewHub()
-	handler := NewWebSocketHandler(hub, nil, nil)
+	handler := NewWebSocketHandler(hub, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/ws?baseId=not-a-uuid", nil)
 	w := httptest.NewRecorder()
@@ -53,7 +53,7 @@ func TestWebSocketHandler_ServeWS_InvalidBaseId(t *testing.T) {
 
 func TestWebSocketHandler_ServeWS_MissingToken(t *testing.T) {
 	hub := realtime.NewHub()
-	handler := NewWebSocketHandler(hub, nil, nil)
+	handler := NewWebSocketHandler(hub, nil, nil, nil)
 
 	baseID := uuid.New()
 	req := httptest.NewRequest(http.MethodGet, "/ws?baseId="+baseID.String(), nil)
@@ -75,3 +75,17 @@ func TestWriteWSError(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "test_error")
 	assert.Contains(t, w.Body.String(), "Test error message")
 }
+
+func TestWebSocketHandler_ServeWS_InvalidLastSeq(t *testing.T) {
+	hub := realtime.NewHub()
+	handler := NewWebSocketHandler(hub, nil, nil, nil)
+
+	baseID := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/ws?baseId="+baseID.String()+"&ticket=abc&lastSeq=-1", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeWS(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid lastSeq")
+}
@@ -0,0 +1,759 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/store"
+	"github.com/vibetable/backend/internal/xlsx"
+)
+
+const (
+	// maxSpreadsheetSize caps xlsx and ndjson uploads
+	maxSpreadsheetSize = 50 << 20
+	// headerSearchRows is how far down a sheet its header row is looked for
+	headerSearchRows = 20
+	xlsxPreviewRows  = 5
+	xlsxContentType  = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+type XLSXHandler struct {
+	baseStore   *store.BaseStore
+	tableStore  *store.TableStore
+	fieldStore  *store.FieldStore
+	recordStore *store.RecordStore
+	viewStore   *store.ViewStore
+	jobs        *store.JobStore
+}
+
+func NewXLSXHandler(baseStore *store.BaseStore, tableStore *store.TableStore, fieldStore *store.FieldStore, recordStore *store.RecordStore, viewStore *store.ViewStore) *XLSXHandler {
+	return &XLSXHandler{
+		baseStore:   baseStore,
+		tableStore:  tableStore,
+		fieldStore:  fieldStore,
+		recordStore: recordStore,
+		viewStore:   viewStore,
+	}
+}
+
+// SetJobStore makes imports create their records in background jobs
+func (h *XLSXHandler) SetJobStore(jobs *store.JobStore) {
+	h.jobs = jobs
+}
+
+// XLSXSheetPreview previews a sheet of an uploaded workbook. HeaderRow is the
+// 1-based row the columns were found in, or 0 for an empty sheet.
+type XLSXSheetPreview struct {
+	Name      string                   `json:"name"`
+	HeaderRow int                      `json:"header_row"`
+	Columns   []string                 `json:"columns"`
+	Rows      []map[string]interface{} `json:"rows"`
+	Total     int                      `json:"total"`
+}
+
+// XLSXSheetImport maps the columns of a sheet to the fields of a table. The
+// header row is found as it is for the preview when HeaderRow is 0.
+type XLSXSheetImport struct {
+	Sheet     string            `json:"sheet"`
+	TableID   uuid.UUID         `json:"table_id"`
+	HeaderRow int               `json:"header_row,omitempty"`
+	Mappings  map[string]string `json:"mappings"` // column name -> field ID
+}
+
+// XLSXImportResult is the outcome of importing a sheet: the job creating its
+// records, or what was imported when imports do not run as jobs
+type XLSXImportResult struct {
+	Sheet   string                      `json:"sheet"`
+	TableID uuid.UUID                   `json:"table_id"`
+	Job     *models.Job                 `json:"job,omitempty"`
+	Result  *models.ImportRecordsResult `json:"result,omitempty"`
+}
+
+// Preview handles POST /bases/:id/xlsx/preview with the workbook as the multipart
+// "file", returning the columns and first rows of every sheet
+func (h *XLSXHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	baseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid base ID")
+		return
+	}
+
+	if _, err := h.baseStore.GetBase(r.Context(), baseID, user.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Base not found")
+			return
+		}
+		log.Printf("Error getting base: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to verify access")
+		return
+	}
+
+	sheets, ok := readWorkbook(w, r)
+	if !ok {
+		return
+	}
+
+	previews := make([]XLSXSheetPreview, 0, len(sheets))
+	for _, sheet := range sheets {
+		previews = append(previews, previewSheet(sheet, xlsxPreviewRows))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sheets": previews,
+	})
+}
+
+// Import handles POST /bases/:id/xlsx/import with the workbook as the multipart
+// "file" and a JSON list of XLSXSheetImport as "sheets", importing each sheet
+// into its table
+func (h *XLSXHandler) Import(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	baseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid base ID")
+		return
+	}
+
+	tables, err := h.tableStore.ListTablesForBase(r.Context(), baseID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Base not found")
+			return
+		}
+		log.Printf("Error listing tables: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to list tables")
+		return
+	}
+	inBase := make(map[uuid.UUID]bool, len(tables))
+	for _, t := range tables {
+		inBase[t.ID] = true
+	}
+
+	sheets, ok := readWorkbook(w, r)
+	if !ok {
+		return
+	}
+	byName := make(map[string]xlsx.Sheet, len(sheets))
+	for _, sheet := range sheets {
+		byName[sheet.Name] = sheet
+	}
+
+	var imports []XLSXSheetImport
+	if err := json.Unmarshal([]byte(r.FormValue("sheets")), &imports); err != nil || len(imports) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Sheets to import are required")
+		return
+	}
+
+	// Every sheet is converted before any is imported, so a mistake in one
+	// mapping imports nothing
+	payloads := make([]models.ImportRecordsPayload, len(imports))
+	for i, imp := range imports {
+		sheet, ok := byName[imp.Sheet]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid_sheet", fmt.Sprintf("Workbook has no sheet named %q", imp.Sheet))
+			return
+		}
+		if !inBase[imp.TableID] {
+			writeError(w, http.StatusBadRequest, "invalid_table", fmt.Sprintf("Table for sheet %q is not in this base", imp.Sheet))
+			return
+		}
+		if imp.HeaderRow < 0 || imp.HeaderRow > len(sheet.Rows) {
+			writeError(w, http.StatusBadRequest, "invalid_header_row", fmt.Sprintf("Sheet %q has no row %d", imp.Sheet, imp.HeaderRow))
+			return
+		}
+
+		fields, err := h.fieldStore.ListFieldsForTable(r.Context(), imp.TableID, user.ID)
+		if err != nil {
+			log.Printf("Error listing fields: %v", err)
+			writeError(w, http.StatusInternalServerError, "server_error", "Failed to get table fields")
+			return
+		}
+		payloads[i] = sheetRecords(sheet, imp, fields)
+	}
+
+	status := http.StatusOK
+	results := make([]XLSXImportResult, len(imports))
+	for i, payload := range payloads {
+		job, err := importRecords(r.Context(), h.jobs, h.recordStore, payload, user.ID)
+		if err != nil {
+			writeImportError(w, err)
+			return
+		}
+		results[i] = XLSXImportResult{Sheet: imports[i].Sheet, TableID: payload.TableID, Job: job}
+		if job != nil {
+			status = http.StatusAccepted
+		} else {
+			results[i].Result = &models.ImportRecordsResult{
+				Imported: len(payload.Records),
+				Skipped:  payload.Skipped,
+				Errors:   payload.Errors,
+			}
+		}
+	}
+
+	writeJSON(w, status, map[string]interface{}{
+		"sheets": results,
+	})
+}
+
+// ExportTable handles GET /tables/:tableId/xlsx/export, downloading the table as
+// a workbook. With ?view_id= the sheet has the view's records and fields, in
+// the view's order.
+func (h *XLSXHandler) ExportTable(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	tableID, err := uuid.Parse(chi.URLParam(r, "tableId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid table ID")
+		return
+	}
+
+	var view *models.View
+	if viewID := r.URL.Query().Get("view_id"); viewID != "" {
+		id, err := uuid.Parse(viewID)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_id", "Invalid view ID")
+			return
+		}
+		view, err = h.viewStore.GetView(r.Context(), id, user.ID)
+		if err == nil && view.TableID != tableID {
+			err = store.ErrNotFound
+		}
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeError(w, http.StatusNotFound, "not_found", "View not found")
+				return
+			}
+			log.Printf("Error getting view: %v", err)
+			writeError(w, http.StatusInternalServerError, "server_error", "Failed to get view")
+			return
+		}
+	}
+
+	table, err := h.tableStore.GetTable(r.Context(), tableID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Table not found")
+			return
+		}
+		log.Printf("Error getting table: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get table")
+		return
+	}
+
+	sheet, err := h.tableSheet(r.Context(), table, view, user.ID)
+	if err != nil {
+		log.Printf("Error exporting table %s: %v", tableID, err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to export table")
+		return
+	}
+
+	filename := table.Name
+	if view != nil {
+		filename += " - " + view.Name
+	}
+	writeWorkbook(w, filename, []xlsx.Sheet{sheet})
+}
+
+// ExportBase handles GET /bases/:id/xlsx/export, downloading the base as a
+// workbook with a sheet for each table
+func (h *XLSXHandler) ExportBase(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	baseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid base ID")
+		return
+	}
+
+	base, err := h.baseStore.GetBase(r.Context(), baseID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "Base not found")
+			return
+		}
+		log.Printf("Error getting base: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to get base")
+		return
+	}
+
+	tables, err := h.tableStore.ListTablesForBase(r.Context(), baseID, user.ID)
+	if err != nil {
+		log.Printf("Error listing tables: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to list tables")
+		return
+	}
+
+	sheets := make([]xlsx.Sheet, 0, len(tables))
+	for i := range tables {
+		sheet, err := h.tableSheet(r.Context(), &tables[i], nil, user.ID)
+		if err != nil {
+			log.Printf("Error exporting table %s: %v", tables[i].ID, err)
+			writeError(w, http.StatusInternalServerError, "server_error", "Failed to export base")
+			return
+		}
+		sheets = append(sheets, sheet)
+	}
+
+	writeWorkbook(w, base.Name, sheets)
+}
+
+// readWorkbook reads the workbook uploaded as the multipart "file", writing the
+// error and returning false when there is none
+func readWorkbook(w http.ResponseWriter, r *http.Request) ([]xlsx.Sheet, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSpreadsheetSize)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "file_too_large", "File exceeds the limit of "+strconv.Itoa(maxSpreadsheetSize)+" bytes")
+			return nil, false
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse multipart form")
+		return nil, false
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file_required", "File is required")
+		return nil, false
+	}
+	defer file.Close()
+
+	sheets, err := xlsx.Read(file, header.Size)
+	if err != nil {
+		if errors.Is(err, xlsx.ErrTooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "file_too_large", capitalize(err.Error()))
+			return nil, false
+		}
+		if errors.Is(err, xlsx.ErrInvalidWorkbook) {
+			writeError(w, http.StatusBadRequest, "invalid_xlsx", capitalize(err.Error()))
+			return nil, false
+		}
+		log.Printf("Error reading workbook: %v", err)
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to read workbook")
+		return nil, false
+	}
+	return sheets, true
+}
+
+func writeWorkbook(w http.ResponseWriter, name string, sheets []xlsx.Sheet) {
+	w.Header().Set("Content-Type", xlsxContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".xlsx"}))
+	if err := xlsx.Write(w, sheets); err != nil {
+		// The response has started, so the client sees a truncated workbook
+		log.Printf("Error writing workbook %q: %v", name, err)
+	}
+}
+
+// tableSheet returns a sheet of a table's records with a header row of field
+// names, limited to and ordered as a view when one is given
+func (h *XLSXHandler) tableSheet(ctx context.Context, table *models.Table, view *models.View, userID uuid.UUID) (xlsx.Sheet, error) {
+	fields, err := h.fieldStore.ListFieldsForTable(ctx, table.ID, userID)
+	if err != nil {
+		return xlsx.Sheet{}, err
+	}
+	records, err := h.recordStore.ListRecordsForTable(ctx, table.ID, userID)
+	if err != nil {
+		return xlsx.Sheet{}, err
+	}
+
+	values := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		var v map[string]interface{}
+		if err := json.Unmarshal(record.Values, &v); err != nil || v == nil {
+			v = make(map[string]interface{})
+		}
+		values = append(values, v)
+	}
+
+	name := table.Name
+	if view != nil {
+		var config models.ViewConfig
+		if len(view.Config) > 0 {
+			json.Unmarshal(view.Config, &config)
+		}
+		values = applyView(config, values)
+		fields = visibleFields(config, fields)
+		name = view.Name
+	}
+
+	// Attachments are kept apart from record values, so their columns would be empty
+	columns := make([]models.Field, 0, len(fields))
+	for _, f := range fields {
+		if f.FieldType != models.FieldTypeAttachment {
+			columns = append(columns, f)
+		}
+	}
+
+	rows := make([][]interface{}, 0, len(values)+1)
+	header := make([]interface{}, len(columns))
+	for i, f := range columns {
+		header[i] = f.Name
+	}
+	rows = append(rows, header)
+	for _, v := range values {
+		row := make([]interface{}, len(columns))
+		for i, f := range columns {
+			row[i] = sheetValue(v[f.ID.String()], f)
+		}
+		rows = append(rows, row)
+	}
+
+	return xlsx.Sheet{Name: name, Rows: rows}, nil
+}
+
+// applyView returns the records a view shows, in its order
+func applyView(config models.ViewConfig, values []map[string]interface{}) []map[string]interface{} {
+	shown := make([]map[string]interface{}, 0, len(values))
+	for _, v := range values {
+		if config.MatchesRecord(v) {
+			shown = append(shown, v)
+		}
+	}
+	sort.SliceStable(shown, func(i, j int) bool {
+		for _, s := range config.Sorts {
+			a, b := shown[i][s.FieldID], shown[j][s.FieldID]
+			// Empty values go last whichever way the view sorts
+			if aEmpty, bEmpty := isEmptyValue(a), isEmptyValue(b); aEmpty || bEmpty {
+				if aEmpty == bEmpty {
+					continue
+				}
+				return bEmpty
+			}
+			c := compareValues(a, b)
+			if c == 0 {
+				continue
+			}
+			if s.Direction == "desc" {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return shown
+}
+
+// visibleFields returns the fields a view shows, in its order
+func visibleFields(config models.ViewConfig, fields []models.Field) []models.Field {
+	if len(config.VisibleFields) == 0 {
+		return fields
+	}
+	byID := make(map[string]models.Field, len(fields))
+	for _, f := range fields {
+		byID[f.ID.String()] = f
+	}
+	visible := make([]models.Field, 0, len(config.VisibleFields))
+	for _, id := range config.VisibleFields {
+		if f, ok := byID[id]; ok {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+// compareValues orders two cell values: numbers by value and everything else as
+// text ignoring case
+func compareValues(a, b interface{}) int {
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// sheetValue converts a record value to a typed cell: dates as dates, select
+// options by name and lists joined with commas, as they are imported
+func sheetValue(value interface{}, field models.Field) interface{} {
+	switch field.FieldType {
+	case models.FieldTypeDate:
+		if s, ok := value.(string); ok {
+			for _, layout := range []string{"2006-01-02", time.RFC3339Nano} {
+				if t, err := time.Parse(layout, s); err == nil {
+					return t
+				}
+			}
+		}
+	case models.FieldTypeSingleSelect, models.FieldTypeMultiSelect:
+		value = selectOptionNames(field, value)
+	}
+
+	switch v := value.(type) {
+	case nil, string, float64, bool:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := sheetValue(item, models.Field{}).(string); ok {
+				parts = append(parts, s)
+			} else {
+				parts = append(parts, fmt.Sprint(item))
+			}
+		}
+		return strings.Join(parts, ", ")
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// selectOptionNames replaces the option IDs in a select value with their names
+func selectOptionNames(field models.Field, value interface{}) interface{} {
+	var options models.FieldOptions
+	if len(field.Options) > 0 {
+		json.Unmarshal(field.Options, &options)
+	}
+	name := func(v interface{}) interface{} {
+		for _, option := range options.Options {
+			if option.ID == v {
+				return option.Name
+			}
+		}
+		return v
+	}
+	if items, ok := value.([]interface{}); ok {
+		names := make([]interface{}, len(items))
+		for i, item := range items {
+			names[i] = name(item)
+		}
+		return names
+	}
+	return name(value)
+}
+
+// sheetRecords converts the rows below a sheet's header row into the values of
+// records, counting rows with nothing to import as skipped
+func sheetRecords(sheet xlsx.Sheet, imp XLSXSheetImport, fields []models.Field) models.ImportRecordsPayload {
+	payload := models.ImportRecordsPayload{TableID: imp.TableID}
+	header := imp.HeaderRow - 1
+	if imp.HeaderRow == 0 {
+		header = findHeaderRow(sheet.Rows)
+	}
+	if header < 0 {
+		return payload
+	}
+
+	fieldMap := make(map[string]models.Field, len(fields))
+	for _, f := range fields {
+		fieldMap[f.ID.String()] = f
+	}
+	columnIndex := make(map[string]int)
+	for i, name := range sheetColumns(sheet.Rows[header]) {
+		columnIndex[name] = i
+	}
+
+	for _, row := range sheet.Rows[header+1:] {
+		if len(row) == 0 {
+			continue
+		}
+
+		values := make(map[string]interface{})
+		for column, fieldID := range imp.Mappings {
+			i, ok := columnIndex[column]
+			if !ok || i >= len(row) || row[i] == nil {
+				continue
+			}
+			field, ok := fieldMap[fieldID]
+			if !ok || models.IsComputedField(field.FieldType) {
+				continue
+			}
+			if v := convertSheetValue(row[i], field); v != nil {
+				values[fieldID] = v
+			}
+		}
+
+		if len(values) == 0 {
+			payload.Skipped++
+			continue
+		}
+		b, err := json.Marshal(values)
+		if err != nil {
+			payload.Errors++
+			continue
+		}
+		payload.Records = append(payload.Records, b)
+	}
+	return payload
+}
+
+// convertSheetValue converts a typed cell to a value of the field's type
+func convertSheetValue(value interface{}, field models.Field) interface{} {
+	switch v := value.(type) {
+	case float64:
+		switch field.FieldType {
+		case models.FieldTypeNumber:
+			return v
+		case models.FieldTypeCheckbox:
+			return v != 0
+		}
+		return convertTextValue(strconv.FormatFloat(v, 'f', -1, 64), field)
+	case bool:
+		if field.FieldType == models.FieldTypeCheckbox {
+			return v
+		}
+		return convertTextValue(strconv.FormatBool(v), field)
+	case time.Time:
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			return convertTextValue(v.Format("2006-01-02"), field)
+		}
+		return convertTextValue(v.Format(time.RFC3339), field)
+	case string:
+		return convertTextValue(v, field)
+	}
+	return nil
+}
+
+// previewSheet returns a sheet's columns and its first rows below the header
+func previewSheet(sheet xlsx.Sheet, maxRows int) XLSXSheetPreview {
+	preview := XLSXSheetPreview{
+		Name:    sheet.Name,
+		Columns: []string{},
+		Rows:    []map[string]interface{}{},
+	}
+	header := findHeaderRow(sheet.Rows)
+	if header < 0 {
+		return preview
+	}
+	preview.HeaderRow = header + 1
+	preview.Columns = sheetColumns(sheet.Rows[header])
+
+	for _, row := range sheet.Rows[header+1:] {
+		if len(row) == 0 {
+			continue
+		}
+		preview.Total++
+		if len(preview.Rows) == maxRows {
+			continue
+		}
+		rowMap := make(map[string]interface{}, len(preview.Columns))
+		for i, column := range preview.Columns {
+			if i < len(row) {
+				rowMap[column] = row[i]
+			} else {
+				rowMap[column] = nil
+			}
+		}
+		preview.Rows = append(preview.Rows, rowMap)
+	}
+	return preview
+}
+
+// findHeaderRow returns the index of the row most likely to hold a sheet's
+// column names: the first near the top that has only text and is at least half
+// as wide as the widest row there, which passes over titles above a table. It
+// falls back to the first row with cells, and returns -1 for an empty sheet.
+func findHeaderRow(rows [][]interface{}) int {
+	top := rows[:min(len(rows), headerSearchRows)]
+	widest := 0
+	for _, row := range top {
+		widest = max(widest, filledCells(row))
+	}
+
+	first := -1
+	for i, row := range top {
+		filled := filledCells(row)
+		if filled == 0 {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if filled*2 < widest {
+			continue
+		}
+		text := true
+		for _, cell := range row {
+			if _, ok := cell.(string); cell != nil && !ok {
+				text = false
+				break
+			}
+		}
+		if text {
+			return i
+		}
+	}
+	return first
+}
+
+func filledCells(row []interface{}) int {
+	n := 0
+	for _, cell := range row {
+		if cell != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// sheetColumns names the columns of a header row. Columns without a name are
+// numbered, and repeated names are told apart by their count.
+func sheetColumns(header []interface{}) []string {
+	columns := make([]string, len(header))
+	seen := make(map[string]int, len(header))
+	for i, cell := range header {
+		name := ""
+		if cell != nil {
+			name = strings.TrimSpace(fmt.Sprint(cell))
+		}
+		if name == "" {
+			name = "Column " + strconv.Itoa(i+1)
+		}
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name = fmt.Sprintf("%s (%d)", name, n)
+		}
+		columns[i] = name
+	}
+	return columns
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/xlsx"
+)
+
+func TestXLSXHandler(t *testing.T) {
+	handlers := map[string]struct {
+		handler func(*XLSXHandler) http.HandlerFunc
+		param   string
+	}{
+		"Preview":     {func(h *XLSXHandler) http.HandlerFunc { return h.Preview }, "id"},
+		"Import":      {func(h *XLSXHandler) http.HandlerFunc { return h.Import }, "id"},
+		"ExportBase":  {func(h *XLSXHandler) http.HandlerFunc { return h.ExportBase }, "id"},
+		"ExportTable": {func(h *XLSXHandler) http.HandlerFunc { return h.ExportTable }, "tableId"},
+	}
+	for name, tt := range handlers {
+		t.Run(name+" returns 401 when no user in context", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/xlsx", nil)
+			w := httptest.NewRecorder()
+
+			tt.handler(NewXLSXHandler(nil, nil, nil, nil, nil))(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+
+		t.Run(name+" returns 400 for invalid ID", func(t *testing.T) {
+			user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+			req := httptest.NewRequest(http.MethodPost, "/xlsx", nil)
+			req = withURLParam(req, tt.param, "not-a-uuid")
+			req = req.WithContext(SetUserInContext(req.Context(), user))
+			w := httptest.NewRecorder()
+
+			tt.handler(NewXLSXHandler(nil, nil, nil, nil, nil))(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestReadWorkbook(t *testing.T) {
+	upload := func(t *testing.T, content []byte) *http.Request {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		if content != nil {
+			fw, err := mw.CreateFormFile("file", "book.xlsx")
+			require.NoError(t, err)
+			fw.Write(content)
+		}
+		require.NoError(t, mw.Close())
+		req := httptest.NewRequest(http.MethodPost, "/xlsx", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req
+	}
+
+	t.Run("reads the uploaded workbook", func(t *testing.T) {
+		var book bytes.Buffer
+		require.NoError(t, xlsx.Write(&book, []xlsx.Sheet{{Name: "People", Rows: [][]interface{}{{"Name"}}}}))
+		w := httptest.NewRecorder()
+
+		sheets, ok := readWorkbook(w, upload(t, book.Bytes()))
+		require.True(t, ok)
+		require.Len(t, sheets, 1)
+		assert.Equal(t, "People", sheets[0].Name)
+	})
+
+	t.Run("requires a file", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, ok := readWorkbook(w, upload(t, nil))
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "file_required")
+	})
+
+	t.Run("rejects files that are not workbooks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, ok := readWorkbook(w, upload(t, []byte("name,age")))
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_xlsx")
+	})
+}
+
+func TestFindHeaderRow(t *testing.T) {
+	t.Run("passes over a title above the table", func(t *testing.T) {
+		rows := [][]interface{}{
+			{"Sales report"},
+			nil,
+			{"Region", "Q1", "Q2"},
+			{"North", 10.0, 12.0},
+		}
+		assert.Equal(t, 2, findHeaderRow(rows))
+	})
+
+	t.Run("falls back to the first row with cells", func(t *testing.T) {
+		rows := [][]interface{}{nil, {1.0, 2.0}, {3.0, 4.0}}
+		assert.Equal(t, 1, findHeaderRow(rows))
+	})
+
+	t.Run("returns -1 for an empty sheet", func(t *testing.T) {
+		assert.Equal(t, -1, findHeaderRow(nil))
+	})
+}
+
+func TestSheetColumns(t *testing.T) {
+	columns := sheetColumns([]interface{}{"Name", nil, " Name ", 2024.0})
+	assert.Equal(t, []string{"Name", "Column 2", "Name (2)", "2024"}, columns)
+}
+
+func TestPreviewSheet(t *testing.T) {
+	sheet := xlsx.Sheet{Name: "People", Rows: [][]interface{}{
+		{"Name", "Age"},
+		{"Ada", 36.0},
+		nil,
+		{"Grace"},
+		{"Alan", 41.0},
+	}}
+
+	preview := previewSheet(sheet, 2)
+	assert.Equal(t, "People", preview.Name)
+	assert.Equal(t, 1, preview.HeaderRow)
+	assert.Equal(t, []string{"Name", "Age"}, preview.Columns)
+	assert.Equal(t, 3, preview.Total)
+	assert.Equal(t, []map[string]interface{}{
+		{"Name": "Ada", "Age": 36.0},
+		{"Name": "Grace", "Age": nil},
+	}, preview.Rows)
+}
+
+func TestSheetRecords(t *testing.T) {
+	name := models.Field{ID: uuid.New(), FieldType: models.FieldTypeText}
+	age := models.Field{ID: uuid.New(), FieldType: models.FieldTypeNumber}
+	joined := models.Field{ID: uuid.New(), FieldType: models.FieldTypeDate}
+	tags := models.Field{
+		ID:        uuid.New(),
+		FieldType: models.FieldTypeMultiSelect,
+		Options:   json.RawMessage(`{"options":[{"id":"opt-1","name":"VIP"},{"id":"opt-2","name":"New"}]}`),
+	}
+	total := models.Field{ID: uuid.New(), FieldType: models.FieldTypeFormula}
+	fields := []models.Field{name, age, joined, tags, total}
+
+	sheet := xlsx.Sheet{Rows: [][]interface{}{
+		{"Contacts"},
+		{"Name", "Age", "Joined", "Tags", "Total"},
+		{"Ada", 36.0, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), "vip, new", 1.0},
+		{nil, nil, nil, nil, 2.0},
+		nil,
+	}}
+	imp := XLSXSheetImport{
+		TableID: uuid.New(),
+		Mappings: map[string]string{
+			"Name": name.ID.String(), "Age": age.ID.String(), "Joined": joined.ID.String(),
+			"Tags": tags.ID.String(), "Total": total.ID.String(), "Missing": uuid.New().String(),
+		},
+	}
+
+	payload := sheetRecords(sheet, imp, fields)
+	assert.Equal(t, imp.TableID, payload.TableID)
+	assert.Equal(t, 1, payload.Skipped)
+	require.Len(t, payload.Records, 1)
+
+	var values map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload.Records[0], &values))
+	assert.Equal(t, map[string]interface{}{
+		name.ID.String():   "Ada",
+		age.ID.String():    36.0,
+		joined.ID.String(): "2024-03-15",
+		tags.ID.String():   []interface{}{"opt-1", "opt-2"},
+	}, values)
+
+	t.Run("uses the header row it is given", func(t *testing.T) {
+		imp := imp
+		imp.HeaderRow = 3
+		imp.Mappings = map[string]string{"Ada": name.ID.String()}
+		payload := sheetRecords(sheet, imp, fields)
+		assert.Empty(t, payload.Records)
+		assert.Equal(t, 1, payload.Skipped)
+	})
+}
+
+func TestConvertSheetValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     interface{}
+		fieldType models.FieldType
+		want      interface{}
+	}{
+		{"number to number", 4.5, models.FieldTypeNumber, 4.5},
+		{"number to text", 4.5, models.FieldTypeText, "4.5"},
+		{"number to checkbox", 1.0, models.FieldTypeCheckbox, true},
+		{"bool to checkbox", false, models.FieldTypeCheckbox, false},
+		{"bool to text", true, models.FieldTypeText, "true"},
+		{"date", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), models.FieldTypeDate, "2024-01-02"},
+		{"date and time", time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC), models.FieldTypeDate, "2024-01-02T09:30:00Z"},
+		{"text to number", "12", models.FieldTypeNumber, 12.0},
+		{"linked records", "id-1, id-2", models.FieldTypeLinkedRecord, []string{"id-1", "id-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, convertSheetValue(tt.value, models.Field{FieldType: tt.fieldType}))
+		})
+	}
+}
+
+func TestSheetValue(t *testing.T) {
+	status := models.Field{
+		FieldType: models.FieldTypeSingleSelect,
+		Options:   json.RawMessage(`{"options":[{"id":"opt-1","name":"Done"}]}`),
+	}
+	tags := status
+	tags.FieldType = models.FieldTypeMultiSelect
+
+	assert.Equal(t, "Done", sheetValue("opt-1", status))
+	assert.Equal(t, "Done, removed", sheetValue([]interface{}{"opt-1", "removed"}, tags))
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), sheetValue("2024-01-02", models.Field{FieldType: models.FieldTypeDate}))
+	assert.Equal(t, "soon", sheetValue("soon", models.Field{FieldType: models.FieldTypeDate}))
+	assert.Equal(t, 3.0, sheetValue(3.0, models.Field{FieldType: models.FieldTypeNumber}))
+	assert.Equal(t, "id-1, id-2", sheetValue([]interface{}{"id-1", "id-2"}, models.Field{FieldType: models.FieldTypeLinkedRecord}))
+	assert.Equal(t, `{"a":1}`, sheetValue(map[string]interface{}{"a": 1}, models.Field{FieldType: models.FieldTypeText}))
+	assert.Nil(t, sheetValue(nil, models.Field{FieldType: models.FieldTypeText}))
+}
+
+func TestApplyView(t *testing.T) {
+	values := []map[string]interface{}{
+		{"name": "b", "age": 30.0},
+		{"name": "A", "age": 20.0},
+		{"name": "c"},
+		{"name": "skip", "age": 99.0},
+	}
+	config := models.ViewConfig{
+		Filters: []models.ViewFilter{{FieldID: "name", Operator: "not_equals", Value: "skip"}},
+		Sorts:   []models.ViewSort{{FieldID: "age", Direction: "desc"}},
+	}
+
+	shown := applyView(config, values)
+	require.Len(t, shown, 3)
+	assert.Equal(t, "b", shown[0]["name"])
+	assert.Equal(t, "A", shown[1]["name"])
+	assert.Equal(t, "c", shown[2]["name"], "empty values sort last")
+
+	config.Sorts = []models.ViewSort{{FieldID: "name", Direction: "asc"}}
+	shown = applyView(config, values)
+	assert.Equal(t, "A", shown[0]["name"])
+}
+
+func TestVisibleFields(t *testing.T) {
+	a := models.Field{ID: uuid.New(), Name: "A"}
+	b := models.Field{ID: uuid.New(), Name: "B"}
+	fields := []models.Field{a, b}
+
+	assert.Equal(t, fields, visibleFields(models.ViewConfig{}, fields))
+	assert.Equal(t, []models.Field{b, a}, visibleFields(models.ViewConfig{
+		VisibleFields: []string{b.ID.String(), uuid.New().String(), a.ID.String()},
+	}, fields))
+}
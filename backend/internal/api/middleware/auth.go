@@ -5,23 +5,56 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/vibetable/backend/internal/apicontext"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/storage"
 	"github.com/vibetable/backend/internal/store"
 )
 
 // AuthMiddleware creates a middleware that validates session tokens
 // and adds the authenticated user to the request context
 type AuthMiddleware struct {
-	store *store.AuthStore
+	store  *store.AuthStore
+	signer *storage.URLSigner
 }
 
 func NewAuthMiddleware(store *store.AuthStore) *AuthMiddleware {
 	return &AuthMiddleware{store: store}
 }
 
-// extractToken gets the bearer token from Authorization header or query param
+// SetURLSigner lets Required accept signed download links, such as export links,
+// from requests that have no session token
+func (m *AuthMiddleware) SetURLSigner(signer *storage.URLSigner) {
+	m.signer = signer
+}
+
+// DownloadLinkResource is the resource signed for a GET of path on behalf of userID
+func DownloadLinkResource(path string, userID uuid.UUID) string {
+	return "download:" + path + ":" + userID.String()
+}
+
+// signedLinkUser returns the user a signed download link was issued to, or nil
+// when the request doesn't carry a signature
+func (m *AuthMiddleware) signedLinkUser(r *http.Request) (*models.User, error) {
+	query := r.URL.Query()
+	if m.signer == nil || r.Method != http.MethodGet || !query.Has("signature") {
+		return nil, nil
+	}
+	userID, err := uuid.Parse(query.Get("user"))
+	if err != nil {
+		return nil, storage.ErrInvalidSignature
+	}
+	if err := m.signer.Verify(DownloadLinkResource(r.URL.Path, userID), query); err != nil {
+		return nil, err
+	}
+	return m.store.GetUserByID(r.Context(), userID)
+}
+
+// extractToken gets the bearer token from the Authorization header. Tokens are never
+// read from the query string, where they would leak into logs and browser history;
+// file downloads and exports use signed URLs instead.
 func extractToken(r *http.Request) string {
-	// First check Authorization header
 	auth := r.Header.Get("Authorization")
 	if auth != "" {
 		// Expect "Bearer <token>"
@@ -31,12 +64,6 @@ func extractToken(r *http.Request) string {
 		}
 	}
 
-	// Fall back to query param (for file downloads)
-	token := r.URL.Query().Get("token")
-	if token != "" {
-		return token
-	}
-
 	return ""
 }
 
@@ -72,11 +99,28 @@ func (m *AuthMiddleware) Optional(next http.Handler) http.Handler {
 }
 
 // Required ensures the request is authenticated
-// Returns 401 if no valid session
+// Returns 401 if no valid session or signed download link
 func (m *AuthMiddleware) Required(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := extractToken(r)
 		if token == "" {
+			user, err := m.signedLinkUser(r)
+			if errors.Is(err, storage.ErrURLExpired) {
+				http.Error(w, `{"error":"link_expired","message":"This link has expired"}`, http.StatusForbidden)
+				return
+			}
+			if errors.Is(err, storage.ErrInvalidSignature) {
+				http.Error(w, `{"error":"invalid_signature","message":"This link is not valid"}`, http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				http.Error(w, `{"error":"unauthorized","message":"User not found"}`, http.StatusUnauthorized)
+				return
+			}
+			if user != nil {
+				next.ServeHTTP(w, r.WithContext(apicontext.SetUserInContext(r.Context(), user)))
+				return
+			}
 			http.Error(w, `{"error":"unauthorized","message":"Authentication required"}`, http.StatusUnauthorized)
 			return
 		}
@@ -40,14 +40,14 @@ func TestExtractToken(t *testing.T) {
 		assert.Equal(t, "my-token-123", token)
 	})
 
-	t.Run("extracts token from query parameter", func(t *testing.T) {
+	t.Run("ignores token query parameter", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/test?token=query-token-456", nil)
 
 		token := extractToken(req)
-		assert.Equal(t, "query-token-456", token)
+		assert.Equal(t, "", token)
 	})
 
-	t.Run("prefers Authorization header over query param", func(t *testing.T) {
+	t.Run("uses Authorization header when a query param is present", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/test?token=query-token", nil)
 		req.Header.Set("Authorization", "Bearer header-token")
 
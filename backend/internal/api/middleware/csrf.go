@@ -35,6 +35,12 @@ func NewCSRFMiddleware() *CSRFMiddleware {
 	}
 }
 
+// SetSecret sets the key tokens are signed with. Instances behind a load balancer
+// must share it so a token from one is accepted by the others.
+func (m *CSRFMiddleware) SetSecret(secret []byte) {
+	m.secret = secret
+}
+
 // generateToken creates a new CSRF token
 func (m *CSRFMiddleware) generateToken() (string, error) {
 	// Generate random bytes
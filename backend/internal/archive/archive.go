@@ -0,0 +1,176 @@
+// Package archive reads and writes base archives: zip files holding a base as
+// base.json, with the content of its attachments under files/.
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/vibetable/backend/internal/models"
+)
+
+const (
+	manifestName    = "base.json"
+	filesDir        = "files/"
+	maxManifestSize = 256 << 20
+)
+
+var (
+	ErrInvalidArchive     = errors.New("not a valid base archive")
+	ErrUnsupportedVersion = errors.New("archive version is not supported")
+)
+
+// Write writes a base archive to w, reading the content of each attachment file
+// with open. Files shared by several attachments are written once.
+func Write(w io.Writer, a *models.BaseArchive, open func(file string) (io.ReadCloser, error)) error {
+	zw := zip.NewWriter(w)
+
+	manifest, err := zw.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(manifest).Encode(a); err != nil {
+		return err
+	}
+
+	written := make(map[string]bool)
+	for _, attachment := range attachments(a) {
+		if written[attachment.File] {
+			continue
+		}
+		written[attachment.File] = true
+		if err := writeFile(zw, attachment.File, open); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", attachment.Filename, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeFile(zw *zip.Writer, file string, open func(file string) (io.ReadCloser, error)) error {
+	// Most attachments are compressed formats already, so they are stored as they are
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: filesDir + file, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	r, err := open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// Reader reads a base archive
+type Reader struct {
+	Archive *models.BaseArchive
+	files   map[string]*zip.File
+}
+
+// Read opens a base archive of size bytes, decoding its base and checking that
+// the content of every attachment is there
+func Read(r io.ReaderAt, size int64) (*Reader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	var manifest *zip.File
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		if f.Name == manifestName {
+			manifest = f
+		} else if file, ok := strings.CutPrefix(f.Name, filesDir); ok {
+			files[file] = f
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, manifestName)
+	}
+
+	rc, err := manifest.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer rc.Close()
+
+	var a models.BaseArchive
+	if err := json.NewDecoder(io.LimitReader(rc, maxManifestSize)).Decode(&a); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if a.Version < 1 || a.Version > models.BaseArchiveVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedVersion, a.Version)
+	}
+	for _, attachment := range attachments(&a) {
+		if files[attachment.File] == nil {
+			return nil, fmt.Errorf("%w: the content of %s is missing", ErrInvalidArchive, attachment.Filename)
+		}
+	}
+
+	return &Reader{Archive: &a, files: files}, nil
+}
+
+// Size returns the uncompressed size the archive declares for an attachment file
+func (r *Reader) Size(file string) int64 {
+	f, ok := r.files[file]
+	if !ok {
+		return 0
+	}
+	if f.UncompressedSize64 > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(f.UncompressedSize64)
+}
+
+// Open opens the content of an attachment file. Reading fails once more than the
+// file's declared size comes out, so checks made against Size hold.
+func (r *Reader) Open(file string) (io.ReadCloser, error) {
+	f, ok := r.files[file]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, file)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	size := r.Size(file)
+	limit := size
+	if limit < math.MaxInt64 {
+		limit++ // One byte over is enough to tell the entry is too large
+	}
+	return &sizedFile{ReadCloser: rc, r: io.LimitReader(rc, limit), remaining: size, name: file}, nil
+}
+
+// sizedFile reads an archive entry, failing if it is larger than it declared
+type sizedFile struct {
+	io.ReadCloser
+	r         io.Reader
+	remaining int64
+	name      string
+}
+
+func (f *sizedFile) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.remaining -= int64(n)
+	if f.remaining < 0 {
+		return n, fmt.Errorf("%w: %s is larger than declared", ErrInvalidArchive, f.name)
+	}
+	return n, err
+}
+
+// attachments returns every attachment in an archive
+func attachments(a *models.BaseArchive) []models.ArchiveAttachment {
+	var all []models.ArchiveAttachment
+	for _, table := range a.Tables {
+		for _, record := range table.Records {
+			all = append(all, record.Attachments...)
+		}
+	}
+	return all
+}
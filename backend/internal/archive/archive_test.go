@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+)
+
+func testArchive() *models.BaseArchive {
+	attachment := func(filename, file string) models.ArchiveAttachment {
+		return models.ArchiveAttachment{ID: uuid.New(), FieldID: uuid.New(), Filename: filename, File: file}
+	}
+	return &models.BaseArchive{
+		Version: models.BaseArchiveVersion,
+		Base:    models.ArchiveBase{ID: uuid.New(), Name: "CRM"},
+		Tables: []models.ArchiveTable{{
+			ID:   uuid.New(),
+			Name: "Contacts",
+			Records: []models.ArchiveRecord{
+				{ID: uuid.New(), Attachments: []models.ArchiveAttachment{attachment("a.txt", "blobs/aa/aa1")}},
+				{ID: uuid.New(), Attachments: []models.ArchiveAttachment{attachment("copy.txt", "blobs/aa/aa1"), attachment("b.txt", "blobs/bb/bb2")}},
+			},
+		}},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	files := map[string]string{"blobs/aa/aa1": "hello", "blobs/bb/bb2": "world"}
+	opened := map[string]int{}
+	open := func(file string) (io.ReadCloser, error) {
+		opened[file]++
+		return io.NopCloser(strings.NewReader(files[file])), nil
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, testArchive(), open))
+	assert.Equal(t, map[string]int{"blobs/aa/aa1": 1, "blobs/bb/bb2": 1}, opened, "shared files are written once")
+
+	r, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, "CRM", r.Archive.Base.Name)
+	require.Len(t, r.Archive.Tables[0].Records, 2)
+
+	rc, err := r.Open("blobs/bb/bb2")
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(data))
+	assert.Equal(t, int64(5), r.Size("blobs/bb/bb2"))
+}
+
+func TestOpen_LargerThanDeclared(t *testing.T) {
+	manifest, err := json.Marshal(testArchive())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(manifestName)
+	require.NoError(t, err)
+	w.Write(manifest)
+	for _, file := range []string{"blobs/aa/aa1", "blobs/bb/bb2"} {
+		content := strings.Repeat("x", 100)
+		w, err := zw.CreateRaw(&zip.FileHeader{
+			Name:               filesDir + file,
+			Method:             zip.Store,
+			CRC32:              crc32.ChecksumIEEE([]byte(content)),
+			CompressedSize64:   uint64(len(content)),
+			UncompressedSize64: 10,
+		})
+		require.NoError(t, err)
+		w.Write([]byte(content))
+	}
+	require.NoError(t, zw.Close())
+
+	r, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), r.Size("blobs/aa/aa1"))
+
+	rc, err := r.Open("blobs/aa/aa1")
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	assert.Error(t, err)
+	assert.LessOrEqual(t, len(data), 11)
+}
+
+func TestWrite_OpenError(t *testing.T) {
+	open := func(file string) (io.ReadCloser, error) {
+		return nil, errors.New("download failed")
+	}
+	err := Write(io.Discard, testArchive(), open)
+	assert.ErrorContains(t, err, "download failed")
+}
+
+func TestRead_Invalid(t *testing.T) {
+	build := func(entries map[string]string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, content := range entries {
+			w, err := zw.Create(name)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"not a zip file", []byte("hello"), ErrInvalidArchive},
+		{"missing base.json", build(map[string]string{"files/x": "x"}), ErrInvalidArchive},
+		{"malformed base.json", build(map[string]string{"base.json": "{"}), ErrInvalidArchive},
+		{"newer version", build(map[string]string{"base.json": `{"version": 99}`}), ErrUnsupportedVersion},
+		{"missing attachment content", build(map[string]string{
+			"base.json": `{"version": 1, "tables": [{"records": [{"attachments": [{"filename": "a.txt", "file": "blobs/aa/aa1"}]}]}]}`,
+		}), ErrInvalidArchive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.data), int64(len(tt.data)))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
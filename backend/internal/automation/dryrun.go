@@ -0,0 +1,361 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+)
+
+// ErrRecordNotInTable is returned when a test record belongs to a different table than the automation
+var ErrRecordNotInTable = errors.New("record does not belong to the automation's table")
+
+// maxDryRunResponseBytes caps how much of a webhook response is returned from a test run
+const maxDryRunResponseBytes = 64 * 1024
+
+// DryRunOptions controls what a test run is allowed to do
+type DryRunOptions struct {
+	RecordID  *uuid.UUID             // Record to run the automation against
+	OldValues map[string]interface{} // Optional previous values, to simulate an update
+	Payload   json.RawMessage        // Sample inbound body for webhook_received triggers
+	AllowHTTP bool                   // Actually send outbound requests for send_webhook
+}
+
+// DryRunRequest describes the outbound HTTP request a send_webhook action would make
+type DryRunRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// DryRunResult describes what an automation would do for a given record
+type DryRunResult struct {
+	AutomationID      uuid.UUID              `json:"automationId"`
+	RecordID          *uuid.UUID             `json:"recordId,omitempty"`
+	ConditionsMet     bool                   `json:"conditionsMet"`
+	ActionType        models.ActionType      `json:"actionType"`
+	ResolvedTemplates map[string]string      `json:"resolvedTemplates,omitempty"`
+	Values            map[string]interface{} `json:"values,omitempty"`
+	TargetTableID     *uuid.UUID             `json:"targetTableId,omitempty"`
+	TargetRecordID    *uuid.UUID             `json:"targetRecordId,omitempty"`
+	TargetRecordIDs   []uuid.UUID            `json:"targetRecordIds,omitempty"`
+	Request           *DryRunRequest         `json:"request,omitempty"`
+	Response          map[string]interface{} `json:"response,omitempty"`
+	Errors            []string               `json:"errors"`
+}
+
+// DryRun evaluates an automation against a record without writing anything.
+// Trigger conditions are checked and the action's templates and values are resolved;
+// the outbound request of a send_webhook action is only sent when opts.AllowHTTP is set.
+func (e *Engine) DryRun(ctx context.Context, automation models.Automation, opts DryRunOptions, userID uuid.UUID) (*DryRunResult, error) {
+	triggerCtx := &TriggerContext{
+		TableID:     automation.TableID,
+		TriggerType: automation.TriggerType,
+		UserID:      userID,
+		Payload:     opts.Payload,
+	}
+
+	if opts.RecordID != nil {
+		record, err := e.recordStore.GetRecord(ctx, *opts.RecordID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if record.TableID != automation.TableID {
+			return nil, ErrRecordNotInTable
+		}
+		triggerCtx.RecordID = &record.ID
+		triggerCtx.Record = record
+
+		if opts.OldValues != nil {
+			oldValues, err := json.Marshal(opts.OldValues)
+			if err != nil {
+				return nil, err
+			}
+			oldRecord := *record
+			oldRecord.Values = oldValues
+			triggerCtx.OldRecord = &oldRecord
+		}
+	}
+
+	result := &DryRunResult{
+		AutomationID: automation.ID,
+		RecordID:     triggerCtx.RecordID,
+		ActionType:   automation.ActionType,
+		Errors:       []string{},
+	}
+
+	result.Errors = append(result.Errors, ValidateTriggerConfig(automation.TriggerType, automation.TriggerConfig)...)
+	result.Errors = append(result.Errors, ValidateActionConfig(automation.ActionType, automation.ActionConfig)...)
+	result.Errors = append(result.Errors, e.validateFieldReferences(ctx, automation, userID)...)
+
+	result.ConditionsMet = e.checkTriggerConditions(automation, triggerCtx)
+	if automation.TriggerType == models.TriggerRecordEntersView && e.viewStore != nil {
+		entered, err := e.recordEntersView(ctx, automation, triggerCtx)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		result.ConditionsMet = entered
+	}
+
+	e.dryRunAction(ctx, automation, triggerCtx, opts, result)
+
+	return result, nil
+}
+
+// dryRunAction resolves what the automation's action would do and records it on result
+func (e *Engine) dryRunAction(ctx context.Context, automation models.Automation, triggerCtx *TriggerContext, opts DryRunOptions, result *DryRunResult) {
+	tmpl := e.newTemplateScope(ctx, automation, triggerCtx)
+	switch automation.ActionType {
+	case models.ActionSendEmail:
+		var config models.SendEmailConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+			return
+		}
+		result.ResolvedTemplates = map[string]string{
+			"to":      tmpl.render(config.To),
+			"subject": tmpl.render(config.Subject),
+			"body":    tmpl.render(config.Body),
+		}
+
+	case models.ActionUpdateRecord:
+		var config models.UpdateRecordConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+			return
+		}
+		if triggerCtx.RecordID == nil {
+			result.Errors = append(result.Errors, "no record to update: choose a record to test against")
+		}
+		result.TargetRecordID = triggerCtx.RecordID
+		result.Values = buildFieldValues(config.Updates, tmpl)
+
+	case models.ActionCreateRecord:
+		var config models.CreateRecordConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+			return
+		}
+		result.TargetTableID = &config.TargetTableID
+		result.Values = buildFieldValues(config.Values, tmpl)
+
+	case models.ActionSendWebhook:
+		var config models.SendWebhookConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+			return
+		}
+		req, body, err := e.buildWebhookRequest(ctx, config, tmpl)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return
+		}
+
+		headers := make(map[string]string, len(req.Header))
+		for key := range req.Header {
+			headers[key] = req.Header.Get(key)
+		}
+		result.Request = &DryRunRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: headers,
+			Body:    body,
+		}
+		if body != "" && strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") && !json.Valid([]byte(body)) {
+			result.Errors = append(result.Errors, "resolved webhook body is not valid JSON")
+		}
+
+		if !opts.AllowHTTP {
+			return
+		}
+		resp, err := e.httpClient.Do(req)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("webhook request failed: %v", err))
+			return
+		}
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxDryRunResponseBytes))
+		result.Response = map[string]interface{}{
+			"status":     resp.StatusCode,
+			"statusText": resp.Status,
+			"body":       string(respBody),
+		}
+
+	case models.ActionDeleteRecord:
+		if triggerCtx.RecordID == nil {
+			result.Errors = append(result.Errors, "no record to delete: choose a record to test against")
+		}
+		result.TargetRecordID = triggerCtx.RecordID
+
+	case models.ActionAddComment:
+		var config models.AddCommentConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+			return
+		}
+		if triggerCtx.RecordID == nil {
+			result.Errors = append(result.Errors, "no record to comment on: choose a record to test against")
+		}
+		result.TargetRecordID = triggerCtx.RecordID
+		result.ResolvedTemplates = map[string]string{
+			"content": tmpl.render(config.Content),
+		}
+
+	case models.ActionUpdateLinkedRecords:
+		var config models.UpdateLinkedRecordsConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+			return
+		}
+		result.Values = buildFieldValues(config.Updates, tmpl)
+		if triggerCtx.Record == nil {
+			result.Errors = append(result.Errors, "no record to follow links from: choose a record to test against")
+			return
+		}
+		if e.fieldStore == nil {
+			return
+		}
+		linkedTableID, recordIDs, err := e.linkedRecordIDs(ctx, automation, config, triggerCtx)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return
+		}
+		result.TargetTableID = &linkedTableID
+		result.TargetRecordIDs = recordIDs
+
+	case models.ActionFindRecords:
+		var config models.FindRecordsConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+			return
+		}
+		result.TargetTableID = &config.TableID
+		if len(config.Updates) > 0 {
+			result.Values = buildFieldValues(config.Updates, tmpl)
+		}
+		if e.recordStore == nil {
+			return
+		}
+		matches, err := e.findRecords(ctx, automation, config, tmpl)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return
+		}
+		result.TargetRecordIDs = make([]uuid.UUID, len(matches))
+		for i, record := range matches {
+			result.TargetRecordIDs[i] = record.ID
+		}
+	}
+}
+
+// validateFieldReferences checks that fields named in the trigger and action configs exist
+// and can be written to
+func (e *Engine) validateFieldReferences(ctx context.Context, automation models.Automation, userID uuid.UUID) []string {
+	if e.fieldStore == nil {
+		return nil
+	}
+
+	var errs []string
+	fieldsByTable := make(map[uuid.UUID]map[uuid.UUID]models.Field)
+	lookup := func(tableID uuid.UUID) (map[uuid.UUID]models.Field, error) {
+		if fields, ok := fieldsByTable[tableID]; ok {
+			return fields, nil
+		}
+		list, err := e.fieldStore.ListFieldsForTable(ctx, tableID, userID)
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[uuid.UUID]models.Field, len(list))
+		for _, f := range list {
+			fields[f.ID] = f
+		}
+		fieldsByTable[tableID] = fields
+		return fields, nil
+	}
+
+	checkWritable := func(path string, tableID uuid.UUID, updates []models.FieldUpdate) {
+		fields, err := lookup(tableID)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: cannot load fields for table %s: %v", path, tableID, err))
+			return
+		}
+		for i, update := range updates {
+			if update.FieldID == uuid.Nil {
+				continue
+			}
+			field, ok := fields[update.FieldID]
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%s[%d].fieldId %s does not exist in the target table", path, i, update.FieldID))
+				continue
+			}
+			if models.IsComputedField(field.FieldType) {
+				errs = append(errs, fmt.Sprintf("%s[%d]: field %q is computed and cannot be written", path, i, field.Name))
+			}
+		}
+	}
+
+	if automation.TriggerType == models.TriggerFieldValueChanged {
+		var config models.FieldValueChangedConfig
+		if err := json.Unmarshal(automation.TriggerConfig, &config); err == nil && config.FieldID != uuid.Nil {
+			if fields, err := lookup(automation.TableID); err == nil {
+				if _, ok := fields[config.FieldID]; !ok {
+					errs = append(errs, fmt.Sprintf("triggerConfig.fieldId %s does not exist in this table", config.FieldID))
+				}
+			}
+		}
+	}
+
+	switch automation.ActionType {
+	case models.ActionUpdateRecord:
+		var config models.UpdateRecordConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err == nil {
+			checkWritable("actionConfig.updates", automation.TableID, config.Updates)
+		}
+	case models.ActionCreateRecord:
+		var config models.CreateRecordConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err == nil && config.TargetTableID != uuid.Nil {
+			checkWritable("actionConfig.values", config.TargetTableID, config.Values)
+		}
+	case models.ActionUpdateLinkedRecords:
+		var config models.UpdateLinkedRecordsConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err != nil || config.LinkedFieldID == uuid.Nil {
+			break
+		}
+		fields, err := lookup(automation.TableID)
+		if err != nil {
+			break
+		}
+		field, ok := fields[config.LinkedFieldID]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("actionConfig.linkedFieldId %s does not exist in this table", config.LinkedFieldID))
+			break
+		}
+		var options models.FieldOptions
+		if len(field.Options) > 0 {
+			json.Unmarshal(field.Options, &options)
+		}
+		if field.FieldType != models.FieldTypeLinkedRecord || options.LinkedTableID == nil {
+			errs = append(errs, fmt.Sprintf("actionConfig.linkedFieldId: field %q is not a linked record field", field.Name))
+			break
+		}
+		checkWritable("actionConfig.updates", *options.LinkedTableID, config.Updates)
+	case models.ActionFindRecords:
+		var config models.FindRecordsConfig
+		if err := json.Unmarshal(automation.ActionConfig, &config); err != nil || config.TableID == uuid.Nil {
+			break
+		}
+		if fields, err := lookup(config.TableID); err == nil {
+			for i, condition := range config.Conditions {
+				if condition.FieldID == uuid.Nil {
+					continue
+				}
+				if _, ok := fields[condition.FieldID]; !ok {
+					errs = append(errs, fmt.Sprintf("actionConfig.conditions[%d].fieldId %s does not exist in the target table", i, condition.FieldID))
+				}
+			}
+		}
+		checkWritable("actionConfig.updates", config.TableID, config.Updates)
+	}
+
+	return errs
+}
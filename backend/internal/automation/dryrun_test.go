@@ -0,0 +1,258 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+)
+
+func TestValidateTriggerConfig(t *testing.T) {
+	t.Run("accepts record triggers without config", func(t *testing.T) {
+		errs := ValidateTriggerConfig(models.TriggerRecordCreated, json.RawMessage(`{}`))
+		assert.Empty(t, errs)
+	})
+
+	t.Run("requires field ID for field_value_changed", func(t *testing.T) {
+		errs := ValidateTriggerConfig(models.TriggerFieldValueChanged, json.RawMessage(`{}`))
+		assert.Contains(t, errs, "triggerConfig.fieldId is required")
+	})
+
+	t.Run("rejects unknown operator", func(t *testing.T) {
+		config := json.RawMessage(`{"fieldId": "` + uuid.New().String() + `", "operator": "greater_than"}`)
+		errs := ValidateTriggerConfig(models.TriggerFieldValueChanged, config)
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0], "greater_than")
+	})
+
+	t.Run("rejects unknown trigger type", func(t *testing.T) {
+		errs := ValidateTriggerConfig("unknown", json.RawMessage(`{}`))
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0], "unknown trigger type")
+	})
+}
+
+func TestValidateActionConfig(t *testing.T) {
+	t.Run("reports invalid JSON", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionSendEmail, json.RawMessage(`{invalid}`))
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0], "invalid JSON")
+	})
+
+	t.Run("requires email recipient and subject", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionSendEmail, json.RawMessage(`{"body": "Hi"}`))
+		assert.Contains(t, errs, "actionConfig.to is required")
+		assert.Contains(t, errs, "actionConfig.subject is required")
+	})
+
+	t.Run("requires at least one update", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionUpdateRecord, json.RawMessage(`{"updates": []}`))
+		assert.Contains(t, errs, "actionConfig.updates must contain at least one field")
+	})
+
+	t.Run("requires target table for create_record", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionCreateRecord, json.RawMessage(`{"values": [{"value": "x"}]}`))
+		assert.Contains(t, errs, "actionConfig.targetTableId is required")
+		assert.Contains(t, errs, "actionConfig.values[0].fieldId is required")
+	})
+
+	t.Run("validates webhook URL and method", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionSendWebhook, json.RawMessage(`{"url": "ftp://example.com", "method": "DELETE"}`))
+		assert.Contains(t, errs, "actionConfig.url must be an absolute http or https URL")
+		require.Len(t, errs, 2)
+		assert.Contains(t, errs[1], "DELETE")
+	})
+
+	t.Run("accepts valid webhook config", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionSendWebhook, json.RawMessage(`{"url": "https://example.com/hook", "method": "post"}`))
+		assert.Empty(t, errs)
+	})
+
+	t.Run("requires comment content", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionAddComment, json.RawMessage(`{"content": "  "}`))
+		assert.Contains(t, errs, "actionConfig.content is required")
+	})
+
+	t.Run("requires linked field and updates for update_linked_records", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionUpdateLinkedRecords, json.RawMessage(`{}`))
+		assert.Contains(t, errs, "actionConfig.linkedFieldId is required")
+		assert.Contains(t, errs, "actionConfig.updates must contain at least one field")
+	})
+
+	t.Run("validates find_records conditions", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionFindRecords, json.RawMessage(`{"conditions": [{"operator": "like"}], "limit": 5000}`))
+		assert.Contains(t, errs, "actionConfig.tableId is required")
+		assert.Contains(t, errs, "actionConfig.conditions[0].fieldId is required")
+		assert.Contains(t, errs, `actionConfig.conditions[0].operator "like" is not supported`)
+		assert.Contains(t, errs, "actionConfig.limit must be between 1 and 1000")
+	})
+
+	t.Run("accepts delete_record without config", func(t *testing.T) {
+		errs := ValidateActionConfig(models.ActionDeleteRecord, json.RawMessage(`{}`))
+		assert.Empty(t, errs)
+	})
+}
+
+func TestValidateActionTemplates(t *testing.T) {
+	t.Run("accepts incomplete configs with valid templates", func(t *testing.T) {
+		errs := ValidateActionTemplates(models.ActionSendEmail, json.RawMessage(`{"subject": "{{field:Name | upper}}"}`))
+		assert.Empty(t, errs)
+	})
+
+	t.Run("reports template problems", func(t *testing.T) {
+		errs := ValidateActionTemplates(models.ActionFindRecords, json.RawMessage(`{"conditions": [{"value": "{{payload:id | shout}}"}], "updates": [{"value": "{{/if}}"}]}`))
+		assert.Equal(t, []string{
+			`actionConfig.conditions[0].value: unknown filter "shout"`,
+			"actionConfig.updates[0].value: unexpected {{/if}}",
+		}, errs)
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("resolves email templates without sending", func(t *testing.T) {
+		engine := NewEngine(nil, nil, nil)
+		automation := models.Automation{
+			ID:            uuid.New(),
+			TriggerType:   models.TriggerRecordCreated,
+			TriggerConfig: json.RawMessage(`{}`),
+			ActionType:    models.ActionSendEmail,
+			ActionConfig:  json.RawMessage(`{"to": "test@example.com", "subject": "New record", "body": "Hello"}`),
+		}
+
+		result, err := engine.DryRun(ctx, automation, DryRunOptions{}, uuid.New())
+		require.NoError(t, err)
+		assert.True(t, result.ConditionsMet)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, "test@example.com", result.ResolvedTemplates["to"])
+		assert.Equal(t, "New record", result.ResolvedTemplates["subject"])
+	})
+
+	t.Run("reports values for create_record", func(t *testing.T) {
+		engine := NewEngine(nil, nil, nil)
+		targetTableID := uuid.New()
+		fieldID := uuid.New()
+		automation := models.Automation{
+			TriggerType:   models.TriggerRecordCreated,
+			TriggerConfig: json.RawMessage(`{}`),
+			ActionType:    models.ActionCreateRecord,
+			ActionConfig:  json.RawMessage(`{"targetTableId": "` + targetTableID.String() + `", "values": [{"fieldId": "` + fieldID.String() + `", "value": "Static"}]}`),
+		}
+
+		result, err := engine.DryRun(ctx, automation, DryRunOptions{}, uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, &targetTableID, result.TargetTableID)
+		assert.Equal(t, "Static", result.Values[fieldID.String()])
+	})
+
+	t.Run("flags update_record without a record", func(t *testing.T) {
+		engine := NewEngine(nil, nil, nil)
+		automation := models.Automation{
+			TriggerType:   models.TriggerRecordUpdated,
+			TriggerConfig: json.RawMessage(`{}`),
+			ActionType:    models.ActionUpdateRecord,
+			ActionConfig:  json.RawMessage(`{"updates": [{"fieldId": "` + uuid.New().String() + `", "value": "Done"}]}`),
+		}
+
+		result, err := engine.DryRun(ctx, automation, DryRunOptions{}, uuid.New())
+		require.NoError(t, err)
+		assert.Contains(t, result.Errors, "no record to update: choose a record to test against")
+	})
+
+	t.Run("does not send webhook unless allowed", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		engine := &Engine{httpClient: server.Client()}
+		automation := models.Automation{
+			TriggerType:   models.TriggerRecordCreated,
+			TriggerConfig: json.RawMessage(`{}`),
+			ActionType:    models.ActionSendWebhook,
+			ActionConfig:  json.RawMessage(`{"url": "` + server.URL + `", "body": "{\"ok\": true}"}`),
+		}
+
+		result, err := engine.DryRun(ctx, automation, DryRunOptions{}, uuid.New())
+		require.NoError(t, err)
+		assert.False(t, called)
+		require.NotNil(t, result.Request)
+		assert.Equal(t, "POST", result.Request.Method)
+		assert.Equal(t, `{"ok": true}`, result.Request.Body)
+		assert.Nil(t, result.Response)
+	})
+
+	t.Run("sends webhook when allowed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("queued"))
+		}))
+		defer server.Close()
+
+		engine := &Engine{httpClient: server.Client()}
+		automation := models.Automation{
+			TriggerType:   models.TriggerRecordCreated,
+			TriggerConfig: json.RawMessage(`{}`),
+			ActionType:    models.ActionSendWebhook,
+			ActionConfig:  json.RawMessage(`{"url": "` + server.URL + `", "body": "{}"}`),
+		}
+
+		result, err := engine.DryRun(ctx, automation, DryRunOptions{AllowHTTP: true}, uuid.New())
+		require.NoError(t, err)
+		require.NotNil(t, result.Response)
+		assert.Equal(t, http.StatusAccepted, result.Response["status"])
+		assert.Equal(t, "queued", result.Response["body"])
+	})
+
+	t.Run("resolves comment content", func(t *testing.T) {
+		engine := NewEngine(nil, nil, nil)
+		automation := models.Automation{
+			TriggerType:   models.TriggerWebhookReceived,
+			TriggerConfig: json.RawMessage(`{}`),
+			ActionType:    models.ActionAddComment,
+			ActionConfig:  json.RawMessage(`{"content": "Ticket {{payload:id}} opened"}`),
+		}
+
+		result, err := engine.DryRun(ctx, automation, DryRunOptions{Payload: json.RawMessage(`{"id": 7}`)}, uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, "Ticket 7 opened", result.ResolvedTemplates["content"])
+		assert.Contains(t, result.Errors, "no record to comment on: choose a record to test against")
+	})
+
+	t.Run("flags delete_record without a record", func(t *testing.T) {
+		engine := NewEngine(nil, nil, nil)
+		automation := models.Automation{
+			TriggerType:   models.TriggerRecordUpdated,
+			TriggerConfig: json.RawMessage(`{}`),
+			ActionType:    models.ActionDeleteRecord,
+			ActionConfig:  json.RawMessage(`{}`),
+		}
+
+		result, err := engine.DryRun(ctx, automation, DryRunOptions{}, uuid.New())
+		require.NoError(t, err)
+		assert.Contains(t, result.Errors, "no record to delete: choose a record to test against")
+	})
+
+	t.Run("reports invalid JSON webhook body", func(t *testing.T) {
+		engine := NewEngine(nil, nil, nil)
+		automation := models.Automation{
+			TriggerType:   models.TriggerRecordCreated,
+			TriggerConfig: json.RawMessage(`{}`),
+			ActionType:    models.ActionSendWebhook,
+			ActionConfig:  json.RawMessage(`{"url": "https://example.com", "body": "{\"name\": \"unterminated}"}`),
+		}
+
+		result, err := engine.DryRun(ctx, automation, DryRunOptions{}, uuid.New())
+		require.NoError(t, err)
+		assert.Contains(t, result.Errors, "resolved webhook body is not valid JSON")
+	})
+}
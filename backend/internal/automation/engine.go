@@ -4,16 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"regexp"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/outbound"
 	"github.com/vibetable/backend/internal/store"
 )
 
@@ -22,21 +23,56 @@ type Engine struct {
 	automationStore *store.AutomationStore
 	recordStore     *store.RecordStore
 	fieldStore      *store.FieldStore
+	viewStore       *store.ViewStore
+	commentStore    *store.CommentStore
+	tableStore      *store.TableStore
+	authStore       *store.AuthStore
 	httpClient      *http.Client
+	appURL          string
 }
 
+// defaultFindRecordsLimit and maxFindRecordsLimit bound how many records find_records touches
+const (
+	defaultFindRecordsLimit = 100
+	maxFindRecordsLimit     = 1000
+)
+
 // NewEngine creates a new automation engine
 func NewEngine(automationStore *store.AutomationStore, recordStore *store.RecordStore, fieldStore *store.FieldStore) *Engine {
 	return &Engine{
 		automationStore: automationStore,
 		recordStore:     recordStore,
 		fieldStore:      fieldStore,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient:      outbound.DefaultPolicy().Client(),
 	}
 }
 
+// SetOutboundPolicy sets the policy that restricts which destinations send_webhook may reach
+func (e *Engine) SetOutboundPolicy(policy outbound.Policy) {
+	e.httpClient = policy.Client()
+}
+
+// SetViewStore sets the view store used to evaluate record_enters_view triggers
+func (e *Engine) SetViewStore(viewStore *store.ViewStore) {
+	e.viewStore = viewStore
+}
+
+// SetCommentStore sets the comment store used by add_comment actions
+func (e *Engine) SetCommentStore(commentStore *store.CommentStore) {
+	e.commentStore = commentStore
+}
+
+// SetTemplateStores sets the stores used to resolve {{record.url}} and {{user.*}} template tags
+func (e *Engine) SetTemplateStores(tableStore *store.TableStore, authStore *store.AuthStore) {
+	e.tableStore = tableStore
+	e.authStore = authStore
+}
+
+// SetAppURL sets the frontend URL used to build {{record.url}} links
+func (e *Engine) SetAppURL(appURL string) {
+	e.appURL = appURL
+}
+
 // TriggerContext contains information about what triggered the automation
 type TriggerContext struct {
 	TableID     uuid.UUID
@@ -45,6 +81,9 @@ type TriggerContext struct {
 	OldRecord   *models.Record // For updates
 	TriggerType models.TriggerType
 	UserID      uuid.UUID
+	FormID      *uuid.UUID      // For form_submitted
+	Comment     *models.Comment // For comment_added
+	Payload     json.RawMessage // For webhook_received: the inbound request body
 }
 
 // ProcessTrigger finds and executes all matching automations
@@ -59,6 +98,116 @@ func (e *Engine) ProcessTrigger(ctx context.Context, triggerCtx *TriggerContext)
 	for _, automation := range automations {
 		go e.executeAutomation(ctx, automation, triggerCtx)
 	}
+
+	// Record changes may also move a record into a view
+	if triggerCtx.TriggerType == models.TriggerRecordCreated || triggerCtx.TriggerType == models.TriggerRecordUpdated {
+		e.processViewEntry(ctx, triggerCtx)
+	}
+}
+
+// processViewEntry runs record_enters_view automations whose view filters the record
+// matches now but did not match before the change
+func (e *Engine) processViewEntry(ctx context.Context, triggerCtx *TriggerContext) {
+	if e.viewStore == nil || triggerCtx.Record == nil {
+		return
+	}
+
+	automations, err := e.automationStore.GetAutomationsByTrigger(ctx, triggerCtx.TableID, models.TriggerRecordEntersView)
+	if err != nil {
+		log.Printf("[Automation] Error fetching view automations: %v", err)
+		return
+	}
+	if len(automations) == 0 {
+		return
+	}
+
+	for _, automation := range automations {
+		entered, err := e.recordEntersView(ctx, automation, triggerCtx)
+		if err != nil {
+			log.Printf("[Automation] Cannot evaluate view for %s: %v", automation.Name, err)
+			continue
+		}
+		if !entered {
+			continue
+		}
+
+		entryCtx := *triggerCtx
+		entryCtx.TriggerType = models.TriggerRecordEntersView
+		go e.executeAutomation(ctx, automation, &entryCtx)
+	}
+}
+
+// recordEntersView reports whether the triggering record matches the filters of the
+// automation's view and, for updates, did not match them before the change
+func (e *Engine) recordEntersView(ctx context.Context, automation models.Automation, triggerCtx *TriggerContext) (bool, error) {
+	if triggerCtx.Record == nil {
+		return false, nil
+	}
+
+	var config models.RecordEntersViewConfig
+	if err := json.Unmarshal(automation.TriggerConfig, &config); err != nil {
+		return false, fmt.Errorf("invalid trigger config: %w", err)
+	}
+
+	view, err := e.viewStore.GetView(ctx, config.ViewID, automation.CreatedBy)
+	if err != nil {
+		return false, fmt.Errorf("view %s unavailable: %w", config.ViewID, err)
+	}
+	if view.TableID != triggerCtx.TableID {
+		return false, fmt.Errorf("view %s belongs to a different table", config.ViewID)
+	}
+
+	var viewConfig models.ViewConfig
+	if len(view.Config) > 0 {
+		if err := json.Unmarshal(view.Config, &viewConfig); err != nil {
+			return false, fmt.Errorf("invalid view config: %w", err)
+		}
+	}
+
+	var newValues map[string]interface{}
+	if err := json.Unmarshal(triggerCtx.Record.Values, &newValues); err != nil {
+		return false, nil
+	}
+	if !viewConfig.MatchesRecord(newValues) {
+		return false, nil
+	}
+
+	if triggerCtx.OldRecord != nil {
+		var oldValues map[string]interface{}
+		json.Unmarshal(triggerCtx.OldRecord.Values, &oldValues)
+		if viewConfig.MatchesRecord(oldValues) {
+			return false, nil // Already in the view
+		}
+	}
+
+	return true, nil
+}
+
+// ProcessWebhook runs a webhook_received automation with the inbound request body.
+// If the body has a top-level "recordId" belonging to the automation's table, that
+// record becomes the triggering record so record actions can use it.
+func (e *Engine) ProcessWebhook(ctx context.Context, automation models.Automation, payload json.RawMessage) {
+	triggerCtx := &TriggerContext{
+		TableID:     automation.TableID,
+		TriggerType: models.TriggerWebhookReceived,
+		UserID:      automation.CreatedBy,
+		Payload:     payload,
+	}
+
+	var body struct {
+		RecordID string `json:"recordId"`
+	}
+	if err := json.Unmarshal(payload, &body); err == nil && body.RecordID != "" && e.recordStore != nil {
+		if recordID, err := uuid.Parse(body.RecordID); err == nil {
+			record, err := e.recordStore.GetRecord(ctx, recordID, automation.CreatedBy)
+			if err == nil && record.TableID == automation.TableID {
+				triggerCtx.RecordID = &record.ID
+				triggerCtx.Record = record
+			}
+		}
+	}
+
+	go e.executeAutomation(ctx, automation, triggerCtx)
 }
 
 // executeAutomation runs a single automation
@@ -72,10 +221,20 @@ func (e *Engine) executeAutomation(ctx context.Context, automation models.Automa
 	}
 
 	// Create run record
-	triggerData, _ := json.Marshal(map[string]interface{}{
+	data := map[string]interface{}{
 		"recordId": triggerCtx.RecordID,
 		"userId":   triggerCtx.UserID,
-	})
+	}
+	if triggerCtx.FormID != nil {
+		data["formId"] = triggerCtx.FormID
+	}
+	if triggerCtx.Comment != nil {
+		data["commentId"] = triggerCtx.Comment.ID
+	}
+	if len(triggerCtx.Payload) > 0 {
+		data["payload"] = triggerCtx.Payload
+	}
+	triggerData, _ := json.Marshal(data)
 
 	run := &models.AutomationRun{
 		AutomationID:    automation.ID,
@@ -112,7 +271,29 @@ func (e *Engine) executeAutomation(ctx context.Context, automation models.Automa
 
 // checkTriggerConditions checks if trigger-specific conditions are met
 func (e *Engine) checkTriggerConditions(automation models.Automation, triggerCtx *TriggerContext) bool {
-	if automation.TriggerType != models.TriggerFieldValueChanged {
+	switch automation.TriggerType {
+	case models.TriggerFieldValueChanged:
+		// Handled below
+	case models.TriggerFormSubmitted:
+		var config models.FormSubmittedConfig
+		if err := json.Unmarshal(automation.TriggerConfig, &config); err != nil {
+			return false
+		}
+		if config.FormID == nil {
+			return true
+		}
+		return triggerCtx.FormID != nil && *triggerCtx.FormID == *config.FormID
+	case models.TriggerCommentAdded:
+		var config models.CommentAddedConfig
+		if err := json.Unmarshal(automation.TriggerConfig, &config); err != nil {
+			return false
+		}
+		if config.Contains == "" {
+			return true
+		}
+		return triggerCtx.Comment != nil &&
+			strings.Contains(strings.ToLower(triggerCtx.Comment.Content), strings.ToLower(config.Contains))
+	default:
 		return true // No conditions for other trigger types
 	}
 
@@ -173,6 +354,14 @@ func (e *Engine) executeAction(ctx context.Context, automation models.Automation
 		return e.executeCreateRecord(ctx, automation, triggerCtx)
 	case models.ActionSendWebhook:
 		return e.executeSendWebhook(ctx, automation, triggerCtx)
+	case models.ActionDeleteRecord:
+		return e.executeDeleteRecord(ctx, automation, triggerCtx)
+	case models.ActionAddComment:
+		return e.executeAddComment(ctx, automation, triggerCtx)
+	case models.ActionUpdateLinkedRecords:
+		return e.executeUpdateLinkedRecords(ctx, automation, triggerCtx)
+	case models.ActionFindRecords:
+		return e.executeFindRecords(ctx, automation, triggerCtx)
 	default:
 		return nil, fmt.Errorf("unknown action type: %s", automation.ActionType)
 	}
@@ -185,10 +374,11 @@ func (e *Engine) executeSendEmail(ctx context.Context, automation models.Automat
 		return nil, fmt.Errorf("invalid email config: %w", err)
 	}
 
-	// Resolve field references in the config
-	to := e.resolveFieldReferences(config.To, triggerCtx)
-	subject := e.resolveFieldReferences(config.Subject, triggerCtx)
-	body := e.resolveFieldReferences(config.Body, triggerCtx)
+	// Resolve template tags in the config
+	tmpl := e.newTemplateScope(ctx, automation, triggerCtx)
+	to := tmpl.render(config.To)
+	subject := tmpl.render(config.Subject)
+	body := tmpl.render(config.Body)
 
 	// For now, just log the email (in production, use Resend/SendGrid/etc.)
 	log.Printf("[Automation] Would send email: to=%s, subject=%s, body=%s", to, subject, body)
@@ -211,16 +401,7 @@ func (e *Engine) executeUpdateRecord(ctx context.Context, automation models.Auto
 		return nil, fmt.Errorf("invalid update config: %w", err)
 	}
 
-	// Build the values map
-	values := make(map[string]interface{})
-	for _, update := range config.Updates {
-		value := update.Value
-		// If value is a string, resolve field references
-		if strVal, ok := value.(string); ok {
-			value = e.resolveFieldReferences(strVal, triggerCtx)
-		}
-		values[update.FieldID.String()] = value
-	}
+	values := buildFieldValues(config.Updates, e.newTemplateScope(ctx, automation, triggerCtx))
 
 	// Use the automation creator as the user for the update
 	record, err := e.recordStore.PatchRecordValues(ctx, *triggerCtx.RecordID, values, automation.CreatedBy)
@@ -238,16 +419,7 @@ func (e *Engine) executeCreateRecord(ctx context.Context, automation models.Auto
 		return nil, fmt.Errorf("invalid create config: %w", err)
 	}
 
-	// Build the values map
-	values := make(map[string]interface{})
-	for _, update := range config.Values {
-		value := update.Value
-		// If value is a string, resolve field references
-		if strVal, ok := value.(string); ok {
-			value = e.resolveFieldReferences(strVal, triggerCtx)
-		}
-		values[update.FieldID.String()] = value
-	}
+	values := buildFieldValues(config.Values, e.newTemplateScope(ctx, automation, triggerCtx))
 
 	valuesJSON, err := json.Marshal(values)
 	if err != nil {
@@ -270,28 +442,9 @@ func (e *Engine) executeSendWebhook(ctx context.Context, automation models.Autom
 		return nil, fmt.Errorf("invalid webhook config: %w", err)
 	}
 
-	// Resolve field references in the body
-	body := e.resolveFieldReferences(config.Body, triggerCtx)
-
-	// If no custom body, use the record as JSON
-	if body == "" && triggerCtx.Record != nil {
-		bodyBytes, _ := json.Marshal(triggerCtx.Record)
-		body = string(bodyBytes)
-	}
-
-	method := config.Method
-	if method == "" {
-		method = "POST"
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, config.URL, bytes.NewBufferString(body))
+	req, _, err := e.buildWebhookRequest(ctx, config, e.newTemplateScope(ctx, automation, triggerCtx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	for key, value := range config.Headers {
-		req.Header.Set(key, value)
+		return nil, err
 	}
 
 	resp, err := e.httpClient.Do(req)
@@ -309,31 +462,294 @@ func (e *Engine) executeSendWebhook(ctx context.Context, automation models.Autom
 	}, nil
 }
 
-// resolveFieldReferences replaces {{field:fieldId}} with actual values
-func (e *Engine) resolveFieldReferences(template string, triggerCtx *TriggerContext) string {
+// executeDeleteRecord deletes the triggering record
+func (e *Engine) executeDeleteRecord(ctx context.Context, automation models.Automation, triggerCtx *TriggerContext) (interface{}, error) {
+	if triggerCtx.RecordID == nil {
+		return nil, fmt.Errorf("no record to delete")
+	}
+	if triggerCtx.TriggerType == models.TriggerRecordDeleted {
+		return nil, fmt.Errorf("record has already been deleted")
+	}
+
+	// Use the automation creator as the user for the delete
+	if err := e.recordStore.DeleteRecord(ctx, *triggerCtx.RecordID, automation.CreatedBy); err != nil {
+		return nil, fmt.Errorf("failed to delete record: %w", err)
+	}
+
+	return map[string]interface{}{
+		"deletedRecordId": triggerCtx.RecordID,
+	}, nil
+}
+
+// executeAddComment posts a comment on the triggering record as the automation
+func (e *Engine) executeAddComment(ctx context.Context, automation models.Automation, triggerCtx *TriggerContext) (interface{}, error) {
+	if triggerCtx.RecordID == nil {
+		return nil, fmt.Errorf("no record to comment on")
+	}
+
+	var config models.AddCommentConfig
+	if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+		return nil, fmt.Errorf("invalid comment config: %w", err)
+	}
+
+	content := strings.TrimSpace(e.newTemplateScope(ctx, automation, triggerCtx).render(config.Content))
+	if content == "" {
+		return nil, fmt.Errorf("comment content is empty")
+	}
+	if e.commentStore == nil {
+		return nil, fmt.Errorf("comments are not available")
+	}
+
+	comment, err := e.commentStore.CreateAutomationComment(ctx, *triggerCtx.RecordID, automation.ID, automation.CreatedBy, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// executeUpdateLinkedRecords updates every record linked from the triggering record
+func (e *Engine) executeUpdateLinkedRecords(ctx context.Context, automation models.Automation, triggerCtx *TriggerContext) (interface{}, error) {
+	var config models.UpdateLinkedRecordsConfig
+	if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+		return nil, fmt.Errorf("invalid linked records config: %w", err)
+	}
+
+	linkedTableID, recordIDs, err := e.linkedRecordIDs(ctx, automation, config, triggerCtx)
+	if err != nil {
+		return nil, err
+	}
+	if len(recordIDs) > 0 {
+		if err := e.recordStore.CheckEditAccess(ctx, linkedTableID, automation.CreatedBy); err != nil {
+			return nil, fmt.Errorf("cannot update linked table: %w", err)
+		}
+	}
+
+	values := buildFieldValues(config.Updates, e.newTemplateScope(ctx, automation, triggerCtx))
+	updated, err := e.patchRecords(ctx, recordIDs, values, automation.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"recordIds": updated,
+		"updated":   len(updated),
+	}, nil
+}
+
+// executeFindRecords finds records matching the configured conditions and applies
+// the configured updates to each of them
+func (e *Engine) executeFindRecords(ctx context.Context, automation models.Automation, triggerCtx *TriggerContext) (interface{}, error) {
+	var config models.FindRecordsConfig
+	if err := json.Unmarshal(automation.ActionConfig, &config); err != nil {
+		return nil, fmt.Errorf("invalid find records config: %w", err)
+	}
+
+	tmpl := e.newTemplateScope(ctx, automation, triggerCtx)
+	matches, err := e.findRecords(ctx, automation, config, tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	recordIDs := make([]uuid.UUID, len(matches))
+	for i, record := range matches {
+		recordIDs[i] = record.ID
+	}
+
+	result := map[string]interface{}{
+		"matched":   len(recordIDs),
+		"recordIds": recordIDs,
+		"updated":   0,
+	}
+
+	if len(config.Updates) == 0 || len(recordIDs) == 0 {
+		return result, nil
+	}
+
+	if err := e.recordStore.CheckEditAccess(ctx, config.TableID, automation.CreatedBy); err != nil {
+		return nil, fmt.Errorf("cannot update records in table: %w", err)
+	}
+
+	values := buildFieldValues(config.Updates, tmpl)
+	updated, err := e.patchRecords(ctx, recordIDs, values, automation.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	result["updated"] = len(updated)
+
+	return result, nil
+}
+
+// linkedRecordIDs returns the linked table and the IDs of records linked from the
+// triggering record through the configured linked_record field
+func (e *Engine) linkedRecordIDs(ctx context.Context, automation models.Automation, config models.UpdateLinkedRecordsConfig, triggerCtx *TriggerContext) (uuid.UUID, []uuid.UUID, error) {
 	if triggerCtx.Record == nil {
-		return template
+		return uuid.Nil, nil, fmt.Errorf("no record to follow links from")
+	}
+
+	field, err := e.fieldStore.GetField(ctx, config.LinkedFieldID, automation.CreatedBy)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("linked field unavailable: %w", err)
+	}
+	if field.TableID != automation.TableID || field.FieldType != models.FieldTypeLinkedRecord {
+		return uuid.Nil, nil, fmt.Errorf("field %s is not a linked record field on this table", field.Name)
+	}
+
+	var options models.FieldOptions
+	if len(field.Options) > 0 {
+		json.Unmarshal(field.Options, &options)
+	}
+	if options.LinkedTableID == nil {
+		return uuid.Nil, nil, fmt.Errorf("field %s has no linked table", field.Name)
 	}
 
 	var recordValues map[string]interface{}
 	if err := json.Unmarshal(triggerCtx.Record.Values, &recordValues); err != nil {
-		return template
+		return uuid.Nil, nil, fmt.Errorf("invalid record values: %w", err)
 	}
 
-	// Match {{field:uuid}} pattern
-	re := regexp.MustCompile(`\{\{field:([a-f0-9-]+)\}\}`)
-	result := re.ReplaceAllStringFunc(template, func(match string) string {
-		fieldID := re.FindStringSubmatch(match)[1]
-		if value, exists := recordValues[fieldID]; exists {
-			return fmt.Sprintf("%v", value)
+	return *options.LinkedTableID, models.LinkedRecordIDs(recordValues[config.LinkedFieldID.String()]), nil
+}
+
+// findRecords returns records in the configured table matching every condition
+func (e *Engine) findRecords(ctx context.Context, automation models.Automation, config models.FindRecordsConfig, tmpl *templateScope) ([]models.Record, error) {
+	records, err := e.recordStore.ListRecordsForTable(ctx, config.TableID, automation.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+
+	filters := make([]models.ViewFilter, len(config.Conditions))
+	for i, condition := range config.Conditions {
+		filters[i] = models.ViewFilter{
+			FieldID:  condition.FieldID.String(),
+			Operator: condition.Operator,
+			Value:    tmpl.render(condition.Value),
 		}
-		return ""
-	})
+	}
+	matcher := models.ViewConfig{Filters: filters}
+
+	limit := config.Limit
+	if limit <= 0 {
+		limit = defaultFindRecordsLimit
+	}
+	if limit > maxFindRecordsLimit {
+		limit = maxFindRecordsLimit
+	}
+
+	var matches []models.Record
+	for _, record := range records {
+		// Never match the triggering record itself
+		if tmpl.trigger.RecordID != nil && record.ID == *tmpl.trigger.RecordID {
+			continue
+		}
+		var values map[string]interface{}
+		if err := json.Unmarshal(record.Values, &values); err != nil {
+			continue
+		}
+		if matcher.MatchesRecord(values) {
+			matches = append(matches, record)
+			if len(matches) >= limit {
+				break
+			}
+		}
+	}
+
+	return matches, nil
+}
 
-	// Also support {{recordId}}
-	if triggerCtx.RecordID != nil {
-		result = strings.ReplaceAll(result, "{{recordId}}", triggerCtx.RecordID.String())
+// patchRecords applies the same values to each record and returns the IDs updated
+func (e *Engine) patchRecords(ctx context.Context, recordIDs []uuid.UUID, values map[string]interface{}, userID uuid.UUID) ([]uuid.UUID, error) {
+	updated := []uuid.UUID{}
+	for _, recordID := range recordIDs {
+		if _, err := e.recordStore.PatchRecordValues(ctx, recordID, values, userID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue // Stale link
+			}
+			return updated, fmt.Errorf("failed to update record %s: %w", recordID, err)
+		}
+		updated = append(updated, recordID)
+	}
+	return updated, nil
+}
+
+// buildFieldValues converts configured field updates into a values map,
+// resolving template tags in string values
+func buildFieldValues(updates []models.FieldUpdate, tmpl *templateScope) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, update := range updates {
+		value := update.Value
+		// If value is a string, resolve template tags
+		if strVal, ok := value.(string); ok {
+			value = tmpl.render(strVal)
+		}
+		values[update.FieldID.String()] = value
 	}
+	return values
+}
 
-	return result
+// buildWebhookRequest prepares the outbound request for a send_webhook action
+// and returns it along with the resolved body
+func (e *Engine) buildWebhookRequest(ctx context.Context, config models.SendWebhookConfig, tmpl *templateScope) (*http.Request, string, error) {
+	// Resolve template tags in the body
+	body := tmpl.renderJSON(config.Body)
+
+	// If no custom body, use the record as JSON
+	if body == "" && tmpl.trigger.Record != nil {
+		bodyBytes, _ := json.Marshal(tmpl.trigger.Record)
+		body = string(bodyBytes)
+	}
+
+	method := config.Method
+	if method == "" {
+		method = "POST"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, config.URL, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	return req, body, nil
+}
+
+// lookupPayloadPath walks a decoded JSON value along a dot-separated path.
+// Numeric segments index into arrays.
+func lookupPayloadPath(value interface{}, path string) interface{} {
+	for _, segment := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			value = v[index]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// formatPayloadValue renders a payload value for substitution: strings as-is,
+// everything else as JSON
+func formatPayloadValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
 }
@@ -119,7 +119,7 @@ func TestResolveFieldReferences(t *testing.T) {
 			Record: nil,
 		}
 
-		result := engine.resolveFieldReferences("Hello {{field:123}}", ctx)
+		result := renderTemplate(engine, "Hello {{field:123}}", ctx)
 		assert.Equal(t, "Hello {{field:123}}", result)
 	})
 
@@ -131,7 +131,7 @@ func TestResolveFieldReferences(t *testing.T) {
 			},
 		}
 
-		result := engine.resolveFieldReferences("Hello {{field:"+fieldID.String()+"}}", ctx)
+		result := renderTemplate(engine, "Hello {{field:"+fieldID.String()+"}}", ctx)
 		assert.Equal(t, "Hello World", result)
 	})
 
@@ -142,7 +142,7 @@ func TestResolveFieldReferences(t *testing.T) {
 			},
 		}
 
-		result := engine.resolveFieldReferences("Hello {{field:"+uuid.New().String()+"}}", ctx)
+		result := renderTemplate(engine, "Hello {{field:"+uuid.New().String()+"}}", ctx)
 		assert.Equal(t, "Hello ", result)
 	})
 
@@ -155,7 +155,7 @@ func TestResolveFieldReferences(t *testing.T) {
 			},
 		}
 
-		result := engine.resolveFieldReferences("Record: {{recordId}}", ctx)
+		result := renderTemplate(engine, "Record: {{recordId}}", ctx)
 		assert.Equal(t, "Record: "+recordID.String(), result)
 	})
 }
@@ -224,6 +224,118 @@ func TestExecuteCreateRecord(t *testing.T) {
 	})
 }
 
+func TestExecuteDeleteRecord(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+
+	t.Run("returns error when no record ID", func(t *testing.T) {
+		automation := models.Automation{ActionType: models.ActionDeleteRecord}
+
+		_, err := engine.executeDeleteRecord(nil, automation, &TriggerContext{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "no record to delete")
+	})
+
+	t.Run("refuses to run on record_deleted triggers", func(t *testing.T) {
+		recordID := uuid.New()
+		automation := models.Automation{ActionType: models.ActionDeleteRecord}
+		ctx := &TriggerContext{RecordID: &recordID, TriggerType: models.TriggerRecordDeleted}
+
+		_, err := engine.executeDeleteRecord(nil, automation, ctx)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "already been deleted")
+	})
+}
+
+func TestExecuteAddComment(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+	recordID := uuid.New()
+
+	t.Run("returns error when no record ID", func(t *testing.T) {
+		automation := models.Automation{
+			ActionType:   models.ActionAddComment,
+			ActionConfig: json.RawMessage(`{"content": "Hi"}`),
+		}
+
+		_, err := engine.executeAddComment(nil, automation, &TriggerContext{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "no record to comment on")
+	})
+
+	t.Run("returns error for invalid config", func(t *testing.T) {
+		automation := models.Automation{
+			ActionType:   models.ActionAddComment,
+			ActionConfig: json.RawMessage(`{invalid}`),
+		}
+
+		_, err := engine.executeAddComment(nil, automation, &TriggerContext{RecordID: &recordID})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid comment config")
+	})
+
+	t.Run("returns error when content is blank", func(t *testing.T) {
+		automation := models.Automation{
+			ActionType:   models.ActionAddComment,
+			ActionConfig: json.RawMessage(`{"content": "   "}`),
+		}
+
+		_, err := engine.executeAddComment(nil, automation, &TriggerContext{RecordID: &recordID})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "comment content is empty")
+	})
+
+	t.Run("returns error without a comment store", func(t *testing.T) {
+		automation := models.Automation{
+			ActionType:   models.ActionAddComment,
+			ActionConfig: json.RawMessage(`{"content": "Needs review"}`),
+		}
+
+		_, err := engine.executeAddComment(nil, automation, &TriggerContext{RecordID: &recordID})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "comments are not available")
+	})
+}
+
+func TestExecuteUpdateLinkedRecords(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+
+	t.Run("returns error for invalid config", func(t *testing.T) {
+		automation := models.Automation{
+			ActionType:   models.ActionUpdateLinkedRecords,
+			ActionConfig: json.RawMessage(`{invalid}`),
+		}
+
+		_, err := engine.executeUpdateLinkedRecords(nil, automation, &TriggerContext{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid linked records config")
+	})
+
+	t.Run("returns error when no record", func(t *testing.T) {
+		automation := models.Automation{
+			ActionType:   models.ActionUpdateLinkedRecords,
+			ActionConfig: json.RawMessage(`{"linkedFieldId": "` + uuid.New().String() + `"}`),
+		}
+
+		_, err := engine.executeUpdateLinkedRecords(nil, automation, &TriggerContext{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "no record to follow links from")
+	})
+}
+
+func TestExecuteFindRecords(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+
+	t.Run("returns error for invalid config", func(t *testing.T) {
+		automation := models.Automation{
+			ActionType:   models.ActionFindRecords,
+			ActionConfig: json.RawMessage(`{invalid}`),
+		}
+
+		_, err := engine.executeFindRecords(nil, automation, &TriggerContext{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid find records config")
+	})
+}
+
 func TestExecuteSendEmail(t *testing.T) {
 	engine := NewEngine(nil, nil, nil)
 
@@ -699,7 +811,7 @@ func TestResolveFieldReferences_AdditionalCases(t *testing.T) {
 			},
 		}
 
-		result := engine.resolveFieldReferences("Hello {{field:123}}", ctx)
+		result := renderTemplate(engine, "Hello {{field:123}}", ctx)
 		assert.Equal(t, "Hello {{field:123}}", result)
 	})
 
@@ -713,7 +825,7 @@ func TestResolveFieldReferences_AdditionalCases(t *testing.T) {
 		}
 
 		template := "{{field:" + field1ID.String() + "}} {{field:" + field2ID.String() + "}}"
-		result := engine.resolveFieldReferences(template, ctx)
+		result := renderTemplate(engine, template, ctx)
 		assert.Equal(t, "Hello World", result)
 	})
 
@@ -725,7 +837,7 @@ func TestResolveFieldReferences_AdditionalCases(t *testing.T) {
 			},
 		}
 
-		result := engine.resolveFieldReferences("Value: {{field:"+fieldID.String()+"}}", ctx)
+		result := renderTemplate(engine, "Value: {{field:"+fieldID.String()+"}}", ctx)
 		assert.Equal(t, "Value: 42", result)
 	})
 }
@@ -758,3 +870,73 @@ func TestExecuteAutomation_Integration(t *testing.T) {
 	// and "handles CreateRun error" require complex database mocking and are covered through
 	// integration/E2E tests in production.
 }
+
+func TestCheckTriggerConditions_NewTriggers(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+
+	t.Run("form_submitted matches any form without config", func(t *testing.T) {
+		automation := models.Automation{
+			TriggerType:   models.TriggerFormSubmitted,
+			TriggerConfig: json.RawMessage(`{}`),
+		}
+		formID := uuid.New()
+		assert.True(t, engine.checkTriggerConditions(automation, &TriggerContext{FormID: &formID}))
+	})
+
+	t.Run("form_submitted restricted to configured form", func(t *testing.T) {
+		formID := uuid.New()
+		automation := models.Automation{
+			TriggerType:   models.TriggerFormSubmitted,
+			TriggerConfig: json.RawMessage(`{"formId": "` + formID.String() + `"}`),
+		}
+		otherFormID := uuid.New()
+		assert.True(t, engine.checkTriggerConditions(automation, &TriggerContext{FormID: &formID}))
+		assert.False(t, engine.checkTriggerConditions(automation, &TriggerContext{FormID: &otherFormID}))
+		assert.False(t, engine.checkTriggerConditions(automation, &TriggerContext{}))
+	})
+
+	t.Run("comment_added filters on text", func(t *testing.T) {
+		automation := models.Automation{
+			TriggerType:   models.TriggerCommentAdded,
+			TriggerConfig: json.RawMessage(`{"contains": "@ops"}`),
+		}
+		assert.True(t, engine.checkTriggerConditions(automation, &TriggerContext{
+			Comment: &models.Comment{Content: "Paging @OPS please"},
+		}))
+		assert.False(t, engine.checkTriggerConditions(automation, &TriggerContext{
+			Comment: &models.Comment{Content: "No mention"},
+		}))
+	})
+
+	t.Run("webhook_received has no conditions", func(t *testing.T) {
+		automation := models.Automation{
+			TriggerType:   models.TriggerWebhookReceived,
+			TriggerConfig: json.RawMessage(`{"token": "abc"}`),
+		}
+		assert.True(t, engine.checkTriggerConditions(automation, &TriggerContext{}))
+	})
+}
+
+func TestResolveFieldReferences_Payload(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+
+	ctx := &TriggerContext{
+		Payload: json.RawMessage(`{"customer": {"name": "Ada", "tags": ["vip", "beta"]}, "total": 12.5, "items": [{"sku": "A1"}]}`),
+	}
+
+	assert.Equal(t, "Hello Ada", renderTemplate(engine, "Hello {{payload:customer.name}}", ctx))
+	assert.Equal(t, "Total 12.5", renderTemplate(engine, "Total {{payload:total}}", ctx))
+	assert.Equal(t, "SKU A1", renderTemplate(engine, "SKU {{payload:items.0.sku}}", ctx))
+	assert.Equal(t, `Tags ["vip","beta"]`, renderTemplate(engine, "Tags {{payload:customer.tags}}", ctx))
+	assert.Equal(t, "Missing ", renderTemplate(engine, "Missing {{payload:customer.email}}", ctx))
+}
+
+func TestResolveFieldReferences_Comment(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+
+	ctx := &TriggerContext{
+		Comment: &models.Comment{Content: "Looks good"},
+	}
+
+	assert.Equal(t, "New comment: Looks good", renderTemplate(engine, "New comment: {{comment}}", ctx))
+}
@@ -0,0 +1,811 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+)
+
+// Templates in action configs support the following tags:
+//
+//	{{field:Name}} or {{field:<id>}}   a field of the triggering record, formatted for display
+//	{{field:Project->Owner}}           a field of the records linked through the Project field
+//	{{payload:path.to.value}}          a value from the inbound webhook body
+//	{{comment}}                        the comment that triggered the automation
+//	{{record.id}} {{record.url}} {{record.createdAt}} {{record.updatedAt}}
+//	{{user.id}} {{user.name}} {{user.email}}
+//	{{automation.id}} {{automation.name}} {{table.id}}
+//
+// Values can be piped through filters, e.g. {{field:Due | date:"Jan 2, 2006"}} or
+// {{field:Notes | json}}. Sections are rendered conditionally with
+// {{#if field:Status == "Done"}}...{{else}}...{{/if}} and {{#unless expr}}...{{/unless}}.
+// Tags that cannot be resolved, such as unknown fields, are left in place.
+//
+// In send_webhook bodies every value is JSON-escaped so it can be placed inside a
+// JSON string; use {{payload:customer | raw}} to insert a value unescaped.
+//
+// Webhook chat messages use the same syntax; there {{payload:...}} reads the event's
+// webhook payload, e.g. {{payload:event}} or {{payload:field.name}}.
+
+// maxTemplateLinkedRecords caps how many linked records a single lookup loads
+const maxTemplateLinkedRecords = 50
+
+// templateFilters lists the supported filters and whether they take an argument
+var templateFilters = map[string]bool{
+	"date":    true,
+	"number":  true,
+	"default": true,
+	"json":    false,
+	"raw":     false,
+	"url":     false,
+	"upper":   false,
+	"lower":   false,
+	"trim":    false,
+}
+
+// dateLayouts are the named layouts accepted by the date filter
+var dateLayouts = map[string]string{
+	"":         "Jan 2, 2006",
+	"date":     "Jan 2, 2006",
+	"datetime": "Jan 2, 2006 3:04 PM",
+	"time":     "3:04 PM",
+	"iso":      time.RFC3339,
+}
+
+type templateNode interface{}
+
+type textNode string
+
+type exprNode struct {
+	raw     string // Original tag, rendered when the expression cannot be resolved
+	source  string
+	filters []templateFilter
+}
+
+type templateFilter struct {
+	name string
+	arg  string
+}
+
+type ifNode struct {
+	expr      exprNode
+	operator  string // "", "==" or "!="
+	value     string
+	negate    bool
+	then      []templateNode
+	otherwise []templateNode
+}
+
+// templateValue is a resolved value: the raw decoded value and its display text
+type templateValue struct {
+	raw  interface{}
+	text string
+}
+
+type templateParser struct {
+	src  string
+	pos  int
+	errs []string
+}
+
+// parseTemplate parses a template into nodes, collecting syntax problems
+func parseTemplate(src string) ([]templateNode, []string) {
+	p := &templateParser{src: src}
+	nodes, closing := p.parse()
+	if closing != "" {
+		p.errs = append(p.errs, fmt.Sprintf("unexpected {{%s}}", closing))
+	}
+	return nodes, p.errs
+}
+
+// parse reads nodes until EOF or one of the given closing tags, which it returns
+func (p *templateParser) parse(stopAt ...string) ([]templateNode, string) {
+	var nodes []templateNode
+	for p.pos < len(p.src) {
+		start := strings.Index(p.src[p.pos:], "{{")
+		if start < 0 {
+			nodes = append(nodes, textNode(p.src[p.pos:]))
+			p.pos = len(p.src)
+			break
+		}
+		start += p.pos
+		end := strings.Index(p.src[start+2:], "}}")
+		if end < 0 {
+			nodes = append(nodes, textNode(p.src[p.pos:]))
+			p.pos = len(p.src)
+			break
+		}
+		end += start + 2
+
+		if start > p.pos {
+			nodes = append(nodes, textNode(p.src[p.pos:start]))
+		}
+		raw := p.src[start : end+2]
+		tag := strings.TrimSpace(p.src[start+2 : end])
+		p.pos = end + 2
+
+		for _, stop := range stopAt {
+			if tag == stop {
+				return nodes, tag
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(tag, "#if "), strings.HasPrefix(tag, "#unless "):
+			nodes = append(nodes, p.parseIf(tag))
+		case tag == "else", tag == "/if", tag == "/unless":
+			p.errs = append(p.errs, fmt.Sprintf("unexpected {{%s}}", tag))
+			nodes = append(nodes, textNode(raw))
+		default:
+			nodes = append(nodes, p.parseExpr(raw, tag))
+		}
+	}
+	return nodes, ""
+}
+
+// parseIf parses a conditional section whose opening tag has already been read
+func (p *templateParser) parseIf(tag string) *ifNode {
+	node := &ifNode{negate: strings.HasPrefix(tag, "#unless ")}
+	closeTag := "/if"
+	if node.negate {
+		closeTag = "/unless"
+	}
+	cond := strings.TrimSpace(tag[strings.Index(tag, " ")+1:])
+
+	if i := comparisonIndex(cond); i >= 0 {
+		node.operator = cond[i : i+2]
+		node.value = unquoteTemplateArg(strings.TrimSpace(cond[i+2:]))
+		cond = strings.TrimSpace(cond[:i])
+	}
+	node.expr = p.parseExpr("", cond)
+
+	var closing string
+	node.then, closing = p.parse("else", closeTag)
+	if closing == "else" {
+		node.otherwise, closing = p.parse(closeTag)
+	}
+	if closing == "" {
+		p.errs = append(p.errs, fmt.Sprintf("{{%s}} is missing {{%s}}", tag, closeTag))
+	}
+	return node
+}
+
+// parseExpr parses "source | filter:arg | filter"
+func (p *templateParser) parseExpr(raw, tag string) exprNode {
+	parts := splitOutsideQuotes(tag, '|')
+	expr := exprNode{raw: raw, source: strings.TrimSpace(parts[0])}
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		name, arg, hasArg := strings.Cut(part, ":")
+		name = strings.TrimSpace(name)
+		takesArg, known := templateFilters[name]
+		if !known {
+			p.errs = append(p.errs, fmt.Sprintf("unknown filter %q", name))
+			continue
+		}
+		if hasArg && !takesArg {
+			p.errs = append(p.errs, fmt.Sprintf("filter %q does not take an argument", name))
+		}
+		expr.filters = append(expr.filters, templateFilter{name: name, arg: unquoteTemplateArg(strings.TrimSpace(arg))})
+	}
+	return expr
+}
+
+// splitOutsideQuotes splits s on sep, ignoring separators inside double quotes
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	last := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && inQuotes:
+			i++
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, s[last:])
+}
+
+// comparisonIndex returns the position of the first == or != outside double quotes, or -1
+func comparisonIndex(s string) int {
+	inQuotes := false
+	for i := 0; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '\\' && inQuotes:
+			i++
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && (s[i] == '=' || s[i] == '!') && s[i+1] == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// unquoteTemplateArg strips surrounding double quotes from a filter or comparison argument
+func unquoteTemplateArg(arg string) string {
+	if len(arg) >= 2 && arg[0] == '"' && arg[len(arg)-1] == '"' {
+		if s, err := strconv.Unquote(arg); err == nil {
+			return s
+		}
+		return arg[1 : len(arg)-1]
+	}
+	return arg
+}
+
+// ValidateTemplate reports syntax problems in a template: unknown filters and
+// unbalanced conditional sections
+func ValidateTemplate(path, template string) []string {
+	_, errs := parseTemplate(template)
+	for i, err := range errs {
+		errs[i] = fmt.Sprintf("%s: %s", path, err)
+	}
+	return errs
+}
+
+// templateScope resolves templates for one automation run. Fields, linked records and
+// the triggering user are loaded lazily and cached for the lifetime of the scope.
+type templateScope struct {
+	ctx        context.Context
+	engine     *Engine
+	automation models.Automation
+	trigger    *TriggerContext
+
+	values     map[string]interface{}
+	payload    interface{}
+	hasPayload bool
+
+	fields  map[uuid.UUID][]models.Field
+	records map[uuid.UUID]*models.Record
+	user    *models.User
+}
+
+// newTemplateScope prepares a scope for resolving templates against a trigger
+func (e *Engine) newTemplateScope(ctx context.Context, automation models.Automation, triggerCtx *TriggerContext) *templateScope {
+	s := &templateScope{
+		ctx:        ctx,
+		engine:     e,
+		automation: automation,
+		trigger:    triggerCtx,
+		fields:     make(map[uuid.UUID][]models.Field),
+		records:    make(map[uuid.UUID]*models.Record),
+	}
+	if triggerCtx.Record != nil {
+		if err := json.Unmarshal(triggerCtx.Record.Values, &s.values); err == nil && s.values == nil {
+			s.values = map[string]interface{}{}
+		}
+	}
+	if len(triggerCtx.Payload) > 0 {
+		s.hasPayload = json.Unmarshal(triggerCtx.Payload, &s.payload) == nil
+	}
+	return s
+}
+
+// RenderMessage renders a webhook chat message for an event. Fields and linked
+// records are loaded with the access of actorID, normally the webhook's creator.
+func (e *Engine) RenderMessage(ctx context.Context, template string, payload *models.WebhookPayload, actorID uuid.UUID) string {
+	triggerCtx := &TriggerContext{
+		TableID:  payload.TableID,
+		RecordID: payload.RecordID,
+		Record:   payload.Record,
+		Comment:  payload.Comment,
+		UserID:   payload.UserID,
+	}
+	if data, err := json.Marshal(payload); err == nil {
+		triggerCtx.Payload = data
+	}
+	return e.newTemplateScope(ctx, models.Automation{CreatedBy: actorID}, triggerCtx).render(template)
+}
+
+// render resolves every tag in the template
+func (s *templateScope) render(template string) string {
+	return s.renderTemplate(template, false)
+}
+
+// renderJSON resolves every tag in a JSON template, escaping values for use inside
+// JSON strings unless they pass through the json or raw filter
+func (s *templateScope) renderJSON(template string) string {
+	return s.renderTemplate(template, true)
+}
+
+func (s *templateScope) renderTemplate(template string, escapeJSON bool) string {
+	if !strings.Contains(template, "{{") {
+		return template
+	}
+	nodes, _ := parseTemplate(template)
+	var b strings.Builder
+	s.renderNodes(&b, nodes, escapeJSON)
+	return b.String()
+}
+
+func (s *templateScope) renderNodes(b *strings.Builder, nodes []templateNode, escapeJSON bool) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case textNode:
+			b.WriteString(string(n))
+		case exprNode:
+			value, ok := s.evaluate(n)
+			switch {
+			case !ok:
+				b.WriteString(n.raw)
+			case escapeJSON && !n.hasFilter("json") && !n.hasFilter("raw"):
+				b.WriteString(jsonEscape(value.text))
+			default:
+				b.WriteString(value.text)
+			}
+		case *ifNode:
+			if s.conditionHolds(n) {
+				s.renderNodes(b, n.then, escapeJSON)
+			} else {
+				s.renderNodes(b, n.otherwise, escapeJSON)
+			}
+		}
+	}
+}
+
+func (expr exprNode) hasFilter(name string) bool {
+	for _, filter := range expr.filters {
+		if filter.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEscape escapes s for use inside a JSON string literal
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+// conditionHolds evaluates the condition of an {{#if}} or {{#unless}} section
+func (s *templateScope) conditionHolds(n *ifNode) bool {
+	value, _ := s.evaluate(n.expr)
+	var holds bool
+	switch n.operator {
+	case "==":
+		holds = strings.EqualFold(strings.TrimSpace(value.text), n.value)
+	case "!=":
+		holds = !strings.EqualFold(strings.TrimSpace(value.text), n.value)
+	default:
+		text := strings.TrimSpace(value.text)
+		holds = text != "" && !strings.EqualFold(text, "false")
+	}
+	if n.negate {
+		return !holds
+	}
+	return holds
+}
+
+// evaluate resolves an expression and applies its filters. It reports false when
+// the expression's source is unknown or unavailable for this trigger.
+func (s *templateScope) evaluate(expr exprNode) (templateValue, bool) {
+	value, ok := s.resolveSource(expr.source)
+	if !ok {
+		return templateValue{}, false
+	}
+	for _, filter := range expr.filters {
+		value = applyTemplateFilter(value, filter)
+	}
+	return value, true
+}
+
+func (s *templateScope) resolveSource(source string) (templateValue, bool) {
+	trigger := s.trigger
+
+	switch {
+	case strings.HasPrefix(source, "field:"):
+		if s.values == nil {
+			return templateValue{}, false
+		}
+		return s.fieldValue(strings.TrimPrefix(source, "field:"))
+
+	case strings.HasPrefix(source, "payload:"):
+		if !s.hasPayload {
+			return templateValue{}, false
+		}
+		value := lookupPayloadPath(s.payload, strings.TrimPrefix(source, "payload:"))
+		return templateValue{raw: value, text: formatPayloadValue(value)}, true
+	}
+
+	switch source {
+	case "comment":
+		if trigger.Comment == nil {
+			return templateValue{}, false
+		}
+		return stringValue(trigger.Comment.Content), true
+	case "recordId", "record.id":
+		if trigger.RecordID == nil {
+			return templateValue{}, false
+		}
+		return stringValue(trigger.RecordID.String()), true
+	case "record.url":
+		if trigger.RecordID == nil {
+			return templateValue{}, false
+		}
+		return stringValue(s.recordURL()), true
+	case "record.createdAt":
+		if trigger.Record == nil {
+			return templateValue{}, false
+		}
+		return stringValue(trigger.Record.CreatedAt.UTC().Format(time.RFC3339)), true
+	case "record.updatedAt":
+		if trigger.Record == nil {
+			return templateValue{}, false
+		}
+		return stringValue(trigger.Record.UpdatedAt.UTC().Format(time.RFC3339)), true
+	case "user.id":
+		if trigger.UserID == uuid.Nil {
+			return stringValue(""), true
+		}
+		return stringValue(trigger.UserID.String()), true
+	case "user.name":
+		if user := s.triggeringUser(); user != nil && user.Name != nil {
+			return stringValue(*user.Name), true
+		}
+		return stringValue(""), true
+	case "user.email":
+		if user := s.triggeringUser(); user != nil {
+			return stringValue(user.Email), true
+		}
+		return stringValue(""), true
+	case "automation.id":
+		return stringValue(s.automation.ID.String()), true
+	case "automation.name":
+		return stringValue(s.automation.Name), true
+	case "table.id":
+		return stringValue(trigger.TableID.String()), true
+	}
+
+	return templateValue{}, false
+}
+
+func stringValue(s string) templateValue {
+	return templateValue{raw: s, text: s}
+}
+
+// fieldValue resolves a field path such as "Status" or "Project->Owner->Email"
+// against the triggering record. It reports false when a field in the path does not
+// exist or an intermediate field is not a link.
+func (s *templateScope) fieldValue(path string) (templateValue, bool) {
+	segments := strings.Split(path, "->")
+	tableID := s.trigger.TableID
+	rows := []map[string]interface{}{s.values}
+
+	for i, segment := range segments {
+		field := s.findField(tableID, strings.TrimSpace(segment))
+		if field == nil {
+			return templateValue{}, false
+		}
+
+		if i == len(segments)-1 {
+			if len(rows) == 1 {
+				raw := rows[0][field.ID.String()]
+				return templateValue{raw: raw, text: s.display(field, raw, true)}, true
+			}
+			raws := make([]interface{}, 0, len(rows))
+			texts := make([]string, 0, len(rows))
+			for _, row := range rows {
+				raw := row[field.ID.String()]
+				raws = append(raws, raw)
+				if text := s.display(field, raw, true); text != "" {
+					texts = append(texts, text)
+				}
+			}
+			return templateValue{raw: raws, text: strings.Join(texts, ", ")}, true
+		}
+
+		// Follow the link to the next table
+		linkedTableID := linkedTableOf(field)
+		if linkedTableID == nil {
+			return templateValue{}, false
+		}
+		var next []map[string]interface{}
+		for _, row := range rows {
+			for _, record := range s.linkedRecords(models.LinkedRecordIDs(row[field.ID.String()])) {
+				var values map[string]interface{}
+				if err := json.Unmarshal(record.Values, &values); err == nil {
+					next = append(next, values)
+				}
+			}
+		}
+		if len(next) == 0 {
+			return stringValue(""), true
+		}
+		tableID = *linkedTableID
+		rows = next
+	}
+
+	return stringValue(""), true
+}
+
+// findField finds a field of a table by ID or, case-insensitively, by name.
+// When the table's fields cannot be loaded, IDs are still accepted.
+func (s *templateScope) findField(tableID uuid.UUID, ref string) *models.Field {
+	fields := s.tableFields(tableID)
+	if id, err := uuid.Parse(ref); err == nil {
+		for i := range fields {
+			if fields[i].ID == id {
+				return &fields[i]
+			}
+		}
+		if fields == nil {
+			return &models.Field{ID: id, TableID: tableID}
+		}
+		return nil
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].Name, ref) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func (s *templateScope) tableFields(tableID uuid.UUID) []models.Field {
+	if fields, ok := s.fields[tableID]; ok {
+		return fields
+	}
+	var fields []models.Field
+	if s.engine.fieldStore != nil {
+		if list, err := s.engine.fieldStore.ListFieldsForTable(s.ctx, tableID, s.automation.CreatedBy); err == nil {
+			fields = list
+		}
+	}
+	s.fields[tableID] = fields
+	return fields
+}
+
+// linkedRecords loads the given records, skipping any that are missing or inaccessible
+func (s *templateScope) linkedRecords(ids []uuid.UUID) []*models.Record {
+	if s.engine.recordStore == nil {
+		return nil
+	}
+	if len(ids) > maxTemplateLinkedRecords {
+		ids = ids[:maxTemplateLinkedRecords]
+	}
+	var records []*models.Record
+	for _, id := range ids {
+		record, cached := s.records[id]
+		if !cached {
+			record, _ = s.engine.recordStore.GetRecord(s.ctx, id, s.automation.CreatedBy)
+			s.records[id] = record
+		}
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// display formats a field value for humans: select option names instead of IDs,
+// linked records by their primary field, and lists joined with commas
+func (s *templateScope) display(field *models.Field, value interface{}, followLinks bool) string {
+	if value == nil {
+		return ""
+	}
+
+	switch field.FieldType {
+	case models.FieldTypeSingleSelect, models.FieldTypeMultiSelect:
+		var options models.FieldOptions
+		if len(field.Options) > 0 {
+			json.Unmarshal(field.Options, &options)
+		}
+		names := make(map[string]string, len(options.Options))
+		for _, option := range options.Options {
+			names[option.ID] = option.Name
+		}
+		optionName := func(v interface{}) string {
+			text := formatTemplateValue(v)
+			if name, ok := names[text]; ok {
+				return name
+			}
+			return text
+		}
+		if items, ok := value.([]interface{}); ok {
+			parts := make([]string, 0, len(items))
+			for _, item := range items {
+				parts = append(parts, optionName(item))
+			}
+			return strings.Join(parts, ", ")
+		}
+		return optionName(value)
+
+	case models.FieldTypeLinkedRecord:
+		ids := models.LinkedRecordIDs(value)
+		linkedTableID := linkedTableOf(field)
+		if !followLinks || linkedTableID == nil {
+			return joinIDs(ids)
+		}
+		fields := s.tableFields(*linkedTableID)
+		if len(fields) == 0 {
+			return joinIDs(ids)
+		}
+		primary := &fields[0]
+		var parts []string
+		for _, record := range s.linkedRecords(ids) {
+			var values map[string]interface{}
+			if err := json.Unmarshal(record.Values, &values); err != nil {
+				continue
+			}
+			if text := s.display(primary, values[primary.ID.String()], false); text != "" {
+				parts = append(parts, text)
+			} else {
+				parts = append(parts, record.ID.String())
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	return formatTemplateValue(value)
+}
+
+// recordURL links to the triggering record in the app
+func (s *templateScope) recordURL() string {
+	e := s.engine
+	if e.appURL == "" || e.tableStore == nil {
+		return ""
+	}
+	table, err := e.tableStore.GetTable(s.ctx, s.trigger.TableID, s.automation.CreatedBy)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/bases/%s?table=%s&record=%s", strings.TrimRight(e.appURL, "/"), table.BaseID, table.ID, s.trigger.RecordID)
+}
+
+// triggeringUser loads the user that triggered the automation, if any
+func (s *templateScope) triggeringUser() *models.User {
+	if s.user != nil || s.trigger.UserID == uuid.Nil || s.engine.authStore == nil {
+		return s.user
+	}
+	user, err := s.engine.authStore.GetUserByID(s.ctx, s.trigger.UserID)
+	if err != nil {
+		return nil
+	}
+	s.user = user
+	return user
+}
+
+func linkedTableOf(field *models.Field) *uuid.UUID {
+	if field.FieldType != models.FieldTypeLinkedRecord || len(field.Options) == 0 {
+		return nil
+	}
+	var options models.FieldOptions
+	if err := json.Unmarshal(field.Options, &options); err != nil {
+		return nil
+	}
+	return options.LinkedTableID
+}
+
+func joinIDs(ids []uuid.UUID) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = id.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatTemplateValue renders a decoded JSON value without type-specific formatting
+func formatTemplateValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, formatTemplateValue(item))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(b)
+	}
+}
+
+// applyTemplateFilter applies a single filter to a value. Filters that cannot
+// interpret the value leave it unchanged.
+func applyTemplateFilter(value templateValue, filter templateFilter) templateValue {
+	switch filter.name {
+	case "date":
+		t, ok := parseTemplateTime(value.text)
+		if !ok {
+			return value
+		}
+		layout, named := dateLayouts[filter.arg]
+		if !named {
+			layout = filter.arg
+		}
+		return stringValue(t.Format(layout))
+
+	case "number":
+		n, ok := value.raw.(float64)
+		if !ok {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value.text), 64)
+			if err != nil {
+				return value
+			}
+			n = parsed
+		}
+		decimals := -1
+		if filter.arg != "" {
+			if d, err := strconv.Atoi(filter.arg); err == nil && d >= 0 && d <= 10 {
+				decimals = d
+			}
+		}
+		return stringValue(formatTemplateNumber(n, decimals))
+
+	case "json":
+		return stringValue(jsonEscape(value.text))
+	case "url":
+		return stringValue(url.QueryEscape(value.text))
+	case "upper":
+		return stringValue(strings.ToUpper(value.text))
+	case "lower":
+		return stringValue(strings.ToLower(value.text))
+	case "trim":
+		return stringValue(strings.TrimSpace(value.text))
+	case "default":
+		if strings.TrimSpace(value.text) == "" {
+			return stringValue(filter.arg)
+		}
+	}
+	return value
+}
+
+// parseTemplateTime parses the date formats stored by date fields
+func parseTemplateTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatTemplateNumber formats n with thousands separators and the given number of
+// decimals (-1 keeps as many as needed)
+func formatTemplateNumber(n float64, decimals int) string {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	s := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if n < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if hasFrac {
+		b.WriteByte('.')
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
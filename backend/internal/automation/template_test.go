@@ -0,0 +1,271 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// renderTemplate resolves a template against a trigger without an automation
+func renderTemplate(e *Engine, template string, triggerCtx *TriggerContext) string {
+	return e.newTemplateScope(context.Background(), models.Automation{}, triggerCtx).render(template)
+}
+
+var fieldColumns = []string{"id", "table_id", "name", "field_type", "options", "position", "created_at", "updated_at"}
+
+func TestValidateTemplate(t *testing.T) {
+	t.Run("accepts valid templates", func(t *testing.T) {
+		errs := ValidateTemplate("body", `{{#if field:Status == "Done"}}{{field:Due | date:"2006-01-02"}}{{else}}-{{/if}}`)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("reports unknown filters", func(t *testing.T) {
+		errs := ValidateTemplate("body", "{{field:Name | shout}}")
+		assert.Equal(t, []string{`body: unknown filter "shout"`}, errs)
+	})
+
+	t.Run("reports unterminated sections", func(t *testing.T) {
+		errs := ValidateTemplate("body", "{{#if field:Name}}Hi")
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0], "is missing {{/if}}")
+	})
+
+	t.Run("reports stray closing tags", func(t *testing.T) {
+		errs := ValidateTemplate("body", "Hi{{/if}}")
+		assert.Equal(t, []string{"body: unexpected {{/if}}"}, errs)
+	})
+}
+
+func TestTemplateFilters(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+	fieldID := uuid.New().String()
+	triggerCtx := &TriggerContext{
+		Record: &models.Record{
+			Values: json.RawMessage(`{"` + fieldID + `": "2024-03-05T14:30:00Z"}`),
+		},
+		Payload: json.RawMessage(`{"amount": 1234567.891, "name": "Ada \"The Countess\"", "query": "a b&c", "blank": ""}`),
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"date with named layout", "{{field:" + fieldID + " | date}}", "Mar 5, 2024"},
+		{"date with custom layout", `{{field:` + fieldID + ` | date:"2006-01-02 15:04"}}`, "2024-03-05 14:30"},
+		{"number with decimals", "{{payload:amount | number:2}}", "1,234,567.89"},
+		{"number without decimals argument", "{{payload:amount | number}}", "1,234,567.891"},
+		{"json escape", "{{payload:name | json}}", `Ada \"The Countess\"`},
+		{"url encode", "{{payload:query | url}}", "a+b%26c"},
+		{"chained filters", "{{payload:name | upper | url}}", "ADA+%22THE+COUNTESS%22"},
+		{"default for empty values", `{{payload:blank | default:"n/a"}}`, "n/a"},
+		{"default keeps present values", `{{payload:query | default:"n/a"}}`, "a b&c"},
+		{"date leaves unparseable values", "{{payload:query | date}}", "a b&c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, renderTemplate(engine, tt.template, triggerCtx))
+		})
+	}
+}
+
+func TestTemplateJSONEscaping(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+	triggerCtx := &TriggerContext{
+		Payload: json.RawMessage(`{"name": "Ada \"The Countess\"\n", "tags": ["a", "b"]}`),
+	}
+	scope := engine.newTemplateScope(context.Background(), models.Automation{}, triggerCtx)
+
+	assert.Equal(t, `{"name": "Ada \"The Countess\"\n"}`, scope.renderJSON(`{"name": "{{payload:name}}"}`))
+	assert.Equal(t, `{"name": "Ada \"The Countess\"\n"}`, scope.renderJSON(`{"name": "{{payload:name | json}}"}`), "json is not applied twice")
+	assert.Equal(t, `{"tags": ["a","b"]}`, scope.renderJSON(`{"tags": {{payload:tags | raw}}}`))
+	assert.Equal(t, "Ada \"The Countess\"\n", scope.render("{{payload:name}}"), "other templates are not escaped")
+}
+
+func TestTemplateConditionals(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+	statusID := uuid.New().String()
+	notesID := uuid.New().String()
+	triggerCtx := &TriggerContext{
+		Record: &models.Record{
+			Values: json.RawMessage(`{"` + statusID + `": "Done", "` + notesID + `": ""}`),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"truthy value", "{{#if field:" + statusID + "}}yes{{/if}}", "yes"},
+		{"falsy value with else", "{{#if field:" + notesID + "}}yes{{else}}no{{/if}}", "no"},
+		{"equality is case-insensitive", `{{#if field:` + statusID + ` == "done"}}closed{{/if}}`, "closed"},
+		{"inequality", `{{#if field:` + statusID + ` != "Done"}}open{{else}}closed{{/if}}`, "closed"},
+		{"unless", "{{#unless field:" + notesID + "}}no notes{{/unless}}", "no notes"},
+		{"nested sections", `{{#if field:` + statusID + `}}[{{#if field:` + notesID + `}}notes{{else}}empty{{/if}}]{{/if}}`, "[empty]"},
+		{"quoted operators in values", `{{#if field:` + statusID + ` == "a==b"}}x{{else}}y{{/if}}`, "y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, renderTemplate(engine, tt.template, triggerCtx))
+		})
+	}
+}
+
+func TestTemplateMetadata(t *testing.T) {
+	engine := NewEngine(nil, nil, nil)
+	recordID := uuid.New()
+	tableID := uuid.New()
+	userID := uuid.New()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	triggerCtx := &TriggerContext{
+		TableID:  tableID,
+		RecordID: &recordID,
+		Record:   &models.Record{ID: recordID, Values: json.RawMessage(`{}`), CreatedAt: createdAt},
+		UserID:   userID,
+	}
+	automation := models.Automation{ID: uuid.New(), Name: "Escalate"}
+	scope := engine.newTemplateScope(context.Background(), automation, triggerCtx)
+
+	assert.Equal(t, recordID.String(), scope.render("{{record.id}}"))
+	assert.Equal(t, "2024-01-02T03:04:05Z", scope.render("{{record.createdAt}}"))
+	assert.Equal(t, tableID.String(), scope.render("{{table.id}}"))
+	assert.Equal(t, userID.String(), scope.render("{{user.id}}"))
+	assert.Equal(t, "Escalate", scope.render("{{automation.name}}"))
+	assert.Equal(t, "", scope.render("{{record.url}}"), "no app URL configured")
+	assert.Equal(t, "{{unknown}}", scope.render("{{unknown}}"))
+}
+
+func TestTemplateFieldDisplay(t *testing.T) {
+	ctx := context.Background()
+
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	baseStore := store.NewBaseStore(mock)
+	tableStore := store.NewTableStore(mock, baseStore)
+	fieldStore := store.NewFieldStore(mock, baseStore, tableStore)
+	recordStore := store.NewRecordStore(mock, baseStore, tableStore)
+	engine := NewEngine(nil, recordStore, fieldStore)
+
+	baseID := uuid.New()
+	userID := uuid.New()
+	tableID := uuid.New()
+	projectsTableID := uuid.New()
+	statusID := uuid.New()
+	tagsID := uuid.New()
+	projectID := uuid.New()
+	projectNameID := uuid.New()
+	linkedRecordID := uuid.New()
+	now := time.Now()
+
+	selectOptions := json.RawMessage(`{"options": [{"id": "opt1", "name": "In progress"}, {"id": "opt2", "name": "Blocked"}]}`)
+	linkOptions := json.RawMessage(`{"linked_table_id": "` + projectsTableID.String() + `"}`)
+
+	// Fields of the triggering table
+	mock.ExpectQuery("SELECT base_id FROM tables").WithArgs(tableID).
+		WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+	mock.ExpectQuery("SELECT role FROM base_collaborators").WithArgs(baseID, userID).
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+	mock.ExpectQuery("SELECT id, table_id, name, field_type, options, position, created_at, updated_at").WithArgs(tableID).
+		WillReturnRows(pgxmock.NewRows(fieldColumns).
+			AddRow(statusID, tableID, "Status", models.FieldTypeSingleSelect, selectOptions, 0, now, now).
+			AddRow(tagsID, tableID, "Tags", models.FieldTypeMultiSelect, selectOptions, 1, now, now).
+			AddRow(projectID, tableID, "Project", models.FieldTypeLinkedRecord, linkOptions, 2, now, now))
+
+	// Linked project record
+	mock.ExpectQuery("SELECT id, table_id, values, position, color, created_at, updated_at").WithArgs(linkedRecordID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "values", "position", "color", "created_at", "updated_at"}).
+			AddRow(linkedRecordID, projectsTableID, json.RawMessage(`{"`+projectNameID.String()+`": "Apollo"}`), 0, nil, now, now))
+	mock.ExpectQuery("SELECT base_id FROM tables").WithArgs(projectsTableID).
+		WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+	mock.ExpectQuery("SELECT role FROM base_collaborators").WithArgs(baseID, userID).
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+	mock.ExpectQuery("SELECT id, table_id, name, field_type, options, position, created_at, updated_at").WithArgs(projectsTableID).
+		WillReturnRows(pgxmock.NewRows(fieldColumns).
+			AddRow(projectNameID, projectsTableID, "Name", models.FieldTypeText, json.RawMessage(`{}`), 0, now, now))
+
+	// Fields of the linked table
+	mock.ExpectQuery("SELECT base_id FROM tables").WithArgs(projectsTableID).
+		WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+	mock.ExpectQuery("SELECT role FROM base_collaborators").WithArgs(baseID, userID).
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+	mock.ExpectQuery("SELECT id, table_id, name, field_type, options, position, created_at, updated_at").WithArgs(projectsTableID).
+		WillReturnRows(pgxmock.NewRows(fieldColumns).
+			AddRow(projectNameID, projectsTableID, "Name", models.FieldTypeText, json.RawMessage(`{}`), 0, now, now))
+
+	triggerCtx := &TriggerContext{
+		TableID: tableID,
+		Record: &models.Record{
+			Values: json.RawMessage(`{"` + statusID.String() + `": "opt2", "` + tagsID.String() + `": ["opt1", "opt2"], "` + projectID.String() + `": ["` + linkedRecordID.String() + `"]}`),
+		},
+	}
+	scope := engine.newTemplateScope(ctx, models.Automation{CreatedBy: userID}, triggerCtx)
+
+	assert.Equal(t, "Blocked", scope.render("{{field:Status}}"))
+	assert.Equal(t, "In progress, Blocked", scope.render("{{field:tags}}"))
+	assert.Equal(t, "APOLLO", scope.render("{{field:Project->Name | upper}}"))
+	assert.Equal(t, "Apollo", scope.render("{{field:Project}}"))
+	assert.Equal(t, "{{field:Missing}}", scope.render("{{field:Missing}}"), "unknown fields are left in place")
+	assert.Equal(t, "{{field:Status->Name}}", scope.render("{{field:Status->Name}}"), "only links can be followed")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEngine_RenderMessage(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	baseStore := store.NewBaseStore(mock)
+	tableStore := store.NewTableStore(mock, baseStore)
+	engine := NewEngine(nil, nil, store.NewFieldStore(mock, baseStore, tableStore))
+
+	baseID := uuid.New()
+	creatorID := uuid.New()
+	tableID := uuid.New()
+	nameID := uuid.New()
+	now := time.Now()
+
+	// Fields are loaded with the webhook creator's access
+	mock.ExpectQuery("SELECT base_id FROM tables").WithArgs(tableID).
+		WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+	mock.ExpectQuery("SELECT role FROM base_collaborators").WithArgs(baseID, creatorID).
+		WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+	mock.ExpectQuery("SELECT id, table_id, name, field_type, options, position, created_at, updated_at").WithArgs(tableID).
+		WillReturnRows(pgxmock.NewRows(fieldColumns).
+			AddRow(nameID, tableID, "Name", models.FieldTypeText, json.RawMessage(`{}`), 0, now, now))
+
+	recordID := uuid.New()
+	payload := &models.WebhookPayload{
+		Event:    models.WebhookEventRecordCreated,
+		BaseID:   baseID,
+		TableID:  tableID,
+		RecordID: &recordID,
+		Record:   &models.Record{ID: recordID, Values: json.RawMessage(`{"` + nameID.String() + `": "Ada"}`)},
+	}
+
+	message := engine.RenderMessage(context.Background(), "{{payload:event}}: {{field:Name}} ({{record.id}})", payload, creatorID)
+	assert.Equal(t, "record.created: Ada ("+recordID.String()+")", message)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFormatTemplateNumber(t *testing.T) {
+	assert.Equal(t, "0", formatTemplateNumber(0, -1))
+	assert.Equal(t, "999", formatTemplateNumber(999, -1))
+	assert.Equal(t, "1,000", formatTemplateNumber(1000, 0))
+	assert.Equal(t, "-12,345.50", formatTemplateNumber(-12345.5, 2))
+	assert.Equal(t, "0.00", formatTemplateNumber(-0.001, 2))
+}
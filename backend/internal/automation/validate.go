@@ -0,0 +1,265 @@
+package automation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+)
+
+// validOperators lists the operators supported by field_value_changed triggers
+var validOperators = map[string]bool{
+	"":             true, // No operator: fire whenever the value changes
+	"equals":       true,
+	"not_equals":   true,
+	"contains":     true,
+	"is_empty":     true,
+	"is_not_empty": true,
+}
+
+// validConditionOperators lists the operators supported by find_records conditions
+var validConditionOperators = map[string]bool{
+	"equals":       true,
+	"not_equals":   true,
+	"contains":     true,
+	"not_contains": true,
+	"is_empty":     true,
+	"is_not_empty": true,
+	"greater_than": true,
+	"less_than":    true,
+}
+
+// ValidateTriggerConfig checks a trigger configuration for structural errors.
+// It returns a list of human-readable problems; an empty list means the config is valid.
+func ValidateTriggerConfig(triggerType models.TriggerType, config json.RawMessage) []string {
+	var errs []string
+
+	switch triggerType {
+	case models.TriggerRecordCreated, models.TriggerRecordUpdated, models.TriggerRecordDeleted,
+		models.TriggerWebhookReceived:
+		// No user configuration required
+	case models.TriggerFormSubmitted:
+		var c models.FormSubmittedConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("triggerConfig: invalid JSON: %v", err))
+		}
+	case models.TriggerCommentAdded:
+		var c models.CommentAddedConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("triggerConfig: invalid JSON: %v", err))
+		}
+	case models.TriggerRecordEntersView:
+		var c models.RecordEntersViewConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("triggerConfig: invalid JSON: %v", err))
+		}
+		if c.ViewID == uuid.Nil {
+			errs = append(errs, "triggerConfig.viewId is required")
+		}
+	case models.TriggerFieldValueChanged:
+		var c models.FieldValueChangedConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("triggerConfig: invalid JSON: %v", err))
+		}
+		if c.FieldID == uuid.Nil {
+			errs = append(errs, "triggerConfig.fieldId is required")
+		}
+		if !validOperators[c.Operator] {
+			errs = append(errs, fmt.Sprintf("triggerConfig.operator %q is not supported", c.Operator))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("unknown trigger type: %s", triggerType))
+	}
+
+	return errs
+}
+
+// ValidateActionConfig checks an action configuration for structural errors.
+// It returns a list of human-readable problems; an empty list means the config is valid.
+func ValidateActionConfig(actionType models.ActionType, config json.RawMessage) []string {
+	var errs []string
+
+	switch actionType {
+	case models.ActionSendEmail:
+		var c models.SendEmailConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("actionConfig: invalid JSON: %v", err))
+		}
+		if strings.TrimSpace(c.To) == "" {
+			errs = append(errs, "actionConfig.to is required")
+		}
+		if strings.TrimSpace(c.Subject) == "" {
+			errs = append(errs, "actionConfig.subject is required")
+		}
+		errs = append(errs, ValidateTemplate("actionConfig.to", c.To)...)
+		errs = append(errs, ValidateTemplate("actionConfig.subject", c.Subject)...)
+		errs = append(errs, ValidateTemplate("actionConfig.body", c.Body)...)
+
+	case models.ActionUpdateRecord:
+		var c models.UpdateRecordConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("actionConfig: invalid JSON: %v", err))
+		}
+		if len(c.Updates) == 0 {
+			errs = append(errs, "actionConfig.updates must contain at least one field")
+		}
+		errs = append(errs, validateFieldUpdates("actionConfig.updates", c.Updates)...)
+
+	case models.ActionCreateRecord:
+		var c models.CreateRecordConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("actionConfig: invalid JSON: %v", err))
+		}
+		if c.TargetTableID == uuid.Nil {
+			errs = append(errs, "actionConfig.targetTableId is required")
+		}
+		errs = append(errs, validateFieldUpdates("actionConfig.values", c.Values)...)
+
+	case models.ActionSendWebhook:
+		var c models.SendWebhookConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("actionConfig: invalid JSON: %v", err))
+		}
+		if c.URL == "" {
+			errs = append(errs, "actionConfig.url is required")
+		} else if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, "actionConfig.url must be an absolute http or https URL")
+		}
+		switch strings.ToUpper(c.Method) {
+		case "", "POST", "PUT", "PATCH":
+		default:
+			errs = append(errs, fmt.Sprintf("actionConfig.method %q is not supported (use POST, PUT or PATCH)", c.Method))
+		}
+		errs = append(errs, ValidateTemplate("actionConfig.body", c.Body)...)
+
+	case models.ActionDeleteRecord:
+		// No user configuration required
+
+	case models.ActionAddComment:
+		var c models.AddCommentConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("actionConfig: invalid JSON: %v", err))
+		}
+		if strings.TrimSpace(c.Content) == "" {
+			errs = append(errs, "actionConfig.content is required")
+		}
+		errs = append(errs, ValidateTemplate("actionConfig.content", c.Content)...)
+
+	case models.ActionUpdateLinkedRecords:
+		var c models.UpdateLinkedRecordsConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("actionConfig: invalid JSON: %v", err))
+		}
+		if c.LinkedFieldID == uuid.Nil {
+			errs = append(errs, "actionConfig.linkedFieldId is required")
+		}
+		if len(c.Updates) == 0 {
+			errs = append(errs, "actionConfig.updates must contain at least one field")
+		}
+		errs = append(errs, validateFieldUpdates("actionConfig.updates", c.Updates)...)
+
+	case models.ActionFindRecords:
+		var c models.FindRecordsConfig
+		if err := json.Unmarshal(config, &c); err != nil {
+			return append(errs, fmt.Sprintf("actionConfig: invalid JSON: %v", err))
+		}
+		if c.TableID == uuid.Nil {
+			errs = append(errs, "actionConfig.tableId is required")
+		}
+		if c.Limit < 0 || c.Limit > maxFindRecordsLimit {
+			errs = append(errs, fmt.Sprintf("actionConfig.limit must be between 1 and %d", maxFindRecordsLimit))
+		}
+		for i, condition := range c.Conditions {
+			if condition.FieldID == uuid.Nil {
+				errs = append(errs, fmt.Sprintf("actionConfig.conditions[%d].fieldId is required", i))
+			}
+			if !validConditionOperators[condition.Operator] {
+				errs = append(errs, fmt.Sprintf("actionConfig.conditions[%d].operator %q is not supported", i, condition.Operator))
+			}
+		}
+		errs = append(errs, validateFieldUpdates("actionConfig.updates", c.Updates)...)
+
+	default:
+		errs = append(errs, fmt.Sprintf("unknown action type: %s", actionType))
+	}
+
+	return errs
+}
+
+// validateFieldUpdates checks that every update targets a field and that
+// templated values parse
+func validateFieldUpdates(path string, updates []models.FieldUpdate) []string {
+	var errs []string
+	for i, update := range updates {
+		if update.FieldID == uuid.Nil {
+			errs = append(errs, fmt.Sprintf("%s[%d].fieldId is required", path, i))
+		}
+	}
+	return append(errs, validateUpdateTemplates(path, updates)...)
+}
+
+// ValidateActionTemplates reports syntax problems in the templates of an action
+// configuration. Unlike ValidateActionConfig it accepts incomplete configs, so it can
+// run whenever an automation is saved.
+func ValidateActionTemplates(actionType models.ActionType, config json.RawMessage) []string {
+	var errs []string
+
+	switch actionType {
+	case models.ActionSendEmail:
+		var c models.SendEmailConfig
+		if json.Unmarshal(config, &c) == nil {
+			errs = append(errs, ValidateTemplate("actionConfig.to", c.To)...)
+			errs = append(errs, ValidateTemplate("actionConfig.subject", c.Subject)...)
+			errs = append(errs, ValidateTemplate("actionConfig.body", c.Body)...)
+		}
+	case models.ActionSendWebhook:
+		var c models.SendWebhookConfig
+		if json.Unmarshal(config, &c) == nil {
+			errs = append(errs, ValidateTemplate("actionConfig.body", c.Body)...)
+		}
+	case models.ActionAddComment:
+		var c models.AddCommentConfig
+		if json.Unmarshal(config, &c) == nil {
+			errs = append(errs, ValidateTemplate("actionConfig.content", c.Content)...)
+		}
+	case models.ActionUpdateRecord:
+		var c models.UpdateRecordConfig
+		if json.Unmarshal(config, &c) == nil {
+			errs = append(errs, validateUpdateTemplates("actionConfig.updates", c.Updates)...)
+		}
+	case models.ActionCreateRecord:
+		var c models.CreateRecordConfig
+		if json.Unmarshal(config, &c) == nil {
+			errs = append(errs, validateUpdateTemplates("actionConfig.values", c.Values)...)
+		}
+	case models.ActionUpdateLinkedRecords:
+		var c models.UpdateLinkedRecordsConfig
+		if json.Unmarshal(config, &c) == nil {
+			errs = append(errs, validateUpdateTemplates("actionConfig.updates", c.Updates)...)
+		}
+	case models.ActionFindRecords:
+		var c models.FindRecordsConfig
+		if json.Unmarshal(config, &c) == nil {
+			for i, condition := range c.Conditions {
+				errs = append(errs, ValidateTemplate(fmt.Sprintf("actionConfig.conditions[%d].value", i), condition.Value)...)
+			}
+			errs = append(errs, validateUpdateTemplates("actionConfig.updates", c.Updates)...)
+		}
+	}
+
+	return errs
+}
+
+// validateUpdateTemplates checks the templated values of field updates
+func validateUpdateTemplates(path string, updates []models.FieldUpdate) []string {
+	var errs []string
+	for i, update := range updates {
+		if value, ok := update.Value.(string); ok {
+			errs = append(errs, ValidateTemplate(fmt.Sprintf("%s[%d].value", path, i), value)...)
+		}
+	}
+	return errs
+}
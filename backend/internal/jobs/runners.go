@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// DuplicateBase runs duplicate_base jobs; the result is the new base
+func DuplicateBase(bases *store.BaseStore) Runner {
+	return func(ctx context.Context, job *models.Job, progress store.ProgressFunc) (interface{}, error) {
+		var payload models.DuplicateBasePayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, err
+		}
+		return bases.DuplicateBase(ctx, job.BaseID, job.CreatedBy, payload.IncludeRecords, progress)
+	}
+}
+
+// DuplicateTable runs duplicate_table jobs; the result is the new table
+func DuplicateTable(tables *store.TableStore) Runner {
+	return func(ctx context.Context, job *models.Job, progress store.ProgressFunc) (interface{}, error) {
+		var payload models.DuplicateTablePayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, err
+		}
+		return tables.DuplicateTable(ctx, payload.TableID, job.CreatedBy, payload.IncludeRecords, progress)
+	}
+}
+
+// ImportRecords runs import_records jobs; the result counts the records imported
+func ImportRecords(records *store.RecordStore) Runner {
+	return func(ctx context.Context, job *models.Job, progress store.ProgressFunc) (interface{}, error) {
+		var payload models.ImportRecordsPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, err
+		}
+		if len(payload.Records) > 0 {
+			if _, err := records.BulkCreateRecords(ctx, payload.TableID, payload.Records, job.CreatedBy, progress); err != nil {
+				return nil, err
+			}
+		}
+		return models.ImportRecordsResult{
+			Imported: len(payload.Records),
+			Skipped:  payload.Skipped,
+			Errors:   payload.Errors,
+		}, nil
+	}
+}
@@ -0,0 +1,266 @@
+// Package jobs runs long-running operations, such as duplicating a base, in the
+// background, reporting their progress and stopping them when they are cancelled.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// Worker settings
+const (
+	concurrency     = 2 // Jobs run at once by each worker
+	pollInterval    = 30 * time.Second
+	lease           = time.Minute // How long a running job is held without a heartbeat
+	saveInterval    = time.Second // How often progress is saved and cancellation checked
+	maxAttempts     = 3           // Runs of a job whose worker stopped before giving up
+	retention       = 7 * 24 * time.Hour
+	cleanupInterval = time.Hour
+)
+
+var errInterrupted = errors.New("the job was interrupted too many times")
+
+// Runner runs one type of job as the user who queued it, telling progress how far
+// it has got. What it returns is saved as the job's result. It must stop when ctx
+// is cancelled.
+type Runner func(ctx context.Context, job *models.Job, progress store.ProgressFunc) (interface{}, error)
+
+// Worker claims queued jobs and runs them
+type Worker struct {
+	jobs    *store.JobStore
+	runners map[models.JobType]Runner
+	wake    chan struct{}
+	slots   chan struct{}
+}
+
+// NewWorker creates a job worker with no runners
+func NewWorker(jobs *store.JobStore) *Worker {
+	return &Worker{
+		jobs:    jobs,
+		runners: make(map[models.JobType]Runner),
+		wake:    make(chan struct{}, 1),
+		slots:   make(chan struct{}, concurrency),
+	}
+}
+
+// Register sets the runner for a type of job. Runners are registered before Start.
+func (w *Worker) Register(jobType models.JobType, runner Runner) {
+	w.runners[jobType] = runner
+}
+
+// Notify wakes the worker to look for queued jobs
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the worker in the background until ctx is cancelled. Jobs still
+// running then are left for another worker to take once their lease runs out.
+func (w *Worker) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *Worker) run(ctx context.Context) {
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		w.claimJobs(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		case <-poll.C:
+		case <-cleanup.C:
+			w.cleanup(ctx)
+		}
+	}
+}
+
+// claimJobs starts queued jobs until every slot is busy or none are left
+func (w *Worker) claimJobs(ctx context.Context) {
+	for ctx.Err() == nil {
+		select {
+		case w.slots <- struct{}{}:
+		default:
+			return
+		}
+
+		job, err := w.jobs.ClaimJob(ctx, lease)
+		if err != nil || job == nil {
+			<-w.slots
+			if err != nil {
+				log.Printf("Failed to claim a job: %v", err)
+			}
+			return
+		}
+
+		go func() {
+			defer func() {
+				<-w.slots
+				w.Notify()
+			}()
+			w.runJob(ctx, job)
+		}()
+	}
+}
+
+// runJob runs a claimed job and records how it ended
+func (w *Worker) runJob(ctx context.Context, job *models.Job) {
+	if job.CancelRequested {
+		w.finish(ctx, job, models.JobCancelled, nil, nil)
+		return
+	}
+	if job.Attempts > maxAttempts {
+		w.finish(ctx, job, models.JobFailed, nil, errInterrupted)
+		return
+	}
+	runner, ok := w.runners[job.JobType]
+	if !ok {
+		w.finish(ctx, job, models.JobFailed, nil, errors.New("unknown job type "+string(job.JobType)))
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t := &tracker{}
+	stopped := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.heartbeat(ctx, job, t, cancel, stopped)
+	}()
+
+	result, err := runner(jobCtx, job, t.set)
+	close(stopped)
+	wg.Wait()
+
+	job.Progress, job.Total = t.get()
+	switch {
+	case ctx.Err() != nil:
+		// Shutting down; the job is run again once its lease runs out
+	case err != nil && jobCtx.Err() != nil:
+		w.finish(ctx, job, models.JobCancelled, nil, nil)
+	case err != nil:
+		w.finish(ctx, job, models.JobFailed, nil, err)
+	default:
+		w.finish(ctx, job, models.JobSucceeded, result, nil)
+	}
+}
+
+// heartbeat saves a running job's progress, extending its lease, and cancels it
+// when it has been asked to stop
+func (w *Worker) heartbeat(ctx context.Context, job *models.Job, t *tracker, cancel context.CancelFunc, stopped <-chan struct{}) {
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+
+	savedProgress, savedTotal := job.Progress, job.Total
+	savedAt := time.Now()
+	for {
+		select {
+		case <-stopped:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		progress, total := t.get()
+		if progress == savedProgress && total == savedTotal && time.Since(savedAt) < lease/4 {
+			continue
+		}
+		updated, err := w.jobs.UpdateJobProgress(ctx, job.ID, progress, total, lease)
+		if err != nil {
+			log.Printf("Failed to save progress of job %s: %v", job.ID, err)
+			continue
+		}
+		savedProgress, savedTotal, savedAt = progress, total, time.Now()
+		if updated.CancelRequested {
+			cancel()
+		}
+	}
+}
+
+// finish records how a job ended. Errors are logged in full; users see a summary.
+func (w *Worker) finish(ctx context.Context, job *models.Job, status models.JobStatus, result interface{}, runErr error) {
+	job.Status = status
+	job.Result = nil
+	job.Error = nil
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			job.Status = models.JobFailed
+			runErr = err
+		} else {
+			job.Result = data
+		}
+	}
+	if runErr != nil {
+		log.Printf("Job %s (%s) failed: %v", job.ID, job.JobType, runErr)
+		message := jobErrorMessage(runErr)
+		job.Error = &message
+	}
+
+	if _, err := w.jobs.FinishJob(ctx, job); err != nil {
+		log.Printf("Failed to finish job %s: %v", job.ID, err)
+	}
+}
+
+// jobErrorMessage describes why a job failed without exposing internal errors
+func jobErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, store.ErrForbidden):
+		return "You no longer have permission to do this"
+	case errors.Is(err, store.ErrNotFound):
+		return "What the job was working on no longer exists"
+	case errors.Is(err, errInterrupted):
+		return "The job was interrupted too many times"
+	default:
+		return "The job failed unexpectedly"
+	}
+}
+
+// cleanup removes jobs that finished more than retention ago
+func (w *Worker) cleanup(ctx context.Context) {
+	removed, err := w.jobs.DeleteFinishedJobs(ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.Printf("Failed to remove finished jobs: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Removed %d finished jobs", removed)
+	}
+}
+
+// tracker holds a running job's latest progress
+type tracker struct {
+	mu       sync.Mutex
+	progress int
+	total    int
+}
+
+func (t *tracker) set(done, total int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress, t.total = done, total
+}
+
+func (t *tracker) get() (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.progress, t.total
+}
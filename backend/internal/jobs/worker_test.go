@@ -0,0 +1,227 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/store"
+)
+
+var jobColumns = []string{
+	"id", "base_id", "job_type", "status", "payload", "progress", "total", "result", "error",
+	"cancel_requested", "attempts", "created_by", "created_at", "started_at", "finished_at", "updated_at",
+}
+
+func testJob(jobType models.JobType) *models.Job {
+	now := time.Now().UTC()
+	return &models.Job{
+		ID:        uuid.New(),
+		BaseID:    uuid.New(),
+		JobType:   jobType,
+		Status:    models.JobRunning,
+		Payload:   json.RawMessage(`{}`),
+		Attempts:  1,
+		CreatedBy: uuid.New(),
+		CreatedAt: now,
+		StartedAt: &now,
+		UpdatedAt: now,
+	}
+}
+
+func jobRow(j *models.Job) *pgxmock.Rows {
+	return pgxmock.NewRows(jobColumns).AddRow(
+		j.ID, j.BaseID, j.JobType, j.Status, j.Payload, j.Progress, j.Total, j.Result, j.Error,
+		j.CancelRequested, j.Attempts, j.CreatedBy, j.CreatedAt, j.StartedAt, j.FinishedAt, j.UpdatedAt,
+	)
+}
+
+// expectFinish expects a job to be finished with status, and returns the error
+// message it is saved with
+func expectFinish(mock pgxmock.PgxPoolIface, job *models.Job, status models.JobStatus, result interface{}) *messageArg {
+	message := &messageArg{}
+	var resultArg interface{} = pgxmock.AnyArg()
+	if result != nil {
+		resultArg = result
+	}
+	mock.ExpectQuery("UPDATE jobs SET").
+		WithArgs(job.ID, status, pgxmock.AnyArg(), pgxmock.AnyArg(), resultArg, message).
+		WillReturnRows(jobRow(job))
+	return message
+}
+
+// messageArg matches a job's error message, keeping it for assertions
+type messageArg struct {
+	message *string
+}
+
+func (m *messageArg) Match(v interface{}) bool {
+	m.message, _ = v.(*string)
+	return true
+}
+
+func setup(t *testing.T) (pgxmock.PgxPoolIface, *Worker) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mock.Close)
+	return mock, NewWorker(store.NewJobStore(mock, nil))
+}
+
+func TestWorker_runJob(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("saves the result of a job that succeeds", func(t *testing.T) {
+		mock, w := setup(t)
+		job := testJob(models.JobImportRecords)
+		w.Register(models.JobImportRecords, func(ctx context.Context, job *models.Job, progress store.ProgressFunc) (interface{}, error) {
+			progress(2, 2)
+			return models.ImportRecordsResult{Imported: 2}, nil
+		})
+		mock.ExpectQuery("UPDATE jobs SET").
+			WithArgs(job.ID, models.JobSucceeded, 2, 2, json.RawMessage(`{"imported":2,"skipped":0,"errors":0}`), (*string)(nil)).
+			WillReturnRows(jobRow(job))
+
+		w.runJob(ctx, job)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("saves a summary of why a job failed", func(t *testing.T) {
+		mock, w := setup(t)
+		job := testJob(models.JobDuplicateBase)
+		w.Register(models.JobDuplicateBase, func(ctx context.Context, job *models.Job, progress store.ProgressFunc) (interface{}, error) {
+			return nil, store.ErrForbidden
+		})
+		message := expectFinish(mock, job, models.JobFailed, nil)
+
+		w.runJob(ctx, job)
+		require.NoError(t, mock.ExpectationsWereMet())
+		require.NotNil(t, message.message)
+		assert.Equal(t, "You no longer have permission to do this", *message.message)
+	})
+
+	t.Run("fails jobs of unknown types", func(t *testing.T) {
+		mock, w := setup(t)
+		job := testJob("unknown")
+		expectFinish(mock, job, models.JobFailed, nil)
+
+		w.runJob(ctx, job)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("gives up on jobs interrupted too often", func(t *testing.T) {
+		mock, w := setup(t)
+		job := testJob(models.JobDuplicateBase)
+		job.Attempts = maxAttempts + 1
+		ran := false
+		w.Register(models.JobDuplicateBase, func(ctx context.Context, job *models.Job, progress store.ProgressFunc) (interface{}, error) {
+			ran = true
+			return nil, nil
+		})
+		message := expectFinish(mock, job, models.JobFailed, nil)
+
+		w.runJob(ctx, job)
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.False(t, ran)
+		assert.Equal(t, "The job was interrupted too many times", *message.message)
+	})
+
+	t.Run("cancels a reclaimed job that was asked to stop", func(t *testing.T) {
+		mock, w := setup(t)
+		job := testJob(models.JobDuplicateBase)
+		job.CancelRequested = true
+		expectFinish(mock, job, models.JobCancelled, nil)
+
+		w.runJob(ctx, job)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stops a running job when it is cancelled", func(t *testing.T) {
+		mock, w := setup(t)
+		job := testJob(models.JobDuplicateTable)
+		w.Register(models.JobDuplicateTable, func(ctx context.Context, job *models.Job, progress store.ProgressFunc) (interface{}, error) {
+			progress(1, 5)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		cancelled := *job
+		cancelled.Progress, cancelled.Total, cancelled.CancelRequested = 1, 5, true
+		mock.ExpectQuery("UPDATE jobs SET").
+			WithArgs(job.ID, 1, 5, lease.Seconds()).
+			WillReturnRows(jobRow(&cancelled))
+		expectFinish(mock, job, models.JobCancelled, nil)
+
+		done := make(chan struct{})
+		go func() {
+			w.runJob(ctx, job)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("job was not cancelled")
+		}
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("leaves jobs running when shutting down", func(t *testing.T) {
+		mock, w := setup(t)
+		job := testJob(models.JobDuplicateBase)
+		ctx, cancel := context.WithCancel(context.Background())
+		w.Register(models.JobDuplicateBase, func(ctx context.Context, job *models.Job, progress store.ProgressFunc) (interface{}, error) {
+			cancel()
+			return nil, ctx.Err()
+		})
+
+		w.runJob(ctx, job)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWorker_claimJobs(t *testing.T) {
+	t.Run("frees its slot when nothing is queued", func(t *testing.T) {
+		mock, w := setup(t)
+		mock.ExpectQuery("FOR UPDATE SKIP LOCKED").
+			WithArgs(lease.Seconds()).
+			WillReturnError(pgx.ErrNoRows)
+
+		w.claimJobs(context.Background())
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.Empty(t, w.slots)
+	})
+
+	t.Run("frees its slot when claiming fails", func(t *testing.T) {
+		mock, w := setup(t)
+		mock.ExpectQuery("FOR UPDATE SKIP LOCKED").
+			WithArgs(lease.Seconds()).
+			WillReturnError(errors.New("connection refused"))
+
+		w.claimJobs(context.Background())
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.Empty(t, w.slots)
+	})
+}
+
+func TestJobErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{store.ErrForbidden, "You no longer have permission to do this"},
+		{fmt.Errorf("copying: %w", store.ErrNotFound), "What the job was working on no longer exists"},
+		{errors.New("duplicate key value"), "The job failed unexpectedly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.want, jobErrorMessage(tt.err))
+		})
+	}
+}
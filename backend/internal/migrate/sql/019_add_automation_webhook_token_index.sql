@@ -0,0 +1,9 @@
+-- Migration: 019_add_automation_webhook_token_index
+-- Description: Look up webhook_received automations by their inbound token
+
+-- New trigger types: form_submitted, comment_added, record_enters_view, webhook_received
+-- The inbound webhook token lives in trigger_config->>'token' and is generated server-side
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_automations_webhook_token
+    ON automations ((trigger_config->>'token'))
+    WHERE trigger_type = 'webhook_received';
@@ -0,0 +1,4 @@
+-- Migration: 020_add_comment_automation_id
+-- Description: Track comments posted by automations
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS automation_id UUID REFERENCES automations(id) ON DELETE SET NULL;
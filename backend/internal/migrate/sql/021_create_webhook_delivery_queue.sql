@@ -0,0 +1,29 @@
+-- Migration: 021_create_webhook_delivery_queue
+-- Description: Persistent webhook delivery queue with retries, attempt tracking and auto-disable
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_queue (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seq BIGSERIAL,                       -- Preserves event order per webhook
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, delivered, failed
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE, -- Set while a worker is delivering the item
+    last_error TEXT,
+    redelivery_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_queue_pending ON webhook_delivery_queue(webhook_id, seq) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_queue_finished ON webhook_delivery_queue(updated_at) WHERE status <> 'pending';
+
+-- Attempt tracking on the delivery log
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS attempt INT NOT NULL DEFAULT 1;
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS queue_item_id UUID REFERENCES webhook_delivery_queue(id) ON DELETE SET NULL;
+
+-- Auto-disable after repeated failures
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS consecutive_failures INT NOT NULL DEFAULT 0;
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS disabled_reason TEXT;
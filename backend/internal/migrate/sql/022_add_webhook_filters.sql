@@ -0,0 +1,6 @@
+-- Migration: 022_add_webhook_filters
+-- Description: Scope webhooks to specific tables and to changes in specific fields
+
+-- Empty arrays mean every table / any field
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS table_ids JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS watch_field_ids JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,5 @@
+-- Migration: 023_add_webhook_format
+-- Description: Let webhooks choose a payload format and a chat message template
+
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS format VARCHAR(32) NOT NULL DEFAULT 'native';
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS message_template TEXT;
@@ -0,0 +1,9 @@
+-- Migration: 024_add_webhook_secret_rotation
+-- Description: Store webhook secrets encrypted and keep the previous secret during rotation
+
+-- Encrypted secrets are longer than the plaintext
+ALTER TABLE webhooks ALTER COLUMN secret TYPE TEXT;
+
+-- The previous secret keeps signing deliveries until it expires
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS previous_secret TEXT;
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS previous_secret_expires_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,29 @@
+-- Migration: 025_create_realtime_relay
+-- Description: Shared state for running several backend instances: WebSocket tickets,
+-- oversized realtime messages and server-wide secrets
+
+-- One-time WebSocket tickets; any instance may receive the connection
+CREATE TABLE IF NOT EXISTS ws_tickets (
+    ticket_hash VARCHAR(64) PRIMARY KEY, -- SHA-256 of the ticket
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    base_id UUID NOT NULL REFERENCES bases(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ws_tickets_expires_at ON ws_tickets(expires_at);
+
+-- Realtime messages too large for a NOTIFY payload
+CREATE TABLE IF NOT EXISTS realtime_spill (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_realtime_spill_created_at ON realtime_spill(created_at);
+
+-- Secrets every instance must agree on, such as the CSRF signing key
+CREATE TABLE IF NOT EXISTS server_secrets (
+    name VARCHAR(64) PRIMARY KEY,
+    value TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
@@ -0,0 +1,17 @@
+-- Migration: 026_create_realtime_events
+-- Description: Per-base sequence numbers and a short log of realtime messages for replay on reconnect
+
+CREATE TABLE IF NOT EXISTS realtime_sequences (
+    base_id UUID PRIMARY KEY REFERENCES bases(id) ON DELETE CASCADE,
+    last_seq BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS realtime_events (
+    base_id UUID NOT NULL REFERENCES bases(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL,
+    message JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (base_id, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_realtime_events_created_at ON realtime_events(created_at);
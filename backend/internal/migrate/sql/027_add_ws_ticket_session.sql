@@ -0,0 +1,5 @@
+-- Migration: 027_add_ws_ticket_session
+-- Description: Tie WebSocket tickets to the session that requested them, so open
+-- connections can be re-checked against it
+
+ALTER TABLE ws_tickets ADD COLUMN IF NOT EXISTS session_id UUID REFERENCES sessions(id) ON DELETE CASCADE;
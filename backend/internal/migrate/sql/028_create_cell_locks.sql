@@ -0,0 +1,14 @@
+-- Migration: 028_create_cell_locks
+-- Description: Leases on cells being edited, shared by all instances
+
+CREATE TABLE IF NOT EXISTS cell_locks (
+    base_id UUID NOT NULL REFERENCES bases(id) ON DELETE CASCADE,
+    table_id UUID NOT NULL,
+    record_id UUID NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    field_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (base_id, record_id, field_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cell_locks_expires_at ON cell_locks(expires_at);
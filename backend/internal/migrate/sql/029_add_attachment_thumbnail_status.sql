@@ -0,0 +1,13 @@
+-- Migration: 029_add_attachment_thumbnail_status
+-- Description: Track thumbnail generation for image attachments so a background
+-- worker can pick up pending images and not retry ones it failed on
+
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS thumbnail_status VARCHAR(20);
+
+-- Images uploaded before thumbnails existed are generated by the worker
+UPDATE attachments SET thumbnail_status = 'pending'
+WHERE thumbnail_status IS NULL
+  AND thumbnail_key IS NULL
+  AND content_type IN ('image/jpeg', 'image/png', 'image/gif');
+
+CREATE INDEX IF NOT EXISTS idx_attachments_thumbnail_pending ON attachments(created_at) WHERE thumbnail_status = 'pending';
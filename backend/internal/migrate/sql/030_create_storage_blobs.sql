@@ -0,0 +1,47 @@
+-- Migration: 030_create_storage_blobs
+-- Description: Reference count stored files so attachments with the same content
+-- share one file and unreferenced files can be garbage collected
+
+CREATE TABLE IF NOT EXISTS storage_blobs (
+    storage_key VARCHAR(500) PRIMARY KEY,
+    size_bytes BIGINT NOT NULL,
+    ref_count INT NOT NULL DEFAULT 0,        -- Attachments using the file
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Index for the garbage collector to find files nothing uses any more
+CREATE INDEX IF NOT EXISTS idx_storage_blobs_unreferenced ON storage_blobs(updated_at) WHERE ref_count <= 0;
+
+-- Files uploaded before content addressing are counted like any other
+INSERT INTO storage_blobs (storage_key, size_bytes, ref_count)
+SELECT storage_key, MAX(size_bytes), COUNT(*)
+FROM attachments
+GROUP BY storage_key
+ON CONFLICT (storage_key) DO NOTHING;
+
+-- Counts are kept by trigger so cascade deletes of records, fields, tables and
+-- bases release their files too
+CREATE OR REPLACE FUNCTION count_attachment_blob_refs() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO storage_blobs (storage_key, size_bytes, ref_count)
+        VALUES (NEW.storage_key, NEW.size_bytes, 1)
+        ON CONFLICT (storage_key) DO UPDATE
+        SET ref_count = storage_blobs.ref_count + 1, updated_at = NOW();
+        RETURN NEW;
+    END IF;
+
+    UPDATE storage_blobs
+    SET ref_count = ref_count - 1, updated_at = NOW()
+    WHERE storage_key = OLD.storage_key;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS attachments_blob_refs ON attachments;
+CREATE TRIGGER attachments_blob_refs
+    AFTER INSERT OR DELETE ON attachments
+    FOR EACH ROW EXECUTE FUNCTION count_attachment_blob_refs();
+
+-- Index for the garbage collector to check whether a thumbnail is still used
+CREATE INDEX IF NOT EXISTS idx_attachments_thumbnail_key ON attachments(thumbnail_key) WHERE thumbnail_key IS NOT NULL;
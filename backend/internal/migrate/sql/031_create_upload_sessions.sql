@@ -0,0 +1,31 @@
+-- Migration: 031_create_upload_sessions
+-- Description: Resumable attachment uploads, sent in chunks that are kept as
+-- temporary files until the upload is completed or abandoned
+
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    record_id UUID NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    field_id UUID NOT NULL REFERENCES fields(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    received_bytes BIGINT NOT NULL DEFAULT 0,
+    completing BOOLEAN NOT NULL DEFAULT FALSE,  -- Set while the parts are assembled
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Index for finding abandoned uploads
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_updated_at ON upload_sessions(updated_at);
+
+CREATE TABLE IF NOT EXISTS upload_parts (
+    upload_id UUID NOT NULL REFERENCES upload_sessions(id) ON DELETE CASCADE,
+    offset_bytes BIGINT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key VARCHAR(500) NOT NULL,
+    PRIMARY KEY (upload_id, offset_bytes)
+);
+
+-- Index for the garbage collector to check whether a file is an upload part
+CREATE INDEX IF NOT EXISTS idx_upload_parts_storage_key ON upload_parts(storage_key);
@@ -0,0 +1,18 @@
+-- Migration: 032_create_base_templates
+-- Description: Templates published from bases, stored as base archives that new
+-- bases are created from
+
+CREATE TABLE IF NOT EXISTS base_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    source_base_id UUID REFERENCES bases(id) ON DELETE SET NULL,
+    includes_records BOOLEAN NOT NULL DEFAULT FALSE,
+    content JSONB NOT NULL,  -- The base as a models.BaseArchive
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Index for listing the gallery
+CREATE INDEX IF NOT EXISTS idx_base_templates_name ON base_templates(name);
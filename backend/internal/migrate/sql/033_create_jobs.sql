@@ -0,0 +1,32 @@
+-- Migration: 033_create_jobs
+-- Description: Long-running operations such as duplicating bases and importing
+-- records, run in the background by a worker that reports their progress
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    base_id UUID NOT NULL REFERENCES bases(id) ON DELETE CASCADE,
+    job_type VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',  -- queued, running, succeeded, failed, cancelled
+    payload JSONB NOT NULL DEFAULT '{}',
+    progress INTEGER NOT NULL DEFAULT 0,
+    total INTEGER NOT NULL DEFAULT 0,
+    result JSONB,
+    error TEXT,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,  -- When a running job's worker is presumed gone
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Index for workers looking for jobs to run
+CREATE INDEX IF NOT EXISTS idx_jobs_pending ON jobs(created_at) WHERE status IN ('queued', 'running');
+
+-- Index for listing a base's jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_base_id ON jobs(base_id, created_at DESC);
+
+-- Index for removing old finished jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_finished_at ON jobs(finished_at) WHERE finished_at IS NOT NULL;
@@ -9,18 +9,20 @@ import (
 
 // Activity action types
 const (
-	ActionCreate = "create"
-	ActionUpdate = "update"
-	ActionDelete = "delete"
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionDisable = "disable"
 )
 
 // Activity entity types
 const (
-	EntityTypeRecord = "record"
-	EntityTypeField  = "field"
-	EntityTypeTable  = "table"
-	EntityTypeView   = "view"
-	EntityTypeBase   = "base"
+	EntityTypeRecord  = "record"
+	EntityTypeField   = "field"
+	EntityTypeTable   = "table"
+	EntityTypeView    = "view"
+	EntityTypeBase    = "base"
+	EntityTypeWebhook = "webhook"
 )
 
 type Activity struct {
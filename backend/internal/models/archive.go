@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BaseArchiveVersion is the archive format written by export. Import accepts this
+// version and any earlier one.
+const BaseArchiveVersion = 1
+
+// BaseArchive is a portable copy of a whole base. IDs are those of the exporting
+// server; import gives everything new IDs and rewrites the references to match.
+type BaseArchive struct {
+	Version     int                 `json:"version"`
+	ExportedAt  time.Time           `json:"exported_at"`
+	Base        ArchiveBase         `json:"base"`
+	Tables      []ArchiveTable      `json:"tables"`
+	Automations []ArchiveAutomation `json:"automations"`
+	Webhooks    []ArchiveWebhook    `json:"webhooks"`
+}
+
+type ArchiveBase struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type ArchiveTable struct {
+	ID       uuid.UUID       `json:"id"`
+	Name     string          `json:"name"`
+	Position int             `json:"position"`
+	Fields   []ArchiveField  `json:"fields"`
+	Views    []ArchiveView   `json:"views"`
+	Forms    []ArchiveForm   `json:"forms"`
+	Records  []ArchiveRecord `json:"records"`
+}
+
+type ArchiveField struct {
+	ID        uuid.UUID       `json:"id"`
+	Name      string          `json:"name"`
+	FieldType FieldType       `json:"field_type"`
+	Options   json.RawMessage `json:"options"`
+	Position  int             `json:"position"`
+}
+
+// ArchiveView is a view without its public link, which is not carried over
+type ArchiveView struct {
+	ID       uuid.UUID       `json:"id"`
+	Name     string          `json:"name"`
+	Type     ViewType        `json:"type"`
+	Config   json.RawMessage `json:"config"`
+	Position int             `json:"position"`
+}
+
+// ArchiveForm is a form without its public token; imported forms get a new one
+type ArchiveForm struct {
+	ID               uuid.UUID          `json:"id"`
+	Name             string             `json:"name"`
+	Description      *string            `json:"description,omitempty"`
+	IsActive         bool               `json:"is_active"`
+	SuccessMessage   string             `json:"success_message"`
+	RedirectURL      *string            `json:"redirect_url,omitempty"`
+	SubmitButtonText string             `json:"submit_button_text"`
+	Fields           []ArchiveFormField `json:"fields"`
+}
+
+type ArchiveFormField struct {
+	FieldID    uuid.UUID `json:"field_id"`
+	Label      *string   `json:"label,omitempty"`
+	HelpText   *string   `json:"help_text,omitempty"`
+	IsRequired bool      `json:"is_required"`
+	IsVisible  bool      `json:"is_visible"`
+	Position   int       `json:"position"`
+}
+
+type ArchiveRecord struct {
+	ID          uuid.UUID           `json:"id"`
+	Values      json.RawMessage     `json:"values"`
+	Position    int                 `json:"position"`
+	Color       *string             `json:"color,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	Comments    []ArchiveComment    `json:"comments,omitempty"`
+	Attachments []ArchiveAttachment `json:"attachments,omitempty"`
+}
+
+// ArchiveComment identifies its author by email, as user IDs differ between servers
+type ArchiveComment struct {
+	ID           uuid.UUID  `json:"id"`
+	ParentID     *uuid.UUID `json:"parent_id,omitempty"`
+	AutomationID *uuid.UUID `json:"automation_id,omitempty"`
+	AuthorEmail  string     `json:"author_email"`
+	Content      string     `json:"content"`
+	IsResolved   bool       `json:"is_resolved"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ArchiveAttachment refers to its content by File, the name of the file in the
+// archive, which attachments with the same content share
+type ArchiveAttachment struct {
+	ID          uuid.UUID `json:"id"`
+	FieldID     uuid.UUID `json:"field_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	File        string    `json:"file"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ArchiveAutomation is an automation without its run history. Inbound webhook
+// tokens are left out; imported automations get new ones.
+type ArchiveAutomation struct {
+	ID            uuid.UUID       `json:"id"`
+	TableID       uuid.UUID       `json:"table_id"`
+	Name          string          `json:"name"`
+	Description   *string         `json:"description,omitempty"`
+	Enabled       bool            `json:"enabled"`
+	TriggerType   TriggerType     `json:"trigger_type"`
+	TriggerConfig json.RawMessage `json:"trigger_config"`
+	ActionType    ActionType      `json:"action_type"`
+	ActionConfig  json.RawMessage `json:"action_config"`
+}
+
+// ArchiveWebhook is a webhook's configuration without its secret. Imported
+// webhooks start inactive until they are given a secret and turned back on.
+type ArchiveWebhook struct {
+	ID              uuid.UUID      `json:"id"`
+	Name            string         `json:"name"`
+	URL             string         `json:"url"`
+	Events          []WebhookEvent `json:"events"`
+	TableIDs        []uuid.UUID    `json:"table_ids"`
+	WatchFieldIDs   []uuid.UUID    `json:"watch_field_ids"`
+	Format          WebhookFormat  `json:"format"`
+	MessageTemplate *string        `json:"message_template,omitempty"`
+}
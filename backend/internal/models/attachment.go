@@ -48,6 +48,50 @@ func (a *Attachment) IsImage() bool {
 	}
 }
 
+// ThumbnailSize is a bounding box image attachments get a thumbnail for
+type ThumbnailSize struct {
+	Name         string
+	MaxDimension int
+}
+
+// ThumbnailSizes are the thumbnails generated for each image attachment
+var ThumbnailSizes = []ThumbnailSize{
+	{Name: "small", MaxDimension: 256},
+	{Name: "large", MaxDimension: 1024},
+}
+
+// DefaultThumbnailSize is served when no size is asked for
+const DefaultThumbnailSize = "small"
+
+// HasThumbnailSize reports whether name is one of ThumbnailSizes
+func HasThumbnailSize(name string) bool {
+	for _, size := range ThumbnailSizes {
+		if size.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CanThumbnail returns true if thumbnails can be generated for the content type
+func CanThumbnail(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+// ThumbnailStorageKey returns the storage key of one thumbnail size, or "" if the
+// attachment has no thumbnails. ThumbnailKey holds the prefix shared by all sizes.
+func (a *Attachment) ThumbnailStorageKey(size string) string {
+	if a.ThumbnailKey == nil {
+		return ""
+	}
+	return *a.ThumbnailKey + "_" + size + ".jpg"
+}
+
 // ToSummary converts an Attachment to AttachmentSummary
 func (a *Attachment) ToSummary() AttachmentSummary {
 	return AttachmentSummary{
@@ -61,3 +105,11 @@ func (a *Attachment) ToSummary() AttachmentSummary {
 		ThumbnailURL: a.ThumbnailURL,
 	}
 }
+
+// StorageUsage is how much attachment storage a base uses and its quota
+type StorageUsage struct {
+	BaseID          uuid.UUID `json:"base_id"`
+	AttachmentCount int64     `json:"attachment_count"`
+	UsedBytes       int64     `json:"used_bytes"`
+	QuotaBytes      *int64    `json:"quota_bytes"` // nil when unlimited
+}
@@ -15,6 +15,10 @@ const (
 	TriggerRecordUpdated     TriggerType = "record_updated"
 	TriggerRecordDeleted     TriggerType = "record_deleted"
 	TriggerFieldValueChanged TriggerType = "field_value_changed"
+	TriggerFormSubmitted     TriggerType = "form_submitted"
+	TriggerCommentAdded      TriggerType = "comment_added"
+	TriggerRecordEntersView  TriggerType = "record_enters_view"
+	TriggerWebhookReceived   TriggerType = "webhook_received"
 	TriggerScheduled         TriggerType = "scheduled" // Future: cron-based triggers
 )
 
@@ -26,6 +30,11 @@ const (
 	ActionUpdateRecord ActionType = "update_record"
 	ActionCreateRecord ActionType = "create_record"
 	ActionSendWebhook  ActionType = "send_webhook"
+
+	ActionDeleteRecord        ActionType = "delete_record"
+	ActionAddComment          ActionType = "add_comment"
+	ActionUpdateLinkedRecords ActionType = "update_linked_records"
+	ActionFindRecords         ActionType = "find_records"
 )
 
 // RunStatus represents the status of an automation run
@@ -79,6 +88,27 @@ type FieldValueChangedConfig struct {
 	Value    any       `json:"value,omitempty"`    // Optional: only trigger when value matches
 }
 
+// FormSubmittedConfig optionally restricts the trigger to a single form
+type FormSubmittedConfig struct {
+	FormID *uuid.UUID `json:"formId,omitempty"` // Empty means any form on the table
+}
+
+// CommentAddedConfig optionally restricts the trigger to matching comments
+type CommentAddedConfig struct {
+	Contains string `json:"contains,omitempty"` // Only trigger when the comment contains this text
+}
+
+// RecordEntersViewConfig specifies the view whose filters a record must start matching
+type RecordEntersViewConfig struct {
+	ViewID uuid.UUID `json:"viewId"`
+}
+
+// WebhookReceivedConfig holds the secret token for the automation's inbound URL.
+// The token is generated by the server and cannot be set by clients.
+type WebhookReceivedConfig struct {
+	Token string `json:"token"`
+}
+
 // ScheduledConfig for scheduled triggers (future)
 type ScheduledConfig struct {
 	CronExpression string `json:"cronExpression"`
@@ -119,6 +149,33 @@ type SendWebhookConfig struct {
 	Body    string            `json:"body,omitempty"` // JSON template with field references
 }
 
+// AddCommentConfig configures a comment posted on the triggering record
+type AddCommentConfig struct {
+	Content string `json:"content"` // Can include field references
+}
+
+// UpdateLinkedRecordsConfig updates every record linked from the triggering record
+type UpdateLinkedRecordsConfig struct {
+	LinkedFieldID uuid.UUID     `json:"linkedFieldId"` // linked_record field on the automation's table
+	Updates       []FieldUpdate `json:"updates"`       // Fields in the linked table
+}
+
+// FindRecordsConfig finds records in a table matching all conditions and
+// applies the optional updates to each match
+type FindRecordsConfig struct {
+	TableID    uuid.UUID         `json:"tableId"`
+	Conditions []RecordCondition `json:"conditions"`
+	Limit      int               `json:"limit,omitempty"`   // Defaults to 100
+	Updates    []FieldUpdate     `json:"updates,omitempty"` // Applied to every matching record
+}
+
+// RecordCondition compares a field value using the view filter operators
+type RecordCondition struct {
+	FieldID  uuid.UUID `json:"fieldId"`
+	Operator string    `json:"operator"`        // equals, not_equals, contains, not_contains, is_empty, is_not_empty, greater_than, less_than
+	Value    string    `json:"value,omitempty"` // Can include field references
+}
+
 // AutomationWithRuns includes recent run history
 type AutomationWithRuns struct {
 	Automation
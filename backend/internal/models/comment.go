@@ -7,14 +7,15 @@ import (
 )
 
 type Comment struct {
-	ID         uuid.UUID  `json:"id"`
-	RecordID   uuid.UUID  `json:"record_id"`
-	UserID     uuid.UUID  `json:"user_id"`
-	Content    string     `json:"content"`
-	ParentID   *uuid.UUID `json:"parent_id,omitempty"`
-	IsResolved bool       `json:"is_resolved"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	ID           uuid.UUID  `json:"id"`
+	RecordID     uuid.UUID  `json:"record_id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Content      string     `json:"content"`
+	ParentID     *uuid.UUID `json:"parent_id,omitempty"`
+	AutomationID *uuid.UUID `json:"automation_id,omitempty"` // Set when posted by an automation
+	IsResolved   bool       `json:"is_resolved"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 
 	// Joined fields (not in database)
 	User    *User      `json:"user,omitempty"`
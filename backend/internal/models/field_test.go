@@ -3,6 +3,7 @@ package models
 import (
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -93,3 +94,26 @@ func TestIsComputedField(t *testing.T) {
 		})
 	}
 }
+
+func TestLinkedRecordIDs(t *testing.T) {
+	id1 := uuid.New()
+	id2 := uuid.New()
+
+	t.Run("parses JSON array values", func(t *testing.T) {
+		ids := LinkedRecordIDs([]interface{}{id1.String(), "not-a-uuid", 42, id2.String()})
+		assert.Equal(t, []uuid.UUID{id1, id2}, ids)
+	})
+
+	t.Run("parses string slices", func(t *testing.T) {
+		assert.Equal(t, []uuid.UUID{id1}, LinkedRecordIDs([]string{id1.String()}))
+	})
+
+	t.Run("parses a single ID", func(t *testing.T) {
+		assert.Equal(t, []uuid.UUID{id2}, LinkedRecordIDs(id2.String()))
+	})
+
+	t.Run("returns nil for empty values", func(t *testing.T) {
+		assert.Nil(t, LinkedRecordIDs(nil))
+		assert.Nil(t, LinkedRecordIDs(""))
+	})
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobType names an operation that runs in the background
+type JobType string
+
+const (
+	JobDuplicateBase  JobType = "duplicate_base"
+	JobDuplicateTable JobType = "duplicate_table"
+	JobImportRecords  JobType = "import_records"
+)
+
+// JobStatus is where a job is in its life
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// IsFinished returns true once a job will not run any more
+func (s JobStatus) IsFinished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCancelled
+}
+
+// Job is a long-running operation on a base. Progress counts the steps done out
+// of Total, which is 0 until the job knows how many there are. Result holds what
+// the operation returned once it has succeeded.
+type Job struct {
+	ID              uuid.UUID       `json:"id"`
+	BaseID          uuid.UUID       `json:"base_id"`
+	JobType         JobType         `json:"job_type"`
+	Status          JobStatus       `json:"status"`
+	Payload         json.RawMessage `json:"-"`
+	Progress        int             `json:"progress"`
+	Total           int             `json:"total"`
+	Result          json.RawMessage `json:"result,omitempty"`
+	Error           *string         `json:"error,omitempty"`
+	CancelRequested bool            `json:"cancel_requested"`
+	Attempts        int             `json:"-"`
+	CreatedBy       uuid.UUID       `json:"created_by"`
+	CreatedAt       time.Time       `json:"created_at"`
+	StartedAt       *time.Time      `json:"started_at,omitempty"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// DuplicateBasePayload is the input of a duplicate_base job, which copies the job's base
+type DuplicateBasePayload struct {
+	IncludeRecords bool `json:"include_records"`
+}
+
+// DuplicateTablePayload is the input of a duplicate_table job
+type DuplicateTablePayload struct {
+	TableID        uuid.UUID `json:"table_id"`
+	IncludeRecords bool      `json:"include_records"`
+}
+
+// ImportRecordsPayload is the input of an import_records job: the values of the
+// records to create, already converted from the imported file. Skipped and Errors
+// count the rows left out while converting, for the result.
+type ImportRecordsPayload struct {
+	TableID uuid.UUID         `json:"table_id"`
+	Records []json.RawMessage `json:"records"`
+	Skipped int               `json:"skipped"`
+	Errors  int               `json:"errors"`
+}
+
+// ImportRecordsResult is the result of an import_records job
+type ImportRecordsResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+	Errors   int `json:"errors"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BaseTemplate is a base's structure published for anyone to start a new base
+// from. Content holds it as an archive without webhooks, comments or attachments,
+// and without records unless IncludesRecords is set.
+type BaseTemplate struct {
+	ID              uuid.UUID    `json:"id"`
+	Name            string       `json:"name"`
+	Description     *string      `json:"description,omitempty"`
+	SourceBaseID    *uuid.UUID   `json:"source_base_id,omitempty"`
+	IncludesRecords bool         `json:"includes_records"`
+	TableCount      int          `json:"table_count"`
+	CreatedBy       uuid.UUID    `json:"created_by"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	Content         *BaseArchive `json:"content,omitempty"` // Only set when getting a single template
+}
+
+// CreateTemplateRequest publishes a base as a template
+type CreateTemplateRequest struct {
+	BaseID         uuid.UUID `json:"base_id"`
+	Name           string    `json:"name"`
+	Description    *string   `json:"description,omitempty"`
+	IncludeRecords bool      `json:"include_records"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UploadSession is a resumable attachment upload. The file is sent in chunks, each
+// starting where the last left off, and becomes an attachment once complete.
+type UploadSession struct {
+	ID            uuid.UUID `json:"id"`
+	RecordID      uuid.UUID `json:"record_id"`
+	FieldID       uuid.UUID `json:"field_id"`
+	TableID       uuid.UUID `json:"table_id"`
+	BaseID        uuid.UUID `json:"base_id"`
+	Filename      string    `json:"filename"`
+	ContentType   string    `json:"content_type"`
+	SizeBytes     int64     `json:"size_bytes"`
+	ReceivedBytes int64     `json:"received_bytes"` // Offset the next chunk starts at
+	MaxChunkBytes int64     `json:"max_chunk_bytes"`
+	CreatedBy     uuid.UUID `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ExpiresAt     time.Time `json:"expires_at"` // Abandoned uploads are removed after this
+}
+
+// CreateUploadRequest starts a resumable upload
+type CreateUploadRequest struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+}
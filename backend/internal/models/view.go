@@ -2,6 +2,9 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -76,3 +79,101 @@ type ViewSort struct {
 	FieldID   string `json:"field_id"`
 	Direction string `json:"direction"` // "asc" or "desc"
 }
+
+// MatchesRecord reports whether record values satisfy every filter in the view config.
+// Filters are evaluated the same way the frontend applies them; unknown operators match.
+func (c ViewConfig) MatchesRecord(values map[string]interface{}) bool {
+	for _, filter := range c.Filters {
+		if !filter.Matches(values[filter.FieldID]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether a single cell value satisfies the filter
+func (f ViewFilter) Matches(value interface{}) bool {
+	switch f.Operator {
+	case "equals":
+		return filterEquals(value, f.Value)
+	case "not_equals":
+		return !filterEquals(value, f.Value)
+	case "contains":
+		return filterContains(value, f.Value)
+	case "not_contains":
+		return !filterContains(value, f.Value)
+	case "is_empty", "empty":
+		return isEmptyFilterValue(value)
+	case "is_not_empty", "not_empty":
+		return !isEmptyFilterValue(value)
+	case "greater_than", "gt":
+		a, okA := filterNumber(value)
+		b, okB := filterNumber(f.Value)
+		return okA && okB && a > b
+	case "less_than", "lt":
+		a, okA := filterNumber(value)
+		b, okB := filterNumber(f.Value)
+		return okA && okB && a < b
+	default:
+		return true
+	}
+}
+
+func filterEquals(value interface{}, target string) bool {
+	if a, ok := filterNumber(value); ok {
+		if b, ok := filterNumber(target); ok {
+			return a == b
+		}
+	}
+	return strings.EqualFold(filterString(value), target)
+}
+
+func filterContains(value interface{}, target string) bool {
+	target = strings.ToLower(target)
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if strings.Contains(strings.ToLower(filterString(item)), target) {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.Contains(strings.ToLower(filterString(value)), target)
+}
+
+func isEmptyFilterValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+func filterString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func filterNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -41,3 +41,42 @@ func TestIsValidViewType_AllTypes(t *testing.T) {
 	assert.True(t, IsValidViewType(ViewTypeCalendar))
 	assert.True(t, IsValidViewType(ViewTypeGallery))
 }
+
+func TestViewConfig_MatchesRecord(t *testing.T) {
+	values := map[string]interface{}{
+		"status": "Done",
+		"score":  float64(42),
+		"notes":  "",
+		"tags":   []interface{}{"urgent", "backend"},
+	}
+
+	tests := []struct {
+		name     string
+		filters  []ViewFilter
+		expected bool
+	}{
+		{"no filters", nil, true},
+		{"equals is case-insensitive", []ViewFilter{{FieldID: "status", Operator: "equals", Value: "done"}}, true},
+		{"equals compares numbers", []ViewFilter{{FieldID: "score", Operator: "equals", Value: "42.0"}}, true},
+		{"not_equals", []ViewFilter{{FieldID: "status", Operator: "not_equals", Value: "Done"}}, false},
+		{"contains in array", []ViewFilter{{FieldID: "tags", Operator: "contains", Value: "URG"}}, true},
+		{"not_contains", []ViewFilter{{FieldID: "status", Operator: "not_contains", Value: "on"}}, false},
+		{"is_empty on empty string", []ViewFilter{{FieldID: "notes", Operator: "is_empty"}}, true},
+		{"empty alias on missing field", []ViewFilter{{FieldID: "missing", Operator: "empty"}}, true},
+		{"not_empty on array", []ViewFilter{{FieldID: "tags", Operator: "not_empty"}}, true},
+		{"greater_than", []ViewFilter{{FieldID: "score", Operator: "greater_than", Value: "40"}}, true},
+		{"lt alias", []ViewFilter{{FieldID: "score", Operator: "lt", Value: "40"}}, false},
+		{"unknown operator matches", []ViewFilter{{FieldID: "score", Operator: "between", Value: "1"}}, true},
+		{"all filters must match", []ViewFilter{
+			{FieldID: "status", Operator: "equals", Value: "Done"},
+			{FieldID: "score", Operator: "lt", Value: "10"},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ViewConfig{Filters: tt.filters}
+			assert.Equal(t, tt.expected, config.MatchesRecord(values))
+		})
+	}
+}
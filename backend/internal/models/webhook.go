@@ -13,6 +13,24 @@ const (
 	WebhookEventRecordCreated WebhookEvent = "record.created"
 	WebhookEventRecordUpdated WebhookEvent = "record.updated"
 	WebhookEventRecordDeleted WebhookEvent = "record.deleted"
+
+	WebhookEventFieldCreated WebhookEvent = "field.created"
+	WebhookEventFieldUpdated WebhookEvent = "field.updated"
+	WebhookEventFieldDeleted WebhookEvent = "field.deleted"
+
+	WebhookEventTableCreated WebhookEvent = "table.created"
+	WebhookEventTableUpdated WebhookEvent = "table.updated"
+	WebhookEventTableDeleted WebhookEvent = "table.deleted"
+
+	WebhookEventViewCreated WebhookEvent = "view.created"
+	WebhookEventViewUpdated WebhookEvent = "view.updated"
+	WebhookEventViewDeleted WebhookEvent = "view.deleted"
+
+	WebhookEventCommentCreated WebhookEvent = "comment.created"
+	WebhookEventCommentUpdated WebhookEvent = "comment.updated"
+	WebhookEventCommentDeleted WebhookEvent = "comment.deleted"
+
+	WebhookEventFormSubmitted WebhookEvent = "form.submitted"
 )
 
 // ValidWebhookEvents returns all valid webhook events
@@ -21,9 +39,69 @@ func ValidWebhookEvents() []WebhookEvent {
 		WebhookEventRecordCreated,
 		WebhookEventRecordUpdated,
 		WebhookEventRecordDeleted,
+		WebhookEventFieldCreated,
+		WebhookEventFieldUpdated,
+		WebhookEventFieldDeleted,
+		WebhookEventTableCreated,
+		WebhookEventTableUpdated,
+		WebhookEventTableDeleted,
+		WebhookEventViewCreated,
+		WebhookEventViewUpdated,
+		WebhookEventViewDeleted,
+		WebhookEventCommentCreated,
+		WebhookEventCommentUpdated,
+		WebhookEventCommentDeleted,
+		WebhookEventFormSubmitted,
 	}
 }
 
+// IsValidWebhookEvent reports whether an event name is one webhooks can subscribe to
+func IsValidWebhookEvent(event WebhookEvent) bool {
+	for _, valid := range ValidWebhookEvents() {
+		if event == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookFormat is the shape of the request body sent to a webhook
+type WebhookFormat string
+
+const (
+	WebhookFormatNative            WebhookFormat = "native"             // WebhookPayload as JSON
+	WebhookFormatCloudEvents       WebhookFormat = "cloudevents"        // CloudEvents 1.0 structured mode
+	WebhookFormatCloudEventsBinary WebhookFormat = "cloudevents_binary" // CloudEvents 1.0 binary mode
+	WebhookFormatSlack             WebhookFormat = "slack"              // Slack incoming webhook message
+	WebhookFormatTeams             WebhookFormat = "teams"              // Microsoft Teams message card
+)
+
+// ValidWebhookFormats returns all valid webhook payload formats
+func ValidWebhookFormats() []WebhookFormat {
+	return []WebhookFormat{
+		WebhookFormatNative,
+		WebhookFormatCloudEvents,
+		WebhookFormatCloudEventsBinary,
+		WebhookFormatSlack,
+		WebhookFormatTeams,
+	}
+}
+
+// IsValidWebhookFormat reports whether format is a known payload format
+func IsValidWebhookFormat(format WebhookFormat) bool {
+	for _, valid := range ValidWebhookFormats() {
+		if format == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsChat reports whether the format posts a rendered message rather than event data
+func (f WebhookFormat) IsChat() bool {
+	return f == WebhookFormatSlack || f == WebhookFormatTeams
+}
+
 // Webhook represents a webhook configuration
 type Webhook struct {
 	ID        uuid.UUID      `json:"id"`
@@ -31,28 +109,83 @@ type Webhook struct {
 	Name      string         `json:"name"`
 	URL       string         `json:"url"`
 	Events    []WebhookEvent `json:"events"`
-	Secret    *string        `json:"secret,omitempty"` // For HMAC verification
+	Secret    *string        `json:"secret,omitempty"` // For HMAC verification; only returned when created or rotated
 	IsActive  bool           `json:"is_active"`
 	CreatedBy uuid.UUID      `json:"created_by"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
+
+	// Filters: empty lists match every table / any field
+	TableIDs      []uuid.UUID `json:"table_ids"`       // Tables whose events are delivered
+	WatchFieldIDs []uuid.UUID `json:"watch_field_ids"` // record.updated fires only when one of these fields changes
+
+	Format          WebhookFormat `json:"format"`
+	MessageTemplate *string       `json:"message_template,omitempty"` // Chat formats only; uses the automation template syntax
+
+	// The previous secret also signs deliveries until it expires, while receivers switch over
+	HasSecret               bool       `json:"has_secret"`
+	PreviousSecret          *string    `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	DisabledReason      *string `json:"disabled_reason,omitempty"` // Set when disabled after repeated failures
+}
+
+// SigningSecrets returns the secrets deliveries are signed with at the given time:
+// the current secret and, during a rotation, the previous one
+func (w *Webhook) SigningSecrets(now time.Time) []string {
+	var secrets []string
+	if w.Secret != nil && *w.Secret != "" {
+		secrets = append(secrets, *w.Secret)
+	}
+	if w.PreviousSecret != nil && *w.PreviousSecret != "" &&
+		w.PreviousSecretExpiresAt != nil && now.Before(*w.PreviousSecretExpiresAt) {
+		secrets = append(secrets, *w.PreviousSecret)
+	}
+	return secrets
+}
+
+// RedactSecrets clears the secrets before a webhook is returned to a client
+func (w *Webhook) RedactSecrets() {
+	w.Secret = nil
+	w.PreviousSecret = nil
 }
 
 // CreateWebhookRequest represents a request to create a webhook
 type CreateWebhookRequest struct {
-	Name   string         `json:"name"`
-	URL    string         `json:"url"`
-	Events []WebhookEvent `json:"events,omitempty"`
-	Secret *string        `json:"secret,omitempty"`
+	Name            string         `json:"name"`
+	URL             string         `json:"url"`
+	Events          []WebhookEvent `json:"events,omitempty"`
+	Secret          *string        `json:"secret,omitempty"` // Generated when not provided
+	TableIDs        []uuid.UUID    `json:"table_ids,omitempty"`
+	WatchFieldIDs   []uuid.UUID    `json:"watch_field_ids,omitempty"`
+	Format          WebhookFormat  `json:"format,omitempty"` // Defaults to native
+	MessageTemplate *string        `json:"message_template,omitempty"`
 }
 
 // UpdateWebhookRequest represents a request to update a webhook
 type UpdateWebhookRequest struct {
-	Name     *string        `json:"name,omitempty"`
-	URL      *string        `json:"url,omitempty"`
-	Events   []WebhookEvent `json:"events,omitempty"`
-	Secret   *string        `json:"secret,omitempty"`
-	IsActive *bool          `json:"is_active,omitempty"`
+	Name            *string        `json:"name,omitempty"`
+	URL             *string        `json:"url,omitempty"`
+	Events          []WebhookEvent `json:"events,omitempty"`
+	Secret          *string        `json:"secret,omitempty"` // Replaces the secret at once; use rotation to keep the old one working
+	IsActive        *bool          `json:"is_active,omitempty"`
+	TableIDs        []uuid.UUID    `json:"table_ids,omitempty"`       // An empty list removes the filter
+	WatchFieldIDs   []uuid.UUID    `json:"watch_field_ids,omitempty"` // An empty list removes the filter
+	Format          *WebhookFormat `json:"format,omitempty"`
+	MessageTemplate *string        `json:"message_template,omitempty"` // An empty string restores the default message
+}
+
+// RotateWebhookSecretRequest replaces a webhook's secret, keeping the old one valid for
+// a grace period
+type RotateWebhookSecretRequest struct {
+	Secret           *string `json:"secret,omitempty"`             // Generated when not provided
+	GracePeriodHours *int    `json:"grace_period_hours,omitempty"` // Defaults to 24; 0 revokes the old secret immediately
+}
+
+// TestWebhookRequest asks for a sample event to be sent to a webhook
+type TestWebhookRequest struct {
+	Event WebhookEvent `json:"event,omitempty"` // Defaults to the webhook's first event
 }
 
 // WebhookDelivery represents a webhook delivery attempt
@@ -66,16 +199,55 @@ type WebhookDelivery struct {
 	Error          *string    `json:"error,omitempty"`
 	DurationMs     *int       `json:"duration_ms,omitempty"`
 	DeliveredAt    time.Time  `json:"delivered_at"`
+	Attempt        int        `json:"attempt"`
+	QueueItemID    *uuid.UUID `json:"queue_item_id,omitempty"`
+}
+
+// Webhook queue item statuses
+const (
+	WebhookQueuePending   = "pending"
+	WebhookQueueDelivered = "delivered"
+	WebhookQueueFailed    = "failed"
+)
+
+// WebhookQueueItem is an event waiting to be delivered (or retried) to a webhook
+type WebhookQueueItem struct {
+	ID            uuid.UUID  `json:"id"`
+	WebhookID     uuid.UUID  `json:"webhook_id"`
+	EventType     string     `json:"event_type"`
+	Payload       string     `json:"payload"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error,omitempty"`
+	RedeliveryOf  *uuid.UUID `json:"redelivery_of,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
-// WebhookPayload represents the payload sent to webhook endpoints
+// WebhookPayload represents the payload sent to webhook endpoints. Which of the
+// optional fields are set depends on the event.
 type WebhookPayload struct {
-	Event     WebhookEvent   `json:"event"`
-	Timestamp time.Time      `json:"timestamp"`
-	BaseID    uuid.UUID      `json:"base_id"`
-	TableID   uuid.UUID      `json:"table_id"`
-	RecordID  *uuid.UUID     `json:"record_id,omitempty"`
-	Record    *Record        `json:"record,omitempty"`
-	OldRecord *Record        `json:"old_record,omitempty"`
-	UserID    uuid.UUID      `json:"user_id"`
+	ID        uuid.UUID            `json:"id"` // Shared by every delivery of the same event
+	Event     WebhookEvent         `json:"event"`
+	Timestamp time.Time            `json:"timestamp"`
+	BaseID    uuid.UUID            `json:"base_id"`
+	TableID   uuid.UUID            `json:"table_id"`
+	RecordID  *uuid.UUID           `json:"record_id,omitempty"`
+	Record    *Record              `json:"record,omitempty"`
+	Changes   []WebhookFieldChange `json:"changes,omitempty"` // record.updated only
+	Field     *Field               `json:"field,omitempty"`
+	Table     *Table               `json:"table,omitempty"`
+	View      *View                `json:"view,omitempty"`
+	Comment   *Comment             `json:"comment,omitempty"`
+	FormID    *uuid.UUID           `json:"form_id,omitempty"`
+	UserID    uuid.UUID            `json:"user_id"`
+	Test      bool                 `json:"test,omitempty"` // Sample event sent from the test endpoint
+}
+
+// WebhookFieldChange is one field's before and after values in a record.updated payload
+type WebhookFieldChange struct {
+	FieldID  string      `json:"field_id"`
+	OldValue interface{} `json:"old_value"`
+	NewValue interface{} `json:"new_value"`
 }
@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -9,10 +10,22 @@ import (
 func TestValidWebhookEvents(t *testing.T) {
 	events := ValidWebhookEvents()
 
-	assert.Len(t, events, 3)
+	assert.Len(t, events, 16)
 	assert.Contains(t, events, WebhookEventRecordCreated)
 	assert.Contains(t, events, WebhookEventRecordUpdated)
 	assert.Contains(t, events, WebhookEventRecordDeleted)
+	assert.Contains(t, events, WebhookEventFieldCreated)
+	assert.Contains(t, events, WebhookEventTableDeleted)
+	assert.Contains(t, events, WebhookEventViewUpdated)
+	assert.Contains(t, events, WebhookEventCommentCreated)
+	assert.Contains(t, events, WebhookEventFormSubmitted)
+}
+
+func TestIsValidWebhookEvent(t *testing.T) {
+	assert.True(t, IsValidWebhookEvent(WebhookEventRecordCreated))
+	assert.True(t, IsValidWebhookEvent(WebhookEventFormSubmitted))
+	assert.False(t, IsValidWebhookEvent("record.archived"))
+	assert.False(t, IsValidWebhookEvent(""))
 }
 
 func TestWebhookEventConstants(t *testing.T) {
@@ -20,3 +33,41 @@ func TestWebhookEventConstants(t *testing.T) {
 	assert.Equal(t, WebhookEvent("record.updated"), WebhookEventRecordUpdated)
 	assert.Equal(t, WebhookEvent("record.deleted"), WebhookEventRecordDeleted)
 }
+
+func TestIsValidWebhookFormat(t *testing.T) {
+	for _, format := range ValidWebhookFormats() {
+		assert.True(t, IsValidWebhookFormat(format), format)
+	}
+	assert.False(t, IsValidWebhookFormat("xml"))
+	assert.False(t, IsValidWebhookFormat(""))
+}
+
+func TestWebhookFormat_IsChat(t *testing.T) {
+	assert.True(t, WebhookFormatSlack.IsChat())
+	assert.True(t, WebhookFormatTeams.IsChat())
+	assert.False(t, WebhookFormatNative.IsChat())
+	assert.False(t, WebhookFormatCloudEvents.IsChat())
+}
+
+func TestWebhook_SigningSecrets(t *testing.T) {
+	now := time.Now()
+	current, previous := "current", "previous"
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	assert.Empty(t, (&Webhook{}).SigningSecrets(now))
+	assert.Equal(t, []string{current}, (&Webhook{Secret: &current}).SigningSecrets(now))
+	assert.Equal(t, []string{current, previous}, (&Webhook{Secret: &current, PreviousSecret: &previous, PreviousSecretExpiresAt: &later}).SigningSecrets(now))
+	assert.Equal(t, []string{current}, (&Webhook{Secret: &current, PreviousSecret: &previous, PreviousSecretExpiresAt: &earlier}).SigningSecrets(now))
+	assert.Equal(t, []string{current}, (&Webhook{Secret: &current, PreviousSecret: &previous}).SigningSecrets(now))
+}
+
+func TestWebhook_RedactSecrets(t *testing.T) {
+	current, previous := "current", "previous"
+	webhook := &Webhook{Secret: &current, PreviousSecret: &previous, HasSecret: true}
+
+	webhook.RedactSecrets()
+	assert.Nil(t, webhook.Secret)
+	assert.Nil(t, webhook.PreviousSecret)
+	assert.True(t, webhook.HasSecret)
+}
@@ -0,0 +1,315 @@
+// Package outbound provides the HTTP client used for requests to user-supplied URLs,
+// such as webhook deliveries and automation webhooks. It refuses to connect to
+// loopback, private, link-local and other internal addresses unless they are
+// explicitly allowlisted, and pins each connection to the address it validated so
+// a DNS answer cannot change between the check and the dial.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidURL          = errors.New("invalid URL")
+	ErrBlockedDestination  = errors.New("destination is not allowed")
+	ErrTooManyRedirects    = errors.New("too many redirects")
+	ErrResponseTooLarge    = errors.New("response body too large")
+	errNoResolvedAddresses = errors.New("no addresses found")
+)
+
+// AllowlistEnv names the environment variable holding extra allowed destinations
+const AllowlistEnv = "OUTBOUND_ALLOWLIST"
+
+// blockedNetworks are ranges that are never reachable from outside our network or
+// that map onto such ranges. Loopback, private, link-local and multicast addresses
+// are covered by the net.IP helpers in isBlockedIP.
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // "This" network
+	"100.64.0.0/10",   // Carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // TEST-NET-1
+	"198.18.0.0/15",   // Benchmarking
+	"198.51.100.0/24", // TEST-NET-2
+	"203.0.113.0/24",  // TEST-NET-3
+	"240.0.0.0/4",     // Reserved, including broadcast
+	"64:ff9b::/96",    // NAT64, which embeds IPv4 addresses
+	"64:ff9b:1::/48",  // Local-use NAT64
+	"2001::/32",       // Teredo
+	"2001:db8::/32",   // Documentation
+	"2002::/16",       // 6to4, which embeds IPv4 addresses
+	"fec0::/10",       // Deprecated site-local
+	"100::/64",        // Discard-only
+)
+
+// Policy controls which destinations outbound requests may reach and how much they may read
+type Policy struct {
+	AllowedNetworks  []*net.IPNet  // Ranges allowed even if they would otherwise be blocked
+	AllowedHosts     []string      // Hostnames allowed regardless of the addresses they resolve to
+	MaxRedirects     int           // Redirects followed before giving up
+	MaxResponseBytes int64         // Bytes of a response body that may be read
+	Timeout          time.Duration // Overall timeout for a request, including reading the body
+
+	// lookup resolves hostnames; tests replace it to avoid real DNS
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// DefaultPolicy blocks all internal destinations, follows up to 3 redirects and reads up to 1MB
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxRedirects:     3,
+		MaxResponseBytes: 1 << 20,
+		Timeout:          30 * time.Second,
+	}
+}
+
+// PolicyFromEnv returns the default policy with the allowlist from OUTBOUND_ALLOWLIST,
+// a comma-separated list of IPs, CIDR ranges and hostnames. Invalid entries are
+// reported and skipped.
+func PolicyFromEnv() (Policy, error) {
+	return DefaultPolicy().WithAllowlist(os.Getenv(AllowlistEnv))
+}
+
+// WithAllowlist returns a copy of the policy that also allows the given comma-separated
+// IPs, CIDR ranges and hostnames. Valid entries are kept even if others fail to parse.
+func (p Policy) WithAllowlist(list string) (Policy, error) {
+	var invalid []string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			p.AllowedNetworks = append(p.AllowedNetworks, network)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			p.AllowedNetworks = append(p.AllowedNetworks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		if strings.ContainsAny(entry, "/: ") {
+			invalid = append(invalid, entry)
+			continue
+		}
+		p.AllowedHosts = append(p.AllowedHosts, strings.ToLower(strings.TrimSuffix(entry, ".")))
+	}
+	if len(invalid) > 0 {
+		return p, fmt.Errorf("invalid %s entries: %s", AllowlistEnv, strings.Join(invalid, ", "))
+	}
+	return p, nil
+}
+
+// Client returns an HTTP client that enforces the policy on every connection,
+// including those made while following redirects
+func (p Policy) Client() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		// Never go through a proxy: the dial below must see the real destination
+		Proxy:                 nil,
+		DialContext:           p.dialContext(dialer),
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: p.Timeout,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   p.Timeout,
+		Transport: &limitedTransport{base: transport, max: p.MaxResponseBytes},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > p.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirect to %s URL", ErrBlockedDestination, req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// CheckURL reports whether a URL may be used as a destination. It resolves the host,
+// so it catches names that point at internal addresses; the client checks again when
+// it connects because DNS answers can change.
+func (p Policy) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: must be an absolute http or https URL", ErrInvalidURL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: must be an absolute http or https URL", ErrInvalidURL)
+	}
+
+	_, err = p.resolve(ctx, u.Hostname())
+	return err
+}
+
+// resolve looks up a host and returns its addresses if all of them are allowed.
+// A host with any blocked address is refused outright rather than dialled selectively.
+func (p Policy) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return nil, fmt.Errorf("%w: missing host", ErrInvalidURL)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !p.allowedIP(ip) {
+			return nil, fmt.Errorf("%w: %s is an internal address", ErrBlockedDestination, ip)
+		}
+		return []net.IP{ip}, nil
+	}
+
+	lookup := p.lookup
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupIPAddr
+	}
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("could not resolve %s: %w", host, errNoResolvedAddresses)
+	}
+
+	hostAllowed := p.allowedHost(host)
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if !hostAllowed && !p.allowedIP(addr.IP) {
+			return nil, fmt.Errorf("%w: %s resolves to internal address %s", ErrBlockedDestination, host, addr.IP)
+		}
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// dialContext resolves and validates the destination, then connects to the validated
+// address itself so a second lookup cannot swap in a different one
+func (p Policy) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := p.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+func (p Policy) allowedHost(host string) bool {
+	for _, allowed := range p.AllowedHosts {
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) allowedIP(ip net.IP) bool {
+	for _, network := range p.AllowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return !isBlockedIP(ip)
+}
+
+// isBlockedIP reports whether an address is internal or otherwise unsuitable as a destination
+func isBlockedIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4 // Treat IPv4-mapped IPv6 addresses as the IPv4 address they carry
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// limitedTransport caps how much of each response body can be read
+type limitedTransport struct {
+	base http.RoundTripper
+	max  int64
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || t.max <= 0 {
+		return resp, err
+	}
+	resp.Body = &limitedBody{body: resp.Body, remaining: t.max}
+	return resp, nil
+}
+
+// limitedBody returns ErrResponseTooLarge once more than the allowed bytes have been read
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Probe for one more byte to tell a body of exactly the limit from a longer one
+		var probe [1]byte
+		n, err := b.body.Read(probe[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
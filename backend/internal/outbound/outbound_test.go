@@ -0,0 +1,222 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticLookup resolves every hostname to the given addresses
+func staticLookup(ips ...string) func(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		addrs := make([]net.IPAddr, len(ips))
+		for i, ip := range ips {
+			addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+		}
+		return addrs, nil
+	}
+}
+
+func TestIsBlockedIP(t *testing.T) {
+	blocked := []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"0.0.0.0", "100.64.0.1", "224.0.0.1", "255.255.255.255",
+		"::1", "::", "fc00::1", "fe80::1", "::ffff:127.0.0.1", "::ffff:169.254.169.254", "64:ff9b::a9fe:a9fe",
+	}
+	for _, ip := range blocked {
+		assert.True(t, isBlockedIP(net.ParseIP(ip)), ip)
+	}
+
+	allowed := []string{"93.184.216.34", "8.8.8.8", "2606:4700:4700::1111"}
+	for _, ip := range allowed {
+		assert.False(t, isBlockedIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestPolicy_WithAllowlist(t *testing.T) {
+	t.Run("parses IPs, ranges and hostnames", func(t *testing.T) {
+		policy, err := DefaultPolicy().WithAllowlist(" 10.0.0.0/8, 192.168.1.5 ,hooks.internal., ::1")
+		require.NoError(t, err)
+
+		assert.Len(t, policy.AllowedNetworks, 3)
+		assert.Equal(t, []string{"hooks.internal"}, policy.AllowedHosts)
+		assert.True(t, policy.allowedIP(net.ParseIP("10.20.30.40")))
+		assert.True(t, policy.allowedIP(net.ParseIP("192.168.1.5")))
+		assert.False(t, policy.allowedIP(net.ParseIP("192.168.1.6")))
+		assert.True(t, policy.allowedIP(net.ParseIP("::1")))
+	})
+
+	t.Run("reports invalid entries and keeps the rest", func(t *testing.T) {
+		policy, err := DefaultPolicy().WithAllowlist("10.0.0.0/33, example.com")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "10.0.0.0/33")
+		assert.Equal(t, []string{"example.com"}, policy.AllowedHosts)
+	})
+
+	t.Run("empty list allows nothing extra", func(t *testing.T) {
+		policy, err := DefaultPolicy().WithAllowlist("")
+		require.NoError(t, err)
+		assert.Empty(t, policy.AllowedNetworks)
+		assert.Empty(t, policy.AllowedHosts)
+	})
+}
+
+func TestPolicy_CheckURL(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		url     string
+		lookup  []string
+		wantErr error
+	}{
+		{"public host", "https://example.com/hook", []string{"93.184.216.34"}, nil},
+		{"metadata address", "http://169.254.169.254/latest/meta-data", nil, ErrBlockedDestination},
+		{"loopback literal", "http://127.0.0.1:8080/admin", nil, ErrBlockedDestination},
+		{"ipv6 loopback literal", "http://[::1]/", nil, ErrBlockedDestination},
+		{"host resolving to private address", "https://internal.example.com", []string{"10.0.0.5"}, ErrBlockedDestination},
+		{"host with one private address", "https://mixed.example.com", []string{"93.184.216.34", "192.168.0.1"}, ErrBlockedDestination},
+		{"unsupported scheme", "ftp://example.com/file", nil, ErrInvalidURL},
+		{"relative URL", "/hooks", nil, ErrInvalidURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultPolicy()
+			policy.lookup = staticLookup(tt.lookup...)
+
+			err := policy.CheckURL(ctx, tt.url)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("allowlisted host may resolve to internal addresses", func(t *testing.T) {
+		policy, err := DefaultPolicy().WithAllowlist("hooks.internal")
+		require.NoError(t, err)
+		policy.lookup = staticLookup("10.0.0.5")
+
+		assert.NoError(t, policy.CheckURL(ctx, "http://hooks.internal/receive"))
+		assert.ErrorIs(t, policy.CheckURL(ctx, "http://other.internal/receive"), ErrBlockedDestination)
+	})
+}
+
+func TestPolicy_Client(t *testing.T) {
+	t.Run("refuses to connect to loopback servers", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer server.Close()
+
+		_, err := DefaultPolicy().Client().Get(server.URL)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrBlockedDestination)
+		assert.False(t, called)
+	})
+
+	t.Run("connects to the validated address of an allowlisted range", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		policy, err := DefaultPolicy().WithAllowlist("127.0.0.0/8")
+		require.NoError(t, err)
+
+		// The hostname is resolved by the policy, not the system resolver
+		policy.lookup = staticLookup("127.0.0.1")
+		_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+		resp, err := policy.Client().Get("http://hooks.example.test:" + port)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(body))
+	})
+
+	t.Run("blocks redirects to internal addresses", func(t *testing.T) {
+		internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer internal.Close()
+
+		public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, internal.URL, http.StatusFound)
+		}))
+		defer public.Close()
+
+		// Treat the first server as public by allowlisting its hostname only
+		policy, err := DefaultPolicy().WithAllowlist("public.example.test")
+		require.NoError(t, err)
+		policy.lookup = staticLookup("127.0.0.1")
+		_, port, _ := net.SplitHostPort(strings.TrimPrefix(public.URL, "http://"))
+
+		_, err = policy.Client().Get("http://public.example.test:" + port)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrBlockedDestination)
+	})
+
+	t.Run("limits redirects", func(t *testing.T) {
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, server.URL, http.StatusFound)
+		}))
+		defer server.Close()
+
+		policy, err := DefaultPolicy().WithAllowlist("127.0.0.1")
+		require.NoError(t, err)
+		policy.MaxRedirects = 2
+
+		_, err = policy.Client().Get(server.URL)
+		assert.ErrorIs(t, err, ErrTooManyRedirects)
+	})
+
+	t.Run("limits response size", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(strings.Repeat("x", 100)))
+		}))
+		defer server.Close()
+
+		policy, err := DefaultPolicy().WithAllowlist("127.0.0.1")
+		require.NoError(t, err)
+		policy.MaxResponseBytes = 10
+
+		resp, err := policy.Client().Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		assert.True(t, errors.Is(err, ErrResponseTooLarge))
+		assert.Len(t, body, 10)
+	})
+
+	t.Run("reads bodies of exactly the limit", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(strings.Repeat("x", 10)))
+		}))
+		defer server.Close()
+
+		policy, err := DefaultPolicy().WithAllowlist("127.0.0.1")
+		require.NoError(t, err)
+		policy.MaxResponseBytes = 10
+
+		resp, err := policy.Client().Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Len(t, body, 10)
+	})
+}
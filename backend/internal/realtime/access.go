@@ -0,0 +1,147 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// reauthorizeInterval is how often long-lived connections are checked against their
+// session and base role
+const reauthorizeInterval = time.Minute
+
+// Authorizer re-checks a connection's access to its base
+type Authorizer interface {
+	// Authorize returns the user's role in the base while the session is valid, or ""
+	// once the session has ended or the user has lost access
+	Authorize(ctx context.Context, sessionID, baseID, userID uuid.UUID) (string, error)
+}
+
+// SetAuthorizer sets the authorizer used to re-check open connections. It must be
+// called before Run.
+func (h *Hub) SetAuthorizer(authorizer Authorizer) {
+	h.authorizer = authorizer
+}
+
+// RevokeAccess disconnects a user's clients from a base on every instance
+func (h *Hub) RevokeAccess(baseID, userID uuid.UUID) {
+	h.changeAccess(&Envelope{Kind: EnvelopeAccess, BaseID: baseID, UserID: userID})
+}
+
+// ChangeRole tells a user's clients on every instance about their new role in a base
+func (h *Hub) ChangeRole(baseID, userID uuid.UUID, role string) {
+	h.changeAccess(&Envelope{Kind: EnvelopeAccess, BaseID: baseID, UserID: userID, Role: role})
+}
+
+// CloseBase disconnects every client of a deleted base on every instance
+func (h *Hub) CloseBase(baseID uuid.UUID) {
+	h.changeAccess(&Envelope{Kind: EnvelopeAccess, BaseID: baseID})
+}
+
+// changeAccess applies an access change here and relays it to other instances
+func (h *Hub) changeAccess(env *Envelope) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.applyAccess(env)
+	h.publish(env)
+}
+
+// applyAccess disconnects or updates the clients an access change affects. An
+// envelope without a user applies to the whole base, and one without a role revokes
+// access. The caller must hold the lock.
+func (h *Hub) applyAccess(env *Envelope) {
+	for client := range h.bases[env.BaseID] {
+		if env.UserID != uuid.Nil && client.userID != env.UserID {
+			continue
+		}
+		switch {
+		case env.Role != "":
+			h.setClientRole(client, env.Role)
+		case env.UserID == uuid.Nil:
+			h.revokeClient(client, MsgTypeBaseDeleted)
+		default:
+			h.revokeClient(client, MsgTypeAccessRevoked)
+		}
+	}
+
+	if env.Role == "" && env.UserID == uuid.Nil {
+		delete(h.remote, env.BaseID)
+	}
+}
+
+// setClientRole updates a client's role and tells it
+func (h *Hub) setClientRole(client *Client, role string) {
+	client.role = role
+	msg := NewMessage(MsgTypeRoleChanged, client.baseID, client.userID).
+		WithPayload(map[string]interface{}{
+			"role": role,
+		})
+	h.sendTo(client, msg)
+}
+
+// revokeClient tells a client why it is being disconnected, then disconnects it
+func (h *Hub) revokeClient(client *Client, reason string) {
+	// A full buffer just closes the socket without the reason
+	select {
+	case client.send <- NewMessage(reason, client.baseID, client.userID):
+	default:
+	}
+	h.removeClient(client)
+}
+
+// reauthorizeLoop periodically re-checks open connections
+func (h *Hub) reauthorizeLoop() {
+	ticker := time.NewTicker(reauthorizeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.reauthorize()
+	}
+}
+
+// reauthorize re-checks every connection and revokes those whose session has ended
+// or whose access has changed. It runs outside the event loop because the checks may
+// query the database.
+func (h *Hub) reauthorize() {
+	type grant struct {
+		sessionID, baseID, userID uuid.UUID
+	}
+
+	h.mu.RLock()
+	grants := make(map[grant]string)
+	for baseID, clients := range h.bases {
+		for client := range clients {
+			grants[grant{client.sessionID, baseID, client.userID}] = client.role
+		}
+	}
+	h.mu.RUnlock()
+
+	ctx := context.Background()
+	for g, current := range grants {
+		role, err := h.authorizer.Authorize(ctx, g.sessionID, g.baseID, g.userID)
+		if err != nil {
+			// Keep the connection; it is checked again on the next pass
+			log.Printf("Failed to re-authorize realtime client: %v", err)
+			continue
+		}
+		if role == current {
+			continue
+		}
+
+		h.mu.Lock()
+		for client := range h.bases[g.baseID] {
+			if client.sessionID != g.sessionID || client.userID != g.userID {
+				continue
+			}
+			if role == "" {
+				h.revokeClient(client, MsgTypeAccessRevoked)
+			} else {
+				h.setClientRole(client, role)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,157 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuthorizer returns fixed roles by user
+type fakeAuthorizer struct {
+	mu    sync.Mutex
+	roles map[uuid.UUID]string
+}
+
+func (a *fakeAuthorizer) Authorize(ctx context.Context, sessionID, baseID, userID uuid.UUID) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.roles[userID], nil
+}
+
+// messageTypes drains a client's buffered messages, reporting whether the hub closed it
+func messageTypes(client *Client) ([]string, bool) {
+	var types []string
+	for {
+		select {
+		case msg, ok := <-client.send:
+			if !ok {
+				return types, true
+			}
+			types = append(types, msg.Type)
+		default:
+			return types, false
+		}
+	}
+}
+
+func TestHub_RevokeAccess(t *testing.T) {
+	hub := NewHub()
+	baseID := uuid.New()
+	removed := newTestClient(hub, baseID)
+	other := newTestClient(hub, baseID)
+	hub.registerClient(removed)
+	hub.registerClient(other)
+	messageTypes(removed)
+	messageTypes(other)
+
+	hub.RevokeAccess(baseID, removed.userID)
+
+	types, closed := messageTypes(removed)
+	assert.Equal(t, []string{MsgTypeAccessRevoked}, types)
+	assert.True(t, closed)
+
+	types, closed = messageTypes(other)
+	assert.Equal(t, []string{MsgTypeUserLeft}, types)
+	assert.False(t, closed)
+	assert.Equal(t, 1, hub.GetActiveUsers(baseID))
+	assert.Len(t, hub.GetPresence(baseID), 1)
+
+	// The read pump unregistering afterwards is harmless
+	hub.unregisterClient(removed)
+	assert.Equal(t, 1, hub.GetActiveUsers(baseID))
+}
+
+func TestHub_ChangeRole(t *testing.T) {
+	hub := NewHub()
+	baseID := uuid.New()
+	client := newTestClient(hub, baseID)
+	client.SetAccess(uuid.New(), "editor")
+	hub.registerClient(client)
+	messageTypes(client)
+
+	hub.ChangeRole(baseID, client.userID, "viewer")
+
+	msg := <-client.send
+	assert.Equal(t, MsgTypeRoleChanged, msg.Type)
+	assert.Equal(t, map[string]interface{}{"role": "viewer"}, msg.Payload)
+	assert.Equal(t, "viewer", client.role)
+	assert.Equal(t, 1, hub.GetActiveUsers(baseID))
+}
+
+func TestHub_CloseBase(t *testing.T) {
+	hub := NewHub()
+	baseID := uuid.New()
+	clients := []*Client{newTestClient(hub, baseID), newTestClient(hub, baseID)}
+	for _, client := range clients {
+		hub.registerClient(client)
+	}
+
+	hub.CloseBase(baseID)
+
+	for _, client := range clients {
+		types, closed := messageTypes(client)
+		assert.Contains(t, types, MsgTypeBaseDeleted)
+		assert.True(t, closed)
+	}
+	assert.Equal(t, 0, hub.GetActiveUsers(baseID))
+}
+
+func TestHub_AccessRelayed(t *testing.T) {
+	backend := &memoryBackend{}
+	hubA, hubB := NewHub(), NewHub()
+	hubA.SetBackend(backend)
+	hubB.SetBackend(backend)
+	go hubA.Run()
+	go hubB.Run()
+	require.Eventually(t, func() bool { return backend.listening() == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	baseID := uuid.New()
+	client := newTestClient(hubB, baseID)
+	hubB.Register(client)
+	nextMessage(t, client, MsgTypePresenceList)
+	require.Eventually(t, func() bool { return len(hubA.GetPresence(baseID)) == 1 }, time.Second, 10*time.Millisecond)
+
+	// Removed through an instance the user isn't connected to
+	hubA.RevokeAccess(baseID, client.userID)
+
+	nextMessage(t, client, MsgTypeAccessRevoked)
+	require.Eventually(t, func() bool { return hubB.GetActiveUsers(baseID) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestHub_reauthorize(t *testing.T) {
+	hub := NewHub()
+	baseID := uuid.New()
+	kept := newTestClient(hub, baseID)
+	demoted := newTestClient(hub, baseID)
+	expired := newTestClient(hub, baseID)
+	for _, client := range []*Client{kept, demoted, expired} {
+		client.SetAccess(uuid.New(), "editor")
+		hub.registerClient(client)
+		messageTypes(client)
+	}
+	hub.SetAuthorizer(&fakeAuthorizer{roles: map[uuid.UUID]string{
+		kept.userID:    "editor",
+		demoted.userID: "viewer",
+	}})
+
+	hub.reauthorize()
+
+	types, closed := messageTypes(kept)
+	assert.NotContains(t, types, MsgTypeRoleChanged)
+	assert.False(t, closed)
+
+	types, closed = messageTypes(demoted)
+	assert.Contains(t, types, MsgTypeRoleChanged)
+	assert.False(t, closed)
+	assert.Equal(t, "viewer", demoted.role)
+
+	types, closed = messageTypes(expired)
+	assert.Equal(t, []string{MsgTypeAccessRevoked}, types)
+	assert.True(t, closed)
+	assert.Equal(t, 2, hub.GetActiveUsers(baseID))
+}
@@ -0,0 +1,54 @@
+package realtime
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Backend relays hub traffic between server instances, so clients connected to
+// different replicas see each other's changes and presence. Without one the hub only
+// reaches clients of the current process.
+type Backend interface {
+	// Publish sends an envelope to every instance, including this one
+	Publish(ctx context.Context, env *Envelope) error
+
+	// Listen delivers envelopes from all instances until ctx is done or the
+	// connection fails
+	Listen(ctx context.Context, deliver func(*Envelope)) error
+}
+
+// Envelope kinds
+const (
+	EnvelopeMessage  = "message"  // A message for a base's clients
+	EnvelopePresence = "presence" // An instance's full presence list for a base
+	EnvelopeAccess   = "access"   // A user's access to a base changed, or the base was deleted
+)
+
+// Presence relay timing: instances announce who is connected to them, and users of
+// an instance that stops announcing are dropped
+const (
+	presenceHeartbeat = 15 * time.Second
+	presenceTimeout   = 3 * presenceHeartbeat
+)
+
+// Envelope wraps hub traffic sent between instances
+type Envelope struct {
+	Origin        uuid.UUID       `json:"origin"` // Instance that published it
+	Kind          string          `json:"kind"`
+	BaseID        uuid.UUID       `json:"baseId"`
+	Message       *Message        `json:"message,omitempty"`
+	ExcludeUserID uuid.UUID       `json:"excludeUserId,omitempty"`
+	Direct        bool            `json:"direct,omitempty"`   // Only for the message's user
+	Presence      []*UserPresence `json:"presence,omitempty"` // Joined or updated users, or the full list
+	UserID        uuid.UUID       `json:"userId,omitempty"`   // User whose access changed; nil for the whole base
+	Role          string          `json:"role,omitempty"`     // New role; empty when access is revoked
+}
+
+// remotePresence is a user connected to another instance
+type remotePresence struct {
+	presence *UserPresence
+	origin   uuid.UUID
+	seenAt   time.Time
+}
@@ -0,0 +1,187 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBackend relays envelopes between hubs in the same process, round-tripping
+// them through JSON like a real backend
+type memoryBackend struct {
+	mu        sync.Mutex
+	listeners []func(*Envelope)
+}
+
+func (b *memoryBackend) Publish(ctx context.Context, env *Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	listeners := append([]func(*Envelope){}, b.listeners...)
+	b.mu.Unlock()
+	for _, deliver := range listeners {
+		var copied Envelope
+		if err := json.Unmarshal(data, &copied); err != nil {
+			return err
+		}
+		deliver(&copied)
+	}
+	return nil
+}
+
+func (b *memoryBackend) Listen(ctx context.Context, deliver func(*Envelope)) error {
+	b.mu.Lock()
+	b.listeners = append(b.listeners, deliver)
+	b.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *memoryBackend) listening() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.listeners)
+}
+
+func newTestClient(hub *Hub, baseID uuid.UUID) *Client {
+	return &Client{hub: hub, userID: uuid.New(), email: "user@example.com", baseID: baseID, send: make(chan *Message, 256)}
+}
+
+// nextMessage waits for a message of the given type, skipping others
+func nextMessage(t *testing.T, client *Client, msgType string) *Message {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-client.send:
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", msgType)
+			return nil
+		}
+	}
+}
+
+func TestHub_Backend(t *testing.T) {
+	backend := &memoryBackend{}
+	hubA, hubB := NewHub(), NewHub()
+	hubA.SetBackend(backend)
+	hubB.SetBackend(backend)
+	go hubA.Run()
+	go hubB.Run()
+	require.Eventually(t, func() bool { return backend.listening() == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	baseID := uuid.New()
+	clientA := newTestClient(hubA, baseID)
+	hubA.Register(clientA)
+	nextMessage(t, clientA, MsgTypePresenceList)
+	require.Eventually(t, func() bool { return len(hubB.GetPresence(baseID)) == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	t.Run("presence reaches clients on other instances", func(t *testing.T) {
+		clientB := newTestClient(hubB, baseID)
+		hubB.Register(clientB)
+
+		joined := nextMessage(t, clientA, MsgTypeUserJoined)
+		assert.Equal(t, clientB.userID, joined.UserID)
+		require.Eventually(t, func() bool { return len(hubA.GetPresence(baseID)) == 2 }, 2*time.Second, 10*time.Millisecond)
+
+		// The joining client's presence list includes users on the other instance
+		list := nextMessage(t, clientB, MsgTypePresenceList)
+		assert.Len(t, list.Payload, 2)
+
+		hubB.Unregister(clientB)
+		left := nextMessage(t, clientA, MsgTypeUserLeft)
+		assert.Equal(t, clientB.userID, left.UserID)
+		assert.Len(t, hubA.GetPresence(baseID), 1)
+	})
+
+	t.Run("broadcasts reach clients on other instances", func(t *testing.T) {
+		recordID := uuid.New()
+		hubB.Broadcast(NewMessage(MsgTypeRecordUpdated, baseID, uuid.New()).WithRecord(recordID))
+
+		msg := nextMessage(t, clientA, MsgTypeRecordUpdated)
+		require.NotNil(t, msg.RecordID)
+		assert.Equal(t, recordID, *msg.RecordID)
+	})
+
+	t.Run("direct messages reach only their user on other instances", func(t *testing.T) {
+		clientB := newTestClient(hubB, baseID)
+		hubB.Register(clientB)
+		nextMessage(t, clientB, MsgTypePresenceList)
+
+		hubB.AnnounceToUser(NewMessage(MsgTypeUploadProgress, baseID, clientB.userID))
+		hubB.AnnounceToUser(NewMessage(MsgTypeUploadProgress, baseID, clientA.userID))
+
+		assert.Equal(t, clientB.userID, nextMessage(t, clientB, MsgTypeUploadProgress).UserID)
+		assert.Equal(t, clientA.userID, nextMessage(t, clientA, MsgTypeUploadProgress).UserID)
+		select {
+		case msg := <-clientB.send:
+			assert.NotEqual(t, MsgTypeUploadProgress, msg.Type)
+		case <-time.After(100 * time.Millisecond):
+		}
+		hubB.Unregister(clientB)
+	})
+}
+
+func TestHub_receiveEnvelope(t *testing.T) {
+	baseID := uuid.New()
+	origin := uuid.New()
+
+	t.Run("ignores its own envelopes", func(t *testing.T) {
+		hub := NewHub()
+		hub.deliverEnvelope(&Envelope{Origin: hub.instanceID, Kind: EnvelopeMessage})
+		assert.Empty(t, hub.incoming)
+	})
+
+	t.Run("presence sync adds and drops remote users", func(t *testing.T) {
+		hub := NewHub()
+		client := newTestClient(hub, baseID)
+		hub.bases[baseID] = map[*Client]bool{client: true}
+
+		stays := &UserPresence{UserID: uuid.New(), Email: "stays@example.com"}
+		goes := &UserPresence{UserID: uuid.New(), Email: "goes@example.com"}
+		hub.receiveEnvelope(&Envelope{Origin: origin, Kind: EnvelopePresence, BaseID: baseID, Presence: []*UserPresence{stays, goes}})
+		assert.Len(t, hub.GetPresence(baseID), 2)
+		assert.Equal(t, MsgTypeUserJoined, (<-client.send).Type)
+		assert.Equal(t, MsgTypeUserJoined, (<-client.send).Type)
+
+		hub.receiveEnvelope(&Envelope{Origin: origin, Kind: EnvelopePresence, BaseID: baseID, Presence: []*UserPresence{stays}})
+		assert.Len(t, hub.GetPresence(baseID), 1)
+		left := <-client.send
+		assert.Equal(t, MsgTypeUserLeft, left.Type)
+		assert.Equal(t, goes.UserID, left.UserID)
+	})
+
+	t.Run("keeps a user who is still connected locally", func(t *testing.T) {
+		hub := NewHub()
+		client := newTestClient(hub, baseID)
+		hub.bases[baseID] = map[*Client]bool{client: true}
+		userID := uuid.New()
+		hub.presence[baseID] = map[uuid.UUID]*UserPresence{userID: {UserID: userID}}
+		hub.setRemotePresence(baseID, origin, &UserPresence{UserID: userID})
+
+		hub.receiveEnvelope(&Envelope{Origin: origin, Kind: EnvelopeMessage, BaseID: baseID, Message: NewMessage(MsgTypeUserLeft, baseID, userID)})
+		assert.Empty(t, client.send)
+		assert.Len(t, hub.GetPresence(baseID), 1)
+	})
+
+	t.Run("drops users of instances that stop announcing", func(t *testing.T) {
+		hub := NewHub()
+		userID := uuid.New()
+		hub.setRemotePresence(baseID, origin, &UserPresence{UserID: userID})
+		hub.remote[baseID][userID].seenAt = time.Now().Add(-2 * presenceTimeout)
+
+		hub.syncPresence()
+		assert.Empty(t, hub.GetPresence(baseID))
+	})
+}
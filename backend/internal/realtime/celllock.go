@@ -0,0 +1,258 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+)
+
+const (
+	// CellLockLease is how long a cell lock lasts; clients renew it by sending
+	// cell_lock again while the user is still editing
+	CellLockLease = 30 * time.Second
+
+	// cellLockSweep is how often expired locks are released
+	cellLockSweep = 5 * time.Second
+)
+
+// CellLock is a user's lease on editing a cell
+type CellLock struct {
+	BaseID    uuid.UUID `json:"baseId"`
+	TableID   uuid.UUID `json:"tableId"`
+	RecordID  uuid.UUID `json:"recordId"`
+	FieldID   uuid.UUID `json:"fieldId"`
+	UserID    uuid.UUID `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CellLocks stores cell locks. Locks are keyed by base, record and field, so a lock
+// taken in one base can never block writes in another.
+type CellLocks interface {
+	// Acquire takes or renews a lock and returns the cell's holder, which is another
+	// user's lock if they hold it
+	Acquire(ctx context.Context, lock *CellLock) (*CellLock, error)
+
+	// Release removes a lock if the user holds it, reporting whether it did
+	Release(ctx context.Context, lock *CellLock) (bool, error)
+
+	// ReleaseUser removes all of a user's locks in a base and returns them
+	ReleaseUser(ctx context.Context, baseID, userID uuid.UUID) ([]*CellLock, error)
+
+	// List returns a base's unexpired locks
+	List(ctx context.Context, baseID uuid.UUID) ([]*CellLock, error)
+
+	// Expire removes expired locks and returns them
+	Expire(ctx context.Context) ([]*CellLock, error)
+}
+
+// MemoryCellLocks is a CellLocks for a single instance
+type MemoryCellLocks struct {
+	mu    sync.Mutex
+	locks map[cellKey]*CellLock
+}
+
+type cellKey struct {
+	baseID, recordID, fieldID uuid.UUID
+}
+
+func keyOf(lock *CellLock) cellKey {
+	return cellKey{lock.BaseID, lock.RecordID, lock.FieldID}
+}
+
+// NewMemoryCellLocks creates an in-memory lock store
+func NewMemoryCellLocks() *MemoryCellLocks {
+	return &MemoryCellLocks{locks: make(map[cellKey]*CellLock)}
+}
+
+// Acquire takes or renews a lock and returns the cell's holder
+func (l *MemoryCellLocks) Acquire(ctx context.Context, lock *CellLock) (*CellLock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := keyOf(lock)
+	if held, ok := l.locks[key]; ok && held.UserID != lock.UserID && held.ExpiresAt.After(time.Now()) {
+		copied := *held
+		return &copied, nil
+	}
+	copied := *lock
+	l.locks[key] = &copied
+	return lock, nil
+}
+
+// Release removes a lock if the user holds it
+func (l *MemoryCellLocks) Release(ctx context.Context, lock *CellLock) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := keyOf(lock)
+	if held, ok := l.locks[key]; ok && held.UserID == lock.UserID {
+		delete(l.locks, key)
+		return true, nil
+	}
+	return false, nil
+}
+
+// ReleaseUser removes all of a user's locks in a base
+func (l *MemoryCellLocks) ReleaseUser(ctx context.Context, baseID, userID uuid.UUID) ([]*CellLock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var released []*CellLock
+	for key, lock := range l.locks {
+		if lock.BaseID == baseID && lock.UserID == userID {
+			delete(l.locks, key)
+			released = append(released, lock)
+		}
+	}
+	return released, nil
+}
+
+// List returns a base's unexpired locks
+func (l *MemoryCellLocks) List(ctx context.Context, baseID uuid.UUID) ([]*CellLock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	locks := []*CellLock{}
+	for _, lock := range l.locks {
+		if lock.BaseID == baseID && lock.ExpiresAt.After(now) {
+			copied := *lock
+			locks = append(locks, &copied)
+		}
+	}
+	return locks, nil
+}
+
+// Expire removes expired locks
+func (l *MemoryCellLocks) Expire(ctx context.Context) ([]*CellLock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var expired []*CellLock
+	for key, lock := range l.locks {
+		if !lock.ExpiresAt.After(now) {
+			delete(l.locks, key)
+			expired = append(expired, lock)
+		}
+	}
+	return expired, nil
+}
+
+// SetCellLocks replaces the in-memory lock store. It must be shared by all instances
+// when a backend is set, and must be called before Run.
+func (h *Hub) SetCellLocks(locks CellLocks) {
+	h.cellLocks = locks
+}
+
+// CellLocks returns the base's cells being edited, for checking writes against
+func (h *Hub) CellLocks(ctx context.Context, baseID uuid.UUID) ([]*CellLock, error) {
+	return h.cellLocks.List(ctx, baseID)
+}
+
+// lockCell handles a client's request to lock or unlock a cell. It runs on the
+// client's read goroutine since the lock store may be in the database.
+func (h *Hub) lockCell(client *Client, req *CellLockRequest, lock bool) {
+	h.mu.RLock()
+	connected := h.bases[client.baseID][client]
+	canEdit := models.CollaboratorRole(client.role).CanEdit()
+	h.mu.RUnlock()
+	if !connected {
+		return
+	}
+
+	ctx := context.Background()
+	cell := &CellLock{
+		BaseID:    client.baseID,
+		TableID:   req.TableID,
+		RecordID:  req.RecordID,
+		FieldID:   req.FieldID,
+		UserID:    client.userID,
+		ExpiresAt: time.Now().Add(CellLockLease).UTC(),
+	}
+
+	if !lock {
+		released, err := h.cellLocks.Release(ctx, cell)
+		if err != nil {
+			log.Printf("Failed to release cell lock: %v", err)
+			return
+		}
+		if released {
+			h.announceLocks(MsgTypeCellUnlocked, cell)
+		}
+		return
+	}
+
+	if !canEdit {
+		h.reply(client, NewMessage(MsgTypeCellLockDenied, client.baseID, client.userID).WithPayload(cell))
+		return
+	}
+	holder, err := h.cellLocks.Acquire(ctx, cell)
+	if err != nil {
+		log.Printf("Failed to acquire cell lock: %v", err)
+		return
+	}
+	if holder.UserID != client.userID {
+		h.reply(client, NewMessage(MsgTypeCellLockDenied, client.baseID, holder.UserID).WithPayload(holder))
+		return
+	}
+	h.announceLocks(MsgTypeCellLocked, holder)
+}
+
+// announceLocks tells every instance's clients that cells were locked or unlocked
+func (h *Hub) announceLocks(msgType string, locks ...*CellLock) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, lock := range locks {
+		msg := NewMessage(msgType, lock.BaseID, lock.UserID).
+			WithTable(lock.TableID).
+			WithRecord(lock.RecordID).
+			WithField(lock.FieldID).
+			WithPayload(lock)
+		h.broadcastToBase(lock.BaseID, msg, uuid.Nil)
+		h.publish(&Envelope{Kind: EnvelopeMessage, BaseID: lock.BaseID, Message: msg})
+	}
+}
+
+// sendCellLocks tells a newly connected client which cells are being edited
+func (h *Hub) sendCellLocks(client *Client) {
+	locks, err := h.cellLocks.List(context.Background(), client.baseID)
+	if err != nil {
+		log.Printf("Failed to list cell locks: %v", err)
+		return
+	}
+	if len(locks) == 0 {
+		return
+	}
+	h.reply(client, NewMessage(MsgTypeCellLocks, client.baseID, client.userID).WithPayload(locks))
+}
+
+// releaseUserLocks unlocks the cells of a user who has closed their last connection
+func (h *Hub) releaseUserLocks(baseID, userID uuid.UUID) {
+	released, err := h.cellLocks.ReleaseUser(context.Background(), baseID, userID)
+	if err != nil {
+		log.Printf("Failed to release cell locks: %v", err)
+		return
+	}
+	h.announceLocks(MsgTypeCellUnlocked, released...)
+}
+
+// expireLocksLoop periodically releases locks whose lease ran out
+func (h *Hub) expireLocksLoop() {
+	ticker := time.NewTicker(cellLockSweep)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := h.cellLocks.Expire(context.Background())
+		if err != nil {
+			log.Printf("Failed to expire cell locks: %v", err)
+			continue
+		}
+		h.announceLocks(MsgTypeCellUnlocked, expired...)
+	}
+}
@@ -0,0 +1,125 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCellLocks(t *testing.T) {
+	ctx := context.Background()
+	locks := NewMemoryCellLocks()
+	baseID, recordID, fieldID := uuid.New(), uuid.New(), uuid.New()
+	alice, bob := uuid.New(), uuid.New()
+	lockFor := func(userID uuid.UUID, lease time.Duration) *CellLock {
+		return &CellLock{BaseID: baseID, RecordID: recordID, FieldID: fieldID, UserID: userID, ExpiresAt: time.Now().Add(lease)}
+	}
+
+	held, err := locks.Acquire(ctx, lockFor(alice, time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, alice, held.UserID)
+
+	// Renewing your own lock succeeds; someone else gets the holder back
+	held, _ = locks.Acquire(ctx, lockFor(alice, time.Minute))
+	assert.Equal(t, alice, held.UserID)
+	held, _ = locks.Acquire(ctx, lockFor(bob, time.Minute))
+	assert.Equal(t, alice, held.UserID)
+
+	// Only the holder can release
+	released, _ := locks.Release(ctx, lockFor(bob, 0))
+	assert.False(t, released)
+	released, _ = locks.Release(ctx, lockFor(alice, 0))
+	assert.True(t, released)
+
+	// Expired locks can be taken over, and are swept
+	_, _ = locks.Acquire(ctx, lockFor(alice, -time.Second))
+	held, _ = locks.Acquire(ctx, lockFor(bob, -time.Second))
+	assert.Equal(t, bob, held.UserID)
+	list, _ := locks.List(ctx, baseID)
+	assert.Empty(t, list)
+	expired, _ := locks.Expire(ctx)
+	assert.Len(t, expired, 1)
+
+	// Locks are per base
+	other := lockFor(bob, time.Minute)
+	other.BaseID = uuid.New()
+	_, _ = locks.Acquire(ctx, lockFor(alice, time.Minute))
+	held, _ = locks.Acquire(ctx, other)
+	assert.Equal(t, bob, held.UserID)
+
+	released2, _ := locks.ReleaseUser(ctx, baseID, alice)
+	assert.Len(t, released2, 1)
+}
+
+func TestHub_lockCell(t *testing.T) {
+	setup := func() (*Hub, *Client, *Client) {
+		hub := NewHub()
+		baseID := uuid.New()
+		editor := newTestClient(hub, baseID)
+		editor.SetAccess(uuid.New(), "editor")
+		other := newTestClient(hub, baseID)
+		other.SetAccess(uuid.New(), "editor")
+		hub.registerClient(editor)
+		hub.registerClient(other)
+		messageTypes(editor)
+		messageTypes(other)
+		return hub, editor, other
+	}
+	req := &CellLockRequest{TableID: uuid.New(), RecordID: uuid.New(), FieldID: uuid.New()}
+
+	t.Run("announces locks and refuses a second editor", func(t *testing.T) {
+		hub, editor, other := setup()
+
+		hub.lockCell(editor, req, true)
+		locked := nextMessage(t, other, MsgTypeCellLocked)
+		assert.Equal(t, editor.userID, locked.UserID)
+		assert.Equal(t, req.FieldID, *locked.FieldID)
+
+		hub.lockCell(other, req, true)
+		denied := nextMessage(t, other, MsgTypeCellLockDenied)
+		assert.Equal(t, editor.userID, denied.Payload.(*CellLock).UserID)
+
+		hub.lockCell(editor, req, false)
+		nextMessage(t, other, MsgTypeCellUnlocked)
+		hub.lockCell(other, req, true)
+		nextMessage(t, editor, MsgTypeCellLocked)
+	})
+
+	t.Run("viewers cannot lock cells", func(t *testing.T) {
+		hub, editor, other := setup()
+		editor.role = "viewer"
+
+		hub.lockCell(editor, req, true)
+		nextMessage(t, editor, MsgTypeCellLockDenied)
+		types, _ := messageTypes(other)
+		assert.NotContains(t, types, MsgTypeCellLocked)
+	})
+
+	t.Run("leaving unlocks the user's cells", func(t *testing.T) {
+		hub, editor, other := setup()
+		hub.lockCell(editor, req, true)
+		nextMessage(t, other, MsgTypeCellLocked)
+
+		hub.unregisterClient(editor)
+
+		nextMessage(t, other, MsgTypeCellUnlocked)
+		locks, err := hub.CellLocks(context.Background(), editor.baseID)
+		require.NoError(t, err)
+		assert.Empty(t, locks)
+	})
+
+	t.Run("new clients are told which cells are locked", func(t *testing.T) {
+		hub, editor, _ := setup()
+		hub.lockCell(editor, req, true)
+
+		late := newTestClient(hub, editor.baseID)
+		hub.registerClient(late)
+
+		msg := nextMessage(t, late, MsgTypeCellLocks)
+		assert.Len(t, msg.Payload, 1)
+	})
+}
@@ -32,6 +32,21 @@ type Client struct {
 	email  string
 	name   *string
 	baseID uuid.UUID
+
+	// Access the connection was authorized with, guarded by the hub's mutex
+	sessionID uuid.UUID
+	role      string
+
+	// Tables and views the client asked for, guarded by the hub's mutex
+	subs subscriptions
+
+	// This connection's own cursor, guarded by the hub's mutex
+	cursor UserPresence
+
+	// Replay state, owned by the hub
+	resumeFrom *int64     // Last sequence number seen before reconnecting
+	catchingUp bool       // Numbered messages are held until the replay is sent
+	pending    []*Message // Messages held while catching up
 }
 
 // NewClient creates a new WebSocket client
@@ -47,6 +62,17 @@ func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID, email string, n
 	}
 }
 
+// SetAccess records the session and base role the connection was authorized with
+func (c *Client) SetAccess(sessionID uuid.UUID, role string) {
+	c.sessionID = sessionID
+	c.role = role
+}
+
+// ResumeFrom asks for the messages after seq to be replayed when the client registers
+func (c *Client) ResumeFrom(seq int64) {
+	c.resumeFrom = &seq
+}
+
 // ReadPump pumps messages from the WebSocket connection to the hub
 func (c *Client) ReadPump() {
 	defer func() {
@@ -93,7 +119,7 @@ func (c *Client) handleMessage(msg *IncomingMessage) {
 			return
 		}
 
-		c.hub.UpdatePresence(c.baseID, c.userID, func(p *UserPresence) {
+		c.hub.updateClientPresence(c, func(p *UserPresence) {
 			p.TableID = &update.TableID
 			if update.ViewID != uuid.Nil {
 				p.ViewID = &update.ViewID
@@ -101,10 +127,26 @@ func (c *Client) handleMessage(msg *IncomingMessage) {
 			p.CellRef = update.CellRef
 		})
 
+	case MsgTypeSubscribe, MsgTypeUnsubscribe:
+		var req SubscribeMessage
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			log.Printf("Error parsing subscription: %v", err)
+			return
+		}
+		c.hub.Subscribe(c, &req, msg.Type == MsgTypeSubscribe)
+
+	case MsgTypeCellLock, MsgTypeCellUnlock:
+		var req CellLockRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			log.Printf("Error parsing cell lock: %v", err)
+			return
+		}
+		c.hub.lockCell(c, &req, msg.Type == MsgTypeCellLock)
+
 	case "ping":
 		// Respond with pong
 		pong := NewMessage("pong", c.baseID, c.userID)
-		c.send <- pong
+		c.hub.reply(c, pong)
 
 	default:
 		log.Printf("Unknown message type: %s", msg.Type)
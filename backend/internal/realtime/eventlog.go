@@ -0,0 +1,202 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Replay limits
+const (
+	// maxReplayEvents is the largest gap replayed on reconnect; clients further
+	// behind are asked to resync instead
+	maxReplayEvents = 200
+
+	// maxPendingEvents bounds the live messages held for a client while its replay loads
+	maxPendingEvents = 1000
+
+	// memoryLogSize is how many messages the in-memory log keeps per base
+	memoryLogSize = 1000
+)
+
+// EventLog numbers each base's broadcast messages and keeps recent ones, so clients
+// can reconnect and receive what they missed
+type EventLog interface {
+	// Append assigns the message the base's next sequence number and stores it
+	Append(ctx context.Context, msg *Message) error
+
+	// Since returns up to limit messages after seq, oldest first
+	Since(ctx context.Context, baseID uuid.UUID, seq int64, limit int) ([]*Message, error)
+
+	// Latest returns the base's last sequence number, or 0 if it has none
+	Latest(ctx context.Context, baseID uuid.UUID) (int64, error)
+}
+
+// MemoryEventLog is an EventLog for a single instance
+type MemoryEventLog struct {
+	mu    sync.Mutex
+	bases map[uuid.UUID]*memoryBaseLog
+}
+
+type memoryBaseLog struct {
+	seq      int64
+	messages []*Message // The most recent messages, oldest first
+}
+
+// NewMemoryEventLog creates an in-memory event log
+func NewMemoryEventLog() *MemoryEventLog {
+	return &MemoryEventLog{bases: make(map[uuid.UUID]*memoryBaseLog)}
+}
+
+// Append assigns the message the base's next sequence number and stores it
+func (l *MemoryEventLog) Append(ctx context.Context, msg *Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	base := l.bases[msg.BaseID]
+	if base == nil {
+		base = &memoryBaseLog{}
+		l.bases[msg.BaseID] = base
+	}
+	base.seq++
+	msg.Seq = base.seq
+
+	base.messages = append(base.messages, msg)
+	if len(base.messages) > memoryLogSize {
+		base.messages = base.messages[len(base.messages)-memoryLogSize:]
+	}
+	return nil
+}
+
+// Since returns up to limit messages after seq, oldest first
+func (l *MemoryEventLog) Since(ctx context.Context, baseID uuid.UUID, seq int64, limit int) ([]*Message, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	base := l.bases[baseID]
+	if base == nil {
+		return nil, nil
+	}
+	var missed []*Message
+	for _, msg := range base.messages {
+		if msg.Seq > seq {
+			missed = append(missed, msg)
+			if len(missed) == limit {
+				break
+			}
+		}
+	}
+	return missed, nil
+}
+
+// Latest returns the base's last sequence number
+func (l *MemoryEventLog) Latest(ctx context.Context, baseID uuid.UUID) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if base := l.bases[baseID]; base != nil {
+		return base.seq, nil
+	}
+	return 0, nil
+}
+
+// replayResult is what a reconnecting client missed
+type replayResult struct {
+	client   *Client
+	missed   []*Message
+	seq      int64 // Sequence number the client is caught up to
+	complete bool  // False when the gap could not be replayed
+}
+
+// loadReplay finds the messages a client missed. It runs outside the event loop
+// because the log may be in the database.
+func (h *Hub) loadReplay(client *Client) {
+	ctx := context.Background()
+	result := &replayResult{client: client}
+	defer func() { h.replays <- result }()
+
+	// A fresh connection only needs to know where the base is up to. If that is
+	// unavailable it still has nothing to catch up on, so it is not asked to resync.
+	latest, err := h.eventLog.Latest(ctx, client.baseID)
+	if client.resumeFrom == nil {
+		result.seq = latest
+		result.complete = true
+		return
+	}
+	if err != nil {
+		return
+	}
+	result.seq = latest
+
+	from := *client.resumeFrom
+	if from >= latest {
+		// A client ahead of the log has seen a sequence that no longer exists
+		result.complete = from == latest
+		return
+	}
+
+	missed, err := h.eventLog.Since(ctx, client.baseID, from, maxReplayEvents)
+	if err != nil || len(missed) == 0 {
+		return
+	}
+	for i, msg := range missed {
+		if msg.Seq != from+int64(i)+1 {
+			// Part of the gap has already left the log
+			return
+		}
+	}
+	last := missed[len(missed)-1].Seq
+	if last < latest {
+		return
+	}
+	result.missed = missed
+	result.seq = last
+	result.complete = true
+}
+
+// finishReplay sends a client what it missed, then the live messages held while the
+// replay loaded
+func (h *Hub) finishReplay(result *replayResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client := result.client
+	if !h.bases[client.baseID][client] {
+		// Disconnected while the replay loaded
+		return
+	}
+
+	if result.complete {
+		for _, msg := range result.missed {
+			if !client.wants(msg) {
+				continue
+			}
+			if !h.sendTo(client, msg) {
+				return
+			}
+		}
+		synced := NewMessage(MsgTypeSynced, client.baseID, client.userID)
+		synced.Seq = result.seq
+		if !h.sendTo(client, synced) {
+			return
+		}
+	} else {
+		resync := NewMessage(MsgTypeResyncRequired, client.baseID, client.userID)
+		resync.Seq = result.seq
+		if !h.sendTo(client, resync) {
+			return
+		}
+	}
+
+	pending := client.pending
+	client.pending = nil
+	client.catchingUp = false
+	for _, msg := range pending {
+		if msg.Seq > result.seq {
+			if !h.sendTo(client, msg) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryEventLog(t *testing.T) {
+	ctx := context.Background()
+	eventLog := NewMemoryEventLog()
+	baseID, otherBase := uuid.New(), uuid.New()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, eventLog.Append(ctx, NewMessage(MsgTypeRecordUpdated, baseID, uuid.New())))
+	}
+	other := NewMessage(MsgTypeRecordCreated, otherBase, uuid.New())
+	require.NoError(t, eventLog.Append(ctx, other))
+
+	t.Run("numbers messages per base", func(t *testing.T) {
+		latest, err := eventLog.Latest(ctx, baseID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), latest)
+		assert.Equal(t, int64(1), other.Seq)
+
+		latest, err = eventLog.Latest(ctx, uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), latest)
+	})
+
+	t.Run("returns messages after a sequence number", func(t *testing.T) {
+		missed, err := eventLog.Since(ctx, baseID, 1, 10)
+		require.NoError(t, err)
+		require.Len(t, missed, 2)
+		assert.Equal(t, int64(2), missed[0].Seq)
+		assert.Equal(t, int64(3), missed[1].Seq)
+
+		missed, err = eventLog.Since(ctx, baseID, 0, 1)
+		require.NoError(t, err)
+		assert.Len(t, missed, 1)
+	})
+
+	t.Run("keeps a bounded number of messages", func(t *testing.T) {
+		busy := uuid.New()
+		for i := 0; i < memoryLogSize+10; i++ {
+			require.NoError(t, eventLog.Append(ctx, NewMessage(MsgTypeRecordUpdated, busy, uuid.New())))
+		}
+		missed, err := eventLog.Since(ctx, busy, 0, memoryLogSize*2)
+		require.NoError(t, err)
+		assert.Len(t, missed, memoryLogSize)
+		assert.Equal(t, int64(11), missed[0].Seq)
+	})
+}
+
+// replayHub returns a hub with an event log holding n messages for a base, and a
+// registered client that reconnects after the given sequence number
+func replayHub(t *testing.T, n int, resumeFrom *int64) (*Hub, *Client) {
+	hub := NewHub()
+	hub.SetEventLog(NewMemoryEventLog())
+	baseID := uuid.New()
+	for i := 0; i < n; i++ {
+		require.NoError(t, hub.eventLog.Append(context.Background(), NewMessage(MsgTypeRecordUpdated, baseID, uuid.New())))
+	}
+
+	client := newTestClient(hub, baseID)
+	client.resumeFrom = resumeFrom
+	hub.registerClient(client)
+	require.Equal(t, MsgTypePresenceList, (<-client.send).Type)
+	return hub, client
+}
+
+func TestHub_Replay(t *testing.T) {
+	seq := func(n int64) *int64 { return &n }
+
+	t.Run("tells a new client the current sequence number", func(t *testing.T) {
+		hub, client := replayHub(t, 3, nil)
+		hub.finishReplay(<-hub.replays)
+
+		synced := <-client.send
+		assert.Equal(t, MsgTypeSynced, synced.Type)
+		assert.Equal(t, int64(3), synced.Seq)
+	})
+
+	t.Run("replays missed messages in order", func(t *testing.T) {
+		hub, client := replayHub(t, 5, seq(2))
+		hub.finishReplay(<-hub.replays)
+
+		for _, want := range []int64{3, 4, 5} {
+			msg := <-client.send
+			assert.Equal(t, MsgTypeRecordUpdated, msg.Type)
+			assert.Equal(t, want, msg.Seq)
+		}
+		synced := <-client.send
+		assert.Equal(t, MsgTypeSynced, synced.Type)
+		assert.Equal(t, int64(5), synced.Seq)
+	})
+
+	t.Run("asks for a resync when the gap is too large", func(t *testing.T) {
+		hub, client := replayHub(t, maxReplayEvents+5, seq(1))
+		hub.finishReplay(<-hub.replays)
+
+		msg := <-client.send
+		assert.Equal(t, MsgTypeResyncRequired, msg.Type)
+		assert.Equal(t, int64(maxReplayEvents+5), msg.Seq)
+	})
+
+	t.Run("asks for a resync when the client is ahead of the log", func(t *testing.T) {
+		hub, client := replayHub(t, 2, seq(10))
+		hub.finishReplay(<-hub.replays)
+
+		assert.Equal(t, MsgTypeResyncRequired, (<-client.send).Type)
+	})
+
+	t.Run("holds live messages until the replay is sent", func(t *testing.T) {
+		hub, client := replayHub(t, 2, seq(1))
+		result := <-hub.replays
+
+		// A message broadcast while the replay was loading, and one it already covers
+		live := NewMessage(MsgTypeRecordCreated, client.baseID, uuid.New())
+		require.NoError(t, hub.eventLog.Append(context.Background(), live))
+		hub.broadcastToBase(client.baseID, live, uuid.Nil)
+		covered := &Message{Type: MsgTypeRecordUpdated, BaseID: client.baseID, Seq: 2}
+		hub.broadcastToBase(client.baseID, covered, uuid.Nil)
+		assert.Empty(t, client.send)
+
+		hub.finishReplay(result)
+		assert.Equal(t, int64(2), (<-client.send).Seq)
+		assert.Equal(t, MsgTypeSynced, (<-client.send).Type)
+		assert.Equal(t, live, <-client.send)
+		assert.Empty(t, client.send)
+		assert.False(t, client.catchingUp)
+	})
+
+	t.Run("does not ask new clients to resync when the log is unavailable", func(t *testing.T) {
+		for _, tt := range []struct {
+			resumeFrom *int64
+			want       string
+		}{{nil, MsgTypeSynced}, {seq(1), MsgTypeResyncRequired}} {
+			hub := NewHub()
+			hub.SetEventLog(failingEventLog{})
+			client := newTestClient(hub, uuid.New())
+			client.resumeFrom = tt.resumeFrom
+			hub.registerClient(client)
+			require.Equal(t, MsgTypePresenceList, (<-client.send).Type)
+
+			hub.finishReplay(<-hub.replays)
+			assert.Equal(t, tt.want, (<-client.send).Type)
+		}
+	})
+
+	t.Run("ignores clients that disconnected meanwhile", func(t *testing.T) {
+		hub, client := replayHub(t, 2, seq(1))
+		result := <-hub.replays
+		hub.unregisterClient(client)
+
+		hub.finishReplay(result)
+	})
+}
+
+// failingEventLog is an EventLog whose database is unreachable
+type failingEventLog struct{}
+
+func (failingEventLog) Append(ctx context.Context, msg *Message) error {
+	return errors.New("unavailable")
+}
+
+func (failingEventLog) Since(ctx context.Context, baseID uuid.UUID, seq int64, limit int) ([]*Message, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (failingEventLog) Latest(ctx context.Context, baseID uuid.UUID) (int64, error) {
+	return 0, errors.New("unavailable")
+}
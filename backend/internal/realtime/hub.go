@@ -1,6 +1,7 @@
 package realtime
 
 import (
+	"context"
 	"log"
 	"sync"
 	"time"
@@ -19,12 +20,38 @@ type Hub struct {
 	// Channel for broadcasting messages
 	broadcast chan *Message
 
+	// Messages for the connections of the message's user only
+	direct chan *Message
+
 	// Register requests from clients
 	register chan *Client
 
 	// Unregister requests from clients
 	unregister chan *Client
 
+	// Cross-instance relay; nil when running as a single instance
+	backend    Backend
+	instanceID uuid.UUID
+	incoming   chan *Envelope
+	outgoing   chan *Envelope
+
+	// Users connected to other instances, by base ID
+	remote map[uuid.UUID]map[uuid.UUID]*remotePresence
+
+	// Numbers broadcasts so reconnecting clients can catch up; nil disables replay
+	eventLog EventLog
+	appends  chan *Message
+	replays  chan *replayResult
+
+	// Re-checks open connections; nil disables re-authorization
+	authorizer Authorizer
+
+	// Slow-consumer counters
+	stats hubStats
+
+	// Cells being edited
+	cellLocks CellLocks
+
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
 }
@@ -35,14 +62,52 @@ func NewHub() *Hub {
 		bases:      make(map[uuid.UUID]map[*Client]bool),
 		presence:   make(map[uuid.UUID]map[uuid.UUID]*UserPresence),
 		broadcast:  make(chan *Message, 256),
+		direct:     make(chan *Message, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		instanceID: uuid.New(),
+		incoming:   make(chan *Envelope, 256),
+		outgoing:   make(chan *Envelope, 256),
+		remote:     make(map[uuid.UUID]map[uuid.UUID]*remotePresence),
+		appends:    make(chan *Message, 256),
+		replays:    make(chan *replayResult, 64),
+		cellLocks:  NewMemoryCellLocks(),
 	}
 	return h
 }
 
+// SetBackend sets the backend that relays messages and presence to other instances.
+// It must be called before Run.
+func (h *Hub) SetBackend(backend Backend) {
+	h.backend = backend
+}
+
+// SetEventLog sets the log that numbers broadcasts for replay. It must be shared by
+// all instances when a backend is set.
+func (h *Hub) SetEventLog(eventLog EventLog) {
+	h.eventLog = eventLog
+}
+
 // Run starts the hub's main event loop
 func (h *Hub) Run() {
+	var heartbeat <-chan time.Time
+	if h.backend != nil {
+		ctx := context.Background()
+		go h.publishLoop(ctx)
+		go h.listenLoop(ctx)
+
+		ticker := time.NewTicker(presenceHeartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	if h.authorizer != nil {
+		go h.reauthorizeLoop()
+	}
+	if h.eventLog != nil {
+		go h.appendLoop()
+	}
+	go h.expireLocksLoop()
+
 	for {
 		select {
 		case client := <-h.register:
@@ -53,10 +118,64 @@ func (h *Hub) Run() {
 
 		case message := <-h.broadcast:
 			h.broadcastMessage(message)
+
+		case message := <-h.direct:
+			h.directMessage(message)
+
+		case env := <-h.incoming:
+			h.receiveEnvelope(env)
+
+		case result := <-h.replays:
+			h.finishReplay(result)
+
+		case <-heartbeat:
+			h.syncPresence()
 		}
 	}
 }
 
+// publishLoop sends queued envelopes to the backend
+func (h *Hub) publishLoop(ctx context.Context) {
+	for env := range h.outgoing {
+		if err := h.backend.Publish(ctx, env); err != nil {
+			log.Printf("Failed to publish realtime %s: %v", env.Kind, err)
+		}
+	}
+}
+
+// listenLoop receives envelopes from the backend, reconnecting when it fails
+func (h *Hub) listenLoop(ctx context.Context) {
+	for {
+		err := h.backend.Listen(ctx, h.deliverEnvelope)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Realtime backend listener stopped, reconnecting: %v", err)
+		time.Sleep(time.Second)
+	}
+}
+
+// deliverEnvelope queues an envelope from another instance for the event loop
+func (h *Hub) deliverEnvelope(env *Envelope) {
+	if env.Origin == h.instanceID {
+		return
+	}
+	h.incoming <- env
+}
+
+// publish queues an envelope for other instances
+func (h *Hub) publish(env *Envelope) {
+	if h.backend == nil {
+		return
+	}
+	env.Origin = h.instanceID
+	select {
+	case h.outgoing <- env:
+	default:
+		log.Printf("Realtime publish queue full, dropping %s", env.Kind)
+	}
+}
+
 // registerClient registers a new client
 func (h *Hub) registerClient(client *Client) {
 	h.mu.Lock()
@@ -77,28 +196,47 @@ func (h *Hub) registerClient(client *Client) {
 	if h.presence[baseID] == nil {
 		h.presence[baseID] = make(map[uuid.UUID]*UserPresence)
 	}
-
-	presence := &UserPresence{
-		UserID:    client.userID,
-		Email:     client.email,
-		Name:      client.name,
-		JoinedAt:  time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-	}
-	h.presence[baseID][client.userID] = presence
+	client.cursor.UpdatedAt = time.Now().UTC()
 
 	log.Printf("Client registered: user=%s base=%s", client.userID, baseID)
 
-	// Notify other clients that this user joined
-	joinMsg := NewMessage(MsgTypeUserJoined, baseID, client.userID).
-		WithPayload(presence)
-	h.broadcastToBase(baseID, joinMsg, client.userID)
+	if presence, ok := h.presence[baseID][client.userID]; ok {
+		// Another tab of a user who is already here
+		presence.Connections = h.connections(baseID, client.userID)
+		h.announcePresence(baseID, presence)
+	} else {
+		presence := &UserPresence{
+			UserID:      client.userID,
+			Email:       client.email,
+			Name:        client.name,
+			Connections: 1,
+			JoinedAt:    time.Now().UTC(),
+			UpdatedAt:   time.Now().UTC(),
+		}
+		h.presence[baseID][client.userID] = presence
+
+		// Notify other clients that this user joined
+		snapshot := *presence
+		joinMsg := NewMessage(MsgTypeUserJoined, baseID, client.userID).
+			WithPayload(&snapshot)
+		h.broadcastToBase(baseID, joinMsg, client.userID)
+		h.publish(&Envelope{Kind: EnvelopeMessage, BaseID: baseID, Message: joinMsg, ExcludeUserID: client.userID, Presence: []*UserPresence{&snapshot}})
+	}
 
 	// Send presence list to the new client
 	presenceList := h.getPresenceList(baseID)
 	listMsg := NewMessage(MsgTypePresenceList, baseID, client.userID).
 		WithPayload(presenceList)
-	client.send <- listMsg
+	if !h.sendTo(client, listMsg) {
+		return
+	}
+	go h.sendCellLocks(client)
+
+	// Hold numbered messages until the client knows where it is up to
+	if h.eventLog != nil {
+		client.catchingUp = true
+		go h.loadReplay(client)
+	}
 }
 
 // unregisterClient removes a client
@@ -106,6 +244,11 @@ func (h *Hub) unregisterClient(client *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	h.removeClient(client)
+}
+
+// removeClient closes a client and tells the base it left. The caller must hold the lock.
+func (h *Hub) removeClient(client *Client) {
 	baseID := client.baseID
 	if baseID == uuid.Nil {
 		return
@@ -116,17 +259,28 @@ func (h *Hub) unregisterClient(client *Client) {
 			delete(h.bases[baseID], client)
 			close(client.send)
 
-			// Remove from presence
-			delete(h.presence[baseID], client.userID)
-
 			log.Printf("Client unregistered: user=%s base=%s", client.userID, baseID)
 
-			// Notify other clients that this user left
-			leftMsg := NewMessage(MsgTypeUserLeft, baseID, client.userID).
-				WithPayload(map[string]interface{}{
-					"userId": client.userID,
-				})
-			h.broadcastToBase(baseID, leftMsg, client.userID)
+			if presence, ok := h.presence[baseID][client.userID]; ok && h.connections(baseID, client.userID) > 0 {
+				// The user still has other tabs open; show the most recent one
+				h.restoreCursor(baseID, presence)
+				presence.Connections = h.connections(baseID, client.userID)
+				h.announcePresence(baseID, presence)
+			} else {
+				// Remove from presence and unlock the cells they were editing
+				delete(h.presence[baseID], client.userID)
+				go h.releaseUserLocks(baseID, client.userID)
+
+				// Notify other clients that this user left
+				leftMsg := NewMessage(MsgTypeUserLeft, baseID, client.userID).
+					WithPayload(map[string]interface{}{
+						"userId": client.userID,
+					})
+				if !h.isPresent(baseID, client.userID) {
+					h.broadcastToBase(baseID, leftMsg, client.userID)
+				}
+				h.publish(&Envelope{Kind: EnvelopeMessage, BaseID: baseID, Message: leftMsg, ExcludeUserID: client.userID})
+			}
 
 			// Clean up empty base
 			if len(h.bases[baseID]) == 0 {
@@ -139,10 +293,154 @@ func (h *Hub) unregisterClient(client *Client) {
 
 // broadcastMessage sends a message to all clients in the relevant base
 func (h *Hub) broadcastMessage(message *Message) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	// Slow clients may be disconnected, so this needs the write lock
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
 	h.broadcastToBase(message.BaseID, message, uuid.Nil)
+	h.publish(&Envelope{Kind: EnvelopeMessage, BaseID: message.BaseID, Message: message})
+}
+
+// directMessage sends a message to its user's clients in the base, here and on
+// other instances
+func (h *Hub) directMessage(message *Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sendToUser(message)
+	h.publish(&Envelope{Kind: EnvelopeMessage, BaseID: message.BaseID, Message: message, Direct: true})
+}
+
+// sendToUser sends a message to the clients of message.UserID connected here
+func (h *Hub) sendToUser(message *Message) {
+	for client := range h.bases[message.BaseID] {
+		if client.userID == message.UserID {
+			h.sendTo(client, message)
+		}
+	}
+}
+
+// receiveEnvelope applies traffic from another instance
+func (h *Hub) receiveEnvelope(env *Envelope) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch env.Kind {
+	case EnvelopeMessage:
+		if env.Message == nil {
+			return
+		}
+		if env.Direct {
+			h.sendToUser(env.Message)
+			return
+		}
+		switch env.Message.Type {
+		case MsgTypeUserJoined, MsgTypePresence:
+			for _, p := range env.Presence {
+				h.setRemotePresence(env.BaseID, env.Origin, p)
+			}
+		case MsgTypeUserLeft:
+			h.removeRemotePresence(env.BaseID, env.Origin, env.Message.UserID)
+			if h.isPresent(env.BaseID, env.Message.UserID) {
+				// Still connected here or to another instance
+				return
+			}
+		}
+		h.broadcastToBase(env.BaseID, env.Message, env.ExcludeUserID)
+
+	case EnvelopePresence:
+		h.replaceRemotePresence(env.BaseID, env.Origin, env.Presence)
+
+	case EnvelopeAccess:
+		h.applyAccess(env)
+	}
+}
+
+// setRemotePresence records a user connected to another instance
+func (h *Hub) setRemotePresence(baseID, origin uuid.UUID, presence *UserPresence) {
+	if h.remote[baseID] == nil {
+		h.remote[baseID] = make(map[uuid.UUID]*remotePresence)
+	}
+	h.remote[baseID][presence.UserID] = &remotePresence{presence: presence, origin: origin, seenAt: time.Now()}
+}
+
+// removeRemotePresence forgets a user connected to another instance
+func (h *Hub) removeRemotePresence(baseID, origin, userID uuid.UUID) {
+	if r, ok := h.remote[baseID][userID]; ok && r.origin == origin {
+		delete(h.remote[baseID], userID)
+		if len(h.remote[baseID]) == 0 {
+			delete(h.remote, baseID)
+		}
+	}
+}
+
+// replaceRemotePresence applies an instance's full presence list for a base,
+// announcing users this instance had missed or who are gone
+func (h *Hub) replaceRemotePresence(baseID, origin uuid.UUID, list []*UserPresence) {
+	listed := make(map[uuid.UUID]bool, len(list))
+	for _, p := range list {
+		listed[p.UserID] = true
+		if !h.isPresent(baseID, p.UserID) {
+			msg := NewMessage(MsgTypeUserJoined, baseID, p.UserID).WithPayload(p)
+			h.broadcastToBase(baseID, msg, p.UserID)
+		}
+		h.setRemotePresence(baseID, origin, p)
+	}
+
+	for userID, r := range h.remote[baseID] {
+		if r.origin == origin && !listed[userID] {
+			h.dropRemotePresence(baseID, userID)
+		}
+	}
+}
+
+// dropRemotePresence removes a remote user and tells local clients they left
+func (h *Hub) dropRemotePresence(baseID, userID uuid.UUID) {
+	delete(h.remote[baseID], userID)
+	if len(h.remote[baseID]) == 0 {
+		delete(h.remote, baseID)
+	}
+	if !h.isPresent(baseID, userID) {
+		msg := NewMessage(MsgTypeUserLeft, baseID, userID).
+			WithPayload(map[string]interface{}{
+				"userId": userID,
+			})
+		h.broadcastToBase(baseID, msg, userID)
+	}
+}
+
+// syncPresence announces this instance's users to the others and drops remote
+// users whose instance has stopped announcing them
+func (h *Hub) syncPresence() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for baseID, users := range h.presence {
+		list := make([]*UserPresence, 0, len(users))
+		for _, p := range users {
+			snapshot := *p
+			list = append(list, &snapshot)
+		}
+		h.publish(&Envelope{Kind: EnvelopePresence, BaseID: baseID, Presence: list})
+	}
+
+	cutoff := time.Now().Add(-presenceTimeout)
+	for baseID, users := range h.remote {
+		for userID, r := range users {
+			if r.seenAt.Before(cutoff) {
+				h.dropRemotePresence(baseID, userID)
+			}
+		}
+	}
+}
+
+// isPresent reports whether a user is connected to a base on any instance
+func (h *Hub) isPresent(baseID, userID uuid.UUID) bool {
+	if _, ok := h.presence[baseID][userID]; ok {
+		return true
+	}
+	_, ok := h.remote[baseID][userID]
+	return ok
 }
 
 // broadcastToBase sends a message to all clients in a base (optionally excluding a user)
@@ -157,27 +455,69 @@ func (h *Hub) broadcastToBase(baseID uuid.UUID, message *Message, excludeUserID
 		if excludeUserID != uuid.Nil && client.userID == excludeUserID {
 			continue
 		}
+		if !client.wants(message) {
+			continue
+		}
 
-		select {
-		case client.send <- message:
-		default:
-			// Client's buffer is full, close and remove
-			close(client.send)
-			delete(h.bases[baseID], client)
+		if client.catchingUp && message.Seq > 0 {
+			if len(client.pending) >= maxPendingEvents {
+				h.disconnectSlow(client)
+				continue
+			}
+			client.pending = append(client.pending, message)
+			continue
 		}
+		h.sendTo(client, message)
 	}
 }
 
-// getPresenceList returns all users present in a base
-func (h *Hub) getPresenceList(baseID uuid.UUID) []*UserPresence {
-	presenceMap, ok := h.presence[baseID]
-	if !ok {
-		return []*UserPresence{}
+// sendTo queues a message for a client without blocking. When the client's queue is
+// full, presence updates are dropped since the next one supersedes them; anything else
+// disconnects the client, which catches up by replay when it reconnects.
+func (h *Hub) sendTo(client *Client, message *Message) bool {
+	select {
+	case client.send <- message:
+		return true
+	default:
 	}
 
-	list := make([]*UserPresence, 0, len(presenceMap))
-	for _, p := range presenceMap {
-		list = append(list, p)
+	if message.Type == MsgTypePresence {
+		h.stats.droppedMessages.Add(1)
+		return true
+	}
+	h.disconnectSlow(client)
+	return false
+}
+
+// disconnectSlow removes a client that cannot keep up
+func (h *Hub) disconnectSlow(client *Client) {
+	if !h.bases[client.baseID][client] {
+		return
+	}
+	log.Printf("Disconnecting slow realtime client: user=%s base=%s", client.userID, client.baseID)
+	h.stats.slowDisconnects.Add(1)
+	h.removeClient(client)
+}
+
+// getPresenceList returns all users present in a base, on this and other instances
+func (h *Hub) getPresenceList(baseID uuid.UUID) []*UserPresence {
+	presenceMap := h.presence[baseID]
+	remoteMap := h.remote[baseID]
+
+	// Copies, since the list is encoded after the lock is released
+	list := make([]*UserPresence, 0, len(presenceMap)+len(remoteMap))
+	for userID, p := range presenceMap {
+		snapshot := *p
+		if r, ok := remoteMap[userID]; ok {
+			snapshot.Connections += r.presence.Connections
+		}
+		list = append(list, &snapshot)
+	}
+	for userID, r := range remoteMap {
+		if _, ok := presenceMap[userID]; !ok {
+			snapshot := *r.presence
+			list = append(list, &snapshot)
+		}
 	}
 	return list
 }
@@ -200,12 +540,39 @@ func (h *Hub) UpdatePresence(baseID, userID uuid.UUID, update func(*UserPresence
 	presence.UpdatedAt = time.Now().UTC()
 
 	// Broadcast presence update
-	msg := NewMessage(MsgTypePresence, baseID, userID).WithPayload(presence)
-	h.broadcastToBase(baseID, msg, uuid.Nil)
+	h.announcePresence(baseID, presence)
 }
 
-// Broadcast sends a message to the broadcast channel
+// Broadcast queues a message to be numbered and sent to the base's clients. It does
+// not wait for the event log, so callers on the request path never block on it.
 func (h *Hub) Broadcast(message *Message) {
+	queue := h.broadcast
+	if h.eventLog != nil {
+		queue = h.appends
+	}
+
+	select {
+	case queue <- message:
+	default:
+		log.Printf("Broadcast channel full, dropping message: %s", message.Type)
+	}
+}
+
+// appendLoop numbers queued broadcasts in order and hands them to the event loop.
+// With a shared log each append is a database round trip, which caps how many
+// messages per second an instance can broadcast; bursts beyond that wait in appends.
+func (h *Hub) appendLoop() {
+	for message := range h.appends {
+		if err := h.eventLog.Append(context.Background(), message); err != nil {
+			log.Printf("Failed to log realtime message: %v", err)
+		}
+		h.broadcast <- message
+	}
+}
+
+// Announce sends a message to the base's clients on every instance without numbering
+// it, for transient state that is not worth replaying to reconnecting clients
+func (h *Hub) Announce(message *Message) {
 	select {
 	case h.broadcast <- message:
 	default:
@@ -213,6 +580,16 @@ func (h *Hub) Broadcast(message *Message) {
 	}
 }
 
+// AnnounceToUser sends a transient message only to the connections of the message's
+// user, on every instance, for state that concerns nobody else in the base
+func (h *Hub) AnnounceToUser(message *Message) {
+	select {
+	case h.direct <- message:
+	default:
+		log.Printf("Direct message channel full, dropping message: %s", message.Type)
+	}
+}
+
 // GetActiveUsers returns the count of active users in a base
 func (h *Hub) GetActiveUsers(baseID uuid.UUID) int {
 	h.mu.RLock()
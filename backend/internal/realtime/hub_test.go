@@ -1,6 +1,7 @@
 package realtime
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -146,6 +147,73 @@ func TestHub_Broadcast(t *testing.T) {
 	})
 }
 
+func TestHub_appendLoop(t *testing.T) {
+	t.Run("numbers broadcasts off the caller's path", func(t *testing.T) {
+		hub := NewHub()
+		hub.SetEventLog(NewMemoryEventLog())
+		baseID := uuid.New()
+
+		first := NewMessage(MsgTypeRecordCreated, baseID, uuid.New())
+		second := NewMessage(MsgTypeRecordUpdated, baseID, uuid.New())
+		hub.Broadcast(first)
+		hub.Broadcast(second)
+		assert.Empty(t, hub.broadcast, "messages wait for the append loop")
+
+		go hub.appendLoop()
+		assert.Equal(t, first, <-hub.broadcast)
+		assert.Equal(t, second, <-hub.broadcast)
+		assert.Equal(t, int64(1), first.Seq)
+		assert.Equal(t, int64(2), second.Seq)
+		close(hub.appends)
+	})
+}
+
+func TestHub_Announce(t *testing.T) {
+	t.Run("sends message without numbering it", func(t *testing.T) {
+		hub := NewHub()
+		eventLog := NewMemoryEventLog()
+		hub.SetEventLog(eventLog)
+		baseID := uuid.New()
+
+		msg := NewMessage(MsgTypeUploadProgress, baseID, uuid.New())
+		hub.Announce(msg)
+
+		select {
+		case received := <-hub.broadcast:
+			assert.Equal(t, msg, received)
+			assert.Zero(t, received.Seq)
+		default:
+			t.Error("Expected message in broadcast channel")
+		}
+
+		latest, err := eventLog.Latest(context.Background(), baseID)
+		require.NoError(t, err)
+		assert.Zero(t, latest)
+	})
+}
+
+func TestHub_AnnounceToUser(t *testing.T) {
+	t.Run("sends message only to the user's clients", func(t *testing.T) {
+		hub := NewHub()
+		baseID := uuid.New()
+		userID := uuid.New()
+
+		tab1 := &Client{hub: hub, userID: userID, baseID: baseID, send: make(chan *Message, 256)}
+		tab2 := &Client{hub: hub, userID: userID, baseID: baseID, send: make(chan *Message, 256)}
+		other := &Client{hub: hub, userID: uuid.New(), baseID: baseID, send: make(chan *Message, 256)}
+		hub.bases[baseID] = map[*Client]bool{tab1: true, tab2: true, other: true}
+
+		msg := NewMessage(MsgTypeUploadProgress, baseID, userID)
+		hub.AnnounceToUser(msg)
+		assert.Empty(t, hub.broadcast)
+		hub.directMessage(<-hub.direct)
+
+		assert.Equal(t, msg, <-tab1.send)
+		assert.Equal(t, msg, <-tab2.send)
+		assert.Empty(t, other.send)
+	})
+}
+
 func TestHub_registerClient(t *testing.T) {
 	t.Run("registers client to base", func(t *testing.T) {
 		hub := NewHub()
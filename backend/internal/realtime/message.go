@@ -35,6 +35,32 @@ const (
 	MsgTypeViewCreated = "view_created"
 	MsgTypeViewUpdated = "view_updated"
 	MsgTypeViewDeleted = "view_deleted"
+
+	// Subscription messages
+	MsgTypeSubscribe     = "subscribe"     // Client subscribes to tables or views
+	MsgTypeUnsubscribe   = "unsubscribe"   // Client unsubscribes from tables or views
+	MsgTypeSubscriptions = "subscriptions" // The client's subscriptions after a change
+
+	// Cell lock messages
+	MsgTypeCellLock       = "cell_lock"        // Client starts or keeps editing a cell
+	MsgTypeCellUnlock     = "cell_unlock"      // Client stops editing a cell
+	MsgTypeCellLocked     = "cell_locked"      // A user is editing a cell
+	MsgTypeCellUnlocked   = "cell_unlocked"    // A user stopped editing a cell, or their lease ran out
+	MsgTypeCellLockDenied = "cell_lock_denied" // Another user is editing the cell; payload is their lock
+	MsgTypeCellLocks      = "cell_locks"       // Cells being edited when the client connects, if any
+
+	// Access messages
+	MsgTypeRoleChanged   = "role_changed"   // The user's role in the base changed
+	MsgTypeAccessRevoked = "access_revoked" // The user lost access; the socket is closing
+	MsgTypeBaseDeleted   = "base_deleted"   // The base was deleted; the socket is closing
+
+	// Transient messages, not numbered or replayed
+	MsgTypeUploadProgress = "upload_progress" // A resumable attachment upload advanced, finished or was cancelled
+	MsgTypeJobProgress    = "job_progress"    // A background job started, advanced or finished
+
+	// Replay messages
+	MsgTypeSynced         = "synced"          // Caught up; seq is the base's current sequence number
+	MsgTypeResyncRequired = "resync_required" // Missed messages are gone; reload data and continue from seq
 )
 
 // Message represents a WebSocket message
@@ -46,6 +72,7 @@ type Message struct {
 	FieldID   *uuid.UUID  `json:"fieldId,omitempty"`
 	ViewID    *uuid.UUID  `json:"viewId,omitempty"`
 	UserID    uuid.UUID   `json:"userId"`
+	Seq       int64       `json:"seq,omitempty"` // Per-base sequence number of data changes
 	Payload   interface{} `json:"payload,omitempty"`
 	Timestamp time.Time   `json:"timestamp"`
 }
@@ -60,6 +87,9 @@ type UserPresence struct {
 	CellRef   *CellRef   `json:"cellRef,omitempty"` // Which cell they have selected
 	JoinedAt  time.Time  `json:"joinedAt"`
 	UpdatedAt time.Time  `json:"updatedAt"`
+
+	Connections int `json:"connections"` // Open tabs or devices
+
 }
 
 // CellRef represents a cell reference (for cursor tracking)
@@ -75,15 +105,59 @@ type CursorUpdate struct {
 	CellRef  *CellRef  `json:"cellRef,omitempty"`
 }
 
+// CellLockRequest asks to lock or unlock a cell
+type CellLockRequest struct {
+	TableID  uuid.UUID `json:"tableId"`
+	RecordID uuid.UUID `json:"recordId"`
+	FieldID  uuid.UUID `json:"fieldId"`
+}
+
+// Resumable upload states reported in UploadProgress
+const (
+	UploadStatusUploading = "uploading"
+	UploadStatusComplete  = "complete"
+	UploadStatusCancelled = "cancelled"
+)
+
+// UploadProgress reports how far a resumable attachment upload has got
+type UploadProgress struct {
+	UploadID      uuid.UUID  `json:"uploadId"`
+	Filename      string     `json:"filename"`
+	ReceivedBytes int64      `json:"receivedBytes"`
+	SizeBytes     int64      `json:"sizeBytes"`
+	Status        string     `json:"status"`
+	AttachmentID  *uuid.UUID `json:"attachmentId,omitempty"` // Set once complete
+}
+
+// JobProgress reports how far a background job has got. Result is set once it
+// has succeeded and Error once it has failed.
+type JobProgress struct {
+	JobID    uuid.UUID       `json:"jobId"`
+	JobType  string          `json:"jobType"`
+	Status   string          `json:"status"`
+	Progress int             `json:"progress"`
+	Total    int             `json:"total"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    *string         `json:"error,omitempty"`
+}
+
 // IncomingMessage represents a message from the client
 type IncomingMessage struct {
 	Type    string          `json:"type"`
 	Payload json.RawMessage `json:"payload,omitempty"`
 }
 
-// SubscribeMessage is sent by client to subscribe to a base
+// SubscribeMessage is sent by a client to subscribe to or unsubscribe from tables and
+// views. A client without subscriptions receives every message for its base.
 type SubscribeMessage struct {
-	BaseID uuid.UUID `json:"baseId"`
+	TableIDs []uuid.UUID        `json:"tableIds,omitempty"`
+	Views    []ViewSubscription `json:"views,omitempty"`
+}
+
+// ViewSubscription subscribes to a view, which receives its table's record messages
+type ViewSubscription struct {
+	ViewID  uuid.UUID `json:"viewId"`
+	TableID uuid.UUID `json:"tableId"`
 }
 
 // NewMessage creates a new outgoing message
@@ -319,13 +319,17 @@ func TestIncomingMessage(t *testing.T) {
 
 func TestSubscribeMessage(t *testing.T) {
 	t.Run("deserializes from JSON", func(t *testing.T) {
-		baseID := uuid.New()
-		jsonData := []byte(`{"baseId":"` + baseID.String() + `"}`)
+		tableID := uuid.New()
+		viewID := uuid.New()
+		jsonData := []byte(`{"tableIds":["` + tableID.String() + `"],"views":[{"viewId":"` + viewID.String() + `","tableId":"` + tableID.String() + `"}]}`)
 
 		var msg SubscribeMessage
 		err := json.Unmarshal(jsonData, &msg)
 		require.NoError(t, err)
 
-		assert.Equal(t, baseID, msg.BaseID)
+		assert.Equal(t, []uuid.UUID{tableID}, msg.TableIDs)
+		require.Len(t, msg.Views, 1)
+		assert.Equal(t, viewID, msg.Views[0].ViewID)
+		assert.Equal(t, tableID, msg.Views[0].TableID)
 	})
 }
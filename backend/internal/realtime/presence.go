@@ -0,0 +1,76 @@
+package realtime
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A user may have a base open in several tabs, each its own client. Their presence is
+// shown once, with the cursor of whichever tab moved last and a count of open tabs.
+// The caller must hold the lock for all of these.
+
+// connections counts a user's clients connected to a base
+func (h *Hub) connections(baseID, userID uuid.UUID) int {
+	count := 0
+	for client := range h.bases[baseID] {
+		if client.userID == userID {
+			count++
+		}
+	}
+	return count
+}
+
+// restoreCursor shows the cursor of the user's most recently active remaining client
+func (h *Hub) restoreCursor(baseID uuid.UUID, presence *UserPresence) {
+	var latest *Client
+	for client := range h.bases[baseID] {
+		if client.userID == presence.UserID && (latest == nil || client.cursor.UpdatedAt.After(latest.cursor.UpdatedAt)) {
+			latest = client
+		}
+	}
+	if latest != nil {
+		presence.TableID = latest.cursor.TableID
+		presence.ViewID = latest.cursor.ViewID
+		presence.CellRef = latest.cursor.CellRef
+		presence.UpdatedAt = time.Now().UTC()
+	}
+}
+
+// announcePresence broadcasts a user's current presence here and to other instances
+func (h *Hub) announcePresence(baseID uuid.UUID, presence *UserPresence) {
+	snapshot := *presence
+	msg := NewMessage(MsgTypePresence, baseID, presence.UserID).WithPayload(&snapshot)
+	h.broadcastToBase(baseID, msg, uuid.Nil)
+	h.publish(&Envelope{Kind: EnvelopeMessage, BaseID: baseID, Message: msg, Presence: []*UserPresence{&snapshot}})
+}
+
+// updateClientPresence applies a cursor update from one of a user's clients
+func (h *Hub) updateClientPresence(client *Client, update func(*UserPresence)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	presence, ok := h.presence[client.baseID][client.userID]
+	if !ok || !h.bases[client.baseID][client] {
+		return
+	}
+
+	update(&client.cursor)
+	client.cursor.UpdatedAt = time.Now().UTC()
+
+	presence.TableID = client.cursor.TableID
+	presence.ViewID = client.cursor.ViewID
+	presence.CellRef = client.cursor.CellRef
+	presence.UpdatedAt = client.cursor.UpdatedAt
+	h.announcePresence(client.baseID, presence)
+}
+
+// reply sends a message to a single client if it is still connected
+func (h *Hub) reply(client *Client, message *Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.bases[client.baseID][client] {
+		h.sendTo(client, message)
+	}
+}
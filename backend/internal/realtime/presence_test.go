@@ -0,0 +1,198 @@
+package realtime
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_MultipleConnections(t *testing.T) {
+	hub := NewHub()
+	baseID := uuid.New()
+	observer := newTestClient(hub, baseID)
+	tab1 := newTestClient(hub, baseID)
+	tab2 := &Client{hub: hub, userID: tab1.userID, email: tab1.email, baseID: baseID, send: make(chan *Message, 256)}
+
+	hub.registerClient(observer)
+	hub.registerClient(tab1)
+	hub.registerClient(tab2)
+	messageTypes(observer)
+
+	list := hub.GetPresence(baseID)
+	require.Len(t, list, 2)
+	for _, p := range list {
+		if p.UserID == tab1.userID {
+			assert.Equal(t, 2, p.Connections)
+		}
+	}
+
+	t.Run("the latest tab's cursor is shown", func(t *testing.T) {
+		tableID := uuid.New()
+		hub.updateClientPresence(tab2, func(p *UserPresence) { p.TableID = &tableID })
+
+		msg := nextMessage(t, observer, MsgTypePresence)
+		presence := msg.Payload.(*UserPresence)
+		require.NotNil(t, presence.TableID)
+		assert.Equal(t, tableID, *presence.TableID)
+	})
+
+	t.Run("closing one tab keeps the user present", func(t *testing.T) {
+		otherTable := uuid.New()
+		hub.updateClientPresence(tab1, func(p *UserPresence) { p.TableID = &otherTable })
+		messageTypes(observer)
+
+		hub.unregisterClient(tab1)
+
+		types, _ := messageTypes(observer)
+		assert.NotContains(t, types, MsgTypeUserLeft)
+		presence := hub.presence[baseID][tab1.userID]
+		require.NotNil(t, presence)
+		assert.Equal(t, 1, presence.Connections)
+
+		// Falls back to the remaining tab's cursor
+		require.NotNil(t, presence.TableID)
+		assert.NotEqual(t, otherTable, *presence.TableID)
+	})
+
+	t.Run("closing the last tab removes the user", func(t *testing.T) {
+		hub.unregisterClient(tab2)
+
+		nextMessage(t, observer, MsgTypeUserLeft)
+		assert.Len(t, hub.GetPresence(baseID), 1)
+	})
+}
+
+func TestHub_SlowConsumers(t *testing.T) {
+	fill := func(client *Client) {
+		for len(client.send) < cap(client.send) {
+			client.send <- NewMessage("filler", client.baseID, client.userID)
+		}
+	}
+
+	t.Run("presence updates are dropped for full queues", func(t *testing.T) {
+		hub := NewHub()
+		baseID := uuid.New()
+		slow := newTestClient(hub, baseID)
+		mover := newTestClient(hub, baseID)
+		hub.registerClient(slow)
+		hub.registerClient(mover)
+		fill(slow)
+
+		hub.updateClientPresence(mover, func(p *UserPresence) { p.CellRef = &CellRef{} })
+
+		assert.True(t, hub.bases[baseID][slow])
+		assert.Equal(t, int64(1), hub.Stats().DroppedMessages)
+	})
+
+	t.Run("clients that miss data changes are disconnected", func(t *testing.T) {
+		hub := NewHub()
+		baseID := uuid.New()
+		slow := newTestClient(hub, baseID)
+		fast := newTestClient(hub, baseID)
+		hub.registerClient(slow)
+		hub.registerClient(fast)
+		fill(slow)
+
+		hub.broadcastMessage(NewMessage(MsgTypeRecordCreated, baseID, uuid.New()))
+
+		assert.False(t, hub.bases[baseID][slow])
+		assert.True(t, hub.bases[baseID][fast])
+		assert.Equal(t, int64(1), hub.Stats().SlowDisconnects)
+		assert.Len(t, hub.GetPresence(baseID), 1)
+		nextMessage(t, fast, MsgTypeUserLeft)
+	})
+
+	t.Run("replies to disconnected clients are discarded", func(t *testing.T) {
+		hub := NewHub()
+		client := newTestClient(hub, uuid.New())
+		hub.registerClient(client)
+		hub.unregisterClient(client)
+
+		assert.NotPanics(t, func() { hub.reply(client, NewMessage("pong", client.baseID, client.userID)) })
+	})
+}
+
+func TestHub_ManyClients(t *testing.T) {
+	const (
+		bases   = 5
+		users   = 60
+		tabs    = 2
+		changes = 50
+	)
+
+	hub := NewHub()
+	go hub.Run()
+
+	baseIDs := make([]uuid.UUID, bases)
+	for i := range baseIDs {
+		baseIDs[i] = uuid.New()
+	}
+
+	// A quarter of the users never read their queue
+	var readers sync.WaitGroup
+	received := make([]int, bases*users*tabs)
+	var clients []*Client
+	for b, baseID := range baseIDs {
+		for u := 0; u < users; u++ {
+			userID := uuid.New()
+			for tab := 0; tab < tabs; tab++ {
+				client := &Client{hub: hub, userID: userID, email: fmt.Sprintf("user%d@example.com", u), baseID: baseID, send: make(chan *Message, 64)}
+				clients = append(clients, client)
+				hub.Register(client)
+
+				if u%4 == 0 {
+					continue
+				}
+				index := (b*users+u)*tabs + tab
+				readers.Add(1)
+				go func() {
+					defer readers.Done()
+					for msg := range client.send {
+						if msg.Type == MsgTypeRecordUpdated {
+							received[index]++
+						}
+					}
+				}()
+			}
+		}
+	}
+
+	for i := 0; i < changes; i++ {
+		for _, baseID := range baseIDs {
+			hub.Broadcast(NewMessage(MsgTypeRecordUpdated, baseID, uuid.New()))
+		}
+		// Keep within the broadcast channel's buffer
+		time.Sleep(time.Millisecond)
+	}
+
+	require.Eventually(t, func() bool {
+		stats := hub.Stats()
+		return stats.Clients == bases*users*tabs*3/4
+	}, 5*time.Second, 10*time.Millisecond, "slow clients should be disconnected")
+
+	stats := hub.Stats()
+	assert.Equal(t, bases, stats.Bases)
+	assert.Equal(t, bases*users*3/4, stats.Users)
+	assert.Equal(t, int64(bases*users*tabs/4), stats.SlowDisconnects)
+
+	require.Eventually(t, func() bool {
+		return hub.GetActiveUsers(baseIDs[0]) == users*tabs*3/4
+	}, time.Second, 10*time.Millisecond)
+
+	for _, client := range clients {
+		hub.Unregister(client)
+	}
+	readers.Wait()
+
+	// Every reading client saw every change to its base
+	for i, count := range received {
+		if (i/tabs)%users%4 != 0 {
+			assert.Equal(t, changes, count, "client %d", i)
+		}
+	}
+}
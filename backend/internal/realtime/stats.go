@@ -0,0 +1,35 @@
+package realtime
+
+import "sync/atomic"
+
+// hubStats counts how the hub has dealt with slow clients
+type hubStats struct {
+	droppedMessages atomic.Int64
+	slowDisconnects atomic.Int64
+}
+
+// Stats is a snapshot of the hub's activity
+type Stats struct {
+	Bases           int   `json:"bases"`
+	Clients         int   `json:"clients"`
+	Users           int   `json:"users"`           // Distinct users per base, summed over bases
+	DroppedMessages int64 `json:"droppedMessages"` // Presence updates skipped for clients with full queues
+	SlowDisconnects int64 `json:"slowDisconnects"` // Clients disconnected for falling behind
+}
+
+// Stats returns the hub's connection counts and slow-consumer counters
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := Stats{
+		Bases:           len(h.bases),
+		DroppedMessages: h.stats.droppedMessages.Load(),
+		SlowDisconnects: h.stats.slowDisconnects.Load(),
+	}
+	for baseID, clients := range h.bases {
+		stats.Clients += len(clients)
+		stats.Users += len(h.presence[baseID])
+	}
+	return stats
+}
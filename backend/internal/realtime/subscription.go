@@ -0,0 +1,98 @@
+package realtime
+
+import "github.com/google/uuid"
+
+// subscriptions are the tables and views a client asked for. Record messages only go
+// to clients subscribed to their table; schema and presence messages go base-wide.
+type subscriptions struct {
+	tables map[uuid.UUID]bool
+	views  map[uuid.UUID]uuid.UUID // View ID to table ID
+}
+
+// isTableScoped reports whether a message type is routed by subscription
+func isTableScoped(msgType string) bool {
+	switch msgType {
+	case MsgTypeRecordCreated, MsgTypeRecordUpdated, MsgTypeRecordDeleted,
+		MsgTypeCellLocked, MsgTypeCellUnlocked:
+		return true
+	}
+	return false
+}
+
+// wants reports whether a client should receive a message. Clients without
+// subscriptions receive everything for their base.
+func (c *Client) wants(msg *Message) bool {
+	if !isTableScoped(msg.Type) || msg.TableID == nil || c.subs.empty() {
+		return true
+	}
+	return c.subs.coversTable(*msg.TableID)
+}
+
+func (s *subscriptions) empty() bool {
+	return len(s.tables) == 0 && len(s.views) == 0
+}
+
+func (s *subscriptions) coversTable(tableID uuid.UUID) bool {
+	if s.tables[tableID] {
+		return true
+	}
+	for _, viewTable := range s.views {
+		if viewTable == tableID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *subscriptions) add(req *SubscribeMessage) {
+	if s.tables == nil {
+		s.tables = make(map[uuid.UUID]bool)
+		s.views = make(map[uuid.UUID]uuid.UUID)
+	}
+	for _, tableID := range req.TableIDs {
+		s.tables[tableID] = true
+	}
+	for _, view := range req.Views {
+		s.views[view.ViewID] = view.TableID
+	}
+}
+
+func (s *subscriptions) remove(req *SubscribeMessage) {
+	for _, tableID := range req.TableIDs {
+		delete(s.tables, tableID)
+	}
+	for _, view := range req.Views {
+		delete(s.views, view.ViewID)
+	}
+}
+
+// list describes the subscriptions for the client's acknowledgement
+func (s *subscriptions) list() *SubscribeMessage {
+	list := &SubscribeMessage{TableIDs: []uuid.UUID{}, Views: []ViewSubscription{}}
+	for tableID := range s.tables {
+		list.TableIDs = append(list.TableIDs, tableID)
+	}
+	for viewID, tableID := range s.views {
+		list.Views = append(list.Views, ViewSubscription{ViewID: viewID, TableID: tableID})
+	}
+	return list
+}
+
+// Subscribe adds or removes a client's table and view subscriptions and acknowledges
+// the resulting set
+func (h *Hub) Subscribe(client *Client, req *SubscribeMessage, subscribe bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.bases[client.baseID][client] {
+		return
+	}
+	if subscribe {
+		client.subs.add(req)
+	} else {
+		client.subs.remove(req)
+	}
+
+	ack := NewMessage(MsgTypeSubscriptions, client.baseID, client.userID).WithPayload(client.subs.list())
+	h.sendTo(client, ack)
+}
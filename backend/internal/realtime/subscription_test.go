@@ -0,0 +1,89 @@
+package realtime
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_wants(t *testing.T) {
+	baseID := uuid.New()
+	tableA, tableB := uuid.New(), uuid.New()
+	recordIn := func(tableID uuid.UUID) *Message {
+		return NewMessage(MsgTypeRecordUpdated, baseID, uuid.New()).WithTable(tableID)
+	}
+
+	t.Run("clients without subscriptions receive everything", func(t *testing.T) {
+		client := &Client{}
+		assert.True(t, client.wants(recordIn(tableA)))
+	})
+
+	t.Run("table subscriptions filter record messages", func(t *testing.T) {
+		client := &Client{}
+		client.subs.add(&SubscribeMessage{TableIDs: []uuid.UUID{tableA}})
+
+		assert.True(t, client.wants(recordIn(tableA)))
+		assert.False(t, client.wants(recordIn(tableB)))
+	})
+
+	t.Run("view subscriptions receive their table's records", func(t *testing.T) {
+		client := &Client{}
+		client.subs.add(&SubscribeMessage{Views: []ViewSubscription{{ViewID: uuid.New(), TableID: tableB}}})
+
+		assert.True(t, client.wants(recordIn(tableB)))
+		assert.False(t, client.wants(recordIn(tableA)))
+	})
+
+	t.Run("schema changes go base-wide", func(t *testing.T) {
+		client := &Client{}
+		client.subs.add(&SubscribeMessage{TableIDs: []uuid.UUID{tableA}})
+
+		assert.True(t, client.wants(NewMessage(MsgTypeFieldCreated, baseID, uuid.New()).WithTable(tableB)))
+		assert.True(t, client.wants(NewMessage(MsgTypeTableDeleted, baseID, uuid.New()).WithTable(tableB)))
+		assert.True(t, client.wants(NewMessage(MsgTypeUserJoined, baseID, uuid.New())))
+	})
+
+	t.Run("unsubscribing keeps tables covered another way", func(t *testing.T) {
+		client := &Client{}
+		viewID := uuid.New()
+		client.subs.add(&SubscribeMessage{TableIDs: []uuid.UUID{tableA}, Views: []ViewSubscription{{ViewID: viewID, TableID: tableA}}})
+
+		client.subs.remove(&SubscribeMessage{TableIDs: []uuid.UUID{tableA}})
+		assert.True(t, client.wants(recordIn(tableA)))
+
+		client.subs.remove(&SubscribeMessage{Views: []ViewSubscription{{ViewID: viewID}}})
+		assert.True(t, client.subs.empty())
+	})
+}
+
+func TestHub_Subscribe(t *testing.T) {
+	hub := NewHub()
+	baseID := uuid.New()
+	tableA, tableB := uuid.New(), uuid.New()
+
+	subscribed := newTestClient(hub, baseID)
+	everything := newTestClient(hub, baseID)
+	hub.bases[baseID] = map[*Client]bool{subscribed: true, everything: true}
+
+	hub.Subscribe(subscribed, &SubscribeMessage{TableIDs: []uuid.UUID{tableA}}, true)
+	ack := <-subscribed.send
+	assert.Equal(t, MsgTypeSubscriptions, ack.Type)
+	list, ok := ack.Payload.(*SubscribeMessage)
+	require.True(t, ok)
+	assert.Equal(t, []uuid.UUID{tableA}, list.TableIDs)
+
+	hub.broadcastToBase(baseID, NewMessage(MsgTypeRecordCreated, baseID, uuid.New()).WithTable(tableB), uuid.Nil)
+	assert.Empty(t, subscribed.send)
+	assert.Len(t, everything.send, 1)
+
+	hub.broadcastToBase(baseID, NewMessage(MsgTypeRecordCreated, baseID, uuid.New()).WithTable(tableA), uuid.Nil)
+	assert.Len(t, subscribed.send, 1)
+	assert.Len(t, everything.send, 2)
+
+	hub.Subscribe(subscribed, &SubscribeMessage{TableIDs: []uuid.UUID{tableA}}, false)
+	<-subscribed.send
+	hub.broadcastToBase(baseID, NewMessage(MsgTypeRecordCreated, baseID, uuid.New()).WithTable(tableB), uuid.Nil)
+	assert.Len(t, subscribed.send, 2)
+}
@@ -0,0 +1,126 @@
+// Package secrets encrypts values that are stored in the database but must be read
+// back in plaintext, such as webhook signing secrets. Values are sealed with
+// AES-256-GCM under a server key and tagged so plaintext written before a key was
+// configured can still be read.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyEnv names the environment variable holding the 32-byte key, base64 or hex encoded
+const KeyEnv = "SECRETS_ENCRYPTION_KEY"
+
+// encryptedPrefix marks sealed values; the version allows changing the scheme later
+const encryptedPrefix = "enc:v1:"
+
+var (
+	ErrNoKey      = errors.New("no encryption key configured")
+	ErrInvalidKey = errors.New("encryption key must be 32 bytes")
+	ErrCorrupt    = errors.New("encrypted value is corrupt or was sealed with a different key")
+)
+
+// Box seals and opens values. A nil *Box stores values in plaintext, so callers can
+// use it unconditionally and encryption switches on once a key is configured.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox returns a box using the given 32-byte key
+func NewBox(key []byte) (*Box, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// BoxFromEnv returns a box using the key in SECRETS_ENCRYPTION_KEY, or nil if it is unset
+func BoxFromEnv() (*Box, error) {
+	encoded := strings.TrimSpace(os.Getenv(KeyEnv))
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := decodeKey(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", KeyEnv, err)
+	}
+	return NewBox(key)
+}
+
+// decodeKey accepts hex or standard/URL-safe base64
+func decodeKey(encoded string) ([]byte, error) {
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(encoded); err == nil {
+			if len(key) != 32 {
+				return nil, ErrInvalidKey
+			}
+			return key, nil
+		}
+	}
+	return nil, errors.New("key is neither hex nor base64")
+}
+
+// Enabled reports whether values are encrypted
+func (b *Box) Enabled() bool {
+	return b != nil
+}
+
+// Encrypt seals a value. Without a key the value is returned unchanged.
+func (b *Box) Encrypt(plaintext string) (string, error) {
+	if b == nil {
+		return plaintext, nil
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a sealed value. Values that were never encrypted are returned as is.
+func (b *Box) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if b == nil {
+		return "", ErrNoKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", ErrCorrupt
+	}
+	nonceSize := b.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", ErrCorrupt
+	}
+	plaintext, err := b.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", ErrCorrupt
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether a stored value was sealed by a Box
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestBox_EncryptDecrypt(t *testing.T) {
+	box, err := NewBox(testKey(1))
+	require.NoError(t, err)
+
+	sealed, err := box.Encrypt("whsec_abc")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(sealed))
+	assert.NotContains(t, sealed, "whsec_abc")
+
+	// A fresh nonce is used each time
+	again, err := box.Encrypt("whsec_abc")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+
+	opened, err := box.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_abc", opened)
+}
+
+func TestBox_Decrypt(t *testing.T) {
+	box, err := NewBox(testKey(1))
+	require.NoError(t, err)
+
+	t.Run("returns plaintext values unchanged", func(t *testing.T) {
+		opened, err := box.Decrypt("legacy-secret")
+		require.NoError(t, err)
+		assert.Equal(t, "legacy-secret", opened)
+	})
+
+	t.Run("rejects values sealed with another key", func(t *testing.T) {
+		other, err := NewBox(testKey(2))
+		require.NoError(t, err)
+		sealed, err := other.Encrypt("secret")
+		require.NoError(t, err)
+
+		_, err = box.Decrypt(sealed)
+		assert.ErrorIs(t, err, ErrCorrupt)
+	})
+
+	t.Run("rejects tampered values", func(t *testing.T) {
+		_, err := box.Decrypt(encryptedPrefix + "not base64!")
+		assert.ErrorIs(t, err, ErrCorrupt)
+		_, err = box.Decrypt(encryptedPrefix + "AAAA")
+		assert.ErrorIs(t, err, ErrCorrupt)
+	})
+}
+
+func TestNilBox(t *testing.T) {
+	var box *Box
+	assert.False(t, box.Enabled())
+
+	stored, err := box.Encrypt("secret")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", stored)
+
+	opened, err := box.Decrypt("secret")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", opened)
+
+	sealed, err := mustBox(t).Encrypt("secret")
+	require.NoError(t, err)
+	_, err = box.Decrypt(sealed)
+	assert.ErrorIs(t, err, ErrNoKey)
+}
+
+func mustBox(t *testing.T) *Box {
+	box, err := NewBox(testKey(3))
+	require.NoError(t, err)
+	return box
+}
+
+func TestNewBox_InvalidKey(t *testing.T) {
+	_, err := NewBox([]byte("short"))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestBoxFromEnv(t *testing.T) {
+	t.Run("returns nil when unset", func(t *testing.T) {
+		t.Setenv(KeyEnv, "")
+		box, err := BoxFromEnv()
+		require.NoError(t, err)
+		assert.Nil(t, box)
+	})
+
+	t.Run("accepts hex and base64 keys", func(t *testing.T) {
+		for _, encoded := range []string{
+			hex.EncodeToString(testKey(4)),
+			base64.StdEncoding.EncodeToString(testKey(4)),
+			base64.RawURLEncoding.EncodeToString(testKey(4)),
+		} {
+			t.Setenv(KeyEnv, encoded)
+			box, err := BoxFromEnv()
+			require.NoError(t, err, encoded)
+			assert.True(t, box.Enabled())
+		}
+	})
+
+	t.Run("rejects keys of the wrong length", func(t *testing.T) {
+		t.Setenv(KeyEnv, base64.StdEncoding.EncodeToString([]byte("too short")))
+		_, err := BoxFromEnv()
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	})
+}
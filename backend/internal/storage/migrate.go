@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// CopyLocal copies every file under a local storage directory into dst. Files dst
+// already has are skipped, so an interrupted copy can simply be run again. It returns
+// how many files were copied and skipped.
+func CopyLocal(ctx context.Context, src *LocalStorage, dst Storage) (copied, skipped int, err error) {
+	err = filepath.WalkDir(src.basePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return ctx.Err()
+		}
+
+		rel, err := filepath.Rel(src.basePath, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+
+		exists, err := dst.Exists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if exists {
+			skipped++
+			return nil
+		}
+
+		contentType := mime.TypeByExtension(filepath.Ext(key))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := dst.Upload(ctx, key, file, contentType); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", key, err)
+		}
+		copied++
+		return nil
+	})
+	return copied, skipped, err
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyLocal(t *testing.T) {
+	ctx := context.Background()
+
+	local, err := NewLocalStorage(t.TempDir(), "http://localhost")
+	require.NoError(t, err)
+	require.NoError(t, local.Upload(ctx, "field/record/a.png", strings.NewReader("png"), ""))
+	require.NoError(t, local.Upload(ctx, "field/record/b.txt", strings.NewReader("text"), ""))
+
+	fake, bucket := newFakeS3(t)
+	require.NoError(t, bucket.Upload(ctx, "field/record/b.txt", strings.NewReader("text"), "text/plain"))
+
+	copied, skipped, err := CopyLocal(ctx, local, bucket)
+	require.NoError(t, err)
+	assert.Equal(t, 1, copied)
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, "png", string(fake.objects["field/record/a.png"]))
+	assert.Equal(t, "image/png", fake.contentTypes["field/record/a.png"])
+
+	// Running it again copies nothing
+	copied, skipped, err = CopyLocal(ctx, local, bucket)
+	require.NoError(t, err)
+	assert.Equal(t, 0, copied)
+	assert.Equal(t, 2, skipped)
+}
@@ -0,0 +1,364 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Multipart upload settings. S3 requires every part but the last to be at least 5MB.
+const (
+	minPartSize     = 5 << 20
+	defaultPartSize = 8 << 20
+)
+
+// S3Config configures an S3-compatible storage backend
+type S3Config struct {
+	Endpoint        string // Defaults to AWS for the region; set it for MinIO and other stand-ins
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool // Address the bucket in the path rather than the host name
+	PartSize        int  // Size of multipart upload parts; files smaller than this are sent in one request
+}
+
+// S3ConfigFromEnv reads the S3 settings from the S3_* environment variables
+func S3ConfigFromEnv() S3Config {
+	return S3Config{
+		Endpoint:        os.Getenv("S3_ENDPOINT"),
+		Region:          os.Getenv("S3_REGION"),
+		Bucket:          os.Getenv("S3_BUCKET"),
+		AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		PathStyle:       os.Getenv("S3_FORCE_PATH_STYLE") == "true",
+	}
+}
+
+// S3Storage implements Storage using an S3-compatible object store
+type S3Storage struct {
+	endpoint   *url.URL
+	bucket     string
+	pathStyle  bool
+	partSize   int
+	signer     *signer
+	httpClient *http.Client
+}
+
+// NewS3Storage creates a new S3 storage backend
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("S3 bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("S3 access key ID and secret access key are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: %s", cfg.Endpoint)
+	}
+
+	partSize := cfg.PartSize
+	if partSize == 0 {
+		partSize = defaultPartSize
+	}
+	if partSize < minPartSize {
+		return nil, fmt.Errorf("S3 part size must be at least %d bytes", minPartSize)
+	}
+
+	return &S3Storage{
+		endpoint:  endpoint,
+		bucket:    cfg.Bucket,
+		pathStyle: cfg.PathStyle,
+		partSize:  partSize,
+		signer: &signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			region:          cfg.Region,
+			now:             time.Now,
+		},
+		// No overall timeout, as downloads are streamed to clients; requests are
+		// bounded by their contexts
+		httpClient: &http.Client{},
+	}, nil
+}
+
+// Upload stores a file in the bucket, using a multipart upload for files larger
+// than one part
+func (s *S3Storage) Upload(ctx context.Context, key string, data io.Reader, contentType string) error {
+	first, err := io.ReadAll(io.LimitReader(data, int64(s.partSize)))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(first) < s.partSize {
+		return s.putObject(ctx, key, first, contentType)
+	}
+	return s.multipartUpload(ctx, key, first, data, contentType)
+}
+
+func (s *S3Storage) putObject(ctx context.Context, key string, body []byte, contentType string) error {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	resp, err := s.do(ctx, http.MethodPut, key, nil, header, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// multipartUpload sends first and the rest of data as parts, aborting the upload
+// if any part fails so the bucket is not left holding orphaned parts
+func (s *S3Storage) multipartUpload(ctx context.Context, key string, first []byte, data io.Reader, contentType string) error {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	if err := s.uploadParts(ctx, key, initiated.UploadID, first, data); err != nil {
+		abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if resp, abortErr := s.do(abortCtx, http.MethodDelete, key, url.Values{"uploadId": {initiated.UploadID}}, nil, nil); abortErr == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3Storage) uploadParts(ctx context.Context, key, uploadID string, part []byte, data io.Reader) error {
+	var parts []completedPart
+	buf := make([]byte, s.partSize)
+	for number := 1; len(part) > 0; number++ {
+		query := url.Values{
+			"partNumber": {fmt.Sprint(number)},
+			"uploadId":   {uploadID},
+		}
+		resp, err := s.do(ctx, http.MethodPut, key, query, nil, part)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		n, err := io.ReadFull(data, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		part = buf[:n]
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Completion can fail after the 200 status has been sent
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if s3Err := parseS3Error(respBody); s3Err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", s3Err)
+	}
+	return nil
+}
+
+// Download retrieves a file from the bucket
+func (s *S3Storage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		var s3Err *S3Error
+		if errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("file not found: %s", key)
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetURL returns a presigned URL that allows downloading the file until expiry passes
+func (s *S3Storage) GetURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return "", err
+	}
+	s.signer.presign(req, expiry)
+	return req.URL.String(), nil
+}
+
+// Delete removes a file from the bucket. Deleting a missing file is not an error.
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		var s3Err *S3Error
+		if errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Exists checks if a file exists in the bucket
+func (s *S3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		var s3Err *S3Error
+		if errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// listPageSize is how many keys each ListObjectsV2 request asks for
+const listPageSize = 1000
+
+// List pages through the bucket and calls fn for every object in it
+func (s *S3Storage) List(ctx context.Context, fn func(ObjectInfo) error) error {
+	query := url.Values{"list-type": {"2"}, "max-keys": {fmt.Sprint(listPageSize)}}
+	for {
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+			Contents              []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read bucket listing: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			if err := fn(ObjectInfo{Key: object.Key, Size: object.Size, ModTime: object.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+}
+
+// objectURL returns the URL of an object, with the bucket in the host name or path
+func (s *S3Storage) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	path := u.Path + "/" + uriEncode(key, false)
+	if s.pathStyle {
+		path = u.Path + "/" + uriEncode(s.bucket, true) + "/" + uriEncode(key, false)
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.RawPath = path
+	u.Path, _ = url.PathUnescape(path)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do sends a signed request and turns error responses into *S3Error
+func (s *S3Storage) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	payloadHash := emptyPayload
+	if len(body) > 0 {
+		payloadHash = hashHex(body)
+	}
+	s.signer.sign(req, payloadHash)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s %s: %w", method, key, err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		s3Err := parseS3Error(respBody)
+		if s3Err == nil {
+			s3Err = &S3Error{Code: http.StatusText(resp.StatusCode)}
+		}
+		s3Err.StatusCode = resp.StatusCode
+		return nil, fmt.Errorf("S3 %s %s: %w", method, key, s3Err)
+	}
+	return resp, nil
+}
+
+// S3Error is an error response from the object store
+type S3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// parseS3Error returns the error in an S3 XML response body, or nil if there is none
+func parseS3Error(body []byte) *S3Error {
+	var s3Err struct {
+		XMLName xml.Name
+		S3Error
+	}
+	if err := xml.Unmarshal(body, &s3Err); err != nil || s3Err.XMLName.Local != "Error" {
+		return nil
+	}
+	return &s3Err.S3Error
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLSigner(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	signer := NewURLSigner([]byte("test-key"))
+	signer.now = func() time.Time { return now }
+
+	query, err := url.ParseQuery(signer.Sign("download:abc", 5*time.Minute))
+	require.NoError(t, err)
+
+	t.Run("accepts its own signature", func(t *testing.T) {
+		assert.NoError(t, signer.Verify("download:abc", query))
+	})
+
+	t.Run("is bound to the resource", func(t *testing.T) {
+		assert.ErrorIs(t, signer.Verify("download:xyz", query), ErrInvalidSignature)
+	})
+
+	t.Run("rejects a changed expiry", func(t *testing.T) {
+		tampered := url.Values{"expires": {"99999999999"}, "signature": query["signature"]}
+		assert.ErrorIs(t, signer.Verify("download:abc", tampered), ErrInvalidSignature)
+	})
+
+	t.Run("rejects another key's signature", func(t *testing.T) {
+		other := NewURLSigner([]byte("other-key"))
+		other.now = signer.now
+		assert.ErrorIs(t, other.Verify("download:abc", query), ErrInvalidSignature)
+	})
+
+	t.Run("rejects missing parameters", func(t *testing.T) {
+		assert.ErrorIs(t, signer.Verify("download:abc", url.Values{}), ErrInvalidSignature)
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		later := NewURLSigner([]byte("test-key"))
+		later.now = func() time.Time { return now.Add(6 * time.Minute) }
+		assert.ErrorIs(t, later.Verify("download:abc", query), ErrURLExpired)
+	})
+}
@@ -2,8 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
@@ -27,12 +29,23 @@ type Storage interface {
 
 	// Exists checks if a file exists
 	Exists(ctx context.Context, key string) (bool, error)
+
+	// List calls fn for every stored file, stopping at the first error fn returns
+	List(ctx context.Context, fn func(ObjectInfo) error) error
+}
+
+// ObjectInfo describes a stored file
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
 }
 
 // LocalStorage implements Storage using the local filesystem
 type LocalStorage struct {
-	basePath string
-	baseURL  string
+	basePath  string
+	baseURL   string
+	urlSigner *URLSigner
 }
 
 // NewLocalStorage creates a new local storage backend
@@ -55,6 +68,12 @@ func GenerateKey(fieldID, recordID uuid.UUID, filename string) string {
 	return fmt.Sprintf("%s/%s/%s%s", fieldID.String(), recordID.String(), uniqueID, ext)
 }
 
+// ContentKey returns the storage key for a file with the given hex SHA-256 hash.
+// Files with the same content share a key, so each is stored once.
+func ContentKey(sha256Hex string) string {
+	return fmt.Sprintf("blobs/%s/%s", sha256Hex[:2], sha256Hex)
+}
+
 // Upload stores a file on the local filesystem
 func (s *LocalStorage) Upload(ctx context.Context, key string, data io.Reader, contentType string) error {
 	fullPath := filepath.Join(s.basePath, key)
@@ -95,12 +114,23 @@ func (s *LocalStorage) Download(ctx context.Context, key string) (io.ReadCloser,
 	return file, nil
 }
 
-// GetURL returns a URL to access the file
-// For local storage, this returns a static URL path
+// SetURLSigner sets the signer GetURL uses
+func (s *LocalStorage) SetURLSigner(signer *URLSigner) {
+	s.urlSigner = signer
+}
+
+// GetURL returns a signed URL to access the file until expiry passes
+// For local storage, files are served through an API endpoint that checks the signature
 func (s *LocalStorage) GetURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
-	// For local storage, we serve files through an API endpoint
-	// The expiry parameter is ignored for local storage
-	return fmt.Sprintf("%s/api/v1/attachments/file/%s", s.baseURL, key), nil
+	if s.urlSigner == nil {
+		return "", errors.New("signed URLs are not configured")
+	}
+	return fmt.Sprintf("%s/api/v1/attachments/file/%s?%s", s.baseURL, key, s.urlSigner.Sign(FileResource(key), expiry)), nil
+}
+
+// FileResource is what a signed URL for a stored file is bound to
+func FileResource(key string) string {
+	return "file:" + key
 }
 
 // Delete removes a file from the local filesystem
@@ -132,6 +162,28 @@ func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
 	return true, nil
 }
 
+// List walks the storage directory and calls fn for every file in it
+func (s *LocalStorage) List(ctx context.Context, fn func(ObjectInfo) error) error {
+	return filepath.WalkDir(s.basePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return ctx.Err()
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.basePath, path)
+		if err != nil {
+			return err
+		}
+		return fn(ObjectInfo{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
+
 // GetBasePath returns the base path for the storage
 func (s *LocalStorage) GetBasePath() string {
 	return s.basePath
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -86,6 +87,11 @@ func TestGenerateKey(t *testing.T) {
 	})
 }
 
+func TestContentKey(t *testing.T) {
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	assert.Equal(t, "blobs/9f/"+hash, ContentKey(hash))
+}
+
 func TestLocalStorage_Upload(t *testing.T) {
 	t.Run("uploads file successfully", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -180,36 +186,33 @@ func TestLocalStorage_Download(t *testing.T) {
 }
 
 func TestLocalStorage_GetURL(t *testing.T) {
-	t.Run("returns correct URL format", func(t *testing.T) {
+	t.Run("returns a signed URL", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		baseURL := "http://localhost:8080"
 		storage, err := NewLocalStorage(tmpDir, baseURL)
 		require.NoError(t, err)
+		signer := NewURLSigner([]byte("test-key"))
+		storage.SetURLSigner(signer)
 
 		ctx := context.Background()
 		key := "test/file.pdf"
-		url, err := storage.GetURL(ctx, key, time.Hour)
+		rawURL, err := storage.GetURL(ctx, key, time.Hour)
 		require.NoError(t, err)
 
-		expectedURL := "http://localhost:8080/api/v1/attachments/file/test/file.pdf"
-		assert.Equal(t, expectedURL, url)
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/attachments/file/test/file.pdf", u.Path)
+		assert.NoError(t, signer.Verify(FileResource(key), u.Query()))
+		assert.ErrorIs(t, signer.Verify(FileResource("test/other.pdf"), u.Query()), ErrInvalidSignature)
 	})
 
-	t.Run("ignores expiry parameter", func(t *testing.T) {
+	t.Run("fails without a signer", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		storage, err := NewLocalStorage(tmpDir, "http://localhost:8080")
 		require.NoError(t, err)
 
-		ctx := context.Background()
-		key := "file.txt"
-
-		url1, err := storage.GetURL(ctx, key, time.Hour)
-		require.NoError(t, err)
-
-		url2, err := storage.GetURL(ctx, key, time.Minute)
-		require.NoError(t, err)
-
-		assert.Equal(t, url1, url2)
+		_, err = storage.GetURL(context.Background(), "test/file.pdf", time.Hour)
+		assert.Error(t, err)
 	})
 }
 
@@ -275,6 +278,25 @@ func TestLocalStorage_Exists(t *testing.T) {
 	})
 }
 
+func TestLocalStorage_List(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage, err := NewLocalStorage(tmpDir, "http://localhost:8080")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, storage.Upload(ctx, "a.txt", strings.NewReader("a"), "text/plain"))
+	require.NoError(t, storage.Upload(ctx, "blobs/ab/abc", strings.NewReader("abc"), "text/plain"))
+
+	listed := map[string]int64{}
+	err = storage.List(ctx, func(obj ObjectInfo) error {
+		listed[obj.Key] = obj.Size
+		assert.False(t, obj.ModTime.IsZero())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"a.txt": 1, "blobs/ab/abc": 3}, listed)
+}
+
 func TestLocalStorage_GetBasePath(t *testing.T) {
 	t.Run("returns correct base path", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -290,6 +312,7 @@ func TestLocalStorage_Integration(t *testing.T) {
 		tmpDir := t.TempDir()
 		storage, err := NewLocalStorage(tmpDir, "http://localhost:8080")
 		require.NoError(t, err)
+		storage.SetURLSigner(NewURLSigner([]byte("test-key")))
 
 		ctx := context.Background()
 		fieldID := uuid.New()
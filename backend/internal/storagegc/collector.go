@@ -0,0 +1,152 @@
+package storagegc
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/storage"
+	"github.com/vibetable/backend/internal/store"
+	"github.com/vibetable/backend/internal/thumbnail"
+)
+
+// Collector settings
+const (
+	interval    = 6 * time.Hour
+	gracePeriod = 24 * time.Hour // Files are kept this long after they were last used or written
+	batchSize   = 100
+	checkSize   = 500 // Listed files checked against the database at a time
+)
+
+// Collector removes stored files that no attachment uses any more
+type Collector struct {
+	attachments *store.AttachmentStore
+	storage     storage.Storage
+	now         func() time.Time
+}
+
+// NewCollector creates a storage garbage collector
+func NewCollector(attachments *store.AttachmentStore, stor storage.Storage) *Collector {
+	return &Collector{
+		attachments: attachments,
+		storage:     stor,
+		now:         time.Now,
+	}
+}
+
+// Result counts the files a collection removed
+type Result struct {
+	Released  int // Files whose last attachment was deleted
+	Orphaned  int // Files the database had no record of
+	Abandoned int // Resumable uploads left idle past their timeout
+}
+
+// Start runs a collection now and then every interval until ctx is cancelled
+func (c *Collector) Start(ctx context.Context) {
+	go c.run(ctx)
+}
+
+func (c *Collector) run(ctx context.Context) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := c.Collect(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Storage garbage collection failed: %v", err)
+		}
+		if result.Released > 0 || result.Orphaned > 0 || result.Abandoned > 0 {
+			log.Printf("Storage garbage collection removed %d unused and %d orphaned files and %d abandoned uploads", result.Released, result.Orphaned, result.Abandoned)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect removes resumable uploads that were abandoned, then files past their grace
+// period that nothing uses: first those whose last attachment was deleted, then any
+// in storage the database does not know about, such as leftovers from interrupted
+// uploads
+func (c *Collector) Collect(ctx context.Context) (Result, error) {
+	var result Result
+
+	n, parts, err := c.attachments.DeleteExpiredUploads(ctx, c.now().Add(-store.UploadSessionTimeout))
+	if err != nil {
+		return result, err
+	}
+	result.Abandoned = n
+	for _, key := range parts {
+		// Parts that cannot be deleted now are found as orphans later
+		_ = c.storage.Delete(ctx, key)
+	}
+
+	cutoff := c.now().Add(-gracePeriod)
+	for {
+		n, err := c.attachments.DeleteUnreferencedBlobs(ctx, cutoff, batchSize, c.remove)
+		result.Released += n
+		if err != nil {
+			return result, err
+		}
+		if n < batchSize {
+			break
+		}
+	}
+
+	var batch []string
+	err = c.storage.List(ctx, func(obj storage.ObjectInfo) error {
+		if !obj.ModTime.Before(cutoff) {
+			return nil
+		}
+		batch = append(batch, obj.Key)
+		if len(batch) < checkSize {
+			return nil
+		}
+		n, err := c.removeOrphans(ctx, batch)
+		result.Orphaned += n
+		batch = batch[:0]
+		return err
+	})
+	if err != nil {
+		return result, err
+	}
+	if len(batch) > 0 {
+		n, err := c.removeOrphans(ctx, batch)
+		result.Orphaned += n
+		return result, err
+	}
+	return result, nil
+}
+
+// remove deletes a released file along with any thumbnails made from it
+func (c *Collector) remove(ctx context.Context, key string) error {
+	if err := c.storage.Delete(ctx, key); err != nil {
+		return err
+	}
+	thumbnailKey := thumbnail.KeyPrefix(key)
+	thumbnails := models.Attachment{ThumbnailKey: &thumbnailKey}
+	for _, size := range models.ThumbnailSizes {
+		if err := c.storage.Delete(ctx, thumbnails.ThumbnailStorageKey(size.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeOrphans deletes the listed files the database has no record of
+func (c *Collector) removeOrphans(ctx context.Context, keys []string) (int, error) {
+	orphans, err := c.attachments.FindUnreferencedKeys(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	for i, key := range orphans {
+		if err := c.storage.Delete(ctx, key); err != nil {
+			return i, err
+		}
+	}
+	return len(orphans), nil
+}
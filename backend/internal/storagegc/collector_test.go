@@ -0,0 +1,198 @@
+package storagegc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/storage"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// memoryStorage implements storage.Storage in memory for testing
+type memoryStorage struct {
+	files    map[string]time.Time // Key to modification time
+	failKeys map[string]bool
+}
+
+func (m *memoryStorage) Upload(ctx context.Context, key string, data io.Reader, contentType string) error {
+	m.files[key] = time.Now()
+	return nil
+}
+
+func (m *memoryStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(nil)), nil
+}
+
+func (m *memoryStorage) GetURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "http://localhost/files/" + key, nil
+}
+
+func (m *memoryStorage) Delete(ctx context.Context, key string) error {
+	if m.failKeys[key] {
+		return errors.New("delete failed")
+	}
+	delete(m.files, key)
+	return nil
+}
+
+func (m *memoryStorage) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := m.files[key]
+	return ok, nil
+}
+
+func (m *memoryStorage) List(ctx context.Context, fn func(storage.ObjectInfo) error) error {
+	keys := make([]string, 0, len(m.files))
+	for key := range m.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(storage.ObjectInfo{Key: key, ModTime: m.files[key]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestCollector(t *testing.T, files map[string]time.Time) (pgxmock.PgxPoolIface, *Collector, *memoryStorage, time.Time) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mock.Close)
+
+	stor := &memoryStorage{files: files, failKeys: map[string]bool{}}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := NewCollector(store.NewAttachmentStore(mock, nil, nil, nil, stor, "http://localhost"), stor)
+	c.now = func() time.Time { return now }
+	return mock, c, stor, now.Add(-gracePeriod)
+}
+
+// expectExpiredUploads expects the sweep of abandoned uploads, returning parts of one
+// upload when there are any
+func expectExpiredUploads(mock pgxmock.PgxPoolIface, parts ...string) {
+	rows := pgxmock.NewRows([]string{"id", "storage_key"})
+	uploadID := uuid.New()
+	for _, key := range parts {
+		rows.AddRow(uploadID, &key)
+	}
+	mock.ExpectQuery("WITH expired AS").WithArgs(pgxmock.AnyArg()).WillReturnRows(rows)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("removes released files, their thumbnails and old orphans", func(t *testing.T) {
+		old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		recent := time.Date(2024, 6, 1, 11, 0, 0, 0, time.UTC)
+		mock, c, stor, cutoff := newTestCollector(t, map[string]time.Time{
+			"blobs/aa/aa1":                 old,
+			"blobs/aa/aa1_thumb_small.jpg": old,
+			"blobs/aa/aa1_thumb_large.jpg": old,
+			"blobs/bb/bb2":                 old,
+			"field/record/orphan.txt":      old,
+			"field/record/upload.txt":      recent,
+		})
+
+		expectExpiredUploads(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT storage_key FROM storage_blobs").
+			WithArgs(cutoff, batchSize).
+			WillReturnRows(pgxmock.NewRows([]string{"storage_key"}).AddRow("blobs/aa/aa1"))
+		mock.ExpectExec("DELETE FROM storage_blobs").
+			WithArgs([]string{"blobs/aa/aa1"}).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectCommit()
+
+		// Files written within the grace period are not checked
+		mock.ExpectQuery("SELECT k.storage_key").
+			WithArgs([]string{"blobs/bb/bb2", "field/record/orphan.txt"}, []string{"", ""}).
+			WillReturnRows(pgxmock.NewRows([]string{"storage_key"}).AddRow("field/record/orphan.txt"))
+
+		result, err := c.Collect(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Result{Released: 1, Orphaned: 1}, result)
+
+		var left []string
+		for key := range stor.files {
+			left = append(left, key)
+		}
+		assert.ElementsMatch(t, []string{"blobs/bb/bb2", "field/record/upload.txt"}, left)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("keeps the rows of files it could not delete", func(t *testing.T) {
+		old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock, c, stor, cutoff := newTestCollector(t, map[string]time.Time{
+			"blobs/aa/aa1": old,
+			"blobs/bb/bb2": old,
+		})
+		stor.failKeys["blobs/bb/bb2"] = true
+
+		expectExpiredUploads(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT storage_key FROM storage_blobs").
+			WithArgs(cutoff, batchSize).
+			WillReturnRows(pgxmock.NewRows([]string{"storage_key"}).AddRow("blobs/aa/aa1").AddRow("blobs/bb/bb2"))
+		mock.ExpectExec("DELETE FROM storage_blobs").
+			WithArgs([]string{"blobs/aa/aa1"}).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectCommit()
+
+		result, err := c.Collect(ctx)
+		assert.ErrorContains(t, err, "delete failed")
+		assert.Equal(t, 1, result.Released)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("recognizes thumbnails when checking listed files", func(t *testing.T) {
+		old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock, c, _, cutoff := newTestCollector(t, map[string]time.Time{
+			"f/r/photo_thumb_large.jpg": old,
+		})
+
+		expectExpiredUploads(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT storage_key FROM storage_blobs").
+			WithArgs(cutoff, batchSize).
+			WillReturnRows(pgxmock.NewRows([]string{"storage_key"}))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT k.storage_key").
+			WithArgs([]string{"f/r/photo_thumb_large.jpg"}, []string{"f/r/photo_thumb"}).
+			WillReturnRows(pgxmock.NewRows([]string{"storage_key"}))
+
+		result, err := c.Collect(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Result{}, result)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("removes abandoned uploads and their parts", func(t *testing.T) {
+		recent := time.Date(2024, 6, 1, 11, 0, 0, 0, time.UTC)
+		parts := []string{"uploads/u/00000000000000000000", "uploads/u/00000000000000001024"}
+		mock, c, stor, cutoff := newTestCollector(t, map[string]time.Time{
+			parts[0]: recent,
+			parts[1]: recent,
+		})
+
+		expectExpiredUploads(mock, parts...)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT storage_key FROM storage_blobs").
+			WithArgs(cutoff, batchSize).
+			WillReturnRows(pgxmock.NewRows([]string{"storage_key"}))
+		mock.ExpectCommit()
+
+		result, err := c.Collect(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Result{Abandoned: 1}, result)
+		assert.Empty(t, stor.files)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
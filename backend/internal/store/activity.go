@@ -297,3 +297,19 @@ func (s *ActivityStore) LogTableDelete(ctx context.Context, baseID, userID uuid.
 		EntityName: &tableName,
 	})
 }
+
+// LogWebhookDisabled logs a webhook being disabled automatically, attributed to its creator
+func (s *ActivityStore) LogWebhookDisabled(ctx context.Context, baseID, userID uuid.UUID, webhookName, reason string) error {
+	changes, _ := json.Marshal([]models.ActivityChanges{
+		{FieldName: "is_active", OldValue: true, NewValue: false},
+		{FieldName: "disabled_reason", NewValue: reason},
+	})
+	return s.LogActivity(ctx, &models.Activity{
+		BaseID:     baseID,
+		UserID:     userID,
+		Action:     models.ActionDisable,
+		EntityType: models.EntityTypeWebhook,
+		EntityName: &webhookName,
+		Changes:    changes,
+	})
+}
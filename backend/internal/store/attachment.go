@@ -1,17 +1,36 @@
 package store
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/realtime"
 	"github.com/vibetable/backend/internal/storage"
 )
 
+// Upload rule errors
+var (
+	ErrNotAttachmentField   = errors.New("field is not an attachment field")
+	ErrFileTooLarge         = errors.New("file is too large")
+	ErrUnsupportedMediaType = errors.New("file type is not allowed")
+)
+
+// Thumbnail generation states of an image attachment
+const (
+	ThumbnailPending = "pending"
+	ThumbnailReady   = "ready"
+	ThumbnailFailed  = "failed"
+)
+
 type AttachmentStore struct {
 	db          DBTX
 	baseStore   *BaseStore
@@ -19,8 +38,16 @@ type AttachmentStore struct {
 	recordStore *RecordStore
 	storage     storage.Storage
 	baseURL     string
+	urlSigner   *storage.URLSigner
+
+	quota           int64
+	thumbnailNotify func()
+	hub             *realtime.Hub
 }
 
+// AttachmentURLExpiry is how long the signed URLs in attachment responses stay valid
+const AttachmentURLExpiry = time.Hour
+
 func NewAttachmentStore(db DBTX, baseStore *BaseStore, tableStore *TableStore, recordStore *RecordStore, stor storage.Storage, baseURL string) *AttachmentStore {
 	return &AttachmentStore{
 		db:          db,
@@ -32,6 +59,51 @@ func NewAttachmentStore(db DBTX, baseStore *BaseStore, tableStore *TableStore, r
 	}
 }
 
+// SetThumbnailNotifier sets a function called when an image is uploaded that needs
+// thumbnails, so the worker can pick it up without waiting for its next poll
+func (s *AttachmentStore) SetThumbnailNotifier(notify func()) {
+	s.thumbnailNotify = notify
+}
+
+// SetURLSigner sets the signer used to put short-lived signatures on attachment URLs,
+// so they work without a session token
+func (s *AttachmentStore) SetURLSigner(signer *storage.URLSigner) {
+	s.urlSigner = signer
+}
+
+// setURLs fills in the download and thumbnail URLs
+func (s *AttachmentStore) setURLs(a *models.Attachment) {
+	a.URL = s.signURL(fmt.Sprintf("%s/api/v1/attachments/%s/download", s.baseURL, a.ID.String()), downloadResource(a.ID))
+	if a.ThumbnailKey != nil {
+		thumbnailURL := s.signURL(fmt.Sprintf("%s/api/v1/attachments/%s/thumbnail", s.baseURL, a.ID.String()), thumbnailResource(a.ID))
+		a.ThumbnailURL = &thumbnailURL
+	}
+}
+
+func (s *AttachmentStore) signURL(rawURL, resource string) string {
+	if s.urlSigner == nil {
+		return rawURL
+	}
+	return rawURL + "?" + s.urlSigner.Sign(resource, AttachmentURLExpiry)
+}
+
+// Signed URLs are bound to the attachment and to what they download
+func downloadResource(attachmentID uuid.UUID) string {
+	return "download:" + attachmentID.String()
+}
+
+func thumbnailResource(attachmentID uuid.UUID) string {
+	return "thumbnail:" + attachmentID.String()
+}
+
+// verifySignedURL checks a signed URL's query for a resource
+func (s *AttachmentStore) verifySignedURL(resource string, query url.Values) error {
+	if s.urlSigner == nil {
+		return storage.ErrInvalidSignature
+	}
+	return s.urlSigner.Verify(resource, query)
+}
+
 // getBaseIDForRecord returns the base ID for a record
 func (s *AttachmentStore) getBaseIDForRecord(ctx context.Context, recordID uuid.UUID) (uuid.UUID, error) {
 	var baseID uuid.UUID
@@ -62,38 +134,128 @@ func (s *AttachmentStore) CreateAttachment(ctx context.Context, recordID, fieldI
 		return nil, ErrForbidden
 	}
 
-	// Generate storage key
-	storageKey := storage.GenerateKey(fieldID, recordID, filename)
+	options, err := s.getAttachmentFieldOptions(ctx, recordID, fieldID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The declared size is checked up front and the stream is cut off past the limit
+	maxSize := maxAttachmentSize(options)
+	if sizeBytes > maxSize {
+		return nil, fmt.Errorf("%w: the limit for this field is %d bytes", ErrFileTooLarge, maxSize)
+	}
+
+	// The stored type comes from the file's content, not what the client claims
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(data, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+	contentType = sniffContentType(head, filename, contentType)
+	if len(options.AllowedTypes) > 0 && !matchesContentType(contentType, options.AllowedTypes) {
+		return nil, fmt.Errorf("%w: %s is not accepted by this field", ErrUnsupportedMediaType, contentType)
+	}
+	data = &sizeLimitedReader{r: io.MultiReader(bytes.NewReader(head), data), remaining: maxSize}
+
+	// Files are stored under a hash of their content, so it is read in full first
+	file, hash, size, err := spoolUpload(data)
+	if err != nil {
+		if errors.Is(err, ErrFileTooLarge) {
+			return nil, fmt.Errorf("%w: the limit for this field is %d bytes", ErrFileTooLarge, maxSize)
+		}
+		return nil, err
+	}
+	defer removeSpool(file)
 
-	// Upload file to storage
-	if err := s.storage.Upload(ctx, storageKey, data, contentType); err != nil {
+	if err := s.checkQuota(ctx, baseID, size); err != nil {
 		return nil, err
 	}
 
-	// Create database record
+	storageKey := storage.ContentKey(hash)
+
+	// Images get thumbnails from the background worker
+	var thumbnailStatus *string
+	if models.CanThumbnail(contentType) {
+		status := ThumbnailPending
+		thumbnailStatus = &status
+	}
+
+	// The row is created first, counting a reference to the file, so the garbage
+	// collector cannot remove a copy already in storage before it is used
 	var a models.Attachment
 	err = s.db.QueryRow(ctx, `
-		INSERT INTO attachments (record_id, field_id, filename, content_type, size_bytes, storage_key, created_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO attachments (record_id, field_id, filename, content_type, size_bytes, storage_key, created_by, thumbnail_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, record_id, field_id, filename, content_type, size_bytes, storage_key, thumbnail_key, width, height, created_by, created_at
-	`, recordID, fieldID, filename, contentType, sizeBytes, storageKey, userID).Scan(
+	`, recordID, fieldID, filename, contentType, size, storageKey, userID, thumbnailStatus).Scan(
 		&a.ID, &a.RecordID, &a.FieldID, &a.Filename, &a.ContentType, &a.SizeBytes,
 		&a.StorageKey, &a.ThumbnailKey, &a.Width, &a.Height, &a.CreatedBy, &a.CreatedAt,
 	)
 	if err != nil {
-		// Try to clean up uploaded file
-		_ = s.storage.Delete(ctx, storageKey)
 		return nil, err
 	}
 
-	// Add URL using the download endpoint
-	a.URL = fmt.Sprintf("%s/api/v1/attachments/%s/download", s.baseURL, a.ID.String())
+	if err := s.storeBlob(ctx, storageKey, file, contentType); err != nil {
+		_, _ = s.db.Exec(ctx, `DELETE FROM attachments WHERE id = $1`, a.ID)
+		return nil, err
+	}
+
+	if thumbnailStatus != nil && s.thumbnailNotify != nil {
+		s.thumbnailNotify()
+	}
+
+	s.setURLs(&a)
 
 	return &a, nil
 }
 
-// GetAttachment returns an attachment by ID
-func (s *AttachmentStore) GetAttachment(ctx context.Context, attachmentID, userID uuid.UUID) (*models.Attachment, error) {
+// getAttachmentFieldOptions returns the options of an attachment field in the record's table
+func (s *AttachmentStore) getAttachmentFieldOptions(ctx context.Context, recordID, fieldID uuid.UUID) (*models.FieldOptions, error) {
+	var fieldType models.FieldType
+	var raw json.RawMessage
+	err := s.db.QueryRow(ctx, `
+		SELECT f.field_type, f.options
+		FROM fields f
+		JOIN records r ON r.table_id = f.table_id
+		WHERE f.id = $1 AND r.id = $2
+	`, fieldID, recordID).Scan(&fieldType, &raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fieldType != models.FieldTypeAttachment {
+		return nil, ErrNotAttachmentField
+	}
+
+	var options models.FieldOptions
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &options); err != nil {
+			return nil, fmt.Errorf("invalid attachment field options: %w", err)
+		}
+	}
+	return &options, nil
+}
+
+// sizeLimitedReader fails with ErrFileTooLarge once more than remaining bytes are read
+type sizeLimitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrFileTooLarge
+	}
+	return n, err
+}
+
+// getAttachmentByID returns an attachment without checking access
+func (s *AttachmentStore) getAttachmentByID(ctx context.Context, attachmentID uuid.UUID) (*models.Attachment, error) {
 	var a models.Attachment
 	err := s.db.QueryRow(ctx, `
 		SELECT id, record_id, field_id, filename, content_type, size_bytes, storage_key, thumbnail_key, width, height, created_by, created_at
@@ -108,6 +270,15 @@ func (s *AttachmentStore) GetAttachment(ctx context.Context, attachmentID, userI
 	if err != nil {
 		return nil, err
 	}
+	return &a, nil
+}
+
+// GetAttachment returns an attachment by ID
+func (s *AttachmentStore) GetAttachment(ctx context.Context, attachmentID, userID uuid.UUID) (*models.Attachment, error) {
+	a, err := s.getAttachmentByID(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Verify user has access
 	baseID, err := s.getBaseIDForRecord(ctx, a.RecordID)
@@ -119,11 +290,9 @@ func (s *AttachmentStore) GetAttachment(ctx context.Context, attachmentID, userI
 		return nil, err
 	}
 
-	// Add URL using the download endpoint
-	a.URL = fmt.Sprintf("%s/api/v1/attachments/%s/download", s.baseURL, a.ID.String())
-	// Note: thumbnails would need their own endpoint if implemented
+	s.setURLs(a)
 
-	return &a, nil
+	return a, nil
 }
 
 // ListAttachmentsForField returns all attachments for a record's field
@@ -159,8 +328,7 @@ func (s *AttachmentStore) ListAttachmentsForField(ctx context.Context, recordID,
 			return nil, err
 		}
 
-		// Add URL using the download endpoint
-		a.URL = fmt.Sprintf("%s/api/v1/attachments/%s/download", s.baseURL, a.ID.String())
+		s.setURLs(&a)
 
 		attachments = append(attachments, &a)
 	}
@@ -202,12 +370,8 @@ func (s *AttachmentStore) DeleteAttachment(ctx context.Context, attachmentID, us
 		return ErrNotFound
 	}
 
-	// Delete from storage (best effort - don't fail if storage delete fails)
-	_ = s.storage.Delete(ctx, a.StorageKey)
-	if a.ThumbnailKey != nil {
-		_ = s.storage.Delete(ctx, *a.ThumbnailKey)
-	}
-
+	// The file may be shared with other attachments, so it is left for the
+	// garbage collector to remove once nothing uses it
 	return nil
 }
 
@@ -225,3 +389,114 @@ func (s *AttachmentStore) DownloadAttachment(ctx context.Context, attachmentID,
 
 	return reader, a, nil
 }
+
+// DownloadSignedAttachment returns a reader for the attachment file when the query
+// carries a valid signature from the attachment's URL. No user is needed.
+func (s *AttachmentStore) DownloadSignedAttachment(ctx context.Context, attachmentID uuid.UUID, query url.Values) (io.ReadCloser, *models.Attachment, error) {
+	if err := s.verifySignedURL(downloadResource(attachmentID), query); err != nil {
+		return nil, nil, err
+	}
+	a, err := s.getAttachmentByID(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Download(ctx, a.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reader, a, nil
+}
+
+// DownloadSignedThumbnail returns a reader for one size of a thumbnail when the query
+// carries a valid signature from the attachment's thumbnail URL
+func (s *AttachmentStore) DownloadSignedThumbnail(ctx context.Context, attachmentID uuid.UUID, size string, query url.Values) (io.ReadCloser, error) {
+	if err := s.verifySignedURL(thumbnailResource(attachmentID), query); err != nil {
+		return nil, err
+	}
+	a, err := s.getAttachmentByID(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a.ThumbnailKey == nil {
+		return nil, ErrNotFound
+	}
+
+	return s.storage.Download(ctx, a.ThumbnailStorageKey(size))
+}
+
+// DownloadSignedFile returns a reader for a stored file when the query carries a
+// valid signature from storage.GetURL
+func (s *AttachmentStore) DownloadSignedFile(ctx context.Context, key string, query url.Values) (io.ReadCloser, error) {
+	if err := s.verifySignedURL(storage.FileResource(key), query); err != nil {
+		return nil, err
+	}
+	return s.storage.Download(ctx, key)
+}
+
+// DownloadThumbnail returns a reader for one size of an image attachment's thumbnail.
+// It returns ErrNotFound until the thumbnails have been generated.
+func (s *AttachmentStore) DownloadThumbnail(ctx context.Context, attachmentID, userID uuid.UUID, size string) (io.ReadCloser, *models.Attachment, error) {
+	a, err := s.GetAttachment(ctx, attachmentID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.ThumbnailKey == nil {
+		return nil, nil, ErrNotFound
+	}
+
+	reader, err := s.storage.Download(ctx, a.ThumbnailStorageKey(size))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reader, a, nil
+}
+
+// ListPendingThumbnails returns image attachments still waiting for thumbnails,
+// oldest first. It does not check access and is meant for the thumbnail worker.
+func (s *AttachmentStore) ListPendingThumbnails(ctx context.Context, limit int) ([]*models.Attachment, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, record_id, field_id, filename, content_type, size_bytes, storage_key, thumbnail_key, width, height, created_by, created_at
+		FROM attachments
+		WHERE thumbnail_status = $1
+		ORDER BY created_at ASC
+		LIMIT $2
+	`, ThumbnailPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*models.Attachment
+	for rows.Next() {
+		var a models.Attachment
+		if err := rows.Scan(
+			&a.ID, &a.RecordID, &a.FieldID, &a.Filename, &a.ContentType, &a.SizeBytes,
+			&a.StorageKey, &a.ThumbnailKey, &a.Width, &a.Height, &a.CreatedBy, &a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &a)
+	}
+
+	return attachments, rows.Err()
+}
+
+// SetImageInfo records the outcome of thumbnail generation. The dimensions are kept
+// even when generation failed, as long as they could be read.
+func (s *AttachmentStore) SetImageInfo(ctx context.Context, attachmentID uuid.UUID, status string, thumbnailKey *string, width, height *int) error {
+	result, err := s.db.Exec(ctx, `
+		UPDATE attachments
+		SET thumbnail_status = $2, thumbnail_key = $3, width = $4, height = $5
+		WHERE id = $1
+	`, attachmentID, status, thumbnailKey, width, height)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vibetable/backend/internal/models"
+)
+
+// spoolUpload copies an upload to a temporary file, hashing it on the way, so its
+// content-addressed key is known before anything is stored. The caller removes the
+// file with removeSpool.
+func spoolUpload(data io.Reader) (*os.File, string, int64, error) {
+	file, err := os.CreateTemp("", "vibetable-upload-*")
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), data)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		removeSpool(file)
+		return nil, "", 0, err
+	}
+	return file, hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+func removeSpool(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
+
+// storeBlob uploads a file under its content-addressed key unless an earlier upload
+// of the same content is already there
+func (s *AttachmentStore) storeBlob(ctx context.Context, key string, file *os.File, contentType string) error {
+	exists, err := s.storage.Exists(ctx, key)
+	if err != nil || exists {
+		return err
+	}
+	return s.storage.Upload(ctx, key, file, contentType)
+}
+
+// DeleteUnreferencedBlobs removes up to limit stored files that no attachment has
+// used since before cutoff, returning how many were removed. remove is called with
+// each file's row locked, so an upload of the same content waits for the file to go
+// and then stores it again. It stops at the first error remove returns.
+func (s *AttachmentStore) DeleteUnreferencedBlobs(ctx context.Context, cutoff time.Time, limit int, remove func(ctx context.Context, key string) error) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT storage_key FROM storage_blobs
+		WHERE ref_count <= 0 AND updated_at < $1
+		ORDER BY updated_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var removed []string
+	var removeErr error
+	for _, key := range keys {
+		if removeErr = remove(ctx, key); removeErr != nil {
+			break
+		}
+		removed = append(removed, key)
+	}
+
+	if len(removed) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM storage_blobs WHERE storage_key = ANY($1)`, removed); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(removed), removeErr
+}
+
+// FindUnreferencedKeys returns the storage keys that are neither a counted file, a
+// thumbnail of an attachment nor part of a resumable upload. It is meant for reconciling storage against the
+// database, where such files are left behind by interrupted uploads and deletes.
+func (s *AttachmentStore) FindUnreferencedKeys(ctx context.Context, keys []string) ([]string, error) {
+	thumbnailKeys := make([]string, len(keys))
+	for i, key := range keys {
+		thumbnailKeys[i] = thumbnailKeyOf(key)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT k.storage_key
+		FROM unnest($1::text[], $2::text[]) AS k(storage_key, thumbnail_key)
+		WHERE NOT EXISTS (SELECT 1 FROM storage_blobs b WHERE b.storage_key = k.storage_key)
+		  AND NOT EXISTS (SELECT 1 FROM attachments a WHERE a.storage_key = k.storage_key)
+		  AND NOT EXISTS (SELECT 1 FROM attachments a WHERE a.thumbnail_key = k.thumbnail_key)
+		  AND NOT EXISTS (SELECT 1 FROM upload_parts p WHERE p.storage_key = k.storage_key)
+	`, keys, thumbnailKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var unreferenced []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		unreferenced = append(unreferenced, key)
+	}
+	return unreferenced, rows.Err()
+}
+
+// thumbnailKeyOf returns the thumbnail prefix a storage key would belong to if it
+// is a thumbnail, or "" if it is not named like one
+func thumbnailKeyOf(key string) string {
+	for _, size := range models.ThumbnailSizes {
+		if prefix, ok := strings.CutSuffix(key, "_"+size.Name+".jpg"); ok {
+			return prefix
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+)
+
+// ErrStorageQuotaExceeded is returned when an upload would take a base over its quota
+var ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+
+// SetQuota sets how many bytes of attachments each base may store. Zero means unlimited.
+func (s *AttachmentStore) SetQuota(bytes int64) {
+	s.quota = bytes
+}
+
+// GetStorageUsage returns how much attachment storage a base uses and its quota.
+// Every attachment counts its full size, even when its content is shared.
+func (s *AttachmentStore) GetStorageUsage(ctx context.Context, baseID, userID uuid.UUID) (*models.StorageUsage, error) {
+	if _, err := s.baseStore.GetUserRole(ctx, baseID, userID); err != nil {
+		return nil, err
+	}
+	return s.storageUsage(ctx, baseID)
+}
+
+func (s *AttachmentStore) storageUsage(ctx context.Context, baseID uuid.UUID) (*models.StorageUsage, error) {
+	usage := models.StorageUsage{BaseID: baseID}
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(a.id), COALESCE(SUM(a.size_bytes), 0)
+		FROM attachments a
+		JOIN records r ON r.id = a.record_id
+		JOIN tables t ON t.id = r.table_id
+		WHERE t.base_id = $1
+	`, baseID).Scan(&usage.AttachmentCount, &usage.UsedBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.quota > 0 {
+		quota := s.quota
+		usage.QuotaBytes = &quota
+	}
+	return &usage, nil
+}
+
+// checkQuota returns ErrStorageQuotaExceeded if adding size bytes would take the
+// base over its quota
+func (s *AttachmentStore) checkQuota(ctx context.Context, baseID uuid.UUID, size int64) error {
+	usage, err := s.storageUsage(ctx, baseID)
+	if err != nil {
+		return err
+	}
+	if usage.QuotaBytes != nil && usage.UsedBytes+size > *usage.QuotaBytes {
+		return fmt.Errorf("%w: this base has %d of its %d bytes left", ErrStorageQuotaExceeded, max(*usage.QuotaBytes-usage.UsedBytes, 0), *usage.QuotaBytes)
+	}
+	return nil
+}
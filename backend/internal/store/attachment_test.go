@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"net/url"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/storage"
 )
 
 // mockStorage implements storage.Storage for testing
@@ -30,8 +32,9 @@ func (m *mockStorage) Upload(ctx context.Context, key string, data io.Reader, co
 		return m.uploadErr
 	}
 	// Read the data
-	m.data, _ = io.ReadAll(data)
-	return nil
+	var err error
+	m.data, err = io.ReadAll(data)
+	return err
 }
 
 func (m *mockStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
@@ -56,6 +59,16 @@ func (m *mockStorage) Exists(ctx context.Context, key string) (bool, error) {
 	return m.exists, nil
 }
 
+func (m *mockStorage) List(ctx context.Context, fn func(storage.ObjectInfo) error) error {
+	return nil
+}
+
+func expectStorageUsage(mock pgxmock.PgxPoolIface, baseID uuid.UUID, used int64) {
+	mock.ExpectQuery("SELECT COUNT\\(a.id\\), COALESCE\\(SUM\\(a.size_bytes\\), 0\\)").
+		WithArgs(baseID).
+		WillReturnRows(pgxmock.NewRows([]string{"count", "used"}).AddRow(int64(3), used))
+}
+
 func TestNewAttachmentStore(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
@@ -267,9 +280,17 @@ func TestAttachmentStore_CreateAttachment(t *testing.T) {
 			WithArgs(baseID, userID).
 			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
 
-		// Insert attachment
+		// Attachment field options
+		mock.ExpectQuery("SELECT f.field_type, f.options").
+			WithArgs(fieldID, recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"field_type", "options"}).AddRow(models.FieldTypeAttachment, []byte(`{}`)))
+
+		// Quota check
+		expectStorageUsage(mock, baseID, 100)
+
+		// Insert attachment, keyed by the content's SHA-256
 		mock.ExpectQuery("INSERT INTO attachments").
-			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WithArgs(recordID, fieldID, "test.txt", "text/plain", int64(12), "blobs/6a/6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72", userID, (*string)(nil)).
 			WillReturnRows(pgxmock.NewRows([]string{
 				"id", "record_id", "field_id", "filename", "content_type", "size_bytes",
 				"storage_key", "thumbnail_key", "width", "height", "created_by", "created_at",
@@ -283,6 +304,56 @@ func TestAttachmentStore_CreateAttachment(t *testing.T) {
 		assert.Equal(t, attachmentID, attachment.ID)
 		assert.Equal(t, "test.txt", attachment.Filename)
 		assert.Contains(t, attachment.URL, attachmentID.String())
+		assert.Equal(t, "test content", string(mockStor.data))
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("queues thumbnails for images", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		baseStore := NewBaseStore(mock)
+		store := NewAttachmentStore(mock, baseStore, nil, nil, &mockStorage{}, "http://localhost:8080")
+		notified := 0
+		store.SetThumbnailNotifier(func() { notified++ })
+
+		recordID := uuid.New()
+		fieldID := uuid.New()
+		userID := uuid.New()
+		baseID := uuid.New()
+
+		mock.ExpectQuery("SELECT t.base_id").
+			WithArgs(recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+
+		mock.ExpectQuery("SELECT f.field_type, f.options").
+			WithArgs(fieldID, recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"field_type", "options"}).AddRow(models.FieldTypeAttachment, []byte(`{"allowed_types":["image/*"]}`)))
+
+		expectStorageUsage(mock, baseID, 0)
+
+		pending := ThumbnailPending
+		mock.ExpectQuery("INSERT INTO attachments").
+			WithArgs(recordID, fieldID, "photo.png", "image/png", int64(12), pgxmock.AnyArg(), userID, &pending).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "record_id", "field_id", "filename", "content_type", "size_bytes",
+				"storage_key", "thumbnail_key", "width", "height", "created_by", "created_at",
+			}).AddRow(
+				uuid.New(), recordID, fieldID, "photo.png", "image/png", int64(12),
+				"storage/key", nil, nil, nil, userID, time.Now().UTC(),
+			))
+
+		// Declared as text, but the content is a PNG
+		png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x00")
+		attachment, err := store.CreateAttachment(ctx, recordID, fieldID, userID, "photo.png", "text/plain", int64(len(png)), bytes.NewReader(png))
+		require.NoError(t, err)
+		assert.Nil(t, attachment.ThumbnailURL)
+		assert.Equal(t, 1, notified)
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -315,6 +386,217 @@ func TestAttachmentStore_CreateAttachment(t *testing.T) {
 	})
 }
 
+func TestAttachmentStore_CreateAttachment_Storage(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, stor *mockStorage) (pgxmock.PgxPoolIface, *AttachmentStore, uuid.UUID, uuid.UUID, uuid.UUID, uuid.UUID) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, stor, "http://localhost")
+
+		recordID, fieldID, userID, baseID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+		mock.ExpectQuery("SELECT t.base_id").
+			WithArgs(recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		mock.ExpectQuery("SELECT f.field_type, f.options").
+			WithArgs(fieldID, recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"field_type", "options"}).AddRow(models.FieldTypeAttachment, []byte(`{}`)))
+		return mock, store, recordID, fieldID, userID, baseID
+	}
+
+	expectInsert := func(mock pgxmock.PgxPoolIface, attachmentID, recordID, fieldID, userID uuid.UUID) {
+		mock.ExpectQuery("INSERT INTO attachments").
+			WithArgs(recordID, fieldID, "a.txt", "text/plain", int64(5), pgxmock.AnyArg(), userID, (*string)(nil)).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "record_id", "field_id", "filename", "content_type", "size_bytes",
+				"storage_key", "thumbnail_key", "width", "height", "created_by", "created_at",
+			}).AddRow(
+				attachmentID, recordID, fieldID, "a.txt", "text/plain", int64(5),
+				"blobs/2c/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", nil, nil, nil, userID, time.Now().UTC(),
+			))
+	}
+
+	t.Run("does not upload content that is already stored", func(t *testing.T) {
+		stor := &mockStorage{exists: true}
+		mock, store, recordID, fieldID, userID, baseID := setup(t, stor)
+		expectStorageUsage(mock, baseID, 0)
+		expectInsert(mock, uuid.New(), recordID, fieldID, userID)
+
+		_, err := store.CreateAttachment(ctx, recordID, fieldID, userID, "a.txt", "text/plain", 5, bytes.NewReader([]byte("hello")))
+		require.NoError(t, err)
+		assert.Nil(t, stor.data)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("removes the attachment when the upload fails", func(t *testing.T) {
+		stor := &mockStorage{uploadErr: assert.AnError}
+		mock, store, recordID, fieldID, userID, baseID := setup(t, stor)
+		expectStorageUsage(mock, baseID, 0)
+		attachmentID := uuid.New()
+		expectInsert(mock, attachmentID, recordID, fieldID, userID)
+		mock.ExpectExec("DELETE FROM attachments WHERE id").
+			WithArgs(attachmentID).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		_, err := store.CreateAttachment(ctx, recordID, fieldID, userID, "a.txt", "text/plain", 5, bytes.NewReader([]byte("hello")))
+		assert.ErrorIs(t, err, assert.AnError)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects uploads over the base's quota", func(t *testing.T) {
+		stor := &mockStorage{}
+		mock, store, recordID, fieldID, userID, baseID := setup(t, stor)
+		store.SetQuota(100)
+		expectStorageUsage(mock, baseID, 98)
+
+		_, err := store.CreateAttachment(ctx, recordID, fieldID, userID, "a.txt", "text/plain", 5, bytes.NewReader([]byte("hello")))
+		assert.ErrorIs(t, err, ErrStorageQuotaExceeded)
+		assert.ErrorContains(t, err, "2 of its 100 bytes left")
+		assert.Nil(t, stor.data)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAttachmentStore_GetStorageUsage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reports usage against the quota", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, nil, "http://localhost")
+		store.SetQuota(5000)
+		baseID, userID := uuid.New(), uuid.New()
+
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleViewer))
+		expectStorageUsage(mock, baseID, 1234)
+
+		usage, err := store.GetStorageUsage(ctx, baseID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, baseID, usage.BaseID)
+		assert.Equal(t, int64(3), usage.AttachmentCount)
+		assert.Equal(t, int64(1234), usage.UsedBytes)
+		require.NotNil(t, usage.QuotaBytes)
+		assert.Equal(t, int64(5000), *usage.QuotaBytes)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reports no quota when unlimited", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, nil, "http://localhost")
+		baseID, userID := uuid.New(), uuid.New()
+
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleViewer))
+		expectStorageUsage(mock, baseID, 0)
+
+		usage, err := store.GetStorageUsage(ctx, baseID, userID)
+		require.NoError(t, err)
+		assert.Nil(t, usage.QuotaBytes)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns ErrNotFound without access", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, nil, "http://localhost")
+		baseID, userID := uuid.New(), uuid.New()
+
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnError(pgx.ErrNoRows)
+
+		_, err = store.GetStorageUsage(ctx, baseID, userID)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAttachmentStore_CreateAttachment_Rules(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, fieldType models.FieldType, options string) (pgxmock.PgxPoolIface, *AttachmentStore, *mockStorage, uuid.UUID, uuid.UUID, uuid.UUID) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+
+		stor := &mockStorage{}
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, stor, "http://localhost")
+
+		recordID, fieldID, userID, baseID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+		mock.ExpectQuery("SELECT t.base_id").
+			WithArgs(recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		mock.ExpectQuery("SELECT f.field_type, f.options").
+			WithArgs(fieldID, recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"field_type", "options"}).AddRow(fieldType, []byte(options)))
+		return mock, store, stor, recordID, fieldID, userID
+	}
+
+	t.Run("rejects files over the field's limit", func(t *testing.T) {
+		mock, store, _, recordID, fieldID, userID := setup(t, models.FieldTypeAttachment, `{"max_size_bytes":10}`)
+
+		_, err := store.CreateAttachment(ctx, recordID, fieldID, userID, "a.txt", "text/plain", 11, bytes.NewReader(make([]byte, 11)))
+		assert.ErrorIs(t, err, ErrFileTooLarge)
+		assert.ErrorContains(t, err, "10 bytes")
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects files that turn out larger than declared", func(t *testing.T) {
+		mock, store, _, recordID, fieldID, userID := setup(t, models.FieldTypeAttachment, `{"max_size_bytes":10}`)
+
+		_, err := store.CreateAttachment(ctx, recordID, fieldID, userID, "a.txt", "text/plain", 5, bytes.NewReader(make([]byte, 2000)))
+		assert.ErrorIs(t, err, ErrFileTooLarge)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects types the field does not allow, whatever the client claims", func(t *testing.T) {
+		mock, store, stor, recordID, fieldID, userID := setup(t, models.FieldTypeAttachment, `{"allowed_types":["image/*","application/pdf"]}`)
+
+		html := []byte("<html><script>alert(1)</script></html>")
+		_, err := store.CreateAttachment(ctx, recordID, fieldID, userID, "photo.png", "image/png", int64(len(html)), bytes.NewReader(html))
+		assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+		assert.ErrorContains(t, err, "text/html")
+		assert.Nil(t, stor.data)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects fields that are not attachment fields", func(t *testing.T) {
+		mock, store, _, recordID, fieldID, userID := setup(t, models.FieldTypeText, `{}`)
+
+		_, err := store.CreateAttachment(ctx, recordID, fieldID, userID, "a.txt", "text/plain", 1, bytes.NewReader([]byte("a")))
+		assert.ErrorIs(t, err, ErrNotAttachmentField)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestAttachmentStore_DeleteAttachment(t *testing.T) {
 	ctx := context.Background()
 
@@ -475,3 +757,220 @@ func TestAttachmentStore_DownloadAttachment(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestAttachmentStore_DownloadThumbnail(t *testing.T) {
+	ctx := context.Background()
+
+	expectAttachment := func(mock pgxmock.PgxPoolIface, attachmentID, userID uuid.UUID, thumbnailKey *string) {
+		recordID := uuid.New()
+		baseID := uuid.New()
+		mock.ExpectQuery("SELECT id, record_id, field_id, filename, content_type, size_bytes").
+			WithArgs(attachmentID).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "record_id", "field_id", "filename", "content_type", "size_bytes",
+				"storage_key", "thumbnail_key", "width", "height", "created_by", "created_at",
+			}).AddRow(
+				attachmentID, recordID, uuid.New(), "photo.png", "image/png", int64(12),
+				"storage/key.png", thumbnailKey, nil, nil, userID, time.Now().UTC(),
+			))
+		mock.ExpectQuery("SELECT t.base_id").
+			WithArgs(recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleViewer))
+	}
+
+	t.Run("downloads a generated thumbnail", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, &mockStorage{data: []byte("jpeg")}, "http://localhost")
+
+		attachmentID := uuid.New()
+		userID := uuid.New()
+		thumbnailKey := "storage/key_thumb"
+		expectAttachment(mock, attachmentID, userID, &thumbnailKey)
+
+		reader, attachment, err := store.DownloadThumbnail(ctx, attachmentID, userID, "small")
+		require.NoError(t, err)
+		defer reader.Close()
+		require.NotNil(t, attachment.ThumbnailURL)
+		assert.Equal(t, "http://localhost/api/v1/attachments/"+attachmentID.String()+"/thumbnail", *attachment.ThumbnailURL)
+		assert.Equal(t, "storage/key_thumb_small.jpg", attachment.ThumbnailStorageKey("small"))
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns ErrNotFound before thumbnails exist", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, &mockStorage{}, "http://localhost")
+
+		attachmentID := uuid.New()
+		userID := uuid.New()
+		expectAttachment(mock, attachmentID, userID, nil)
+
+		reader, attachment, err := store.DownloadThumbnail(ctx, attachmentID, userID, "small")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Nil(t, reader)
+		assert.Nil(t, attachment)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAttachmentStore_ListPendingThumbnails(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	store := NewAttachmentStore(mock, nil, nil, nil, nil, "http://localhost")
+
+	attachmentID := uuid.New()
+	mock.ExpectQuery("SELECT id, record_id, field_id(.+)WHERE thumbnail_status = \\$1").
+		WithArgs(ThumbnailPending, 10).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "record_id", "field_id", "filename", "content_type", "size_bytes",
+			"storage_key", "thumbnail_key", "width", "height", "created_by", "created_at",
+		}).AddRow(
+			attachmentID, uuid.New(), uuid.New(), "photo.gif", "image/gif", int64(12),
+			"storage/key.gif", nil, nil, nil, uuid.New(), time.Now().UTC(),
+		))
+
+	attachments, err := store.ListPendingThumbnails(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	assert.Equal(t, attachmentID, attachments[0].ID)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttachmentStore_SetImageInfo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("records thumbnails and dimensions", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAttachmentStore(mock, nil, nil, nil, nil, "http://localhost")
+
+		attachmentID := uuid.New()
+		thumbnailKey := "storage/key_thumb"
+		width, height := 640, 480
+		mock.ExpectExec("UPDATE attachments").
+			WithArgs(attachmentID, ThumbnailReady, &thumbnailKey, &width, &height).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		err = store.SetImageInfo(ctx, attachmentID, ThumbnailReady, &thumbnailKey, &width, &height)
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns ErrNotFound for a deleted attachment", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAttachmentStore(mock, nil, nil, nil, nil, "http://localhost")
+
+		attachmentID := uuid.New()
+		mock.ExpectExec("UPDATE attachments").
+			WithArgs(attachmentID, ThumbnailFailed, (*string)(nil), (*int)(nil), (*int)(nil)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		err = store.SetImageInfo(ctx, attachmentID, ThumbnailFailed, nil, nil, nil)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAttachmentStore_SignedURLs(t *testing.T) {
+	ctx := context.Background()
+
+	expectAttachment := func(mock pgxmock.PgxPoolIface, attachmentID uuid.UUID) {
+		thumbnailKey := "storage/key_thumb"
+		mock.ExpectQuery("SELECT id, record_id, field_id, filename, content_type, size_bytes").
+			WithArgs(attachmentID).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "record_id", "field_id", "filename", "content_type", "size_bytes",
+				"storage_key", "thumbnail_key", "width", "height", "created_by", "created_at",
+			}).AddRow(
+				attachmentID, uuid.New(), uuid.New(), "photo.png", "image/png", int64(4),
+				"storage/key.png", &thumbnailKey, nil, nil, uuid.New(), time.Now().UTC(),
+			))
+	}
+
+	newStore := func(t *testing.T) (pgxmock.PgxPoolIface, *AttachmentStore) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, &mockStorage{data: []byte("file")}, "http://localhost")
+		store.SetURLSigner(storage.NewURLSigner([]byte("test-key")))
+		return mock, store
+	}
+
+	t.Run("signed URLs download without a user", func(t *testing.T) {
+		mock, store := newStore(t)
+
+		a := &models.Attachment{ID: uuid.New(), ThumbnailKey: new(string)}
+		store.setURLs(a)
+		downloadURL, err := url.Parse(a.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/attachments/"+a.ID.String()+"/download", downloadURL.Path)
+		thumbnailURL, err := url.Parse(*a.ThumbnailURL)
+		require.NoError(t, err)
+
+		// No access checks are made for signed URLs
+		expectAttachment(mock, a.ID)
+		reader, attachment, err := store.DownloadSignedAttachment(ctx, a.ID, downloadURL.Query())
+		require.NoError(t, err)
+		reader.Close()
+		assert.Equal(t, "photo.png", attachment.Filename)
+
+		expectAttachment(mock, a.ID)
+		reader, err = store.DownloadSignedThumbnail(ctx, a.ID, "small", thumbnailURL.Query())
+		require.NoError(t, err)
+		reader.Close()
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("signatures are bound to the attachment and the download", func(t *testing.T) {
+		mock, store := newStore(t)
+
+		a := &models.Attachment{ID: uuid.New(), ThumbnailKey: new(string)}
+		store.setURLs(a)
+		downloadURL, err := url.Parse(a.URL)
+		require.NoError(t, err)
+
+		_, _, err = store.DownloadSignedAttachment(ctx, uuid.New(), downloadURL.Query())
+		assert.ErrorIs(t, err, storage.ErrInvalidSignature)
+
+		_, err = store.DownloadSignedThumbnail(ctx, a.ID, "small", downloadURL.Query())
+		assert.ErrorIs(t, err, storage.ErrInvalidSignature)
+
+		_, err = store.DownloadSignedFile(ctx, "storage/key.png", downloadURL.Query())
+		assert.ErrorIs(t, err, storage.ErrInvalidSignature)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects signed URLs when no signer is set", func(t *testing.T) {
+		store := NewAttachmentStore(nil, nil, nil, nil, &mockStorage{}, "http://localhost")
+
+		a := &models.Attachment{ID: uuid.New()}
+		store.setURLs(a)
+		assert.Equal(t, "http://localhost/api/v1/attachments/"+a.ID.String()+"/download", a.URL)
+
+		_, _, err := store.DownloadSignedAttachment(ctx, a.ID, url.Values{"signature": {"x"}, "expires": {"1"}})
+		assert.ErrorIs(t, err, storage.ErrInvalidSignature)
+	})
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/vibetable/backend/internal/models"
+)
+
+// DefaultMaxAttachmentSize applies to attachment fields without a max_size_bytes option
+const DefaultMaxAttachmentSize int64 = 10 << 20
+
+// maxAttachmentSize returns the largest file an attachment field accepts
+func maxAttachmentSize(options *models.FieldOptions) int64 {
+	if options.MaxSizeBytes != nil && *options.MaxSizeBytes > 0 {
+		return *options.MaxSizeBytes
+	}
+	return DefaultMaxAttachmentSize
+}
+
+// sniffLen is how much of a file is read to detect its type
+const sniffLen = 512
+
+// narrowable lists the more specific types a generic sniffed type may be narrowed to
+// using the type the client declared or the file extension. Anything else the client
+// claims is ignored.
+var narrowable = map[string][]string{
+	"text/plain": {
+		"text/csv", "text/markdown", "text/tab-separated-values", "text/calendar",
+		"text/vcard", "application/json",
+	},
+	"application/zip": {
+		"application/vnd.openxmlformats-officedocument.*", "application/vnd.oasis.opendocument.*",
+		"application/epub+zip", "application/java-archive",
+	},
+	"application/x-ole-storage": {
+		"application/msword", "application/vnd.ms-excel", "application/vnd.ms-powerpoint",
+	},
+}
+
+// sniffContentType works out a file's type from its first bytes, using the declared
+// type and file extension only to narrow a generic result
+func sniffContentType(head []byte, filename, declared string) string {
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if magic := sniffMagic(head); magic != "" && (detected == "application/octet-stream" || detected == "text/xml" || detected == "text/plain") {
+		detected = magic
+	}
+
+	candidates := []string{declared, mime.TypeByExtension(filepath.Ext(filename))}
+	for _, candidate := range candidates {
+		candidate, _, err := mime.ParseMediaType(candidate)
+		if err == nil && matchesContentType(candidate, narrowable[detected]) {
+			return candidate
+		}
+	}
+	return detected
+}
+
+// sniffMagic recognizes formats http.DetectContentType does not
+func sniffMagic(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return "image/tiff"
+	case bytes.HasPrefix(head, []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1")):
+		return "application/x-ole-storage"
+	case bytes.HasPrefix(head, []byte("7z\xBC\xAF\x27\x1C")):
+		return "application/x-7z-compressed"
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		switch string(head[8:12]) {
+		case "heic", "heix", "mif1":
+			return "image/heic"
+		case "avif":
+			return "image/avif"
+		}
+	case isSVG(head):
+		return "image/svg+xml"
+	}
+	return ""
+}
+
+// isSVG reports whether an XML or text document's root element is <svg>
+func isSVG(head []byte) bool {
+	text := bytes.ToLower(bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF")))
+	i := bytes.Index(text, []byte("<svg"))
+	if i < 0 {
+		return false
+	}
+	// Only a prolog, doctype and comments may come before the root element
+	for _, tag := range bytes.SplitAfter(text[:i], []byte(">")) {
+		tag = bytes.TrimSpace(tag)
+		if len(tag) > 0 && !bytes.HasPrefix(tag, []byte("<?")) && !bytes.HasPrefix(tag, []byte("<!")) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesContentType reports whether a media type matches any of the patterns. A
+// pattern ending in "*" matches every type starting with the text before it, so
+// "image/*" matches all images.
+func matchesContentType(contentType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(contentType, prefix) {
+				return true
+			}
+		} else if contentType == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniffContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     string
+		filename string
+		declared string
+		want     string
+	}{
+		{"png declared as something else", "\x89PNG\r\n\x1a\n\x00\x00\x00\x00", "x.pdf", "application/pdf", "image/png"},
+		{"pdf", "%PDF-1.7\n", "x.pdf", "application/pdf", "application/pdf"},
+		{"html claiming to be an image", "<!DOCTYPE html><html></html>", "x.png", "image/png", "text/html"},
+		{"svg", `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`, "x.svg", "image/svg+xml", "image/svg+xml"},
+		{"svg without a prolog", `<svg xmlns="http://www.w3.org/2000/svg"></svg>`, "x.svg", "", "image/svg+xml"},
+		{"xml mentioning svg later", `<?xml version="1.0"?><doc><svg/></doc>`, "x.xml", "", "text/xml"},
+		{"csv narrowed from text", "a,b\n1,2\n", "x.csv", "text/csv", "text/csv"},
+		{"json narrowed by extension", `{"a": 1}`, "x.json", "", "application/json"},
+		{"text cannot be narrowed to an image", "hello", "x.png", "image/png", "text/plain"},
+		{"docx narrowed from zip", "PK\x03\x04rest", "x.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"zip stays zip", "PK\x03\x04rest", "x.zip", "application/x-zip-compressed", "application/zip"},
+		{"legacy office document", "\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1rest", "x.doc", "application/msword", "application/msword"},
+		{"tiff", "II*\x00rest", "x.tif", "", "image/tiff"},
+		{"heic", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", "x.heic", "", "image/heic"},
+		{"unknown binary", "\x00\x01\x02\x03", "x.png", "image/png", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sniffContentType([]byte(tt.head), tt.filename, tt.declared))
+		})
+	}
+}
+
+func TestMatchesContentType(t *testing.T) {
+	assert.True(t, matchesContentType("image/png", []string{"image/*"}))
+	assert.True(t, matchesContentType("application/pdf", []string{"image/*", " Application/PDF "}))
+	assert.False(t, matchesContentType("image/svg+xml", []string{"image/png"}))
+	assert.False(t, matchesContentType("text/html", []string{"image/*"}))
+	assert.False(t, matchesContentType("image/png", nil))
+}
@@ -0,0 +1,402 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/realtime"
+	"github.com/vibetable/backend/internal/storage"
+)
+
+// Resumable upload settings
+const (
+	// MaxUploadChunkSize is the most one chunk of a resumable upload may carry
+	MaxUploadChunkSize = 16 << 20
+
+	// UploadSessionTimeout is how long an upload may sit idle before it is abandoned
+	UploadSessionTimeout = 24 * time.Hour
+)
+
+// Resumable upload errors
+var (
+	ErrUploadOffsetMismatch = errors.New("chunk does not start where the upload left off")
+	ErrUploadIncomplete     = errors.New("upload is not complete")
+	ErrUploadCompleting     = errors.New("upload is already being completed")
+)
+
+// SetHub sets the realtime hub upload progress is reported through
+func (s *AttachmentStore) SetHub(hub *realtime.Hub) {
+	s.hub = hub
+}
+
+const uploadSessionColumns = `
+	s.id, s.record_id, s.field_id, r.table_id, t.base_id, s.filename, s.content_type,
+	s.size_bytes, s.received_bytes, s.completing, s.created_by, s.created_at, s.updated_at`
+
+const uploadSessionJoins = `
+	JOIN records r ON r.id = s.record_id
+	JOIN tables t ON t.id = r.table_id`
+
+func scanUploadSession(row pgx.Row) (*models.UploadSession, bool, error) {
+	var u models.UploadSession
+	var completing bool
+	err := row.Scan(
+		&u.ID, &u.RecordID, &u.FieldID, &u.TableID, &u.BaseID, &u.Filename, &u.ContentType,
+		&u.SizeBytes, &u.ReceivedBytes, &completing, &u.CreatedBy, &u.CreatedAt, &u.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, ErrNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	u.MaxChunkBytes = MaxUploadChunkSize
+	u.ExpiresAt = u.UpdatedAt.Add(UploadSessionTimeout)
+	return &u, completing, nil
+}
+
+// CreateUpload starts a resumable upload to a record's attachment field. The declared
+// size is checked against the field's limit and the base's quota up front; the
+// content type is checked once the upload is complete.
+func (s *AttachmentStore) CreateUpload(ctx context.Context, recordID, fieldID, userID uuid.UUID, req *models.CreateUploadRequest) (*models.UploadSession, error) {
+	baseID, err := s.getBaseIDForRecord(ctx, recordID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.baseStore.GetUserRole(ctx, baseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanEdit() {
+		return nil, ErrForbidden
+	}
+
+	options, err := s.getAttachmentFieldOptions(ctx, recordID, fieldID)
+	if err != nil {
+		return nil, err
+	}
+	maxSize := maxAttachmentSize(options)
+	if req.SizeBytes > maxSize {
+		return nil, fmt.Errorf("%w: the limit for this field is %d bytes", ErrFileTooLarge, maxSize)
+	}
+	if err := s.checkQuota(ctx, baseID, req.SizeBytes); err != nil {
+		return nil, err
+	}
+
+	session, _, err := scanUploadSession(s.db.QueryRow(ctx, `
+		WITH s AS (
+			INSERT INTO upload_sessions (record_id, field_id, filename, content_type, size_bytes, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING *
+		)
+		SELECT `+uploadSessionColumns+` FROM s`+uploadSessionJoins,
+		recordID, fieldID, req.Filename, req.ContentType, req.SizeBytes, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	s.announceUpload(session, realtime.UploadStatusUploading, nil)
+	return session, nil
+}
+
+// GetUpload returns one of the user's resumable uploads, so it can be resumed from
+// its received bytes
+func (s *AttachmentStore) GetUpload(ctx context.Context, uploadID, userID uuid.UUID) (*models.UploadSession, error) {
+	session, _, err := scanUploadSession(s.db.QueryRow(ctx, `
+		SELECT `+uploadSessionColumns+` FROM upload_sessions s`+uploadSessionJoins+`
+		WHERE s.id = $1 AND s.created_by = $2
+	`, uploadID, userID))
+	return session, err
+}
+
+// WriteUploadChunk stores the next chunk of a resumable upload. The chunk must start
+// at the upload's received bytes; otherwise ErrUploadOffsetMismatch is returned with
+// the upload so the client can carry on from the right place.
+func (s *AttachmentStore) WriteUploadChunk(ctx context.Context, uploadID, userID uuid.UUID, offset int64, data io.Reader) (*models.UploadSession, error) {
+	// The chunk is read before the upload is locked, as clients can be slow
+	chunk, err := io.ReadAll(io.LimitReader(data, MaxUploadChunkSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(chunk) > MaxUploadChunkSize {
+		return nil, fmt.Errorf("%w: chunks may be at most %d bytes", ErrFileTooLarge, MaxUploadChunkSize)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	session, completing, err := scanUploadSession(tx.QueryRow(ctx, `
+		SELECT `+uploadSessionColumns+` FROM upload_sessions s`+uploadSessionJoins+`
+		WHERE s.id = $1 AND s.created_by = $2
+		FOR UPDATE OF s
+	`, uploadID, userID))
+	if err != nil {
+		return nil, err
+	}
+	if completing {
+		return nil, ErrUploadCompleting
+	}
+	if offset != session.ReceivedBytes {
+		return session, fmt.Errorf("%w: the next chunk starts at %d", ErrUploadOffsetMismatch, session.ReceivedBytes)
+	}
+	if int64(len(chunk)) > session.SizeBytes-session.ReceivedBytes {
+		return nil, fmt.Errorf("%w: the upload was declared as %d bytes", ErrFileTooLarge, session.SizeBytes)
+	}
+	if len(chunk) == 0 {
+		return session, nil
+	}
+
+	key := uploadPartKey(uploadID, offset)
+	if err := s.storage.Upload(ctx, key, bytes.NewReader(chunk), "application/octet-stream"); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO upload_parts (upload_id, offset_bytes, size_bytes, storage_key)
+		VALUES ($1, $2, $3, $4)
+	`, uploadID, offset, len(chunk), key)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, `
+		UPDATE upload_sessions
+		SET received_bytes = received_bytes + $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING received_bytes, updated_at
+	`, uploadID, len(chunk)).Scan(&session.ReceivedBytes, &session.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	session.ExpiresAt = session.UpdatedAt.Add(UploadSessionTimeout)
+
+	s.announceUpload(session, realtime.UploadStatusUploading, nil)
+	return session, nil
+}
+
+// CompleteUpload assembles a fully received upload into an attachment, applying the
+// same rules as a direct upload. If that fails the upload is left as it was, so it
+// can be retried or cancelled.
+func (s *AttachmentStore) CompleteUpload(ctx context.Context, uploadID, userID uuid.UUID) (*models.Attachment, error) {
+	session, _, err := scanUploadSession(s.db.QueryRow(ctx, `
+		WITH s AS (
+			UPDATE upload_sessions
+			SET completing = TRUE, updated_at = NOW()
+			WHERE id = $1 AND created_by = $2 AND NOT completing AND received_bytes = size_bytes
+			RETURNING *
+		)
+		SELECT `+uploadSessionColumns+` FROM s`+uploadSessionJoins,
+		uploadID, userID))
+	if errors.Is(err, ErrNotFound) {
+		return nil, s.uploadNotCompletable(ctx, uploadID, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := s.listUploadParts(ctx, uploadID)
+	if err == nil {
+		parts := &partsReader{ctx: ctx, storage: s.storage, keys: keys}
+		var a *models.Attachment
+		a, err = s.CreateAttachment(ctx, session.RecordID, session.FieldID, userID, session.Filename, session.ContentType, session.SizeBytes, parts)
+		parts.Close()
+		if err == nil {
+			s.deleteUpload(ctx, uploadID, keys)
+			s.announceUpload(session, realtime.UploadStatusComplete, &a.ID)
+			return a, nil
+		}
+	}
+
+	_, _ = s.db.Exec(ctx, `UPDATE upload_sessions SET completing = FALSE WHERE id = $1`, uploadID)
+	return nil, err
+}
+
+// uploadNotCompletable works out why an upload could not be completed
+func (s *AttachmentStore) uploadNotCompletable(ctx context.Context, uploadID, userID uuid.UUID) error {
+	session, completing, err := scanUploadSession(s.db.QueryRow(ctx, `
+		SELECT `+uploadSessionColumns+` FROM upload_sessions s`+uploadSessionJoins+`
+		WHERE s.id = $1 AND s.created_by = $2
+	`, uploadID, userID))
+	switch {
+	case err != nil:
+		return err
+	case completing:
+		return ErrUploadCompleting
+	default:
+		return fmt.Errorf("%w: %d of %d bytes received", ErrUploadIncomplete, session.ReceivedBytes, session.SizeBytes)
+	}
+}
+
+// CancelUpload abandons a resumable upload and removes what was received
+func (s *AttachmentStore) CancelUpload(ctx context.Context, uploadID, userID uuid.UUID) error {
+	session, err := s.GetUpload(ctx, uploadID, userID)
+	if err != nil {
+		return err
+	}
+	keys, err := s.listUploadParts(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(ctx, `DELETE FROM upload_sessions WHERE id = $1 AND NOT completing`, uploadID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUploadCompleting
+	}
+	for _, key := range keys {
+		_ = s.storage.Delete(ctx, key)
+	}
+
+	s.announceUpload(session, realtime.UploadStatusCancelled, nil)
+	return nil
+}
+
+// DeleteExpiredUploads removes uploads idle since before cutoff, returning how many
+// were removed and the storage keys of their parts for the caller to delete
+func (s *AttachmentStore) DeleteExpiredUploads(ctx context.Context, cutoff time.Time) (int, []string, error) {
+	rows, err := s.db.Query(ctx, `
+		WITH expired AS (
+			DELETE FROM upload_sessions WHERE updated_at < $1 RETURNING id
+		)
+		SELECT e.id, p.storage_key
+		FROM expired e
+		LEFT JOIN upload_parts p ON p.upload_id = e.id
+	`, cutoff)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	sessions := map[uuid.UUID]bool{}
+	var keys []string
+	for rows.Next() {
+		var id uuid.UUID
+		var key *string
+		if err := rows.Scan(&id, &key); err != nil {
+			return 0, nil, err
+		}
+		sessions[id] = true
+		if key != nil {
+			keys = append(keys, *key)
+		}
+	}
+	return len(sessions), keys, rows.Err()
+}
+
+func (s *AttachmentStore) listUploadParts(ctx context.Context, uploadID uuid.UUID) ([]string, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT storage_key FROM upload_parts
+		WHERE upload_id = $1
+		ORDER BY offset_bytes
+	`, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// deleteUpload removes a finished upload. Parts that cannot be deleted are left for
+// the garbage collector.
+func (s *AttachmentStore) deleteUpload(ctx context.Context, uploadID uuid.UUID, keys []string) {
+	if _, err := s.db.Exec(ctx, `DELETE FROM upload_sessions WHERE id = $1`, uploadID); err != nil {
+		return
+	}
+	for _, key := range keys {
+		_ = s.storage.Delete(ctx, key)
+	}
+}
+
+// announceUpload reports an upload's progress to the uploader's connections. Only
+// completion is announced to the whole base, since that is when the attachment appears.
+func (s *AttachmentStore) announceUpload(session *models.UploadSession, status string, attachmentID *uuid.UUID) {
+	if s.hub == nil {
+		return
+	}
+	msg := realtime.NewMessage(realtime.MsgTypeUploadProgress, session.BaseID, session.CreatedBy).
+		WithTable(session.TableID).
+		WithRecord(session.RecordID).
+		WithField(session.FieldID).
+		WithPayload(realtime.UploadProgress{
+			UploadID:      session.ID,
+			Filename:      session.Filename,
+			ReceivedBytes: session.ReceivedBytes,
+			SizeBytes:     session.SizeBytes,
+			Status:        status,
+			AttachmentID:  attachmentID,
+		})
+	if status == realtime.UploadStatusComplete {
+		s.hub.Announce(msg)
+		return
+	}
+	s.hub.AnnounceToUser(msg)
+}
+
+// uploadPartKey returns the temporary storage key of the chunk at offset
+func uploadPartKey(uploadID uuid.UUID, offset int64) string {
+	return fmt.Sprintf("uploads/%s/%020d", uploadID, offset)
+}
+
+// partsReader reads an upload's parts from storage one after another
+type partsReader struct {
+	ctx     context.Context
+	storage storage.Storage
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			part, err := r.storage.Download(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			r.current = part
+			r.keys = r.keys[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
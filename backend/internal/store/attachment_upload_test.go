@@ -0,0 +1,285 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+)
+
+func uploadSessionRows(u *models.UploadSession, completing bool) *pgxmock.Rows {
+	return pgxmock.NewRows([]string{
+		"id", "record_id", "field_id", "table_id", "base_id", "filename", "content_type",
+		"size_bytes", "received_bytes", "completing", "created_by", "created_at", "updated_at",
+	}).AddRow(
+		u.ID, u.RecordID, u.FieldID, u.TableID, u.BaseID, u.Filename, u.ContentType,
+		u.SizeBytes, u.ReceivedBytes, completing, u.CreatedBy, u.CreatedAt, u.UpdatedAt,
+	)
+}
+
+func newTestUpload(size, received int64) *models.UploadSession {
+	now := time.Now().UTC()
+	return &models.UploadSession{
+		ID:            uuid.New(),
+		RecordID:      uuid.New(),
+		FieldID:       uuid.New(),
+		TableID:       uuid.New(),
+		BaseID:        uuid.New(),
+		Filename:      "video.mp4",
+		ContentType:   "video/mp4",
+		SizeBytes:     size,
+		ReceivedBytes: received,
+		CreatedBy:     uuid.New(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+func TestAttachmentStore_CreateUpload(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, options string) (pgxmock.PgxPoolIface, *AttachmentStore, *models.UploadSession) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, &mockStorage{}, "http://localhost")
+		u := newTestUpload(500<<20, 0)
+		mock.ExpectQuery("SELECT t.base_id").
+			WithArgs(u.RecordID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(u.BaseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(u.BaseID, u.CreatedBy).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		mock.ExpectQuery("SELECT f.field_type, f.options").
+			WithArgs(u.FieldID, u.RecordID).
+			WillReturnRows(pgxmock.NewRows([]string{"field_type", "options"}).AddRow(models.FieldTypeAttachment, []byte(options)))
+		return mock, store, u
+	}
+
+	t.Run("creates a session for a file within the field's limit", func(t *testing.T) {
+		mock, store, u := setup(t, `{"max_size_bytes": 1073741824}`)
+		expectStorageUsage(mock, u.BaseID, 0)
+		mock.ExpectQuery("INSERT INTO upload_sessions").
+			WithArgs(u.RecordID, u.FieldID, u.Filename, u.ContentType, u.SizeBytes, u.CreatedBy).
+			WillReturnRows(uploadSessionRows(u, false))
+
+		session, err := store.CreateUpload(ctx, u.RecordID, u.FieldID, u.CreatedBy, &models.CreateUploadRequest{
+			Filename:    u.Filename,
+			ContentType: u.ContentType,
+			SizeBytes:   u.SizeBytes,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, u.ID, session.ID)
+		assert.Equal(t, u.TableID, session.TableID)
+		assert.Equal(t, int64(MaxUploadChunkSize), session.MaxChunkBytes)
+		assert.Equal(t, u.UpdatedAt.Add(UploadSessionTimeout), session.ExpiresAt)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects files over the field's limit before anything is sent", func(t *testing.T) {
+		mock, store, u := setup(t, `{}`)
+
+		_, err := store.CreateUpload(ctx, u.RecordID, u.FieldID, u.CreatedBy, &models.CreateUploadRequest{
+			Filename:  u.Filename,
+			SizeBytes: DefaultMaxAttachmentSize + 1,
+		})
+		assert.ErrorIs(t, err, ErrFileTooLarge)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects files over the base's quota", func(t *testing.T) {
+		mock, store, u := setup(t, `{"max_size_bytes": 1073741824}`)
+		store.SetQuota(100 << 20)
+		expectStorageUsage(mock, u.BaseID, 0)
+
+		_, err := store.CreateUpload(ctx, u.RecordID, u.FieldID, u.CreatedBy, &models.CreateUploadRequest{
+			Filename:  u.Filename,
+			SizeBytes: u.SizeBytes,
+		})
+		assert.ErrorIs(t, err, ErrStorageQuotaExceeded)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAttachmentStore_WriteUploadChunk(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (pgxmock.PgxPoolIface, *AttachmentStore, *mockStorage) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+
+		stor := &mockStorage{}
+		return mock, NewAttachmentStore(mock, nil, nil, nil, stor, "http://localhost"), stor
+	}
+
+	t.Run("stores the chunk as a part and advances the offset", func(t *testing.T) {
+		mock, store, stor := setup(t)
+		u := newTestUpload(10, 4)
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE OF s").
+			WithArgs(u.ID, u.CreatedBy).
+			WillReturnRows(uploadSessionRows(u, false))
+		mock.ExpectExec("INSERT INTO upload_parts").
+			WithArgs(u.ID, int64(4), 3, uploadPartKey(u.ID, 4)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectQuery("UPDATE upload_sessions").
+			WithArgs(u.ID, 3).
+			WillReturnRows(pgxmock.NewRows([]string{"received_bytes", "updated_at"}).AddRow(int64(7), time.Now().UTC()))
+		mock.ExpectCommit()
+
+		session, err := store.WriteUploadChunk(ctx, u.ID, u.CreatedBy, 4, bytes.NewReader([]byte("abc")))
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), session.ReceivedBytes)
+		assert.Equal(t, []byte("abc"), stor.data)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects chunks that do not start at the received bytes", func(t *testing.T) {
+		mock, store, stor := setup(t)
+		u := newTestUpload(10, 4)
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE OF s").
+			WithArgs(u.ID, u.CreatedBy).
+			WillReturnRows(uploadSessionRows(u, false))
+		mock.ExpectRollback()
+
+		session, err := store.WriteUploadChunk(ctx, u.ID, u.CreatedBy, 0, bytes.NewReader([]byte("abc")))
+		assert.ErrorIs(t, err, ErrUploadOffsetMismatch)
+		assert.ErrorContains(t, err, "starts at 4")
+		assert.Equal(t, int64(4), session.ReceivedBytes)
+		assert.Nil(t, stor.data)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects chunks past the declared size", func(t *testing.T) {
+		mock, store, _ := setup(t)
+		u := newTestUpload(5, 4)
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE OF s").
+			WithArgs(u.ID, u.CreatedBy).
+			WillReturnRows(uploadSessionRows(u, false))
+		mock.ExpectRollback()
+
+		_, err := store.WriteUploadChunk(ctx, u.ID, u.CreatedBy, 4, bytes.NewReader([]byte("abc")))
+		assert.ErrorIs(t, err, ErrFileTooLarge)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects chunks while the upload is being completed", func(t *testing.T) {
+		mock, store, _ := setup(t)
+		u := newTestUpload(10, 10)
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE OF s").
+			WithArgs(u.ID, u.CreatedBy).
+			WillReturnRows(uploadSessionRows(u, true))
+		mock.ExpectRollback()
+
+		_, err := store.WriteUploadChunk(ctx, u.ID, u.CreatedBy, 10, bytes.NewReader(nil))
+		assert.ErrorIs(t, err, ErrUploadCompleting)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAttachmentStore_CompleteUpload(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reports how much of an incomplete upload was received", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAttachmentStore(mock, nil, nil, nil, &mockStorage{}, "http://localhost")
+		u := newTestUpload(10, 4)
+		mock.ExpectQuery("SET completing = TRUE").
+			WithArgs(u.ID, u.CreatedBy).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("FROM upload_sessions s").
+			WithArgs(u.ID, u.CreatedBy).
+			WillReturnRows(uploadSessionRows(u, false))
+
+		_, err = store.CompleteUpload(ctx, u.ID, u.CreatedBy)
+		assert.ErrorIs(t, err, ErrUploadIncomplete)
+		assert.ErrorContains(t, err, "4 of 10 bytes")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reopens the upload when the attachment is rejected", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAttachmentStore(mock, NewBaseStore(mock), nil, nil, &mockStorage{}, "http://localhost")
+		u := newTestUpload(10, 10)
+		mock.ExpectQuery("SET completing = TRUE").
+			WithArgs(u.ID, u.CreatedBy).
+			WillReturnRows(uploadSessionRows(u, true))
+		mock.ExpectQuery("SELECT storage_key FROM upload_parts").
+			WithArgs(u.ID).
+			WillReturnRows(pgxmock.NewRows([]string{"storage_key"}).AddRow(uploadPartKey(u.ID, 0)))
+		mock.ExpectQuery("SELECT t.base_id").
+			WithArgs(u.RecordID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(u.BaseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(u.BaseID, u.CreatedBy).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleViewer))
+		mock.ExpectExec("SET completing = FALSE").
+			WithArgs(u.ID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		_, err = store.CompleteUpload(ctx, u.ID, u.CreatedBy)
+		assert.ErrorIs(t, err, ErrForbidden)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAttachmentStore_CancelUpload(t *testing.T) {
+	ctx := context.Background()
+
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	store := NewAttachmentStore(mock, nil, nil, nil, &mockStorage{}, "http://localhost")
+	u := newTestUpload(10, 4)
+	mock.ExpectQuery("FROM upload_sessions s").
+		WithArgs(u.ID, u.CreatedBy).
+		WillReturnRows(uploadSessionRows(u, false))
+	mock.ExpectQuery("SELECT storage_key FROM upload_parts").
+		WithArgs(u.ID).
+		WillReturnRows(pgxmock.NewRows([]string{"storage_key"}).AddRow(uploadPartKey(u.ID, 0)))
+	mock.ExpectExec("DELETE FROM upload_sessions").
+		WithArgs(u.ID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	require.NoError(t, store.CancelUpload(ctx, u.ID, u.CreatedBy))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// partStorage serves each key's own content
+type partStorage struct {
+	mockStorage
+	parts map[string]string
+}
+
+func (p *partStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader([]byte(p.parts[key]))), nil
+}
+
+func TestPartsReader(t *testing.T) {
+	stor := &partStorage{parts: map[string]string{"a": "hello ", "b": "", "c": "world"}}
+	r := &partsReader{ctx: context.Background(), storage: stor, keys: []string{"a", "b", "c"}}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -31,6 +32,10 @@ func (s *AutomationStore) ListAutomationsForTable(ctx context.Context, tableID u
 	if err != nil {
 		return nil, err
 	}
+	role, err := s.baseStore.GetUserRole(ctx, table.BaseID, userID)
+	if err != nil {
+		return nil, err
+	}
 
 	rows, err := s.db.Query(ctx, `
 		SELECT id, base_id, table_id, name, description, enabled,
@@ -55,6 +60,9 @@ func (s *AutomationStore) ListAutomationsForTable(ctx context.Context, tableID u
 		); err != nil {
 			return nil, err
 		}
+		if !role.CanEdit() {
+			hideTriggerToken(&a)
+		}
 		automations = append(automations, a)
 	}
 
@@ -83,6 +91,9 @@ func (s *AutomationStore) CreateAutomation(ctx context.Context, a *models.Automa
 
 	a.BaseID = table.BaseID
 	a.CreatedBy = userID
+	if a.TriggerType == models.TriggerWebhookReceived {
+		a.TriggerConfig = ensureTriggerToken(a.TriggerConfig, "")
+	}
 
 	err = s.db.QueryRow(ctx, `
 		INSERT INTO automations (base_id, table_id, name, description, enabled,
@@ -107,6 +118,32 @@ func (s *AutomationStore) CreateAutomation(ctx context.Context, a *models.Automa
 
 // GetAutomation returns an automation by ID
 func (s *AutomationStore) GetAutomation(ctx context.Context, automationID uuid.UUID, userID uuid.UUID) (*models.Automation, error) {
+	a, role, err := s.getAutomation(ctx, automationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanEdit() {
+		hideTriggerToken(a)
+	}
+
+	return a, nil
+}
+
+// GetAutomationForEdit returns an automation after verifying the user can edit its base
+func (s *AutomationStore) GetAutomationForEdit(ctx context.Context, automationID uuid.UUID, userID uuid.UUID) (*models.Automation, error) {
+	a, role, err := s.getAutomation(ctx, automationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanEdit() {
+		return nil, ErrForbidden
+	}
+
+	return a, nil
+}
+
+// getAutomation returns an automation along with the user's role in its base
+func (s *AutomationStore) getAutomation(ctx context.Context, automationID uuid.UUID, userID uuid.UUID) (*models.Automation, models.CollaboratorRole, error) {
 	var a models.Automation
 
 	err := s.db.QueryRow(ctx, `
@@ -122,19 +159,19 @@ func (s *AutomationStore) GetAutomation(ctx context.Context, automationID uuid.U
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, ErrNotFound
+		return nil, "", ErrNotFound
 	}
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Verify user has access to this automation's base
-	_, err = s.baseStore.GetUserRole(ctx, a.BaseID, userID)
+	role, err := s.baseStore.GetUserRole(ctx, a.BaseID, userID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return &a, nil
+	return &a, role, nil
 }
 
 // UpdateAutomation updates an automation
@@ -154,6 +191,9 @@ func (s *AutomationStore) UpdateAutomation(ctx context.Context, automationID uui
 		return nil, ErrForbidden
 	}
 
+	// Remember the inbound webhook token so it survives config edits
+	existingToken := triggerToken(a)
+
 	// Apply updates
 	if name, ok := updates["name"].(string); ok {
 		a.Name = name
@@ -176,6 +216,9 @@ func (s *AutomationStore) UpdateAutomation(ctx context.Context, automationID uui
 	if actionConfig, ok := updates["actionConfig"]; ok {
 		a.ActionConfig, _ = actionConfig.([]byte)
 	}
+	if a.TriggerType == models.TriggerWebhookReceived {
+		a.TriggerConfig = ensureTriggerToken(a.TriggerConfig, existingToken)
+	}
 
 	err = s.db.QueryRow(ctx, `
 		UPDATE automations
@@ -289,6 +332,87 @@ func (s *AutomationStore) GetAutomationsByTrigger(ctx context.Context, tableID u
 	return automations, rows.Err()
 }
 
+// GetAutomationByTriggerToken returns the webhook_received automation owning an inbound
+// webhook token (no auth check: the token itself is the credential)
+func (s *AutomationStore) GetAutomationByTriggerToken(ctx context.Context, token string) (*models.Automation, error) {
+	var a models.Automation
+
+	err := s.db.QueryRow(ctx, `
+		SELECT id, base_id, table_id, name, description, enabled,
+			   trigger_type, trigger_config, action_type, action_config,
+			   created_by, last_triggered_at, run_count, created_at, updated_at
+		FROM automations
+		WHERE trigger_type = $1 AND trigger_config->>'token' = $2
+	`, models.TriggerWebhookReceived, token).Scan(
+		&a.ID, &a.BaseID, &a.TableID, &a.Name, &a.Description, &a.Enabled,
+		&a.TriggerType, &a.TriggerConfig, &a.ActionType, &a.ActionConfig,
+		&a.CreatedBy, &a.LastTriggeredAt, &a.RunCount, &a.CreatedAt, &a.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// triggerToken returns the inbound webhook token of an automation, if it has one
+func triggerToken(a *models.Automation) string {
+	if a.TriggerType != models.TriggerWebhookReceived {
+		return ""
+	}
+	var config models.WebhookReceivedConfig
+	if err := json.Unmarshal(a.TriggerConfig, &config); err != nil {
+		return ""
+	}
+	return config.Token
+}
+
+// hideTriggerToken removes the inbound webhook token from an automation shown to a
+// collaborator who cannot edit it, since the token is the only credential for its URL
+func hideTriggerToken(a *models.Automation) {
+	if a.TriggerType == models.TriggerWebhookReceived {
+		a.TriggerConfig = withoutTriggerToken(a.TriggerConfig)
+	}
+}
+
+// withoutTriggerToken removes the inbound webhook token from a trigger config
+func withoutTriggerToken(config json.RawMessage) json.RawMessage {
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(config, &values); err != nil {
+		return config
+	}
+	delete(values, "token")
+	result, err := json.Marshal(values)
+	if err != nil {
+		return config
+	}
+	return result
+}
+
+// ensureTriggerToken sets the server-controlled token on a webhook_received trigger config,
+// keeping existingToken when there is one and generating a new token otherwise
+func ensureTriggerToken(config json.RawMessage, existingToken string) json.RawMessage {
+	values := make(map[string]interface{})
+	if len(config) > 0 {
+		json.Unmarshal(config, &values)
+	}
+
+	if existingToken == "" {
+		existingToken = generateToken()
+	}
+	values["token"] = existingToken
+
+	result, err := json.Marshal(values)
+	if err != nil {
+		return config
+	}
+	return result
+}
+
 // CreateRun creates a new automation run record
 func (s *AutomationStore) CreateRun(ctx context.Context, run *models.AutomationRun) (*models.AutomationRun, error) {
 	err := s.db.QueryRow(ctx, `
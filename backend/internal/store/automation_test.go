@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -67,6 +68,42 @@ func TestAutomationStore_GetAutomation(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("hides the inbound webhook token from viewers", func(t *testing.T) {
+		for _, tt := range []struct {
+			role  models.CollaboratorRole
+			token bool
+		}{{models.RoleViewer, false}, {models.RoleEditor, true}} {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+
+			store := NewAutomationStore(mock, NewBaseStore(mock), nil)
+			automationID, baseID, userID := uuid.New(), uuid.New(), uuid.New()
+			now := time.Now().UTC()
+
+			mock.ExpectQuery("SELECT id, base_id, table_id, name, description, enabled").
+				WithArgs(automationID).
+				WillReturnRows(pgxmock.NewRows([]string{
+					"id", "base_id", "table_id", "name", "description", "enabled",
+					"trigger_type", "trigger_config", "action_type", "action_config",
+					"created_by", "last_triggered_at", "run_count", "created_at", "updated_at",
+				}).AddRow(
+					automationID, baseID, uuid.New(), "Inbound", nil, true,
+					models.TriggerWebhookReceived, json.RawMessage(`{"token":"secret"}`), models.ActionCreateRecord, json.RawMessage(`{}`),
+					userID, nil, 0, now, now,
+				))
+			mock.ExpectQuery("SELECT role FROM base_collaborators").
+				WithArgs(baseID, userID).
+				WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(tt.role))
+
+			automation, err := store.GetAutomation(ctx, automationID, userID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.token, strings.Contains(string(automation.TriggerConfig), "secret"), string(tt.role))
+
+			require.NoError(t, mock.ExpectationsWereMet())
+			mock.Close()
+		}
+	})
+
 	t.Run("returns ErrNotFound when automation not found", func(t *testing.T) {
 		mock, err := pgxmock.NewPool()
 		require.NoError(t, err)
@@ -156,6 +193,9 @@ func TestAutomationStore_ListAutomationsForTable(t *testing.T) {
 		mock.ExpectQuery("SELECT role FROM base_collaborators").
 			WithArgs(baseID, userID).
 			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleOwner))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleOwner))
 
 		// List automations
 		rows := pgxmock.NewRows([]string{
@@ -204,6 +244,9 @@ func TestAutomationStore_ListAutomationsForTable(t *testing.T) {
 		mock.ExpectQuery("SELECT role FROM base_collaborators").
 			WithArgs(baseID, userID).
 			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleOwner))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleOwner))
 
 		mock.ExpectQuery("SELECT id, base_id, table_id, name, description, enabled").
 			WithArgs(tableID).
@@ -318,6 +361,66 @@ func TestAutomationStore_DeleteAutomation(t *testing.T) {
 	})
 }
 
+func TestAutomationStore_GetAutomationForEdit(t *testing.T) {
+	ctx := context.Background()
+
+	automationRows := func(automationID, baseID, tableID, userID uuid.UUID) *pgxmock.Rows {
+		now := time.Now().UTC()
+		return pgxmock.NewRows([]string{
+			"id", "base_id", "table_id", "name", "description", "enabled",
+			"trigger_type", "trigger_config", "action_type", "action_config",
+			"created_by", "last_triggered_at", "run_count", "created_at", "updated_at",
+		}).AddRow(
+			automationID, baseID, tableID, "Test Automation", nil, true,
+			models.TriggerRecordCreated, json.RawMessage(`{}`), models.ActionSendEmail, json.RawMessage(`{}`),
+			userID, nil, 0, now, now,
+		)
+	}
+
+	t.Run("returns automation for editor", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAutomationStore(mock, NewBaseStore(mock), nil)
+		automationID, baseID, tableID, userID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+		mock.ExpectQuery("SELECT id, base_id, table_id, name, description, enabled").
+			WithArgs(automationID).
+			WillReturnRows(automationRows(automationID, baseID, tableID, userID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+
+		automation, err := store.GetAutomationForEdit(ctx, automationID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, automationID, automation.ID)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns ErrForbidden for viewer", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAutomationStore(mock, NewBaseStore(mock), nil)
+		automationID, baseID, tableID, userID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+		mock.ExpectQuery("SELECT id, base_id, table_id, name, description, enabled").
+			WithArgs(automationID).
+			WillReturnRows(automationRows(automationID, baseID, tableID, userID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleViewer))
+
+		_, err = store.GetAutomationForEdit(ctx, automationID, userID)
+		assert.ErrorIs(t, err, ErrForbidden)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestAutomationStore_ToggleAutomation(t *testing.T) {
 	ctx := context.Background()
 
@@ -443,6 +546,83 @@ func TestAutomationStore_GetAutomationsByTrigger(t *testing.T) {
 	})
 }
 
+func TestAutomationStore_GetAutomationByTriggerToken(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns automation for token", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAutomationStore(mock, nil, nil)
+		automationID := uuid.New()
+		now := time.Now().UTC()
+
+		mock.ExpectQuery("SELECT id, base_id, table_id, name, description, enabled").
+			WithArgs(models.TriggerWebhookReceived, "secret-token").
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "base_id", "table_id", "name", "description", "enabled",
+				"trigger_type", "trigger_config", "action_type", "action_config",
+				"created_by", "last_triggered_at", "run_count", "created_at", "updated_at",
+			}).AddRow(
+				automationID, uuid.New(), uuid.New(), "Inbound", nil, true,
+				models.TriggerWebhookReceived, json.RawMessage(`{"token": "secret-token"}`), models.ActionCreateRecord, json.RawMessage(`{}`),
+				uuid.New(), nil, 0, now, now,
+			))
+
+		automation, err := store.GetAutomationByTriggerToken(ctx, "secret-token")
+		require.NoError(t, err)
+		assert.Equal(t, automationID, automation.ID)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns ErrNotFound for unknown token", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewAutomationStore(mock, nil, nil)
+
+		mock.ExpectQuery("SELECT id, base_id, table_id, name, description, enabled").
+			WithArgs(models.TriggerWebhookReceived, "unknown").
+			WillReturnError(pgx.ErrNoRows)
+
+		_, err = store.GetAutomationByTriggerToken(ctx, "unknown")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEnsureTriggerToken(t *testing.T) {
+	t.Run("generates token and keeps other keys", func(t *testing.T) {
+		config := ensureTriggerToken(json.RawMessage(`{"note": "hi", "token": "client-chosen"}`), "")
+
+		var values map[string]string
+		require.NoError(t, json.Unmarshal(config, &values))
+		assert.Equal(t, "hi", values["note"])
+		assert.Len(t, values["token"], 32)
+		assert.NotEqual(t, "client-chosen", values["token"])
+	})
+
+	t.Run("keeps existing token", func(t *testing.T) {
+		config := ensureTriggerToken(json.RawMessage(`{"token": "other"}`), "existing")
+
+		var values map[string]string
+		require.NoError(t, json.Unmarshal(config, &values))
+		assert.Equal(t, "existing", values["token"])
+	})
+
+	t.Run("handles empty config", func(t *testing.T) {
+		config := ensureTriggerToken(nil, "")
+
+		var values map[string]string
+		require.NoError(t, json.Unmarshal(config, &values))
+		assert.NotEmpty(t, values["token"])
+	})
+}
+
 func TestAutomationStore_CreateRun(t *testing.T) {
 	ctx := context.Background()
 
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/realtime"
 )
 
 var (
@@ -16,13 +17,26 @@ var (
 )
 
 type BaseStore struct {
-	db DBTX
+	db           DBTX
+	hub          *realtime.Hub
+	quota        int64
 }
 
 func NewBaseStore(db DBTX) *BaseStore {
 	return &BaseStore{db: db}
 }
 
+// SetHub sets the realtime hub told about access changes
+func (s *BaseStore) SetHub(hub *realtime.Hub) {
+	s.hub = hub
+}
+
+// SetQuota sets how many bytes of attachments each base may store, which limits
+// what a duplicate may copy. Zero means unlimited.
+func (s *BaseStore) SetQuota(bytes int64) {
+	s.quota = bytes
+}
+
 // ListBasesForUser returns all bases the user has access to
 func (s *BaseStore) ListBasesForUser(ctx context.Context, userID uuid.UUID) ([]models.Base, error) {
 	rows, err := s.db.Query(ctx, `
@@ -179,11 +193,16 @@ func (s *BaseStore) DeleteBase(ctx context.Context, baseID uuid.UUID, userID uui
 	if result.RowsAffected() == 0 {
 		return ErrNotFound
 	}
+
+	if s.hub != nil {
+		s.hub.CloseBase(baseID)
+	}
 	return nil
 }
 
-// DuplicateBase duplicates a base with all tables, fields, views, and optionally records
-func (s *BaseStore) DuplicateBase(ctx context.Context, baseID uuid.UUID, userID uuid.UUID, includeRecords bool) (*models.Base, error) {
+// DuplicateBase duplicates a base with all tables, fields, views, and optionally records.
+// progress, if set, is told how many tables have been copied.
+func (s *BaseStore) DuplicateBase(ctx context.Context, baseID uuid.UUID, userID uuid.UUID, includeRecords bool, progress ProgressFunc) (*models.Base, error) {
 	// Check user has access to base
 	role, err := s.GetUserRole(ctx, baseID, userID)
 	if err != nil {
@@ -270,8 +289,13 @@ func (s *BaseStore) DuplicateBase(ctx context.Context, baseID uuid.UUID, userID
 		tableIDMap[origTable.oldID] = newTableID
 	}
 
+	// Map old record and field IDs to new ones (for attachments)
+	recordIDMap := make(map[uuid.UUID]uuid.UUID)
+	allFieldIDMap := make(map[uuid.UUID]uuid.UUID)
+
 	// Now duplicate each table's contents
-	for _, origTable := range originalTables {
+	progress.report(0, len(originalTables))
+	for i, origTable := range originalTables {
 		newTableID := tableIDMap[origTable.oldID]
 		fieldIDMap := make(map[uuid.UUID]uuid.UUID)
 
@@ -393,7 +417,7 @@ func (s *BaseStore) DuplicateBase(ctx context.Context, baseID uuid.UUID, userID
 		// Copy records if requested
 		if includeRecords {
 			recordRows, err := tx.Query(ctx, `
-				SELECT values, position
+				SELECT id, values, position
 				FROM records
 				WHERE table_id = $1
 				ORDER BY position
@@ -402,14 +426,24 @@ func (s *BaseStore) DuplicateBase(ctx context.Context, baseID uuid.UUID, userID
 				return nil, err
 			}
 
+			type recordInfo struct {
+				oldID    uuid.UUID
+				values   json.RawMessage
+				position int
+			}
+			var originalRecords []recordInfo
 			for recordRows.Next() {
-				var values json.RawMessage
-				var position int
-
-				if err := recordRows.Scan(&values, &position); err != nil {
+				var r recordInfo
+				if err := recordRows.Scan(&r.oldID, &r.values, &r.position); err != nil {
 					recordRows.Close()
 					return nil, err
 				}
+				originalRecords = append(originalRecords, r)
+			}
+			recordRows.Close()
+
+			for _, origRecord := range originalRecords {
+				values := origRecord.values
 
 				// Remap field IDs in values
 				var valuesMap map[string]interface{}
@@ -427,16 +461,33 @@ func (s *BaseStore) DuplicateBase(ctx context.Context, baseID uuid.UUID, userID
 					values, _ = json.Marshal(newValuesMap)
 				}
 
-				_, err = tx.Exec(ctx, `
+				var newRecordID uuid.UUID
+				err = tx.QueryRow(ctx, `
 					INSERT INTO records (table_id, values, position)
 					VALUES ($1, $2, $3)
-				`, newTableID, values, position)
+					RETURNING id
+				`, newTableID, values, origRecord.position).Scan(&newRecordID)
 				if err != nil {
-					recordRows.Close()
 					return nil, err
 				}
+				recordIDMap[origRecord.oldID] = newRecordID
 			}
-			recordRows.Close()
+		}
+
+		for oldID, newID := range fieldIDMap {
+			allFieldIDMap[oldID] = newID
+		}
+		progress.report(i+1, len(originalTables))
+	}
+
+	// Attachments share the original's stored files, so nothing is uploaded again,
+	// but the copies still count towards the new base's quota
+	if len(recordIDMap) > 0 {
+		if err := s.checkDuplicateQuota(ctx, tx, recordIDMap, allFieldIDMap); err != nil {
+			return nil, err
+		}
+		if err := copyAttachments(ctx, tx, recordIDMap, allFieldIDMap); err != nil {
+			return nil, err
 		}
 	}
 
@@ -449,6 +500,58 @@ func (s *BaseStore) DuplicateBase(ctx context.Context, baseID uuid.UUID, userID
 	return &newBase, nil
 }
 
+// checkDuplicateQuota returns ErrStorageQuotaExceeded if the attachments of the
+// duplicated records would not fit in the new base's quota
+func (s *BaseStore) checkDuplicateQuota(ctx context.Context, tx pgx.Tx, recordIDMap, fieldIDMap map[uuid.UUID]uuid.UUID) error {
+	if s.quota <= 0 {
+		return nil
+	}
+
+	oldRecordIDs, _ := splitIDMap(recordIDMap)
+	oldFieldIDs, _ := splitIDMap(fieldIDMap)
+	var size int64
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(size_bytes), 0)
+		FROM attachments
+		WHERE record_id = ANY($1) AND field_id = ANY($2)
+	`, oldRecordIDs, oldFieldIDs).Scan(&size)
+	if err != nil {
+		return err
+	}
+
+	if size > s.quota {
+		return fmt.Errorf("%w: the copied attachments take %d bytes and a base may store %d", ErrStorageQuotaExceeded, size, s.quota)
+	}
+	return nil
+}
+
+// copyAttachments copies the attachments of duplicated records onto their copies,
+// pointing at the same stored files
+func copyAttachments(ctx context.Context, tx pgx.Tx, recordIDMap, fieldIDMap map[uuid.UUID]uuid.UUID) error {
+	oldRecordIDs, newRecordIDs := splitIDMap(recordIDMap)
+	oldFieldIDs, newFieldIDs := splitIDMap(fieldIDMap)
+	_, err := tx.Exec(ctx, `
+		INSERT INTO attachments (record_id, field_id, filename, content_type, size_bytes, storage_key,
+			thumbnail_key, thumbnail_status, width, height, created_by, created_at)
+		SELECT r.new_id, f.new_id, a.filename, a.content_type, a.size_bytes, a.storage_key,
+			a.thumbnail_key, a.thumbnail_status, a.width, a.height, a.created_by, a.created_at
+		FROM attachments a
+		JOIN unnest($1::uuid[], $2::uuid[]) AS r(old_id, new_id) ON r.old_id = a.record_id
+		JOIN unnest($3::uuid[], $4::uuid[]) AS f(old_id, new_id) ON f.old_id = a.field_id
+	`, oldRecordIDs, newRecordIDs, oldFieldIDs, newFieldIDs)
+	return err
+}
+
+func splitIDMap(ids map[uuid.UUID]uuid.UUID) ([]uuid.UUID, []uuid.UUID) {
+	oldIDs := make([]uuid.UUID, 0, len(ids))
+	newIDs := make([]uuid.UUID, 0, len(ids))
+	for oldID, newID := range ids {
+		oldIDs = append(oldIDs, oldID)
+		newIDs = append(newIDs, newID)
+	}
+	return oldIDs, newIDs
+}
+
 // --- Collaborator operations ---
 
 // ListCollaborators returns all collaborators for a base
@@ -573,6 +676,9 @@ func (s *BaseStore) UpdateCollaboratorRole(ctx context.Context, baseID uuid.UUID
 		return nil, err
 	}
 
+	if s.hub != nil {
+		s.hub.ChangeRole(baseID, targetUserID, string(collab.Role))
+	}
 	return &collab, nil
 }
 
@@ -606,5 +712,9 @@ func (s *BaseStore) RemoveCollaborator(ctx context.Context, baseID uuid.UUID, ta
 	if result.RowsAffected() == 0 {
 		return ErrNotFound
 	}
+
+	if s.hub != nil {
+		s.hub.RevokeAccess(baseID, targetUserID)
+	}
 	return nil
 }
@@ -0,0 +1,847 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vibetable/backend/internal/archive"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/storage"
+)
+
+// ArchiveStore exports bases to portable archives and imports them again
+type ArchiveStore struct {
+	db          DBTX
+	baseStore   *BaseStore
+	attachments *AttachmentStore
+}
+
+func NewArchiveStore(db DBTX, baseStore *BaseStore, attachments *AttachmentStore) *ArchiveStore {
+	return &ArchiveStore{
+		db:          db,
+		baseStore:   baseStore,
+		attachments: attachments,
+	}
+}
+
+// ExportBase reads a whole base into an archive. Secrets are left out: webhook
+// secrets, inbound automation tokens and the public links of forms and views.
+func (s *ArchiveStore) ExportBase(ctx context.Context, baseID, userID uuid.UUID) (*models.BaseArchive, error) {
+	role, err := s.baseStore.GetUserRole(ctx, baseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanEdit() {
+		return nil, ErrForbidden
+	}
+	base, err := s.baseStore.GetBase(ctx, baseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.exportBase(ctx, base)
+}
+
+// exportBase reads a base into an archive, without checking access
+func (s *ArchiveStore) exportBase(ctx context.Context, base *models.Base) (*models.BaseArchive, error) {
+	baseID := base.ID
+	a := &models.BaseArchive{
+		Version:     models.BaseArchiveVersion,
+		ExportedAt:  time.Now().UTC(),
+		Base:        models.ArchiveBase{ID: base.ID, Name: base.Name},
+		Tables:      []models.ArchiveTable{},
+		Automations: []models.ArchiveAutomation{},
+		Webhooks:    []models.ArchiveWebhook{},
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT id, name, position FROM tables
+		WHERE base_id = $1
+		ORDER BY position
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var t models.ArchiveTable
+		if err := rows.Scan(&t.ID, &t.Name, &t.Position); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.Tables = append(a.Tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fields, err := s.exportFields(ctx, baseID)
+	if err != nil {
+		return nil, err
+	}
+	views, err := s.exportViews(ctx, baseID)
+	if err != nil {
+		return nil, err
+	}
+	forms, err := s.exportForms(ctx, baseID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.exportRecords(ctx, baseID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range a.Tables {
+		t := &a.Tables[i]
+		t.Fields = nonNil(fields[t.ID])
+		t.Views = nonNil(views[t.ID])
+		t.Forms = nonNil(forms[t.ID])
+		t.Records = nonNil(records[t.ID])
+	}
+
+	if a.Automations, err = s.exportAutomations(ctx, baseID); err != nil {
+		return nil, err
+	}
+	if a.Webhooks, err = s.exportWebhooks(ctx, baseID); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *ArchiveStore) exportFields(ctx context.Context, baseID uuid.UUID) (map[uuid.UUID][]models.ArchiveField, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT f.id, f.table_id, f.name, f.field_type, f.options, f.position
+		FROM fields f
+		JOIN tables t ON t.id = f.table_id
+		WHERE t.base_id = $1
+		ORDER BY f.position
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byTable := make(map[uuid.UUID][]models.ArchiveField)
+	for rows.Next() {
+		var f models.ArchiveField
+		var tableID uuid.UUID
+		if err := rows.Scan(&f.ID, &tableID, &f.Name, &f.FieldType, &f.Options, &f.Position); err != nil {
+			return nil, err
+		}
+		byTable[tableID] = append(byTable[tableID], f)
+	}
+	return byTable, rows.Err()
+}
+
+func (s *ArchiveStore) exportViews(ctx context.Context, baseID uuid.UUID) (map[uuid.UUID][]models.ArchiveView, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT v.id, v.table_id, v.name, v.view_type, v.config, v.position
+		FROM views v
+		JOIN tables t ON t.id = v.table_id
+		WHERE t.base_id = $1
+		ORDER BY v.position
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byTable := make(map[uuid.UUID][]models.ArchiveView)
+	for rows.Next() {
+		var v models.ArchiveView
+		var tableID uuid.UUID
+		if err := rows.Scan(&v.ID, &tableID, &v.Name, &v.Type, &v.Config, &v.Position); err != nil {
+			return nil, err
+		}
+		byTable[tableID] = append(byTable[tableID], v)
+	}
+	return byTable, rows.Err()
+}
+
+func (s *ArchiveStore) exportForms(ctx context.Context, baseID uuid.UUID) (map[uuid.UUID][]models.ArchiveForm, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT ff.form_id, ff.field_id, ff.label, ff.help_text, ff.is_required, ff.is_visible, ff.position
+		FROM form_fields ff
+		JOIN forms fo ON fo.id = ff.form_id
+		JOIN tables t ON t.id = fo.table_id
+		WHERE t.base_id = $1
+		ORDER BY ff.position
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	formFields := make(map[uuid.UUID][]models.ArchiveFormField)
+	for rows.Next() {
+		var ff models.ArchiveFormField
+		var formID uuid.UUID
+		if err := rows.Scan(&formID, &ff.FieldID, &ff.Label, &ff.HelpText, &ff.IsRequired, &ff.IsVisible, &ff.Position); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		formFields[formID] = append(formFields[formID], ff)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(ctx, `
+		SELECT fo.id, fo.table_id, fo.name, fo.description, fo.is_active,
+		       fo.success_message, fo.redirect_url, fo.submit_button_text
+		FROM forms fo
+		JOIN tables t ON t.id = fo.table_id
+		WHERE t.base_id = $1
+		ORDER BY fo.created_at
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byTable := make(map[uuid.UUID][]models.ArchiveForm)
+	for rows.Next() {
+		var f models.ArchiveForm
+		var tableID uuid.UUID
+		if err := rows.Scan(&f.ID, &tableID, &f.Name, &f.Description, &f.IsActive, &f.SuccessMessage, &f.RedirectURL, &f.SubmitButtonText); err != nil {
+			return nil, err
+		}
+		f.Fields = nonNil(formFields[f.ID])
+		byTable[tableID] = append(byTable[tableID], f)
+	}
+	return byTable, rows.Err()
+}
+
+func (s *ArchiveStore) exportRecords(ctx context.Context, baseID uuid.UUID) (map[uuid.UUID][]models.ArchiveRecord, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT c.id, c.record_id, c.parent_id, c.automation_id, u.email, c.content, c.is_resolved, c.created_at, c.updated_at
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		JOIN records r ON r.id = c.record_id
+		JOIN tables t ON t.id = r.table_id
+		WHERE t.base_id = $1
+		ORDER BY c.created_at
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	comments := make(map[uuid.UUID][]models.ArchiveComment)
+	for rows.Next() {
+		var c models.ArchiveComment
+		var recordID uuid.UUID
+		if err := rows.Scan(&c.ID, &recordID, &c.ParentID, &c.AutomationID, &c.AuthorEmail, &c.Content, &c.IsResolved, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		comments[recordID] = append(comments[recordID], c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(ctx, `
+		SELECT a.id, a.record_id, a.field_id, a.filename, a.content_type, a.size_bytes, a.storage_key, a.created_at
+		FROM attachments a
+		JOIN records r ON r.id = a.record_id
+		JOIN tables t ON t.id = r.table_id
+		WHERE t.base_id = $1
+		ORDER BY a.created_at
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	attachments := make(map[uuid.UUID][]models.ArchiveAttachment)
+	for rows.Next() {
+		var a models.ArchiveAttachment
+		var recordID uuid.UUID
+		if err := rows.Scan(&a.ID, &recordID, &a.FieldID, &a.Filename, &a.ContentType, &a.SizeBytes, &a.File, &a.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		attachments[recordID] = append(attachments[recordID], a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(ctx, `
+		SELECT r.id, r.table_id, r.values, r.position, r.color, r.created_at, r.updated_at
+		FROM records r
+		JOIN tables t ON t.id = r.table_id
+		WHERE t.base_id = $1
+		ORDER BY r.position
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byTable := make(map[uuid.UUID][]models.ArchiveRecord)
+	for rows.Next() {
+		var r models.ArchiveRecord
+		var tableID uuid.UUID
+		if err := rows.Scan(&r.ID, &tableID, &r.Values, &r.Position, &r.Color, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		r.Comments = comments[r.ID]
+		r.Attachments = attachments[r.ID]
+		byTable[tableID] = append(byTable[tableID], r)
+	}
+	return byTable, rows.Err()
+}
+
+func (s *ArchiveStore) exportAutomations(ctx context.Context, baseID uuid.UUID) ([]models.ArchiveAutomation, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, table_id, name, description, enabled, trigger_type, trigger_config, action_type, action_config
+		FROM automations
+		WHERE base_id = $1
+		ORDER BY created_at
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	automations := []models.ArchiveAutomation{}
+	for rows.Next() {
+		var a models.ArchiveAutomation
+		if err := rows.Scan(&a.ID, &a.TableID, &a.Name, &a.Description, &a.Enabled, &a.TriggerType, &a.TriggerConfig, &a.ActionType, &a.ActionConfig); err != nil {
+			return nil, err
+		}
+		if a.TriggerType == models.TriggerWebhookReceived {
+			a.TriggerConfig = withoutTriggerToken(a.TriggerConfig)
+		}
+		automations = append(automations, a)
+	}
+	return automations, rows.Err()
+}
+
+func (s *ArchiveStore) exportWebhooks(ctx context.Context, baseID uuid.UUID) ([]models.ArchiveWebhook, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, name, url, events, table_ids, watch_field_ids, format, message_template
+		FROM webhooks
+		WHERE base_id = $1
+		ORDER BY created_at
+	`, baseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.ArchiveWebhook{}
+	for rows.Next() {
+		var w models.Webhook
+		var events, tableIDs, watchFieldIDs []byte
+		if err := rows.Scan(&w.ID, &w.Name, &w.URL, &events, &tableIDs, &watchFieldIDs, &w.Format, &w.MessageTemplate); err != nil {
+			return nil, err
+		}
+		if err := decodeWebhookJSON(&w, events, tableIDs, watchFieldIDs); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, models.ArchiveWebhook{
+			ID:              w.ID,
+			Name:            w.Name,
+			URL:             w.URL,
+			Events:          w.Events,
+			TableIDs:        w.TableIDs,
+			WatchFieldIDs:   w.WatchFieldIDs,
+			Format:          w.Format,
+			MessageTemplate: w.MessageTemplate,
+		})
+	}
+	return webhooks, rows.Err()
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// WriteArchive writes an exported base as a zip archive, with the content of its
+// attachments read from storage
+func (s *ArchiveStore) WriteArchive(ctx context.Context, w io.Writer, a *models.BaseArchive) error {
+	return archive.Write(w, a, func(file string) (io.ReadCloser, error) {
+		return s.attachments.storage.Download(ctx, file)
+	})
+}
+
+// importedFile is an attachment file from an archive, copied into storage
+type importedFile struct {
+	key  string
+	size int64
+	head []byte // For sniffing the type of each attachment using the file
+}
+
+// ImportBase creates a new base from an archive, owned by the importing user. Every
+// row gets a new ID and references to the archive's IDs are rewritten, including
+// those inside cell values, field options, view and automation configs. Comments
+// keep their author when a user with the same email exists here.
+func (s *ArchiveStore) ImportBase(ctx context.Context, r *archive.Reader, name string, userID uuid.UUID) (*models.Base, error) {
+	a := r.Archive
+	if name == "" {
+		name = a.Base.Name
+	}
+
+	// Files are stored before the base is created; those of a failed import are
+	// removed by the garbage collector
+	files, err := s.importFiles(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createBase(ctx, a, name, userID, files)
+}
+
+// createBase creates a new base from an archive whose attachment files are already
+// in storage, as described by files
+func (s *ArchiveStore) createBase(ctx context.Context, a *models.BaseArchive, name string, userID uuid.UUID, files map[string]importedFile) (*models.Base, error) {
+	ids := newArchiveIDMap(a)
+	authors, err := s.commentAuthors(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var base models.Base
+	err = tx.QueryRow(ctx, `
+		INSERT INTO bases (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id, name, created_by, created_at, updated_at
+	`, name, userID).Scan(&base.ID, &base.Name, &base.CreatedBy, &base.CreatedAt, &base.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO base_collaborators (base_id, user_id, role)
+		VALUES ($1, $2, 'owner')
+	`, base.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tables are all created first, as fields and records refer across tables
+	for _, t := range a.Tables {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO tables (id, base_id, name, position)
+			VALUES ($1, $2, $3, $4)
+		`, ids[t.ID], base.ID, t.Name, t.Position)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range a.Tables {
+		if err := importTable(ctx, tx, ids, t, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, au := range a.Automations {
+		tableID, ok := ids[au.TableID]
+		if !ok {
+			return nil, fmt.Errorf("%w: automation %q refers to a missing table", archive.ErrInvalidArchive, au.Name)
+		}
+		triggerConfig := ids.remapJSON(au.TriggerConfig)
+		if au.TriggerType == models.TriggerWebhookReceived {
+			triggerConfig = ensureTriggerToken(triggerConfig, "")
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO automations (id, base_id, table_id, name, description, enabled,
+				trigger_type, trigger_config, action_type, action_config, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`, ids[au.ID], base.ID, tableID, au.Name, au.Description, au.Enabled,
+			au.TriggerType, triggerConfig, au.ActionType, ids.remapJSON(au.ActionConfig), userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Comments can refer to automations, so they come after them
+	thumbnails := false
+	for _, t := range a.Tables {
+		for _, rec := range t.Records {
+			if err := importComments(ctx, tx, ids, rec, authors, userID); err != nil {
+				return nil, err
+			}
+			for _, at := range rec.Attachments {
+				fieldID, ok := ids[at.FieldID]
+				if !ok {
+					return nil, fmt.Errorf("%w: attachment %s refers to a missing field", archive.ErrInvalidArchive, at.Filename)
+				}
+				file := files[at.File]
+				contentType := sniffContentType(file.head, at.Filename, at.ContentType)
+				var thumbnailStatus *string
+				if models.CanThumbnail(contentType) {
+					status := ThumbnailPending
+					thumbnailStatus = &status
+					thumbnails = true
+				}
+				_, err = tx.Exec(ctx, `
+					INSERT INTO attachments (id, record_id, field_id, filename, content_type, size_bytes,
+						storage_key, created_by, created_at, thumbnail_status)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				`, ids[at.ID], ids[rec.ID], fieldID, at.Filename, contentType, file.size,
+					file.key, userID, at.CreatedAt, thumbnailStatus)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// Webhooks come without secrets, so they start inactive
+	for _, wh := range a.Webhooks {
+		events, err := json.Marshal(wh.Events)
+		if err != nil {
+			return nil, err
+		}
+		tableIDs, watchFieldIDs := encodeWebhookFilters(ids.remapIDs(wh.TableIDs), ids.remapIDs(wh.WatchFieldIDs))
+		format := wh.Format
+		if format == "" {
+			format = models.WebhookFormatNative
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO webhooks (id, base_id, name, url, events, is_active, created_by,
+				table_ids, watch_field_ids, format, message_template)
+			VALUES ($1, $2, $3, $4, $5, false, $6, $7, $8, $9, $10)
+		`, ids[wh.ID], base.ID, wh.Name, wh.URL, events, userID,
+			tableIDs, watchFieldIDs, format, ids.remapText(wh.MessageTemplate))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	if thumbnails && s.attachments.thumbnailNotify != nil {
+		s.attachments.thumbnailNotify()
+	}
+
+	role := models.RoleOwner
+	base.Role = &role
+	return &base, nil
+}
+
+// importTable creates a table's fields, views, forms and records
+func importTable(ctx context.Context, tx pgx.Tx, ids archiveIDMap, t models.ArchiveTable, userID uuid.UUID) error {
+	tableID := ids[t.ID]
+
+	for _, f := range t.Fields {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO fields (id, table_id, name, field_type, options, position)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, ids[f.ID], tableID, f.Name, f.FieldType, ids.remapJSON(f.Options), f.Position)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, v := range t.Views {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO views (id, table_id, name, view_type, config, position)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, ids[v.ID], tableID, v.Name, v.Type, ids.remapJSON(v.Config), v.Position)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, f := range t.Forms {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO forms (id, table_id, name, description, public_token, is_active,
+				success_message, redirect_url, submit_button_text, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, ids[f.ID], tableID, f.Name, f.Description, generateToken(), f.IsActive,
+			f.SuccessMessage, f.RedirectURL, f.SubmitButtonText, userID)
+		if err != nil {
+			return err
+		}
+		for _, ff := range f.Fields {
+			fieldID, ok := ids[ff.FieldID]
+			if !ok {
+				return fmt.Errorf("%w: form %q refers to a missing field", archive.ErrInvalidArchive, f.Name)
+			}
+			_, err := tx.Exec(ctx, `
+				INSERT INTO form_fields (form_id, field_id, label, help_text, is_required, is_visible, position)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, ids[f.ID], fieldID, ff.Label, ff.HelpText, ff.IsRequired, ff.IsVisible, ff.Position)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, rec := range t.Records {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO records (id, table_id, values, position, color, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, ids[rec.ID], tableID, ids.remapJSON(rec.Values), rec.Position, rec.Color, rec.CreatedAt, rec.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importComments creates a record's comments, linking replies once every comment
+// they could reply to exists
+func importComments(ctx context.Context, tx pgx.Tx, ids archiveIDMap, rec models.ArchiveRecord, authors map[string]uuid.UUID, userID uuid.UUID) error {
+	for _, c := range rec.Comments {
+		author, ok := authors[c.AuthorEmail]
+		if !ok {
+			author = userID
+		}
+		var automationID *uuid.UUID
+		if c.AutomationID != nil {
+			if id, ok := ids[*c.AutomationID]; ok {
+				automationID = &id
+			}
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO comments (id, record_id, user_id, content, automation_id, is_resolved, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, ids[c.ID], ids[rec.ID], author, c.Content, automationID, c.IsResolved, c.CreatedAt, c.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range rec.Comments {
+		if c.ParentID == nil {
+			continue
+		}
+		parentID, ok := ids[*c.ParentID]
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `UPDATE comments SET parent_id = $2 WHERE id = $1`, ids[c.ID], parentID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importFiles copies each distinct attachment file in an archive into storage
+func (s *ArchiveStore) importFiles(ctx context.Context, r *archive.Reader) (map[string]importedFile, error) {
+	options := make(map[uuid.UUID]*models.FieldOptions)
+	for _, t := range r.Archive.Tables {
+		for _, f := range t.Fields {
+			if f.FieldType != models.FieldTypeAttachment {
+				continue
+			}
+			var o models.FieldOptions
+			if len(f.Options) > 0 {
+				if err := json.Unmarshal(f.Options, &o); err != nil {
+					return nil, fmt.Errorf("%w: field %s has invalid options", archive.ErrInvalidArchive, f.Name)
+				}
+			}
+			options[f.ID] = &o
+		}
+	}
+
+	// The sizes the archive declares are checked against each field's limit and the
+	// quota before anything is decompressed; Open fails on entries that lie
+	var order []string
+	uses := make(map[string][]models.ArchiveAttachment)
+	var total int64
+	for _, t := range r.Archive.Tables {
+		for _, rec := range t.Records {
+			for _, at := range rec.Attachments {
+				o, ok := options[at.FieldID]
+				if !ok {
+					return nil, fmt.Errorf("%w: attachment %s is not in an attachment field", archive.ErrInvalidArchive, at.Filename)
+				}
+				size := r.Size(at.File)
+				if maxSize := maxAttachmentSize(o); size > maxSize {
+					return nil, fmt.Errorf("%w: %s is larger than the %d bytes its field accepts", ErrFileTooLarge, at.Filename, maxSize)
+				}
+				total += size
+				if uses[at.File] == nil {
+					order = append(order, at.File)
+				}
+				uses[at.File] = append(uses[at.File], at)
+			}
+		}
+	}
+	if quota := s.attachments.quota; quota > 0 && total > quota {
+		return nil, fmt.Errorf("%w: the archive's attachments take %d bytes and bases may store %d", ErrStorageQuotaExceeded, total, quota)
+	}
+
+	files := make(map[string]importedFile, len(order))
+	for _, name := range order {
+		file, err := s.importFile(ctx, r, name, uses[name], options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", uses[name][0].Filename, err)
+		}
+		files[name] = file
+	}
+	return files, nil
+}
+
+// importFile stores an archive file after checking its type is accepted by the
+// field of every attachment using it
+func (s *ArchiveStore) importFile(ctx context.Context, r *archive.Reader, name string, uses []models.ArchiveAttachment, options map[uuid.UUID]*models.FieldOptions) (importedFile, error) {
+	rc, err := r.Open(name)
+	if err != nil {
+		return importedFile{}, err
+	}
+	spool, hash, size, err := spoolUpload(rc)
+	rc.Close()
+	if err != nil {
+		return importedFile{}, err
+	}
+	defer removeSpool(spool)
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(spool, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return importedFile{}, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return importedFile{}, err
+	}
+
+	file := importedFile{key: storage.ContentKey(hash), size: size, head: head[:n]}
+	for _, at := range uses {
+		contentType := sniffContentType(file.head, at.Filename, at.ContentType)
+		if allowed := options[at.FieldID].AllowedTypes; len(allowed) > 0 && !matchesContentType(contentType, allowed) {
+			return importedFile{}, fmt.Errorf("%w: %s is not accepted by the field of %s", ErrUnsupportedMediaType, contentType, at.Filename)
+		}
+	}
+	contentType := sniffContentType(file.head, uses[0].Filename, uses[0].ContentType)
+	if err := s.attachments.storeBlob(ctx, file.key, spool, contentType); err != nil {
+		return importedFile{}, err
+	}
+	return file, nil
+}
+
+// commentAuthors finds the users here who wrote comments in an archive, by email
+func (s *ArchiveStore) commentAuthors(ctx context.Context, a *models.BaseArchive) (map[string]uuid.UUID, error) {
+	seen := make(map[string]bool)
+	var emails []string
+	for _, t := range a.Tables {
+		for _, rec := range t.Records {
+			for _, c := range rec.Comments {
+				if !seen[c.AuthorEmail] {
+					seen[c.AuthorEmail] = true
+					emails = append(emails, c.AuthorEmail)
+				}
+			}
+		}
+	}
+
+	authors := make(map[string]uuid.UUID)
+	if len(emails) == 0 {
+		return authors, nil
+	}
+	rows, err := s.db.Query(ctx, `SELECT id, email FROM users WHERE email = ANY($1)`, emails)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			return nil, err
+		}
+		authors[email] = id
+	}
+	return authors, rows.Err()
+}
+
+// archiveIDMap gives every row in an archive a new ID
+type archiveIDMap map[uuid.UUID]uuid.UUID
+
+func newArchiveIDMap(a *models.BaseArchive) archiveIDMap {
+	ids := make(archiveIDMap)
+	add := func(id uuid.UUID) { ids[id] = uuid.New() }
+	for _, t := range a.Tables {
+		add(t.ID)
+		for _, f := range t.Fields {
+			add(f.ID)
+		}
+		for _, v := range t.Views {
+			add(v.ID)
+		}
+		for _, f := range t.Forms {
+			add(f.ID)
+		}
+		for _, rec := range t.Records {
+			add(rec.ID)
+			for _, c := range rec.Comments {
+				add(c.ID)
+			}
+			for _, at := range rec.Attachments {
+				add(at.ID)
+			}
+		}
+	}
+	for _, au := range a.Automations {
+		add(au.ID)
+	}
+	for _, wh := range a.Webhooks {
+		add(wh.ID)
+	}
+	return ids
+}
+
+var uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// remapJSON rewrites the archive's IDs wherever they appear in a JSON document: as
+// keys of cell values, in linked record values, options and configs, and inside
+// text such as formula and template field references. IDs from outside the archive
+// are left as they are.
+func (ids archiveIDMap) remapJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage(`{}`)
+	}
+	return ids.remap(raw)
+}
+
+// remapText rewrites the archive's IDs inside text
+func (ids archiveIDMap) remapText(text *string) *string {
+	if text == nil {
+		return nil
+	}
+	remapped := string(ids.remap([]byte(*text)))
+	return &remapped
+}
+
+func (ids archiveIDMap) remap(data []byte) []byte {
+	return uuidPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		id, err := uuid.ParseBytes(match)
+		if err != nil {
+			return match
+		}
+		if newID, ok := ids[id]; ok {
+			return []byte(newID.String())
+		}
+		return match
+	})
+}
+
+// remapIDs maps a list of the archive's IDs, dropping any from outside it
+func (ids archiveIDMap) remapIDs(oldIDs []uuid.UUID) []uuid.UUID {
+	var newIDs []uuid.UUID
+	for _, id := range oldIDs {
+		if newID, ok := ids[id]; ok {
+			newIDs = append(newIDs, newID)
+		}
+	}
+	return newIDs
+}
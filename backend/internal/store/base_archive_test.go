@@ -0,0 +1,325 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/archive"
+	"github.com/vibetable/backend/internal/models"
+)
+
+// captureArg matches any argument, keeping it for later assertions
+type captureArg struct {
+	value interface{}
+}
+
+func (c *captureArg) Match(v interface{}) bool {
+	c.value = v
+	return true
+}
+
+func TestArchiveIDMap_RemapJSON(t *testing.T) {
+	oldField, oldRecord, outside := uuid.New(), uuid.New(), uuid.New()
+	ids := archiveIDMap{oldField: uuid.New(), oldRecord: uuid.New()}
+
+	t.Run("rewrites keys, values and references in text", func(t *testing.T) {
+		raw := json.RawMessage(`{"` + oldField.String() + `": ["` + oldRecord.String() + `"], "expression": "{` + strings.ToUpper(oldField.String()) + `} * 2"}`)
+
+		var values map[string]interface{}
+		require.NoError(t, json.Unmarshal(ids.remapJSON(raw), &values))
+		assert.Equal(t, []interface{}{ids[oldRecord].String()}, values[ids[oldField].String()])
+		assert.Equal(t, "{"+ids[oldField].String()+"} * 2", values["expression"])
+	})
+
+	t.Run("leaves IDs from outside the archive alone", func(t *testing.T) {
+		raw := json.RawMessage(`{"linked_table_id": "` + outside.String() + `"}`)
+		assert.JSONEq(t, string(raw), string(ids.remapJSON(raw)))
+	})
+
+	t.Run("defaults missing documents to an empty object", func(t *testing.T) {
+		assert.Equal(t, json.RawMessage(`{}`), ids.remapJSON(nil))
+	})
+
+	t.Run("keeps empty text empty", func(t *testing.T) {
+		empty := ""
+		assert.Equal(t, "", *ids.remapText(&empty))
+		assert.Nil(t, ids.remapText(nil))
+	})
+
+	t.Run("drops listed IDs from outside the archive", func(t *testing.T) {
+		assert.Equal(t, []uuid.UUID{ids[oldField]}, ids.remapIDs([]uuid.UUID{oldField, outside}))
+	})
+}
+
+func TestArchiveStore_ExportBase(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("requires edit access", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewArchiveStore(mock, NewBaseStore(mock), nil)
+		baseID, userID := uuid.New(), uuid.New()
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleViewer))
+
+		_, err = store.ExportBase(ctx, baseID, userID)
+		assert.ErrorIs(t, err, ErrForbidden)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("exports the base without secrets", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewArchiveStore(mock, NewBaseStore(mock), nil)
+		baseID, userID := uuid.New(), uuid.New()
+		tableID, fieldID, viewID, formID, recordID := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+		automationID, webhookID := uuid.New(), uuid.New()
+		now := time.Now().UTC()
+
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		mock.ExpectQuery("SELECT b.id, b.name").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "created_by", "created_at", "updated_at", "role"}).
+				AddRow(baseID, "CRM", userID, now, now, models.RoleEditor))
+		mock.ExpectQuery("SELECT id, name, position FROM tables").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "position"}).AddRow(tableID, "Contacts", 0))
+		mock.ExpectQuery("FROM fields f").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "name", "field_type", "options", "position"}).
+				AddRow(fieldID, tableID, "Files", models.FieldTypeAttachment, json.RawMessage(`{}`), 0))
+		mock.ExpectQuery("FROM views v").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "name", "view_type", "config", "position"}).
+				AddRow(viewID, tableID, "Grid", models.ViewTypeGrid, json.RawMessage(`{}`), 0))
+		mock.ExpectQuery("FROM form_fields ff").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"form_id", "field_id", "label", "help_text", "is_required", "is_visible", "position"}).
+				AddRow(formID, fieldID, nil, nil, true, true, 0))
+		mock.ExpectQuery("FROM forms fo").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "name", "description", "is_active", "success_message", "redirect_url", "submit_button_text"}).
+				AddRow(formID, tableID, "Signup", nil, true, "Thanks!", nil, "Submit"))
+		mock.ExpectQuery("FROM comments c").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "record_id", "parent_id", "automation_id", "email", "content", "is_resolved", "created_at", "updated_at"}).
+				AddRow(uuid.New(), recordID, nil, nil, "ann@example.com", "Looks good", false, now, now))
+		mock.ExpectQuery("FROM attachments a").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "record_id", "field_id", "filename", "content_type", "size_bytes", "storage_key", "created_at"}).
+				AddRow(uuid.New(), recordID, fieldID, "a.txt", "text/plain", int64(5), "blobs/aa/aa1", now))
+		mock.ExpectQuery("FROM records r").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "values", "position", "color", "created_at", "updated_at"}).
+				AddRow(recordID, tableID, json.RawMessage(`{}`), 0, nil, now, now))
+		mock.ExpectQuery("FROM automations").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "name", "description", "enabled", "trigger_type", "trigger_config", "action_type", "action_config"}).
+				AddRow(automationID, tableID, "Inbound", nil, true, models.TriggerWebhookReceived, json.RawMessage(`{"token": "secret-token"}`), models.ActionAddComment, json.RawMessage(`{}`)))
+		mock.ExpectQuery("FROM webhooks").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "url", "events", "table_ids", "watch_field_ids", "format", "message_template"}).
+				AddRow(webhookID, "Hook", "https://example.com/hook", []byte(`["record.created"]`), []byte(`["`+tableID.String()+`"]`), []byte(`[]`), models.WebhookFormatNative, nil))
+
+		a, err := store.ExportBase(ctx, baseID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, models.BaseArchiveVersion, a.Version)
+		assert.Equal(t, "CRM", a.Base.Name)
+		require.Len(t, a.Tables, 1)
+
+		table := a.Tables[0]
+		require.Len(t, table.Fields, 1)
+		require.Len(t, table.Views, 1)
+		require.Len(t, table.Forms, 1)
+		assert.Len(t, table.Forms[0].Fields, 1)
+		require.Len(t, table.Records, 1)
+		assert.Equal(t, "ann@example.com", table.Records[0].Comments[0].AuthorEmail)
+		assert.Equal(t, "blobs/aa/aa1", table.Records[0].Attachments[0].File)
+
+		require.Len(t, a.Automations, 1)
+		assert.NotContains(t, string(a.Automations[0].TriggerConfig), "secret-token")
+		require.Len(t, a.Webhooks, 1)
+		assert.Equal(t, []uuid.UUID{tableID}, a.Webhooks[0].TableIDs)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestArchiveStore_ImportBase(t *testing.T) {
+	ctx := context.Background()
+
+	tableID, fieldID, linkID, recordID, otherID := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	now := time.Now().UTC()
+	testArchive := &models.BaseArchive{
+		Version: models.BaseArchiveVersion,
+		Base:    models.ArchiveBase{ID: uuid.New(), Name: "CRM"},
+		Tables: []models.ArchiveTable{{
+			ID:   tableID,
+			Name: "Contacts",
+			Fields: []models.ArchiveField{
+				{ID: fieldID, Name: "Files", FieldType: models.FieldTypeAttachment, Options: json.RawMessage(`{}`)},
+				{ID: linkID, Name: "Related", FieldType: models.FieldTypeLinkedRecord, Options: json.RawMessage(`{"linked_table_id": "` + tableID.String() + `"}`), Position: 1},
+			},
+			Records: []models.ArchiveRecord{
+				{ID: recordID, Values: json.RawMessage(`{"` + linkID.String() + `": ["` + otherID.String() + `"]}`), CreatedAt: now, UpdatedAt: now},
+				{
+					ID: otherID, Values: json.RawMessage(`{}`), Position: 1, CreatedAt: now, UpdatedAt: now,
+					Attachments: []models.ArchiveAttachment{{ID: uuid.New(), FieldID: fieldID, Filename: "a.txt", ContentType: "text/plain", SizeBytes: 5, File: "blobs/aa/aa1", CreatedAt: now}},
+				},
+			},
+		}},
+		Webhooks: []models.ArchiveWebhook{{ID: uuid.New(), Name: "Hook", URL: "https://example.com/hook", Events: []models.WebhookEvent{models.WebhookEventRecordCreated}}},
+	}
+
+	readArchive := func(t *testing.T, a *models.BaseArchive) *archive.Reader {
+		var buf bytes.Buffer
+		err := archive.Write(&buf, a, func(file string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("hello")), nil
+		})
+		require.NoError(t, err)
+		r, err := archive.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		return r
+	}
+
+	setup := func(t *testing.T) (pgxmock.PgxPoolIface, *ArchiveStore, *mockStorage) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+
+		stor := &mockStorage{}
+		attachments := NewAttachmentStore(mock, nil, nil, nil, stor, "http://localhost")
+		return mock, NewArchiveStore(mock, NewBaseStore(mock), attachments), stor
+	}
+
+	t.Run("recreates the base with new IDs", func(t *testing.T) {
+		mock, store, stor := setup(t)
+		userID, baseID := uuid.New(), uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO bases").
+			WithArgs("Imported CRM", userID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "created_by", "created_at", "updated_at"}).
+				AddRow(baseID, "Imported CRM", userID, now, now))
+		mock.ExpectExec("INSERT INTO base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		newTableID := &captureArg{}
+		mock.ExpectExec("INSERT INTO tables").
+			WithArgs(newTableID, baseID, "Contacts", 0).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		newFieldID, newLinkID, linkOptions := &captureArg{}, &captureArg{}, &captureArg{}
+		mock.ExpectExec("INSERT INTO fields").
+			WithArgs(newFieldID, pgxmock.AnyArg(), "Files", models.FieldTypeAttachment, pgxmock.AnyArg(), 0).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec("INSERT INTO fields").
+			WithArgs(newLinkID, pgxmock.AnyArg(), "Related", models.FieldTypeLinkedRecord, linkOptions, 1).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		newRecordID, recordValues, newOtherID := &captureArg{}, &captureArg{}, &captureArg{}
+		mock.ExpectExec("INSERT INTO records").
+			WithArgs(newRecordID, pgxmock.AnyArg(), recordValues, 0, (*string)(nil), now, now).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec("INSERT INTO records").
+			WithArgs(newOtherID, pgxmock.AnyArg(), pgxmock.AnyArg(), 1, (*string)(nil), now, now).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec("INSERT INTO attachments").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "a.txt", "text/plain", int64(5),
+				"blobs/2c/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", userID, now, (*string)(nil)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec("INSERT INTO webhooks").
+			WithArgs(pgxmock.AnyArg(), baseID, "Hook", "https://example.com/hook", pgxmock.AnyArg(), userID,
+				pgxmock.AnyArg(), pgxmock.AnyArg(), models.WebhookFormatNative, (*string)(nil)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		base, err := store.ImportBase(ctx, readArchive(t, testArchive), "Imported CRM", userID)
+		require.NoError(t, err)
+		assert.Equal(t, baseID, base.ID)
+		assert.Equal(t, []byte("hello"), stor.data)
+
+		assert.NotEqual(t, tableID, newTableID.value)
+		assert.NotEqual(t, fieldID, newFieldID.value)
+		assert.JSONEq(t, `{"linked_table_id": "`+newTableID.value.(uuid.UUID).String()+`"}`, string(linkOptions.value.(json.RawMessage)))
+		assert.JSONEq(t,
+			`{"`+newLinkID.value.(uuid.UUID).String()+`": ["`+newOtherID.value.(uuid.UUID).String()+`"]}`,
+			string(recordValues.value.(json.RawMessage)))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects archives over the storage quota before storing files", func(t *testing.T) {
+		mock, store, stor := setup(t)
+		store.attachments.SetQuota(4)
+
+		_, err := store.ImportBase(ctx, readArchive(t, testArchive), "", uuid.New())
+		assert.ErrorIs(t, err, ErrStorageQuotaExceeded)
+		assert.Nil(t, stor.data)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// withFieldOptions returns the test archive with new options on its attachment field
+	withFieldOptions := func(options string) *models.BaseArchive {
+		a := *testArchive
+		table := a.Tables[0]
+		table.Fields = append([]models.ArchiveField(nil), table.Fields...)
+		table.Fields[0].Options = json.RawMessage(options)
+		a.Tables = []models.ArchiveTable{table}
+		return &a
+	}
+
+	t.Run("applies the field's size limit", func(t *testing.T) {
+		mock, store, stor := setup(t)
+
+		_, err := store.ImportBase(ctx, readArchive(t, withFieldOptions(`{"max_size_bytes": 4}`)), "", uuid.New())
+		assert.ErrorIs(t, err, ErrFileTooLarge)
+		assert.Nil(t, stor.data)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("applies the field's allowed types", func(t *testing.T) {
+		mock, store, stor := setup(t)
+
+		_, err := store.ImportBase(ctx, readArchive(t, withFieldOptions(`{"allowed_types": ["image/*"]}`)), "", uuid.New())
+		assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+		assert.Nil(t, stor.data)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects automations on tables missing from the archive", func(t *testing.T) {
+		mock, store, _ := setup(t)
+		userID, baseID := uuid.New(), uuid.New()
+		a := &models.BaseArchive{
+			Version:     models.BaseArchiveVersion,
+			Base:        models.ArchiveBase{Name: "Empty"},
+			Automations: []models.ArchiveAutomation{{ID: uuid.New(), TableID: uuid.New(), Name: "Orphan"}},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO bases").
+			WithArgs("Empty", userID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "created_by", "created_at", "updated_at"}).
+				AddRow(baseID, "Empty", userID, now, now))
+		mock.ExpectExec("INSERT INTO base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectRollback()
+
+		_, err := store.ImportBase(ctx, readArchive(t, a), "", userID)
+		assert.ErrorIs(t, err, archive.ErrInvalidArchive)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
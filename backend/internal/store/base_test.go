@@ -344,6 +344,46 @@ func TestBaseStore_ListCollaborators(t *testing.T) {
 	})
 }
 
+func TestBaseStore_checkDuplicateQuota(t *testing.T) {
+	ctx := context.Background()
+	recordIDMap := map[uuid.UUID]uuid.UUID{uuid.New(): uuid.New()}
+	fieldIDMap := map[uuid.UUID]uuid.UUID{uuid.New(): uuid.New()}
+
+	check := func(t *testing.T, quota int64, size int64) error {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewBaseStore(mock)
+		store.SetQuota(quota)
+
+		mock.ExpectBegin()
+		if quota > 0 {
+			mock.ExpectQuery("SELECT COALESCE\\(SUM\\(size_bytes\\), 0\\)").
+				WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+				WillReturnRows(pgxmock.NewRows([]string{"sum"}).AddRow(size))
+		}
+
+		tx, err := mock.Begin(ctx)
+		require.NoError(t, err)
+		err = store.checkDuplicateQuota(ctx, tx, recordIDMap, fieldIDMap)
+		require.NoError(t, mock.ExpectationsWereMet())
+		return err
+	}
+
+	t.Run("allows copies within the quota", func(t *testing.T) {
+		assert.NoError(t, check(t, 100, 100))
+	})
+
+	t.Run("rejects copies over the quota", func(t *testing.T) {
+		assert.ErrorIs(t, check(t, 100, 101), ErrStorageQuotaExceeded)
+	})
+
+	t.Run("allows any size without a quota", func(t *testing.T) {
+		assert.NoError(t, check(t, 0, 1<<40))
+	})
+}
+
 func TestErrForbidden(t *testing.T) {
 	assert.NotNil(t, ErrForbidden)
 	assert.Equal(t, "forbidden", ErrForbidden.Error())
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vibetable/backend/internal/realtime"
+)
+
+const cellLockColumns = `base_id, table_id, record_id, field_id, user_id, expires_at`
+
+// CellLockStore is a realtime.CellLocks shared by all instances
+type CellLockStore struct {
+	db DBTX
+}
+
+func NewCellLockStore(db DBTX) *CellLockStore {
+	return &CellLockStore{db: db}
+}
+
+func scanCellLock(row pgx.Row) (*realtime.CellLock, error) {
+	var lock realtime.CellLock
+	err := row.Scan(&lock.BaseID, &lock.TableID, &lock.RecordID, &lock.FieldID, &lock.UserID, &lock.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+func (s *CellLockStore) queryCellLocks(ctx context.Context, sql string, args ...interface{}) ([]*realtime.CellLock, error) {
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locks := []*realtime.CellLock{}
+	for rows.Next() {
+		lock, err := scanCellLock(rows)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, rows.Err()
+}
+
+// Acquire takes or renews a lock and returns the cell's holder
+func (s *CellLockStore) Acquire(ctx context.Context, lock *realtime.CellLock) (*realtime.CellLock, error) {
+	// The lock is only taken over when it is the user's own or has expired
+	held, err := scanCellLock(s.db.QueryRow(ctx, `
+		INSERT INTO cell_locks (`+cellLockColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (base_id, record_id, field_id) DO UPDATE
+		SET table_id = EXCLUDED.table_id, user_id = EXCLUDED.user_id, expires_at = EXCLUDED.expires_at
+		WHERE cell_locks.user_id = EXCLUDED.user_id OR cell_locks.expires_at <= NOW()
+		RETURNING `+cellLockColumns,
+		lock.BaseID, lock.TableID, lock.RecordID, lock.FieldID, lock.UserID, lock.ExpiresAt))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return held, err
+	}
+
+	// Someone else holds it
+	held, err = scanCellLock(s.db.QueryRow(ctx, `
+		SELECT `+cellLockColumns+` FROM cell_locks
+		WHERE base_id = $1 AND record_id = $2 AND field_id = $3
+	`, lock.BaseID, lock.RecordID, lock.FieldID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Released in between; try again
+		return s.Acquire(ctx, lock)
+	}
+	return held, err
+}
+
+// Release removes a lock if the user holds it
+func (s *CellLockStore) Release(ctx context.Context, lock *realtime.CellLock) (bool, error) {
+	result, err := s.db.Exec(ctx, `
+		DELETE FROM cell_locks
+		WHERE base_id = $1 AND record_id = $2 AND field_id = $3 AND user_id = $4
+	`, lock.BaseID, lock.RecordID, lock.FieldID, lock.UserID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ReleaseUser removes all of a user's locks in a base
+func (s *CellLockStore) ReleaseUser(ctx context.Context, baseID, userID uuid.UUID) ([]*realtime.CellLock, error) {
+	return s.queryCellLocks(ctx, `
+		DELETE FROM cell_locks WHERE base_id = $1 AND user_id = $2
+		RETURNING `+cellLockColumns, baseID, userID)
+}
+
+// List returns a base's unexpired locks
+func (s *CellLockStore) List(ctx context.Context, baseID uuid.UUID) ([]*realtime.CellLock, error) {
+	return s.queryCellLocks(ctx, `
+		SELECT `+cellLockColumns+` FROM cell_locks
+		WHERE base_id = $1 AND expires_at > NOW()
+	`, baseID)
+}
+
+// Expire removes expired locks
+func (s *CellLockStore) Expire(ctx context.Context) ([]*realtime.CellLock, error) {
+	return s.queryCellLocks(ctx, `
+		DELETE FROM cell_locks WHERE expires_at <= NOW()
+		RETURNING `+cellLockColumns)
+}
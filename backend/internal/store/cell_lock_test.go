@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/realtime"
+)
+
+var cellLockRowColumns = []string{"base_id", "table_id", "record_id", "field_id", "user_id", "expires_at"}
+
+func TestCellLockStore_Acquire(t *testing.T) {
+	ctx := context.Background()
+	lock := &realtime.CellLock{
+		BaseID: uuid.New(), TableID: uuid.New(), RecordID: uuid.New(), FieldID: uuid.New(),
+		UserID: uuid.New(), ExpiresAt: time.Now().Add(realtime.CellLockLease),
+	}
+
+	t.Run("takes a free lock", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery("INSERT INTO cell_locks").
+			WithArgs(lock.BaseID, lock.TableID, lock.RecordID, lock.FieldID, lock.UserID, lock.ExpiresAt).
+			WillReturnRows(pgxmock.NewRows(cellLockRowColumns).
+				AddRow(lock.BaseID, lock.TableID, lock.RecordID, lock.FieldID, lock.UserID, lock.ExpiresAt))
+
+		held, err := NewCellLockStore(mock).Acquire(ctx, lock)
+		require.NoError(t, err)
+		assert.Equal(t, lock, held)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns another user's lock", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		holder := uuid.New()
+		mock.ExpectQuery("INSERT INTO cell_locks").
+			WithArgs(lock.BaseID, lock.TableID, lock.RecordID, lock.FieldID, lock.UserID, lock.ExpiresAt).
+			WillReturnError(pgx.ErrNoRows)
+		mock.ExpectQuery("SELECT (.+) FROM cell_locks").
+			WithArgs(lock.BaseID, lock.RecordID, lock.FieldID).
+			WillReturnRows(pgxmock.NewRows(cellLockRowColumns).
+				AddRow(lock.BaseID, lock.TableID, lock.RecordID, lock.FieldID, holder, lock.ExpiresAt))
+
+		held, err := NewCellLockStore(mock).Acquire(ctx, lock)
+		require.NoError(t, err)
+		assert.Equal(t, holder, held.UserID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCellLockStore_Release(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	lock := &realtime.CellLock{BaseID: uuid.New(), RecordID: uuid.New(), FieldID: uuid.New(), UserID: uuid.New()}
+	mock.ExpectExec("DELETE FROM cell_locks").
+		WithArgs(lock.BaseID, lock.RecordID, lock.FieldID, lock.UserID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	released, err := NewCellLockStore(mock).Release(context.Background(), lock)
+	require.NoError(t, err)
+	assert.False(t, released)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCellLockStore_Expire(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("DELETE FROM cell_locks WHERE expires_at").
+		WillReturnRows(pgxmock.NewRows(cellLockRowColumns).
+			AddRow(uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), time.Now()))
+
+	expired, err := NewCellLockStore(mock).Expire(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, expired, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
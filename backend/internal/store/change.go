@@ -0,0 +1,15 @@
+package store
+
+import "github.com/google/uuid"
+
+// Change kinds reported to a ChangeCallback
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// ChangeCallback is called after a table, field, view or comment is created, updated or
+// deleted. entity is the *models.Table, *models.Field, *models.View or *models.Comment;
+// for deletions it holds the state before the delete.
+type ChangeCallback func(baseID uuid.UUID, tableID uuid.UUID, change string, entity interface{}, userID uuid.UUID)
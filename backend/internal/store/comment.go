@@ -9,11 +9,16 @@ import (
 	"github.com/vibetable/backend/internal/models"
 )
 
+// CommentCallback is called after a comment is added to a record
+type CommentCallback func(tableID uuid.UUID, comment *models.Comment, userID uuid.UUID)
+
 type CommentStore struct {
-	db          DBTX
-	baseStore   *BaseStore
-	tableStore  *TableStore
-	recordStore *RecordStore
+	db              DBTX
+	baseStore       *BaseStore
+	tableStore      *TableStore
+	recordStore     *RecordStore
+	commentCallback CommentCallback
+	changeCallback  ChangeCallback
 }
 
 func NewCommentStore(db DBTX, baseStore *BaseStore, tableStore *TableStore, recordStore *RecordStore) *CommentStore {
@@ -25,6 +30,33 @@ func NewCommentStore(db DBTX, baseStore *BaseStore, tableStore *TableStore, reco
 	}
 }
 
+// SetCommentCallback sets the callback for new comments
+func (s *CommentStore) SetCommentCallback(cb CommentCallback) {
+	s.commentCallback = cb
+}
+
+// SetChangeCallback sets the callback for created, updated and deleted comments
+func (s *CommentStore) SetChangeCallback(cb ChangeCallback) {
+	s.changeCallback = cb
+}
+
+// notifyChange reports a comment change along with the base and table of its record
+func (s *CommentStore) notifyChange(ctx context.Context, change string, c *models.Comment, userID uuid.UUID) {
+	if s.changeCallback == nil {
+		return
+	}
+	var baseID, tableID uuid.UUID
+	err := s.db.QueryRow(ctx, `
+		SELECT t.base_id, t.id FROM records r
+		JOIN tables t ON r.table_id = t.id
+		WHERE r.id = $1
+	`, c.RecordID).Scan(&baseID, &tableID)
+	if err != nil {
+		return
+	}
+	s.changeCallback(baseID, tableID, change, c, userID)
+}
+
 // getBaseIDForRecord returns the base ID for a record
 func (s *CommentStore) getBaseIDForRecord(ctx context.Context, recordID uuid.UUID) (uuid.UUID, error) {
 	var baseID uuid.UUID
@@ -53,7 +85,7 @@ func (s *CommentStore) ListComments(ctx context.Context, recordID uuid.UUID, use
 	}
 
 	rows, err := s.db.Query(ctx, `
-		SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id, c.is_resolved, c.created_at, c.updated_at,
+		SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id, c.automation_id, c.is_resolved, c.created_at, c.updated_at,
 		       u.id, u.email, u.name, u.created_at, u.updated_at
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
@@ -72,7 +104,7 @@ func (s *CommentStore) ListComments(ctx context.Context, recordID uuid.UUID, use
 		var c models.Comment
 		var user models.User
 		if err := rows.Scan(
-			&c.ID, &c.RecordID, &c.UserID, &c.Content, &c.ParentID, &c.IsResolved, &c.CreatedAt, &c.UpdatedAt,
+			&c.ID, &c.RecordID, &c.UserID, &c.Content, &c.ParentID, &c.AutomationID, &c.IsResolved, &c.CreatedAt, &c.UpdatedAt,
 			&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt,
 		); err != nil {
 			return nil, err
@@ -131,9 +163,9 @@ func (s *CommentStore) CreateComment(ctx context.Context, recordID uuid.UUID, us
 	err = s.db.QueryRow(ctx, `
 		INSERT INTO comments (record_id, user_id, content, parent_id)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, record_id, user_id, content, parent_id, is_resolved, created_at, updated_at
+		RETURNING id, record_id, user_id, content, parent_id, automation_id, is_resolved, created_at, updated_at
 	`, recordID, userID, content, parentID).Scan(
-		&c.ID, &c.RecordID, &c.UserID, &c.Content, &c.ParentID, &c.IsResolved, &c.CreatedAt, &c.UpdatedAt,
+		&c.ID, &c.RecordID, &c.UserID, &c.Content, &c.ParentID, &c.AutomationID, &c.IsResolved, &c.CreatedAt, &c.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -150,6 +182,46 @@ func (s *CommentStore) CreateComment(ctx context.Context, recordID uuid.UUID, us
 	c.User = &user
 	c.Replies = []*models.Comment{}
 
+	// Trigger comment automations
+	if s.commentCallback != nil {
+		var tableID uuid.UUID
+		if err := s.db.QueryRow(ctx, `SELECT table_id FROM records WHERE id = $1`, recordID).Scan(&tableID); err == nil {
+			s.commentCallback(tableID, &c, userID)
+		}
+	}
+	s.notifyChange(ctx, ChangeCreated, &c, userID)
+
+	return &c, nil
+}
+
+// CreateAutomationComment posts a comment on behalf of an automation. The comment is
+// attributed to authorID (the automation's creator) and tagged with the automation ID.
+// Comment callbacks are not invoked so automations cannot trigger themselves.
+func (s *CommentStore) CreateAutomationComment(ctx context.Context, recordID uuid.UUID, automationID uuid.UUID, authorID uuid.UUID, content string) (*models.Comment, error) {
+	// Verify the author still has access to the record's base
+	baseID, err := s.getBaseIDForRecord(ctx, recordID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.baseStore.GetUserRole(ctx, baseID, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var c models.Comment
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO comments (record_id, user_id, content, automation_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, record_id, user_id, content, parent_id, automation_id, is_resolved, created_at, updated_at
+	`, recordID, authorID, content, automationID).Scan(
+		&c.ID, &c.RecordID, &c.UserID, &c.Content, &c.ParentID, &c.AutomationID, &c.IsResolved, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.Replies = []*models.Comment{}
+	s.notifyChange(ctx, ChangeCreated, &c, authorID)
+
 	return &c, nil
 }
 
@@ -158,13 +230,13 @@ func (s *CommentStore) GetComment(ctx context.Context, commentID uuid.UUID, user
 	var c models.Comment
 	var user models.User
 	err := s.db.QueryRow(ctx, `
-		SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id, c.is_resolved, c.created_at, c.updated_at,
+		SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id, c.automation_id, c.is_resolved, c.created_at, c.updated_at,
 		       u.id, u.email, u.name, u.created_at, u.updated_at
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
 		WHERE c.id = $1
 	`, commentID).Scan(
-		&c.ID, &c.RecordID, &c.UserID, &c.Content, &c.ParentID, &c.IsResolved, &c.CreatedAt, &c.UpdatedAt,
+		&c.ID, &c.RecordID, &c.UserID, &c.Content, &c.ParentID, &c.AutomationID, &c.IsResolved, &c.CreatedAt, &c.UpdatedAt,
 		&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt,
 	)
 
@@ -205,13 +277,14 @@ func (s *CommentStore) UpdateComment(ctx context.Context, commentID uuid.UUID, u
 	err = s.db.QueryRow(ctx, `
 		UPDATE comments SET content = $2, updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, record_id, user_id, content, parent_id, is_resolved, created_at, updated_at
+		RETURNING id, record_id, user_id, content, parent_id, automation_id, is_resolved, created_at, updated_at
 	`, commentID, content).Scan(
-		&comment.ID, &comment.RecordID, &comment.UserID, &comment.Content, &comment.ParentID, &comment.IsResolved, &comment.CreatedAt, &comment.UpdatedAt,
+		&comment.ID, &comment.RecordID, &comment.UserID, &comment.Content, &comment.ParentID, &comment.AutomationID, &comment.IsResolved, &comment.CreatedAt, &comment.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+	s.notifyChange(ctx, ChangeUpdated, comment, userID)
 
 	return comment, nil
 }
@@ -247,6 +320,7 @@ func (s *CommentStore) DeleteComment(ctx context.Context, commentID uuid.UUID, u
 	if result.RowsAffected() == 0 {
 		return ErrNotFound
 	}
+	s.notifyChange(ctx, ChangeDeleted, comment, userID)
 	return nil
 }
 
@@ -262,13 +336,14 @@ func (s *CommentStore) ResolveComment(ctx context.Context, commentID uuid.UUID,
 	err = s.db.QueryRow(ctx, `
 		UPDATE comments SET is_resolved = $2, updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, record_id, user_id, content, parent_id, is_resolved, created_at, updated_at
+		RETURNING id, record_id, user_id, content, parent_id, automation_id, is_resolved, created_at, updated_at
 	`, commentID, resolved).Scan(
-		&comment.ID, &comment.RecordID, &comment.UserID, &comment.Content, &comment.ParentID, &comment.IsResolved, &comment.CreatedAt, &comment.UpdatedAt,
+		&comment.ID, &comment.RecordID, &comment.UserID, &comment.Content, &comment.ParentID, &comment.AutomationID, &comment.IsResolved, &comment.CreatedAt, &comment.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+	s.notifyChange(ctx, ChangeUpdated, comment, userID)
 
 	return comment, nil
 }
@@ -42,10 +42,10 @@ func TestCommentStore_GetComment(t *testing.T) {
 
 		// Query for comment
 		commentRows := pgxmock.NewRows([]string{
-			"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
+			"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
 			"u_id", "email", "name", "u_created_at", "u_updated_at",
 		}).AddRow(
-			commentID, recordID, userID, "Test comment", nil, false, now, now,
+			commentID, recordID, userID, "Test comment", nil, nil, false, now, now,
 			userID, "test@example.com", name, now, now,
 		)
 
@@ -124,10 +124,10 @@ func TestCommentStore_ListComments(t *testing.T) {
 
 		// List comments
 		rows := pgxmock.NewRows([]string{
-			"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
+			"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
 			"u_id", "email", "name", "u_created_at", "u_updated_at",
 		}).AddRow(
-			commentID, recordID, userID, "Test comment", nil, false, now, now,
+			commentID, recordID, userID, "Test comment", nil, nil, false, now, now,
 			userID, "test@example.com", name, now, now,
 		)
 
@@ -187,7 +187,7 @@ func TestCommentStore_ListComments(t *testing.T) {
 		mock.ExpectQuery("SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id").
 			WithArgs(recordID).
 			WillReturnRows(pgxmock.NewRows([]string{
-				"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
 				"u_id", "email", "name", "u_created_at", "u_updated_at",
 			}))
 
@@ -232,8 +232,8 @@ func TestCommentStore_CreateComment(t *testing.T) {
 		mock.ExpectQuery("INSERT INTO comments").
 			WithArgs(recordID, userID, "New comment", (*uuid.UUID)(nil)).
 			WillReturnRows(pgxmock.NewRows([]string{
-				"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
-			}).AddRow(commentID, recordID, userID, "New comment", nil, false, now, now))
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
+			}).AddRow(commentID, recordID, userID, "New comment", nil, nil, false, now, now))
 
 		// Fetch user info
 		mock.ExpectQuery("SELECT id, email, name, created_at, updated_at FROM users").
@@ -250,6 +250,55 @@ func TestCommentStore_CreateComment(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("invokes comment callback with table ID", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		baseStore := NewBaseStore(mock)
+		store := NewCommentStore(mock, baseStore, nil, nil)
+
+		recordID := uuid.New()
+		userID := uuid.New()
+		baseID := uuid.New()
+		tableID := uuid.New()
+		commentID := uuid.New()
+		now := time.Now().UTC()
+
+		var gotTableID uuid.UUID
+		var gotComment *models.Comment
+		store.SetCommentCallback(func(tableID uuid.UUID, comment *models.Comment, userID uuid.UUID) {
+			gotTableID, gotComment = tableID, comment
+		})
+
+		mock.ExpectQuery("SELECT t.base_id").
+			WithArgs(recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		mock.ExpectQuery("INSERT INTO comments").
+			WithArgs(recordID, userID, "Ping", (*uuid.UUID)(nil)).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
+			}).AddRow(commentID, recordID, userID, "Ping", nil, nil, false, now, now))
+		mock.ExpectQuery("SELECT id, email, name, created_at, updated_at FROM users").
+			WithArgs(userID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "email", "name", "created_at", "updated_at"}).
+				AddRow(userID, "test@example.com", strPtr("Test User"), now, now))
+		mock.ExpectQuery("SELECT table_id FROM records").
+			WithArgs(recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"table_id"}).AddRow(tableID))
+
+		_, err = store.CreateComment(ctx, recordID, userID, "Ping", nil)
+		require.NoError(t, err)
+		assert.Equal(t, tableID, gotTableID)
+		require.NotNil(t, gotComment)
+		assert.Equal(t, commentID, gotComment.ID)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("validates parent comment belongs to same record", func(t *testing.T) {
 		mock, err := pgxmock.NewPool()
 		require.NoError(t, err)
@@ -321,6 +370,71 @@ func TestCommentStore_CreateComment(t *testing.T) {
 	})
 }
 
+func TestCommentStore_CreateAutomationComment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("creates comment linked to the automation", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		baseStore := NewBaseStore(mock)
+		store := NewCommentStore(mock, baseStore, nil, nil)
+
+		recordID := uuid.New()
+		automationID := uuid.New()
+		userID := uuid.New()
+		baseID := uuid.New()
+		commentID := uuid.New()
+		now := time.Now().UTC()
+
+		called := false
+		store.SetCommentCallback(func(tableID uuid.UUID, comment *models.Comment, userID uuid.UUID) {
+			called = true
+		})
+
+		mock.ExpectQuery("SELECT t.base_id").
+			WithArgs(recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		mock.ExpectQuery("INSERT INTO comments").
+			WithArgs(recordID, userID, "Escalated", automationID).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
+			}).AddRow(commentID, recordID, userID, "Escalated", nil, &automationID, false, now, now))
+
+		comment, err := store.CreateAutomationComment(ctx, recordID, automationID, userID, "Escalated")
+		require.NoError(t, err)
+		assert.Equal(t, commentID, comment.ID)
+		require.NotNil(t, comment.AutomationID)
+		assert.Equal(t, automationID, *comment.AutomationID)
+		assert.False(t, called, "automation comments must not re-trigger comment automations")
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns ErrNotFound when record does not exist", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		baseStore := NewBaseStore(mock)
+		store := NewCommentStore(mock, baseStore, nil, nil)
+		recordID := uuid.New()
+
+		mock.ExpectQuery("SELECT t.base_id").
+			WithArgs(recordID).
+			WillReturnError(pgx.ErrNoRows)
+
+		_, err = store.CreateAutomationComment(ctx, recordID, uuid.New(), uuid.New(), "Hi")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestCommentStore_UpdateComment(t *testing.T) {
 	ctx := context.Background()
 
@@ -343,9 +457,9 @@ func TestCommentStore_UpdateComment(t *testing.T) {
 		mock.ExpectQuery("SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id").
 			WithArgs(commentID).
 			WillReturnRows(pgxmock.NewRows([]string{
-				"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
 				"u_id", "email", "name", "u_created_at", "u_updated_at",
-			}).AddRow(commentID, recordID, userID, "Old content", nil, false, now, now, userID, "test@example.com", name, now, now))
+			}).AddRow(commentID, recordID, userID, "Old content", nil, nil, false, now, now, userID, "test@example.com", name, now, now))
 
 		// Get base ID for record
 		mock.ExpectQuery("SELECT t.base_id").
@@ -361,8 +475,8 @@ func TestCommentStore_UpdateComment(t *testing.T) {
 		mock.ExpectQuery("UPDATE comments SET content").
 			WithArgs(commentID, "Updated content").
 			WillReturnRows(pgxmock.NewRows([]string{
-				"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
-			}).AddRow(commentID, recordID, userID, "Updated content", nil, false, now, now))
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
+			}).AddRow(commentID, recordID, userID, "Updated content", nil, nil, false, now, now))
 
 		comment, err := store.UpdateComment(ctx, commentID, userID, "Updated content")
 		require.NoError(t, err)
@@ -391,9 +505,9 @@ func TestCommentStore_UpdateComment(t *testing.T) {
 		mock.ExpectQuery("SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id").
 			WithArgs(commentID).
 			WillReturnRows(pgxmock.NewRows([]string{
-				"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
 				"u_id", "email", "name", "u_created_at", "u_updated_at",
-			}).AddRow(commentID, recordID, authorID, "Content", nil, false, now, now, authorID, "author@example.com", name, now, now))
+			}).AddRow(commentID, recordID, authorID, "Content", nil, nil, false, now, now, authorID, "author@example.com", name, now, now))
 
 		mock.ExpectQuery("SELECT t.base_id").
 			WithArgs(recordID).
@@ -433,9 +547,9 @@ func TestCommentStore_DeleteComment(t *testing.T) {
 		mock.ExpectQuery("SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id").
 			WithArgs(commentID).
 			WillReturnRows(pgxmock.NewRows([]string{
-				"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
 				"u_id", "email", "name", "u_created_at", "u_updated_at",
-			}).AddRow(commentID, recordID, userID, "Content", nil, false, now, now, userID, "test@example.com", name, now, now))
+			}).AddRow(commentID, recordID, userID, "Content", nil, nil, false, now, now, userID, "test@example.com", name, now, now))
 
 		mock.ExpectQuery("SELECT t.base_id").
 			WithArgs(recordID).
@@ -476,9 +590,9 @@ func TestCommentStore_DeleteComment(t *testing.T) {
 		mock.ExpectQuery("SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id").
 			WithArgs(commentID).
 			WillReturnRows(pgxmock.NewRows([]string{
-				"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
 				"u_id", "email", "name", "u_created_at", "u_updated_at",
-			}).AddRow(commentID, recordID, authorID, "Content", nil, false, now, now, authorID, "author@example.com", name, now, now))
+			}).AddRow(commentID, recordID, authorID, "Content", nil, nil, false, now, now, authorID, "author@example.com", name, now, now))
 
 		mock.ExpectQuery("SELECT t.base_id").
 			WithArgs(recordID).
@@ -532,9 +646,9 @@ func TestCommentStore_ResolveComment(t *testing.T) {
 		mock.ExpectQuery("SELECT c.id, c.record_id, c.user_id, c.content, c.parent_id").
 			WithArgs(commentID).
 			WillReturnRows(pgxmock.NewRows([]string{
-				"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
 				"u_id", "email", "name", "u_created_at", "u_updated_at",
-			}).AddRow(commentID, recordID, userID, "Content", nil, false, now, now, userID, "test@example.com", name, now, now))
+			}).AddRow(commentID, recordID, userID, "Content", nil, nil, false, now, now, userID, "test@example.com", name, now, now))
 
 		mock.ExpectQuery("SELECT t.base_id").
 			WithArgs(recordID).
@@ -548,8 +662,8 @@ func TestCommentStore_ResolveComment(t *testing.T) {
 		mock.ExpectQuery("UPDATE comments SET is_resolved").
 			WithArgs(commentID, true).
 			WillReturnRows(pgxmock.NewRows([]string{
-				"id", "record_id", "user_id", "content", "parent_id", "is_resolved", "created_at", "updated_at",
-			}).AddRow(commentID, recordID, userID, "Content", nil, true, now, now))
+				"id", "record_id", "user_id", "content", "parent_id", "automation_id", "is_resolved", "created_at", "updated_at",
+			}).AddRow(commentID, recordID, userID, "Content", nil, nil, true, now, now))
 
 		comment, err := store.ResolveComment(ctx, commentID, userID, true)
 		require.NoError(t, err)
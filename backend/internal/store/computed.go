@@ -217,30 +217,7 @@ func (s *ComputedFieldService) computeRollup(ctx context.Context, field models.F
 
 // getLinkedRecordIDs extracts record IDs from a linked record field value
 func (s *ComputedFieldService) getLinkedRecordIDs(value interface{}) []uuid.UUID {
-	var ids []uuid.UUID
-
-	switch v := value.(type) {
-	case []interface{}:
-		for _, item := range v {
-			if idStr, ok := item.(string); ok {
-				if id, err := uuid.Parse(idStr); err == nil {
-					ids = append(ids, id)
-				}
-			}
-		}
-	case []string:
-		for _, idStr := range v {
-			if id, err := uuid.Parse(idStr); err == nil {
-				ids = append(ids, id)
-			}
-		}
-	case string:
-		if id, err := uuid.Parse(v); err == nil {
-			ids = append(ids, id)
-		}
-	}
-
-	return ids
+	return models.LinkedRecordIDs(value)
 }
 
 // fetchLookupValues fetches field values from a list of records
@@ -12,10 +12,11 @@ import (
 )
 
 type FieldStore struct {
-	db         DBTX
-	baseStore  *BaseStore
-	tableStore *TableStore
-	hub        *realtime.Hub
+	db             DBTX
+	baseStore      *BaseStore
+	tableStore     *TableStore
+	hub            *realtime.Hub
+	changeCallback ChangeCallback
 }
 
 func NewFieldStore(db DBTX, baseStore *BaseStore, tableStore *TableStore) *FieldStore {
@@ -27,6 +28,11 @@ func (s *FieldStore) SetHub(hub *realtime.Hub) {
 	s.hub = hub
 }
 
+// SetChangeCallback sets the callback for field changes
+func (s *FieldStore) SetChangeCallback(cb ChangeCallback) {
+	s.changeCallback = cb
+}
+
 // getBaseIDForTable returns the base ID for a table
 func (s *FieldStore) getBaseIDForTable(ctx context.Context, tableID uuid.UUID) (uuid.UUID, error) {
 	var baseID uuid.UUID
@@ -125,6 +131,9 @@ func (s *FieldStore) CreateField(ctx context.Context, tableID uuid.UUID, name st
 			WithPayload(f)
 		s.hub.Broadcast(msg)
 	}
+	if s.changeCallback != nil {
+		s.changeCallback(baseID, tableID, ChangeCreated, &f, userID)
+	}
 
 	return &f, nil
 }
@@ -205,6 +214,9 @@ func (s *FieldStore) UpdateField(ctx context.Context, fieldID uuid.UUID, name *s
 			WithPayload(f)
 		s.hub.Broadcast(msg)
 	}
+	if s.changeCallback != nil {
+		s.changeCallback(baseID, f.TableID, ChangeUpdated, f, userID)
+	}
 
 	return f, nil
 }
@@ -249,6 +261,9 @@ func (s *FieldStore) DeleteField(ctx context.Context, fieldID uuid.UUID, userID
 			})
 		s.hub.Broadcast(msg)
 	}
+	if s.changeCallback != nil {
+		s.changeCallback(baseID, f.TableID, ChangeDeleted, f, userID)
+	}
 
 	return nil
 }
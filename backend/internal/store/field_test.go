@@ -535,6 +535,53 @@ func TestFieldStore_DeleteField(t *testing.T) {
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("reports the deleted field to the change callback", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		baseStore := NewBaseStore(mock)
+		store := NewFieldStore(mock, baseStore, NewTableStore(mock, baseStore))
+		userID := uuid.New()
+		baseID := uuid.New()
+		tableID := uuid.New()
+		fieldID := uuid.New()
+		now := time.Now().UTC()
+
+		var gotChange string
+		var gotField *models.Field
+		store.SetChangeCallback(func(b, tbl uuid.UUID, change string, entity interface{}, u uuid.UUID) {
+			assert.Equal(t, baseID, b)
+			assert.Equal(t, tableID, tbl)
+			assert.Equal(t, userID, u)
+			gotChange = change
+			gotField, _ = entity.(*models.Field)
+		})
+
+		mock.ExpectQuery("SELECT id, table_id, name, field_type, options, position, created_at, updated_at").
+			WithArgs(fieldID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "name", "field_type", "options", "position", "created_at", "updated_at"}).
+				AddRow(fieldID, tableID, "Name", models.FieldTypeText, json.RawMessage(`{}`), 0, now, now))
+		for i := 0; i < 2; i++ {
+			mock.ExpectQuery("SELECT base_id FROM tables WHERE id").
+				WithArgs(tableID).
+				WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+			mock.ExpectQuery("SELECT role FROM base_collaborators").
+				WithArgs(baseID, userID).
+				WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleOwner))
+		}
+		mock.ExpectExec("DELETE FROM fields WHERE id").
+			WithArgs(fieldID).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		require.NoError(t, store.DeleteField(ctx, fieldID, userID))
+		assert.Equal(t, ChangeDeleted, gotChange)
+		require.NotNil(t, gotField)
+		assert.Equal(t, fieldID, gotField.ID)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestFieldStore_ReorderFields(t *testing.T) {
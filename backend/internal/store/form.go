@@ -12,11 +12,15 @@ import (
 	"github.com/vibetable/backend/internal/models"
 )
 
+// FormSubmitCallback is called after a public form submission creates a record
+type FormSubmitCallback func(formID uuid.UUID, tableID uuid.UUID, record *models.Record)
+
 type FormStore struct {
-	db          DBTX
-	baseStore   *BaseStore
-	tableStore  *TableStore
-	recordStore *RecordStore
+	db             DBTX
+	baseStore      *BaseStore
+	tableStore     *TableStore
+	recordStore    *RecordStore
+	submitCallback FormSubmitCallback
 }
 
 func NewFormStore(db DBTX, baseStore *BaseStore, tableStore *TableStore, recordStore *RecordStore) *FormStore {
@@ -28,6 +32,11 @@ func NewFormStore(db DBTX, baseStore *BaseStore, tableStore *TableStore, recordS
 	}
 }
 
+// SetSubmitCallback sets the callback for public form submissions
+func (s *FormStore) SetSubmitCallback(cb FormSubmitCallback) {
+	s.submitCallback = cb
+}
+
 func generateToken() string {
 	b := make([]byte, 32)
 	rand.Read(b)
@@ -503,5 +512,10 @@ func (s *FormStore) SubmitPublicForm(ctx context.Context, token string, values m
 		return nil, err
 	}
 
+	// Trigger form submission automations
+	if s.submitCallback != nil {
+		s.submitCallback(formID, tableID, &r)
+	}
+
 	return &r, nil
 }
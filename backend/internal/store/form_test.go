@@ -451,6 +451,50 @@ func TestFormStore_SubmitPublicForm(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("invokes submit callback with form and record", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewFormStore(mock, nil, nil, nil)
+
+		formID := uuid.New()
+		tableID := uuid.New()
+		recordID := uuid.New()
+		now := time.Now().UTC()
+
+		var gotFormID, gotTableID uuid.UUID
+		var gotRecord *models.Record
+		store.SetSubmitCallback(func(formID uuid.UUID, tableID uuid.UUID, record *models.Record) {
+			gotFormID, gotTableID, gotRecord = formID, tableID, record
+		})
+
+		mock.ExpectQuery("SELECT id, table_id, is_active FROM forms WHERE public_token").
+			WithArgs("token").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "is_active"}).
+				AddRow(formID, tableID, true))
+		mock.ExpectQuery("SELECT field_id, is_required FROM form_fields").
+			WithArgs(formID).
+			WillReturnRows(pgxmock.NewRows([]string{"field_id", "is_required"}))
+		mock.ExpectQuery("SELECT COALESCE").
+			WithArgs(tableID).
+			WillReturnRows(pgxmock.NewRows([]string{"max"}).AddRow(-1))
+		mock.ExpectQuery("INSERT INTO records").
+			WithArgs(tableID, []byte(`{}`), 0).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "table_id", "values", "position", "color", "created_at", "updated_at",
+			}).AddRow(recordID, tableID, json.RawMessage(`{}`), 0, nil, now, now))
+
+		_, err = store.SubmitPublicForm(ctx, "token", map[string]interface{}{})
+		require.NoError(t, err)
+		assert.Equal(t, formID, gotFormID)
+		assert.Equal(t, tableID, gotTableID)
+		require.NotNil(t, gotRecord)
+		assert.Equal(t, recordID, gotRecord.ID)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("returns error when form is inactive", func(t *testing.T) {
 		mock, err := pgxmock.NewPool()
 		require.NoError(t, err)
@@ -0,0 +1,297 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/realtime"
+)
+
+var ErrJobFinished = errors.New("job has already finished")
+
+// progressInterval is how many records operations copy or create between reports
+// of their progress
+const progressInterval = 100
+
+// ProgressFunc is told how many steps of a long operation are done, out of total
+type ProgressFunc func(done, total int)
+
+func (p ProgressFunc) report(done, total int) {
+	if p != nil {
+		p(done, total)
+	}
+}
+
+// JobStore queues long-running operations for the job worker and tracks them
+type JobStore struct {
+	db        DBTX
+	baseStore *BaseStore
+	hub       *realtime.Hub
+	notify    func()
+}
+
+func NewJobStore(db DBTX, baseStore *BaseStore) *JobStore {
+	return &JobStore{
+		db:        db,
+		baseStore: baseStore,
+	}
+}
+
+// SetHub sets the realtime hub that job progress is announced through
+func (s *JobStore) SetHub(hub *realtime.Hub) {
+	s.hub = hub
+}
+
+// SetNotifier sets a function called when a job is queued, so the worker can pick
+// it up without waiting for its next poll
+func (s *JobStore) SetNotifier(notify func()) {
+	s.notify = notify
+}
+
+const jobColumns = `
+	id, base_id, job_type, status, payload, progress, total, result, error,
+	cancel_requested, attempts, created_by, created_at, started_at, finished_at, updated_at`
+
+func scanJob(row pgx.Row) (*models.Job, error) {
+	var j models.Job
+	err := row.Scan(
+		&j.ID, &j.BaseID, &j.JobType, &j.Status, &j.Payload, &j.Progress, &j.Total, &j.Result, &j.Error,
+		&j.CancelRequested, &j.Attempts, &j.CreatedBy, &j.CreatedAt, &j.StartedAt, &j.FinishedAt, &j.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// CreateJob queues a job on a base. Jobs change their base, so the user needs
+// edit access; the job runs as them and checks their access again when it runs.
+func (s *JobStore) CreateJob(ctx context.Context, baseID uuid.UUID, jobType models.JobType, payload interface{}, userID uuid.UUID) (*models.Job, error) {
+	role, err := s.baseStore.GetUserRole(ctx, baseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanEdit() {
+		return nil, ErrForbidden
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job, err := scanJob(s.db.QueryRow(ctx, `
+		INSERT INTO jobs (base_id, job_type, payload, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+jobColumns,
+		baseID, jobType, data, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	s.announceJob(job)
+	if s.notify != nil {
+		s.notify()
+	}
+	return job, nil
+}
+
+// CreateTableJob queues a job on the base of a table
+func (s *JobStore) CreateTableJob(ctx context.Context, tableID uuid.UUID, jobType models.JobType, payload interface{}, userID uuid.UUID) (*models.Job, error) {
+	var baseID uuid.UUID
+	err := s.db.QueryRow(ctx, `SELECT base_id FROM tables WHERE id = $1`, tableID).Scan(&baseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.CreateJob(ctx, baseID, jobType, payload, userID)
+}
+
+// GetJob gets a job for any collaborator on its base
+func (s *JobStore) GetJob(ctx context.Context, jobID, userID uuid.UUID) (*models.Job, error) {
+	job, _, err := s.getJob(ctx, jobID, userID)
+	return job, err
+}
+
+// getJob gets a job with the user's role on its base
+func (s *JobStore) getJob(ctx context.Context, jobID, userID uuid.UUID) (*models.Job, models.CollaboratorRole, error) {
+	job, err := scanJob(s.db.QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, jobID))
+	if err != nil {
+		return nil, "", err
+	}
+	role, err := s.baseStore.GetUserRole(ctx, job.BaseID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	return job, role, nil
+}
+
+// ListJobsForBase lists a base's most recent jobs, newest first
+func (s *JobStore) ListJobsForBase(ctx context.Context, baseID, userID uuid.UUID, limit int) ([]models.Job, error) {
+	if _, err := s.baseStore.GetUserRole(ctx, baseID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT `+jobColumns+` FROM jobs
+		WHERE base_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, baseID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// CancelJob cancels a job. A queued job is cancelled straight away; a running one
+// is asked to stop, and is cancelled once its worker notices. The user who queued
+// the job and the base's editors can cancel it.
+func (s *JobStore) CancelJob(ctx context.Context, jobID, userID uuid.UUID) (*models.Job, error) {
+	job, role, err := s.getJob(ctx, jobID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if job.CreatedBy != userID && !role.CanEdit() {
+		return nil, ErrForbidden
+	}
+
+	job, err = scanJob(s.db.QueryRow(ctx, `
+		UPDATE jobs SET
+			status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+			finished_at = CASE WHEN status = 'queued' THEN NOW() ELSE finished_at END,
+			cancel_requested = TRUE,
+			updated_at = NOW()
+		WHERE id = $1 AND status IN ('queued', 'running')
+		RETURNING `+jobColumns,
+		jobID))
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrJobFinished
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.announceJob(job)
+	return job, nil
+}
+
+// ClaimJob takes the oldest queued job for a worker to run, holding it for lease.
+// Running jobs whose lease has run out are taken again, as their worker has gone.
+// It returns nil when there is nothing to run.
+func (s *JobStore) ClaimJob(ctx context.Context, lease time.Duration) (*models.Job, error) {
+	job, err := scanJob(s.db.QueryRow(ctx, `
+		UPDATE jobs SET
+			status = 'running',
+			attempts = attempts + 1,
+			started_at = COALESCE(started_at, NOW()),
+			locked_until = NOW() + $1 * INTERVAL '1 second',
+			updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'queued' OR (status = 'running' AND locked_until < NOW())
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns,
+		lease.Seconds()))
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.announceJob(job)
+	return job, nil
+}
+
+// UpdateJobProgress records a running job's progress and extends its lease. The
+// returned job tells the worker whether it has been asked to stop.
+func (s *JobStore) UpdateJobProgress(ctx context.Context, jobID uuid.UUID, progress, total int, lease time.Duration) (*models.Job, error) {
+	job, err := scanJob(s.db.QueryRow(ctx, `
+		UPDATE jobs SET
+			progress = $2,
+			total = $3,
+			locked_until = NOW() + $4 * INTERVAL '1 second',
+			updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+		RETURNING `+jobColumns,
+		jobID, progress, total, lease.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	s.announceJob(job)
+	return job, nil
+}
+
+// FinishJob records how a running job ended, as set on job: its status, final
+// progress, and its result if it succeeded or its error if it failed
+func (s *JobStore) FinishJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	finished, err := scanJob(s.db.QueryRow(ctx, `
+		UPDATE jobs SET
+			status = $2,
+			progress = $3,
+			total = $4,
+			result = $5,
+			error = $6,
+			locked_until = NULL,
+			finished_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+		RETURNING `+jobColumns,
+		job.ID, job.Status, job.Progress, job.Total, job.Result, job.Error))
+	if err != nil {
+		return nil, err
+	}
+
+	s.announceJob(finished)
+	return finished, nil
+}
+
+// DeleteFinishedJobs removes jobs that finished before cutoff, returning how many
+func (s *JobStore) DeleteFinishedJobs(ctx context.Context, cutoff time.Time) (int, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM jobs WHERE finished_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (s *JobStore) announceJob(job *models.Job) {
+	if s.hub == nil {
+		return
+	}
+	msg := realtime.NewMessage(realtime.MsgTypeJobProgress, job.BaseID, job.CreatedBy).
+		WithPayload(realtime.JobProgress{
+			JobID:    job.ID,
+			JobType:  string(job.JobType),
+			Status:   string(job.Status),
+			Progress: job.Progress,
+			Total:    job.Total,
+			Result:   job.Result,
+			Error:    job.Error,
+		})
+	s.hub.Announce(msg)
+}
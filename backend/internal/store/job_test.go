@@ -0,0 +1,278 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+)
+
+var jobRowColumns = []string{
+	"id", "base_id", "job_type", "status", "payload", "progress", "total", "result", "error",
+	"cancel_requested", "attempts", "created_by", "created_at", "started_at", "finished_at", "updated_at",
+}
+
+func jobRows(jobs ...models.Job) *pgxmock.Rows {
+	rows := pgxmock.NewRows(jobRowColumns)
+	for _, j := range jobs {
+		rows.AddRow(j.ID, j.BaseID, j.JobType, j.Status, j.Payload, j.Progress, j.Total, j.Result, j.Error,
+			j.CancelRequested, j.Attempts, j.CreatedBy, j.CreatedAt, j.StartedAt, j.FinishedAt, j.UpdatedAt)
+	}
+	return rows
+}
+
+func testJob(status models.JobStatus) models.Job {
+	now := time.Now().UTC()
+	return models.Job{
+		ID:        uuid.New(),
+		BaseID:    uuid.New(),
+		JobType:   models.JobDuplicateBase,
+		Status:    status,
+		Payload:   json.RawMessage(`{}`),
+		CreatedBy: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestProgressFunc_Report(t *testing.T) {
+	t.Run("ignores a nil func", func(t *testing.T) {
+		var progress ProgressFunc
+		assert.NotPanics(t, func() { progress.report(1, 2) })
+	})
+
+	t.Run("passes on progress", func(t *testing.T) {
+		var done, total int
+		progress := ProgressFunc(func(d, t int) { done, total = d, t })
+		progress.report(3, 10)
+		assert.Equal(t, 3, done)
+		assert.Equal(t, 10, total)
+	})
+}
+
+func TestJobStore_CreateJob(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("requires edit access", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewJobStore(mock, NewBaseStore(mock))
+		baseID, userID := uuid.New(), uuid.New()
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleViewer))
+
+		_, err = store.CreateJob(ctx, baseID, models.JobDuplicateBase, models.DuplicateBasePayload{}, userID)
+		assert.ErrorIs(t, err, ErrForbidden)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("queues the job and wakes the worker", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewJobStore(mock, NewBaseStore(mock))
+		notified := false
+		store.SetNotifier(func() { notified = true })
+		job := testJob(models.JobQueued)
+
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(job.BaseID, job.CreatedBy).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		mock.ExpectQuery("INSERT INTO jobs").
+			WithArgs(job.BaseID, models.JobDuplicateBase, []byte(`{"include_records":true}`), job.CreatedBy).
+			WillReturnRows(jobRows(job))
+
+		created, err := store.CreateJob(ctx, job.BaseID, models.JobDuplicateBase, models.DuplicateBasePayload{IncludeRecords: true}, job.CreatedBy)
+		require.NoError(t, err)
+		assert.Equal(t, job.ID, created.ID)
+		assert.True(t, notified)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestJobStore_CreateTableJob(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns not found for missing table", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewJobStore(mock, NewBaseStore(mock))
+		tableID := uuid.New()
+		mock.ExpectQuery("SELECT base_id FROM tables").
+			WithArgs(tableID).
+			WillReturnError(pgx.ErrNoRows)
+
+		_, err = store.CreateTableJob(ctx, tableID, models.JobDuplicateTable, models.DuplicateTablePayload{TableID: tableID}, uuid.New())
+		assert.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestJobStore_GetJob(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("hides jobs on other bases", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewJobStore(mock, NewBaseStore(mock))
+		job := testJob(models.JobRunning)
+		userID := uuid.New()
+		mock.ExpectQuery("FROM jobs WHERE id").
+			WithArgs(job.ID).
+			WillReturnRows(jobRows(job))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(job.BaseID, userID).
+			WillReturnError(pgx.ErrNoRows)
+
+		_, err = store.GetJob(ctx, job.ID, userID)
+		assert.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestJobStore_CancelJob(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, job models.Job, userID uuid.UUID, role models.CollaboratorRole) (pgxmock.PgxPoolIface, *JobStore) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+
+		mock.ExpectQuery("FROM jobs WHERE id").
+			WithArgs(job.ID).
+			WillReturnRows(jobRows(job))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(job.BaseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(role))
+		return mock, NewJobStore(mock, NewBaseStore(mock))
+	}
+
+	t.Run("viewers cannot cancel other users' jobs", func(t *testing.T) {
+		job := testJob(models.JobRunning)
+		userID := uuid.New()
+		mock, store := setup(t, job, userID, models.RoleViewer)
+
+		_, err := store.CancelJob(ctx, job.ID, userID)
+		assert.ErrorIs(t, err, ErrForbidden)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("asks a running job to stop", func(t *testing.T) {
+		job := testJob(models.JobRunning)
+		mock, store := setup(t, job, job.CreatedBy, models.RoleViewer)
+		cancelled := job
+		cancelled.CancelRequested = true
+		mock.ExpectQuery("UPDATE jobs SET").
+			WithArgs(job.ID).
+			WillReturnRows(jobRows(cancelled))
+
+		result, err := store.CancelJob(ctx, job.ID, job.CreatedBy)
+		require.NoError(t, err)
+		assert.True(t, result.CancelRequested)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns an error for finished jobs", func(t *testing.T) {
+		job := testJob(models.JobSucceeded)
+		mock, store := setup(t, job, job.CreatedBy, models.RoleEditor)
+		mock.ExpectQuery("UPDATE jobs SET").
+			WithArgs(job.ID).
+			WillReturnError(pgx.ErrNoRows)
+
+		_, err := store.CancelJob(ctx, job.ID, job.CreatedBy)
+		assert.ErrorIs(t, err, ErrJobFinished)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestJobStore_ClaimJob(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns nil when nothing is queued", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewJobStore(mock, nil)
+		mock.ExpectQuery("UPDATE jobs SET").
+			WithArgs(float64(60)).
+			WillReturnError(pgx.ErrNoRows)
+
+		job, err := store.ClaimJob(ctx, time.Minute)
+		require.NoError(t, err)
+		assert.Nil(t, job)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("claims the oldest job", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewJobStore(mock, nil)
+		job := testJob(models.JobRunning)
+		job.Attempts = 1
+		mock.ExpectQuery("FOR UPDATE SKIP LOCKED").
+			WithArgs(float64(60)).
+			WillReturnRows(jobRows(job))
+
+		claimed, err := store.ClaimJob(ctx, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, job.ID, claimed.ID)
+		assert.Equal(t, 1, claimed.Attempts)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestJobStore_FinishJob(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	store := NewJobStore(mock, nil)
+	job := testJob(models.JobSucceeded)
+	job.Progress, job.Total = 3, 3
+	job.Result = json.RawMessage(`{"id": "x"}`)
+	mock.ExpectQuery("UPDATE jobs SET").
+		WithArgs(job.ID, models.JobSucceeded, 3, 3, job.Result, (*string)(nil)).
+		WillReturnRows(jobRows(job))
+
+	finished, err := store.FinishJob(ctx, &job)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobSucceeded, finished.Status)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobStore_DeleteFinishedJobs(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	store := NewJobStore(mock, nil)
+	cutoff := time.Now().Add(-time.Hour)
+	mock.ExpectExec("DELETE FROM jobs").
+		WithArgs(cutoff).
+		WillReturnResult(pgxmock.NewResult("DELETE", 4))
+
+	removed, err := store.DeleteFinishedJobs(ctx, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 4, removed)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
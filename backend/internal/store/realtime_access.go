@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// RealtimeAuthorizer is a realtime.Authorizer that re-checks connections against
+// their session and base role
+type RealtimeAuthorizer struct {
+	db DBTX
+}
+
+func NewRealtimeAuthorizer(db DBTX) *RealtimeAuthorizer {
+	return &RealtimeAuthorizer{db: db}
+}
+
+// Authorize returns the user's role in the base while the session is valid, or "" once
+// the session has ended or the user is no longer a collaborator
+func (a *RealtimeAuthorizer) Authorize(ctx context.Context, sessionID, baseID, userID uuid.UUID) (string, error) {
+	var role string
+	err := a.db.QueryRow(ctx, `
+		SELECT bc.role
+		FROM sessions s
+		JOIN base_collaborators bc ON bc.user_id = s.user_id
+		WHERE s.id = $1 AND s.user_id = $3 AND s.expires_at > NOW() AND bc.base_id = $2
+	`, sessionID, baseID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
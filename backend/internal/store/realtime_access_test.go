@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealtimeAuthorizer_Authorize(t *testing.T) {
+	ctx := context.Background()
+	sessionID, baseID, userID := uuid.New(), uuid.New(), uuid.New()
+
+	t.Run("returns the role for a valid session", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery("SELECT bc.role").
+			WithArgs(sessionID, baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow("editor"))
+
+		role, err := NewRealtimeAuthorizer(mock).Authorize(ctx, sessionID, baseID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, "editor", role)
+	})
+
+	t.Run("returns no role once the session or access is gone", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery("SELECT bc.role").
+			WithArgs(sessionID, baseID, userID).
+			WillReturnError(pgx.ErrNoRows)
+
+		role, err := NewRealtimeAuthorizer(mock).Authorize(ctx, sessionID, baseID, userID)
+		require.NoError(t, err)
+		assert.Empty(t, role)
+	})
+
+	t.Run("returns database errors", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery("SELECT bc.role").
+			WithArgs(sessionID, baseID, userID).
+			WillReturnError(errors.New("connection lost"))
+
+		_, err = NewRealtimeAuthorizer(mock).Authorize(ctx, sessionID, baseID, userID)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vibetable/backend/internal/realtime"
+)
+
+// realtimeEventRetention is how long messages stay available for replay
+const realtimeEventRetention = "1 hour"
+
+// RealtimeEventLog is a realtime.EventLog shared by all instances
+type RealtimeEventLog struct {
+	db DBTX
+}
+
+func NewRealtimeEventLog(db DBTX) *RealtimeEventLog {
+	return &RealtimeEventLog{db: db}
+}
+
+// Append assigns the message the base's next sequence number and stores it
+func (l *RealtimeEventLog) Append(ctx context.Context, msg *realtime.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return l.db.QueryRow(ctx, `
+		WITH next AS (
+			INSERT INTO realtime_sequences (base_id, last_seq)
+			VALUES ($1, 1)
+			ON CONFLICT (base_id) DO UPDATE SET last_seq = realtime_sequences.last_seq + 1
+			RETURNING last_seq
+		)
+		INSERT INTO realtime_events (base_id, seq, message)
+		SELECT $1, last_seq, $2 FROM next
+		RETURNING seq
+	`, msg.BaseID, data).Scan(&msg.Seq)
+}
+
+// Since returns up to limit messages after seq, oldest first
+func (l *RealtimeEventLog) Since(ctx context.Context, baseID uuid.UUID, seq int64, limit int) ([]*realtime.Message, error) {
+	rows, err := l.db.Query(ctx, `
+		SELECT seq, message FROM realtime_events
+		WHERE base_id = $1 AND seq > $2
+		ORDER BY seq
+		LIMIT $3
+	`, baseID, seq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*realtime.Message
+	for rows.Next() {
+		var msgSeq int64
+		var data []byte
+		if err := rows.Scan(&msgSeq, &data); err != nil {
+			return nil, err
+		}
+		var msg realtime.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+		msg.Seq = msgSeq
+		messages = append(messages, &msg)
+	}
+	return messages, rows.Err()
+}
+
+// Latest returns the base's last sequence number, or 0 if it has none
+func (l *RealtimeEventLog) Latest(ctx context.Context, baseID uuid.UUID) (int64, error) {
+	var seq int64
+	err := l.db.QueryRow(ctx, `SELECT last_seq FROM realtime_sequences WHERE base_id = $1`, baseID).Scan(&seq)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return seq, err
+}
+
+// CleanupExpiredEvents removes messages too old to replay
+func (l *RealtimeEventLog) CleanupExpiredEvents(ctx context.Context) error {
+	_, err := l.db.Exec(ctx, `DELETE FROM realtime_events WHERE created_at < NOW() - INTERVAL '`+realtimeEventRetention+`'`)
+	return err
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/realtime"
+)
+
+func TestRealtimeEventLog_Append(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	baseID := uuid.New()
+	msg := realtime.NewMessage(realtime.MsgTypeRecordUpdated, baseID, uuid.New())
+
+	mock.ExpectQuery("INSERT INTO realtime_sequences").
+		WithArgs(baseID, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"seq"}).AddRow(int64(42)))
+
+	require.NoError(t, NewRealtimeEventLog(mock).Append(context.Background(), msg))
+	assert.Equal(t, int64(42), msg.Seq)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRealtimeEventLog_Since(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	baseID := uuid.New()
+	recordID := uuid.New()
+	data, err := json.Marshal(realtime.NewMessage(realtime.MsgTypeRecordUpdated, baseID, uuid.New()).WithRecord(recordID))
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT seq, message FROM realtime_events").
+		WithArgs(baseID, int64(5), 100).
+		WillReturnRows(pgxmock.NewRows([]string{"seq", "message"}).
+			AddRow(int64(6), data).
+			AddRow(int64(7), data))
+
+	messages, err := NewRealtimeEventLog(mock).Since(context.Background(), baseID, 5, 100)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, int64(6), messages[0].Seq)
+	assert.Equal(t, int64(7), messages[1].Seq)
+	assert.Equal(t, recordID, *messages[0].RecordID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRealtimeEventLog_Latest(t *testing.T) {
+	ctx := context.Background()
+	baseID := uuid.New()
+
+	t.Run("returns the last sequence number", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery("SELECT last_seq FROM realtime_sequences").
+			WithArgs(baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"last_seq"}).AddRow(int64(9)))
+
+		seq, err := NewRealtimeEventLog(mock).Latest(ctx, baseID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), seq)
+	})
+
+	t.Run("returns 0 for a base without messages", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery("SELECT last_seq FROM realtime_sequences").
+			WithArgs(baseID).
+			WillReturnError(pgx.ErrNoRows)
+
+		seq, err := NewRealtimeEventLog(mock).Latest(ctx, baseID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), seq)
+	})
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vibetable/backend/internal/realtime"
+)
+
+const (
+	// realtimeChannel is the NOTIFY channel hub traffic is relayed on
+	realtimeChannel = "vibetable_realtime"
+
+	// maxNotifyPayload keeps payloads under Postgres' 8000 byte NOTIFY limit;
+	// larger envelopes are spilled to a table and only their ID is sent
+	maxNotifyPayload = 7900
+
+	spillPrefix    = "spill:"
+	spillRetention = "5 minutes"
+)
+
+// RealtimeRelay is a realtime.Backend that relays hub traffic between instances
+// through Postgres LISTEN/NOTIFY
+type RealtimeRelay struct {
+	db   DBTX
+	pool *pgxpool.Pool // Provides the dedicated LISTEN connection
+}
+
+func NewRealtimeRelay(pool *pgxpool.Pool) *RealtimeRelay {
+	return &RealtimeRelay{db: pool, pool: pool}
+}
+
+// Publish sends an envelope to every instance
+func (r *RealtimeRelay) Publish(ctx context.Context, env *realtime.Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	payload := string(data)
+	if len(data) > maxNotifyPayload {
+		var id uuid.UUID
+		err := r.db.QueryRow(ctx, `INSERT INTO realtime_spill (payload) VALUES ($1) RETURNING id`, data).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to spill realtime message: %w", err)
+		}
+		payload = spillPrefix + id.String()
+	}
+
+	_, err = r.db.Exec(ctx, `SELECT pg_notify($1, $2)`, realtimeChannel, payload)
+	return err
+}
+
+// Listen delivers envelopes from all instances until ctx is done or the
+// connection fails
+func (r *RealtimeRelay) Listen(ctx context.Context, deliver func(*realtime.Envelope)) error {
+	pooled, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection stays subscribed, so it is not returned to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+realtimeChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		env, err := r.decode(ctx, notification.Payload)
+		if err != nil {
+			// A single bad message should not stop the relay
+			log.Printf("Failed to decode realtime message: %v", err)
+			continue
+		}
+		deliver(env)
+	}
+}
+
+// decode reads a notification payload, loading spilled envelopes from the table
+func (r *RealtimeRelay) decode(ctx context.Context, payload string) (*realtime.Envelope, error) {
+	data := []byte(payload)
+	if idStr, ok := strings.CutPrefix(payload, spillPrefix); ok {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.db.QueryRow(ctx, `SELECT payload FROM realtime_spill WHERE id = $1`, id).Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to load spilled realtime message: %w", err)
+		}
+	}
+
+	var env realtime.Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// CleanupSpill removes spilled messages every instance has had time to read
+func (r *RealtimeRelay) CleanupSpill(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM realtime_spill WHERE created_at < NOW() - INTERVAL '`+spillRetention+`'`)
+	return err
+}
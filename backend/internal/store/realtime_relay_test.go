@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/realtime"
+)
+
+func TestRealtimeRelay_Publish(t *testing.T) {
+	ctx := context.Background()
+	baseID := uuid.New()
+
+	t.Run("sends small envelopes in the notification", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		relay := &RealtimeRelay{db: mock}
+		env := &realtime.Envelope{Origin: uuid.New(), Kind: realtime.EnvelopeMessage, BaseID: baseID,
+			Message: realtime.NewMessage(realtime.MsgTypeRecordCreated, baseID, uuid.New())}
+		data, err := json.Marshal(env)
+		require.NoError(t, err)
+
+		mock.ExpectExec("SELECT pg_notify").
+			WithArgs(realtimeChannel, string(data)).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+		require.NoError(t, relay.Publish(ctx, env))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("spills large envelopes to a table", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		relay := &RealtimeRelay{db: mock}
+		env := &realtime.Envelope{Kind: realtime.EnvelopeMessage, BaseID: baseID,
+			Message: realtime.NewMessage(realtime.MsgTypeRecordUpdated, baseID, uuid.New()).
+				WithPayload(map[string]string{"notes": strings.Repeat("x", 10000)})}
+		spillID := uuid.New()
+
+		mock.ExpectQuery("INSERT INTO realtime_spill").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(spillID))
+		mock.ExpectExec("SELECT pg_notify").
+			WithArgs(realtimeChannel, spillPrefix+spillID.String()).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+		require.NoError(t, relay.Publish(ctx, env))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRealtimeRelay_decode(t *testing.T) {
+	ctx := context.Background()
+	env := &realtime.Envelope{Origin: uuid.New(), Kind: realtime.EnvelopePresence, BaseID: uuid.New(),
+		Presence: []*realtime.UserPresence{{UserID: uuid.New(), Email: "user@example.com"}}}
+	data, err := json.Marshal(env)
+	require.NoError(t, err)
+
+	t.Run("decodes inline envelopes", func(t *testing.T) {
+		got, err := (&RealtimeRelay{}).decode(ctx, string(data))
+		require.NoError(t, err)
+		assert.Equal(t, env.Origin, got.Origin)
+		require.Len(t, got.Presence, 1)
+		assert.Equal(t, "user@example.com", got.Presence[0].Email)
+	})
+
+	t.Run("loads spilled envelopes", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		spillID := uuid.New()
+		mock.ExpectQuery("SELECT payload FROM realtime_spill").
+			WithArgs(spillID).
+			WillReturnRows(pgxmock.NewRows([]string{"payload"}).AddRow(data))
+
+		got, err := (&RealtimeRelay{db: mock}).decode(ctx, spillPrefix+spillID.String())
+		require.NoError(t, err)
+		assert.Equal(t, env.BaseID, got.BaseID)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects malformed payloads", func(t *testing.T) {
+		_, err := (&RealtimeRelay{}).decode(ctx, "not json")
+		assert.Error(t, err)
+		_, err = (&RealtimeRelay{}).decode(ctx, spillPrefix+"not-a-uuid")
+		assert.Error(t, err)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"reflect"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -11,6 +12,9 @@ import (
 	"github.com/vibetable/backend/internal/realtime"
 )
 
+// ErrCellLocked is returned when a write touches a cell another user is editing
+var ErrCellLocked = errors.New("cell is being edited by another user")
+
 // AutomationCallback is called when records change to trigger automations
 type AutomationCallback func(tableID uuid.UUID, recordID *uuid.UUID, record *models.Record, oldRecord *models.Record, triggerType string, userID uuid.UUID)
 
@@ -52,6 +56,22 @@ func (s *RecordStore) getBaseIDForTable(ctx context.Context, tableID uuid.UUID)
 	return baseID, err
 }
 
+// CheckEditAccess returns ErrForbidden unless the user can edit records in the table
+func (s *RecordStore) CheckEditAccess(ctx context.Context, tableID uuid.UUID, userID uuid.UUID) error {
+	baseID, err := s.getBaseIDForTable(ctx, tableID)
+	if err != nil {
+		return err
+	}
+	role, err := s.baseStore.GetUserRole(ctx, baseID, userID)
+	if err != nil {
+		return err
+	}
+	if !role.CanEdit() {
+		return ErrForbidden
+	}
+	return nil
+}
+
 // ListRecordsForTable returns all records in a table
 func (s *RecordStore) ListRecordsForTable(ctx context.Context, tableID uuid.UUID, userID uuid.UUID) ([]models.Record, error) {
 	// Verify user has access
@@ -255,6 +275,9 @@ func (s *RecordStore) UpdateRecord(ctx context.Context, recordID uuid.UUID, valu
 	if !role.CanEdit() {
 		return nil, ErrForbidden
 	}
+	if err := s.checkCellLocks(ctx, baseID, recordID, changedValues(r.Values, values), userID); err != nil {
+		return nil, err
+	}
 
 	err = s.db.QueryRow(ctx, `
 		UPDATE records SET values = $2, updated_at = NOW()
@@ -307,6 +330,9 @@ func (s *RecordStore) PatchRecord(ctx context.Context, recordID uuid.UUID, newVa
 	if !role.CanEdit() {
 		return nil, ErrForbidden
 	}
+	if err := s.checkCellLocks(ctx, baseID, recordID, newValues, userID); err != nil {
+		return nil, err
+	}
 
 	// Parse existing values
 	var existingValues map[string]interface{}
@@ -357,6 +383,47 @@ func (s *RecordStore) PatchRecord(ctx context.Context, recordID uuid.UUID, newVa
 	return r, nil
 }
 
+// checkCellLocks rejects changes to cells another user is editing
+func (s *RecordStore) checkCellLocks(ctx context.Context, baseID, recordID uuid.UUID, values map[string]interface{}, userID uuid.UUID) error {
+	if s.hub == nil {
+		return nil
+	}
+	locks, err := s.hub.CellLocks(ctx, baseID)
+	if err != nil {
+		return err
+	}
+	for _, lock := range locks {
+		if lock.RecordID != recordID || lock.UserID == userID {
+			continue
+		}
+		if _, ok := values[lock.FieldID.String()]; ok {
+			return ErrCellLocked
+		}
+	}
+	return nil
+}
+
+// changedValues returns the cells a full write of newValues changes in oldValues,
+// with removed cells as nil, so locks only stop writes that would alter a cell
+func changedValues(oldValues, newValues json.RawMessage) map[string]interface{} {
+	var before, after map[string]interface{}
+	_ = json.Unmarshal(oldValues, &before)
+	_ = json.Unmarshal(newValues, &after)
+
+	changed := make(map[string]interface{})
+	for k, v := range after {
+		if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+			changed[k] = v
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			changed[k] = nil
+		}
+	}
+	return changed
+}
+
 // UpdateRecordColor updates only the color of a record
 func (s *RecordStore) UpdateRecordColor(ctx context.Context, recordID uuid.UUID, color *string, userID uuid.UUID) (*models.Record, error) {
 	// Get record to check access
@@ -445,8 +512,9 @@ func (s *RecordStore) DeleteRecord(ctx context.Context, recordID uuid.UUID, user
 	return nil
 }
 
-// BulkCreateRecords creates multiple records at once
-func (s *RecordStore) BulkCreateRecords(ctx context.Context, tableID uuid.UUID, recordValues []json.RawMessage, userID uuid.UUID) ([]models.Record, error) {
+// BulkCreateRecords creates multiple records at once. progress, if set, is told how
+// many have been created.
+func (s *RecordStore) BulkCreateRecords(ctx context.Context, tableID uuid.UUID, recordValues []json.RawMessage, userID uuid.UUID, progress ProgressFunc) ([]models.Record, error) {
 	// Verify user has edit access
 	baseID, err := s.getBaseIDForTable(ctx, tableID)
 	if err != nil {
@@ -493,6 +561,9 @@ func (s *RecordStore) BulkCreateRecords(ctx context.Context, tableID uuid.UUID,
 			return nil, err
 		}
 		records = append(records, r)
+		if (i+1)%progressInterval == 0 || i+1 == len(recordValues) {
+			progress.report(i+1, len(recordValues))
+		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -74,6 +74,49 @@ func TestRecordStore_getBaseIDForTable(t *testing.T) {
 	})
 }
 
+func TestRecordStore_CheckEditAccess(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		role    models.CollaboratorRole
+		wantErr error
+	}{
+		{"editor can edit", models.RoleEditor, nil},
+		{"viewer cannot edit", models.RoleViewer, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			baseStore := NewBaseStore(mock)
+			store := NewRecordStore(mock, baseStore, NewTableStore(mock, baseStore))
+			tableID := uuid.New()
+			baseID := uuid.New()
+			userID := uuid.New()
+
+			mock.ExpectQuery("SELECT base_id FROM tables WHERE id").
+				WithArgs(tableID).
+				WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+			mock.ExpectQuery("SELECT role FROM base_collaborators").
+				WithArgs(baseID, userID).
+				WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(tt.role))
+
+			err = store.CheckEditAccess(ctx, tableID, userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestRecordStore_ListRecordsForTable(t *testing.T) {
 	ctx := context.Background()
 
@@ -501,6 +544,82 @@ func TestRecordStore_UpdateRecord(t *testing.T) {
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	// lockedUpdate sets up a write by userID to a record holding oldValues while
+	// another user holds a lock on lockedField
+	lockedUpdate := func(t *testing.T, userID, tableID, recordID uuid.UUID, oldValues json.RawMessage, lockedField uuid.UUID) (pgxmock.PgxPoolIface, *RecordStore) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+
+		baseStore := NewBaseStore(mock)
+		tableStore := NewTableStore(mock, baseStore)
+		store := NewRecordStore(mock, baseStore, tableStore)
+		baseID := uuid.New()
+		now := time.Now().UTC()
+
+		locks := realtime.NewMemoryCellLocks()
+		hub := realtime.NewHub()
+		hub.SetCellLocks(locks)
+		store.SetHub(hub)
+		_, err = locks.Acquire(ctx, &realtime.CellLock{
+			BaseID: baseID, TableID: tableID, RecordID: recordID, FieldID: lockedField,
+			UserID: uuid.New(), ExpiresAt: now.Add(time.Minute),
+		})
+		require.NoError(t, err)
+
+		mock.ExpectQuery("SELECT id, table_id, values, position, color, created_at, updated_at").
+			WithArgs(recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "values", "position", "color", "created_at", "updated_at"}).
+				AddRow(recordID, tableID, oldValues, 0, nil, now, now))
+		mock.ExpectQuery("SELECT base_id FROM tables WHERE id").
+			WithArgs(tableID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		mock.ExpectQuery("SELECT id, table_id, name, field_type, options, position, created_at, updated_at FROM fields").
+			WithArgs(tableID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "name", "field_type", "options", "position", "created_at", "updated_at"}))
+		mock.ExpectQuery("SELECT base_id FROM tables WHERE id").
+			WithArgs(tableID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		return mock, store
+	}
+
+	t.Run("returns ErrCellLocked when changing a cell another user has locked", func(t *testing.T) {
+		lockedField := uuid.New()
+		oldValues := json.RawMessage(`{"` + lockedField.String() + `": "theirs"}`)
+		newValues := json.RawMessage(`{"` + lockedField.String() + `": "mine"}`)
+		userID, recordID := uuid.New(), uuid.New()
+		mock, store := lockedUpdate(t, userID, uuid.New(), recordID, oldValues, lockedField)
+		defer mock.Close()
+
+		_, err := store.UpdateRecord(ctx, recordID, newValues, userID)
+		assert.ErrorIs(t, err, ErrCellLocked)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("allows a write that leaves the locked cell unchanged", func(t *testing.T) {
+		lockedField := uuid.New()
+		oldValues := json.RawMessage(`{"` + lockedField.String() + `": "theirs", "other": "old"}`)
+		newValues := json.RawMessage(`{"` + lockedField.String() + `": "theirs", "other": "new"}`)
+		userID, tableID, recordID := uuid.New(), uuid.New(), uuid.New()
+		mock, store := lockedUpdate(t, userID, tableID, recordID, oldValues, lockedField)
+		defer mock.Close()
+
+		now := time.Now().UTC()
+		mock.ExpectQuery("UPDATE records SET values").
+			WithArgs(recordID, newValues).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "values", "position", "color", "created_at", "updated_at"}).
+				AddRow(recordID, tableID, newValues, 0, nil, now, now))
+
+		_, err := store.UpdateRecord(ctx, recordID, newValues, userID)
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestRecordStore_PatchRecord(t *testing.T) {
@@ -641,6 +760,56 @@ func TestRecordStore_PatchRecord(t *testing.T) {
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("rejects changes to cells another user is editing", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		baseStore := NewBaseStore(mock)
+		tableStore := NewTableStore(mock, baseStore)
+		store := NewRecordStore(mock, baseStore, tableStore)
+		userID := uuid.New()
+		baseID := uuid.New()
+		tableID := uuid.New()
+		recordID := uuid.New()
+		fieldID := uuid.New()
+		now := time.Now().UTC()
+
+		locks := realtime.NewMemoryCellLocks()
+		hub := realtime.NewHub()
+		hub.SetCellLocks(locks)
+		store.SetHub(hub)
+		_, err = locks.Acquire(ctx, &realtime.CellLock{
+			BaseID: baseID, TableID: tableID, RecordID: recordID, FieldID: fieldID,
+			UserID: uuid.New(), ExpiresAt: now.Add(time.Minute),
+		})
+		require.NoError(t, err)
+
+		mock.ExpectQuery("SELECT id, table_id, values, position, color, created_at, updated_at").
+			WithArgs(recordID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "values", "position", "color", "created_at", "updated_at"}).
+				AddRow(recordID, tableID, json.RawMessage(`{}`), 0, nil, now, now))
+		mock.ExpectQuery("SELECT base_id FROM tables WHERE id").
+			WithArgs(tableID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+		mock.ExpectQuery("SELECT id, table_id, name, field_type, options, position, created_at, updated_at FROM fields").
+			WithArgs(tableID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "table_id", "name", "field_type", "options", "position", "created_at", "updated_at"}))
+		mock.ExpectQuery("SELECT base_id FROM tables WHERE id").
+			WithArgs(tableID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+
+		_, err = store.PatchRecord(ctx, recordID, map[string]interface{}{fieldID.String(): "mine"}, userID)
+		assert.ErrorIs(t, err, ErrCellLocked)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestRecordStore_DeleteRecord(t *testing.T) {
@@ -825,7 +994,7 @@ func TestRecordStore_BulkCreateRecords(t *testing.T) {
 
 		mock.ExpectCommit()
 
-		records, err := store.BulkCreateRecords(ctx, tableID, []json.RawMessage{values1, values2}, userID)
+		records, err := store.BulkCreateRecords(ctx, tableID, []json.RawMessage{values1, values2}, userID, nil)
 		require.NoError(t, err)
 		assert.Len(t, records, 2)
 
@@ -877,7 +1046,7 @@ func TestRecordStore_BulkCreateRecords(t *testing.T) {
 
 		mock.ExpectCommit()
 
-		records, err := store.BulkCreateRecords(ctx, tableID, []json.RawMessage{nil}, userID)
+		records, err := store.BulkCreateRecords(ctx, tableID, []json.RawMessage{nil}, userID, nil)
 		require.NoError(t, err)
 		assert.Len(t, records, 1)
 
@@ -908,7 +1077,7 @@ func TestRecordStore_BulkCreateRecords(t *testing.T) {
 			WithArgs(baseID, userID).
 			WillReturnRows(roleRows)
 
-		records, err := store.BulkCreateRecords(ctx, tableID, []json.RawMessage{nil}, userID)
+		records, err := store.BulkCreateRecords(ctx, tableID, []json.RawMessage{nil}, userID, nil)
 		assert.ErrorIs(t, err, ErrForbidden)
 		assert.Nil(t, records)
 
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// ServerSecretStore keeps secrets that every backend instance must share
+type ServerSecretStore struct {
+	db DBTX
+}
+
+func NewServerSecretStore(db DBTX) *ServerSecretStore {
+	return &ServerSecretStore{db: db}
+}
+
+// GetOrCreate returns the named secret, generating and storing a random one the
+// first time it is requested. Concurrent callers all get the same value.
+func (s *ServerSecretStore) GetOrCreate(ctx context.Context, name string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	var value string
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO server_secrets (name, value)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING value
+	`, name, base64.StdEncoding.EncodeToString(b)).Scan(&value)
+	if err != nil {
+		return "", fmt.Errorf("failed to load server secret %s: %w", name, err)
+	}
+	return value, nil
+}
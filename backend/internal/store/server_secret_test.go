@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerSecretStore_GetOrCreate(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	// The stored value wins over the newly generated one
+	mock.ExpectQuery("INSERT INTO server_secrets").
+		WithArgs("csrf", pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"value"}).AddRow("existing"))
+
+	value, err := NewServerSecretStore(mock).GetOrCreate(context.Background(), "csrf")
+	require.NoError(t, err)
+	assert.Equal(t, "existing", value)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -13,9 +13,10 @@ import (
 )
 
 type TableStore struct {
-	db        DBTX
-	baseStore *BaseStore
-	hub       *realtime.Hub
+	db             DBTX
+	baseStore      *BaseStore
+	hub            *realtime.Hub
+	changeCallback ChangeCallback
 }
 
 func NewTableStore(db DBTX, baseStore *BaseStore) *TableStore {
@@ -27,6 +28,22 @@ func (s *TableStore) SetHub(hub *realtime.Hub) {
 	s.hub = hub
 }
 
+// SetChangeCallback sets the callback for table changes
+func (s *TableStore) SetChangeCallback(cb ChangeCallback) {
+	s.changeCallback = cb
+}
+
+// GetBaseID returns the base a table belongs to without checking access, for callers
+// acting on behalf of the system such as public form submissions
+func (s *TableStore) GetBaseID(ctx context.Context, tableID uuid.UUID) (uuid.UUID, error) {
+	var baseID uuid.UUID
+	err := s.db.QueryRow(ctx, `SELECT base_id FROM tables WHERE id = $1`, tableID).Scan(&baseID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrNotFound
+	}
+	return baseID, err
+}
+
 // ListTablesForBase returns all tables in a base
 func (s *TableStore) ListTablesForBase(ctx context.Context, baseID uuid.UUID, userID uuid.UUID) ([]models.Table, error) {
 	// Verify user has access to base
@@ -99,6 +116,9 @@ func (s *TableStore) CreateTable(ctx context.Context, baseID uuid.UUID, name str
 			WithPayload(t)
 		s.hub.Broadcast(msg)
 	}
+	if s.changeCallback != nil {
+		s.changeCallback(baseID, t.ID, ChangeCreated, &t, userID)
+	}
 
 	return &t, nil
 }
@@ -164,6 +184,9 @@ func (s *TableStore) UpdateTable(ctx context.Context, tableID uuid.UUID, name st
 			WithPayload(t)
 		s.hub.Broadcast(msg)
 	}
+	if s.changeCallback != nil {
+		s.changeCallback(t.BaseID, t.ID, ChangeUpdated, t, userID)
+	}
 
 	return t, nil
 }
@@ -203,6 +226,9 @@ func (s *TableStore) DeleteTable(ctx context.Context, tableID uuid.UUID, userID
 			})
 		s.hub.Broadcast(msg)
 	}
+	if s.changeCallback != nil {
+		s.changeCallback(t.BaseID, tableID, ChangeDeleted, t, userID)
+	}
 
 	return nil
 }
@@ -237,8 +263,9 @@ func (s *TableStore) ReorderTables(ctx context.Context, baseID uuid.UUID, tableI
 	return tx.Commit(ctx)
 }
 
-// DuplicateTable duplicates a table with all its fields, views, and optionally records
-func (s *TableStore) DuplicateTable(ctx context.Context, tableID uuid.UUID, userID uuid.UUID, includeRecords bool) (*models.Table, error) {
+// DuplicateTable duplicates a table with all its fields, views, and optionally records.
+// progress, if set, is told how many records have been copied.
+func (s *TableStore) DuplicateTable(ctx context.Context, tableID uuid.UUID, userID uuid.UUID, includeRecords bool, progress ProgressFunc) (*models.Table, error) {
 	// Get original table
 	origTable, err := s.GetTable(ctx, tableID, userID)
 	if err != nil {
@@ -388,7 +415,7 @@ func (s *TableStore) DuplicateTable(ctx context.Context, tableID uuid.UUID, user
 	// Copy records if requested
 	if includeRecords {
 		recordRows, err := tx.Query(ctx, `
-			SELECT values, position
+			SELECT values, position, COUNT(*) OVER ()
 			FROM records
 			WHERE table_id = $1
 			ORDER BY position
@@ -398,11 +425,12 @@ func (s *TableStore) DuplicateTable(ctx context.Context, tableID uuid.UUID, user
 		}
 		defer recordRows.Close()
 
+		copied := 0
 		for recordRows.Next() {
 			var values json.RawMessage
-			var position int
+			var position, total int
 
-			if err := recordRows.Scan(&values, &position); err != nil {
+			if err := recordRows.Scan(&values, &position, &total); err != nil {
 				return nil, err
 			}
 
@@ -429,6 +457,10 @@ func (s *TableStore) DuplicateTable(ctx context.Context, tableID uuid.UUID, user
 			if err != nil {
 				return nil, err
 			}
+			copied++
+			if copied%progressInterval == 0 || copied == total {
+				progress.report(copied, total)
+			}
 		}
 		recordRows.Close()
 	}
@@ -437,5 +469,9 @@ func (s *TableStore) DuplicateTable(ctx context.Context, tableID uuid.UUID, user
 		return nil, err
 	}
 
+	if s.changeCallback != nil {
+		s.changeCallback(newTable.BaseID, newTable.ID, ChangeCreated, &newTable, userID)
+	}
+
 	return &newTable, nil
 }
@@ -238,6 +238,48 @@ func TestTableStore_GetTable(t *testing.T) {
 	})
 }
 
+func TestTableStore_GetBaseID(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns base ID without an access check", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewTableStore(mock, NewBaseStore(mock))
+		baseID := uuid.New()
+		tableID := uuid.New()
+
+		mock.ExpectQuery("SELECT base_id FROM tables WHERE id").
+			WithArgs(tableID).
+			WillReturnRows(pgxmock.NewRows([]string{"base_id"}).AddRow(baseID))
+
+		got, err := store.GetBaseID(ctx, tableID)
+		require.NoError(t, err)
+		assert.Equal(t, baseID, got)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns ErrNotFound when table doesn't exist", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewTableStore(mock, NewBaseStore(mock))
+		tableID := uuid.New()
+
+		mock.ExpectQuery("SELECT base_id FROM tables WHERE id").
+			WithArgs(tableID).
+			WillReturnError(pgx.ErrNoRows)
+
+		_, err = store.GetBaseID(ctx, tableID)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestTableStore_UpdateTable(t *testing.T) {
 	ctx := context.Background()
 
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vibetable/backend/internal/models"
+)
+
+// TemplateStore publishes bases as templates and creates new bases from them
+type TemplateStore struct {
+	db        DBTX
+	baseStore *BaseStore
+	archives  *ArchiveStore
+}
+
+func NewTemplateStore(db DBTX, baseStore *BaseStore, archives *ArchiveStore) *TemplateStore {
+	return &TemplateStore{
+		db:        db,
+		baseStore: baseStore,
+		archives:  archives,
+	}
+}
+
+// CreateTemplate publishes a snapshot of a base as a template. Only the base's
+// owners can publish it; later changes to the base do not change the template.
+func (s *TemplateStore) CreateTemplate(ctx context.Context, userID uuid.UUID, req *models.CreateTemplateRequest) (*models.BaseTemplate, error) {
+	role, err := s.baseStore.GetUserRole(ctx, req.BaseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.RoleOwner {
+		return nil, ErrForbidden
+	}
+	base, err := s.baseStore.GetBase(ctx, req.BaseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.archives.exportBase(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	templateContent(a, req.IncludeRecords)
+	content, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+
+	template := models.BaseTemplate{
+		Name:            req.Name,
+		Description:     req.Description,
+		SourceBaseID:    &base.ID,
+		IncludesRecords: req.IncludeRecords,
+		TableCount:      len(a.Tables),
+		CreatedBy:       userID,
+	}
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO base_templates (name, description, source_base_id, includes_records, content, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, req.Name, req.Description, base.ID, req.IncludeRecords, content, userID).Scan(&template.ID, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// templateContent reduces an exported base to what a template carries: its tables,
+// fields, views, forms and automations, and its records when includeRecords is set.
+// Webhooks point at the publishing team's services, so they are left out.
+func templateContent(a *models.BaseArchive, includeRecords bool) {
+	a.Webhooks = []models.ArchiveWebhook{}
+	for i := range a.Tables {
+		t := &a.Tables[i]
+		if !includeRecords {
+			t.Records = []models.ArchiveRecord{}
+			continue
+		}
+		for j := range t.Records {
+			t.Records[j].Comments = nil
+			t.Records[j].Attachments = nil
+		}
+	}
+}
+
+// ListTemplates lists every published template, without their content
+func (s *TemplateStore) ListTemplates(ctx context.Context) ([]models.BaseTemplate, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, name, description, source_base_id, includes_records,
+		       jsonb_array_length(content->'tables'), created_by, created_at, updated_at
+		FROM base_templates
+		ORDER BY name, created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.BaseTemplate{}
+	for rows.Next() {
+		var t models.BaseTemplate
+		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.SourceBaseID, &t.IncludesRecords,
+			&t.TableCount, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// GetTemplate gets a template with its content, so its structure can be previewed
+func (s *TemplateStore) GetTemplate(ctx context.Context, templateID uuid.UUID) (*models.BaseTemplate, error) {
+	var t models.BaseTemplate
+	var content []byte
+	err := s.db.QueryRow(ctx, `
+		SELECT id, name, description, source_base_id, includes_records,
+		       content, created_by, created_at, updated_at
+		FROM base_templates
+		WHERE id = $1
+	`, templateID).Scan(&t.ID, &t.Name, &t.Description, &t.SourceBaseID, &t.IncludesRecords,
+		&content, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &t.Content); err != nil {
+		return nil, err
+	}
+	t.TableCount = len(t.Content.Tables)
+	return &t, nil
+}
+
+// DeleteTemplate removes a template; only the user who published it can
+func (s *TemplateStore) DeleteTemplate(ctx context.Context, templateID, userID uuid.UUID) error {
+	var createdBy uuid.UUID
+	err := s.db.QueryRow(ctx, `SELECT created_by FROM base_templates WHERE id = $1`, templateID).Scan(&createdBy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if createdBy != userID {
+		return ErrForbidden
+	}
+
+	_, err = s.db.Exec(ctx, `DELETE FROM base_templates WHERE id = $1`, templateID)
+	return err
+}
+
+// CreateBaseFromTemplate creates a new base owned by the user from a template,
+// named after the template unless a name is given. Everything gets new IDs, as
+// when importing an archive.
+func (s *TemplateStore) CreateBaseFromTemplate(ctx context.Context, templateID uuid.UUID, name string, userID uuid.UUID) (*models.Base, error) {
+	t, err := s.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = t.Name
+	}
+	return s.archives.createBase(ctx, t.Content, name, userID, nil)
+}
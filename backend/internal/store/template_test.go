@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+)
+
+func TestTemplateContent(t *testing.T) {
+	build := func() *models.BaseArchive {
+		return &models.BaseArchive{
+			Tables: []models.ArchiveTable{{
+				ID: uuid.New(),
+				Records: []models.ArchiveRecord{{
+					ID:          uuid.New(),
+					Comments:    []models.ArchiveComment{{ID: uuid.New()}},
+					Attachments: []models.ArchiveAttachment{{ID: uuid.New()}},
+				}},
+			}},
+			Webhooks: []models.ArchiveWebhook{{ID: uuid.New()}},
+		}
+	}
+
+	t.Run("leaves out records unless asked", func(t *testing.T) {
+		a := build()
+		templateContent(a, false)
+		assert.Empty(t, a.Webhooks)
+		assert.Empty(t, a.Tables[0].Records)
+	})
+
+	t.Run("keeps sample records without comments or attachments", func(t *testing.T) {
+		a := build()
+		templateContent(a, true)
+		assert.Empty(t, a.Webhooks)
+		require.Len(t, a.Tables[0].Records, 1)
+		assert.Nil(t, a.Tables[0].Records[0].Comments)
+		assert.Nil(t, a.Tables[0].Records[0].Attachments)
+	})
+}
+
+func TestTemplateStore_CreateTemplate(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (pgxmock.PgxPoolIface, *TemplateStore) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+
+		baseStore := NewBaseStore(mock)
+		return mock, NewTemplateStore(mock, baseStore, NewArchiveStore(mock, baseStore, nil))
+	}
+
+	t.Run("only owners can publish", func(t *testing.T) {
+		mock, store := setup(t)
+		req := &models.CreateTemplateRequest{BaseID: uuid.New(), Name: "CRM"}
+		userID := uuid.New()
+
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(req.BaseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+
+		_, err := store.CreateTemplate(ctx, userID, req)
+		assert.ErrorIs(t, err, ErrForbidden)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stores a snapshot of the base", func(t *testing.T) {
+		mock, store := setup(t)
+		req := &models.CreateTemplateRequest{BaseID: uuid.New(), Name: "CRM template"}
+		userID, tableID, templateID := uuid.New(), uuid.New(), uuid.New()
+		now := time.Now().UTC()
+
+		mock.ExpectQuery("SELECT role FROM base_collaborators").
+			WithArgs(req.BaseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"role"}).AddRow(models.RoleOwner))
+		mock.ExpectQuery("SELECT b.id, b.name").
+			WithArgs(req.BaseID, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "created_by", "created_at", "updated_at", "role"}).
+				AddRow(req.BaseID, "CRM", userID, now, now, models.RoleOwner))
+		mock.ExpectQuery("SELECT id, name, position FROM tables").
+			WithArgs(req.BaseID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "position"}).AddRow(tableID, "Contacts", 0))
+		for _, from := range []string{"FROM fields f", "FROM views v", "FROM form_fields ff", "FROM forms fo",
+			"FROM comments c", "FROM attachments a", "FROM records r", "FROM automations", "FROM webhooks"} {
+			mock.ExpectQuery(from).WithArgs(req.BaseID).WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		}
+		content := &captureArg{}
+		mock.ExpectQuery("INSERT INTO base_templates").
+			WithArgs("CRM template", (*string)(nil), req.BaseID, false, content, userID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(templateID, now, now))
+
+		template, err := store.CreateTemplate(ctx, userID, req)
+		require.NoError(t, err)
+		assert.Equal(t, templateID, template.ID)
+		assert.Equal(t, 1, template.TableCount)
+
+		var a models.BaseArchive
+		require.NoError(t, json.Unmarshal(content.value.([]byte), &a))
+		require.Len(t, a.Tables, 1)
+		assert.Equal(t, "Contacts", a.Tables[0].Name)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTemplateStore_DeleteTemplate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns not found for missing template", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewTemplateStore(mock, nil, nil)
+		templateID := uuid.New()
+		mock.ExpectQuery("SELECT created_by FROM base_templates").
+			WithArgs(templateID).
+			WillReturnError(pgx.ErrNoRows)
+
+		err = store.DeleteTemplate(ctx, templateID, uuid.New())
+		assert.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("only the publisher can delete", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewTemplateStore(mock, nil, nil)
+		templateID := uuid.New()
+		mock.ExpectQuery("SELECT created_by FROM base_templates").
+			WithArgs(templateID).
+			WillReturnRows(pgxmock.NewRows([]string{"created_by"}).AddRow(uuid.New()))
+
+		err = store.DeleteTemplate(ctx, templateID, uuid.New())
+		assert.ErrorIs(t, err, ErrForbidden)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deletes the template", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewTemplateStore(mock, nil, nil)
+		templateID, userID := uuid.New(), uuid.New()
+		mock.ExpectQuery("SELECT created_by FROM base_templates").
+			WithArgs(templateID).
+			WillReturnRows(pgxmock.NewRows([]string{"created_by"}).AddRow(userID))
+		mock.ExpectExec("DELETE FROM base_templates").
+			WithArgs(templateID).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		require.NoError(t, store.DeleteTemplate(ctx, templateID, userID))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTemplateStore_CreateBaseFromTemplate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns not found for missing template", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewTemplateStore(mock, nil, nil)
+		templateID := uuid.New()
+		mock.ExpectQuery("FROM base_templates").
+			WithArgs(templateID).
+			WillReturnError(pgx.ErrNoRows)
+
+		_, err = store.CreateBaseFromTemplate(ctx, templateID, "", uuid.New())
+		assert.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("creates a base with new IDs", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		baseStore := NewBaseStore(mock)
+		store := NewTemplateStore(mock, baseStore, NewArchiveStore(mock, baseStore, nil))
+		templateID, userID, baseID, tableID, fieldID := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+		now := time.Now().UTC()
+
+		content, err := json.Marshal(models.BaseArchive{
+			Version: models.BaseArchiveVersion,
+			Tables: []models.ArchiveTable{{
+				ID:     tableID,
+				Name:   "Deals",
+				Fields: []models.ArchiveField{{ID: fieldID, Name: "Name", FieldType: models.FieldTypeText, Options: json.RawMessage(`{}`)}},
+			}},
+		})
+		require.NoError(t, err)
+
+		mock.ExpectQuery("FROM base_templates").
+			WithArgs(templateID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "description", "source_base_id", "includes_records", "content", "created_by", "created_at", "updated_at"}).
+				AddRow(templateID, "Sales pipeline", nil, nil, false, content, uuid.New(), now, now))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO bases").
+			WithArgs("Sales pipeline", userID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "created_by", "created_at", "updated_at"}).
+				AddRow(baseID, "Sales pipeline", userID, now, now))
+		mock.ExpectExec("INSERT INTO base_collaborators").
+			WithArgs(baseID, userID).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		newTableID := &captureArg{}
+		mock.ExpectExec("INSERT INTO tables").
+			WithArgs(newTableID, baseID, "Deals", 0).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		newFieldID := &captureArg{}
+		mock.ExpectExec("INSERT INTO fields").
+			WithArgs(newFieldID, pgxmock.AnyArg(), "Name", models.FieldTypeText, pgxmock.AnyArg(), 0).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		base, err := store.CreateBaseFromTemplate(ctx, templateID, "", userID)
+		require.NoError(t, err)
+		assert.Equal(t, baseID, base.ID)
+		assert.NotEqual(t, tableID, newTableID.value)
+		assert.NotEqual(t, fieldID, newFieldID.value)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
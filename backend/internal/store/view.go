@@ -12,10 +12,11 @@ import (
 )
 
 type ViewStore struct {
-	db         DBTX
-	baseStore  *BaseStore
-	tableStore *TableStore
-	hub        *realtime.Hub
+	db             DBTX
+	baseStore      *BaseStore
+	tableStore     *TableStore
+	hub            *realtime.Hub
+	changeCallback ChangeCallback
 }
 
 func NewViewStore(db DBTX, baseStore *BaseStore, tableStore *TableStore) *ViewStore {
@@ -31,6 +32,11 @@ func (s *ViewStore) SetHub(hub *realtime.Hub) {
 	s.hub = hub
 }
 
+// SetChangeCallback sets the callback for view changes
+func (s *ViewStore) SetChangeCallback(cb ChangeCallback) {
+	s.changeCallback = cb
+}
+
 // ListViewsForTable returns all views for a table
 func (s *ViewStore) ListViewsForTable(ctx context.Context, tableID uuid.UUID, userID uuid.UUID) ([]models.View, error) {
 	// First verify user has access to this table's base
@@ -115,6 +121,10 @@ func (s *ViewStore) CreateView(ctx context.Context, tableID uuid.UUID, name stri
 		return nil, err
 	}
 
+	if s.changeCallback != nil {
+		s.changeCallback(table.BaseID, tableID, ChangeCreated, view, userID)
+	}
+
 	return view, nil
 }
 
@@ -193,6 +203,10 @@ func (s *ViewStore) UpdateView(ctx context.Context, viewID uuid.UUID, name *stri
 		return nil, err
 	}
 
+	if s.changeCallback != nil {
+		s.changeCallback(table.BaseID, view.TableID, ChangeUpdated, view, userID)
+	}
+
 	return view, nil
 }
 
@@ -219,7 +233,15 @@ func (s *ViewStore) DeleteView(ctx context.Context, viewID uuid.UUID, userID uui
 	}
 
 	_, err = s.db.Exec(ctx, `DELETE FROM views WHERE id = $1`, viewID)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if s.changeCallback != nil {
+		s.changeCallback(table.BaseID, view.TableID, ChangeDeleted, view, userID)
+	}
+
+	return nil
 }
 
 // SetViewPublic enables or disables public sharing for a view
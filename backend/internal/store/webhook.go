@@ -3,22 +3,32 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/secrets"
 )
 
 type WebhookStore struct {
 	db        DBTX
 	baseStore *BaseStore
+	box       *secrets.Box
 }
 
 func NewWebhookStore(db DBTX, baseStore *BaseStore) *WebhookStore {
 	return &WebhookStore{db: db, baseStore: baseStore}
 }
 
+// SetSecretBox sets the box used to encrypt webhook secrets at rest. Without one,
+// secrets are stored in plaintext.
+func (s *WebhookStore) SetSecretBox(box *secrets.Box) {
+	s.box = box
+}
+
 // Create creates a new webhook
 func (s *WebhookStore) Create(ctx context.Context, baseID, userID uuid.UUID, req *models.CreateWebhookRequest) (*models.Webhook, error) {
 	// Default events if not provided
@@ -35,32 +45,52 @@ func (s *WebhookStore) Create(ctx context.Context, baseID, userID uuid.UUID, req
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal events: %w", err)
 	}
+	tableIDsJSON, watchFieldIDsJSON := encodeWebhookFilters(req.TableIDs, req.WatchFieldIDs)
+
+	format := req.Format
+	if format == "" {
+		format = models.WebhookFormatNative
+	}
+	sealedSecret, err := s.seal(req.Secret)
+	if err != nil {
+		return nil, err
+	}
 
 	id := uuid.New()
 	now := time.Now()
 
 	query := `
-		INSERT INTO webhooks (id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, true, $7, $8, $8)
-		RETURNING id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at
+		INSERT INTO webhooks (id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at,
+		                      table_ids, watch_field_ids, format, message_template)
+		VALUES ($1, $2, $3, $4, $5, $6, true, $7, $8, $8, $9, $10, $11, $12)
+		RETURNING id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at,
+		          consecutive_failures, disabled_reason, table_ids, watch_field_ids, format, message_template,
+		          previous_secret, previous_secret_expires_at
 	`
 
 	webhook := &models.Webhook{}
-	var eventsRaw []byte
+	var eventsRaw, tableIDsRaw, watchFieldIDsRaw []byte
 
 	err = s.db.QueryRow(ctx, query,
-		id, baseID, req.Name, req.URL, eventsJSON, req.Secret, userID, now,
+		id, baseID, req.Name, req.URL, eventsJSON, sealedSecret, userID, now, tableIDsJSON, watchFieldIDsJSON,
+		format, nonEmpty(req.MessageTemplate),
 	).Scan(
 		&webhook.ID, &webhook.BaseID, &webhook.Name, &webhook.URL,
 		&eventsRaw, &webhook.Secret, &webhook.IsActive, &webhook.CreatedBy,
 		&webhook.CreatedAt, &webhook.UpdatedAt,
+		&webhook.ConsecutiveFailures, &webhook.DisabledReason, &tableIDsRaw, &watchFieldIDsRaw,
+		&webhook.Format, &webhook.MessageTemplate,
+		&webhook.PreviousSecret, &webhook.PreviousSecretExpiresAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create webhook: %w", err)
 	}
 
-	if err := json.Unmarshal(eventsRaw, &webhook.Events); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal events: %w", err)
+	if err := decodeWebhookJSON(webhook, eventsRaw, tableIDsRaw, watchFieldIDsRaw); err != nil {
+		return nil, err
+	}
+	if err := s.openSecrets(webhook); err != nil {
+		return nil, err
 	}
 
 	return webhook, nil
@@ -69,24 +99,32 @@ func (s *WebhookStore) Create(ctx context.Context, baseID, userID uuid.UUID, req
 // GetByID retrieves a webhook by ID
 func (s *WebhookStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
 	query := `
-		SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at
+		SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at,
+		       consecutive_failures, disabled_reason, table_ids, watch_field_ids, format, message_template,
+		       previous_secret, previous_secret_expires_at
 		FROM webhooks WHERE id = $1
 	`
 
 	webhook := &models.Webhook{}
-	var eventsRaw []byte
+	var eventsRaw, tableIDsRaw, watchFieldIDsRaw []byte
 
 	err := s.db.QueryRow(ctx, query, id).Scan(
 		&webhook.ID, &webhook.BaseID, &webhook.Name, &webhook.URL,
 		&eventsRaw, &webhook.Secret, &webhook.IsActive, &webhook.CreatedBy,
 		&webhook.CreatedAt, &webhook.UpdatedAt,
+		&webhook.ConsecutiveFailures, &webhook.DisabledReason, &tableIDsRaw, &watchFieldIDsRaw,
+		&webhook.Format, &webhook.MessageTemplate,
+		&webhook.PreviousSecret, &webhook.PreviousSecretExpiresAt,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(eventsRaw, &webhook.Events); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal events: %w", err)
+	if err := decodeWebhookJSON(webhook, eventsRaw, tableIDsRaw, watchFieldIDsRaw); err != nil {
+		return nil, err
+	}
+	if err := s.openSecrets(webhook); err != nil {
+		return nil, err
 	}
 
 	return webhook, nil
@@ -95,7 +133,9 @@ func (s *WebhookStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Webho
 // ListByBase lists all webhooks for a base
 func (s *WebhookStore) ListByBase(ctx context.Context, baseID uuid.UUID) ([]models.Webhook, error) {
 	query := `
-		SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at
+		SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at,
+		       consecutive_failures, disabled_reason, table_ids, watch_field_ids, format, message_template,
+		       previous_secret, previous_secret_expires_at
 		FROM webhooks WHERE base_id = $1 ORDER BY created_at DESC
 	`
 
@@ -108,18 +148,24 @@ func (s *WebhookStore) ListByBase(ctx context.Context, baseID uuid.UUID) ([]mode
 	var webhooks []models.Webhook
 	for rows.Next() {
 		var webhook models.Webhook
-		var eventsRaw []byte
+		var eventsRaw, tableIDsRaw, watchFieldIDsRaw []byte
 
 		if err := rows.Scan(
 			&webhook.ID, &webhook.BaseID, &webhook.Name, &webhook.URL,
 			&eventsRaw, &webhook.Secret, &webhook.IsActive, &webhook.CreatedBy,
 			&webhook.CreatedAt, &webhook.UpdatedAt,
+			&webhook.ConsecutiveFailures, &webhook.DisabledReason, &tableIDsRaw, &watchFieldIDsRaw,
+			&webhook.Format, &webhook.MessageTemplate,
+			&webhook.PreviousSecret, &webhook.PreviousSecretExpiresAt,
 		); err != nil {
 			return nil, err
 		}
 
-		if err := json.Unmarshal(eventsRaw, &webhook.Events); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal events: %w", err)
+		if err := decodeWebhookJSON(&webhook, eventsRaw, tableIDsRaw, watchFieldIDsRaw); err != nil {
+			return nil, err
+		}
+		if err := s.openSecrets(&webhook); err != nil {
+			return nil, err
 		}
 
 		webhooks = append(webhooks, webhook)
@@ -129,17 +175,22 @@ func (s *WebhookStore) ListByBase(ctx context.Context, baseID uuid.UUID) ([]mode
 }
 
 // GetActiveByBaseAndEvent gets active webhooks for a base that listen to a specific event
-func (s *WebhookStore) GetActiveByBaseAndEvent(ctx context.Context, baseID uuid.UUID, event models.WebhookEvent) ([]models.Webhook, error) {
+// in the given table. Webhooks without a table filter match every table.
+func (s *WebhookStore) GetActiveByBaseAndEvent(ctx context.Context, baseID, tableID uuid.UUID, event models.WebhookEvent) ([]models.Webhook, error) {
 	query := `
-		SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at
+		SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at,
+		       consecutive_failures, disabled_reason, table_ids, watch_field_ids, format, message_template,
+		       previous_secret, previous_secret_expires_at
 		FROM webhooks
 		WHERE base_id = $1 AND is_active = true AND events @> $2
+		  AND (table_ids = '[]'::jsonb OR table_ids @> $3)
 		ORDER BY created_at ASC
 	`
 
 	eventJSON, _ := json.Marshal([]models.WebhookEvent{event})
+	tableJSON, _ := json.Marshal([]uuid.UUID{tableID})
 
-	rows, err := s.db.Query(ctx, query, baseID, eventJSON)
+	rows, err := s.db.Query(ctx, query, baseID, eventJSON, tableJSON)
 	if err != nil {
 		return nil, err
 	}
@@ -148,18 +199,24 @@ func (s *WebhookStore) GetActiveByBaseAndEvent(ctx context.Context, baseID uuid.
 	var webhooks []models.Webhook
 	for rows.Next() {
 		var webhook models.Webhook
-		var eventsRaw []byte
+		var eventsRaw, tableIDsRaw, watchFieldIDsRaw []byte
 
 		if err := rows.Scan(
 			&webhook.ID, &webhook.BaseID, &webhook.Name, &webhook.URL,
 			&eventsRaw, &webhook.Secret, &webhook.IsActive, &webhook.CreatedBy,
 			&webhook.CreatedAt, &webhook.UpdatedAt,
+			&webhook.ConsecutiveFailures, &webhook.DisabledReason, &tableIDsRaw, &watchFieldIDsRaw,
+			&webhook.Format, &webhook.MessageTemplate,
+			&webhook.PreviousSecret, &webhook.PreviousSecretExpiresAt,
 		); err != nil {
 			return nil, err
 		}
 
-		if err := json.Unmarshal(eventsRaw, &webhook.Events); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal events: %w", err)
+		if err := decodeWebhookJSON(&webhook, eventsRaw, tableIDsRaw, watchFieldIDsRaw); err != nil {
+			return nil, err
+		}
+		if err := s.openSecrets(&webhook); err != nil {
+			return nil, err
 		}
 
 		webhooks = append(webhooks, webhook)
@@ -188,25 +245,60 @@ func (s *WebhookStore) Update(ctx context.Context, id uuid.UUID, req *models.Upd
 	}
 	if req.Secret != nil {
 		webhook.Secret = req.Secret
+		webhook.PreviousSecret = nil
+		webhook.PreviousSecretExpiresAt = nil
+	}
+	if req.TableIDs != nil {
+		webhook.TableIDs = req.TableIDs
+	}
+	if req.WatchFieldIDs != nil {
+		webhook.WatchFieldIDs = req.WatchFieldIDs
 	}
+	if req.Format != nil {
+		webhook.Format = *req.Format
+	}
+	if req.MessageTemplate != nil {
+		webhook.MessageTemplate = nonEmpty(req.MessageTemplate)
+	}
+	// Re-enabling a webhook clears its failure streak
+	reenabled := req.IsActive != nil && *req.IsActive
 	if req.IsActive != nil {
 		webhook.IsActive = *req.IsActive
 	}
+	if reenabled {
+		webhook.ConsecutiveFailures = 0
+		webhook.DisabledReason = nil
+	}
 
 	eventsJSON, err := json.Marshal(webhook.Events)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal events: %w", err)
 	}
+	tableIDsJSON, watchFieldIDsJSON := encodeWebhookFilters(webhook.TableIDs, webhook.WatchFieldIDs)
+	sealedSecret, err := s.seal(webhook.Secret)
+	if err != nil {
+		return nil, err
+	}
+	sealedPrevious, err := s.seal(webhook.PreviousSecret)
+	if err != nil {
+		return nil, err
+	}
 
 	query := `
 		UPDATE webhooks
-		SET name = $2, url = $3, events = $4, secret = $5, is_active = $6, updated_at = NOW()
+		SET name = $2, url = $3, events = $4, secret = $5, is_active = $6, updated_at = NOW(),
+		    table_ids = $8, watch_field_ids = $9, format = $10, message_template = $11,
+		    previous_secret = $12, previous_secret_expires_at = $13,
+		    consecutive_failures = CASE WHEN $7 THEN 0 ELSE consecutive_failures END,
+		    disabled_reason = CASE WHEN $7 THEN NULL ELSE disabled_reason END
 		WHERE id = $1
 		RETURNING updated_at
 	`
 
 	err = s.db.QueryRow(ctx, query,
-		id, webhook.Name, webhook.URL, eventsJSON, webhook.Secret, webhook.IsActive,
+		id, webhook.Name, webhook.URL, eventsJSON, sealedSecret, webhook.IsActive, reenabled,
+		tableIDsJSON, watchFieldIDsJSON, webhook.Format, webhook.MessageTemplate,
+		sealedPrevious, webhook.PreviousSecretExpiresAt,
 	).Scan(&webhook.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update webhook: %w", err)
@@ -215,6 +307,172 @@ func (s *WebhookStore) Update(ctx context.Context, id uuid.UUID, req *models.Upd
 	return webhook, nil
 }
 
+// RotateSecret replaces a webhook's secret. The old secret keeps signing deliveries
+// until the grace period ends; a zero grace period revokes it immediately.
+func (s *WebhookStore) RotateSecret(ctx context.Context, id uuid.UUID, secret string, grace time.Duration) (*models.Webhook, error) {
+	webhook, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.PreviousSecret = nil
+	webhook.PreviousSecretExpiresAt = nil
+	if grace > 0 && webhook.Secret != nil && *webhook.Secret != "" {
+		expiresAt := time.Now().Add(grace)
+		webhook.PreviousSecret = webhook.Secret
+		webhook.PreviousSecretExpiresAt = &expiresAt
+	}
+	webhook.Secret = &secret
+	webhook.HasSecret = true
+
+	sealedSecret, err := s.seal(webhook.Secret)
+	if err != nil {
+		return nil, err
+	}
+	sealedPrevious, err := s.seal(webhook.PreviousSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRow(ctx, `
+		UPDATE webhooks
+		SET secret = $2, previous_secret = $3, previous_secret_expires_at = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, id, sealedSecret, sealedPrevious, webhook.PreviousSecretExpiresAt).Scan(&webhook.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// EncryptPlaintextSecrets encrypts secrets stored before a key was configured and
+// returns how many webhooks were updated
+func (s *WebhookStore) EncryptPlaintextSecrets(ctx context.Context) (int, error) {
+	if !s.box.Enabled() {
+		return 0, nil
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT id, secret, previous_secret FROM webhooks
+		WHERE secret IS NOT NULL OR previous_secret IS NOT NULL
+	`)
+	if err != nil {
+		return 0, err
+	}
+	type storedSecrets struct {
+		id               uuid.UUID
+		secret, previous *string
+	}
+	var pending []storedSecrets
+	for rows.Next() {
+		var row storedSecrets
+		if err := rows.Scan(&row.id, &row.secret, &row.previous); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if needsSealing(row.secret) || needsSealing(row.previous) {
+			pending = append(pending, row)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, row := range pending {
+		secret, previous := row.secret, row.previous
+		if needsSealing(secret) {
+			if secret, err = s.seal(secret); err != nil {
+				return 0, err
+			}
+		}
+		if needsSealing(previous) {
+			if previous, err = s.seal(previous); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := s.db.Exec(ctx, `UPDATE webhooks SET secret = $2, previous_secret = $3 WHERE id = $1`, row.id, secret, previous); err != nil {
+			return 0, err
+		}
+	}
+	return len(pending), nil
+}
+
+func needsSealing(value *string) bool {
+	return value != nil && *value != "" && !secrets.IsEncrypted(*value)
+}
+
+// seal encrypts a secret for storage, keeping nil and empty secrets as NULL
+func (s *WebhookStore) seal(secret *string) (*string, error) {
+	if secret == nil || *secret == "" {
+		return nil, nil
+	}
+	sealed, err := s.box.Encrypt(*secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openSecrets decrypts a webhook's secrets after loading it
+func (s *WebhookStore) openSecrets(webhook *models.Webhook) error {
+	for _, secret := range []*string{webhook.Secret, webhook.PreviousSecret} {
+		if secret == nil {
+			continue
+		}
+		opened, err := s.box.Decrypt(*secret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt webhook secret: %w", err)
+		}
+		*secret = opened
+	}
+	webhook.HasSecret = webhook.Secret != nil && *webhook.Secret != ""
+	return nil
+}
+
+// encodeWebhookFilters marshals table and field filters, storing nil as an empty list
+func encodeWebhookFilters(tableIDs, watchFieldIDs []uuid.UUID) ([]byte, []byte) {
+	if tableIDs == nil {
+		tableIDs = []uuid.UUID{}
+	}
+	if watchFieldIDs == nil {
+		watchFieldIDs = []uuid.UUID{}
+	}
+	tableIDsJSON, _ := json.Marshal(tableIDs)
+	watchFieldIDsJSON, _ := json.Marshal(watchFieldIDs)
+	return tableIDsJSON, watchFieldIDsJSON
+}
+
+// nonEmpty returns nil for a nil or empty string so it is stored as NULL
+func nonEmpty(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
+
+// decodeWebhookJSON unmarshals a webhook's event list and filters
+func decodeWebhookJSON(webhook *models.Webhook, events, tableIDs, watchFieldIDs []byte) error {
+	if err := json.Unmarshal(events, &webhook.Events); err != nil {
+		return fmt.Errorf("failed to unmarshal events: %w", err)
+	}
+	webhook.TableIDs = []uuid.UUID{}
+	if len(tableIDs) > 0 {
+		if err := json.Unmarshal(tableIDs, &webhook.TableIDs); err != nil {
+			return fmt.Errorf("failed to unmarshal table filter: %w", err)
+		}
+	}
+	webhook.WatchFieldIDs = []uuid.UUID{}
+	if len(watchFieldIDs) > 0 {
+		if err := json.Unmarshal(watchFieldIDs, &webhook.WatchFieldIDs); err != nil {
+			return fmt.Errorf("failed to unmarshal field filter: %w", err)
+		}
+	}
+	return nil
+}
+
 // Delete deletes a webhook
 func (s *WebhookStore) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM webhooks WHERE id = $1`
@@ -237,18 +495,25 @@ func (s *WebhookStore) CreateDelivery(ctx context.Context, delivery *models.Webh
 	now := time.Now()
 
 	query := `
-		INSERT INTO webhook_deliveries (id, webhook_id, event_type, payload, response_status, response_body, error, duration_ms, delivered_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, webhook_id, event_type, payload, response_status, response_body, error, duration_ms, delivered_at
+		INSERT INTO webhook_deliveries (id, webhook_id, event_type, payload, response_status, response_body, error, duration_ms, delivered_at, attempt, queue_item_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, webhook_id, event_type, payload, response_status, response_body, error, duration_ms, delivered_at, attempt, queue_item_id
 	`
 
+	attempt := delivery.Attempt
+	if attempt < 1 {
+		attempt = 1
+	}
+
 	d := &models.WebhookDelivery{}
 	err := s.db.QueryRow(ctx, query,
 		id, delivery.WebhookID, delivery.EventType, delivery.Payload,
 		delivery.ResponseStatus, delivery.ResponseBody, delivery.Error, delivery.DurationMs, now,
+		attempt, delivery.QueueItemID,
 	).Scan(
 		&d.ID, &d.WebhookID, &d.EventType, &d.Payload,
 		&d.ResponseStatus, &d.ResponseBody, &d.Error, &d.DurationMs, &d.DeliveredAt,
+		&d.Attempt, &d.QueueItemID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create delivery record: %w", err)
@@ -264,7 +529,8 @@ func (s *WebhookStore) ListDeliveries(ctx context.Context, webhookID uuid.UUID,
 	}
 
 	query := `
-		SELECT id, webhook_id, event_type, payload, response_status, response_body, error, duration_ms, delivered_at
+		SELECT id, webhook_id, event_type, payload, response_status, response_body, error, duration_ms, delivered_at,
+		       attempt, queue_item_id
 		FROM webhook_deliveries WHERE webhook_id = $1
 		ORDER BY delivered_at DESC LIMIT $2
 	`
@@ -281,6 +547,7 @@ func (s *WebhookStore) ListDeliveries(ctx context.Context, webhookID uuid.UUID,
 		if err := rows.Scan(
 			&d.ID, &d.WebhookID, &d.EventType, &d.Payload,
 			&d.ResponseStatus, &d.ResponseBody, &d.Error, &d.DurationMs, &d.DeliveredAt,
+			&d.Attempt, &d.QueueItemID,
 		); err != nil {
 			return nil, err
 		}
@@ -289,3 +556,163 @@ func (s *WebhookStore) ListDeliveries(ctx context.Context, webhookID uuid.UUID,
 
 	return deliveries, rows.Err()
 }
+
+// GetDelivery retrieves a single delivery attempt
+func (s *WebhookStore) GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, event_type, payload, response_status, response_body, error, duration_ms, delivered_at,
+		       attempt, queue_item_id
+		FROM webhook_deliveries WHERE id = $1
+	`
+
+	d := &models.WebhookDelivery{}
+	err := s.db.QueryRow(ctx, query, id).Scan(
+		&d.ID, &d.WebhookID, &d.EventType, &d.Payload,
+		&d.ResponseStatus, &d.ResponseBody, &d.Error, &d.DurationMs, &d.DeliveredAt,
+		&d.Attempt, &d.QueueItemID,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// EnqueueDelivery adds an event to a webhook's delivery queue
+func (s *WebhookStore) EnqueueDelivery(ctx context.Context, webhookID uuid.UUID, eventType string, payload string, redeliveryOf *uuid.UUID) (*models.WebhookQueueItem, error) {
+	query := `
+		INSERT INTO webhook_delivery_queue (webhook_id, event_type, payload, redelivery_of)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, webhook_id, event_type, payload, status, attempts, next_attempt_at, last_error, redelivery_of, created_at, updated_at
+	`
+
+	item := &models.WebhookQueueItem{}
+	err := s.db.QueryRow(ctx, query, webhookID, eventType, payload, redeliveryOf).Scan(
+		&item.ID, &item.WebhookID, &item.EventType, &item.Payload, &item.Status, &item.Attempts,
+		&item.NextAttemptAt, &item.LastError, &item.RedeliveryOf, &item.CreatedAt, &item.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue delivery: %w", err)
+	}
+
+	return item, nil
+}
+
+// ClaimQueueItems leases the oldest pending item of each webhook whose next attempt is due.
+// Only the head of each webhook's queue is eligible, so events are delivered in order;
+// the lease keeps other workers away until it expires.
+func (s *WebhookStore) ClaimQueueItems(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookQueueItem, error) {
+	query := `
+		WITH heads AS (
+			SELECT DISTINCT ON (webhook_id) id, next_attempt_at, locked_until
+			FROM webhook_delivery_queue
+			WHERE status = 'pending'
+			ORDER BY webhook_id, seq
+		), due AS (
+			SELECT id FROM heads
+			WHERE next_attempt_at <= NOW() AND (locked_until IS NULL OR locked_until < NOW())
+			LIMIT $1
+		)
+		UPDATE webhook_delivery_queue q
+		SET locked_until = NOW() + make_interval(secs => $2), updated_at = NOW()
+		FROM due
+		WHERE q.id = due.id AND q.status = 'pending' AND (q.locked_until IS NULL OR q.locked_until < NOW())
+		RETURNING q.id, q.webhook_id, q.event_type, q.payload, q.status, q.attempts, q.next_attempt_at,
+		          q.last_error, q.redelivery_of, q.created_at, q.updated_at
+	`
+
+	rows, err := s.db.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.WebhookQueueItem
+	for rows.Next() {
+		var item models.WebhookQueueItem
+		if err := rows.Scan(
+			&item.ID, &item.WebhookID, &item.EventType, &item.Payload, &item.Status, &item.Attempts,
+			&item.NextAttemptAt, &item.LastError, &item.RedeliveryOf, &item.CreatedAt, &item.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// CompleteQueueItem marks a queue item as delivered or permanently failed
+func (s *WebhookStore) CompleteQueueItem(ctx context.Context, id uuid.UUID, status string, attempts int, lastError *string) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE webhook_delivery_queue
+		SET status = $2, attempts = $3, last_error = $4, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, status, attempts, lastError)
+	return err
+}
+
+// RetryQueueItem releases a queue item and schedules its next attempt
+func (s *WebhookStore) RetryQueueItem(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError *string) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE webhook_delivery_queue
+		SET attempts = $2, next_attempt_at = $3, last_error = $4, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, attempts, nextAttemptAt, lastError)
+	return err
+}
+
+// PruneQueue deletes finished queue items last updated before the cutoff
+func (s *WebhookStore) PruneQueue(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.Exec(ctx, `
+		DELETE FROM webhook_delivery_queue WHERE status <> 'pending' AND updated_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// RecordWebhookFailure increments a webhook's consecutive failure count and returns the new count
+func (s *WebhookStore) RecordWebhookFailure(ctx context.Context, webhookID uuid.UUID) (int, error) {
+	var failures int
+	err := s.db.QueryRow(ctx, `
+		UPDATE webhooks SET consecutive_failures = consecutive_failures + 1
+		WHERE id = $1
+		RETURNING consecutive_failures
+	`, webhookID).Scan(&failures)
+	return failures, err
+}
+
+// ResetWebhookFailures clears a webhook's consecutive failure count after a successful delivery
+func (s *WebhookStore) ResetWebhookFailures(ctx context.Context, webhookID uuid.UUID) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures <> 0
+	`, webhookID)
+	return err
+}
+
+// DisableWebhook deactivates a webhook and fails its pending deliveries. It reports
+// false if the webhook was already inactive.
+func (s *WebhookStore) DisableWebhook(ctx context.Context, webhookID uuid.UUID, reason string) (bool, error) {
+	result, err := s.db.Exec(ctx, `
+		UPDATE webhooks SET is_active = false, disabled_reason = $2, updated_at = NOW()
+		WHERE id = $1 AND is_active = true
+	`, webhookID, reason)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE webhook_delivery_queue
+		SET status = 'failed', last_error = 'webhook disabled', locked_until = NULL, updated_at = NOW()
+		WHERE webhook_id = $1 AND status = 'pending'
+	`, webhookID)
+	return true, err
+}
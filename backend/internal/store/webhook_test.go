@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/secrets"
 )
 
 func TestNewWebhookStore(t *testing.T) {
@@ -50,10 +51,10 @@ func TestWebhookStore_Create(t *testing.T) {
 		}
 
 		// Mock insert
-		insertRows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"}).
-			AddRow(webhookID, baseID, req.Name, req.URL, eventsJSON, &secret, true, userID, now, now)
+		insertRows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+			AddRow(webhookID, baseID, req.Name, req.URL, eventsJSON, &secret, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil)
 		mock.ExpectQuery("INSERT INTO webhooks").
-			WithArgs(pgxmock.AnyArg(), baseID, req.Name, req.URL, pgxmock.AnyArg(), &secret, userID, pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), baseID, req.Name, req.URL, pgxmock.AnyArg(), &secret, userID, pgxmock.AnyArg(), []byte("[]"), []byte("[]"), models.WebhookFormatNative, (*string)(nil)).
 			WillReturnRows(insertRows)
 
 		webhook, err := store.Create(ctx, baseID, userID, req)
@@ -90,10 +91,10 @@ func TestWebhookStore_Create(t *testing.T) {
 		}
 
 		// Mock insert
-		insertRows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"}).
-			AddRow(webhookID, baseID, req.Name, req.URL, eventsJSON, nil, true, userID, now, now)
+		insertRows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+			AddRow(webhookID, baseID, req.Name, req.URL, eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil)
 		mock.ExpectQuery("INSERT INTO webhooks").
-			WithArgs(pgxmock.AnyArg(), baseID, req.Name, req.URL, pgxmock.AnyArg(), (*string)(nil), userID, pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), baseID, req.Name, req.URL, pgxmock.AnyArg(), (*string)(nil), userID, pgxmock.AnyArg(), []byte("[]"), []byte("[]"), models.WebhookFormatNative, (*string)(nil)).
 			WillReturnRows(insertRows)
 
 		webhook, err := store.Create(ctx, baseID, userID, req)
@@ -102,6 +103,41 @@ func TestWebhookStore_Create(t *testing.T) {
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("stores format and message template", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		baseID := uuid.New()
+		userID := uuid.New()
+		now := time.Now().UTC()
+		template := "New lead: {{field:Name}}"
+		eventsJSON, _ := json.Marshal([]models.WebhookEvent{models.WebhookEventRecordCreated})
+
+		req := &models.CreateWebhookRequest{
+			Name:            "Slack",
+			URL:             "https://hooks.slack.com/services/T/B/X",
+			Events:          []models.WebhookEvent{models.WebhookEventRecordCreated},
+			Format:          models.WebhookFormatSlack,
+			MessageTemplate: &template,
+		}
+
+		insertRows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+			AddRow(uuid.New(), baseID, req.Name, req.URL, eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatSlack, &template, nil, nil)
+		mock.ExpectQuery("INSERT INTO webhooks").
+			WithArgs(pgxmock.AnyArg(), baseID, req.Name, req.URL, pgxmock.AnyArg(), (*string)(nil), userID, pgxmock.AnyArg(), []byte("[]"), []byte("[]"), models.WebhookFormatSlack, &template).
+			WillReturnRows(insertRows)
+
+		webhook, err := store.Create(ctx, baseID, userID, req)
+		require.NoError(t, err)
+		assert.Equal(t, models.WebhookFormatSlack, webhook.Format)
+		require.NotNil(t, webhook.MessageTemplate)
+		assert.Equal(t, template, *webhook.MessageTemplate)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestWebhookStore_GetByID(t *testing.T) {
@@ -121,8 +157,8 @@ func TestWebhookStore_GetByID(t *testing.T) {
 		events := []models.WebhookEvent{models.WebhookEventRecordCreated}
 		eventsJSON, _ := json.Marshal(events)
 
-		rows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"}).
-			AddRow(webhookID, baseID, "Test Webhook", "https://example.com/webhook", eventsJSON, nil, true, userID, now, now)
+		rows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+			AddRow(webhookID, baseID, "Test Webhook", "https://example.com/webhook", eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil)
 		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
 			WithArgs(webhookID).
 			WillReturnRows(rows)
@@ -175,9 +211,9 @@ func TestWebhookStore_ListByBase(t *testing.T) {
 		events := []models.WebhookEvent{models.WebhookEventRecordCreated}
 		eventsJSON, _ := json.Marshal(events)
 
-		rows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"}).
-			AddRow(webhookID1, baseID, "Webhook 1", "https://example.com/1", eventsJSON, nil, true, userID, now, now).
-			AddRow(webhookID2, baseID, "Webhook 2", "https://example.com/2", eventsJSON, nil, true, userID, now, now)
+		rows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+			AddRow(webhookID1, baseID, "Webhook 1", "https://example.com/1", eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil).
+			AddRow(webhookID2, baseID, "Webhook 2", "https://example.com/2", eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil)
 		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
 			WithArgs(baseID).
 			WillReturnRows(rows)
@@ -200,7 +236,7 @@ func TestWebhookStore_ListByBase(t *testing.T) {
 		store := NewWebhookStore(mock, baseStore)
 		baseID := uuid.New()
 
-		rows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"})
+		rows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"})
 		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
 			WithArgs(baseID).
 			WillReturnRows(rows)
@@ -230,17 +266,49 @@ func TestWebhookStore_GetActiveByBaseAndEvent(t *testing.T) {
 		events := []models.WebhookEvent{models.WebhookEventRecordCreated}
 		eventsJSON, _ := json.Marshal(events)
 		eventQuery, _ := json.Marshal([]models.WebhookEvent{models.WebhookEventRecordCreated})
+		tableID := uuid.New()
+		tableQuery, _ := json.Marshal([]uuid.UUID{tableID})
 
-		rows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"}).
-			AddRow(webhookID, baseID, "Test Webhook", "https://example.com/webhook", eventsJSON, nil, true, userID, now, now)
+		rows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+			AddRow(webhookID, baseID, "Test Webhook", "https://example.com/webhook", eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil)
 		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
-			WithArgs(baseID, eventQuery).
+			WithArgs(baseID, eventQuery, tableQuery).
 			WillReturnRows(rows)
 
-		webhooks, err := store.GetActiveByBaseAndEvent(ctx, baseID, models.WebhookEventRecordCreated)
+		webhooks, err := store.GetActiveByBaseAndEvent(ctx, baseID, tableID, models.WebhookEventRecordCreated)
 		require.NoError(t, err)
 		assert.Len(t, webhooks, 1)
 		assert.Equal(t, webhookID, webhooks[0].ID)
+		assert.Empty(t, webhooks[0].TableIDs)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("decodes table and field filters", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		baseID := uuid.New()
+		tableID := uuid.New()
+		fieldID := uuid.New()
+		now := time.Now().UTC()
+		eventsJSON, _ := json.Marshal([]models.WebhookEvent{models.WebhookEventRecordUpdated})
+		tableIDsJSON, _ := json.Marshal([]uuid.UUID{tableID})
+		fieldIDsJSON, _ := json.Marshal([]uuid.UUID{fieldID})
+
+		rows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+			AddRow(uuid.New(), baseID, "Filtered", "https://example.com/webhook", eventsJSON, nil, true, uuid.New(), now, now, 0, nil, tableIDsJSON, fieldIDsJSON, models.WebhookFormatNative, nil, nil, nil)
+		mock.ExpectQuery("table_ids @> \\$3").
+			WithArgs(baseID, pgxmock.AnyArg(), tableIDsJSON).
+			WillReturnRows(rows)
+
+		webhooks, err := store.GetActiveByBaseAndEvent(ctx, baseID, tableID, models.WebhookEventRecordUpdated)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, []uuid.UUID{tableID}, webhooks[0].TableIDs)
+		assert.Equal(t, []uuid.UUID{fieldID}, webhooks[0].WatchFieldIDs)
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -265,8 +333,8 @@ func TestWebhookStore_Update(t *testing.T) {
 		newName := "Updated Webhook"
 
 		// Mock GetByID
-		getRows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"}).
-			AddRow(webhookID, baseID, "Old Name", "https://example.com/webhook", eventsJSON, nil, true, userID, now, now)
+		getRows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+			AddRow(webhookID, baseID, "Old Name", "https://example.com/webhook", eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil)
 		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
 			WithArgs(webhookID).
 			WillReturnRows(getRows)
@@ -274,7 +342,7 @@ func TestWebhookStore_Update(t *testing.T) {
 		// Mock Update
 		updateRows := pgxmock.NewRows([]string{"updated_at"}).AddRow(now)
 		mock.ExpectQuery("UPDATE webhooks").
-			WithArgs(webhookID, newName, "https://example.com/webhook", pgxmock.AnyArg(), (*string)(nil), true).
+			WithArgs(webhookID, newName, "https://example.com/webhook", pgxmock.AnyArg(), (*string)(nil), true, false, []byte("[]"), []byte("[]"), models.WebhookFormatNative, (*string)(nil), (*string)(nil), (*time.Time)(nil)).
 			WillReturnRows(updateRows)
 
 		req := &models.UpdateWebhookRequest{
@@ -289,6 +357,214 @@ func TestWebhookStore_Update(t *testing.T) {
 	})
 }
 
+// sealedArg matches a secret argument that was encrypted with the given box
+type sealedArg struct {
+	box       *secrets.Box
+	plaintext string
+}
+
+func (a sealedArg) Match(v interface{}) bool {
+	value, ok := v.(*string)
+	if !ok || value == nil || !secrets.IsEncrypted(*value) {
+		return false
+	}
+	opened, err := a.box.Decrypt(*value)
+	return err == nil && opened == a.plaintext
+}
+
+func testSecretBox(t *testing.T) *secrets.Box {
+	box, err := secrets.NewBox([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	return box
+}
+
+func TestWebhookStore_SecretEncryption(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("encrypts secrets on create and decrypts them on read", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		box := testSecretBox(t)
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		store.SetSecretBox(box)
+		baseID := uuid.New()
+		userID := uuid.New()
+		now := time.Now().UTC()
+		secret := "whsec_test"
+		sealed, err := box.Encrypt(secret)
+		require.NoError(t, err)
+		eventsJSON, _ := json.Marshal([]models.WebhookEvent{models.WebhookEventRecordCreated})
+
+		req := &models.CreateWebhookRequest{
+			Name:   "Encrypted",
+			URL:    "https://example.com/webhook",
+			Events: []models.WebhookEvent{models.WebhookEventRecordCreated},
+			Secret: &secret,
+		}
+
+		mock.ExpectQuery("INSERT INTO webhooks").
+			WithArgs(pgxmock.AnyArg(), baseID, req.Name, req.URL, pgxmock.AnyArg(), sealedArg{box, secret}, userID, pgxmock.AnyArg(), []byte("[]"), []byte("[]"), models.WebhookFormatNative, (*string)(nil)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+				AddRow(uuid.New(), baseID, req.Name, req.URL, eventsJSON, &sealed, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil))
+
+		webhook, err := store.Create(ctx, baseID, userID, req)
+		require.NoError(t, err)
+		require.NotNil(t, webhook.Secret)
+		assert.Equal(t, secret, *webhook.Secret)
+		assert.True(t, webhook.HasSecret)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reads secrets stored before encryption was enabled", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		store.SetSecretBox(testSecretBox(t))
+		webhookID := uuid.New()
+		now := time.Now().UTC()
+		legacy := "legacy-secret"
+
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret").
+			WithArgs(webhookID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+				AddRow(webhookID, uuid.New(), "Legacy", "https://example.com/webhook", []byte("[]"), &legacy, true, uuid.New(), now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil))
+
+		webhook, err := store.GetByID(ctx, webhookID)
+		require.NoError(t, err)
+		assert.Equal(t, legacy, *webhook.Secret)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails to read encrypted secrets without a key", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		webhookID := uuid.New()
+		now := time.Now().UTC()
+		sealed, err := testSecretBox(t).Encrypt("whsec_test")
+		require.NoError(t, err)
+
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret").
+			WithArgs(webhookID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+				AddRow(webhookID, uuid.New(), "Sealed", "https://example.com/webhook", []byte("[]"), &sealed, true, uuid.New(), now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil))
+
+		_, err = store.GetByID(ctx, webhookID)
+		assert.ErrorIs(t, err, secrets.ErrNoKey)
+	})
+}
+
+func TestWebhookStore_RotateSecret(t *testing.T) {
+	ctx := context.Background()
+
+	expectGet := func(mock pgxmock.PgxPoolIface, webhookID uuid.UUID, secret *string) {
+		now := time.Now().UTC()
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret").
+			WithArgs(webhookID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}).
+				AddRow(webhookID, uuid.New(), "Webhook", "https://example.com/webhook", []byte("[]"), secret, true, uuid.New(), now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil))
+	}
+
+	t.Run("keeps the old secret during the grace period", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		webhookID := uuid.New()
+		oldSecret := "old-secret"
+		newSecret := "new-secret"
+
+		expectGet(mock, webhookID, &oldSecret)
+		mock.ExpectQuery("UPDATE webhooks").
+			WithArgs(webhookID, &newSecret, &oldSecret, pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+
+		webhook, err := store.RotateSecret(ctx, webhookID, newSecret, 24*time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, newSecret, *webhook.Secret)
+		assert.Equal(t, oldSecret, *webhook.PreviousSecret)
+		require.NotNil(t, webhook.PreviousSecretExpiresAt)
+		assert.WithinDuration(t, time.Now().Add(24*time.Hour), *webhook.PreviousSecretExpiresAt, time.Minute)
+		assert.Equal(t, []string{newSecret, oldSecret}, webhook.SigningSecrets(time.Now()))
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("revokes the old secret immediately without a grace period", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		webhookID := uuid.New()
+		oldSecret := "old-secret"
+		newSecret := "new-secret"
+
+		expectGet(mock, webhookID, &oldSecret)
+		mock.ExpectQuery("UPDATE webhooks").
+			WithArgs(webhookID, &newSecret, (*string)(nil), (*time.Time)(nil)).
+			WillReturnRows(pgxmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+
+		webhook, err := store.RotateSecret(ctx, webhookID, newSecret, 0)
+		require.NoError(t, err)
+		assert.Nil(t, webhook.PreviousSecret)
+		assert.Equal(t, []string{newSecret}, webhook.SigningSecrets(time.Now()))
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookStore_EncryptPlaintextSecrets(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("does nothing without a key", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		n, err := NewWebhookStore(mock, NewBaseStore(mock)).EncryptPlaintextSecrets(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("encrypts only plaintext secrets", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		box := testSecretBox(t)
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		store.SetSecretBox(box)
+		plainID := uuid.New()
+		plain := "plain-secret"
+		sealed, err := box.Encrypt("sealed-secret")
+		require.NoError(t, err)
+
+		mock.ExpectQuery("SELECT id, secret, previous_secret FROM webhooks").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "secret", "previous_secret"}).
+				AddRow(plainID, &plain, nil).
+				AddRow(uuid.New(), &sealed, nil))
+		mock.ExpectExec("UPDATE webhooks SET secret").
+			WithArgs(plainID, sealedArg{box, plain}, (*string)(nil)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		n, err := store.EncryptPlaintextSecrets(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestWebhookStore_Delete(t *testing.T) {
 	ctx := context.Background()
 
@@ -360,10 +636,10 @@ func TestWebhookStore_CreateDelivery(t *testing.T) {
 		}
 
 		// Mock insert
-		insertRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record_created", payload, &status, &responseBody, nil, &durationMs, now)
+		insertRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at", "attempt", "queue_item_id"}).
+			AddRow(deliveryID, webhookID, "record_created", payload, &status, &responseBody, nil, &durationMs, now, 1, nil)
 		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record_created", payload, &status, &responseBody, (*string)(nil), &durationMs, pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), webhookID, "record_created", payload, &status, &responseBody, (*string)(nil), &durationMs, pgxmock.AnyArg(), 1, (*uuid.UUID)(nil)).
 			WillReturnRows(insertRows)
 
 		result, err := store.CreateDelivery(ctx, delivery)
@@ -397,10 +673,10 @@ func TestWebhookStore_CreateDelivery(t *testing.T) {
 		}
 
 		// Mock insert
-		insertRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record_created", payload, nil, nil, &errorMsg, &durationMs, now)
+		insertRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at", "attempt", "queue_item_id"}).
+			AddRow(deliveryID, webhookID, "record_created", payload, nil, nil, &errorMsg, &durationMs, now, 1, nil)
 		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record_created", payload, (*int)(nil), (*string)(nil), &errorMsg, &durationMs, pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), webhookID, "record_created", payload, (*int)(nil), (*string)(nil), &errorMsg, &durationMs, pgxmock.AnyArg(), 1, (*uuid.UUID)(nil)).
 			WillReturnRows(insertRows)
 
 		result, err := store.CreateDelivery(ctx, delivery)
@@ -431,9 +707,9 @@ func TestWebhookStore_ListDeliveries(t *testing.T) {
 		durationMs2 := 150
 		payload := `{"test": "data"}`
 
-		rows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID1, webhookID, "record_created", payload, &status, &responseBody, nil, &durationMs1, now).
-			AddRow(deliveryID2, webhookID, "record_updated", payload, &status, &responseBody, nil, &durationMs2, now)
+		rows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at", "attempt", "queue_item_id"}).
+			AddRow(deliveryID1, webhookID, "record_created", payload, &status, &responseBody, nil, &durationMs1, now, 1, nil).
+			AddRow(deliveryID2, webhookID, "record_updated", payload, &status, &responseBody, nil, &durationMs2, now, 1, nil)
 		mock.ExpectQuery("SELECT id, webhook_id, event_type, payload, response_status, response_body, error, duration_ms, delivered_at").
 			WithArgs(webhookID, 50).
 			WillReturnRows(rows)
@@ -456,7 +732,7 @@ func TestWebhookStore_ListDeliveries(t *testing.T) {
 		store := NewWebhookStore(mock, baseStore)
 		webhookID := uuid.New()
 
-		rows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"})
+		rows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at", "attempt", "queue_item_id"})
 		mock.ExpectQuery("SELECT id, webhook_id, event_type, payload, response_status, response_body, error, duration_ms, delivered_at").
 			WithArgs(webhookID, 50).
 			WillReturnRows(rows)
@@ -468,3 +744,149 @@ func TestWebhookStore_ListDeliveries(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestWebhookStore_GetDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns not found for missing delivery", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		deliveryID := uuid.New()
+
+		mock.ExpectQuery("SELECT id, webhook_id, event_type, payload").
+			WithArgs(deliveryID).
+			WillReturnError(pgx.ErrNoRows)
+
+		delivery, err := store.GetDelivery(ctx, deliveryID)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Nil(t, delivery)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+var queueColumns = []string{"id", "webhook_id", "event_type", "payload", "status", "attempts", "next_attempt_at", "last_error", "redelivery_of", "created_at", "updated_at"}
+
+func TestWebhookStore_EnqueueDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("inserts a pending queue item", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		webhookID := uuid.New()
+		itemID := uuid.New()
+		originalID := uuid.New()
+		now := time.Now()
+
+		mock.ExpectQuery("INSERT INTO webhook_delivery_queue").
+			WithArgs(webhookID, "record.created", `{"a":1}`, &originalID).
+			WillReturnRows(pgxmock.NewRows(queueColumns).
+				AddRow(itemID, webhookID, "record.created", `{"a":1}`, models.WebhookQueuePending, 0, now, nil, &originalID, now, now))
+
+		item, err := store.EnqueueDelivery(ctx, webhookID, "record.created", `{"a":1}`, &originalID)
+		require.NoError(t, err)
+		assert.Equal(t, itemID, item.ID)
+		assert.Equal(t, models.WebhookQueuePending, item.Status)
+		assert.Equal(t, &originalID, item.RedeliveryOf)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookStore_ClaimQueueItems(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("leases due queue heads", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		now := time.Now()
+
+		mock.ExpectQuery("WITH heads AS").
+			WithArgs(10, float64(120)).
+			WillReturnRows(pgxmock.NewRows(queueColumns).
+				AddRow(uuid.New(), uuid.New(), "record.created", "{}", models.WebhookQueuePending, 0, now, nil, nil, now, now).
+				AddRow(uuid.New(), uuid.New(), "record.updated", "{}", models.WebhookQueuePending, 2, now, nil, nil, now, now))
+
+		items, err := store.ClaimQueueItems(ctx, 10, 2*time.Minute)
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+		assert.Equal(t, 2, items[1].Attempts)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookStore_RecordWebhookFailure(t *testing.T) {
+	ctx := context.Background()
+
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	store := NewWebhookStore(mock, NewBaseStore(mock))
+	webhookID := uuid.New()
+
+	mock.ExpectQuery("UPDATE webhooks SET consecutive_failures = consecutive_failures \\+ 1").
+		WithArgs(webhookID).
+		WillReturnRows(pgxmock.NewRows([]string{"consecutive_failures"}).AddRow(3))
+
+	failures, err := store.RecordWebhookFailure(ctx, webhookID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, failures)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookStore_DisableWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disables active webhook and fails pending deliveries", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		webhookID := uuid.New()
+
+		mock.ExpectExec("UPDATE webhooks SET is_active = false").
+			WithArgs(webhookID, "too many failures").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(webhookID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+		disabled, err := store.DisableWebhook(ctx, webhookID, "too many failures")
+		require.NoError(t, err)
+		assert.True(t, disabled)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reports already inactive webhook", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWebhookStore(mock, NewBaseStore(mock))
+		webhookID := uuid.New()
+
+		mock.ExpectExec("UPDATE webhooks SET is_active = false").
+			WithArgs(webhookID, "too many failures").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		disabled, err := store.DisableWebhook(ctx, webhookID, "too many failures")
+		require.NoError(t, err)
+		assert.False(t, disabled)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
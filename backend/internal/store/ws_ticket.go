@@ -3,12 +3,14 @@ package store
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
@@ -16,48 +18,25 @@ const (
 	wsTicketLifetime = 30 * time.Second // Short-lived for security
 )
 
-// WSTicket represents a one-time WebSocket authentication ticket
-type WSTicket struct {
-	Ticket    string
-	UserID    uuid.UUID
-	BaseID    uuid.UUID
-	ExpiresAt time.Time
-	Used      bool
-}
-
-// WSTicketStore manages WebSocket authentication tickets
+// WSTicketStore manages one-time WebSocket authentication tickets. Tickets are kept
+// in the database so the connection can land on any instance.
 type WSTicketStore struct {
-	mu      sync.Mutex
-	tickets map[string]*WSTicket
+	db DBTX
 }
 
 // NewWSTicketStore creates a new WebSocket ticket store
-func NewWSTicketStore() *WSTicketStore {
-	store := &WSTicketStore{
-		tickets: make(map[string]*WSTicket),
-	}
-	// Start cleanup goroutine
-	go store.cleanup()
-	return store
+func NewWSTicketStore(db DBTX) *WSTicketStore {
+	return &WSTicketStore{db: db}
 }
 
-// cleanup removes expired tickets periodically
-func (s *WSTicketStore) cleanup() {
-	ticker := time.NewTicker(time.Minute)
-	for range ticker.C {
-		s.mu.Lock()
-		now := time.Now()
-		for ticket, t := range s.tickets {
-			if t.ExpiresAt.Before(now) || t.Used {
-				delete(s.tickets, ticket)
-			}
-		}
-		s.mu.Unlock()
-	}
+// hashWSTicket creates a SHA-256 hash of a ticket
+func hashWSTicket(ticket string) string {
+	hash := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(hash[:])
 }
 
-// GenerateTicket creates a new one-time WebSocket ticket for a user
-func (s *WSTicketStore) GenerateTicket(ctx context.Context, userID, baseID uuid.UUID) (string, error) {
+// GenerateTicket creates a new one-time WebSocket ticket for a user's session
+func (s *WSTicketStore) GenerateTicket(ctx context.Context, userID, sessionID, baseID uuid.UUID) (string, error) {
 	// Generate random ticket
 	ticketBytes := make([]byte, wsTicketLength)
 	if _, err := rand.Read(ticketBytes); err != nil {
@@ -65,46 +44,49 @@ func (s *WSTicketStore) GenerateTicket(ctx context.Context, userID, baseID uuid.
 	}
 	ticket := base64.URLEncoding.EncodeToString(ticketBytes)
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.tickets[ticket] = &WSTicket{
-		Ticket:    ticket,
-		UserID:    userID,
-		BaseID:    baseID,
-		ExpiresAt: time.Now().Add(wsTicketLifetime),
-		Used:      false,
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO ws_tickets (ticket_hash, user_id, session_id, base_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, hashWSTicket(ticket), userID, sessionID, baseID, time.Now().Add(wsTicketLifetime))
+	if err != nil {
+		return "", err
 	}
 
 	return ticket, nil
 }
 
-// ValidateTicket validates and consumes a WebSocket ticket (one-time use)
-func (s *WSTicketStore) ValidateTicket(ctx context.Context, ticket string, baseID uuid.UUID) (uuid.UUID, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	t, exists := s.tickets[ticket]
-	if !exists {
-		return uuid.Nil, errors.New("ticket not found")
+// ValidateTicket validates and consumes a WebSocket ticket (one-time use), returning
+// the user and session it was issued to
+func (s *WSTicketStore) ValidateTicket(ctx context.Context, ticket string, baseID uuid.UUID) (uuid.UUID, uuid.UUID, error) {
+	// Deleting the ticket claims it, so it can only be used once across instances
+	var userID uuid.UUID
+	var sessionID *uuid.UUID
+	var expiresAt time.Time
+	err := s.db.QueryRow(ctx, `
+		DELETE FROM ws_tickets
+		WHERE ticket_hash = $1 AND base_id = $2
+		RETURNING user_id, session_id, expires_at
+	`, hashWSTicket(ticket), baseID).Scan(&userID, &sessionID, &expiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, uuid.Nil, errors.New("ticket not found")
 	}
-
-	if t.Used {
-		return uuid.Nil, errors.New("ticket already used")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
 	}
 
-	if time.Now().After(t.ExpiresAt) {
-		delete(s.tickets, ticket)
-		return uuid.Nil, errors.New("ticket expired")
+	if time.Now().After(expiresAt) {
+		return uuid.Nil, uuid.Nil, errors.New("ticket expired")
 	}
-
-	if t.BaseID != baseID {
-		return uuid.Nil, errors.New("ticket not valid for this base")
+	if sessionID == nil {
+		// Issued before tickets recorded their session
+		return uuid.Nil, uuid.Nil, errors.New("ticket has no session")
 	}
 
-	// Mark as used and delete
-	t.Used = true
-	delete(s.tickets, ticket)
+	return userID, *sessionID, nil
+}
 
-	return t.UserID, nil
+// CleanupExpiredTickets removes tickets that were never used
+func (s *WSTicketStore) CleanupExpiredTickets(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM ws_tickets WHERE expires_at < NOW()`)
+	return err
 }
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSTicketStore_GenerateTicket(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	store := NewWSTicketStore(mock)
+	userID := uuid.New()
+	sessionID := uuid.New()
+	baseID := uuid.New()
+
+	mock.ExpectExec("INSERT INTO ws_tickets").
+		WithArgs(pgxmock.AnyArg(), userID, sessionID, baseID, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	ticket, err := store.GenerateTicket(context.Background(), userID, sessionID, baseID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, ticket)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWSTicketStore_ValidateTicket(t *testing.T) {
+	ctx := context.Background()
+	ticket := "ticket"
+	baseID := uuid.New()
+	sessionID := uuid.New()
+
+	t.Run("consumes a valid ticket", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		store := NewWSTicketStore(mock)
+		userID := uuid.New()
+
+		mock.ExpectQuery("DELETE FROM ws_tickets").
+			WithArgs(hashWSTicket(ticket), baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"user_id", "session_id", "expires_at"}).AddRow(userID, &sessionID, time.Now().Add(time.Minute)))
+
+		gotUser, gotSession, err := store.ValidateTicket(ctx, ticket, baseID)
+		require.NoError(t, err)
+		assert.Equal(t, userID, gotUser)
+		assert.Equal(t, sessionID, gotSession)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects unknown or already used tickets", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery("DELETE FROM ws_tickets").
+			WithArgs(hashWSTicket(ticket), baseID).
+			WillReturnError(pgx.ErrNoRows)
+
+		_, _, err = NewWSTicketStore(mock).ValidateTicket(ctx, ticket, baseID)
+		assert.EqualError(t, err, "ticket not found")
+	})
+
+	t.Run("rejects expired tickets", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery("DELETE FROM ws_tickets").
+			WithArgs(hashWSTicket(ticket), baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"user_id", "session_id", "expires_at"}).AddRow(uuid.New(), &sessionID, time.Now().Add(-time.Second)))
+
+		_, _, err = NewWSTicketStore(mock).ValidateTicket(ctx, ticket, baseID)
+		assert.EqualError(t, err, "ticket expired")
+	})
+
+	t.Run("rejects tickets without a session", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		mock.ExpectQuery("DELETE FROM ws_tickets").
+			WithArgs(hashWSTicket(ticket), baseID).
+			WillReturnRows(pgxmock.NewRows([]string{"user_id", "session_id", "expires_at"}).AddRow(uuid.New(), (*uuid.UUID)(nil), time.Now().Add(time.Minute)))
+
+		_, _, err = NewWSTicketStore(mock).ValidateTicket(ctx, ticket, baseID)
+		assert.EqualError(t, err, "ticket has no session")
+	})
+}
@@ -0,0 +1,102 @@
+// Package thumbnail generates thumbnails and reads dimensions of image attachments.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	// Register the decoders for the formats thumbnails are made from
+	_ "image/gif"
+	_ "image/png"
+)
+
+// maxPixels bounds the images decoded, so a small file that declares huge
+// dimensions cannot exhaust memory
+const maxPixels = 40_000_000
+
+// jpegQuality is the quality thumbnails are encoded with
+const jpegQuality = 85
+
+// ErrTooLarge is returned for images with more than maxPixels pixels
+var ErrTooLarge = errors.New("image is too large to thumbnail")
+
+// Decode reads an image, checking its dimensions before decoding it. The
+// dimensions are returned even when the image is too large to decode.
+func Decode(data []byte) (image.Image, image.Config, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, config, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, config, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, config, err
+	}
+	return img, config, nil
+}
+
+// Fit scales width x height down to fit within maxDimension on both sides,
+// keeping the aspect ratio. Images that already fit are left as they are.
+func Fit(width, height, maxDimension int) (int, int) {
+	if width <= maxDimension && height <= maxDimension {
+		return width, height
+	}
+	if width >= height {
+		return maxDimension, max(1, height*maxDimension/width)
+	}
+	return max(1, width*maxDimension/height), maxDimension
+}
+
+// Resize scales src to width x height by averaging the source pixels each
+// thumbnail pixel covers. Transparent areas are flattened onto white.
+func Resize(src image.Image, width, height int) *image.RGBA {
+	// Flatten onto white first, which also gives fast access to the pixels
+	bounds := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	srcW, srcH := flat.Rect.Dx(), flat.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max((y+1)*srcH/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max((x+1)*srcW/width, x0+1)
+
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := flat.Pix[sy*flat.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					n++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
+
+// Encode writes a JPEG thumbnail of img that fits within maxDimension
+func Encode(w io.Writer, img image.Image, maxDimension int) error {
+	bounds := img.Bounds()
+	width, height := Fit(bounds.Dx(), bounds.Dy(), maxDimension)
+	return jpeg.Encode(w, Resize(img, width, height), &jpeg.Options{Quality: jpegQuality})
+}
@@ -0,0 +1,118 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		wantW, wantH  int
+	}{
+		{"landscape", 2000, 1000, 256, 128},
+		{"portrait", 1000, 2000, 128, 256},
+		{"square", 512, 512, 256, 256},
+		{"already fits", 100, 50, 100, 50},
+		{"very thin", 10000, 1, 256, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := Fit(tt.width, tt.height, 256)
+			assert.Equal(t, tt.wantW, w)
+			assert.Equal(t, tt.wantH, h)
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	t.Run("averages the pixels each thumbnail pixel covers", func(t *testing.T) {
+		// Left half black, right half white
+		src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 4; x++ {
+				if x >= 2 {
+					src.Set(x, y, color.White)
+				} else {
+					src.Set(x, y, color.Black)
+				}
+			}
+		}
+
+		dst := Resize(src, 2, 1)
+		assert.Equal(t, color.RGBA{0, 0, 0, 0xff}, dst.RGBAAt(0, 0))
+		assert.Equal(t, color.RGBA{0xff, 0xff, 0xff, 0xff}, dst.RGBAAt(1, 0))
+
+		dst = Resize(src, 1, 1)
+		assert.Equal(t, color.RGBA{0x7f, 0x7f, 0x7f, 0xff}, dst.RGBAAt(0, 0))
+	})
+
+	t.Run("flattens transparency onto white", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		dst := Resize(src, 1, 1)
+		assert.Equal(t, color.RGBA{0xff, 0xff, 0xff, 0xff}, dst.RGBAAt(0, 0))
+	})
+
+	t.Run("handles images not anchored at the origin", func(t *testing.T) {
+		src := image.NewRGBA(image.Rect(10, 10, 12, 12))
+		for y := 10; y < 12; y++ {
+			for x := 10; x < 12; x++ {
+				src.Set(x, y, color.RGBA{0xff, 0, 0, 0xff})
+			}
+		}
+		dst := Resize(src, 1, 1)
+		assert.Equal(t, color.RGBA{0xff, 0, 0, 0xff}, dst.RGBAAt(0, 0))
+	})
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("decodes an image and its dimensions", func(t *testing.T) {
+		data := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 30, 20)))
+
+		img, config, err := Decode(data)
+		require.NoError(t, err)
+		assert.Equal(t, 30, config.Width)
+		assert.Equal(t, 20, config.Height)
+		assert.Equal(t, 30, img.Bounds().Dx())
+	})
+
+	t.Run("refuses images that are too large but keeps their dimensions", func(t *testing.T) {
+		data := encodePNG(t, image.NewGray(image.Rect(0, 0, 8000, 6000)))
+
+		img, config, err := Decode(data)
+		assert.ErrorIs(t, err, ErrTooLarge)
+		assert.Nil(t, img)
+		assert.Equal(t, 8000, config.Width)
+		assert.Equal(t, 6000, config.Height)
+	})
+
+	t.Run("returns an error for data that is not an image", func(t *testing.T) {
+		_, _, err := Decode([]byte("not an image"))
+		assert.Error(t, err)
+	})
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1200, 600)), 256))
+
+	config, err := jpeg.DecodeConfig(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 256, config.Width)
+	assert.Equal(t, 128, config.Height)
+}
@@ -0,0 +1,148 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/storage"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// Worker settings
+const (
+	batchSize      = 10
+	pollInterval   = time.Minute
+	maxSourceBytes = 50 << 20 // Larger originals are not thumbnailed
+)
+
+// Worker generates thumbnails for image attachments in the background
+type Worker struct {
+	attachments *store.AttachmentStore
+	storage     storage.Storage
+	wake        chan struct{}
+}
+
+// NewWorker creates a thumbnail worker
+func NewWorker(attachments *store.AttachmentStore, stor storage.Storage) *Worker {
+	return &Worker{
+		attachments: attachments,
+		storage:     stor,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes the worker to look for new images
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the worker in the background until ctx is cancelled
+func (w *Worker) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *Worker) run(ctx context.Context) {
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+
+	for {
+		w.processPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		case <-poll.C:
+		}
+	}
+}
+
+// processPending generates thumbnails until no images are left waiting
+func (w *Worker) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		attachments, err := w.attachments.ListPendingThumbnails(ctx, batchSize)
+		if err != nil {
+			log.Printf("Failed to list images waiting for thumbnails: %v", err)
+			return
+		}
+
+		for _, a := range attachments {
+			if err := w.process(ctx, a); err != nil {
+				// Leave it pending to be retried on the next poll
+				log.Printf("Failed to save thumbnails for attachment %s: %v", a.ID, err)
+				return
+			}
+		}
+
+		if len(attachments) < batchSize {
+			return
+		}
+	}
+}
+
+// process generates every thumbnail size for one image and records the outcome.
+// Originals that cannot be read or decoded are marked failed rather than retried.
+func (w *Worker) process(ctx context.Context, a *models.Attachment) error {
+	data, err := w.download(ctx, a.StorageKey)
+	if err != nil {
+		log.Printf("Cannot read attachment %s for thumbnails: %v", a.ID, err)
+		return w.attachments.SetImageInfo(ctx, a.ID, store.ThumbnailFailed, nil, nil, nil)
+	}
+
+	img, config, err := Decode(data)
+	var width, height *int
+	if config.Width > 0 && config.Height > 0 {
+		width, height = &config.Width, &config.Height
+	}
+	if err != nil {
+		log.Printf("Cannot thumbnail attachment %s: %v", a.ID, err)
+		return w.attachments.SetImageInfo(ctx, a.ID, store.ThumbnailFailed, nil, width, height)
+	}
+
+	thumbnailKey := KeyPrefix(a.StorageKey)
+	a.ThumbnailKey = &thumbnailKey
+	for _, size := range models.ThumbnailSizes {
+		var buf bytes.Buffer
+		if err := Encode(&buf, img, size.MaxDimension); err != nil {
+			return err
+		}
+		if err := w.storage.Upload(ctx, a.ThumbnailStorageKey(size.Name), &buf, "image/jpeg"); err != nil {
+			return err
+		}
+	}
+
+	return w.attachments.SetImageInfo(ctx, a.ID, store.ThumbnailReady, &thumbnailKey, width, height)
+}
+
+// download reads an original into memory, refusing ones over maxSourceBytes
+func (w *Worker) download(ctx context.Context, key string) ([]byte, error) {
+	reader, err := w.storage.Download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxSourceBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSourceBytes {
+		return nil, fmt.Errorf("%w: over %d bytes", ErrTooLarge, maxSourceBytes)
+	}
+	return data, nil
+}
+
+// KeyPrefix returns the prefix the thumbnails of an original are stored under
+func KeyPrefix(storageKey string) string {
+	return strings.TrimSuffix(storageKey, path.Ext(storageKey)) + "_thumb"
+}
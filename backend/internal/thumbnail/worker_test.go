@@ -0,0 +1,159 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/storage"
+	"github.com/vibetable/backend/internal/store"
+)
+
+// memoryStorage implements storage.Storage in memory for testing
+type memoryStorage struct {
+	files map[string][]byte
+}
+
+func (m *memoryStorage) Upload(ctx context.Context, key string, data io.Reader, contentType string) error {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	m.files[key] = b
+	return nil
+}
+
+func (m *memoryStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	b, ok := m.files[key]
+	if !ok {
+		return nil, errors.New("file not found")
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *memoryStorage) GetURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "http://localhost/files/" + key, nil
+}
+
+func (m *memoryStorage) Delete(ctx context.Context, key string) error {
+	delete(m.files, key)
+	return nil
+}
+
+func (m *memoryStorage) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := m.files[key]
+	return ok, nil
+}
+
+func (m *memoryStorage) List(ctx context.Context, fn func(storage.ObjectInfo) error) error {
+	for key, data := range m.files {
+		if err := fn(storage.ObjectInfo{Key: key, Size: int64(len(data))}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expectPending(mock pgxmock.PgxPoolIface, attachmentID uuid.UUID, contentType, storageKey string) {
+	mock.ExpectQuery("SELECT id, record_id, field_id").
+		WithArgs(store.ThumbnailPending, batchSize).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "record_id", "field_id", "filename", "content_type", "size_bytes",
+			"storage_key", "thumbnail_key", "width", "height", "created_by", "created_at",
+		}).AddRow(
+			attachmentID, uuid.New(), uuid.New(), "file", contentType, int64(100),
+			storageKey, nil, nil, nil, uuid.New(), time.Now().UTC(),
+		))
+}
+
+func TestWorker_processPending(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores every size and records the dimensions", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		stor := &memoryStorage{files: map[string][]byte{
+			"field/record/photo.png": encodePNG(t, image.NewRGBA(image.Rect(0, 0, 2048, 1024))),
+		}}
+		worker := NewWorker(store.NewAttachmentStore(mock, nil, nil, nil, stor, "http://localhost"), stor)
+
+		attachmentID := uuid.New()
+		thumbnailKey := "field/record/photo_thumb"
+		width, height := 2048, 1024
+		expectPending(mock, attachmentID, "image/png", "field/record/photo.png")
+		mock.ExpectExec("UPDATE attachments").
+			WithArgs(attachmentID, store.ThumbnailReady, &thumbnailKey, &width, &height).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		worker.processPending(ctx)
+
+		small, err := jpeg.DecodeConfig(bytes.NewReader(stor.files["field/record/photo_thumb_small.jpg"]))
+		require.NoError(t, err)
+		assert.Equal(t, 256, small.Width)
+		assert.Equal(t, 128, small.Height)
+
+		large, err := jpeg.DecodeConfig(bytes.NewReader(stor.files["field/record/photo_thumb_large.jpg"]))
+		require.NoError(t, err)
+		assert.Equal(t, 1024, large.Width)
+		assert.Equal(t, 512, large.Height)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("marks images it cannot decode as failed", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		stor := &memoryStorage{files: map[string][]byte{
+			"field/record/broken.jpg": []byte("not really a jpeg"),
+		}}
+		worker := NewWorker(store.NewAttachmentStore(mock, nil, nil, nil, stor, "http://localhost"), stor)
+
+		attachmentID := uuid.New()
+		expectPending(mock, attachmentID, "image/jpeg", "field/record/broken.jpg")
+		mock.ExpectExec("UPDATE attachments").
+			WithArgs(attachmentID, store.ThumbnailFailed, (*string)(nil), (*int)(nil), (*int)(nil)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		worker.processPending(ctx)
+
+		assert.Len(t, stor.files, 1)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("marks missing originals as failed", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		stor := &memoryStorage{files: map[string][]byte{}}
+		worker := NewWorker(store.NewAttachmentStore(mock, nil, nil, nil, stor, "http://localhost"), stor)
+
+		attachmentID := uuid.New()
+		expectPending(mock, attachmentID, "image/gif", "field/record/gone.gif")
+		mock.ExpectExec("UPDATE attachments").
+			WithArgs(attachmentID, store.ThumbnailFailed, (*string)(nil), (*int)(nil), (*int)(nil)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		worker.processPending(ctx)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestKeyPrefix(t *testing.T) {
+	assert.Equal(t, "field/record/abc_thumb", KeyPrefix("field/record/abc.png"))
+	assert.Equal(t, "field/record/abc_thumb", KeyPrefix("field/record/abc"))
+}
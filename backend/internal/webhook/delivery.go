@@ -3,26 +3,62 @@ package webhook
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"reflect"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/outbound"
 	"github.com/vibetable/backend/internal/store"
+	"github.com/vibetable/backend/pkg/webhooksig"
 )
 
+// Queue worker settings
+const (
+	queueBatchSize      = 20              // Webhooks served per claim
+	deliveryLease       = 2 * time.Minute // How long a claimed item is reserved for one worker
+	queuePollInterval   = 5 * time.Second // How often the queue is checked without a wake-up
+	queueRetention      = 7 * 24 * time.Hour
+	queuePruneInterval  = time.Hour
+	maxResponseBodySize = 10240 // Bytes of the receiver's response kept on the delivery log
+)
+
+// RetryPolicy controls how failed deliveries are retried
+type RetryPolicy struct {
+	MaxAttempts  int           // Attempts per event before it is marked failed
+	BaseBackoff  time.Duration // Delay before the first retry; doubled for each one after
+	MaxBackoff   time.Duration // Upper bound on the delay between attempts
+	DisableAfter int           // Consecutive failed attempts before the webhook is disabled
+}
+
+// DefaultRetryPolicy retries for roughly an hour and disables a webhook after 20 failures in a row
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+		DisableAfter: 20,
+	}
+}
+
 // DeliveryEngine handles webhook delivery
 type DeliveryEngine struct {
-	webhookStore *store.WebhookStore
-	tableStore   *store.TableStore
-	httpClient   *http.Client
+	webhookStore  *store.WebhookStore
+	tableStore    *store.TableStore
+	activityStore *store.ActivityStore
+	httpClient    *http.Client
+	retryPolicy   RetryPolicy
+	renderer      MessageRenderer
+	wake          chan struct{}
 }
 
 // NewDeliveryEngine creates a new webhook delivery engine
@@ -30,19 +66,39 @@ func NewDeliveryEngine(webhookStore *store.WebhookStore, tableStore *store.Table
 	return &DeliveryEngine{
 		webhookStore: webhookStore,
 		tableStore:   tableStore,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient:   outbound.DefaultPolicy().Client(),
+		retryPolicy:  DefaultRetryPolicy(),
+		wake:         make(chan struct{}, 1),
 	}
 }
 
+// SetActivityStore sets the activity store used to record automatic disabling
+func (e *DeliveryEngine) SetActivityStore(activityStore *store.ActivityStore) {
+	e.activityStore = activityStore
+}
+
+// SetOutboundPolicy sets the policy that restricts which destinations deliveries may reach
+func (e *DeliveryEngine) SetOutboundPolicy(policy outbound.Policy) {
+	e.httpClient = policy.Client()
+}
+
+// SetRetryPolicy overrides the default retry policy
+func (e *DeliveryEngine) SetRetryPolicy(policy RetryPolicy) {
+	e.retryPolicy = policy
+}
+
 // DeliveryContext contains the context for a webhook delivery
 type DeliveryContext struct {
 	BaseID    uuid.UUID
 	TableID   uuid.UUID
 	RecordID  *uuid.UUID
 	Record    *models.Record
-	OldRecord *models.Record
+	OldRecord *models.Record // record.updated only; used to compute the field diff
+	Field     *models.Field
+	Table     *models.Table
+	View      *models.View
+	Comment   *models.Comment
+	FormID    *uuid.UUID
 	Event     models.WebhookEvent
 	UserID    uuid.UUID
 }
@@ -50,7 +106,7 @@ type DeliveryContext struct {
 // ProcessEvent processes a webhook event and delivers to all registered webhooks
 func (e *DeliveryEngine) ProcessEvent(ctx context.Context, deliveryCtx *DeliveryContext) {
 	// Get webhooks that should receive this event
-	webhooks, err := e.webhookStore.GetActiveByBaseAndEvent(ctx, deliveryCtx.BaseID, deliveryCtx.Event)
+	webhooks, err := e.webhookStore.GetActiveByBaseAndEvent(ctx, deliveryCtx.BaseID, deliveryCtx.TableID, deliveryCtx.Event)
 	if err != nil {
 		log.Printf("Failed to get webhooks for event %s: %v", deliveryCtx.Event, err)
 		return
@@ -62,15 +118,23 @@ func (e *DeliveryEngine) ProcessEvent(ctx context.Context, deliveryCtx *Delivery
 
 	// Build the payload
 	payload := &models.WebhookPayload{
+		ID:        uuid.New(),
 		Event:     deliveryCtx.Event,
 		Timestamp: time.Now(),
 		BaseID:    deliveryCtx.BaseID,
 		TableID:   deliveryCtx.TableID,
 		RecordID:  deliveryCtx.RecordID,
 		Record:    deliveryCtx.Record,
-		OldRecord: deliveryCtx.OldRecord,
+		Field:     deliveryCtx.Field,
+		Table:     deliveryCtx.Table,
+		View:      deliveryCtx.View,
+		Comment:   deliveryCtx.Comment,
+		FormID:    deliveryCtx.FormID,
 		UserID:    deliveryCtx.UserID,
 	}
+	if deliveryCtx.Event == models.WebhookEventRecordUpdated {
+		payload.Changes = diffRecordValues(deliveryCtx.OldRecord, deliveryCtx.Record)
+	}
 
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
@@ -78,42 +142,331 @@ func (e *DeliveryEngine) ProcessEvent(ctx context.Context, deliveryCtx *Delivery
 		return
 	}
 
-	// Deliver to each webhook asynchronously
+	// Queue a delivery for each webhook; the worker sends them in order
+	queued := false
 	for _, webhook := range webhooks {
-		go e.deliverToWebhook(ctx, webhook, string(deliveryCtx.Event), payloadJSON)
+		if payload.Event == models.WebhookEventRecordUpdated && !watchedFieldChanged(webhook.WatchFieldIDs, payload.Changes) {
+			continue
+		}
+		if _, err := e.webhookStore.EnqueueDelivery(ctx, webhook.ID, string(deliveryCtx.Event), string(payloadJSON), nil); err != nil {
+			log.Printf("Failed to queue webhook delivery for %s: %v", webhook.URL, err)
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		e.notify()
+	}
+}
+
+// ProcessChange turns a table, field, view or comment change reported by a store into
+// a webhook event. Its signature matches store.ChangeCallback.
+func (e *DeliveryEngine) ProcessChange(baseID, tableID uuid.UUID, change string, entity interface{}, userID uuid.UUID) {
+	deliveryCtx := &DeliveryContext{BaseID: baseID, TableID: tableID, UserID: userID}
+
+	var events [3]models.WebhookEvent // created, updated, deleted
+	switch v := entity.(type) {
+	case *models.Table:
+		deliveryCtx.Table = v
+		events = [3]models.WebhookEvent{models.WebhookEventTableCreated, models.WebhookEventTableUpdated, models.WebhookEventTableDeleted}
+	case *models.Field:
+		deliveryCtx.Field = v
+		events = [3]models.WebhookEvent{models.WebhookEventFieldCreated, models.WebhookEventFieldUpdated, models.WebhookEventFieldDeleted}
+	case *models.View:
+		deliveryCtx.View = v
+		events = [3]models.WebhookEvent{models.WebhookEventViewCreated, models.WebhookEventViewUpdated, models.WebhookEventViewDeleted}
+	case *models.Comment:
+		deliveryCtx.Comment = v
+		deliveryCtx.RecordID = &v.RecordID
+		events = [3]models.WebhookEvent{models.WebhookEventCommentCreated, models.WebhookEventCommentUpdated, models.WebhookEventCommentDeleted}
+	default:
+		return
+	}
+
+	switch change {
+	case store.ChangeCreated:
+		deliveryCtx.Event = events[0]
+	case store.ChangeUpdated:
+		deliveryCtx.Event = events[1]
+	case store.ChangeDeleted:
+		deliveryCtx.Event = events[2]
+	default:
+		return
+	}
+
+	e.ProcessEvent(context.Background(), deliveryCtx)
+}
+
+// diffRecordValues lists the fields whose values differ between two versions of a record
+func diffRecordValues(oldRecord, newRecord *models.Record) []models.WebhookFieldChange {
+	oldValues := recordValues(oldRecord)
+	newValues := recordValues(newRecord)
+
+	fieldIDs := make([]string, 0, len(newValues))
+	for id := range newValues {
+		fieldIDs = append(fieldIDs, id)
+	}
+	for id := range oldValues {
+		if _, ok := newValues[id]; !ok {
+			fieldIDs = append(fieldIDs, id)
+		}
+	}
+	sort.Strings(fieldIDs)
+
+	changes := []models.WebhookFieldChange{}
+	for _, id := range fieldIDs {
+		oldValue, newValue := oldValues[id], newValues[id]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, models.WebhookFieldChange{FieldID: id, OldValue: oldValue, NewValue: newValue})
+	}
+	return changes
+}
+
+func recordValues(record *models.Record) map[string]interface{} {
+	values := map[string]interface{}{}
+	if record != nil && len(record.Values) > 0 {
+		_ = json.Unmarshal(record.Values, &values)
+	}
+	return values
+}
+
+// watchedFieldChanged reports whether a change touches one of the watched fields.
+// A webhook that watches no fields is interested in every change.
+func watchedFieldChanged(watchFieldIDs []uuid.UUID, changes []models.WebhookFieldChange) bool {
+	if len(watchFieldIDs) == 0 {
+		return true
+	}
+	for _, change := range changes {
+		for _, id := range watchFieldIDs {
+			if change.FieldID == id.String() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Redeliver queues a past delivery's event to be sent again
+func (e *DeliveryEngine) Redeliver(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookQueueItem, error) {
+	item, err := e.webhookStore.EnqueueDelivery(ctx, delivery.WebhookID, delivery.EventType, delivery.Payload, &delivery.ID)
+	if err != nil {
+		return nil, err
+	}
+	e.notify()
+	return item, nil
+}
+
+// SendTest sends a sample event to a webhook right away, bypassing the queue and the
+// webhook's filters, and records the attempt in its delivery log
+func (e *DeliveryEngine) SendTest(ctx context.Context, webhook *models.Webhook, event models.WebhookEvent, userID uuid.UUID) *models.WebhookDelivery {
+	payloadJSON, _ := json.Marshal(samplePayload(webhook, event, userID))
+
+	startTime := time.Now()
+	delivery, _ := e.deliverToWebhook(ctx, *webhook, string(event), payloadJSON)
+	delivery.Attempt = 1
+	e.recordDelivery(ctx, delivery, startTime)
+	return delivery
+}
+
+// notify wakes the queue worker without blocking
+func (e *DeliveryEngine) notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the queue worker in the background until ctx is cancelled
+func (e *DeliveryEngine) Start(ctx context.Context) {
+	go e.run(ctx)
+}
+
+func (e *DeliveryEngine) run(ctx context.Context) {
+	poll := time.NewTicker(queuePollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(queuePruneInterval)
+	defer prune.Stop()
+
+	for {
+		e.processQueue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.wake:
+		case <-poll.C:
+		case <-prune.C:
+			if n, err := e.webhookStore.PruneQueue(ctx, time.Now().Add(-queueRetention)); err != nil {
+				log.Printf("Failed to prune webhook queue: %v", err)
+			} else if n > 0 {
+				log.Printf("Pruned %d finished webhook deliveries from the queue", n)
+			}
+		}
+	}
+}
+
+// processQueue delivers due queue items until none are left
+func (e *DeliveryEngine) processQueue(ctx context.Context) {
+	for ctx.Err() == nil {
+		items, err := e.webhookStore.ClaimQueueItems(ctx, queueBatchSize, deliveryLease)
+		if err != nil {
+			log.Printf("Failed to claim webhook deliveries: %v", err)
+			return
+		}
+		if len(items) == 0 {
+			return
+		}
+
+		// Each claimed item belongs to a different webhook, so they can be sent in parallel
+		var wg sync.WaitGroup
+		for _, item := range items {
+			wg.Add(1)
+			go func(item models.WebhookQueueItem) {
+				defer wg.Done()
+				e.processQueueItem(ctx, item)
+			}(item)
+		}
+		wg.Wait()
 	}
 }
 
-// deliverToWebhook delivers a payload to a single webhook
-func (e *DeliveryEngine) deliverToWebhook(ctx context.Context, webhook models.Webhook, eventType string, payloadJSON []byte) {
+// processQueueItem makes one delivery attempt for a queue item and schedules a retry,
+// marks it finished, or disables the webhook depending on the outcome
+func (e *DeliveryEngine) processQueueItem(ctx context.Context, item models.WebhookQueueItem) {
+	webhook, err := e.webhookStore.GetByID(ctx, item.WebhookID)
+	if err != nil {
+		// Loading can keep failing, e.g. when a secret no longer decrypts, so it
+		// counts as an attempt; a webhook that is gone will never be delivered
+		errStr := fmt.Sprintf("failed to load webhook: %v", err)
+		attempt := item.Attempts + 1
+		if !errors.Is(err, pgx.ErrNoRows) && attempt < e.retryPolicy.MaxAttempts {
+			err = e.webhookStore.RetryQueueItem(ctx, item.ID, attempt, time.Now().Add(e.backoff(attempt)), &errStr)
+		} else {
+			err = e.webhookStore.CompleteQueueItem(ctx, item.ID, models.WebhookQueueFailed, attempt, &errStr)
+		}
+		if err != nil {
+			log.Printf("Failed to update webhook delivery %s: %v", item.ID, err)
+		}
+		return
+	}
+	if !webhook.IsActive {
+		errStr := "webhook disabled"
+		if err := e.webhookStore.CompleteQueueItem(ctx, item.ID, models.WebhookQueueFailed, item.Attempts, &errStr); err != nil {
+			log.Printf("Failed to update webhook delivery %s: %v", item.ID, err)
+		}
+		return
+	}
+
+	attempt := item.Attempts + 1
 	startTime := time.Now()
+	delivery, retryable := e.deliverToWebhook(ctx, *webhook, item.EventType, []byte(item.Payload))
+	delivery.Attempt = attempt
+	delivery.QueueItemID = &item.ID
+	e.recordDelivery(ctx, delivery, startTime)
+
+	if delivery.Error == nil {
+		if err := e.webhookStore.CompleteQueueItem(ctx, item.ID, models.WebhookQueueDelivered, attempt, nil); err != nil {
+			log.Printf("Failed to update webhook delivery %s: %v", item.ID, err)
+		}
+		if webhook.ConsecutiveFailures > 0 {
+			if err := e.webhookStore.ResetWebhookFailures(ctx, webhook.ID); err != nil {
+				log.Printf("Failed to reset failures for webhook %s: %v", webhook.ID, err)
+			}
+		}
+		return
+	}
+
+	if retryable && attempt < e.retryPolicy.MaxAttempts {
+		next := time.Now().Add(e.backoff(attempt))
+		err = e.webhookStore.RetryQueueItem(ctx, item.ID, attempt, next, delivery.Error)
+	} else {
+		err = e.webhookStore.CompleteQueueItem(ctx, item.ID, models.WebhookQueueFailed, attempt, delivery.Error)
+	}
+	if err != nil {
+		log.Printf("Failed to update webhook delivery %s: %v", item.ID, err)
+	}
+
+	failures, err := e.webhookStore.RecordWebhookFailure(ctx, webhook.ID)
+	if err != nil {
+		log.Printf("Failed to record failure for webhook %s: %v", webhook.ID, err)
+		return
+	}
+	if e.retryPolicy.DisableAfter > 0 && failures >= e.retryPolicy.DisableAfter {
+		e.disableWebhook(ctx, webhook, failures, *delivery.Error)
+	}
+}
+
+// backoff returns the delay before the attempt after the given one
+func (e *DeliveryEngine) backoff(attempt int) time.Duration {
+	delay := e.retryPolicy.BaseBackoff
+	for i := 1; i < attempt && delay < e.retryPolicy.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > e.retryPolicy.MaxBackoff {
+		delay = e.retryPolicy.MaxBackoff
+	}
+	return delay
+}
+
+// disableWebhook turns off a webhook that keeps failing and records why in the activity log
+func (e *DeliveryEngine) disableWebhook(ctx context.Context, webhook *models.Webhook, failures int, lastError string) {
+	reason := fmt.Sprintf("Disabled after %d consecutive failed deliveries (last error: %s)", failures, lastError)
+	disabled, err := e.webhookStore.DisableWebhook(ctx, webhook.ID, reason)
+	if err != nil {
+		log.Printf("Failed to disable webhook %s: %v", webhook.ID, err)
+		return
+	}
+	if !disabled {
+		return // Already inactive
+	}
+	log.Printf("Webhook %s (%s) disabled after %d consecutive failures", webhook.Name, webhook.URL, failures)
 
+	if e.activityStore != nil {
+		if err := e.activityStore.LogWebhookDisabled(ctx, webhook.BaseID, webhook.CreatedBy, webhook.Name, reason); err != nil {
+			log.Printf("Failed to log webhook disable: %v", err)
+		}
+	}
+}
+
+// deliverToWebhook makes a single delivery attempt and returns its outcome, along with
+// whether a failure is worth retrying. The caller records the delivery.
+func (e *DeliveryEngine) deliverToWebhook(ctx context.Context, webhook models.Webhook, eventType string, payloadJSON []byte) (*models.WebhookDelivery, bool) {
 	delivery := &models.WebhookDelivery{
 		WebhookID: webhook.ID,
 		EventType: eventType,
 		Payload:   string(payloadJSON),
 	}
 
+	// Shape the body for the webhook's format
+	body, header, err := e.formatBody(ctx, webhook, payloadJSON)
+	if err != nil {
+		errStr := fmt.Sprintf("failed to format payload: %v", err)
+		delivery.Error = &errStr
+		return delivery, false
+	}
+
 	// Create the request
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payloadJSON))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
 	if err != nil {
 		errStr := fmt.Sprintf("failed to create request: %v", err)
 		delivery.Error = &errStr
-		e.recordDelivery(ctx, delivery, startTime)
-		return
+		return delivery, false
 	}
 
 	// Set headers
-	req.Header.Set("Content-Type", "application/json")
+	req.Header = header
 	req.Header.Set("User-Agent", "VibeTable-Webhook/1.0")
 	req.Header.Set("X-Webhook-Event", eventType)
 	req.Header.Set("X-Webhook-ID", webhook.ID.String())
-	req.Header.Set("X-Webhook-Timestamp", fmt.Sprintf("%d", time.Now().Unix()))
+	now := time.Now()
+	req.Header.Set(webhooksig.TimestampHeader, fmt.Sprintf("%d", now.Unix()))
 
-	// Add HMAC signature if secret is configured
-	if webhook.Secret != nil && *webhook.Secret != "" {
-		signature := computeHMAC(payloadJSON, *webhook.Secret)
-		req.Header.Set("X-Webhook-Signature", signature)
+	// Sign the timestamp and body with every active secret, so receivers keep
+	// verifying while a rotated secret is in its grace period
+	if secrets := webhook.SigningSecrets(now); len(secrets) > 0 {
+		req.Header.Set(webhooksig.SignatureHeader, webhooksig.Header(now, body, secrets...))
 	}
 
 	// Send the request
@@ -121,28 +474,34 @@ func (e *DeliveryEngine) deliverToWebhook(ctx context.Context, webhook models.We
 	if err != nil {
 		errStr := fmt.Sprintf("request failed: %v", err)
 		delivery.Error = &errStr
-		e.recordDelivery(ctx, delivery, startTime)
 		log.Printf("Webhook delivery failed for %s: %v", webhook.URL, err)
-		return
+		return delivery, true
 	}
 	defer resp.Body.Close()
 
 	// Read response
-	body, _ := io.ReadAll(io.LimitReader(resp.Body, 10240)) // Limit to 10KB
-	bodyStr := string(body)
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	bodyStr := string(respBody)
 
 	delivery.ResponseStatus = &resp.StatusCode
 	delivery.ResponseBody = &bodyStr
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		log.Printf("Webhook delivered successfully to %s (status: %d)", webhook.URL, resp.StatusCode)
-	} else {
-		errStr := fmt.Sprintf("non-success status code: %d", resp.StatusCode)
-		delivery.Error = &errStr
-		log.Printf("Webhook delivery failed for %s: status %d", webhook.URL, resp.StatusCode)
+		return delivery, false
 	}
 
-	e.recordDelivery(ctx, delivery, startTime)
+	errStr := fmt.Sprintf("non-success status code: %d", resp.StatusCode)
+	delivery.Error = &errStr
+	log.Printf("Webhook delivery failed for %s: status %d", webhook.URL, resp.StatusCode)
+
+	return delivery, isRetryableStatus(resp.StatusCode)
+}
+
+// isRetryableStatus reports whether a receiver's response suggests trying again later.
+// Other client errors mean the request itself was rejected and will be again.
+func isRetryableStatus(status int) bool {
+	return status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooEarly || status == http.StatusTooManyRequests
 }
 
 // recordDelivery records a delivery attempt to the database
@@ -154,10 +513,3 @@ func (e *DeliveryEngine) recordDelivery(ctx context.Context, delivery *models.We
 		log.Printf("Failed to record webhook delivery: %v", err)
 	}
 }
-
-// computeHMAC computes an HMAC-SHA256 signature
-func computeHMAC(payload []byte, secret string) string {
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write(payload)
-	return "sha256=" + hex.EncodeToString(h.Sum(nil))
-}
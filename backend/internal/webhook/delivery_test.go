@@ -4,19 +4,24 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vibetable/backend/internal/models"
 	"github.com/vibetable/backend/internal/store"
+	"github.com/vibetable/backend/pkg/webhooksig"
 )
 
 func TestNewDeliveryEngine(t *testing.T) {
@@ -26,36 +31,15 @@ func TestNewDeliveryEngine(t *testing.T) {
 	assert.Equal(t, 30*time.Second, engine.httpClient.Timeout)
 }
 
-func TestComputeHMAC(t *testing.T) {
-	t.Run("computes deterministic HMAC", func(t *testing.T) {
-		payload := []byte(`{"test": "data"}`)
-		secret := "my-secret-key"
-
-		sig1 := computeHMAC(payload, secret)
-		sig2 := computeHMAC(payload, secret)
-
-		assert.Equal(t, sig1, sig2)
-		assert.True(t, len(sig1) > 0)
-		assert.Contains(t, sig1, "sha256=")
-	})
-
-	t.Run("different payloads produce different signatures", func(t *testing.T) {
-		secret := "my-secret-key"
-
-		sig1 := computeHMAC([]byte(`{"test": "data1"}`), secret)
-		sig2 := computeHMAC([]byte(`{"test": "data2"}`), secret)
-
-		assert.NotEqual(t, sig1, sig2)
-	})
-
-	t.Run("different secrets produce different signatures", func(t *testing.T) {
-		payload := []byte(`{"test": "data"}`)
+func TestGenerateSecret(t *testing.T) {
+	secret1, err := GenerateSecret()
+	require.NoError(t, err)
+	secret2, err := GenerateSecret()
+	require.NoError(t, err)
 
-		sig1 := computeHMAC(payload, "secret1")
-		sig2 := computeHMAC(payload, "secret2")
-
-		assert.NotEqual(t, sig1, sig2)
-	})
+	assert.True(t, strings.HasPrefix(secret1, "whsec_"))
+	assert.Len(t, secret1, len("whsec_")+64)
+	assert.NotEqual(t, secret1, secret2)
 }
 
 func TestDeliveryEngine_DeliverToWebhook(t *testing.T) {
@@ -174,15 +158,14 @@ func TestDeliveryEngine_DeliverToWebhook(t *testing.T) {
 		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, nil)
 		require.NoError(t, err)
 
-		signature := computeHMAC(payload, secret)
-		req.Header.Set("X-Webhook-Signature", signature)
+		req.Header.Set("X-Webhook-Signature", webhooksig.Header(time.Now(), payload, secret))
 
 		client := server.Client()
 		resp, err := client.Do(req)
 		require.NoError(t, err)
 		resp.Body.Close()
 
-		assert.Contains(t, receivedHeaders.Get("X-Webhook-Signature"), "sha256=")
+		assert.Contains(t, receivedHeaders.Get("X-Webhook-Signature"), "v1=")
 	})
 }
 
@@ -260,6 +243,12 @@ func TestDeliveryContext(t *testing.T) {
 	})
 }
 
+var webhookColumns = []string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at", "consecutive_failures", "disabled_reason", "table_ids", "watch_field_ids", "format", "message_template", "previous_secret", "previous_secret_expires_at"}
+
+var deliveryColumns = []string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at", "attempt", "queue_item_id"}
+
+var queueColumns = []string{"id", "webhook_id", "event_type", "payload", "status", "attempts", "next_attempt_at", "last_error", "redelivery_of", "created_at", "updated_at"}
+
 func TestDeliveryEngine_ProcessEvent(t *testing.T) {
 	t.Run("does nothing when no webhooks exist", func(t *testing.T) {
 		mock, err := pgxmock.NewPool()
@@ -274,9 +263,9 @@ func TestDeliveryEngine_ProcessEvent(t *testing.T) {
 		eventJSON, _ := json.Marshal([]models.WebhookEvent{models.WebhookEventRecordCreated})
 
 		// Expect query for active webhooks - return empty
-		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at FROM webhooks").
-			WithArgs(baseID, eventJSON).
-			WillReturnRows(pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"}))
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(baseID, eventJSON, pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(webhookColumns))
 
 		ctx := context.Background()
 		deliveryCtx := &DeliveryContext{
@@ -288,64 +277,38 @@ func TestDeliveryEngine_ProcessEvent(t *testing.T) {
 
 		engine.ProcessEvent(ctx, deliveryCtx)
 
-		// Give goroutines time to complete (if any were started)
-		time.Sleep(50 * time.Millisecond)
-
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("delivers to webhook when one exists", func(t *testing.T) {
+	t.Run("queues a delivery for each webhook", func(t *testing.T) {
 		mock, err := pgxmock.NewPool()
 		require.NoError(t, err)
 		defer mock.Close()
 
 		baseStore := store.NewBaseStore(mock)
 		webhookStore := store.NewWebhookStore(mock, baseStore)
+		engine := NewDeliveryEngine(webhookStore, nil)
 
 		baseID := uuid.New()
-		webhookID := uuid.New()
+		webhookID1 := uuid.New()
+		webhookID2 := uuid.New()
 		userID := uuid.New()
 		now := time.Now().UTC()
-		events := []models.WebhookEvent{models.WebhookEventRecordCreated}
-		eventsJSON, _ := json.Marshal(events)
-
-		// Track what the webhook server receives
-		var receivedBody []byte
-		var receivedHeaders http.Header
-		var mu sync.Mutex
-
-		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			mu.Lock()
-			defer mu.Unlock()
-			receivedHeaders = r.Header.Clone()
-			receivedBody, _ = io.ReadAll(r.Body)
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"status": "ok"}`))
-		}))
-		defer server.Close()
-
-		engine := &DeliveryEngine{
-			webhookStore: webhookStore,
-			tableStore:   nil,
-			httpClient:   server.Client(),
+		eventsJSON, _ := json.Marshal([]models.WebhookEvent{models.WebhookEventRecordCreated})
+
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(baseID, eventsJSON, pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(webhookColumns).
+				AddRow(webhookID1, baseID, "First", "https://example.com/1", eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil).
+				AddRow(webhookID2, baseID, "Second", "https://example.com/2", eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil))
+
+		for _, webhookID := range []uuid.UUID{webhookID1, webhookID2} {
+			mock.ExpectQuery("INSERT INTO webhook_delivery_queue").
+				WithArgs(webhookID, "record.created", pgxmock.AnyArg(), (*uuid.UUID)(nil)).
+				WillReturnRows(pgxmock.NewRows(queueColumns).
+					AddRow(uuid.New(), webhookID, "record.created", "{}", models.WebhookQueuePending, 0, now, nil, nil, now, now))
 		}
 
-		// Expect query for active webhooks
-		webhookRows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"}).
-			AddRow(webhookID, baseID, "Test Webhook", server.URL, eventsJSON, nil, true, userID, now, now)
-		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at FROM webhooks").
-			WithArgs(baseID, eventsJSON).
-			WillReturnRows(webhookRows)
-
-		// Expect delivery record to be created
-		deliveryID := uuid.New()
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.created", "{}", 200, `{"status": "ok"}`, nil, 10, now)
-		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnRows(deliveryRows)
-
-		ctx := context.Background()
 		deliveryCtx := &DeliveryContext{
 			BaseID:  baseID,
 			TableID: uuid.New(),
@@ -353,17 +316,10 @@ func TestDeliveryEngine_ProcessEvent(t *testing.T) {
 			UserID:  userID,
 		}
 
-		engine.ProcessEvent(ctx, deliveryCtx)
-
-		// Wait for async delivery
-		time.Sleep(100 * time.Millisecond)
+		engine.ProcessEvent(context.Background(), deliveryCtx)
 
-		mu.Lock()
-		assert.NotEmpty(t, receivedBody)
-		assert.Equal(t, "application/json", receivedHeaders.Get("Content-Type"))
-		assert.Equal(t, "VibeTable-Webhook/1.0", receivedHeaders.Get("User-Agent"))
-		assert.Equal(t, "record.created", receivedHeaders.Get("X-Webhook-Event"))
-		mu.Unlock()
+		// The worker is woken up to send them
+		assert.Len(t, engine.wake, 1)
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -381,8 +337,8 @@ func TestDeliveryEngine_ProcessEvent(t *testing.T) {
 		eventJSON, _ := json.Marshal([]models.WebhookEvent{models.WebhookEventRecordCreated})
 
 		// Expect query to fail
-		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at FROM webhooks").
-			WithArgs(baseID, eventJSON).
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(baseID, eventJSON, pgxmock.AnyArg()).
 			WillReturnError(assert.AnError)
 
 		ctx := context.Background()
@@ -400,206 +356,283 @@ func TestDeliveryEngine_ProcessEvent(t *testing.T) {
 	})
 }
 
-func TestDeliveryEngine_deliverToWebhook(t *testing.T) {
-	t.Run("delivers with HMAC signature when secret is set", func(t *testing.T) {
+func TestDeliveryEngine_ProcessEvent_Filters(t *testing.T) {
+	t.Run("skips webhooks whose watched fields did not change", func(t *testing.T) {
 		mock, err := pgxmock.NewPool()
 		require.NoError(t, err)
 		defer mock.Close()
 
-		baseStore := store.NewBaseStore(mock)
-		webhookStore := store.NewWebhookStore(mock, baseStore)
+		engine := NewDeliveryEngine(store.NewWebhookStore(mock, store.NewBaseStore(mock)), nil)
 
-		var receivedSignature string
+		baseID := uuid.New()
+		tableID := uuid.New()
+		nameField := uuid.New()
+		statusField := uuid.New()
+		watchingName := uuid.New()
+		watchingStatus := uuid.New()
+		userID := uuid.New()
+		now := time.Now().UTC()
+		eventsJSON, _ := json.Marshal([]models.WebhookEvent{models.WebhookEventRecordUpdated})
+		tableJSON, _ := json.Marshal([]uuid.UUID{tableID})
+		watchName, _ := json.Marshal([]uuid.UUID{nameField})
+		watchStatus, _ := json.Marshal([]uuid.UUID{statusField})
+
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(baseID, eventsJSON, tableJSON).
+			WillReturnRows(pgxmock.NewRows(webhookColumns).
+				AddRow(watchingName, baseID, "Name", "https://example.com/1", eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), watchName, models.WebhookFormatNative, nil, nil, nil).
+				AddRow(watchingStatus, baseID, "Status", "https://example.com/2", eventsJSON, nil, true, userID, now, now, 0, nil, tableJSON, watchStatus, models.WebhookFormatNative, nil, nil, nil))
+
+		// Only the webhook watching the name field is queued
+		mock.ExpectQuery("INSERT INTO webhook_delivery_queue").
+			WithArgs(watchingName, "record.updated", pgxmock.AnyArg(), (*uuid.UUID)(nil)).
+			WillReturnRows(pgxmock.NewRows(queueColumns).
+				AddRow(uuid.New(), watchingName, "record.updated", "{}", models.WebhookQueuePending, 0, now, nil, nil, now, now))
+
+		recordID := uuid.New()
+		engine.ProcessEvent(context.Background(), &DeliveryContext{
+			BaseID:    baseID,
+			TableID:   tableID,
+			RecordID:  &recordID,
+			Record:    &models.Record{ID: recordID, Values: json.RawMessage(`{"` + nameField.String() + `":"New","` + statusField.String() + `":"Open"}`)},
+			OldRecord: &models.Record{ID: recordID, Values: json.RawMessage(`{"` + nameField.String() + `":"Old","` + statusField.String() + `":"Open"}`)},
+			Event:     models.WebhookEventRecordUpdated,
+			UserID:    userID,
+		})
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeliveryEngine_ProcessChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		change string
+		entity interface{}
+		event  models.WebhookEvent
+	}{
+		{"table created", store.ChangeCreated, &models.Table{ID: uuid.New()}, models.WebhookEventTableCreated},
+		{"field updated", store.ChangeUpdated, &models.Field{ID: uuid.New()}, models.WebhookEventFieldUpdated},
+		{"view deleted", store.ChangeDeleted, &models.View{ID: uuid.New()}, models.WebhookEventViewDeleted},
+		{"comment created", store.ChangeCreated, &models.Comment{ID: uuid.New(), RecordID: uuid.New()}, models.WebhookEventCommentCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			engine := NewDeliveryEngine(store.NewWebhookStore(mock, store.NewBaseStore(mock)), nil)
+			baseID := uuid.New()
+			tableID := uuid.New()
+			eventJSON, _ := json.Marshal([]models.WebhookEvent{tt.event})
+			tableJSON, _ := json.Marshal([]uuid.UUID{tableID})
+
+			mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+				WithArgs(baseID, eventJSON, tableJSON).
+				WillReturnRows(pgxmock.NewRows(webhookColumns))
+
+			engine.ProcessChange(baseID, tableID, tt.change, tt.entity, uuid.New())
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("ignores unknown entities", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		engine := NewDeliveryEngine(store.NewWebhookStore(mock, store.NewBaseStore(mock)), nil)
+		engine.ProcessChange(uuid.New(), uuid.New(), store.ChangeCreated, &models.Record{}, uuid.New())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDiffRecordValues(t *testing.T) {
+	oldRecord := &models.Record{Values: json.RawMessage(`{"a":"same","b":1,"c":[1,2],"d":"removed"}`)}
+	newRecord := &models.Record{Values: json.RawMessage(`{"a":"same","b":2,"c":[1,2],"e":"added"}`)}
+
+	changes := diffRecordValues(oldRecord, newRecord)
+
+	assert.Equal(t, []models.WebhookFieldChange{
+		{FieldID: "b", OldValue: float64(1), NewValue: float64(2)},
+		{FieldID: "d", OldValue: "removed", NewValue: nil},
+		{FieldID: "e", OldValue: nil, NewValue: "added"},
+	}, changes)
+
+	assert.Empty(t, diffRecordValues(newRecord, newRecord))
+	assert.Len(t, diffRecordValues(nil, newRecord), 4)
+}
+
+func TestWatchedFieldChanged(t *testing.T) {
+	watched := uuid.New()
+	changes := []models.WebhookFieldChange{{FieldID: uuid.New().String()}}
+
+	assert.True(t, watchedFieldChanged(nil, changes))
+	assert.False(t, watchedFieldChanged([]uuid.UUID{watched}, changes))
+	assert.True(t, watchedFieldChanged([]uuid.UUID{watched}, append(changes, models.WebhookFieldChange{FieldID: watched.String()})))
+	assert.False(t, watchedFieldChanged([]uuid.UUID{watched}, nil))
+}
+
+func TestDeliveryEngine_deliverToWebhook(t *testing.T) {
+	t.Run("delivers with HMAC signature when secret is set", func(t *testing.T) {
+		var receivedSignature, receivedTimestamp string
 		var mu sync.Mutex
 
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			mu.Lock()
 			defer mu.Unlock()
 			receivedSignature = r.Header.Get("X-Webhook-Signature")
+			receivedTimestamp = r.Header.Get("X-Webhook-Timestamp")
 			w.WriteHeader(http.StatusOK)
 		}))
 		defer server.Close()
 
-		engine := &DeliveryEngine{
-			webhookStore: webhookStore,
-			tableStore:   nil,
-			httpClient:   server.Client(),
-		}
+		engine := &DeliveryEngine{httpClient: server.Client()}
 
-		webhookID := uuid.New()
 		secret := "my-secret-key"
 		webhook := models.Webhook{
-			ID:     webhookID,
+			ID:     uuid.New(),
 			BaseID: uuid.New(),
 			URL:    server.URL,
 			Secret: &secret,
 		}
 
-		now := time.Now().UTC()
-		deliveryID := uuid.New()
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.created", "{}", 200, "", nil, 10, now)
-		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnRows(deliveryRows)
-
-		ctx := context.Background()
 		payload := []byte(`{"event": "record.created"}`)
+		delivery, _ := engine.deliverToWebhook(context.Background(), webhook, "record.created", payload)
 
-		engine.deliverToWebhook(ctx, webhook, "record.created", payload)
-
+		assert.Nil(t, delivery.Error)
 		mu.Lock()
-		assert.Contains(t, receivedSignature, "sha256=")
+		assert.NoError(t, webhooksig.Verify(receivedSignature, payload, secret, webhooksig.DefaultTolerance))
+		assert.True(t, strings.HasPrefix(receivedSignature, "t="+receivedTimestamp+","))
 		mu.Unlock()
-
-		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("handles request creation error", func(t *testing.T) {
-		mock, err := pgxmock.NewPool()
-		require.NoError(t, err)
-		defer mock.Close()
-
-		baseStore := store.NewBaseStore(mock)
-		webhookStore := store.NewWebhookStore(mock, baseStore)
-
-		engine := &DeliveryEngine{
-			webhookStore: webhookStore,
-			tableStore:   nil,
-			httpClient:   &http.Client{},
-		}
+	t.Run("signs with both secrets during a rotation grace period", func(t *testing.T) {
+		var receivedSignature string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			receivedSignature = r.Header.Get("X-Webhook-Signature")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
 
-		webhookID := uuid.New()
+		engine := &DeliveryEngine{httpClient: server.Client()}
+		secret, previous := "new-secret", "old-secret"
+		expiresAt := time.Now().Add(time.Hour)
 		webhook := models.Webhook{
-			ID:     webhookID,
-			BaseID: uuid.New(),
-			URL:    "://invalid-url", // Invalid URL
+			ID:                      uuid.New(),
+			URL:                     server.URL,
+			Secret:                  &secret,
+			PreviousSecret:          &previous,
+			PreviousSecretExpiresAt: &expiresAt,
 		}
 
-		now := time.Now().UTC()
-		deliveryID := uuid.New()
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.created", "{}", nil, nil, "failed to create request", 0, now)
-		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnRows(deliveryRows)
-
-		ctx := context.Background()
 		payload := []byte(`{"event": "record.created"}`)
+		delivery, _ := engine.deliverToWebhook(context.Background(), webhook, "record.created", payload)
 
-		engine.deliverToWebhook(ctx, webhook, "record.created", payload)
-
-		require.NoError(t, mock.ExpectationsWereMet())
+		assert.Nil(t, delivery.Error)
+		assert.NoError(t, webhooksig.Verify(receivedSignature, payload, secret, webhooksig.DefaultTolerance))
+		assert.NoError(t, webhooksig.Verify(receivedSignature, payload, previous, webhooksig.DefaultTolerance))
 	})
 
-	t.Run("handles HTTP request failure", func(t *testing.T) {
-		mock, err := pgxmock.NewPool()
-		require.NoError(t, err)
-		defer mock.Close()
-
-		baseStore := store.NewBaseStore(mock)
-		webhookStore := store.NewWebhookStore(mock, baseStore)
-
-		// Server that immediately closes the connection
+	t.Run("omits the signature without a secret", func(t *testing.T) {
+		var headers http.Header
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			panic("force connection close")
+			headers = r.Header.Clone()
+			w.WriteHeader(http.StatusOK)
 		}))
-		server.Close() // Close immediately to cause connection failure
+		defer server.Close()
 
-		engine := &DeliveryEngine{
-			webhookStore: webhookStore,
-			tableStore:   nil,
-			httpClient:   &http.Client{Timeout: 1 * time.Second},
-		}
+		engine := &DeliveryEngine{httpClient: server.Client()}
+		delivery, _ := engine.deliverToWebhook(context.Background(), models.Webhook{ID: uuid.New(), URL: server.URL}, "record.created", []byte(`{}`))
+
+		assert.Nil(t, delivery.Error)
+		assert.Empty(t, headers.Get("X-Webhook-Signature"))
+		assert.NotEmpty(t, headers.Get("X-Webhook-Timestamp"))
+	})
+
+	t.Run("handles request creation error", func(t *testing.T) {
+		engine := &DeliveryEngine{httpClient: &http.Client{}}
 
-		webhookID := uuid.New()
 		webhook := models.Webhook{
-			ID:     webhookID,
+			ID:     uuid.New(),
 			BaseID: uuid.New(),
-			URL:    server.URL,
+			URL:    "://invalid-url", // Invalid URL
 		}
 
-		now := time.Now().UTC()
-		deliveryID := uuid.New()
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.created", "{}", nil, nil, "request failed", 0, now)
-		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnRows(deliveryRows)
-
-		ctx := context.Background()
-		payload := []byte(`{"event": "record.created"}`)
+		delivery, retryable := engine.deliverToWebhook(context.Background(), webhook, "record.created", []byte(`{}`))
 
-		engine.deliverToWebhook(ctx, webhook, "record.created", payload)
-
-		require.NoError(t, mock.ExpectationsWereMet())
+		require.NotNil(t, delivery.Error)
+		assert.Contains(t, *delivery.Error, "failed to create request")
+		assert.False(t, retryable)
 	})
 
-	t.Run("handles non-success status code", func(t *testing.T) {
-		mock, err := pgxmock.NewPool()
-		require.NoError(t, err)
-		defer mock.Close()
-
-		baseStore := store.NewBaseStore(mock)
-		webhookStore := store.NewWebhookStore(mock, baseStore)
+	t.Run("handles HTTP request failure", func(t *testing.T) {
+		// Server that is closed immediately to cause connection failure
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
 
-		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(`{"error": "server error"}`))
-		}))
-		defer server.Close()
+		engine := &DeliveryEngine{httpClient: &http.Client{Timeout: 1 * time.Second}}
 
-		engine := &DeliveryEngine{
-			webhookStore: webhookStore,
-			tableStore:   nil,
-			httpClient:   server.Client(),
-		}
-
-		webhookID := uuid.New()
 		webhook := models.Webhook{
-			ID:     webhookID,
+			ID:     uuid.New(),
 			BaseID: uuid.New(),
 			URL:    server.URL,
 		}
 
-		now := time.Now().UTC()
-		deliveryID := uuid.New()
-		status := 500
-		body := `{"error": "server error"}`
-		errStr := "non-success status code: 500"
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.created", "{}", &status, &body, &errStr, 10, now)
-		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnRows(deliveryRows)
+		delivery, retryable := engine.deliverToWebhook(context.Background(), webhook, "record.created", []byte(`{}`))
 
-		ctx := context.Background()
-		payload := []byte(`{"event": "record.created"}`)
+		require.NotNil(t, delivery.Error)
+		assert.Contains(t, *delivery.Error, "request failed")
+		assert.Nil(t, delivery.ResponseStatus)
+		assert.True(t, retryable)
+	})
 
-		engine.deliverToWebhook(ctx, webhook, "record.created", payload)
+	t.Run("handles non-success status codes", func(t *testing.T) {
+		tests := []struct {
+			status    int
+			retryable bool
+		}{
+			{http.StatusInternalServerError, true},
+			{http.StatusServiceUnavailable, true},
+			{http.StatusTooManyRequests, true},
+			{http.StatusRequestTimeout, true},
+			{http.StatusBadRequest, false},
+			{http.StatusGone, false},
+		}
 
-		require.NoError(t, mock.ExpectationsWereMet())
-	})
+		for _, tt := range tests {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"error": "nope"}`))
+			}))
 
-	t.Run("handles success status code", func(t *testing.T) {
-		mock, err := pgxmock.NewPool()
-		require.NoError(t, err)
-		defer mock.Close()
+			engine := &DeliveryEngine{httpClient: server.Client()}
+			webhook := models.Webhook{ID: uuid.New(), BaseID: uuid.New(), URL: server.URL}
 
-		baseStore := store.NewBaseStore(mock)
-		webhookStore := store.NewWebhookStore(mock, baseStore)
+			delivery, retryable := engine.deliverToWebhook(context.Background(), webhook, "record.created", []byte(`{}`))
+			server.Close()
 
+			require.NotNil(t, delivery.Error, "status %d", tt.status)
+			assert.Equal(t, fmt.Sprintf("non-success status code: %d", tt.status), *delivery.Error)
+			require.NotNil(t, delivery.ResponseStatus)
+			assert.Equal(t, tt.status, *delivery.ResponseStatus)
+			assert.Equal(t, `{"error": "nope"}`, *delivery.ResponseBody)
+			assert.Equal(t, tt.retryable, retryable, "status %d", tt.status)
+		}
+	})
+
+	t.Run("handles success status code", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`{"status": "ok"}`))
 		}))
 		defer server.Close()
 
-		engine := &DeliveryEngine{
-			webhookStore: webhookStore,
-			tableStore:   nil,
-			httpClient:   server.Client(),
-		}
+		engine := &DeliveryEngine{httpClient: server.Client()}
 
 		webhookID := uuid.New()
 		webhook := models.Webhook{
@@ -608,32 +641,18 @@ func TestDeliveryEngine_deliverToWebhook(t *testing.T) {
 			URL:    server.URL,
 		}
 
-		now := time.Now().UTC()
-		deliveryID := uuid.New()
-		status := 200
-		body := `{"status": "ok"}`
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.created", "{}", &status, &body, nil, 10, now)
-		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnRows(deliveryRows)
-
-		ctx := context.Background()
-		payload := []byte(`{"event": "record.created"}`)
-
-		engine.deliverToWebhook(ctx, webhook, "record.created", payload)
+		delivery, _ := engine.deliverToWebhook(context.Background(), webhook, "record.created", []byte(`{"event": "record.created"}`))
 
-		require.NoError(t, mock.ExpectationsWereMet())
+		assert.Nil(t, delivery.Error)
+		assert.Equal(t, webhookID, delivery.WebhookID)
+		assert.Equal(t, "record.created", delivery.EventType)
+		assert.Equal(t, `{"event": "record.created"}`, delivery.Payload)
+		require.NotNil(t, delivery.ResponseStatus)
+		assert.Equal(t, http.StatusOK, *delivery.ResponseStatus)
+		assert.Equal(t, `{"status": "ok"}`, *delivery.ResponseBody)
 	})
 
 	t.Run("sends correct payload and headers", func(t *testing.T) {
-		mock, err := pgxmock.NewPool()
-		require.NoError(t, err)
-		defer mock.Close()
-
-		baseStore := store.NewBaseStore(mock)
-		webhookStore := store.NewWebhookStore(mock, baseStore)
-
 		var receivedBody []byte
 		var receivedHeaders http.Header
 		var mu sync.Mutex
@@ -647,11 +666,7 @@ func TestDeliveryEngine_deliverToWebhook(t *testing.T) {
 		}))
 		defer server.Close()
 
-		engine := &DeliveryEngine{
-			webhookStore: webhookStore,
-			tableStore:   nil,
-			httpClient:   server.Client(),
-		}
+		engine := &DeliveryEngine{httpClient: server.Client()}
 
 		webhookID := uuid.New()
 		webhook := models.Webhook{
@@ -660,18 +675,9 @@ func TestDeliveryEngine_deliverToWebhook(t *testing.T) {
 			URL:    server.URL,
 		}
 
-		now := time.Now().UTC()
-		deliveryID := uuid.New()
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.updated", "{}", 200, "", nil, 10, now)
-		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.updated", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnRows(deliveryRows)
-
-		ctx := context.Background()
 		payload := []byte(`{"event": "record.updated", "data": {"id": "123"}}`)
 
-		engine.deliverToWebhook(ctx, webhook, "record.updated", payload)
+		engine.deliverToWebhook(context.Background(), webhook, "record.updated", payload)
 
 		mu.Lock()
 		assert.Equal(t, payload, receivedBody)
@@ -681,8 +687,6 @@ func TestDeliveryEngine_deliverToWebhook(t *testing.T) {
 		assert.Equal(t, webhookID.String(), receivedHeaders.Get("X-Webhook-ID"))
 		assert.NotEmpty(t, receivedHeaders.Get("X-Webhook-Timestamp"))
 		mu.Unlock()
-
-		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
@@ -710,10 +714,10 @@ func TestDeliveryEngine_recordDelivery(t *testing.T) {
 
 		now := time.Now().UTC()
 		deliveryID := uuid.New()
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.deleted", `{"event": "record.deleted"}`, nil, nil, nil, 50, now)
+		deliveryRows := pgxmock.NewRows(deliveryColumns).
+			AddRow(deliveryID, webhookID, "record.deleted", `{"event": "record.deleted"}`, nil, nil, nil, 50, now, 1, nil)
 		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.deleted", `{"event": "record.deleted"}`, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), webhookID, "record.deleted", `{"event": "record.deleted"}`, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
 			WillReturnRows(deliveryRows)
 
 		startTime := time.Now().Add(-50 * time.Millisecond)
@@ -747,7 +751,7 @@ func TestDeliveryEngine_recordDelivery(t *testing.T) {
 		}
 
 		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.deleted", `{"event": "record.deleted"}`, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), webhookID, "record.deleted", `{"event": "record.deleted"}`, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
 			WillReturnError(assert.AnError)
 
 		startTime := time.Now()
@@ -759,6 +763,16 @@ func TestDeliveryEngine_recordDelivery(t *testing.T) {
 	})
 }
 
+// captureArg matches any argument and remembers it
+type captureArg struct {
+	value interface{}
+}
+
+func (c *captureArg) Match(v interface{}) bool {
+	c.value = v
+	return true
+}
+
 func TestIntegration_WebhookDelivery(t *testing.T) {
 	t.Run("full webhook delivery flow", func(t *testing.T) {
 		mock, err := pgxmock.NewPool()
@@ -772,6 +786,7 @@ func TestIntegration_WebhookDelivery(t *testing.T) {
 		tableID := uuid.New()
 		recordID := uuid.New()
 		webhookID := uuid.New()
+		itemID := uuid.New()
 		userID := uuid.New()
 		now := time.Now().UTC()
 		events := []models.WebhookEvent{models.WebhookEventRecordCreated}
@@ -790,28 +805,22 @@ func TestIntegration_WebhookDelivery(t *testing.T) {
 		}))
 		defer server.Close()
 
-		engine := &DeliveryEngine{
-			webhookStore: webhookStore,
-			tableStore:   nil,
-			httpClient:   server.Client(),
-		}
+		engine := NewDeliveryEngine(webhookStore, nil)
+		engine.httpClient = server.Client()
 
 		// Mock GetActiveByBaseAndEvent
-		webhookRows := pgxmock.NewRows([]string{"id", "base_id", "name", "url", "events", "secret", "is_active", "created_by", "created_at", "updated_at"}).
-			AddRow(webhookID, baseID, "Integration Test Webhook", server.URL, eventsJSON, nil, true, userID, now, now)
-		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at FROM webhooks").
-			WithArgs(baseID, eventsJSON).
-			WillReturnRows(webhookRows)
-
-		// Mock CreateDelivery
-		deliveryID := uuid.New()
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.created", "{}", 200, `{"received": true}`, nil, 10, now)
-		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnRows(deliveryRows)
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(baseID, eventsJSON, pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(webhookColumns).
+				AddRow(webhookID, baseID, "Integration Test Webhook", server.URL, eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil))
+
+		// Mock EnqueueDelivery
+		payload := &captureArg{}
+		mock.ExpectQuery("INSERT INTO webhook_delivery_queue").
+			WithArgs(webhookID, "record.created", payload, (*uuid.UUID)(nil)).
+			WillReturnRows(pgxmock.NewRows(queueColumns).
+				AddRow(itemID, webhookID, "record.created", "{}", models.WebhookQueuePending, 0, now, nil, nil, now, now))
 
-		ctx := context.Background()
 		deliveryCtx := &DeliveryContext{
 			BaseID:   baseID,
 			TableID:  tableID,
@@ -825,10 +834,31 @@ func TestIntegration_WebhookDelivery(t *testing.T) {
 			UserID: userID,
 		}
 
+		ctx := context.Background()
 		engine.ProcessEvent(ctx, deliveryCtx)
+		require.NoError(t, mock.ExpectationsWereMet())
 
-		// Wait for async delivery
-		time.Sleep(100 * time.Millisecond)
+		// The worker claims the queued item, delivers it and marks it delivered
+		mock.ExpectQuery("WITH heads AS").
+			WithArgs(queueBatchSize, deliveryLease.Seconds()).
+			WillReturnRows(pgxmock.NewRows(queueColumns).
+				AddRow(itemID, webhookID, "record.created", payload.value, models.WebhookQueuePending, 0, now, nil, nil, now, now))
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(webhookID).
+			WillReturnRows(pgxmock.NewRows(webhookColumns).
+				AddRow(webhookID, baseID, "Integration Test Webhook", server.URL, eventsJSON, nil, true, userID, now, now, 0, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil))
+		mock.ExpectQuery("INSERT INTO webhook_deliveries").
+			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), 1, &itemID).
+			WillReturnRows(pgxmock.NewRows(deliveryColumns).
+				AddRow(uuid.New(), webhookID, "record.created", "{}", 200, `{"received": true}`, nil, 10, now, 1, &itemID))
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(itemID, models.WebhookQueueDelivered, 1, (*string)(nil)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery("WITH heads AS").
+			WithArgs(queueBatchSize, deliveryLease.Seconds()).
+			WillReturnRows(pgxmock.NewRows(queueColumns))
+
+		engine.processQueue(ctx)
 
 		mu.Lock()
 		assert.Equal(t, models.WebhookEventRecordCreated, receivedPayload.Event)
@@ -842,38 +872,6 @@ func TestIntegration_WebhookDelivery(t *testing.T) {
 	})
 }
 
-func TestComputeHMAC_VerifySignature(t *testing.T) {
-	t.Run("signature can be verified", func(t *testing.T) {
-		payload := []byte(`{"event": "record.created", "data": {"id": "abc123"}}`)
-		secret := "webhook-secret-key"
-
-		signature := computeHMAC(payload, secret)
-
-		// Manually verify the signature
-		assert.True(t, len(signature) > 7) // "sha256=" + hash
-
-		// Extract hex hash and verify
-		hexHash := signature[7:] // Remove "sha256=" prefix
-		assert.Len(t, hexHash, 64) // SHA256 produces 64 hex characters
-	})
-
-	t.Run("empty payload produces valid signature", func(t *testing.T) {
-		payload := []byte(``)
-		secret := "secret"
-
-		signature := computeHMAC(payload, secret)
-		assert.Contains(t, signature, "sha256=")
-	})
-
-	t.Run("empty secret produces valid signature", func(t *testing.T) {
-		payload := []byte(`{"test": true}`)
-		secret := ""
-
-		signature := computeHMAC(payload, secret)
-		assert.Contains(t, signature, "sha256=")
-	})
-}
-
 func TestPayloadSerialization(t *testing.T) {
 	t.Run("serializes all fields correctly", func(t *testing.T) {
 		recordID := uuid.New()
@@ -893,10 +891,8 @@ func TestPayloadSerialization(t *testing.T) {
 				TableID: tableID,
 				Values:  json.RawMessage(`{"field1": "value1"}`),
 			},
-			OldRecord: &models.Record{
-				ID:      recordID,
-				TableID: tableID,
-				Values:  json.RawMessage(`{"field1": "old_value"}`),
+			Changes: []models.WebhookFieldChange{
+				{FieldID: "field1", OldValue: "old_value", NewValue: "value1"},
 			},
 			UserID: userID,
 		}
@@ -914,20 +910,16 @@ func TestPayloadSerialization(t *testing.T) {
 		assert.Equal(t, tableID.String(), decoded["table_id"])
 		assert.Equal(t, recordID.String(), decoded["record_id"])
 		assert.NotNil(t, decoded["record"])
-		assert.NotNil(t, decoded["old_record"])
+		assert.Nil(t, decoded["old_record"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"field_id": "field1", "old_value": "old_value", "new_value": "value1"},
+		}, decoded["changes"])
 		assert.Equal(t, userID.String(), decoded["user_id"])
 	})
 }
 
 func TestLargeResponseHandling(t *testing.T) {
-	t.Run("handles large response body", func(t *testing.T) {
-		mock, err := pgxmock.NewPool()
-		require.NoError(t, err)
-		defer mock.Close()
-
-		baseStore := store.NewBaseStore(mock)
-		webhookStore := store.NewWebhookStore(mock, baseStore)
-
+	t.Run("truncates large response body", func(t *testing.T) {
 		// Create a large response (> 10KB limit in code)
 		largeBody := bytes.Repeat([]byte("x"), 20*1024)
 
@@ -937,33 +929,253 @@ func TestLargeResponseHandling(t *testing.T) {
 		}))
 		defer server.Close()
 
-		engine := &DeliveryEngine{
-			webhookStore: webhookStore,
-			tableStore:   nil,
-			httpClient:   server.Client(),
-		}
+		engine := &DeliveryEngine{httpClient: server.Client()}
 
-		webhookID := uuid.New()
 		webhook := models.Webhook{
-			ID:     webhookID,
+			ID:     uuid.New(),
 			BaseID: uuid.New(),
 			URL:    server.URL,
 		}
 
-		now := time.Now().UTC()
-		deliveryID := uuid.New()
-		deliveryRows := pgxmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "response_status", "response_body", "error", "duration_ms", "delivered_at"}).
-			AddRow(deliveryID, webhookID, "record.created", "{}", 200, "", nil, 10, now)
+		delivery, _ := engine.deliverToWebhook(context.Background(), webhook, "record.created", []byte(`{"event": "record.created"}`))
+
+		assert.Nil(t, delivery.Error)
+		require.NotNil(t, delivery.ResponseBody)
+		assert.Len(t, *delivery.ResponseBody, maxResponseBodySize)
+	})
+}
+
+func TestDeliveryEngine_processQueueItem(t *testing.T) {
+	setup := func(t *testing.T, handler http.HandlerFunc) (pgxmock.PgxPoolIface, *DeliveryEngine, *httptest.Server) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		baseStore := store.NewBaseStore(mock)
+		engine := NewDeliveryEngine(store.NewWebhookStore(mock, baseStore), nil)
+		engine.httpClient = server.Client()
+		engine.SetActivityStore(store.NewActivityStore(mock, baseStore))
+		engine.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Minute, MaxBackoff: time.Hour, DisableAfter: 5})
+		return mock, engine, server
+	}
+
+	expectWebhook := func(mock pgxmock.PgxPoolIface, webhook models.Webhook) {
+		eventsJSON, _ := json.Marshal(webhook.Events)
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(webhook.ID).
+			WillReturnRows(pgxmock.NewRows(webhookColumns).
+				AddRow(webhook.ID, webhook.BaseID, webhook.Name, webhook.URL, eventsJSON, nil, webhook.IsActive, webhook.CreatedBy, time.Now(), time.Now(), webhook.ConsecutiveFailures, nil, []byte("[]"), []byte("[]"), models.WebhookFormatNative, nil, nil, nil))
+	}
+
+	expectDeliveryRecord := func(mock pgxmock.PgxPoolIface, webhookID, itemID uuid.UUID, attempt int) {
 		mock.ExpectQuery("INSERT INTO webhook_deliveries").
-			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnRows(deliveryRows)
+			WithArgs(pgxmock.AnyArg(), webhookID, "record.created", "{}", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), attempt, &itemID).
+			WillReturnRows(pgxmock.NewRows(deliveryColumns).
+				AddRow(uuid.New(), webhookID, "record.created", "{}", nil, nil, nil, 10, time.Now(), attempt, &itemID))
+	}
+
+	newWebhook := func(url string) models.Webhook {
+		return models.Webhook{
+			ID:        uuid.New(),
+			BaseID:    uuid.New(),
+			Name:      "Orders",
+			URL:       url,
+			Events:    []models.WebhookEvent{models.WebhookEventRecordCreated},
+			IsActive:  true,
+			CreatedBy: uuid.New(),
+		}
+	}
 
-		ctx := context.Background()
-		payload := []byte(`{"event": "record.created"}`)
+	t.Run("marks successful delivery and resets failure streak", func(t *testing.T) {
+		mock, engine, server := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		webhook := newWebhook(server.URL)
+		webhook.ConsecutiveFailures = 2
+		item := models.WebhookQueueItem{ID: uuid.New(), WebhookID: webhook.ID, EventType: "record.created", Payload: "{}"}
+
+		expectWebhook(mock, webhook)
+		expectDeliveryRecord(mock, webhook.ID, item.ID, 1)
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(item.ID, models.WebhookQueueDelivered, 1, (*string)(nil)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec("UPDATE webhooks SET consecutive_failures = 0").
+			WithArgs(webhook.ID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		engine.processQueueItem(context.Background(), item)
 
-		// Should not panic on large response
-		engine.deliverToWebhook(ctx, webhook, "record.created", payload)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("schedules a retry for retryable failures", func(t *testing.T) {
+		mock, engine, server := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		webhook := newWebhook(server.URL)
+		item := models.WebhookQueueItem{ID: uuid.New(), WebhookID: webhook.ID, EventType: "record.created", Payload: "{}", Attempts: 1}
+
+		expectWebhook(mock, webhook)
+		expectDeliveryRecord(mock, webhook.ID, item.ID, 2)
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(item.ID, 2, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery("UPDATE webhooks SET consecutive_failures = consecutive_failures \\+ 1").
+			WithArgs(webhook.ID).
+			WillReturnRows(pgxmock.NewRows([]string{"consecutive_failures"}).AddRow(1))
+
+		engine.processQueueItem(context.Background(), item)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails the item once attempts are exhausted", func(t *testing.T) {
+		mock, engine, server := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		webhook := newWebhook(server.URL)
+		item := models.WebhookQueueItem{ID: uuid.New(), WebhookID: webhook.ID, EventType: "record.created", Payload: "{}", Attempts: 2}
+
+		expectWebhook(mock, webhook)
+		expectDeliveryRecord(mock, webhook.ID, item.ID, 3)
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(item.ID, models.WebhookQueueFailed, 3, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery("UPDATE webhooks SET consecutive_failures = consecutive_failures \\+ 1").
+			WithArgs(webhook.ID).
+			WillReturnRows(pgxmock.NewRows([]string{"consecutive_failures"}).AddRow(3))
+
+		engine.processQueueItem(context.Background(), item)
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("does not retry rejected requests", func(t *testing.T) {
+		mock, engine, server := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		})
+		webhook := newWebhook(server.URL)
+		item := models.WebhookQueueItem{ID: uuid.New(), WebhookID: webhook.ID, EventType: "record.created", Payload: "{}"}
+
+		expectWebhook(mock, webhook)
+		expectDeliveryRecord(mock, webhook.ID, item.ID, 1)
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(item.ID, models.WebhookQueueFailed, 1, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery("UPDATE webhooks SET consecutive_failures = consecutive_failures \\+ 1").
+			WithArgs(webhook.ID).
+			WillReturnRows(pgxmock.NewRows([]string{"consecutive_failures"}).AddRow(1))
+
+		engine.processQueueItem(context.Background(), item)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("disables the webhook after too many failures", func(t *testing.T) {
+		mock, engine, server := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+		webhook := newWebhook(server.URL)
+		item := models.WebhookQueueItem{ID: uuid.New(), WebhookID: webhook.ID, EventType: "record.created", Payload: "{}"}
+
+		expectWebhook(mock, webhook)
+		expectDeliveryRecord(mock, webhook.ID, item.ID, 1)
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(item.ID, 1, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery("UPDATE webhooks SET consecutive_failures = consecutive_failures \\+ 1").
+			WithArgs(webhook.ID).
+			WillReturnRows(pgxmock.NewRows([]string{"consecutive_failures"}).AddRow(5))
+		reason := "Disabled after 5 consecutive failed deliveries (last error: non-success status code: 502)"
+		mock.ExpectExec("UPDATE webhooks SET is_active = false").
+			WithArgs(webhook.ID, reason).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(webhook.ID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+		mock.ExpectExec("INSERT INTO activities").
+			WithArgs(webhook.BaseID, (*uuid.UUID)(nil), (*uuid.UUID)(nil), webhook.CreatedBy, models.ActionDisable, models.EntityTypeWebhook, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		engine.processQueueItem(context.Background(), item)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails items of inactive webhooks without sending", func(t *testing.T) {
+		called := false
+		mock, engine, server := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		})
+		webhook := newWebhook(server.URL)
+		webhook.IsActive = false
+		item := models.WebhookQueueItem{ID: uuid.New(), WebhookID: webhook.ID, EventType: "record.created", Payload: "{}"}
+
+		expectWebhook(mock, webhook)
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(item.ID, models.WebhookQueueFailed, 0, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		engine.processQueueItem(context.Background(), item)
+
+		assert.False(t, called)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails items of deleted webhooks", func(t *testing.T) {
+		mock, engine, _ := setup(t, nil)
+		item := models.WebhookQueueItem{ID: uuid.New(), WebhookID: uuid.New(), EventType: "record.created", Payload: "{}"}
+
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(item.WebhookID).
+			WillReturnError(pgx.ErrNoRows)
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(item.ID, models.WebhookQueueFailed, 1, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		engine.processQueueItem(context.Background(), item)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("counts failures to load the webhook as attempts", func(t *testing.T) {
+		mock, engine, _ := setup(t, nil)
+		item := models.WebhookQueueItem{ID: uuid.New(), WebhookID: uuid.New(), EventType: "record.created", Payload: "{}", Attempts: 1}
+
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(item.WebhookID).
+			WillReturnError(errors.New("cipher: message authentication failed"))
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(item.ID, 2, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		engine.processQueueItem(context.Background(), item)
+		require.NoError(t, mock.ExpectationsWereMet())
+
+		item.Attempts = 2
+		mock.ExpectQuery("SELECT id, base_id, name, url, events, secret, is_active, created_by, created_at, updated_at").
+			WithArgs(item.WebhookID).
+			WillReturnError(errors.New("cipher: message authentication failed"))
+		mock.ExpectExec("UPDATE webhook_delivery_queue").
+			WithArgs(item.ID, models.WebhookQueueFailed, 3, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		engine.processQueueItem(context.Background(), item)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeliveryEngine_backoff(t *testing.T) {
+	engine := NewDeliveryEngine(nil, nil)
+	engine.SetRetryPolicy(RetryPolicy{MaxAttempts: 10, BaseBackoff: 30 * time.Second, MaxBackoff: 5 * time.Minute})
+
+	assert.Equal(t, 30*time.Second, engine.backoff(1))
+	assert.Equal(t, time.Minute, engine.backoff(2))
+	assert.Equal(t, 2*time.Minute, engine.backoff(3))
+	assert.Equal(t, 4*time.Minute, engine.backoff(4))
+	assert.Equal(t, 5*time.Minute, engine.backoff(5))
+	assert.Equal(t, 5*time.Minute, engine.backoff(50))
 }
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+)
+
+// DefaultMessageTemplate is used by chat formats when a webhook has no template
+const DefaultMessageTemplate = "{{payload:event}}{{#if record.url}}: {{record.url}}{{/if}}"
+
+// cloudEventTypePrefix namespaces our event names as CloudEvents types
+const cloudEventTypePrefix = "com.vibetable."
+
+// MessageRenderer renders chat message templates. The automation engine implements it
+// so webhook messages share the automation template language.
+type MessageRenderer interface {
+	RenderMessage(ctx context.Context, template string, payload *models.WebhookPayload, actorID uuid.UUID) string
+}
+
+// SetMessageRenderer sets the renderer for chat message templates
+func (e *DeliveryEngine) SetMessageRenderer(renderer MessageRenderer) {
+	e.renderer = renderer
+}
+
+// cloudEvent is a CloudEvents 1.0 event in structured JSON mode
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time"`
+	Subject         string          `json:"subject,omitempty"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// formatBody converts a native payload into the body and headers for the webhook's format
+func (e *DeliveryEngine) formatBody(ctx context.Context, webhook models.Webhook, payloadJSON []byte) ([]byte, http.Header, error) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	switch webhook.Format {
+	case "", models.WebhookFormatNative:
+		return payloadJSON, header, nil
+	}
+
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+		return nil, nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	switch webhook.Format {
+	case models.WebhookFormatCloudEvents:
+		event := newCloudEvent(&payload, payloadJSON)
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, nil, err
+		}
+		header.Set("Content-Type", "application/cloudevents+json")
+		return body, header, nil
+
+	case models.WebhookFormatCloudEventsBinary:
+		event := newCloudEvent(&payload, payloadJSON)
+		header.Set("ce-specversion", event.SpecVersion)
+		header.Set("ce-id", event.ID)
+		header.Set("ce-source", event.Source)
+		header.Set("ce-type", event.Type)
+		header.Set("ce-time", event.Time)
+		if event.Subject != "" {
+			header.Set("ce-subject", event.Subject)
+		}
+		return payloadJSON, header, nil
+
+	case models.WebhookFormatSlack:
+		body, err := json.Marshal(map[string]string{"text": e.renderMessage(ctx, webhook, &payload)})
+		return body, header, err
+
+	case models.WebhookFormatTeams:
+		body, err := json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  string(payload.Event),
+			"text":     e.renderMessage(ctx, webhook, &payload),
+		})
+		return body, header, err
+	}
+
+	return nil, nil, fmt.Errorf("unknown payload format %q", webhook.Format)
+}
+
+// newCloudEvent wraps a native payload in CloudEvents attributes
+func newCloudEvent(payload *models.WebhookPayload, data []byte) *cloudEvent {
+	id := payload.ID
+	if id == uuid.Nil {
+		id = uuid.New() // Payloads queued before events had IDs
+	}
+	source := "/bases/" + payload.BaseID.String()
+	if payload.TableID != uuid.Nil {
+		source += "/tables/" + payload.TableID.String()
+	}
+	event := &cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id.String(),
+		Source:          source,
+		Type:            cloudEventTypePrefix + string(payload.Event),
+		Time:            payload.Timestamp.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	if payload.RecordID != nil {
+		event.Subject = payload.RecordID.String()
+	}
+	return event
+}
+
+// renderMessage renders the webhook's chat message, falling back to the event name
+// when no renderer is configured
+func (e *DeliveryEngine) renderMessage(ctx context.Context, webhook models.Webhook, payload *models.WebhookPayload) string {
+	template := DefaultMessageTemplate
+	if webhook.MessageTemplate != nil && *webhook.MessageTemplate != "" {
+		template = *webhook.MessageTemplate
+	}
+	if e.renderer == nil {
+		return string(payload.Event)
+	}
+	return e.renderer.RenderMessage(ctx, template, payload, webhook.CreatedBy)
+}
@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/store"
+	"github.com/vibetable/backend/pkg/webhooksig"
+)
+
+// stubRenderer records the template it was asked to render
+type stubRenderer struct {
+	template string
+	actorID  uuid.UUID
+}
+
+func (r *stubRenderer) RenderMessage(ctx context.Context, template string, payload *models.WebhookPayload, actorID uuid.UUID) string {
+	r.template = template
+	r.actorID = actorID
+	return "rendered " + string(payload.Event)
+}
+
+func samplePayloadJSON(t *testing.T) (*models.WebhookPayload, []byte) {
+	recordID := uuid.New()
+	payload := &models.WebhookPayload{
+		ID:        uuid.New(),
+		Event:     models.WebhookEventRecordCreated,
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		BaseID:    uuid.New(),
+		TableID:   uuid.New(),
+		RecordID:  &recordID,
+		UserID:    uuid.New(),
+	}
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return payload, data
+}
+
+func TestDeliveryEngine_formatBody(t *testing.T) {
+	ctx := context.Background()
+	engine := &DeliveryEngine{}
+	payload, payloadJSON := samplePayloadJSON(t)
+
+	t.Run("native sends the payload unchanged", func(t *testing.T) {
+		body, header, err := engine.formatBody(ctx, models.Webhook{Format: models.WebhookFormatNative}, payloadJSON)
+		require.NoError(t, err)
+		assert.Equal(t, payloadJSON, body)
+		assert.Equal(t, "application/json", header.Get("Content-Type"))
+	})
+
+	t.Run("cloudevents structured mode wraps the payload", func(t *testing.T) {
+		body, header, err := engine.formatBody(ctx, models.Webhook{Format: models.WebhookFormatCloudEvents}, payloadJSON)
+		require.NoError(t, err)
+		assert.Equal(t, "application/cloudevents+json", header.Get("Content-Type"))
+
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, "1.0", event["specversion"])
+		assert.Equal(t, payload.ID.String(), event["id"])
+		assert.Equal(t, "com.vibetable.record.created", event["type"])
+		assert.Equal(t, "/bases/"+payload.BaseID.String()+"/tables/"+payload.TableID.String(), event["source"])
+		assert.Equal(t, payload.RecordID.String(), event["subject"])
+		assert.Equal(t, "2024-05-01T12:00:00Z", event["time"])
+		assert.Equal(t, "application/json", event["datacontenttype"])
+
+		data, ok := event["data"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, "record.created", data["event"])
+	})
+
+	t.Run("cloudevents binary mode uses headers", func(t *testing.T) {
+		body, header, err := engine.formatBody(ctx, models.Webhook{Format: models.WebhookFormatCloudEventsBinary}, payloadJSON)
+		require.NoError(t, err)
+		assert.Equal(t, payloadJSON, body)
+		assert.Equal(t, "application/json", header.Get("Content-Type"))
+		assert.Equal(t, "1.0", header.Get("ce-specversion"))
+		assert.Equal(t, payload.ID.String(), header.Get("ce-id"))
+		assert.Equal(t, "com.vibetable.record.created", header.Get("ce-type"))
+		assert.Equal(t, payload.RecordID.String(), header.Get("ce-subject"))
+	})
+
+	t.Run("slack renders the message template", func(t *testing.T) {
+		renderer := &stubRenderer{}
+		engine := &DeliveryEngine{renderer: renderer}
+		template := "Hello {{field:Name}}"
+		creator := uuid.New()
+
+		body, _, err := engine.formatBody(ctx, models.Webhook{Format: models.WebhookFormatSlack, MessageTemplate: &template, CreatedBy: creator}, payloadJSON)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"text":"rendered record.created"}`, string(body))
+		assert.Equal(t, template, renderer.template)
+		assert.Equal(t, creator, renderer.actorID)
+	})
+
+	t.Run("teams sends a message card with the default template", func(t *testing.T) {
+		renderer := &stubRenderer{}
+		engine := &DeliveryEngine{renderer: renderer}
+
+		body, _, err := engine.formatBody(ctx, models.Webhook{Format: models.WebhookFormatTeams}, payloadJSON)
+		require.NoError(t, err)
+
+		var card map[string]string
+		require.NoError(t, json.Unmarshal(body, &card))
+		assert.Equal(t, "MessageCard", card["@type"])
+		assert.Equal(t, "record.created", card["summary"])
+		assert.Equal(t, "rendered record.created", card["text"])
+		assert.Equal(t, DefaultMessageTemplate, renderer.template)
+	})
+
+	t.Run("chat formats fall back to the event name without a renderer", func(t *testing.T) {
+		body, _, err := engine.formatBody(ctx, models.Webhook{Format: models.WebhookFormatSlack}, payloadJSON)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"text":"record.created"}`, string(body))
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		_, _, err := engine.formatBody(ctx, models.Webhook{Format: "xml"}, payloadJSON)
+		assert.Error(t, err)
+	})
+}
+
+func TestDeliveryEngine_deliverToWebhook_Format(t *testing.T) {
+	var contentType, signature string
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		signature = r.Header.Get("X-Webhook-Signature")
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	engine := &DeliveryEngine{httpClient: server.Client()}
+	secret := "secret"
+	_, payloadJSON := samplePayloadJSON(t)
+	webhook := models.Webhook{ID: uuid.New(), URL: server.URL, Secret: &secret, Format: models.WebhookFormatCloudEvents}
+
+	delivery, _ := engine.deliverToWebhook(context.Background(), webhook, "record.created", payloadJSON)
+	require.Nil(t, delivery.Error)
+
+	// The signature covers the body that was sent; the log keeps the native payload
+	assert.Equal(t, "application/cloudevents+json", contentType)
+	assert.NoError(t, webhooksig.Verify(signature, received, secret, webhooksig.DefaultTolerance))
+	assert.Equal(t, string(payloadJSON), delivery.Payload)
+}
+
+func TestDeliveryEngine_SendTest(t *testing.T) {
+	var received models.WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	engine := NewDeliveryEngine(store.NewWebhookStore(mock, store.NewBaseStore(mock)), nil)
+	engine.httpClient = server.Client()
+
+	tableID := uuid.New()
+	webhook := &models.Webhook{ID: uuid.New(), BaseID: uuid.New(), URL: server.URL, TableIDs: []uuid.UUID{tableID}}
+	userID := uuid.New()
+
+	mock.ExpectQuery("INSERT INTO webhook_deliveries").
+		WithArgs(pgxmock.AnyArg(), webhook.ID, "field.created", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), (*string)(nil), pgxmock.AnyArg(), pgxmock.AnyArg(), 1, (*uuid.UUID)(nil)).
+		WillReturnRows(pgxmock.NewRows(deliveryColumns).
+			AddRow(uuid.New(), webhook.ID, "field.created", "{}", nil, nil, nil, nil, time.Now(), 1, nil))
+
+	delivery := engine.SendTest(context.Background(), webhook, models.WebhookEventFieldCreated, userID)
+	require.Nil(t, delivery.Error)
+	assert.Equal(t, 1, delivery.Attempt)
+
+	assert.True(t, received.Test)
+	assert.Equal(t, models.WebhookEventFieldCreated, received.Event)
+	assert.Equal(t, webhook.BaseID, received.BaseID)
+	assert.Equal(t, tableID, received.TableID)
+	assert.Equal(t, userID, received.UserID)
+	require.NotNil(t, received.Field)
+	assert.Equal(t, "Sample field", received.Field.Name)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSamplePayload(t *testing.T) {
+	webhook := &models.Webhook{BaseID: uuid.New()}
+
+	for _, event := range models.ValidWebhookEvents() {
+		payload := samplePayload(webhook, event, uuid.New())
+		assert.Equal(t, event, payload.Event)
+		assert.True(t, payload.Test)
+		assert.NotEqual(t, uuid.Nil, payload.TableID, event)
+	}
+
+	comment := samplePayload(webhook, models.WebhookEventCommentCreated, uuid.New())
+	require.NotNil(t, comment.Comment)
+	assert.Equal(t, comment.Comment.RecordID, *comment.RecordID)
+}
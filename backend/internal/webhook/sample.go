@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vibetable/backend/internal/models"
+)
+
+// samplePayload builds a made-up event for the test endpoint. It uses the first table
+// the webhook is scoped to, if any, so receivers filtering on table see a familiar ID.
+func samplePayload(webhook *models.Webhook, event models.WebhookEvent, userID uuid.UUID) *models.WebhookPayload {
+	now := time.Now()
+	tableID := uuid.New()
+	if len(webhook.TableIDs) > 0 {
+		tableID = webhook.TableIDs[0]
+	}
+	recordID := uuid.New()
+	record := &models.Record{
+		ID:        recordID,
+		TableID:   tableID,
+		Values:    json.RawMessage(`{}`),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	payload := &models.WebhookPayload{
+		ID:        uuid.New(),
+		Event:     event,
+		Timestamp: now,
+		BaseID:    webhook.BaseID,
+		TableID:   tableID,
+		UserID:    userID,
+		Test:      true,
+	}
+
+	switch event {
+	case models.WebhookEventRecordCreated, models.WebhookEventRecordUpdated, models.WebhookEventRecordDeleted:
+		payload.RecordID = &recordID
+		payload.Record = record
+		if event == models.WebhookEventRecordUpdated {
+			payload.Changes = []models.WebhookFieldChange{}
+		}
+	case models.WebhookEventFieldCreated, models.WebhookEventFieldUpdated, models.WebhookEventFieldDeleted:
+		payload.Field = &models.Field{
+			ID:        uuid.New(),
+			TableID:   tableID,
+			Name:      "Sample field",
+			FieldType: models.FieldTypeText,
+			Options:   json.RawMessage(`{}`),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	case models.WebhookEventTableCreated, models.WebhookEventTableUpdated, models.WebhookEventTableDeleted:
+		payload.Table = &models.Table{ID: tableID, BaseID: webhook.BaseID, Name: "Sample table", CreatedAt: now, UpdatedAt: now}
+	case models.WebhookEventViewCreated, models.WebhookEventViewUpdated, models.WebhookEventViewDeleted:
+		payload.View = &models.View{
+			ID:        uuid.New(),
+			TableID:   tableID,
+			Name:      "Sample view",
+			Type:      models.ViewTypeGrid,
+			Config:    json.RawMessage(`{}`),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	case models.WebhookEventCommentCreated, models.WebhookEventCommentUpdated, models.WebhookEventCommentDeleted:
+		payload.RecordID = &recordID
+		payload.Comment = &models.Comment{
+			ID:        uuid.New(),
+			RecordID:  recordID,
+			UserID:    userID,
+			Content:   "This is a sample comment",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	case models.WebhookEventFormSubmitted:
+		formID := uuid.New()
+		payload.FormID = &formID
+		payload.RecordID = &recordID
+		payload.Record = record
+	}
+
+	return payload
+}
@@ -0,0 +1,18 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// secretPrefix makes generated secrets easy to recognise in receivers' config
+const secretPrefix = "whsec_"
+
+// GenerateSecret returns a new random signing secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,197 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxSheetName = 31
+
+	// Styles in styles.xml: dates without a time of day, and with one
+	dateStyle     = 1
+	dateTimeStyle = 2
+)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+const (
+	nsMain          = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	nsRelationships = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	nsPackageRels   = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+const stylesXML = xmlHeader + `<styleSheet xmlns="` + nsMain + `">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm"/></numFmts>` +
+	`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// Write writes sheets to w as an xlsx workbook. Cells may be nil, a string, a
+// number, a bool or a time.Time, which is shown with its time of day unless it is
+// midnight; anything else is written as text. Sheet names are made valid and
+// unique as Excel requires.
+func Write(w io.Writer, sheets []Sheet) error {
+	if len(sheets) == 0 {
+		sheets = []Sheet{{Name: "Sheet1"}}
+	}
+	names := sheetNames(sheets)
+
+	zw := zip.NewWriter(w)
+	var contentTypes, workbook, rels strings.Builder
+
+	contentTypes.WriteString(xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	workbook.WriteString(xmlHeader + `<workbook xmlns="` + nsMain + `" xmlns:r="` + nsRelationships + `"><sheets>`)
+	rels.WriteString(xmlHeader + `<Relationships xmlns="` + nsPackageRels + `">`)
+
+	for i, sheet := range sheets {
+		part := fmt.Sprintf("worksheets/sheet%d.xml", i+1)
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/%s" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, part)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(names[i]), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s/worksheet" Target="%s"/>`, i+1, nsRelationships, part)
+
+		fw, err := zw.Create("xl/" + part)
+		if err != nil {
+			return err
+		}
+		if err := writeSheet(fw, sheet.Rows); err != nil {
+			return err
+		}
+	}
+
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s/styles" Target="styles.xml"/></Relationships>`, len(sheets)+1, nsRelationships)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xmlHeader + `<Relationships xmlns="` + nsPackageRels + `">` +
+			`<Relationship Id="rId1" Type="` + nsRelationships + `/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+		{"xl/styles.xml", stylesXML},
+	}
+	for _, p := range parts {
+		fw, err := zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, p.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeSheet(w io.Writer, rows [][]interface{}) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xmlHeader + `<worksheet xmlns="` + nsMain + `"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(bw, `<row r="%d">`, i+1)
+		for col, value := range row {
+			writeCell(bw, columnName(col)+strconv.Itoa(i+1), value)
+		}
+		bw.WriteString(`</row>`)
+	}
+	bw.WriteString(`</sheetData></worksheet>`)
+	return bw.Flush()
+}
+
+func writeCell(w *bufio.Writer, ref string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		fmt.Fprintf(w, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+		return
+	case time.Time:
+		style := dateTimeStyle
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			style = dateStyle
+		}
+		serial := v.UTC().Sub(epoch(false)).Seconds() / 86400
+		fmt.Fprintf(w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(serial, 'f', -1, 64))
+		return
+	case int:
+		fmt.Fprintf(w, `<c r="%s"><v>%d</v></c>`, ref, v)
+		return
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+			return
+		}
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		text = fmt.Sprint(value)
+	}
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(text))
+}
+
+// sheetNames returns names for sheets that Excel accepts: at most 31 characters,
+// none of []:*?/\, not starting or ending with an apostrophe and unique ignoring case
+func sheetNames(sheets []Sheet) []string {
+	names := make([]string, len(sheets))
+	used := make(map[string]bool, len(sheets))
+	for i, sheet := range sheets {
+		base := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`[]:*?/\`, r) {
+				return ' '
+			}
+			return r
+		}, sheet.Name)
+		base = strings.TrimSpace(strings.Trim(base, "'"))
+		if base == "" {
+			base = fmt.Sprintf("Sheet%d", i+1)
+		}
+
+		name := truncate(base, maxSheetName)
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			suffix := fmt.Sprintf(" (%d)", n)
+			name = truncate(base, maxSheetName-len(suffix)) + suffix
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,376 @@
+// Package xlsx reads and writes Office Open XML workbooks (.xlsx): sheet names
+// and typed cell values, without formatting beyond telling dates apart.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxPartSize = 256 << 20
+	// maxCells bounds the cells a workbook expands to, as a small file can
+	// address cells far apart
+	maxCells   = 5_000_000
+	maxColumns = 16384
+	maxRows    = 1048576
+)
+
+var (
+	ErrInvalidWorkbook = errors.New("not a valid xlsx workbook")
+	ErrTooLarge        = errors.New("workbook has too many cells")
+)
+
+// Sheet is a worksheet's cells by row. Cells are nil, string, float64, bool or
+// time.Time; rows end at their last cell that has a value.
+type Sheet struct {
+	Name string
+	Rows [][]interface{}
+}
+
+// epoch returns the day that date serial numbers count from. Serials in the 1900
+// date system count 1900-02-29, which did not exist, so counting from 1899-12-30
+// gives the right dates from March 1900 on.
+func epoch(date1904 bool) time.Time {
+	if date1904 {
+		return time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+}
+
+type xmlWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xmlRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xmlText is a string item, either plain or in formatted runs
+type xmlText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xmlText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	b.WriteString(t.Text)
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xmlSharedStrings struct {
+	Items []xmlText `xml:"si"`
+}
+
+type xmlStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xmlWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string  `xml:"r,attr"`
+			Type   string  `xml:"t,attr"`
+			Style  int     `xml:"s,attr"`
+			Value  string  `xml:"v"`
+			Inline xmlText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// Read reads every worksheet of an xlsx workbook of size bytes, in workbook order
+func Read(r io.ReaderAt, size int64) ([]Sheet, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWorkbook, err)
+	}
+	parts := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		parts[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	var workbook xmlWorkbook
+	if err := decodePart(parts, "xl/workbook.xml", true, &workbook); err != nil {
+		return nil, err
+	}
+	var rels xmlRelationships
+	if err := decodePart(parts, "xl/_rels/workbook.xml.rels", true, &rels); err != nil {
+		return nil, err
+	}
+	var shared xmlSharedStrings
+	if err := decodePart(parts, "xl/sharedStrings.xml", false, &shared); err != nil {
+		return nil, err
+	}
+	var styles xmlStyles
+	if err := decodePart(parts, "xl/styles.xml", false, &styles); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if target, ok := strings.CutPrefix(rel.Target, "/"); ok {
+			targets[rel.ID] = target
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+
+	strs := make([]string, len(shared.Items))
+	for i, item := range shared.Items {
+		strs[i] = item.String()
+	}
+
+	customFormats := make(map[int]string, len(styles.NumFmts))
+	for _, f := range styles.NumFmts {
+		customFormats[f.ID] = f.Code
+	}
+	dateStyles := make([]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		dateStyles[i] = isDateFormat(xf.NumFmtID, customFormats[xf.NumFmtID])
+	}
+
+	p := &sheetParser{
+		strings:    strs,
+		dateStyles: dateStyles,
+		epoch:      epoch(workbook.Properties.Date1904),
+	}
+	sheets := make([]Sheet, 0, len(workbook.Sheets))
+	for _, s := range workbook.Sheets {
+		target, ok := targets[s.RID]
+		if !ok {
+			return nil, fmt.Errorf("%w: sheet %q has no part", ErrInvalidWorkbook, s.Name)
+		}
+		var ws xmlWorksheet
+		if err := decodePart(parts, target, true, &ws); err != nil {
+			return nil, err
+		}
+		rows, err := p.rows(&ws)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", s.Name, err)
+		}
+		sheets = append(sheets, Sheet{Name: s.Name, Rows: rows})
+	}
+	return sheets, nil
+}
+
+// decodePart decodes the XML part name into v; a missing part that is not
+// required leaves v as it is
+func decodePart(parts map[string]*zip.File, name string, required bool, v interface{}) error {
+	f, ok := parts[name]
+	if !ok {
+		if required {
+			return fmt.Errorf("%w: %s is missing", ErrInvalidWorkbook, name)
+		}
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWorkbook, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidWorkbook, name, err)
+	}
+	return nil
+}
+
+type sheetParser struct {
+	strings    []string
+	dateStyles []bool
+	epoch      time.Time
+	cells      int
+}
+
+// rows lays a worksheet's cells out by their references. Rows and cells without
+// a reference follow the one before.
+func (p *sheetParser) rows(ws *xmlWorksheet) ([][]interface{}, error) {
+	var rows [][]interface{}
+	next := 0
+	for _, row := range ws.Rows {
+		index := next
+		if row.R > 0 {
+			index = row.R - 1
+		}
+		if index >= maxRows {
+			return nil, fmt.Errorf("%w: row %d is past the last row", ErrInvalidWorkbook, row.R)
+		}
+		next = index + 1
+
+		var cells []interface{}
+		col := 0
+		for _, c := range row.Cells {
+			if c.Ref != "" {
+				var ok bool
+				if col, ok = columnIndex(c.Ref); !ok {
+					return nil, fmt.Errorf("%w: invalid cell reference %q", ErrInvalidWorkbook, c.Ref)
+				}
+			}
+			value, err := p.value(c.Type, c.Style, c.Value, c.Inline)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s: %w", c.Ref, err)
+			}
+			if value != nil {
+				if col >= len(cells) {
+					p.cells += col + 1 - len(cells)
+					if p.cells > maxCells {
+						return nil, ErrTooLarge
+					}
+					cells = append(cells, make([]interface{}, col+1-len(cells))...)
+				}
+				cells[col] = value
+			}
+			col++
+		}
+
+		if len(cells) == 0 {
+			continue
+		}
+		for len(rows) < index {
+			rows = append(rows, nil)
+		}
+		if index < len(rows) {
+			return nil, fmt.Errorf("%w: row %d is out of order", ErrInvalidWorkbook, index+1)
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// value converts a cell's stored value according to its type and style
+func (p *sheetParser) value(cellType string, style int, v string, inline xmlText) (interface{}, error) {
+	switch cellType {
+	case "s":
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 || i >= len(p.strings) {
+			return nil, fmt.Errorf("%w: invalid shared string %q", ErrInvalidWorkbook, v)
+		}
+		return nonEmpty(p.strings[i]), nil
+	case "inlineStr":
+		return nonEmpty(inline.String()), nil
+	case "str", "e":
+		return nonEmpty(v), nil
+	case "b":
+		return v == "1" || v == "true", nil
+	case "d":
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return nonEmpty(v), nil
+	default:
+		if v == "" {
+			return nil, nil
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q", ErrInvalidWorkbook, v)
+		}
+		if style >= 0 && style < len(p.dateStyles) && p.dateStyles[style] {
+			return p.epoch.Add(time.Duration(math.Round(n*86400)) * time.Second), nil
+		}
+		return n, nil
+	}
+}
+
+func nonEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// columnIndex returns the zero-based column of a cell reference such as "AB12"
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	i := 0
+	for ; i < len(ref); i++ {
+		c := ref[i]
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		if col > maxColumns {
+			return 0, false
+		}
+	}
+	if col == 0 || i == len(ref) {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+// columnName returns the letters of a zero-based column
+func columnName(col int) string {
+	var name []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = append([]byte{byte('A' + (col-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// isDateFormat reports whether a number format shows dates or times: one of the
+// built-in date formats, or a format code using date or time parts outside its
+// literal text
+func isDateFormat(id int, code string) bool {
+	switch {
+	case id >= 14 && id <= 22, id >= 27 && id <= 36, id >= 45 && id <= 47, id >= 50 && id <= 58:
+		return true
+	case code == "":
+		return false
+	}
+	inQuotes, inBrackets := false, false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case inQuotes:
+			inQuotes = c != '"'
+		case inBrackets:
+			inBrackets = c != ']'
+		case c == '"':
+			inQuotes = true
+		case c == '[':
+			inBrackets = true
+		case c == '\\' || c == '_' || c == '*':
+			i++ // the next character is literal, or padding
+		case strings.IndexByte("dmyhsDMYHS", c) >= 0:
+			return true
+		}
+	}
+	return false
+}
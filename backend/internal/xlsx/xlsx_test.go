@@ -0,0 +1,181 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// workbook zips parts into an xlsx file
+func workbook(t *testing.T, parts map[string]string) *bytes.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestWriteRead(t *testing.T) {
+	day := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	moment := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+	sheets := []Sheet{
+		{Name: "Contacts", Rows: [][]interface{}{
+			{"Name", "Age", "Active", "Joined", "Last seen"},
+			{"Ada <Lovelace> & co", 36.5, true, day, moment},
+			{nil, 42, false},
+		}},
+		{Name: "Empty"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, sheets))
+
+	read, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+	assert.Equal(t, "Contacts", read[0].Name)
+	assert.Equal(t, [][]interface{}{
+		{"Name", "Age", "Active", "Joined", "Last seen"},
+		{"Ada <Lovelace> & co", 36.5, true, day, moment},
+		{nil, 42.0, false},
+	}, read[0].Rows)
+	assert.Equal(t, "Empty", read[1].Name)
+	assert.Empty(t, read[1].Rows)
+}
+
+func TestRead(t *testing.T) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<workbookPr date1904="1"/>
+			<sheets><sheet name="Data" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId7" Target="/xl/worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>Title</t></si>
+			<si><r><t>Bold</t></r><r><t xml:space="preserve"> text</t></r></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<numFmts><numFmt numFmtId="170" formatCode="d mmm yyyy"/><numFmt numFmtId="171" formatCode="&quot;day&quot; 0"/></numFmts>
+			<cellXfs><xf numFmtId="0"/><xf numFmtId="170"/><xf numFmtId="171"/></cellXfs></styleSheet>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="2"><c r="B2" t="s"><v>0</v></c><c r="D2" t="s"><v>1</v></c></row>
+			<row><c t="inlineStr"><is><t>inline</t></is></c><c s="1"><v>0</v></c><c s="2"><v>3</v></c><c t="str"><v>=A1</v></c><c t="e"><v>#N/A</v></c></row>
+		</sheetData></worksheet>`,
+	}
+
+	r := workbook(t, parts)
+	sheets, err := Read(r, r.Size())
+	require.NoError(t, err)
+	require.Len(t, sheets, 1)
+	assert.Equal(t, "Data", sheets[0].Name)
+	assert.Equal(t, [][]interface{}{
+		nil,
+		{nil, "Title", nil, "Bold text"},
+		{"inline", time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC), 3.0, "=A1", "#N/A"},
+	}, sheets[0].Rows)
+
+	t.Run("rejects files that are not workbooks", func(t *testing.T) {
+		_, err := Read(strings.NewReader("not a zip"), 9)
+		assert.ErrorIs(t, err, ErrInvalidWorkbook)
+
+		r := workbook(t, map[string]string{"base.json": "{}"})
+		_, err = Read(r, r.Size())
+		assert.ErrorIs(t, err, ErrInvalidWorkbook)
+	})
+
+	t.Run("rejects out of range shared strings", func(t *testing.T) {
+		broken := map[string]string{}
+		for name, content := range parts {
+			broken[name] = content
+		}
+		broken["xl/worksheets/data.xml"] = `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>9</v></c></row></sheetData></worksheet>`
+
+		r := workbook(t, broken)
+		_, err := Read(r, r.Size())
+		assert.ErrorIs(t, err, ErrInvalidWorkbook)
+	})
+
+	t.Run("limits how far apart cells can be", func(t *testing.T) {
+		sparse := map[string]string{}
+		for name, content := range parts {
+			sparse[name] = content
+		}
+		var sheet strings.Builder
+		sheet.WriteString(`<worksheet><sheetData>`)
+		for row := 1; row <= maxCells/16384+1; row++ {
+			sheet.WriteString(`<row><c r="XFD1"><v>1</v></c></row>`)
+		}
+		sheet.WriteString(`</sheetData></worksheet>`)
+		sparse["xl/worksheets/data.xml"] = sheet.String()
+
+		r := workbook(t, sparse)
+		_, err := Read(r, r.Size())
+		assert.ErrorIs(t, err, ErrTooLarge)
+	})
+}
+
+func TestSheetNames(t *testing.T) {
+	names := sheetNames([]Sheet{
+		{Name: "Q1/Q2 [draft]"},
+		{Name: "'Quoted'"},
+		{Name: ""},
+		{Name: "q1 q2  draft"},
+		{Name: "A name much longer than thirty-one characters"},
+		{Name: "A name much longer than thirty-one characters too"},
+	})
+	assert.Equal(t, []string{
+		"Q1 Q2  draft",
+		"Quoted",
+		"Sheet3",
+		"q1 q2  draft (2)",
+		"A name much longer than thirty-",
+		"A name much longer than thi (2)",
+	}, names)
+}
+
+func TestIsDateFormat(t *testing.T) {
+	tests := []struct {
+		id   int
+		code string
+		want bool
+	}{
+		{0, "", false},
+		{14, "", true},
+		{22, "", true},
+		{4, "", false},
+		{164, "yyyy-mm-dd", true},
+		{164, "h:mm AM/PM", true},
+		{164, "#,##0.00", false},
+		{164, `0.0 "days"`, false},
+		{164, `[Red]0.00`, false},
+		{164, `0\d`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.want, isDateFormat(tt.id, tt.code))
+		})
+	}
+}
+
+func TestColumns(t *testing.T) {
+	for col, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA", 16383: "XFD"} {
+		assert.Equal(t, name, columnName(col))
+		index, ok := columnIndex(name + "1")
+		assert.True(t, ok)
+		assert.Equal(t, col, index)
+	}
+
+	for _, ref := range []string{"", "12", "A", "XFE1"} {
+		_, ok := columnIndex(ref)
+		assert.False(t, ok, ref)
+	}
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,17 +19,28 @@ import (
 	"github.com/vibetable/backend/internal/api/handlers"
 	authmw "github.com/vibetable/backend/internal/api/middleware"
 	"github.com/vibetable/backend/internal/automation"
+	"github.com/vibetable/backend/internal/jobs"
 	"github.com/vibetable/backend/internal/migrate"
 	"github.com/vibetable/backend/internal/models"
+	"github.com/vibetable/backend/internal/outbound"
 	"github.com/vibetable/backend/internal/realtime"
+	"github.com/vibetable/backend/internal/secrets"
 	"github.com/vibetable/backend/internal/storage"
+	"github.com/vibetable/backend/internal/storagegc"
 	"github.com/vibetable/backend/internal/store"
+	"github.com/vibetable/backend/internal/thumbnail"
 	"github.com/vibetable/backend/internal/webhook"
 )
 
 var db *pgxpool.Pool
 
 func main() {
+	// "migrate-storage" copies local attachment files into the configured bucket and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate-storage" {
+		migrateStorage()
+		return
+	}
+
 	// Get configuration from environment
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -119,16 +132,60 @@ func main() {
 	if baseURL == "" {
 		baseURL = "http://localhost:" + port
 	}
-	fileStorage, err := storage.NewLocalStorage(storagePath, baseURL)
-	if err != nil {
-		log.Fatalf("Failed to initialize storage: %v", err)
+	// With STORAGE_BACKEND=s3, attachments are kept in an S3-compatible bucket so
+	// several instances can serve them
+	var fileStorage storage.Storage
+	var localStorage *storage.LocalStorage
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		localStorage, err = storage.NewLocalStorage(storagePath, baseURL)
+		if err != nil {
+			log.Fatalf("Failed to initialize storage: %v", err)
+		}
+		fileStorage = localStorage
+		log.Printf("File storage initialized at: %s", storagePath)
+	case "s3":
+		s3Config := storage.S3ConfigFromEnv()
+		fileStorage, err = storage.NewS3Storage(s3Config)
+		if err != nil {
+			log.Fatalf("Failed to initialize storage: %v", err)
+		}
+		log.Printf("File storage initialized in bucket: %s", s3Config.Bucket)
+	default:
+		log.Fatalf("Unknown STORAGE_BACKEND: %s", backend)
 	}
-	log.Printf("File storage initialized at: %s", storagePath)
 
-	// Initialize realtime hub
+	// Initialize realtime hub. With REALTIME_BACKEND=postgres, messages and presence
+	// are relayed through Postgres so several instances can run side by side.
 	hub := realtime.NewHub()
+	var realtimeRelay *store.RealtimeRelay
+	var realtimeEvents *store.RealtimeEventLog
+	switch backend := os.Getenv("REALTIME_BACKEND"); backend {
+	case "", "local":
+		hub.SetEventLog(realtime.NewMemoryEventLog())
+	case "postgres":
+		// Sequence numbers and cell locks must agree across instances, so they are shared too
+		realtimeRelay = store.NewRealtimeRelay(db)
+		realtimeEvents = store.NewRealtimeEventLog(db)
+		hub.SetBackend(realtimeRelay)
+		hub.SetEventLog(realtimeEvents)
+		hub.SetCellLocks(store.NewCellLockStore(db))
+	default:
+		log.Fatalf("Unknown REALTIME_BACKEND: %s", backend)
+	}
+	// Open connections are dropped when their session ends or access is lost
+	hub.SetAuthorizer(store.NewRealtimeAuthorizer(db))
 	go hub.Run()
-	log.Println("Real-time hub started")
+	if realtimeRelay != nil {
+		log.Println("Real-time hub started (relaying through Postgres)")
+	} else {
+		log.Println("Real-time hub started")
+	}
+
+	// Realtime connection counts for monitoring, only with METRICS_TOKEN set
+	if metricsToken := os.Getenv("METRICS_TOKEN"); metricsToken != "" {
+		r.Get("/metrics/realtime", realtimeMetricsHandler(hub, metricsToken))
+	}
 
 	// Initialize stores
 	authStore := store.NewAuthStore(db)
@@ -141,15 +198,59 @@ func main() {
 	commentStore := store.NewCommentStore(db, baseStore, tableStore, recordStore)
 	activityStore := store.NewActivityStore(db, baseStore)
 	attachmentStore := store.NewAttachmentStore(db, baseStore, tableStore, recordStore, fileStorage, baseURL)
+	archiveStore := store.NewArchiveStore(db, baseStore, attachmentStore)
+	templateStore := store.NewTemplateStore(db, baseStore, archiveStore)
+	jobStore := store.NewJobStore(db, baseStore)
 	automationStore := store.NewAutomationStore(db, baseStore, tableStore)
 	apiKeyStore := store.NewAPIKeyStore(db)
 	webhookStore := store.NewWebhookStore(db, baseStore)
+	wsTicketStore := store.NewWSTicketStore(db)
+	serverSecretStore := store.NewServerSecretStore(db)
+
+	// Attachment and export URLs are signed with a key shared between instances
+	urlSecret, err := serverSecretStore.GetOrCreate(context.Background(), "attachment_urls")
+	if err != nil {
+		log.Fatalf("Failed to load attachment URL secret: %v", err)
+	}
+	urlSigner := storage.NewURLSigner([]byte(urlSecret))
+	attachmentStore.SetURLSigner(urlSigner)
+	if localStorage != nil {
+		localStorage.SetURLSigner(urlSigner)
+	}
+
+	// Each base may store up to BASE_STORAGE_QUOTA_MB of attachments
+	if quota := os.Getenv("BASE_STORAGE_QUOTA_MB"); quota != "" {
+		quotaMB, err := strconv.ParseInt(quota, 10, 64)
+		if err != nil || quotaMB < 0 {
+			log.Fatalf("Invalid BASE_STORAGE_QUOTA_MB: %s", quota)
+		}
+		attachmentStore.SetQuota(quotaMB << 20)
+		baseStore.SetQuota(quotaMB << 20)
+	}
+
+	// Encrypt webhook secrets at rest when a key is configured
+	secretBox, err := secrets.BoxFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load secrets key: %v", err)
+	}
+	if secretBox == nil {
+		log.Printf("Warning: %s is not set; webhook secrets are stored unencrypted", secrets.KeyEnv)
+	}
+	webhookStore.SetSecretBox(secretBox)
+	if n, err := webhookStore.EncryptPlaintextSecrets(context.Background()); err != nil {
+		log.Fatalf("Failed to encrypt webhook secrets: %v", err)
+	} else if n > 0 {
+		log.Printf("Encrypted %d webhook secrets", n)
+	}
 
 	// Set hub on stores that need to broadcast
 	recordStore.SetHub(hub)
 	fieldStore.SetHub(hub)
 	tableStore.SetHub(hub)
 	viewStore.SetHub(hub)
+	baseStore.SetHub(hub)
+	attachmentStore.SetHub(hub)
+	jobStore.SetHub(hub)
 
 	// Start background session cleanup job
 	go func() {
@@ -168,19 +269,65 @@ func main() {
 				if err := authStore.CleanupExpiredPasswordResetTokens(ctx); err != nil {
 					log.Printf("Error cleaning up expired password reset tokens: %v", err)
 				}
+				if err := wsTicketStore.CleanupExpiredTickets(ctx); err != nil {
+					log.Printf("Error cleaning up expired WebSocket tickets: %v", err)
+				}
+				if realtimeRelay != nil {
+					if err := realtimeRelay.CleanupSpill(ctx); err != nil {
+						log.Printf("Error cleaning up realtime spill: %v", err)
+					}
+					if err := realtimeEvents.CleanupExpiredEvents(ctx); err != nil {
+						log.Printf("Error cleaning up realtime events: %v", err)
+					}
+				}
 				log.Println("Session cleanup completed")
 			}
 		}
 	}()
 	log.Println("Session cleanup job started (runs every hour)")
 
+	// Outbound requests to user-supplied URLs may not reach internal addresses
+	outboundPolicy, err := outbound.PolicyFromEnv()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	// Initialize automation engine
 	automationEngine := automation.NewEngine(automationStore, recordStore, fieldStore)
+	automationEngine.SetOutboundPolicy(outboundPolicy)
+	automationEngine.SetViewStore(viewStore)
+	automationEngine.SetCommentStore(commentStore)
+	automationEngine.SetTemplateStores(tableStore, authStore)
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173"
+	}
+	automationEngine.SetAppURL(frontendURL)
 
 	// Initialize webhook delivery engine
 	webhookEngine := webhook.NewDeliveryEngine(webhookStore, tableStore)
+	webhookEngine.SetActivityStore(activityStore)
+	webhookEngine.SetOutboundPolicy(outboundPolicy)
+	webhookEngine.SetMessageRenderer(automationEngine)
+	webhookEngine.Start(context.Background())
 	log.Println("Webhook delivery engine initialized")
 
+	// Generate thumbnails for uploaded images in the background
+	thumbnailWorker := thumbnail.NewWorker(attachmentStore, fileStorage)
+	attachmentStore.SetThumbnailNotifier(thumbnailWorker.Notify)
+	thumbnailWorker.Start(context.Background())
+
+	// Remove stored files that no attachment uses any more
+	storagegc.NewCollector(attachmentStore, fileStorage).Start(context.Background())
+
+	// Run long operations such as duplicating bases in the background
+	jobWorker := jobs.NewWorker(jobStore)
+	jobWorker.Register(models.JobDuplicateBase, jobs.DuplicateBase(baseStore))
+	jobWorker.Register(models.JobDuplicateTable, jobs.DuplicateTable(tableStore))
+	jobWorker.Register(models.JobImportRecords, jobs.ImportRecords(recordStore))
+	jobStore.SetNotifier(jobWorker.Notify)
+	jobWorker.Start(context.Background())
+
 	// Set automation and webhook callbacks on record store
 	recordStore.SetAutomationCallback(func(tableID uuid.UUID, recordID *uuid.UUID, record *models.Record, oldRecord *models.Record, triggerType string, userID uuid.UUID) {
 		ctx := context.Background()
@@ -222,28 +369,95 @@ func main() {
 			}
 		}
 	})
+
+	// Schema and comment changes are sent to webhooks
+	tableStore.SetChangeCallback(webhookEngine.ProcessChange)
+	fieldStore.SetChangeCallback(webhookEngine.ProcessChange)
+	viewStore.SetChangeCallback(webhookEngine.ProcessChange)
+	commentStore.SetChangeCallback(webhookEngine.ProcessChange)
+
+	// Form submissions and comments have their own automation triggers
+	formStore.SetSubmitCallback(func(formID uuid.UUID, tableID uuid.UUID, record *models.Record) {
+		ctx := context.Background()
+		automationEngine.ProcessTrigger(ctx, &automation.TriggerContext{
+			TableID:     tableID,
+			RecordID:    &record.ID,
+			Record:      record,
+			TriggerType: models.TriggerFormSubmitted,
+			FormID:      &formID,
+		})
+
+		if baseID, err := tableStore.GetBaseID(ctx, tableID); err == nil {
+			webhookEngine.ProcessEvent(ctx, &webhook.DeliveryContext{
+				BaseID:   baseID,
+				TableID:  tableID,
+				RecordID: &record.ID,
+				Record:   record,
+				FormID:   &formID,
+				Event:    models.WebhookEventFormSubmitted,
+			})
+		}
+	})
+	commentStore.SetCommentCallback(func(tableID uuid.UUID, comment *models.Comment, userID uuid.UUID) {
+		ctx := context.Background()
+		triggerCtx := &automation.TriggerContext{
+			TableID:     tableID,
+			RecordID:    &comment.RecordID,
+			TriggerType: models.TriggerCommentAdded,
+			Comment:     comment,
+			UserID:      userID,
+		}
+		if record, err := recordStore.GetRecord(ctx, comment.RecordID, userID); err == nil {
+			triggerCtx.Record = record
+		}
+		automationEngine.ProcessTrigger(ctx, triggerCtx)
+	})
 	log.Println("Automation engine initialized")
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authStore)
 	baseHandler := handlers.NewBaseHandler(baseStore)
+	baseHandler.SetJobStore(jobStore)
 	tableHandler := handlers.NewTableHandler(tableStore)
+	tableHandler.SetJobStore(jobStore)
 	fieldHandler := handlers.NewFieldHandler(fieldStore)
 	recordHandler := handlers.NewRecordHandler(recordStore, activityStore)
 	viewHandler := handlers.NewViewHandler(viewStore)
 	csvHandler := handlers.NewCSVHandler(recordStore, fieldStore, tableStore)
+	csvHandler.SetJobStore(jobStore)
+	xlsxHandler := handlers.NewXLSXHandler(baseStore, tableStore, fieldStore, recordStore, viewStore)
+	xlsxHandler.SetJobStore(jobStore)
+	ndjsonHandler := handlers.NewNDJSONHandler(recordStore, fieldStore, tableStore)
+	ndjsonHandler.SetJobStore(jobStore)
 	formHandler := handlers.NewFormHandler(formStore)
 	commentHandler := handlers.NewCommentHandler(commentStore)
 	activityHandler := handlers.NewActivityHandler(activityStore)
 	attachmentHandler := handlers.NewAttachmentHandler(attachmentStore)
+	archiveHandler := handlers.NewArchiveHandler(archiveStore)
+	exportLinkHandler := handlers.NewExportLinkHandler(baseStore, tableStore, urlSigner, baseURL)
+	templateHandler := handlers.NewTemplateHandler(templateStore)
+	jobHandler := handlers.NewJobHandler(jobStore)
 	automationHandler := handlers.NewAutomationHandler(automationStore)
+	automationHandler.SetEngine(automationEngine)
+	automationHandler.SetOutboundPolicy(outboundPolicy)
 	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyStore)
 	webhookHandler := handlers.NewWebhookHandler(webhookStore, baseStore)
-	wsHandler := handlers.NewWebSocketHandler(hub, authStore, baseStore)
+	webhookHandler.SetDeliveryEngine(webhookEngine)
+	webhookHandler.SetOutboundPolicy(outboundPolicy)
+	wsHandler := handlers.NewWebSocketHandler(hub, authStore, baseStore, wsTicketStore)
 
 	// Initialize middleware
 	authMiddleware := authmw.NewAuthMiddleware(authStore)
+	authMiddleware.SetURLSigner(urlSigner)
 	csrfMiddleware := authmw.NewCSRFMiddleware()
+	if os.Getenv("CSRF_SECRET") == "" {
+		// Share one generated key between instances instead of one per process
+		csrfSecret, err := serverSecretStore.GetOrCreate(context.Background(), "csrf")
+		if err != nil {
+			log.Fatalf("Failed to load CSRF secret: %v", err)
+		}
+		csrfMiddleware.SetSecret([]byte(csrfSecret))
+	}
 	rateLimitMiddleware := authmw.NewRateLimitMiddleware()
 
 	// WebSocket route (outside /api/v1 for simplicity)
@@ -290,12 +504,23 @@ func main() {
 
 			r.Get("/", baseHandler.ListBases)
 			r.Post("/", baseHandler.CreateBase)
+			r.Post("/import", archiveHandler.ImportBase)
 
 			r.Route("/{id}", func(r chi.Router) {
 				r.Get("/", baseHandler.GetBase)
 				r.Patch("/", baseHandler.UpdateBase)
 				r.Delete("/", baseHandler.DeleteBase)
 				r.Post("/duplicate", baseHandler.DuplicateBase)
+				r.Get("/export", archiveHandler.ExportBase)
+				r.Post("/export-link", exportLinkHandler.CreateBaseLink)
+				r.Get("/storage", attachmentHandler.GetStorageUsage)
+
+				// Excel import/export, a sheet per table
+				r.Route("/xlsx", func(r chi.Router) {
+					r.Post("/preview", xlsxHandler.Preview)
+					r.Post("/import", xlsxHandler.Import)
+					r.Get("/export", xlsxHandler.ExportBase)
+				})
 
 				// Collaborators
 				r.Get("/collaborators", baseHandler.ListCollaborators)
@@ -306,6 +531,9 @@ func main() {
 				// Activity log
 				r.Get("/activity", activityHandler.ListActivitiesForBase)
 
+				// Background jobs
+				r.Get("/jobs", jobHandler.ListJobs)
+
 				// Webhooks
 				r.Route("/webhooks", func(r chi.Router) {
 					r.Get("/", webhookHandler.ListWebhooks)
@@ -350,6 +578,16 @@ func main() {
 				r.Get("/export", csvHandler.Export)
 			})
 
+			// Signed links for opening any of the exports in a new tab
+			r.Post("/{tableId}/export-link", exportLinkHandler.CreateTableLink)
+
+			// Excel and newline-delimited JSON export, and JSON import
+			r.Get("/{tableId}/xlsx/export", xlsxHandler.ExportTable)
+			r.Route("/{tableId}/ndjson", func(r chi.Router) {
+				r.Post("/import", ndjsonHandler.Import)
+				r.Get("/export", ndjsonHandler.Export)
+			})
+
 			// Forms within a table
 			r.Route("/{tableId}/forms", func(r chi.Router) {
 				r.Get("/", formHandler.ListForms)
@@ -413,17 +651,39 @@ func main() {
 				r.Post("/", attachmentHandler.UploadAttachment)
 			})
 
+			// Resumable uploads for large attachments
+			r.Post("/{recordId}/fields/{fieldId}/uploads", attachmentHandler.CreateUpload)
+
 			// Activity on records
 			r.Get("/{recordId}/activity", activityHandler.ListActivitiesForRecord)
 		})
 
 		// Attachment routes (by attachment ID)
 		r.Route("/attachments", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.Required)
+				r.Use(csrfMiddleware.Protect)
+				r.Get("/{id}", attachmentHandler.GetAttachment)
+				r.Delete("/{id}", attachmentHandler.DeleteAttachment)
+			})
+
+			// Downloads accept a signed URL in place of a session, so files can be embedded
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.Optional)
+				r.Get("/{id}/download", attachmentHandler.DownloadAttachment)
+				r.Get("/{id}/thumbnail", attachmentHandler.DownloadThumbnail)
+				r.Get("/file/*", attachmentHandler.DownloadFile)
+			})
+		})
+
+		// Resumable upload routes (by upload ID)
+		r.Route("/uploads", func(r chi.Router) {
 			r.Use(authMiddleware.Required)
 			r.Use(csrfMiddleware.Protect)
-			r.Get("/{id}", attachmentHandler.GetAttachment)
-			r.Get("/{id}/download", attachmentHandler.DownloadAttachment)
-			r.Delete("/{id}", attachmentHandler.DeleteAttachment)
+			r.Get("/{id}", attachmentHandler.GetUpload)
+			r.Put("/{id}", attachmentHandler.WriteUploadChunk)
+			r.Delete("/{id}", attachmentHandler.CancelUpload)
+			r.Post("/{id}/complete", attachmentHandler.CompleteUpload)
 		})
 
 		// Comment routes (by comment ID)
@@ -454,9 +714,29 @@ func main() {
 			r.Patch("/{id}", automationHandler.UpdateAutomation)
 			r.Delete("/{id}", automationHandler.DeleteAutomation)
 			r.Post("/{id}/toggle", automationHandler.ToggleAutomation)
+			r.Post("/{id}/test", automationHandler.TestAutomation)
 			r.Get("/{id}/runs", automationHandler.ListRuns)
 		})
 
+		// Background job routes (by job ID)
+		r.Route("/jobs", func(r chi.Router) {
+			r.Use(authMiddleware.Required)
+			r.Use(csrfMiddleware.Protect)
+			r.Get("/{id}", jobHandler.GetJob)
+			r.Post("/{id}/cancel", jobHandler.CancelJob)
+		})
+
+		// Base template gallery
+		r.Route("/templates", func(r chi.Router) {
+			r.Use(authMiddleware.Required)
+			r.Use(csrfMiddleware.Protect)
+			r.Get("/", templateHandler.ListTemplates)
+			r.Post("/", templateHandler.CreateTemplate)
+			r.Get("/{id}", templateHandler.GetTemplate)
+			r.Delete("/{id}", templateHandler.DeleteTemplate)
+			r.Post("/{id}/bases", templateHandler.CreateBaseFromTemplate)
+		})
+
 		// API Key routes
 		r.Route("/api-keys", func(r chi.Router) {
 			r.Use(authMiddleware.Required)
@@ -475,6 +755,9 @@ func main() {
 			r.Patch("/{id}", webhookHandler.UpdateWebhook)
 			r.Delete("/{id}", webhookHandler.DeleteWebhook)
 			r.Get("/{id}/deliveries", webhookHandler.ListDeliveries)
+			r.Post("/{id}/deliveries/{deliveryId}/redeliver", webhookHandler.RedeliverDelivery)
+			r.Post("/{id}/test", webhookHandler.TestWebhook)
+			r.Post("/{id}/rotate-secret", webhookHandler.RotateSecret)
 		})
 
 		// Public form routes (no auth required, with rate limiting)
@@ -484,6 +767,12 @@ func main() {
 			r.Post("/{token}", formHandler.SubmitPublicForm)
 		})
 
+		// Inbound automation webhooks (no auth required, the token is the credential)
+		r.Route("/public/automation-webhooks", func(r chi.Router) {
+			r.Use(rateLimitMiddleware.Public)
+			r.Post("/{token}", automationHandler.ReceiveWebhook)
+		})
+
 		// Public view routes (no auth required, with rate limiting)
 		r.Route("/public/views", func(r chi.Router) {
 			r.Use(rateLimitMiddleware.Public)
@@ -526,6 +815,19 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, response)
 }
 
+// realtimeMetricsHandler serves the hub's connection counts to callers that present
+// the metrics token, keeping them off the public health check
+func realtimeMetricsHandler(hub *realtime.Hub, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized", "message": "A metrics token is required"})
+			return
+		}
+		writeJSON(w, http.StatusOK, hub.Stats())
+	}
+}
+
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -533,3 +835,28 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 		log.Printf("Error encoding JSON response: %v", err)
 	}
 }
+
+// migrateStorage copies the files under STORAGE_PATH into the S3 bucket configured by
+// the S3_* variables. Files already in the bucket are skipped, so it can be re-run.
+func migrateStorage() {
+	storagePath := os.Getenv("STORAGE_PATH")
+	if storagePath == "" {
+		storagePath = "./uploads"
+	}
+	local, err := storage.NewLocalStorage(storagePath, "")
+	if err != nil {
+		log.Fatalf("Failed to open local storage: %v", err)
+	}
+	s3Config := storage.S3ConfigFromEnv()
+	bucket, err := storage.NewS3Storage(s3Config)
+	if err != nil {
+		log.Fatalf("Failed to initialize S3 storage: %v", err)
+	}
+
+	log.Printf("Copying files from %s to bucket %s...", storagePath, s3Config.Bucket)
+	copied, skipped, err := storage.CopyLocal(context.Background(), local, bucket)
+	if err != nil {
+		log.Fatalf("Storage migration stopped after copying %d files: %v", copied, err)
+	}
+	log.Printf("Storage migration complete: %d files copied, %d already in the bucket", copied, skipped)
+}
@@ -0,0 +1,134 @@
+// Package webhooksig signs and verifies VibeTable webhook requests.
+//
+// Each delivery carries an X-Webhook-Signature header of the form
+//
+//	t=1700000000,v1=5257a869...,v1=9f2c1e0b...
+//
+// where t is the Unix time the request was signed and each v1 is the hex HMAC-SHA256
+// of "<t>.<body>" under one of the webhook's secrets. While a secret is being rotated
+// the request is signed with both the new and the previous secret, so receivers can
+// switch secrets at their own pace. Receivers should check that t is recent to
+// reject replayed requests; Verify does both.
+//
+//	body, err := webhooksig.VerifyRequest(r, secret, webhooksig.DefaultTolerance)
+//	if err != nil {
+//		http.Error(w, "invalid signature", http.StatusUnauthorized)
+//		return
+//	}
+package webhooksig
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers set on every signed delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+// DefaultTolerance is how far a signature's timestamp may be from the receiver's clock
+const DefaultTolerance = 5 * time.Minute
+
+// signatureScheme labels signatures over "<timestamp>.<body>" with HMAC-SHA256
+const signatureScheme = "v1"
+
+var (
+	ErrMissingSignature = errors.New("webhooksig: missing signature header")
+	ErrInvalidHeader    = errors.New("webhooksig: malformed signature header")
+	ErrTimestampExpired = errors.New("webhooksig: timestamp outside the allowed tolerance")
+	ErrNoMatch          = errors.New("webhooksig: no signature matches the secret")
+)
+
+// Sign returns the hex signature of a body sent at the given time
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Header builds the signature header value with one signature per secret
+func Header(timestamp time.Time, body []byte, secrets ...string) string {
+	parts := []string{"t=" + strconv.FormatInt(timestamp.Unix(), 10)}
+	for _, secret := range secrets {
+		parts = append(parts, signatureScheme+"="+Sign(secret, timestamp, body))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Verify checks a signature header against a body and secret. A tolerance of zero or
+// less skips the timestamp check, which should only be done in tests.
+func Verify(header string, body []byte, secret string, tolerance time.Duration) error {
+	return verifyAt(header, body, secret, tolerance, time.Now())
+}
+
+// VerifyRequest reads a request's body and verifies its signature. The body is
+// returned so the caller can decode it, and r.Body is replaced so it can be read again.
+func VerifyRequest(r *http.Request, secret string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := Verify(r.Header.Get(SignatureHeader), body, secret, tolerance); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func verifyAt(header string, body []byte, secret string, tolerance time.Duration, now time.Time) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var timestamp time.Time
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidHeader
+		}
+		switch key {
+		case "t":
+			unix, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidHeader
+			}
+			timestamp = time.Unix(unix, 0)
+		case signatureScheme:
+			signatures = append(signatures, value)
+		}
+		// Unknown schemes are ignored so new ones can be added alongside v1
+	}
+	if timestamp.IsZero() || len(signatures) == 0 {
+		return ErrInvalidHeader
+	}
+
+	if tolerance > 0 {
+		age := now.Sub(timestamp)
+		if age > tolerance || age < -tolerance {
+			return ErrTimestampExpired
+		}
+	}
+
+	expected := []byte(Sign(secret, timestamp, body))
+	for _, signature := range signatures {
+		if hmac.Equal(expected, []byte(signature)) {
+			return nil
+		}
+	}
+	return ErrNoMatch
+}
@@ -0,0 +1,100 @@
+package webhooksig
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"record.created"}`)
+
+	signature := Sign("secret", ts, body)
+	assert.Len(t, signature, 64)
+	assert.Equal(t, signature, Sign("secret", ts, body))
+
+	// The timestamp is part of the signed content
+	assert.NotEqual(t, signature, Sign("secret", ts.Add(time.Second), body))
+	assert.NotEqual(t, signature, Sign("other", ts, body))
+}
+
+func TestHeader(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	body := []byte("{}")
+
+	header := Header(ts, body, "new", "old")
+	assert.Equal(t, "t=1700000000,v1="+Sign("new", ts, body)+",v1="+Sign("old", ts, body), header)
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"record.created"}`)
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		secret  string
+		wantErr error
+	}{
+		{"valid signature", Header(now, body, "secret"), body, "secret", nil},
+		{"either of two signatures", Header(now, body, "new", "secret"), body, "secret", nil},
+		{"slightly old timestamp", Header(now.Add(-4*time.Minute), body, "secret"), body, "secret", nil},
+		{"wrong secret", Header(now, body, "secret"), body, "other", ErrNoMatch},
+		{"modified body", Header(now, body, "secret"), []byte(`{"event":"record.deleted"}`), "secret", ErrNoMatch},
+		{"replayed request", Header(now.Add(-10*time.Minute), body, "secret"), body, "secret", ErrTimestampExpired},
+		{"timestamp in the future", Header(now.Add(10*time.Minute), body, "secret"), body, "secret", ErrTimestampExpired},
+		{"missing header", "", body, "secret", ErrMissingSignature},
+		{"missing timestamp", "v1=" + Sign("secret", now, body), body, "secret", ErrInvalidHeader},
+		{"missing signature", "t=1700000000", body, "secret", ErrInvalidHeader},
+		{"bad timestamp", "t=soon,v1=abc", body, "secret", ErrInvalidHeader},
+		{"legacy format", "sha256=abc", body, "secret", ErrInvalidHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyAt(tt.header, tt.body, tt.secret, DefaultTolerance, now)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("ignores unknown schemes", func(t *testing.T) {
+		header := Header(now, body, "secret") + ",v2=whatever"
+		assert.NoError(t, verifyAt(header, body, "secret", DefaultTolerance, now))
+	})
+
+	t.Run("zero tolerance skips the timestamp check", func(t *testing.T) {
+		header := Header(now.Add(-time.Hour), body, "secret")
+		assert.NoError(t, verifyAt(header, body, "secret", 0, now))
+	})
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := `{"event":"record.created"}`
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	req.Header.Set(SignatureHeader, Header(time.Now(), []byte(body), "secret"))
+
+	got, err := VerifyRequest(req, "secret", DefaultTolerance)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(got))
+
+	// The body can still be read by the handler
+	again, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(again))
+
+	req = httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	req.Header.Set(SignatureHeader, Header(time.Now(), []byte(body), "secret"))
+	_, err = VerifyRequest(req, "wrong", DefaultTolerance)
+	assert.ErrorIs(t, err, ErrNoMatch)
+}
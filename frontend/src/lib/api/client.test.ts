@@ -1,5 +1,5 @@
 import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
-import { auth, bases, tables, fields, records, views, csv, forms, publicForms, publicViews, comments, activity, attachments, automations, apiKeys, webhooks, ApiError } from './client';
+import { auth, bases, tables, fields, records, views, csv, jobs, forms, publicForms, publicViews, comments, activity, attachments, automations, apiKeys, webhooks, ApiError } from './client';
 
 // Mock fetch globally
 const mockFetch = vi.fn();
@@ -296,13 +296,21 @@ describe('API Client', () => {
 		});
 
 		it('should duplicate base without records', async () => {
+			vi.useFakeTimers();
 			const mockBase = { id: '2', name: 'Base 1 (copy)' };
 			mockFetch.mockResolvedValueOnce({
 				ok: true,
-				json: () => Promise.resolve(mockBase),
+				json: () => Promise.resolve({ id: 'job-1', job_type: 'duplicate_base', status: 'queued' }),
+			});
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve({ id: 'job-1', job_type: 'duplicate_base', status: 'succeeded', result: mockBase }),
 			});
 
-			const result = await bases.duplicate('1');
+			const promise = bases.duplicate('1');
+			await vi.advanceTimersByTimeAsync(500);
+			const result = await promise;
+			vi.useRealTimers();
 
 			expect(mockFetch).toHaveBeenCalledWith(
 				'http://localhost:8080/api/v1/bases/1/duplicate',
@@ -311,6 +319,10 @@ describe('API Client', () => {
 					body: JSON.stringify({ include_records: false }),
 				})
 			);
+			expect(mockFetch).toHaveBeenCalledWith(
+				'http://localhost:8080/api/v1/jobs/job-1',
+				expect.anything()
+			);
 			expect(result).toEqual(mockBase);
 		});
 
@@ -499,13 +511,21 @@ describe('API Client', () => {
 		});
 
 		it('should duplicate table', async () => {
+			vi.useFakeTimers();
 			const mockTable = { id: '2', name: 'Table 1 (copy)' };
 			mockFetch.mockResolvedValueOnce({
 				ok: true,
-				json: () => Promise.resolve(mockTable),
+				json: () => Promise.resolve({ id: 'job-1', job_type: 'duplicate_table', status: 'running' }),
+			});
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve({ id: 'job-1', job_type: 'duplicate_table', status: 'succeeded', result: mockTable }),
 			});
 
-			const result = await tables.duplicate('1', true);
+			const promise = tables.duplicate('1', true);
+			await vi.advanceTimersByTimeAsync(500);
+			const result = await promise;
+			vi.useRealTimers();
 
 			expect(mockFetch).toHaveBeenCalledWith(
 				'http://localhost:8080/api/v1/tables/1/duplicate',
@@ -561,10 +581,69 @@ describe('API Client', () => {
 			expect(result).toEqual(mockResult);
 		});
 
-		it('should generate export URL', () => {
-			localStorageMock.setItem('token', 'test-token');
-			const url = csv.exportUrl('table-1');
-			expect(url).toBe('http://localhost:8080/api/v1/tables/table-1/csv/export?token=test-token');
+		it('should wait for a large import to finish', async () => {
+			vi.useFakeTimers();
+			const mockResult = { imported: 5000, skipped: 0, errors: 0 };
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve({ id: 'job-1', job_type: 'import_records', status: 'queued' }),
+			});
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve({ id: 'job-1', job_type: 'import_records', status: 'running' }),
+			});
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve({ id: 'job-1', job_type: 'import_records', status: 'succeeded', result: mockResult }),
+			});
+
+			const promise = csv.import('table-1', 'csv-data', { name: 'field-1' });
+			await vi.advanceTimersByTimeAsync(1000);
+			const result = await promise;
+			vi.useRealTimers();
+
+			expect(result).toEqual(mockResult);
+		});
+
+		it('should reject when the import job fails', async () => {
+			vi.useFakeTimers();
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve({ id: 'job-1', job_type: 'import_records', status: 'queued' }),
+			});
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve({ id: 'job-1', job_type: 'import_records', status: 'failed', error: 'The job failed unexpectedly' }),
+			});
+
+			const promise = csv.import('table-1', 'csv-data', { name: 'field-1' });
+			const assertion = expect(promise).rejects.toThrow('The job failed unexpectedly');
+			await vi.advanceTimersByTimeAsync(500);
+			await assertion;
+			vi.useRealTimers();
+		});
+
+		it('should request a signed export link', async () => {
+			const mockLink = {
+				url: 'http://localhost:8080/api/v1/tables/table-1/csv/export?user=user-1&expires=1&signature=sig',
+				expires_at: '2024-01-01T00:05:00Z',
+			};
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve(mockLink),
+			});
+
+			const result = await csv.exportLink('table-1');
+
+			expect(mockFetch).toHaveBeenCalledWith(
+				'http://localhost:8080/api/v1/tables/table-1/export-link',
+				expect.objectContaining({
+					method: 'POST',
+					body: JSON.stringify({ format: 'csv' }),
+				})
+			);
+			expect(result).toEqual(mockLink);
+			expect(result.url).not.toContain('token=');
 		});
 	});
 
@@ -1294,6 +1373,44 @@ describe('API Client', () => {
 		});
 	});
 
+	describe('jobs', () => {
+		beforeEach(() => {
+			localStorageMock.setItem('token', 'test-token');
+		});
+
+		it('should get a job', async () => {
+			const mockJob = { id: 'job-1', job_type: 'duplicate_base', status: 'running', progress: 2, total: 5 };
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve(mockJob),
+			});
+
+			const result = await jobs.get('job-1');
+
+			expect(mockFetch).toHaveBeenCalledWith(
+				'http://localhost:8080/api/v1/jobs/job-1',
+				expect.anything()
+			);
+			expect(result).toEqual(mockJob);
+		});
+
+		it('should cancel a job', async () => {
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: () => Promise.resolve({ id: 'job-1', status: 'running', cancel_requested: true }),
+			});
+
+			await jobs.cancel('job-1');
+
+			expect(mockFetch).toHaveBeenCalledWith(
+				'http://localhost:8080/api/v1/jobs/job-1/cancel',
+				expect.objectContaining({
+					method: 'POST',
+				})
+			);
+		});
+	});
+
 	describe('attachments', () => {
 		beforeEach(() => {
 			localStorageMock.setItem('token', 'test-token');
@@ -1364,12 +1481,6 @@ describe('API Client', () => {
 				})
 			);
 		});
-
-		it('should generate download URL', () => {
-			localStorageMock.setItem('token', 'test-token');
-			const url = attachments.getDownloadUrl('att-1');
-			expect(url).toBe('http://localhost:8080/api/v1/attachments/att-1/download?token=test-token');
-		});
 	});
 
 	describe('automations', () => {
@@ -1,4 +1,4 @@
-import type { User, Base, Table, Field, Record, RecordColor, BaseCollaborator, View, ViewConfig, ViewType, Form, FormField, PublicForm, PublicView, Comment, Activity, Attachment, Automation, AutomationRun, TriggerType, ActionType, APIKey, APIKeyWithToken, Webhook, WebhookDelivery, WebhookEvent } from '$lib/types';
+import type { User, Base, Table, Field, Record, RecordColor, BaseCollaborator, View, ViewConfig, ViewType, Form, FormField, PublicForm, PublicView, Comment, Activity, Attachment, Automation, AutomationRun, TriggerType, ActionType, APIKey, APIKeyWithToken, Webhook, WebhookDelivery, WebhookEvent, Job } from '$lib/types';
 
 const API_URL = import.meta.env.VITE_PUBLIC_API_URL || 'http://localhost:8080';
 
@@ -114,6 +114,40 @@ async function request<T>(
 	return data;
 }
 
+// How often jobResult checks on a background job
+const JOB_POLL_INTERVAL_MS = 500;
+
+// Background jobs API
+export const jobs = {
+	get: <R = unknown>(id: string) => request<Job<R>>(`/jobs/${id}`),
+
+	cancel: (id: string) =>
+		request<Job>(`/jobs/${id}/cancel`, {
+			method: 'POST',
+		}),
+};
+
+function isJob<R>(response: R | Job<R>): response is Job<R> {
+	return typeof response === 'object' && response !== null && 'job_type' in response && 'status' in response;
+}
+
+// jobResult resolves with what an operation returned. Operations that run in a
+// background job answer with the job, which is polled until it finishes; a job
+// that fails or is cancelled rejects with its error.
+export async function jobResult<R>(response: R | Job<R>): Promise<R> {
+	if (!isJob(response)) return response;
+
+	let job = response;
+	while (job.status === 'queued' || job.status === 'running') {
+		await new Promise((resolve) => setTimeout(resolve, JOB_POLL_INTERVAL_MS));
+		job = await jobs.get<R>(job.id);
+	}
+	if (job.status !== 'succeeded') {
+		throw new Error(job.error || `The job was ${job.status}`);
+	}
+	return job.result as R;
+}
+
 // Auth API
 export const auth = {
 	login: (email: string, password: string) =>
@@ -174,11 +208,12 @@ export const bases = {
 			method: 'DELETE',
 		}),
 
+	// Resolves with the new base once the background copy has finished
 	duplicate: (id: string, includeRecords: boolean = false) =>
-		request<Base>(`/bases/${id}/duplicate`, {
+		request<Base | Job<Base>>(`/bases/${id}/duplicate`, {
 			method: 'POST',
 			body: JSON.stringify({ include_records: includeRecords }),
-		}),
+		}).then(jobResult<Base>),
 
 	// Collaborators
 	listCollaborators: (baseId: string) =>
@@ -225,11 +260,12 @@ export const tables = {
 			method: 'DELETE',
 		}),
 
+	// Resolves with the new table once the background copy has finished
 	duplicate: (id: string, includeRecords: boolean = false) =>
-		request<Table>(`/tables/${id}/duplicate`, {
+		request<Table | Job<Table>>(`/tables/${id}/duplicate`, {
 			method: 'POST',
 			body: JSON.stringify({ include_records: includeRecords }),
-		}),
+		}).then(jobResult<Table>),
 };
 
 // CSV API
@@ -245,6 +281,11 @@ export interface CSVImportResponse {
 	errors: number;
 }
 
+export interface ExportLink {
+	url: string;
+	expires_at: string;
+}
+
 export const csv = {
 	preview: (tableId: string, data: string) =>
 		request<CSVPreviewResponse>(`/tables/${tableId}/csv/preview`, {
@@ -252,17 +293,19 @@ export const csv = {
 			body: JSON.stringify({ data }),
 		}),
 
+	// Large imports run in a background job; this resolves once it has finished
 	import: (tableId: string, data: string, mappings: { [column: string]: string }) =>
-		request<CSVImportResponse>(`/tables/${tableId}/csv/import`, {
+		request<CSVImportResponse | Job<CSVImportResponse>>(`/tables/${tableId}/csv/import`, {
 			method: 'POST',
 			body: JSON.stringify({ data, mappings }),
-		}),
+		}).then(jobResult<CSVImportResponse>),
 
-	exportUrl: (tableId: string) => {
-		const token = typeof localStorage !== 'undefined' ? localStorage.getItem('token') : null;
-		const API_URL = import.meta.env.VITE_PUBLIC_API_URL || 'http://localhost:8080';
-		return `${API_URL}/api/v1/tables/${tableId}/csv/export?token=${token}`;
-	},
+	// Returns a short-lived signed link that downloads the table as CSV
+	exportLink: (tableId: string) =>
+		request<ExportLink>(`/tables/${tableId}/export-link`, {
+			method: 'POST',
+			body: JSON.stringify({ format: 'csv' }),
+		}),
 };
 
 // Fields API
@@ -551,11 +594,6 @@ export const attachments = {
 		request<{ message: string }>(`/attachments/${id}`, {
 			method: 'DELETE',
 		}),
-
-	getDownloadUrl: (id: string) => {
-		const token = typeof localStorage !== 'undefined' ? localStorage.getItem('token') : null;
-		return `${API_URL}/api/v1/attachments/${id}/download?token=${token}`;
-	},
 };
 
 // Automations API
@@ -317,6 +317,27 @@ export interface WebhookDelivery {
 	delivered_at: string;
 }
 
+// Background job types
+export type JobType = 'duplicate_base' | 'duplicate_table' | 'import_records';
+export type JobStatus = 'queued' | 'running' | 'succeeded' | 'failed' | 'cancelled';
+
+export interface Job<R = unknown> {
+	id: string;
+	base_id: string;
+	job_type: JobType;
+	status: JobStatus;
+	progress: number;
+	total: number;
+	result?: R;
+	error?: string;
+	cancel_requested: boolean;
+	created_by: string;
+	created_at: string;
+	started_at?: string;
+	finished_at?: string;
+	updated_at: string;
+}
+
 // Activity types
 export type ActivityAction = 'create' | 'update' | 'delete';
 export type ActivityEntityType = 'record' | 'field' | 'table' | 'view' | 'base';